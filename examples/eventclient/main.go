@@ -0,0 +1,125 @@
+// Command eventclient is an example wrapper for the scanner's event stream. It listens
+// on a Unix socket, starts the scanner with -event-socket and the given arguments,
+// prints the events it receives and forwards pause, resume and stop typed on stdin.
+//
+//	go run ./examples/eventclient -- -l 3 -s .li -p D
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// event holds the fields of a scanner event this client prints
+type event struct {
+	Type   string `json:"type"`
+	State  string `json:"state"`
+	Domain string `json:"domain"`
+	Result *struct {
+		Domain    string `json:"domain"`
+		Available bool   `json:"available"`
+		Error     string `json:"error"`
+	} `json:"result"`
+	Progress *struct {
+		Processed int     `json:"processed"`
+		Total     int     `json:"total"`
+		Available int     `json:"available"`
+		Rate      float64 `json:"rate"`
+		Dropped   int64   `json:"dropped_events"`
+	} `json:"progress"`
+	Command string `json:"command"`
+	Message string `json:"message"`
+}
+
+func main() {
+	scannerCmd := flag.String("scanner", "go run .", "Command starting the scanner")
+	flag.Parse()
+
+	dir, err := os.MkdirTemp("", "domain-scanner-events")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "events.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer listener.Close()
+
+	args := append(strings.Fields(*scannerCmd), "-event-socket", socket)
+	cmd := exec.Command(args[0], append(args[1:], flag.Args()...)...)
+	// The console output of the scanner is not needed; events carry everything
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	// Forward commands typed on stdin
+	go func() {
+		input := bufio.NewScanner(os.Stdin)
+		for input.Scan() {
+			command := strings.TrimSpace(input.Text())
+			if command == "" {
+				continue
+			}
+			line, _ := json.Marshal(map[string]string{"command": command})
+			conn.Write(append(line, '\n'))
+		}
+	}()
+
+	lines := bufio.NewScanner(conn)
+	lines.Buffer(make([]byte, 64*1024), 1<<20)
+	for lines.Scan() {
+		var e event
+		if err := json.Unmarshal(lines.Bytes(), &e); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid event: %v\n", err)
+			continue
+		}
+		switch e.Type {
+		case "result":
+			switch {
+			case e.Result.Error != "":
+				fmt.Printf("error      %s: %s\n", e.Result.Domain, e.Result.Error)
+			case e.Result.Available:
+				fmt.Printf("available  %s\n", e.Result.Domain)
+			}
+		case "progress":
+			fmt.Printf("progress   %d/%d checked, %d available, %.1f/s, %d events dropped\n",
+				e.Progress.Processed, e.Progress.Total, e.Progress.Available, e.Progress.Rate, e.Progress.Dropped)
+		case "rate_limit":
+			fmt.Printf("throttled  %s\n", e.Domain)
+		case "state":
+			fmt.Printf("state      %s\n", e.State)
+		case "ack":
+			fmt.Printf("ok         %s\n", e.Command)
+		case "error":
+			fmt.Printf("error      %s %s\n", e.Command, e.Message)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		fmt.Fprintf(os.Stderr, "scanner: %v\n", err)
+		if exit, ok := err.(*exec.ExitError); ok {
+			os.Exit(exit.ExitCode())
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,194 @@
+package batch
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"domain-scanner/internal/scanner"
+)
+
+// RunCommand executes a "batch" subcommand and returns the process exit code
+func RunCommand(args []string) int {
+	if len(args) == 0 {
+		printBatchHelp()
+		return scanner.ExitUsage
+	}
+
+	switch args[0] {
+	case "status":
+		return runStatus(args[1:])
+	case "resume":
+		return runResume(args[1:])
+	case "run":
+		return runRun(args[1:])
+	case "matrix":
+		return runMatrix(args[1:])
+	case "report":
+		return runReport(args[1:])
+	case "-h", "help":
+		printBatchHelp()
+		return scanner.ExitOK
+	default:
+		fmt.Printf("Unknown batch command: %s\n", args[0])
+		printBatchHelp()
+		return scanner.ExitUsage
+	}
+}
+
+func printBatchHelp() {
+	fmt.Println("Usage:")
+	fmt.Println("  domain-scanner batch status -dir ./results")
+	fmt.Println("  domain-scanner batch run -dir ./config [-parallel N] [-whois-interval MS]")
+	fmt.Println("  domain-scanner batch resume -dir ./results [-parallel N] [-whois-interval MS]")
+	fmt.Println("  domain-scanner batch matrix -dir ./config [-max-parallel N] [-split]")
+	fmt.Println("  domain-scanner batch report -dir ./results [-out DIR] [-highlight-regex RE] [-top N]")
+	fmt.Println("\nCommands:")
+	fmt.Println("  status   Show the state of every batch found below -dir")
+	fmt.Println("  run      Scan every batch config in -dir, sharing one WHOIS rate limiter")
+	fmt.Println("  resume   Re-run batches that are not completed")
+	fmt.Println("  matrix   Print a GitHub Actions strategy (matrix + max-parallel) from batch_index.json")
+	fmt.Println("  report   Write a Markdown and HTML campaign report from the batch status files")
+}
+
+// runStatus prints a table with the state of every batch below the results directory
+func runStatus(args []string) int {
+	fs := flag.NewFlagSet("batch status", flag.ContinueOnError)
+	dir := fs.String("dir", "./results", "Batch results directory")
+	if err := fs.Parse(args); err != nil {
+		return scanner.FlagExitCode(err)
+	}
+
+	statuses, err := ListStatuses(*dir)
+	if err != nil {
+		fmt.Printf("Error reading batch status: %v\n", err)
+		return scanner.ExitUsage
+	}
+	if len(statuses) == 0 {
+		fmt.Printf("No batch status files found in %s\n", *dir)
+		return scanner.ExitOK
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BATCH\tSTATE\tSTARTED\tFINISHED\tPROCESSED\tAVAILABLE\tREGISTERED\tUNCERTAIN\tRATE LIMITED\tERRORS")
+	summary := make(map[string]int)
+	for _, status := range statuses {
+		state := status.State
+		if state == StateRunning && !IsLocked(status.OutputDir) {
+			state = StateRunning + " (stale)"
+		}
+		summary[status.State]++
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\n",
+			status.Name, state, formatTime(status.StartedAt), formatTime(status.FinishedAt),
+			status.Counts.Processed, status.Counts.Available, status.Counts.Registered,
+			status.Counts.Uncertain, status.Counts.RateLimited, status.Counts.Errors)
+	}
+	_ = tw.Flush()
+
+	fmt.Printf("\nTotal: %d batches (%d completed, %d running, %d pending, %d aborted)\n",
+		len(statuses), summary[StateCompleted], summary[StateRunning], summary[StatePending], summary[StateAborted])
+	return scanner.ExitOK
+}
+
+// runResume re-runs every batch that has not completed
+func runResume(args []string) int {
+	fs := flag.NewFlagSet("batch resume", flag.ContinueOnError)
+	dir := fs.String("dir", "./results", "Batch results directory")
+	parallel := fs.Int("parallel", 1, "Maximum number of batches to run concurrently")
+	whoisInterval := fs.Int("whois-interval", 0, "Minimum milliseconds between WHOIS queries to the same server across all batches (0: derive from config, <0: unlimited)")
+	if err := fs.Parse(args); err != nil {
+		return scanner.FlagExitCode(err)
+	}
+	if *parallel < 1 {
+		fmt.Println("Error: -parallel must be at least 1")
+		return scanner.ExitUsage
+	}
+
+	statuses, err := ListStatuses(*dir)
+	if err != nil {
+		fmt.Printf("Error reading batch status: %v\n", err)
+		return scanner.ExitUsage
+	}
+
+	var pending []*Status
+	for _, status := range statuses {
+		if status.State == StateCompleted {
+			continue
+		}
+		if IsLocked(status.OutputDir) {
+			fmt.Printf("Skipping batch %s: already running\n", status.Name)
+			continue
+		}
+		pending = append(pending, status)
+	}
+	if len(pending) == 0 {
+		fmt.Println("All batches are completed, nothing to resume")
+		return scanner.ExitOK
+	}
+
+	var configs []string
+	for _, status := range pending {
+		if status.Config == "" {
+			fmt.Printf("Skipping batch %s: status file does not record a config path\n", status.Name)
+			continue
+		}
+		configs = append(configs, status.Config)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return runBatches(ctx, configs, *parallel, time.Duration(*whoisInterval)*time.Millisecond)
+}
+
+// runRun scans every batch config found in a config directory
+func runRun(args []string) int {
+	fs := flag.NewFlagSet("batch run", flag.ContinueOnError)
+	dir := fs.String("dir", "./config", "Directory containing batch config files")
+	parallel := fs.Int("parallel", 1, "Maximum number of batches to run concurrently")
+	whoisInterval := fs.Int("whois-interval", 0, "Minimum milliseconds between WHOIS queries to the same server across all batches (0: derive from config, <0: unlimited)")
+	if err := fs.Parse(args); err != nil {
+		return scanner.FlagExitCode(err)
+	}
+	if *parallel < 1 {
+		fmt.Println("Error: -parallel must be at least 1")
+		return scanner.ExitUsage
+	}
+
+	configs, err := FindConfigs(*dir)
+	if err != nil {
+		fmt.Printf("Error reading batch configs: %v\n", err)
+		return scanner.ExitUsage
+	}
+	if len(configs) == 0 {
+		fmt.Printf("No batch configs found in %s\n", *dir)
+		return scanner.ExitUsage
+	}
+
+	// Ctrl-C stops dispatching; in-flight batches finish their queued checks and are checkpointed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return runBatches(ctx, configs, *parallel, time.Duration(*whoisInterval)*time.Millisecond)
+}
+
+// IsLocked reports whether a live process currently holds the batch lock
+func IsLocked(outputDir string) bool {
+	path := filepath.Join(outputDir, LockFileName)
+	if _, err := os.Stat(path); err != nil {
+		return false
+	}
+	return !staleLock(path)
+}
+
+// formatTime renders an optional timestamp for the status table
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,72 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// IndexFileName is the machine-readable batch index written next to the batch configs
+const IndexFileName = "batch_index.json"
+
+// IndexEntry describes one generated batch
+type IndexEntry struct {
+	Name      string `json:"name"`
+	Config    string `json:"config"`
+	OutputDir string `json:"output_dir"`
+	Regex     string `json:"regex,omitempty"`
+	// Offset and Limit record the keyspace range [offset, offset+limit) of count-split batches
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+	// SpanStart and SpanEnd are the first and last number of a numeric range batch
+	SpanStart string `json:"span_start,omitempty"`
+	SpanEnd   string `json:"span_end,omitempty"`
+	// Expected is the number of domains the batch generates; omitted when unknown
+	Expected *int `json:"expected_count,omitempty"`
+}
+
+// IndexScanner records the scanner tuning written into every batch config
+type IndexScanner struct {
+	Workers        int      `json:"workers"`
+	Delay          int      `json:"delay_ms"`
+	Methods        []string `json:"methods"`
+	ShowRegistered bool     `json:"show_registered"`
+}
+
+// Index describes a set of generated batch configs
+type Index struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	BaseDomain  string       `json:"base_domain"`
+	Length      int          `json:"length"`
+	Pattern     string       `json:"pattern"`
+	SplitBy     string       `json:"split_by"`
+	Keyspace    int          `json:"keyspace"`
+	ConfigDir   string       `json:"config_dir"`
+	OutputDir   string       `json:"output_dir"`
+	Scanner     IndexScanner `json:"scanner"`
+	Batches     []IndexEntry `json:"batches"`
+}
+
+// WriteIndex writes the batch index into the config directory
+func WriteIndex(configDir string, index *Index) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(configDir, IndexFileName), append(data, '\n'), 0644)
+}
+
+// ReadIndex loads the batch index from the config directory
+func ReadIndex(configDir string) (*Index, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, IndexFileName))
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("invalid batch index in %s: %w", configDir, err)
+	}
+	return index, nil
+}
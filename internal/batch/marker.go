@@ -0,0 +1,119 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MarkerFileName records the generation parameters inside each batch output directory
+const MarkerFileName = "batch_params.json"
+
+// Params are the generation parameters that determine the contents of a batch
+type Params struct {
+	BaseDomain string `json:"base_domain"`
+	Length     int    `json:"length"`
+	Pattern    string `json:"pattern"`
+	SplitBy    string `json:"split_by"`
+	Regex      string `json:"regex,omitempty"`
+	Offset     int    `json:"offset"`
+	Limit      int    `json:"limit"`
+}
+
+// Diff describes every parameter that differs from other, one entry per field
+func (p Params) Diff(other Params) []string {
+	var diffs []string
+	add := func(field string, a, b interface{}) {
+		if a != b {
+			diffs = append(diffs, fmt.Sprintf("%s: %v -> %v", field, a, b))
+		}
+	}
+	add("base_domain", p.BaseDomain, other.BaseDomain)
+	add("length", p.Length, other.Length)
+	add("pattern", p.Pattern, other.Pattern)
+	add("split_by", p.SplitBy, other.SplitBy)
+	add("regex", p.Regex, other.Regex)
+	add("offset", p.Offset, other.Offset)
+	add("limit", p.Limit, other.Limit)
+	return diffs
+}
+
+// WriteParams writes the generation marker into a batch output directory
+func WriteParams(outputDir string, params Params) error {
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, MarkerFileName), append(data, '\n'), 0644)
+}
+
+// ReadParams loads the generation marker from a batch output directory
+func ReadParams(outputDir string) (*Params, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, MarkerFileName))
+	if err != nil {
+		return nil, err
+	}
+	params := &Params{}
+	if err := json.Unmarshal(data, params); err != nil {
+		return nil, fmt.Errorf("invalid batch marker in %s: %w", outputDir, err)
+	}
+	return params, nil
+}
+
+// Collision describes an existing batch output directory that a new generation would reuse
+type Collision struct {
+	OutputDir string
+	// Files is the number of entries that were not written by the generator itself
+	Files int
+	// Mismatch lists the parameters that differ from the recorded marker
+	Mismatch []string
+}
+
+// CheckOutputDir reports whether an output directory holds data from a previous run.
+// The marker and a pending status file are written by the generator and do not count,
+// but a marker recording different parameters always does.
+func CheckOutputDir(outputDir string, params Params) (*Collision, error) {
+	entries, err := os.ReadDir(outputDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	collision := &Collision{OutputDir: outputDir}
+	for _, entry := range entries {
+		switch entry.Name() {
+		case MarkerFileName:
+			continue
+		case StatusFileName:
+			if status, err := ReadStatus(outputDir); err == nil && status.State == StatePending {
+				continue
+			}
+		}
+		collision.Files++
+	}
+
+	if recorded, err := ReadParams(outputDir); err == nil {
+		collision.Mismatch = recorded.Diff(params)
+	}
+	if collision.Files == 0 && len(collision.Mismatch) == 0 {
+		return nil, nil
+	}
+	return collision, nil
+}
+
+// CleanOutputDir deletes the contents of a batch output directory, keeping the directory
+func CleanOutputDir(outputDir string) error {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(outputDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package batch
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"domain-scanner/internal/scanner"
+)
+
+// MaxMatrixJobs is the GitHub Actions limit of jobs generated by a single matrix
+const MaxMatrixJobs = 256
+
+// MatrixEntry is one job of a workflow matrix
+type MatrixEntry struct {
+	Name      string `json:"name"`
+	Config    string `json:"config"`
+	OutputDir string `json:"output_dir"`
+}
+
+// Matrix is the value of strategy.matrix in a workflow
+type Matrix struct {
+	Include []MatrixEntry `json:"include"`
+}
+
+// Strategy mirrors a workflow job strategy so it can be consumed with fromJSON()
+type Strategy struct {
+	FailFast    bool   `json:"fail-fast"`
+	MaxParallel int    `json:"max-parallel"`
+	Matrix      Matrix `json:"matrix"`
+}
+
+// BuildStrategies splits the batches of an index into strategies of at most maxJobs jobs
+func BuildStrategies(index *Index, maxParallel, maxJobs int) []Strategy {
+	if maxJobs <= 0 || maxJobs > MaxMatrixJobs {
+		maxJobs = MaxMatrixJobs
+	}
+
+	var strategies []Strategy
+	for start := 0; start < len(index.Batches); start += maxJobs {
+		end := start + maxJobs
+		if end > len(index.Batches) {
+			end = len(index.Batches)
+		}
+		strategy := Strategy{MaxParallel: maxParallel, Matrix: Matrix{Include: []MatrixEntry{}}}
+		for _, entry := range index.Batches[start:end] {
+			strategy.Matrix.Include = append(strategy.Matrix.Include, MatrixEntry{
+				Name:      entry.Name,
+				Config:    entry.Config,
+				OutputDir: entry.OutputDir,
+			})
+		}
+		strategies = append(strategies, strategy)
+	}
+	return strategies
+}
+
+// runMatrix prints a GitHub Actions strategy object built from batch_index.json
+func runMatrix(args []string) int {
+	fs := flag.NewFlagSet("batch matrix", flag.ContinueOnError)
+	dir := fs.String("dir", "./config", "Directory containing batch_index.json")
+	maxParallel := fs.Int("max-parallel", 5, "Maximum number of concurrent jobs per matrix")
+	maxJobs := fs.Int("max-jobs", MaxMatrixJobs, "Maximum number of jobs per matrix")
+	split := fs.Bool("split", false, "Split into multiple matrices when the job limit is exceeded")
+	if err := fs.Parse(args); err != nil {
+		return scanner.FlagExitCode(err)
+	}
+	if *maxParallel < 1 {
+		fmt.Println("Error: -max-parallel must be at least 1")
+		return scanner.ExitUsage
+	}
+
+	index, err := ReadIndex(*dir)
+	if err != nil {
+		fmt.Printf("Error reading batch index: %v\n", err)
+		return scanner.ExitUsage
+	}
+
+	strategies := BuildStrategies(index, *maxParallel, *maxJobs)
+	if len(strategies) == 0 {
+		strategies = []Strategy{{MaxParallel: *maxParallel, Matrix: Matrix{Include: []MatrixEntry{}}}}
+	}
+
+	var output interface{} = strategies[0]
+	if len(strategies) > 1 {
+		if !*split {
+			fmt.Printf("Error: %d batches exceed the limit of %d jobs per matrix; use -split\n",
+				len(index.Batches), *maxJobs)
+			return scanner.ExitUsage
+		}
+		output = struct {
+			Parts []Strategy `json:"parts"`
+		}{Parts: strategies}
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		fmt.Printf("Error encoding matrix: %v\n", err)
+		return scanner.ExitUsage
+	}
+	fmt.Println(string(data))
+	return scanner.ExitOK
+}
@@ -0,0 +1,161 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"domain-scanner/internal/scanner"
+)
+
+// workflowStrategy is the part of a strategy a workflow reads after
+// strategy: ${{ fromJSON(needs.plan.outputs.strategy) }}, with every job using
+// matrix.config and matrix.output_dir
+type workflowStrategy struct {
+	FailFast    bool `json:"fail-fast"`
+	MaxParallel int  `json:"max-parallel"`
+	Matrix      struct {
+		Include []map[string]string `json:"include"`
+	} `json:"matrix"`
+}
+
+// captureStdout returns what fn prints to standard output
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	defer func() { os.Stdout = stdout }()
+	fn()
+	_ = w.Close()
+	return string(<-done)
+}
+
+func TestMatrixMatchesFixture(t *testing.T) {
+	var code int
+	out := captureStdout(t, func() {
+		code = runMatrix([]string{"-dir", filepath.Join("testdata", "matrix"), "-max-parallel", "2"})
+	})
+	if code != scanner.ExitOK {
+		t.Fatalf("runMatrix() = %d, output:\n%s", code, out)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "matrix", "strategy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != string(want) {
+		t.Errorf("runMatrix() printed\n%s\nwant\n%s", out, want)
+	}
+
+	// The workflow sees every batch of the index as a job with its config and output directory
+	var strategy workflowStrategy
+	decoder := json.NewDecoder(strings.NewReader(out))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&strategy); err != nil {
+		t.Fatalf("strategy is not valid JSON for the workflow: %v", err)
+	}
+	index, err := ReadIndex(filepath.Join("testdata", "matrix"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strategy.FailFast || strategy.MaxParallel != 2 || len(strategy.Matrix.Include) != len(index.Batches) {
+		t.Fatalf("strategy = %+v, want max-parallel 2 and %d jobs", strategy, len(index.Batches))
+	}
+	for i, job := range strategy.Matrix.Include {
+		entry := index.Batches[i]
+		if job["name"] != entry.Name || job["config"] != entry.Config || job["output_dir"] != entry.OutputDir {
+			t.Errorf("job %d = %v, want %s %s %s", i, job, entry.Name, entry.Config, entry.OutputDir)
+		}
+	}
+
+	// Encoding the decoded strategy again gives the same JSON
+	var decoded Strategy
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	again, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, bytes.TrimSpace([]byte(out))) {
+		t.Errorf("round trip = %s, want %s", again, out)
+	}
+}
+
+func TestBuildStrategiesSplits(t *testing.T) {
+	tests := []struct {
+		name      string
+		batches   int
+		maxJobs   int
+		wantSizes []int
+	}{
+		{name: "empty", batches: 0, maxJobs: 10},
+		{name: "one matrix", batches: 10, maxJobs: 10, wantSizes: []int{10}},
+		{name: "split", batches: 25, maxJobs: 10, wantSizes: []int{10, 10, 5}},
+		{name: "job limit", batches: 300, maxJobs: 0, wantSizes: []int{MaxMatrixJobs, 300 - MaxMatrixJobs}},
+		{name: "above the job limit", batches: 300, maxJobs: 1000, wantSizes: []int{MaxMatrixJobs, 300 - MaxMatrixJobs}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := &Index{}
+			for i := 0; i < tt.batches; i++ {
+				index.Batches = append(index.Batches, IndexEntry{Name: string(rune('a' + i%26))})
+			}
+			var sizes []int
+			jobs := 0
+			for _, strategy := range BuildStrategies(index, 5, tt.maxJobs) {
+				sizes = append(sizes, len(strategy.Matrix.Include))
+				for _, job := range strategy.Matrix.Include {
+					if job.Name != index.Batches[jobs].Name {
+						t.Fatalf("job %d = %s, want %s", jobs, job.Name, index.Batches[jobs].Name)
+					}
+					jobs++
+				}
+			}
+			if !reflect.DeepEqual(sizes, tt.wantSizes) {
+				t.Errorf("matrix sizes = %v, want %v", sizes, tt.wantSizes)
+			}
+		})
+	}
+}
+
+func TestMatrixSplitOutput(t *testing.T) {
+	var code int
+	out := captureStdout(t, func() {
+		code = runMatrix([]string{"-dir", filepath.Join("testdata", "matrix"), "-max-jobs", "2", "-split"})
+	})
+	if code != scanner.ExitOK {
+		t.Fatalf("runMatrix() = %d, output:\n%s", code, out)
+	}
+	var parts struct {
+		Parts []workflowStrategy `json:"parts"`
+	}
+	if err := json.Unmarshal([]byte(out), &parts); err != nil {
+		t.Fatal(err)
+	}
+	if len(parts.Parts) != 2 || len(parts.Parts[0].Matrix.Include) != 2 || len(parts.Parts[1].Matrix.Include) != 1 {
+		t.Errorf("parts = %+v, want matrices of 2 and 1 jobs", parts.Parts)
+	}
+
+	// Without -split the job limit is an error
+	out = captureStdout(t, func() {
+		code = runMatrix([]string{"-dir", filepath.Join("testdata", "matrix"), "-max-jobs", "2"})
+	})
+	if code == scanner.ExitOK {
+		t.Errorf("runMatrix() without -split = %d, output:\n%s", code, out)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package batch
+
+import (
+	"os"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given PID is still running
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package batch
+
+import "os"
+
+// processAlive reports whether a process with the given PID is still running
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
@@ -0,0 +1,387 @@
+package batch
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"domain-scanner/internal/scanner"
+	"domain-scanner/internal/types"
+)
+
+// Report file names written by "batch report"
+const (
+	ReportMarkdownFileName = "campaign_report.md"
+	ReportHTMLFileName     = "campaign_report.html"
+)
+
+// ReportRow is one batch of a campaign report
+type ReportRow struct {
+	Name     string
+	State    string
+	Counts   Counts
+	Duration time.Duration
+}
+
+// Attention is a batch that needs a look before the campaign results are trusted
+type Attention struct {
+	Name   string
+	Reason string
+}
+
+// InterestingDomain is an available domain listed in a campaign report
+type InterestingDomain struct {
+	Name string
+	// Price is the registration price when the batch looked up prices
+	Price string
+	// Score is the brandability score when the batch scored its domains, otherwise -1
+	Score int
+}
+
+// Report summarizes a campaign of batches
+type Report struct {
+	GeneratedAt    time.Time
+	Rows           []ReportRow
+	Totals         Counts
+	TotalDuration  time.Duration
+	HighlightRegex string
+	// Scored is set when interesting domains are ranked by score instead of length
+	Scored      bool
+	Interesting []InterestingDomain
+	Attention   []Attention
+}
+
+// ReportOptions control the derived sections of a campaign report
+type ReportOptions struct {
+	// Highlight selects the available domains listed as interesting; nil selects all
+	Highlight *regexp.Regexp
+	// TopN limits the interesting domains, best scored or shortest first
+	TopN int
+	// MaxErrorRate flags batches whose errors exceed this share of processed domains
+	MaxErrorRate float64
+}
+
+// runReport writes the campaign report of every batch below a results directory
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("batch report", flag.ContinueOnError)
+	dir := fs.String("dir", "./results", "Batch results directory")
+	out := fs.String("out", "", "Directory for the report files (default: -dir)")
+	highlight := fs.String("highlight-regex", "", "Regex selecting the interesting available domains")
+	top := fs.Int("top", 20, "Number of interesting available domains to list")
+	maxErrorRate := fs.Float64("max-error-rate", 0.05, "Error rate above which a batch needs attention")
+	if err := fs.Parse(args); err != nil {
+		return scanner.FlagExitCode(err)
+	}
+	if *out == "" {
+		*out = *dir
+	}
+
+	opts := ReportOptions{TopN: *top, MaxErrorRate: *maxErrorRate}
+	if *highlight != "" {
+		regex, err := regexp.Compile(*highlight)
+		if err != nil {
+			fmt.Printf("Error: invalid -highlight-regex: %v\n", err)
+			return scanner.ExitUsage
+		}
+		opts.Highlight = regex
+	}
+
+	statuses, err := ListStatuses(*dir)
+	if err != nil {
+		fmt.Printf("Error reading batch status: %v\n", err)
+		return scanner.ExitUsage
+	}
+	if len(statuses) == 0 {
+		fmt.Printf("No batch status files found in %s\n", *dir)
+		return scanner.ExitUsage
+	}
+
+	report := BuildReport(statuses, opts)
+	if err := WriteReport(*out, report); err != nil {
+		fmt.Printf("Error writing report: %v\n", err)
+		return scanner.ExitUsage
+	}
+	fmt.Printf("Report for %d batches written to %s and %s\n", len(statuses),
+		filepath.Join(*out, ReportMarkdownFileName), filepath.Join(*out, ReportHTMLFileName))
+	return scanner.ExitOK
+}
+
+// BuildReport aggregates batch statuses and their available domain files into a report
+func BuildReport(statuses []*Status, opts ReportOptions) *Report {
+	report := &Report{GeneratedAt: time.Now().UTC()}
+	if opts.Highlight != nil {
+		report.HighlightRegex = opts.Highlight.String()
+	}
+
+	seen := make(map[string]bool)
+	var interesting []InterestingDomain
+	for _, status := range statuses {
+		row := ReportRow{Name: status.Name, State: status.State, Counts: status.Counts}
+		if status.State == StateRunning && !IsLocked(status.OutputDir) {
+			row.State = StateRunning + " (stale)"
+		}
+		if status.StartedAt != nil && status.FinishedAt != nil {
+			row.Duration = status.FinishedAt.Sub(*status.StartedAt).Round(time.Second)
+		}
+		report.Rows = append(report.Rows, row)
+
+		report.Totals.Processed += status.Counts.Processed
+		report.Totals.Available += status.Counts.Available
+		report.Totals.Registered += status.Counts.Registered
+		report.Totals.Special += status.Counts.Special
+		report.Totals.Uncertain += status.Counts.Uncertain
+		report.Totals.Errors += status.Counts.Errors
+		report.Totals.RateLimited += status.Counts.RateLimited
+		report.TotalDuration += row.Duration
+
+		if reason := attentionReason(status, opts.MaxErrorRate); reason != "" {
+			report.Attention = append(report.Attention, Attention{Name: status.Name, Reason: reason})
+		}
+
+		prices := readPrices(resultFile(status, status.PricesFile))
+		scores := readScores(resultFile(status, status.ScoresFile))
+		for _, line := range readDomains(resultFile(status, status.AvailableFile)) {
+			// Domains flagged by the blocklist are left out of the report
+			fields := strings.Fields(line)
+			if len(fields) > 1 && fields[1] == types.TrademarkRisk {
+				continue
+			}
+			name := fields[0]
+			if seen[name] || (opts.Highlight != nil && !opts.Highlight.MatchString(name)) {
+				continue
+			}
+			seen[name] = true
+			score, ok := scores[name]
+			if !ok {
+				score = -1
+			}
+			report.Scored = report.Scored || ok
+			interesting = append(interesting, InterestingDomain{Name: name, Price: prices[name], Score: score})
+		}
+	}
+
+	// Scored names are listed best first, otherwise shorter names are the most
+	// valuable; ties are listed alphabetically
+	sort.Slice(interesting, func(i, j int) bool {
+		a, b := interesting[i].Name, interesting[j].Name
+		if interesting[i].Score != interesting[j].Score {
+			return interesting[i].Score > interesting[j].Score
+		}
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+	if opts.TopN > 0 && len(interesting) > opts.TopN {
+		interesting = interesting[:opts.TopN]
+	}
+	report.Interesting = interesting
+	return report
+}
+
+// attentionReason explains why a batch needs attention, or returns an empty string
+func attentionReason(status *Status, maxErrorRate float64) string {
+	switch status.State {
+	case StateAborted:
+		return "aborted"
+	case StatePending:
+		return "never ran"
+	case StateRunning:
+		if !IsLocked(status.OutputDir) {
+			return "stale run (process is gone)"
+		}
+		return "still running"
+	}
+	if status.Counts.Processed > 0 && maxErrorRate > 0 {
+		rate := float64(status.Counts.Errors) / float64(status.Counts.Processed)
+		if rate > maxErrorRate {
+			return fmt.Sprintf("high error rate (%.1f%%)", rate*100)
+		}
+	}
+	return ""
+}
+
+// resultFile locates a result file recorded in a batch status. Results downloaded
+// from CI live in a different directory than where they were written, so the file
+// is also looked up next to the status file.
+func resultFile(status *Status, path string) string {
+	if path == "" {
+		return ""
+	}
+	if _, err := os.Stat(path); err == nil || status.dir == "" {
+		return path
+	}
+	return filepath.Join(status.dir, filepath.Base(path))
+}
+
+// readPrices reads a prices file into a map from domain to its rendered price
+func readPrices(path string) map[string]string {
+	prices := make(map[string]string)
+	for _, line := range readDomains(path) {
+		if name, price, ok := strings.Cut(line, " "); ok {
+			prices[name] = price
+		}
+	}
+	return prices
+}
+
+// readScores reads a scores file into a map from domain to its total score
+func readScores(path string) map[string]int {
+	scores := make(map[string]int)
+	for _, line := range readDomains(path) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if score, err := strconv.Atoi(fields[1]); err == nil {
+			scores[fields[0]] = score
+		}
+	}
+	return scores
+}
+
+// readDomains reads a result file, skipping comments and blank lines
+func readDomains(path string) []string {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names
+}
+
+// WriteReport renders the report as Markdown and HTML into a directory
+func WriteReport(dir string, report *Report) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := writeTemplate(filepath.Join(dir, ReportMarkdownFileName), func(w io.Writer) error {
+		return markdownReportTemplate.Execute(w, report)
+	}); err != nil {
+		return err
+	}
+	return writeTemplate(filepath.Join(dir, ReportHTMLFileName), func(w io.Writer) error {
+		return htmlReportTemplate.Execute(w, report)
+	})
+}
+
+// writeTemplate creates a file and renders a template into it
+func writeTemplate(path string, render func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := render(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// reportFuncs are the helpers shared by the Markdown and HTML report templates
+var reportFuncs = map[string]interface{}{
+	"duration": func(d time.Duration) string {
+		if d == 0 {
+			return "-"
+		}
+		return d.String()
+	},
+	"rate": func(part, total int) string {
+		if total == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", float64(part)*100/float64(total))
+	},
+	"time": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05 MST")
+	},
+}
+
+var markdownReportTemplate = texttemplate.Must(texttemplate.New("markdown").Funcs(reportFuncs).Parse(`# Campaign Report
+
+Generated at: {{time .GeneratedAt}}
+
+## Batches
+
+| Batch | State | Processed | Available | Registered | Uncertain | Errors | Rate limited | Duration |
+|-------|-------|-----------|-----------|------------|-----------|--------|--------------|----------|
+{{range .Rows}}| {{.Name}} | {{.State}} | {{.Counts.Processed}} | {{.Counts.Available}} | {{.Counts.Registered}} | {{.Counts.Uncertain}} | {{.Counts.Errors}} | {{.Counts.RateLimited}} | {{duration .Duration}} |
+{{end}}| **TOTAL** | | {{.Totals.Processed}} | {{.Totals.Available}} | {{.Totals.Registered}} | {{.Totals.Uncertain}} | {{.Totals.Errors}} | {{.Totals.RateLimited}} | {{duration .TotalDuration}} |
+
+Availability rate: {{rate .Totals.Available .Totals.Processed}}, error rate: {{rate .Totals.Errors .Totals.Processed}}
+
+## Interesting Available Domains
+{{if .HighlightRegex}}
+Matching ` + "`{{.HighlightRegex}}`" + `, {{if .Scored}}highest score{{else}}shortest{{end}} first:
+{{else}}
+{{if .Scored}}Highest score{{else}}Shortest{{end}} first:
+{{end}}
+{{range .Interesting}}- {{.Name}}{{if ge .Score 0}} [score {{.Score}}]{{end}}{{if .Price}} ({{.Price}}){{end}}
+{{else}}No available domains found.
+{{end}}
+## Batches Needing Attention
+
+{{range .Attention}}- **{{.Name}}**: {{.Reason}}
+{{else}}All batches completed without problems.
+{{end}}`))
+
+var htmlReportTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Campaign Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child, td:nth-child(2) { text-align: left; }
+tr.total { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Campaign Report</h1>
+<p>Generated at: {{time .GeneratedAt}}</p>
+
+<h2>Batches</h2>
+<table>
+<tr><th>Batch</th><th>State</th><th>Processed</th><th>Available</th><th>Registered</th><th>Uncertain</th><th>Errors</th><th>Rate limited</th><th>Duration</th></tr>
+{{range .Rows}}<tr><td>{{.Name}}</td><td>{{.State}}</td><td>{{.Counts.Processed}}</td><td>{{.Counts.Available}}</td><td>{{.Counts.Registered}}</td><td>{{.Counts.Uncertain}}</td><td>{{.Counts.Errors}}</td><td>{{.Counts.RateLimited}}</td><td>{{duration .Duration}}</td></tr>
+{{end}}<tr class="total"><td>TOTAL</td><td></td><td>{{.Totals.Processed}}</td><td>{{.Totals.Available}}</td><td>{{.Totals.Registered}}</td><td>{{.Totals.Uncertain}}</td><td>{{.Totals.Errors}}</td><td>{{.Totals.RateLimited}}</td><td>{{duration .TotalDuration}}</td></tr>
+</table>
+<p>Availability rate: {{rate .Totals.Available .Totals.Processed}}, error rate: {{rate .Totals.Errors .Totals.Processed}}</p>
+
+<h2>Interesting Available Domains</h2>
+{{if .HighlightRegex}}<p>Matching <code>{{.HighlightRegex}}</code>, {{if .Scored}}highest score{{else}}shortest{{end}} first:</p>{{else}}<p>{{if .Scored}}Highest score{{else}}Shortest{{end}} first:</p>{{end}}
+{{if .Interesting}}<ul>
+{{range .Interesting}}<li>{{.Name}}{{if ge .Score 0}} [score {{.Score}}]{{end}}{{if .Price}} ({{.Price}}){{end}}</li>
+{{end}}</ul>{{else}}<p>No available domains found.</p>{{end}}
+
+<h2>Batches Needing Attention</h2>
+{{if .Attention}}<ul>
+{{range .Attention}}<li><strong>{{.Name}}</strong>: {{.Reason}}</li>
+{{end}}</ul>{{else}}<p>All batches completed without problems.</p>{{end}}
+</body>
+</html>
+`))
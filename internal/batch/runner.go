@@ -0,0 +1,220 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"domain-scanner/internal/config"
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/generator"
+	"domain-scanner/internal/metrics"
+	"domain-scanner/internal/rawdns"
+	"domain-scanner/internal/scanner"
+	"domain-scanner/internal/scoring"
+	"domain-scanner/internal/types"
+)
+
+// batchJob is a loaded batch config ready to be scanned
+type batchJob struct {
+	path string
+	cfg  *types.Config
+}
+
+// batchResult is the outcome of one batch in a run
+type batchResult struct {
+	name    string
+	state   string
+	summary *scanner.Summary
+	err     error
+}
+
+// FindConfigs returns the batch config files (configs with a [batch] name) in a directory
+func FindConfigs(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []string
+	for _, path := range paths {
+		cfg, err := config.LoadConfig(path)
+		if err != nil || cfg.Batch.Name == "" {
+			continue
+		}
+		configs = append(configs, path)
+	}
+	sort.Strings(configs)
+	return configs, nil
+}
+
+// runBatches scans the given batch configs in-process with bounded parallelism.
+// A single WHOIS limiter is shared by all batches; whoisInterval of zero derives it
+// from the first config so parallel batches query no faster than one batch would.
+func runBatches(ctx context.Context, configPaths []string, parallel int, whoisInterval time.Duration) int {
+	var jobs []batchJob
+	for _, path := range configPaths {
+		cfg, err := config.LoadConfig(path)
+		if err != nil {
+			fmt.Printf("Error loading batch config %s: %v\n", path, err)
+			return scanner.ExitUsage
+		}
+		if cfg.Batch.Name == "" {
+			fmt.Printf("Skipping %s: not a batch config\n", path)
+			continue
+		}
+		jobs = append(jobs, batchJob{path: path, cfg: cfg})
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No batch configs to run")
+		return scanner.ExitOK
+	}
+
+	// Detection methods and metrics are process-wide, so every batch uses the first config's settings
+	domain.SetConfig(jobs[0].cfg)
+	exporter, err := metrics.FromConfig(jobs[0].cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return scanner.ExitUsage
+	}
+	domain.SetMetrics(exporter)
+	defer func() {
+		domain.SetMetrics(nil)
+		if err := exporter.Close(); err != nil {
+			fmt.Printf("Warning: could not send metrics: %v\n", err)
+		}
+	}()
+
+	if whoisInterval == 0 && jobs[0].cfg.Scanner.Workers > 0 {
+		whoisInterval = time.Duration(jobs[0].cfg.Scanner.Delay) * time.Millisecond / time.Duration(jobs[0].cfg.Scanner.Workers)
+	}
+	if whoisInterval > 0 {
+		domain.SetWHOISInterval(whoisInterval)
+		defer domain.SetWHOISInterval(0)
+	}
+
+	fmt.Printf("Running %d batches with parallelism %d (per-server WHOIS interval: %v)\n", len(jobs), parallel, whoisInterval)
+
+	results := make([]batchResult, len(jobs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+	for i, job := range jobs {
+		select {
+		case <-ctx.Done():
+			results[i] = batchResult{name: job.cfg.Batch.Name, state: "skipped"}
+			continue
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int, job batchJob) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = runBatch(ctx, job, exporter)
+		}(i, job)
+	}
+	wg.Wait()
+
+	printAggregate(results)
+
+	// Like a single scan, a failed or interrupted batch aborts the run and check errors
+	// of completed batches are reported after it
+	code := scanner.ExitOK
+	for _, result := range results {
+		if result.err != nil || result.state != StateCompleted {
+			return scanner.ExitAborted
+		}
+		if result.summary != nil && result.summary.Errors > 0 {
+			code = scanner.ExitErrors
+		}
+	}
+	return code
+}
+
+// runBatch scans a single batch config and records its status
+func runBatch(ctx context.Context, job batchJob, exporter metrics.Exporter) batchResult {
+	name := job.cfg.Batch.Name
+	result := batchResult{name: name, state: StatePending}
+
+	status, release, err := Begin(job.path, job.cfg)
+	if err != nil {
+		if errors.Is(err, ErrLocked) {
+			result.state = "skipped"
+		}
+		result.err = err
+		fmt.Printf("[%s] Cannot start batch: %v\n", name, err)
+		return result
+	}
+	defer release()
+
+	opts := scanner.OptionsFromConfig(job.cfg)
+	opts.Prefix = fmt.Sprintf("[%s] ", name)
+	opts.Metrics = exporter
+	// A broken blocklist fails the batch rather than scanning names it should exclude
+	if opts.Blocklist, err = generator.BlocklistFromConfig(job.cfg); err != nil {
+		result.err = err
+		fmt.Printf("[%s] Batch failed: %v\n", name, err)
+		return result
+	}
+	opts.BlocklistMode = job.cfg.Domain.BlocklistMode
+	if opts.Prefilter, err = rawdns.FromConfig(job.cfg); err != nil {
+		result.err = err
+		fmt.Printf("[%s] Batch failed: %v\n", name, err)
+		return result
+	}
+	if job.cfg.Scoring.Enabled {
+		if opts.Scorer, err = scoring.FromConfig(job.cfg); err != nil {
+			fmt.Printf("[%s] Warning: scoring disabled: %v\n", name, err)
+		}
+	}
+	summary, err := scanner.Run(ctx, opts)
+	result.summary = summary
+	result.err = err
+	if err != nil {
+		fmt.Printf("[%s] Batch failed: %v\n", name, err)
+	}
+
+	if summary != nil {
+		if err := status.Finish(summary); err != nil {
+			fmt.Printf("[%s] Warning: could not write batch status: %v\n", name, err)
+		}
+	}
+	result.state = status.State
+	return result
+}
+
+// printAggregate prints a per-batch table and the totals of a batch run
+func printAggregate(results []batchResult) {
+	fmt.Printf("\nBatch run summary:\n")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BATCH\tSTATE\tPROCESSED\tAVAILABLE\tREGISTERED\tUNCERTAIN\tRATE LIMITED\tERRORS")
+
+	var total Counts
+	for _, result := range results {
+		var counts Counts
+		if result.summary != nil {
+			counts = countsFromSummary(result.summary)
+		}
+		state := result.state
+		if result.err != nil && state != "skipped" {
+			state = "failed"
+		}
+		total.Processed += counts.Processed
+		total.Available += counts.Available
+		total.Registered += counts.Registered
+		total.Uncertain += counts.Uncertain
+		total.RateLimited += counts.RateLimited
+		total.Errors += counts.Errors
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\n", result.name, state,
+			counts.Processed, counts.Available, counts.Registered, counts.Uncertain, counts.RateLimited, counts.Errors)
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t%d\t%d\t%d\t%d\t%d\t%d\n",
+		total.Processed, total.Available, total.Registered, total.Uncertain, total.RateLimited, total.Errors)
+	_ = tw.Flush()
+}
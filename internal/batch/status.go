@@ -0,0 +1,281 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"domain-scanner/internal/scanner"
+	"domain-scanner/internal/types"
+)
+
+// StatusFileName is the per-batch status file written inside each batch output directory
+const StatusFileName = "batch_status.json"
+
+// LockFileName is the advisory lock file held while a batch is running
+const LockFileName = "batch.lock"
+
+// Batch states
+const (
+	StatePending   = "pending"
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateAborted   = "aborted"
+)
+
+// ErrLocked is returned when another process already holds the batch lock
+var ErrLocked = errors.New("batch is locked by another process")
+
+// Counts holds the result counts of a batch run
+type Counts struct {
+	Processed  int `json:"processed"`
+	Available  int `json:"available"`
+	Registered int `json:"registered"`
+	// Special counts the special status entries; Uncertain and RateLimited split the
+	// processed domains needing review like the scan summary does
+	Special     int `json:"special"`
+	Uncertain   int `json:"uncertain"`
+	RateLimited int `json:"rate_limited"`
+	Errors      int `json:"errors"`
+	// Skipped counts the checks cut short by an interruption
+	Skipped int `json:"skipped"`
+}
+
+// Status represents the persisted state of a single batch
+type Status struct {
+	Name       string     `json:"name"`
+	Config     string     `json:"config"`
+	OutputDir  string     `json:"output_dir"`
+	State      string     `json:"state"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	PID        int        `json:"pid,omitempty"`
+	Counts     Counts     `json:"counts"`
+	// AvailableFile is the available domains file written by the last finished run
+	AvailableFile string `json:"available_file,omitempty"`
+	// PricesFile is the available domain prices file written by the last finished run
+	PricesFile string `json:"prices_file,omitempty"`
+	// ScoresFile is the available domain scores file written by the last finished run
+	ScoresFile string `json:"scores_file,omitempty"`
+	// TLDStats holds the per-suffix counts of the last finished run
+	TLDStats map[string]*scanner.TLDStat `json:"tld_stats,omitempty"`
+	// ExitCode and ExitStatus are the process exit code of the last finished run and its meaning
+	ExitCode   int    `json:"exit_code"`
+	ExitStatus string `json:"exit_status,omitempty"`
+
+	// dir is the directory the status file was read from
+	dir string
+}
+
+// NewPendingStatus creates a pending status for a freshly generated batch
+func NewPendingStatus(name, configPath, outputDir string) *Status {
+	now := time.Now().UTC()
+	return &Status{
+		Name:      name,
+		Config:    configPath,
+		OutputDir: outputDir,
+		State:     StatePending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// ReadStatus loads the status file from a batch output directory
+func ReadStatus(outputDir string) (*Status, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, StatusFileName))
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{}
+	if err := json.Unmarshal(data, status); err != nil {
+		return nil, fmt.Errorf("invalid status file in %s: %w", outputDir, err)
+	}
+	status.dir = outputDir
+	return status, nil
+}
+
+// WriteStatus atomically writes the status file into its batch output directory
+func WriteStatus(status *Status) error {
+	status.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(status.OutputDir, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temp file first so readers never see a partial file
+	path := filepath.Join(status.OutputDir, StatusFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// MarkRunning transitions the status to running
+func (s *Status) MarkRunning() error {
+	now := time.Now().UTC()
+	s.State = StateRunning
+	s.StartedAt = &now
+	s.FinishedAt = nil
+	s.PID = os.Getpid()
+	s.Counts = Counts{}
+	s.TLDStats = nil
+	s.AvailableFile = ""
+	s.PricesFile = ""
+	s.ScoresFile = ""
+	s.ExitCode, s.ExitStatus = 0, ""
+	return WriteStatus(s)
+}
+
+// MarkFinished transitions the status to completed or aborted with the final counts
+func (s *Status) MarkFinished(state string, counts Counts) error {
+	now := time.Now().UTC()
+	s.State = state
+	s.FinishedAt = &now
+	s.PID = 0
+	s.Counts = counts
+	return WriteStatus(s)
+}
+
+// Finish records the outcome of a scan run, marking interrupted runs as aborted
+func (s *Status) Finish(summary *scanner.Summary) error {
+	state := StateCompleted
+	if summary.Interrupted {
+		state = StateAborted
+	}
+	s.TLDStats = summary.TLDStats
+	s.AvailableFile = summary.AvailableFile
+	s.PricesFile = summary.PricesFile
+	s.ScoresFile = summary.ScoresFile
+	s.ExitCode = summary.ExitCode()
+	s.ExitStatus = scanner.ExitMeaning(s.ExitCode)
+	return s.MarkFinished(state, countsFromSummary(summary))
+}
+
+// Abort records a run that failed before it produced a summary
+func (s *Status) Abort() error {
+	s.ExitCode = scanner.ExitUsage
+	s.ExitStatus = scanner.ExitMeaning(s.ExitCode)
+	return s.MarkFinished(StateAborted, Counts{})
+}
+
+// countsFromSummary extracts the persisted counts from a scan summary
+func countsFromSummary(summary *scanner.Summary) Counts {
+	return Counts{
+		Processed:   summary.Processed,
+		Available:   len(summary.Available),
+		Registered:  summary.RegisteredCount,
+		Special:     len(summary.Special),
+		Uncertain:   summary.Uncertain,
+		RateLimited: summary.RateLimited,
+		Errors:      summary.Errors,
+		Skipped:     summary.Skipped,
+	}
+}
+
+// Begin locks the batch output directory of a batch config and marks the batch
+// as running. The returned function releases the lock.
+func Begin(configPath string, cfg *types.Config) (*Status, func(), error) {
+	outputDir := cfg.Output.OutputDir
+	release, err := AcquireLock(outputDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, err := ReadStatus(outputDir)
+	if err != nil {
+		status = NewPendingStatus(cfg.Batch.Name, configPath, outputDir)
+	}
+	status.Config = configPath
+	if err := status.MarkRunning(); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return status, release, nil
+}
+
+// AcquireLock takes the advisory lock for a batch output directory.
+// The returned function releases the lock.
+func AcquireLock(outputDir string) (func(), error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(outputDir, LockFileName)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, _ = file.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			_ = file.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		// Remove stale locks left behind by processes that no longer exist
+		if !staleLock(path) {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+		}
+		_ = os.Remove(path)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+}
+
+// staleLock reports whether the lock file belongs to a process that is no longer running
+func staleLock(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(string(trimNewline(data)))
+	if err != nil || pid <= 0 {
+		return true
+	}
+	return !processAlive(pid)
+}
+
+// trimNewline strips trailing line breaks from file contents
+func trimNewline(data []byte) []byte {
+	for len(data) > 0 && (data[len(data)-1] == '\n' || data[len(data)-1] == '\r') {
+		data = data[:len(data)-1]
+	}
+	return data
+}
+
+// ListStatuses finds all batch status files below a results directory
+func ListStatuses(dir string) ([]*Status, error) {
+	var statuses []*Status
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != StatusFileName {
+			return nil
+		}
+		status, err := ReadStatus(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, status)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses, nil
+}
@@ -0,0 +1,50 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"domain-scanner/internal/scanner"
+	"domain-scanner/internal/types"
+)
+
+func TestBeginAndAbort(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &types.Config{}
+	cfg.Output.OutputDir = dir
+	cfg.Batch.Name = "b1"
+
+	completed := NewPendingStatus("b1", "b1.toml", dir)
+	if err := completed.MarkFinished(StateCompleted, Counts{Processed: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, release, err := Begin("b1.toml", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != StateRunning || status.PID != os.Getpid() || status.Counts.Processed != 0 {
+		t.Errorf("Begin() = %s, PID %d, %d processed; want running with this PID and no counts",
+			status.State, status.PID, status.Counts.Processed)
+	}
+	if _, _, err := Begin("b1.toml", cfg); err == nil {
+		t.Errorf("a second Begin() took the held lock")
+	}
+
+	if err := status.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	release()
+	read, err := ReadStatus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.State != StateAborted || read.PID != 0 || read.FinishedAt == nil || read.ExitCode != scanner.ExitUsage {
+		t.Errorf("after Abort() the batch is %s, PID %d, finished %v, exit code %d; want aborted",
+			read.State, read.PID, read.FinishedAt, read.ExitCode)
+	}
+	if _, err := os.Stat(filepath.Join(dir, LockFileName)); !os.IsNotExist(err) {
+		t.Errorf("the lock was not released: %v", err)
+	}
+}
@@ -1,6 +1,12 @@
 package config
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"domain-scanner/internal/generator"
+	"domain-scanner/internal/notify"
 	"domain-scanner/internal/types"
 	"github.com/BurntSushi/toml"
 )
@@ -11,7 +17,15 @@ func LoadConfig(configPath string) (*types.Config, error) {
 	if _, err := toml.DecodeFile(configPath, config); err != nil {
 		return nil, err
 	}
-	
+	if err := ApplyDefaults(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ApplyDefaults fills unset configuration values with their defaults and validates
+// the policies, so configs built in code behave like configs loaded from a file
+func ApplyDefaults(config *types.Config) error {
 	// Set default values if not specified in config
 	if config.Domain.Length == 0 {
 		config.Domain.Length = 3
@@ -20,11 +34,21 @@ func LoadConfig(configPath string) (*types.Config, error) {
 	if config.Domain.Suffix == "" {
 		config.Domain.Suffix = ".li"
 	}
+	// Expiring lists take several suffixes; generated scans take exactly one
+	suffixes, err := generator.NormalizeSuffixes(config.Domain.Suffix)
+	if err != nil {
+		return err
+	}
+	config.Domain.Suffix = strings.Join(suffixes, ",")
 	
 	if config.Domain.Pattern == "" {
 		config.Domain.Pattern = "D"
 	}
 	
+	if config.Domain.BlocklistMode == "" {
+		config.Domain.BlocklistMode = types.BlocklistDrop
+	}
+	
 	if config.Scanner.Delay == 0 {
 		config.Scanner.Delay = 1000
 	}
@@ -33,15 +57,87 @@ func LoadConfig(configPath string) (*types.Config, error) {
 		config.Scanner.Workers = 10
 	}
 	
+	if config.Scanner.TerseThreshold == 0 {
+		config.Scanner.TerseThreshold = 64
+	}
+	
+	if config.Scanner.WHOISConflict == "" {
+		config.Scanner.WHOISConflict = types.ConflictAvailableWins
+	}
+	
+	if config.Output.OnConflict == "" {
+		config.Output.OnConflict = types.OutputConflictOverwrite
+	}
+	
+	if len(config.Scanner.RawDNS.Resolvers) == 0 {
+		config.Scanner.RawDNS.Resolvers = []string{"1.1.1.1", "8.8.8.8", "9.9.9.9"}
+	}
+	
+	if config.Scanner.RawDNS.Concurrency == 0 {
+		config.Scanner.RawDNS.Concurrency = 1000
+	}
+	
+	if config.Scanner.RawDNS.Timeout == 0 {
+		config.Scanner.RawDNS.Timeout = 2000
+	}
+	
+	if config.Scanner.RawDNS.Retries == 0 {
+		config.Scanner.RawDNS.Retries = 2
+	}
+	
+	if config.Scanner.RateLimitRetryDelay == 0 {
+		config.Scanner.RateLimitRetryDelay = 10000
+	}
+	
+	if config.Scanner.RateLimitRetryWorkers == 0 {
+		config.Scanner.RateLimitRetryWorkers = 1
+	}
+	
+	// Set default values for the shared HTTP client
+	http := &config.Scanner.HTTP
+	if http.DialTimeout == 0 {
+		http.DialTimeout = 5000
+	}
+	if http.TLSHandshakeTimeout == 0 {
+		http.TLSHandshakeTimeout = 5000
+	}
+	if http.ResponseHeaderTimeout == 0 {
+		http.ResponseHeaderTimeout = 10000
+	}
+	if http.RequestTimeout == 0 {
+		http.RequestTimeout = 15000
+	}
+	if http.KeepAlive == 0 {
+		http.KeepAlive = 30000
+	}
+	if http.IdleConnTimeout == 0 {
+		http.IdleConnTimeout = 90000
+	}
+	if http.MaxIdleConns == 0 {
+		http.MaxIdleConns = 100
+	}
+	if http.MaxIdleConnsPerHost == 0 {
+		http.MaxIdleConnsPerHost = 10
+	}
+	
 	// Set default values for scanner methods
 	if !config.Scanner.Methods.DNSCheck && !config.Scanner.Methods.WHOISCheck && 
-	   !config.Scanner.Methods.SSLCheck && !config.Scanner.Methods.HTTPCheck {
+	   !config.Scanner.Methods.SSLCheck && !config.Scanner.Methods.HTTPCheck && !config.Scanner.Methods.RDAPCheck {
 		config.Scanner.Methods.DNSCheck = true
 		config.Scanner.Methods.WHOISCheck = true
 		config.Scanner.Methods.SSLCheck = true
 		config.Scanner.Methods.HTTPCheck = false // Disabled by default
 	}
 	
+	if config.Scanner.Methods.Custom.Command != "" {
+		if config.Scanner.Methods.Custom.Name == "" {
+			config.Scanner.Methods.Custom.Name = "custom"
+		}
+		if config.Scanner.Methods.Custom.Timeout == 0 {
+			config.Scanner.Methods.Custom.Timeout = 10000
+		}
+	}
+	
 	if config.Output.AvailableFile == "" {
 		config.Output.AvailableFile = "available_domains_{pattern}_{length}_{suffix}.txt"
 	}
@@ -54,9 +150,218 @@ func LoadConfig(configPath string) (*types.Config, error) {
 		config.Output.SpecialStatusFile = "special_status_domains_{pattern}_{length}_{suffix}.txt"
 	}
 	
+	if config.Output.PricesFile == "" {
+		config.Output.PricesFile = "available_prices_{pattern}_{length}_{suffix}.txt"
+	}
+	
+	if config.Output.ScoresFile == "" {
+		config.Output.ScoresFile = "available_scores_{pattern}_{length}_{suffix}.txt"
+	}
+	
+	if config.Output.TLDTableFile == "" {
+		config.Output.TLDTableFile = "tld_availability_{pattern}_{length}_{suffix}.txt"
+	}
+	
+	if config.Output.GSheets.Sheet == "" {
+		config.Output.GSheets.Sheet = "Sheet1"
+	}
+	
+	if config.Output.GSheets.MaxRetries == 0 {
+		config.Output.GSheets.MaxRetries = 5
+	}
+	
 	if config.Output.OutputDir == "" {
 		config.Output.OutputDir = "."
 	}
 	
-	return config, nil
+	if config.Pricing.Provider == types.PricingPorkbun {
+		if config.Pricing.APIKeyEnv == "" {
+			config.Pricing.APIKeyEnv = "PORKBUN_API_KEY"
+		}
+		if config.Pricing.SecretAPIKeyEnv == "" {
+			config.Pricing.SecretAPIKeyEnv = "PORKBUN_SECRET_API_KEY"
+		}
+		if config.Pricing.Interval == 0 {
+			config.Pricing.Interval = 10000
+		}
+	}
+	
+	if config.CT.Interval == 0 {
+		config.CT.Interval = 1000
+	}
+	
+	if config.CT.Timeout == 0 {
+		config.CT.Timeout = 10000
+	}
+	
+	if config.Metrics.Prefix == "" {
+		config.Metrics.Prefix = "domain_scanner"
+	}
+	
+	if config.Metrics.StatsD.Host == "" {
+		config.Metrics.StatsD.Host = "127.0.0.1"
+	}
+	
+	if config.Metrics.StatsD.Port == 0 {
+		config.Metrics.StatsD.Port = 8125
+	}
+	
+	if config.Metrics.OTLP.Endpoint == "" {
+		config.Metrics.OTLP.Endpoint = "http://localhost:4318/v1/metrics"
+	}
+	
+	if config.Metrics.OTLP.Interval == 0 {
+		config.Metrics.OTLP.Interval = 10000
+	}
+	
+	if config.Notify.Webhook.MaxRetries == 0 {
+		config.Notify.Webhook.MaxRetries = 3
+	}
+
+	if config.Notify.Webhook.Timeout == 0 {
+		config.Notify.Webhook.Timeout = 10000
+	}
+
+	if config.Metrics.Prometheus.Addr == "" {
+		config.Metrics.Prometheus.Addr = ":9090"
+	}
+	
+	if config.Feed.FeedFile == "" {
+		config.Feed.FeedFile = "dropped_domains.txt"
+	}
+	
+	if config.Feed.PollInterval == 0 {
+		config.Feed.PollInterval = 600
+	}
+	
+	if config.Server.Listen == "" {
+		config.Server.Listen = ":8080"
+	}
+	
+	if config.Server.MaxScans == 0 {
+		config.Server.MaxScans = 2
+	}
+	
+	if config.Server.MaxWorkers == 0 {
+		config.Server.MaxWorkers = 100
+	}
+	
+	if config.Server.ScanRetention == 0 {
+		config.Server.ScanRetention = 86400
+	}
+	
+	if config.Server.MaxFinishedScans == 0 {
+		config.Server.MaxFinishedScans = 100
+	}
+	
+	for tld, policy := range config.Scanner.WildcardDNS {
+		if policy != types.WildcardAIgnore && policy != types.WildcardACombined {
+			return fmt.Errorf("invalid wildcard_dns policy %q for %s (use %q or %q)",
+				policy, tld, types.WildcardAIgnore, types.WildcardACombined)
+		}
+	}
+	
+	switch config.Domain.BlocklistMode {
+	case types.BlocklistDrop, types.BlocklistFlag:
+	default:
+		return fmt.Errorf("invalid blocklist_mode %q (use %q or %q)", config.Domain.BlocklistMode,
+			types.BlocklistDrop, types.BlocklistFlag)
+	}
+	
+	switch config.Scanner.WHOISConflict {
+	case types.ConflictAvailableWins, types.ConflictRegisteredWins, types.ConflictUncertain:
+	default:
+		return fmt.Errorf("invalid whois_conflict policy %q (use %q, %q or %q)", config.Scanner.WHOISConflict,
+			types.ConflictAvailableWins, types.ConflictRegisteredWins, types.ConflictUncertain)
+	}
+	
+	seen := make(map[string]bool)
+	for i, method := range config.Scanner.CheckOrder {
+		method = strings.ToLower(strings.TrimSpace(method))
+		switch method {
+		case types.CheckDNS, types.CheckRDAP, types.CheckWHOIS, types.CheckSSL, types.CheckHTTP:
+		default:
+			return fmt.Errorf("invalid check_order method %q (use %q, %q, %q, %q or %q)", config.Scanner.CheckOrder[i],
+				types.CheckDNS, types.CheckRDAP, types.CheckWHOIS, types.CheckSSL, types.CheckHTTP)
+		}
+		if seen[method] {
+			return fmt.Errorf("invalid check_order: %q is listed twice", method)
+		}
+		seen[method] = true
+		config.Scanner.CheckOrder[i] = method
+	}
+	
+	switch config.Output.OnConflict {
+	case "", types.OutputConflictError, types.OutputConflictRename, types.OutputConflictAppend, types.OutputConflictOverwrite:
+	default:
+		return fmt.Errorf("invalid on_conflict policy %q (use %q, %q, %q or %q)", config.Output.OnConflict,
+			types.OutputConflictError, types.OutputConflictRename, types.OutputConflictAppend, types.OutputConflictOverwrite)
+	}
+	
+	switch config.Scanner.Prefilter {
+	case "", types.PrefilterRawDNS:
+	default:
+		return fmt.Errorf("invalid prefilter %q (use %q)", config.Scanner.Prefilter, types.PrefilterRawDNS)
+	}
+	
+	if _, err := generator.NewLeetTable(config.Domain.LeetTable); err != nil {
+		return err
+	}
+	if config.Domain.MaxNumRange < 0 {
+		return fmt.Errorf("invalid max_num_range %d (use a positive number or 0 for the default)", config.Domain.MaxNumRange)
+	}
+	if config.Domain.NumRange != "" {
+		if _, err := generator.ParseNumRange(config.Domain.NumRange, config.Domain.NumPad, config.Domain.MaxNumRange); err != nil {
+			return err
+		}
+	}
+
+	for suffix, perMinute := range config.Scanner.RateLimits {
+		if strings.Trim(strings.TrimSpace(suffix), ".") == "" || perMinute <= 0 {
+			return fmt.Errorf("invalid rate_limits entry %q = %d (use a suffix and a positive number of queries per minute)", suffix, perMinute)
+		}
+	}
+
+	if doh := config.Scanner.DNS.DoHURL; doh != "" {
+		if u, err := url.Parse(doh); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid doh_url %q (use an http or https URL such as https://cloudflare-dns.com/dns-query)", doh)
+		}
+	}
+
+	if webhook := config.Notify.Webhook.URL; webhook != "" {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid webhook url %q (use an http or https URL)", webhook)
+		}
+	}
+	if _, err := notify.FromConfig(config, nil); err != nil {
+		return err
+	}
+
+	switch config.Metrics.Exporter {
+	case "", types.MetricsStatsD, types.MetricsOTLP, types.MetricsPrometheus:
+	default:
+		return fmt.Errorf("invalid metrics exporter %q (use %q, %q or %q)", config.Metrics.Exporter,
+			types.MetricsStatsD, types.MetricsOTLP, types.MetricsPrometheus)
+	}
+	
+	weights := config.Scoring.Weights
+	for _, weight := range []float64{weights.Length, weights.Pronounceability, weights.Dictionary,
+		weights.Clean, weights.Repetition, weights.TLD} {
+		if weight < 0 {
+			return fmt.Errorf("invalid scoring weight %v (weights must not be negative)", weight)
+		}
+	}
+	for suffix, weight := range config.Scoring.TLDWeights {
+		if weight < 0 || weight > 1 {
+			return fmt.Errorf("invalid scoring tld_weights value %v for %s (use 0 to 1)", weight, suffix)
+		}
+	}
+	
+	switch config.Pricing.Provider {
+	case "", types.PricingPorkbun:
+	default:
+		return fmt.Errorf("invalid pricing provider %q (use %q)", config.Pricing.Provider, types.PricingPorkbun)
+	}
+	
+	return nil
 }
@@ -0,0 +1,175 @@
+// Package ctlog looks up domains in Certificate Transparency logs through a CT search
+// API such as crt.sh. A certificate issued for a name shows that it is or recently was
+// registered, which makes it a cheap pre-check before DNS and WHOIS.
+package ctlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/types"
+)
+
+// Signature marks results with certificates in the CT logs
+const Signature = "CT_LOG"
+
+// DefaultEndpoint is the crt.sh JSON search; {domain} is replaced by the queried name
+const DefaultEndpoint = "https://crt.sh/?q={domain}&output=json"
+
+// maxResponse bounds the response read for one name; popular names list thousands
+// of certificates, and any single entry already answers the query
+const maxResponse = 4 << 20
+
+// Client queries a CT search endpoint, spacing the queries and caching the answers
+type Client struct {
+	endpoint string
+	interval time.Duration
+	timeout  time.Duration
+	// client sends the queries; nil uses the shared domain.HTTPClient
+	client *http.Client
+
+	// next is the earliest time of the next query
+	mu   sync.Mutex
+	next time.Time
+
+	cacheMu sync.Mutex
+	cache   map[string]bool
+}
+
+// entry is a certificate of the search response; name_value holds the names of the
+// certificate separated by newlines
+type entry struct {
+	CommonName string `json:"common_name"`
+	NameValue  string `json:"name_value"`
+}
+
+// New creates a client for an endpoint URL containing {domain}. intervalMillis spaces
+// the queries of all workers and timeoutMillis limits a single query.
+func New(endpoint string, intervalMillis, timeoutMillis int) *Client {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return &Client{
+		endpoint: endpoint,
+		interval: time.Duration(intervalMillis) * time.Millisecond,
+		timeout:  time.Duration(timeoutMillis) * time.Millisecond,
+		cache:    make(map[string]bool),
+	}
+}
+
+// FromConfig creates the client described by the [ct] section, querying through
+// client; a nil client uses the shared domain.HTTPClient
+func FromConfig(cfg *types.Config, client *http.Client) *Client {
+	c := New("", 0, 0)
+	if cfg != nil {
+		c = New(cfg.CT.Endpoint, cfg.CT.Interval, cfg.CT.Timeout)
+	}
+	c.client = client
+	return c
+}
+
+// Issued reports whether a certificate was ever logged for the domain or one of its
+// subdomains. Errors mean the answer is unknown; they are not cached.
+func (c *Client) Issued(ctx context.Context, name string) (bool, error) {
+	name = strings.ToLower(name)
+	c.cacheMu.Lock()
+	issued, ok := c.cache[name]
+	c.cacheMu.Unlock()
+	if ok {
+		return issued, nil
+	}
+
+	if err := c.wait(ctx); err != nil {
+		return false, err
+	}
+	issued, err := c.query(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	c.cacheMu.Lock()
+	c.cache[name] = issued
+	c.cacheMu.Unlock()
+	return issued, nil
+}
+
+// wait blocks until the next query is allowed
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	now := time.Now()
+	slot := c.next
+	if slot.Before(now) {
+		slot = now
+	}
+	c.next = slot.Add(c.interval)
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(slot)):
+		return nil
+	}
+}
+
+// query searches the endpoint for certificates of a name
+func (c *Client) query(ctx context.Context, name string) (bool, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	endpoint := strings.ReplaceAll(c.endpoint, "{domain}", url.QueryEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	client := c.client
+	if client == nil {
+		client = domain.HTTPClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("CT search returned %s", resp.Status)
+	}
+
+	// An entry is decoded at a time so that a truncated response still answers
+	decoder := json.NewDecoder(io.LimitReader(resp.Body, maxResponse))
+	if _, err := decoder.Token(); err != nil {
+		return false, fmt.Errorf("error decoding CT search response: %w", err)
+	}
+	for decoder.More() {
+		var e entry
+		if err := decoder.Decode(&e); err != nil {
+			return false, fmt.Errorf("error decoding CT search response: %w", err)
+		}
+		if covers(e.CommonName, name) {
+			return true, nil
+		}
+		for _, certName := range strings.Split(e.NameValue, "\n") {
+			if covers(certName, name) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// covers reports whether a certificate name is the domain, a wildcard for it or one
+// of its subdomains; search endpoints may also return unrelated partial matches
+func covers(certName, name string) bool {
+	certName = strings.ToLower(strings.TrimSpace(certName))
+	return certName == name || strings.HasSuffix(certName, "."+name)
+}
@@ -1,210 +1,216 @@
 package domain
 
 import (
+	"context"
 	"crypto/tls"
-	"fmt"
 	"net"
+	"net/http"
 	"strings"
-	"sync"
 	"time"
 
 	"domain-scanner/internal/types"
-	"github.com/likexian/whois"
 )
 
-var (
-	// Pre-initialized maps for O(1) lookup
-	availableIndicatorsMap   map[string]bool
-	unavailableIndicatorsMap map[string]bool
-	indicatorsOnce           sync.Once
-
-	// Global config reference
-	globalConfig *types.Config
-
-	// Special status tracking
-	specialStatusDomains []types.SpecialStatusDomain
-	specialStatusMutex   sync.Mutex
-
-	// WHOIS indicators for domain status detection
-	registeredIndicators = []string{
-		"registrar:",
-		"registrant:",
-		"creation date:",
-		"created:",
-		"updated date:",
-		"updated:",
-		"expiration date:",
-		"expires:",
-		"name server:",
-		"nserver:",
-		"nameserver:",
-		"status: active",
-		"status: client",
-		"status: ok",
-		"status: locked",
-		"status: connect",  // Connect status indicates registered domain
-		"status:connect",   // Version without space
-		"domain name:",
-		"domain:",
-		"nsentry:",         // DENIC specific field
-		"changed:",         // DENIC specific field
-	}
-
-	reservedIndicators = []string{
-		"status: reserved",
-		"status: restricted",
-		"status: blocked",
-		"status: prohibited",
-		"status: reserved for registry",
-		"status: reserved for registrar",
-		"status: reserved for registry operator",
-		"status: reserved for future use",
-		"status: not available for registration",
-		"status: not available for general registration",
-		"status: reserved for special purposes",
-		"status: reserved for government use",
-		"status: reserved for educational institutions",
-		"status: reserved for non-profit organizations",
-		"domain reserved",
-		"this domain is reserved",
-		"reserved domain",
-	}
-
-	// WHOIS indicators for domain availability detection
-	availableIndicators = []string{
-		"no match for", "not found", "no data found", "no entries found",
-		"domain not found", "no object found", "no matching record",
-		"status: free", "status: available", "available for registration",
-		"this domain is available", "domain is available", "domain available",
-	}
-
-	unavailableIndicators = []string{
-		"registrar:", "registrant:", "creation date:", "updated date:",
-		"expiration date:", "name server:", "nserver:", "status: registered",
-		"status: active", "status: ok", "status: connect", "status:connect",
-		"domain name:", "domain:", "nsentry:", "changed:",
-	}
-)
+// RateLimitedStatus marks domains whose WHOIS lookups stayed rate limited after all retries
+const RateLimitedStatus = "WHOIS_RATE_LIMITED"
+
+// NoEvidenceStatus marks domains that strict mode did not report available because
+// nothing explicitly said they were; the lax default reports them available
+const NoEvidenceStatus = "NO_AVAILABILITY_EVIDENCE"
 
-// SetConfig sets the global configuration for the domain checker
+// globalConfig is the config of the checker behind the package-level functions
+var globalConfig *types.Config
+
+// SetConfig sets the configuration of the checker behind the package-level functions
 func SetConfig(config *types.Config) {
 	globalConfig = config
+	resetHTTPClient()
+	resetDefaultChecker()
 }
 
-// initIndicatorMaps initializes the indicator maps for fast lookup
-func initIndicatorMaps() {
-	indicatorsOnce.Do(func() {
-		// Initialize available indicators map
-		availableIndicatorsMap = make(map[string]bool, len(availableIndicators))
-		for _, indicator := range availableIndicators {
-			availableIndicatorsMap[indicator] = true
-		}
+// CheckDomainSignatures checks various signatures to determine domain status
+func CheckDomainSignatures(domain string) ([]string, error) {
+	return CheckDomainSignaturesContext(context.Background(), domain)
+}
 
-		// Initialize unavailable indicators map
-		unavailableIndicatorsMap = make(map[string]bool, len(unavailableIndicators))
-		for _, indicator := range unavailableIndicators {
-			unavailableIndicatorsMap[indicator] = true
-		}
-	})
+// CheckDomainSignaturesContext is like CheckDomainSignatures but aborts WHOIS
+// retries and backoff waits as soon as ctx is cancelled
+func CheckDomainSignaturesContext(ctx context.Context, domain string) ([]string, error) {
+	pass, err := defaultChecker().checkSignatures(ctx, domain)
+	return pass.signatures, err
 }
 
-// CheckDomainSignatures checks various signatures to determine domain status
-func CheckDomainSignatures(domain string) ([]string, error) {
-	var signatures []string
+// checkPass is what checkSignatures found out about a domain
+type checkPass struct {
+	signatures []string
+	// lookup is the WHOIS lookup of the pass, not done when WHOIS checks are disabled or skipped
+	lookup whoisLookup
+	// rdap is the RDAP lookup of the pass; its status stays StatusUnknown when RDAP did not run
+	rdap rdapLookup
+	// skipped is the number of methods left out because the signatures decided the domain
+	skipped int
+	// phases is the time spent in each method, WHOIS retries and backoff included
+	phases map[string]time.Duration
+}
 
-	// 1. Check DNS records (if enabled)
-	if globalConfig == nil || globalConfig.Scanner.Methods.DNSCheck {
-		dnsSignatures, err := checkDNSRecords(domain)
-		if err == nil {
-			signatures = append(signatures, dnsSignatures...)
+// checkSignatures collects the registration signatures of a domain together with
+// the WHOIS lookup it made. The methods run in the checker's order; unless the
+// checker is exhaustive, the pass skips the remaining methods once the signatures
+// decide the domain, all but WHOIS when the checker always queries WHOIS.
+func (c *Checker) checkSignatures(ctx context.Context, domain string) (checkPass, error) {
+	pass := checkPass{phases: make(map[string]time.Duration)}
+	whoisStatus := StatusUnknown
+	decided := false
+
+	for _, method := range c.order {
+		if decided && (method != types.CheckWHOIS || !c.opts.WHOISAlways) {
+			pass.skipped++
+			continue
 		}
-	}
+		started := time.Now()
+		switch method {
+		case types.CheckDNS:
+			dnsSignatures, err := c.checkDNSRecords(ctx, domain)
+			c.observe("dns", started)
+			if err == nil {
+				pass.signatures = append(pass.signatures, dnsSignatures...)
+			}
 
-	// 2. Check WHOIS information with retry (if enabled)
-	if globalConfig == nil || globalConfig.Scanner.Methods.WHOISCheck {
-		var whoisResult string
-		maxRetries := 3
-		baseDelay := 2 * time.Second // Increased base delay
+		case types.CheckRDAP:
+			pass.rdap = c.lookupRDAP(ctx, domain)
+			c.observe("rdap", started)
+			if ctx.Err() != nil {
+				pass.phases[method] += time.Since(started)
+				return pass, ctx.Err()
+			}
+			if pass.rdap.err != nil {
+				c.debugf(domain, "RDAP lookup failed: %v", pass.rdap.err)
+			}
+			if pass.rdap.status == StatusRegistered {
+				pass.signatures = append(pass.signatures, "RDAP")
+			}
+			// An RDAP 404 settles the domain like an explicit WHOIS answer
+			if pass.rdap.status == StatusAvailable && whoisStatus == StatusUnknown {
+				whoisStatus = StatusAvailable
+			}
 
-		for i := 0; i < maxRetries; i++ {
-			// Add a small delay before each WHOIS query to avoid rate limiting
-			if i > 0 {
-				waitTime := baseDelay * time.Duration(i+1) // Exponential backoff
-				time.Sleep(waitTime)
+		case types.CheckWHOIS:
+			pass.lookup = c.lookupWHOIS(ctx, domain)
+			if ctx.Err() != nil {
+				pass.phases[method] += time.Since(started)
+				return pass, ctx.Err()
+			}
+			if pass.lookup.err == nil {
+				whoisStatus, _ = c.classifyWHOISResponse(domain, pass.lookup.raw)
+				switch whoisStatus {
+				case StatusRegistered:
+					pass.signatures = append(pass.signatures, "WHOIS")
+				case StatusReserved:
+					pass.signatures = append(pass.signatures, "RESERVED")
+				}
 			}
 
-			result, err := whois.Whois(domain)
-			if err == nil {
-				whoisResult = result
-				break
+		case types.CheckSSL:
+			if c.checkSSL(ctx, domain) {
+				pass.signatures = append(pass.signatures, "SSL")
 			}
 
-			// Check if this is a rate limit error
-			if strings.Contains(err.Error(), "connection refused") ||
-			   strings.Contains(err.Error(), "access control") ||
-			   strings.Contains(err.Error(), "limit exceeded") ||
-			   strings.Contains(err.Error(), "rate limit") {
-				// For rate limit errors, wait longer before retry
-				if i < maxRetries-1 {
-					waitTime := baseDelay * time.Duration((i+1)*3) // Longer wait for rate limits
-					time.Sleep(waitTime)
-				}
+		case types.CheckHTTP:
+			if c.checkHTTP(ctx, domain) {
+				pass.signatures = append(pass.signatures, "HTTP")
 			}
 		}
+		pass.phases[method] += time.Since(started)
 
-		if whoisResult != "" {
-			// Convert WHOIS response to lowercase for case-insensitive matching
-			result := strings.ToLower(whoisResult)
+		if !c.opts.Exhaustive && !decided {
+			decided = c.decided(domain, pass.signatures, whoisStatus)
+		}
+	}
+	if decided {
+		pass.skipped += len(c.customMethods())
+		return pass, nil
+	}
 
-			// First check for available indicators (these take precedence)
-			isAvailable := false
-			for _, indicator := range availableIndicators {
-				if strings.Contains(result, indicator) {
-					isAvailable = true
-					break
-				}
-			}
+	// Run custom check methods (registered in code or configured as a command)
+	if started := time.Now(); len(c.customMethods()) > 0 {
+		pass.signatures = append(pass.signatures, c.checkCustom(ctx, domain)...)
+		pass.phases[types.PhaseCustom] += time.Since(started)
+	}
 
-			// Only check for registration if not explicitly available
-			if !isAvailable {
-				// Enhanced registration status detection
-				for _, indicator := range registeredIndicators {
-					if strings.Contains(result, indicator) {
-						signatures = append(signatures, "WHOIS")
-						break
-					}
-				}
+	return pass, nil
+}
 
-				// Check for reserved domain indicators
-				for _, indicator := range reservedIndicators {
-					if strings.Contains(result, indicator) {
-						signatures = append(signatures, "RESERVED")
-						break
-					}
-				}
-			}
-		}
+// checkSSL reports whether the domain presents a TLS certificate on port 443; cancelling
+// ctx aborts the dial and the handshake
+func (c *Checker) checkSSL(ctx context.Context, domain string) bool {
+	started := time.Now()
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: c.opts.SSLTimeout},
+		Config:    &tls.Config{InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", domain+":443")
+	c.observe("ssl", started)
+	if err != nil {
+		return false
 	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	return len(conn.(*tls.Conn).ConnectionState().PeerCertificates) > 0
+}
 
-	// 3. Check SSL certificate with timeout (if enabled)
-	if globalConfig == nil || globalConfig.Scanner.Methods.SSLCheck {
-		conn, err := tls.DialWithDialer(&net.Dialer{
-			Timeout: 5 * time.Second,
-		}, "tcp", domain+":443", &tls.Config{
-			InsecureSkipVerify: true,
-		})
-		if err == nil {
-			defer func() {
-				_ = conn.Close()
-			}()
-			state := conn.ConnectionState()
-			if len(state.PeerCertificates) > 0 {
-				signatures = append(signatures, "SSL")
+// maxHTTPRedirects is the number of redirects the HTTP check follows
+const maxHTTPRedirects = 3
+
+// checkHTTP reports whether a web server answers for the domain, over HTTPS or else
+// plain HTTP. Any status code counts; connection failures and unresolvable names do not.
+func (c *Checker) checkHTTP(ctx context.Context, domain string) bool {
+	started := time.Now()
+	defer c.observe("http", started)
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.HTTPTimeout)
+	defer cancel()
+	client := &http.Client{
+		Transport: c.httpClient().Transport,
+		// The response that would lead to a further redirect still shows a server
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxHTTPRedirects {
+				return http.ErrUseLastResponse
 			}
+			return nil
+		},
+	}
+	for _, scheme := range []string{"https", "http"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+domain+"/", nil)
+		if err != nil {
+			return false
+		}
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
 		}
 	}
+	return false
+}
 
-	return signatures, nil
+// decided reports whether the signatures collected so far settle the domain: any
+// registration signature or reservation makes it unavailable whatever the remaining
+// methods find, and so does an explicit "available" WHOIS answer by the decision rules
+func (c *Checker) decided(domain string, signatures []string, whoisStatus Status) bool {
+	if whoisStatus == StatusAvailable {
+		return true
+	}
+	for _, sig := range signatures {
+		if sig == "RESERVED" {
+			return true
+		}
+	}
+	registered, _, _ := c.registrationSignals(domain, signatures)
+	return registered
 }
 
 // min returns the smaller of two integers
@@ -216,35 +222,41 @@ func min(a, b int) int {
 }
 
 // checkDNSRecords checks various DNS records for the domain
-func checkDNSRecords(domain string) ([]string, error) {
+func (c *Checker) checkDNSRecords(ctx context.Context, domain string) ([]string, error) {
 	var signatures []string
+	if c.opts.DNSTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.DNSTimeout)
+		defer cancel()
+	}
+	resolver := c.dns
 
 	// 1. Check DNS NS records
-	nsRecords, err := net.LookupNS(domain)
+	nsRecords, err := resolver.LookupNS(ctx, domain)
 	if err == nil && len(nsRecords) > 0 {
 		signatures = append(signatures, "DNS_NS")
 	}
 
 	// 2. Check DNS A records
-	ipRecords, err := net.LookupIP(domain)
+	ipRecords, err := resolver.LookupIP(ctx, "ip", domain)
 	if err == nil && len(ipRecords) > 0 {
 		signatures = append(signatures, "DNS_A")
 	}
 
 	// 3. Check DNS MX records
-	mxRecords, err := net.LookupMX(domain)
+	mxRecords, err := resolver.LookupMX(ctx, domain)
 	if err == nil && len(mxRecords) > 0 {
 		signatures = append(signatures, "DNS_MX")
 	}
 
 	// 4. Check DNS TXT records
-	txtRecords, err := net.LookupTXT(domain)
+	txtRecords, err := resolver.LookupTXT(ctx, domain)
 	if err == nil && len(txtRecords) > 0 {
 		signatures = append(signatures, "DNS_TXT")
 	}
 
 	// 5. Check DNS CNAME records
-	cnameRecord, err := net.LookupCNAME(domain)
+	cnameRecord, err := resolver.LookupCNAME(ctx, domain)
 	if err == nil && cnameRecord != "" && cnameRecord != domain+"." {
 		signatures = append(signatures, "DNS_CNAME")
 	}
@@ -254,296 +266,274 @@ func checkDNSRecords(domain string) ([]string, error) {
 
 // CheckDomainAvailability checks if a domain is available for registration
 func CheckDomainAvailability(domain string) (bool, error) {
-	signatures, err := CheckDomainSignatures(domain)
-	if err != nil {
-		return false, err
-	}
+	return CheckDomainAvailabilityContext(context.Background(), domain)
+}
 
-	// Special logging for dc1.de to debug GitHub Actions issue
-	if domain == "dc1.de" {
-		fmt.Printf("DEBUG dc1.de: Found signatures: %v\n", signatures)
-	}
+// CheckDomainAvailabilityContext is like CheckDomainAvailability but returns
+// ctx.Err() promptly when ctx is cancelled during WHOIS retries or backoff
+func CheckDomainAvailabilityContext(ctx context.Context, domain string) (bool, error) {
+	result, err := CheckDomainContext(ctx, domain)
+	return result.Available, err
+}
 
+// CheckDomainContext checks a domain with the checker configured by SetConfig; domains
+// needing manual review carry their special status in the result
+func CheckDomainContext(ctx context.Context, domain string) (Result, error) {
+	return defaultChecker().Check(ctx, domain)
+}
 
+// decideAvailability decides from the signatures of a pass whether a domain is
+// available, consulting the WHOIS lookup of the pass, or making one when WHOIS was
+// disabled or skipped, when no signature indicates registration. The special status is set
+// for domains that need manual review.
+func (c *Checker) decideAvailability(ctx context.Context, domain string, pass *checkPass) (bool, string, error) {
+	signatures, lookup := pass.signatures, pass.lookup
+	c.debugf(domain, "Found signatures: %v", signatures)
 
 	// If domain is reserved, it's not available
 	for _, sig := range signatures {
 		if sig == "RESERVED" {
-			return false, nil
+			return false, "", nil
 		}
 	}
 
 	// Check if we have any registration signatures
-	hasRegistrationSignatures := false
-	hasDNSSignatures := false
-	hasWHOISSignature := false
-
-	for _, sig := range signatures {
-		if sig == "DNS_NS" || sig == "DNS_A" || sig == "DNS_MX" || sig == "DNS_TXT" || sig == "DNS_CNAME" {
-			hasDNSSignatures = true
-			hasRegistrationSignatures = true
-		} else if sig == "WHOIS" {
-			hasWHOISSignature = true
-			hasRegistrationSignatures = true
-		} else if sig == "SSL" {
-			hasRegistrationSignatures = true
-		}
-	}
+	hasRegistrationSignatures, hasDNSSignatures, hasWHOISSignature := c.registrationSignals(domain, signatures)
 
-	// Special logging for dc1.de
-	if domain == "dc1.de" {
-		fmt.Printf("DEBUG dc1.de: Has registration signatures: %v (DNS: %v, WHOIS: %v)\n",
-			hasRegistrationSignatures, hasDNSSignatures, hasWHOISSignature)
-	}
+	c.debugf(domain, "Has registration signatures: %v (DNS: %v, WHOIS: %v)",
+		hasRegistrationSignatures, hasDNSSignatures, hasWHOISSignature)
 
 	// If we have clear registration signatures, domain is registered
 	if hasRegistrationSignatures {
-		if domain == "dc1.de" {
-			fmt.Printf("DEBUG dc1.de: Returning REGISTERED due to signatures\n")
-		}
-		return false, nil
+		c.debugf(domain, "Returning REGISTERED due to signatures")
+		return false, "", nil
 	}
 
-	// If no signatures found, check WHOIS as final verification
-	// But first, let's check if we have any DNS signatures that might indicate registration
-	if domain == "dc1.de" {
-		fmt.Printf("DEBUG dc1.de: No registration signatures, performing WHOIS check (DNS signatures available: %v)\n", hasDNSSignatures)
+	// RDAP answers with structured data, so its verdict needs no WHOIS confirmation
+	switch pass.rdap.status {
+	case StatusAvailable:
+		c.debugf(domain, "RDAP has no record, returning AVAILABLE")
+		return true, "", nil
+	case StatusSpecial:
+		c.debugf(domain, "RDAP reports special status %s", pass.rdap.special)
+		return false, pass.rdap.special, nil
 	}
 
-	maxRetries := 5  // Increased retry count for rate limit handling
-	baseDelay := 2 * time.Second
+	// If no signatures found, check WHOIS as final verification
+	// But first, let's check if we have any DNS signatures that might indicate registration
+	c.debugf(domain, "No registration signatures, performing WHOIS check (DNS signatures available: %v)", hasDNSSignatures)
 
-	for i := 0; i < maxRetries; i++ {
-		result, err := whois.Whois(domain)
-		if err == nil {
-			// Convert WHOIS response to lowercase for case-insensitive matching
-			result = strings.ToLower(result)
+	if !lookup.done {
+		started := time.Now()
+		lookup = c.lookupWHOIS(ctx, domain)
+		pass.lookup = lookup
+		pass.phases[types.CheckWHOIS] += time.Since(started)
+	}
+	if ctx.Err() != nil {
+		return false, "", ctx.Err()
+	}
+	if lookup.err == errWHOISRateLimited {
+		c.debugf(domain, "All WHOIS attempts failed due to rate limiting")
+		return c.handleRateLimitedDomain(domain, hasDNSSignatures)
+	}
+	if lookup.err == nil {
+		status, indicators := c.classifyWHOISResponse(domain, lookup.raw)
+		if c.opts.Debug {
+			c.debugf(domain, "WHOIS response: %s", strings.ToLower(lookup.raw))
+			c.debugf(domain, "WHOIS classified as %s %v", status, indicators)
+		}
 
-			// Special logging for dc1.de
-			if domain == "dc1.de" {
-				fmt.Printf("DEBUG dc1.de: WHOIS response: %s\n", result)
+		switch status {
+		case StatusAvailable:
+			if c.opts.Strict && indicators[0] == terseIndicator {
+				return false, NoEvidenceStatus, nil
 			}
+			return true, "", nil
+		case StatusRegistered, StatusReserved:
+			return false, "", nil
+		case StatusSpecial:
+			return false, specialStatusName(indicators[0]), nil
+		case StatusConflict:
+			return false, ConflictStatus, nil
+		}
+	}
 
-			// Check for access control errors in WHOIS response
-			isRateLimitResponse := strings.Contains(result, "connection refused") ||
-								   strings.Contains(result, "access control") ||
-								   strings.Contains(result, "limit exceeded") ||
-								   strings.Contains(result, "rate limit") ||
-								   strings.Contains(result, "too many requests")
-
-			if isRateLimitResponse {
-				if domain == "dc1.de" {
-					fmt.Printf("DEBUG dc1.de: Rate limit detected in WHOIS response\n")
-				}
+	// If we can't determine the status, we need to be careful
+	// In GitHub Actions, WHOIS might be blocked, so we can't be sure
+	c.debugf(domain, "No clear indicators found, returning AVAILABLE (but uncertain due to WHOIS limitations)")
+	if c.opts.Strict {
+		return false, NoEvidenceStatus, nil
+	}
+	return true, "", nil
+}
 
-				// If this is not the last attempt, wait and retry
-				if i < maxRetries-1 {
-					waitTime := baseDelay * time.Duration(1<<uint(i+1)) // Exponential backoff
-					if domain == "dc1.de" {
-						fmt.Printf("DEBUG dc1.de: Waiting %v before retry due to rate limit response\n", waitTime)
-					}
-					time.Sleep(waitTime)
-					continue // Retry the WHOIS query
-				} else {
-					// Last attempt failed, handle specially
-					if domain == "dc1.de" {
-						fmt.Printf("DEBUG dc1.de: All attempts failed due to rate limiting in response\n")
-					}
-					return handleRateLimitedDomain(domain, hasDNSSignatures)
-				}
-			}
+// Weights of the registration signatures in registrationSignals: a domain is registered
+// once its signatures weigh fullWeight. Under wildcard DNS every name resolves and
+// reaches the registry's web server, so the A record and the HTTP answer of such a TLD
+// weigh halfWeight under the combined policy and nothing under the ignore policy.
+const (
+	fullWeight = 2
+	halfWeight = 1
+)
 
-			// Check for indicators that domain is definitely available
-			for _, indicator := range availableIndicators {
-				if strings.Contains(result, indicator) {
-					if domain == "dc1.de" {
-						fmt.Printf("DEBUG dc1.de: Found AVAILABLE indicator: %s\n", indicator)
-					}
-					return true, nil
-				}
-			}
+// signatureWeight returns how much a signature counts toward registration under a
+// wildcard A policy; signatures that show no registration weigh nothing
+func signatureWeight(sig, aPolicy string) int {
+	switch {
+	case sig == "DNS_A" || sig == "HTTP":
+		switch aPolicy {
+		case "":
+			return fullWeight
+		case types.WildcardACombined:
+			return halfWeight
+		}
+		return 0
+	case sig == "DNS_NS" || sig == "DNS_MX" || sig == "DNS_TXT" || sig == "DNS_CNAME" || sig == "WHOIS" ||
+		sig == "SSL" || sig == "RDAP" || strings.HasPrefix(sig, CustomSignaturePrefix):
+		return fullWeight
+	}
+	return 0
+}
 
-			// Check for registration indicators
-			enhancedRegisteredIndicators := []string{
-				"registrar:",
-				"registrant:",
-				"creation date:",
-				"created:",
-				"updated date:",
-				"updated:",
-				"expiration date:",
-				"expires:",
-				"name server:",
-				"nserver:",
-				"nameserver:",
-				"status: active",
-				"status: client",
-				"status: ok",
-				"status: locked",
-				"status: connect",  // Connect status indicates registered domain
-				"status:connect",   // Version without space
-				"domain name:",
-				"domain:",
-				"Status: connect",  // Uppercase version
-				"nsentry:",         // DENIC specific field
-				"changed:",         // DENIC specific field
-			}
+// registrationSignals reports whether the signatures show registration and whether
+// DNS and WHOIS contributed to that. The signatures are weighed by signatureWeight, so
+// that the wildcard A policy of a TLD decides how much its A records count.
+func (c *Checker) registrationSignals(domain string, signatures []string) (registered, dns, whois bool) {
+	aPolicy := c.wildcardAPolicy(domain)
 
-			for _, indicator := range enhancedRegisteredIndicators {
-				if strings.Contains(result, indicator) {
-					if domain == "dc1.de" {
-						fmt.Printf("DEBUG dc1.de: Found REGISTERED indicator: %s\n", indicator)
-					}
-					return false, nil
-				}
-			}
+	weight, dnsWeight := 0, 0
+	for _, sig := range signatures {
+		w := signatureWeight(sig, aPolicy)
+		weight += w
+		if strings.HasPrefix(sig, "DNS_") {
+			dnsWeight += w
+		}
+		if sig == "WHOIS" {
+			whois = true
+		}
+	}
+	registered = weight >= fullWeight
+	// A half-weight A record only counts as DNS evidence once another signal backs it
+	dns = dnsWeight >= fullWeight || registered && dnsWeight > 0
+	return registered, dns, whois
+}
 
-			// Check for special status indicators
-			specialStatusIndicators := []string{
-				"status: redemptionperiod",
-				"status: redemption period",
-				"status: redemption",
-				"redemptionperiod",
-				"redemption period",
-				"status: pendingdelete",
-				"status: pending delete",
-				"status: hold",
-				"status: inactive",
-				"status: suspended",
-				"status: reserved",
-				"status: quarantined",
-				"status: pending",
-				"status: transfer",
-				"status: grace",
-				"status: autorenewperiod",
-				"status: auto renew period",
-				"status: expire",
-				"status: expired",
-				"status: clienthold",
-				"status: client hold",
-				"status: serverhold",
-				"status: server hold",
-			}
+// classifyWHOISResponse classifies a WHOIS response and logs contradictory ones for auditing
+func (c *Checker) classifyWHOISResponse(domain, raw string) (Status, []string) {
+	status, indicators := c.ClassifyWHOIS(suffixOf(domain), raw)
+	if available, registered := WHOISConflict(raw); len(registered) > 0 {
+		c.logf("WHOIS CONFLICT: %s - available %v vs registered %v, classified as %s (policy: %s)\n",
+			domain, available, registered, status, c.conflictPolicy())
+	}
+	return status, indicators
+}
 
-			for _, indicator := range specialStatusIndicators {
-				if strings.Contains(result, indicator) {
-					// Extract the status type for better tracking
-					statusType := strings.TrimPrefix(indicator, "status: ")
-					addToSpecialStatus(domain, strings.ToUpper(statusType))
-					return false, nil
-				}
-			}
-			break
-		} else {
-			if domain == "dc1.de" {
-				fmt.Printf("DEBUG dc1.de: WHOIS attempt %d failed: %v\n", i+1, err)
-			}
+// isTerseTLD reports whether a suffix (or a domain under it) is configured as a terse TLD
+func (c *Checker) isTerseTLD(name string) bool {
+	name = strings.ToLower(strings.TrimPrefix(name, "."))
+	for _, tld := range c.opts.TerseTLDs {
+		tld = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tld), "."))
+		if tld != "" && (name == tld || strings.HasSuffix(name, "."+tld)) {
+			return true
+		}
+	}
+	return false
+}
 
-			// Check if this is a rate limit or access control error
-			errorStr := strings.ToLower(err.Error())
-			isRateLimit := strings.Contains(errorStr, "connection refused") ||
-						  strings.Contains(errorStr, "access control") ||
-						  strings.Contains(errorStr, "limit exceeded") ||
-						  strings.Contains(errorStr, "rate limit") ||
-						  strings.Contains(errorStr, "too many requests")
-
-			if isRateLimit {
-				if domain == "dc1.de" {
-					fmt.Printf("DEBUG dc1.de: Rate limit detected, attempt %d/%d\n", i+1, maxRetries)
-				}
+// WildcardAPolicy returns the configured A-record policy for the domain's TLD,
+// or an empty string when A records count toward registration as usual
+func WildcardAPolicy(domain string) string {
+	return defaultChecker().wildcardAPolicy(domain)
+}
 
-				// If this is the last attempt, handle specially
-				if i == maxRetries-1 {
-					if domain == "dc1.de" {
-						fmt.Printf("DEBUG dc1.de: All WHOIS attempts failed due to rate limiting\n")
-					}
-					// Mark domain for special handling
-					return handleRateLimitedDomain(domain, hasDNSSignatures)
-				}
+// WildcardAPolicy is like the package-level WildcardAPolicy but uses the checker's config
+func (c *Checker) WildcardAPolicy(domain string) string {
+	return c.wildcardAPolicy(domain)
+}
 
-				// Use exponential backoff for rate limits
-				waitTime := baseDelay * time.Duration(1<<uint(i)) // 2s, 4s, 8s, 16s, 32s
-				if domain == "dc1.de" {
-					fmt.Printf("DEBUG dc1.de: Waiting %v before retry due to rate limit\n", waitTime)
-				}
-				time.Sleep(waitTime)
-			} else {
-				// For other errors, use shorter delay
-				if i < maxRetries-1 {
-					waitTime := time.Duration(1+i) * time.Second
-					time.Sleep(waitTime)
-				}
-			}
+// wildcardAPolicy returns the A-record policy of the checker for the domain's TLD
+func (c *Checker) wildcardAPolicy(domain string) string {
+	name := strings.ToLower(strings.TrimPrefix(domain, "."))
+	for tld, policy := range c.opts.WildcardDNS {
+		tld = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tld), "."))
+		if tld != "" && (name == tld || strings.HasSuffix(name, "."+tld)) {
+			return policy
 		}
 	}
+	return ""
+}
 
-	// If we can't determine the status, we need to be careful
-	// In GitHub Actions, WHOIS might be blocked, so we can't be sure
-	if domain == "dc1.de" {
-		fmt.Printf("DEBUG dc1.de: No clear indicators found, returning AVAILABLE (but uncertain due to WHOIS limitations)\n")
+// terseThreshold returns the maximum length of a WHOIS response considered minimal
+func (c *Checker) terseThreshold() int {
+	if c.opts.TerseThreshold <= 0 {
+		return 64
 	}
-	return true, nil
+	return c.opts.TerseThreshold
 }
 
 // handleRateLimitedDomain handles domains that couldn't be checked due to WHOIS rate limiting
-func handleRateLimitedDomain(domain string, hasDNSSignatures bool) (bool, error) {
-	if domain == "dc1.de" {
-		fmt.Printf("DEBUG dc1.de: Handling rate-limited domain (DNS signatures: %v)\n", hasDNSSignatures)
-	}
+func (c *Checker) handleRateLimitedDomain(domain string, hasDNSSignatures bool) (bool, string, error) {
+	c.debugf(domain, "Handling rate-limited domain (DNS signatures: %v)", hasDNSSignatures)
 
 	// If we have DNS signatures, it's likely registered
 	if hasDNSSignatures {
-		if domain == "dc1.de" {
-			fmt.Printf("DEBUG dc1.de: Has DNS signatures, considering REGISTERED despite WHOIS rate limit\n")
-		}
-		return false, nil // Domain is registered
+		c.debugf(domain, "Has DNS signatures, considering REGISTERED despite WHOIS rate limit")
+		return false, "", nil // Domain is registered
 	}
 
 	// No DNS signatures and WHOIS unavailable - this is uncertain
-	// We'll add it to special status for manual review and NOT mark as available
-	if globalConfig != nil {
-		// Add to special status list for manual review
-		addToSpecialStatus(domain, "WHOIS_RATE_LIMITED")
-	}
-
-	if domain == "dc1.de" {
-		fmt.Printf("DEBUG dc1.de: No DNS signatures, adding to special status (NOT marking as available)\n")
-	}
+	// We'll report it as special status for manual review and NOT mark as available
+	c.debugf(domain, "No DNS signatures, adding to special status (NOT marking as available)")
 
 	// Return as NOT available since we can't determine the status
 	// The domain will be tracked in special status instead
-	return false, nil
+	return false, RateLimitedStatus, nil
 }
 
-// addToSpecialStatus adds a domain to the special status tracking
-func addToSpecialStatus(domain, reason string) {
-	specialStatusMutex.Lock()
-	defer specialStatusMutex.Unlock()
-
-	specialStatusDomains = append(specialStatusDomains, types.SpecialStatusDomain{
-		Domain: domain,
-		Status: reason,
-		Reason: fmt.Sprintf("WHOIS status: %s", reason),
-	})
-
-	// Also log for immediate visibility
-	fmt.Printf("SPECIAL STATUS: %s - %s\n", domain, reason)
+// expiryLayouts are the date formats of the expiration dates of registries: ISO 8601
+// with and without time and zone, plain dates and the day-month-year form of
+// registries such as .uk and .ie
+var expiryLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006.01.02",
+	"2006/01/02",
+	"02-Jan-2006 15:04:05 MST",
+	"02-Jan-2006",
+	"2-Jan-2006",
+	"02.01.2006",
 }
 
-// GetSpecialStatusDomains returns all domains with special status
-func GetSpecialStatusDomains() []types.SpecialStatusDomain {
-	specialStatusMutex.Lock()
-	defer specialStatusMutex.Unlock()
-
-	// Return a copy to avoid race conditions
-	result := make([]types.SpecialStatusDomain, len(specialStatusDomains))
-	copy(result, specialStatusDomains)
-	return result
+// ParseExpiryDate parses the value of a WHOIS expiration field such as
+// "Registry Expiry Date: 2025-01-02T03:04:05Z", "paid-till: 2025-01-02" or
+// "Expiry date: 02-Jan-2025". Text after the date, e.g. a format note, is ignored;
+// the bool is false for values in none of the known formats.
+func ParseExpiryDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	candidates := []string{value}
+	if fields := strings.Fields(value); len(fields) > 1 {
+		candidates = append(candidates, fields[0])
+	}
+	for _, candidate := range candidates {
+		for _, layout := range expiryLayouts {
+			if t, err := time.Parse(layout, candidate); err == nil {
+				return t.UTC(), true
+			}
+		}
+	}
+	return time.Time{}, false
 }
 
-// ClearSpecialStatusDomains clears the special status domains list
-func ClearSpecialStatusDomains() {
-	specialStatusMutex.Lock()
-	defer specialStatusMutex.Unlock()
-	specialStatusDomains = nil
+// expiryDate returns the expiration date given by the WHOIS response of a registered
+// domain, or the zero time when the response gives none
+func expiryDate(domain, raw string) time.Time {
+	if raw == "" {
+		return time.Time{}
+	}
+	expiry, _ := ParseExpiryDate(ParseWHOIS(domain, raw).Expires)
+	return expiry
 }
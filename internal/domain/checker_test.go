@@ -0,0 +1,307 @@
+package domain
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"domain-scanner/internal/testutil"
+	"domain-scanner/internal/types"
+)
+
+const registeredWHOIS = "Domain Name: EXAMPLE.TEST\n" +
+	"Registrar: Example Registrar, Inc.\n" +
+	"Creation Date: 2001-02-03T04:05:06Z\n" +
+	"Registry Expiry Date: 2030-02-03T04:05:06Z\n" +
+	"Name Server: NS1.EXAMPLE.TEST\n"
+
+// newWHOISServer starts a fake WHOIS server that is stopped with the test
+func newWHOISServer(t *testing.T, answer func(domain string) string) *testutil.WHOISServer {
+	t.Helper()
+	server, err := testutil.NewWHOISServer(answer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+	return server
+}
+
+// newTestChecker creates a checker resolving through the fake resolver and asking the
+// fake WHOIS server about the domains under .test
+func newTestChecker(resolver *testutil.Resolver, server *testutil.WHOISServer, opts CheckerOptions) *Checker {
+	opts.Resolver = resolver
+	opts.WHOISServers = map[string]string{"test": server.Addr()}
+	return NewChecker(opts)
+}
+
+func TestCheckWHOISAlways(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        CheckerOptions
+		wantQueries int
+		wantSkipped int
+	}{
+		{name: "early exit skips WHOIS", wantQueries: 0, wantSkipped: 2},
+		{name: "WHOIS always", opts: CheckerOptions{WHOISAlways: true}, wantQueries: 1, wantSkipped: 1},
+		{
+			name:        "WHOIS first",
+			opts:        CheckerOptions{CheckOrder: []string{types.CheckWHOIS}},
+			wantQueries: 1,
+			wantSkipped: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := testutil.NewResolver()
+			resolver.SetNS("example.test", "ns1.example.test")
+			server := newWHOISServer(t, testutil.StaticWHOIS(registeredWHOIS))
+			c := newTestChecker(resolver, server, tt.opts)
+
+			result, err := c.Check(context.Background(), "example.test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Available {
+				t.Errorf("Available = true, want false")
+			}
+			if got := server.Queries("example.test"); got != tt.wantQueries {
+				t.Errorf("WHOIS queries = %d, want %d", got, tt.wantQueries)
+			}
+			if (result.WHOIS != "") != (tt.wantQueries > 0) {
+				t.Errorf("WHOIS = %q, want a response: %v", result.WHOIS, tt.wantQueries > 0)
+			}
+			if result.Skipped != tt.wantSkipped {
+				t.Errorf("Skipped = %d, want %d", result.Skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestCheckSpecialStatusWithoutDNS(t *testing.T) {
+	// Domains without registration signatures always reach WHOIS, which reports their
+	// special status
+	resolver := testutil.NewResolver()
+	server := newWHOISServer(t, testutil.StaticWHOIS("Status: redemptionPeriod\n"))
+	c := newTestChecker(resolver, server, CheckerOptions{DNSCheck: true, WHOISCheck: true})
+
+	result, err := c.Check(context.Background(), "example.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Available || result.SpecialStatus != "REDEMPTIONPERIOD" {
+		t.Errorf("Check() = available %v, special %q, want REDEMPTIONPERIOD", result.Available, result.SpecialStatus)
+	}
+}
+
+func TestCheckWildcardAPolicy(t *testing.T) {
+	const available = "No match for \"EXAMPLE.TEST\".\n"
+	tests := []struct {
+		name          string
+		policy        string
+		program       func(r *testutil.Resolver)
+		whois         string
+		wantAvailable bool
+	}{
+		{name: "A record without policy", program: func(r *testutil.Resolver) { r.SetA("example.test", "192.0.2.1") }, whois: available},
+		{
+			name:          "ignored A record",
+			policy:        types.WildcardAIgnore,
+			program:       func(r *testutil.Resolver) { r.SetA("example.test", "192.0.2.1") },
+			whois:         available,
+			wantAvailable: true,
+		},
+		// The other DNS records still decide the domain
+		{
+			name:   "ignored A record with MX",
+			policy: types.WildcardAIgnore,
+			program: func(r *testutil.Resolver) {
+				r.SetA("example.test", "192.0.2.1")
+				r.SetMX("example.test", "mail.example.test")
+			},
+			whois: available,
+		},
+		// An A record alone does not decide the domain under the combined policy either
+		{
+			name:          "combined A record",
+			policy:        types.WildcardACombined,
+			program:       func(r *testutil.Resolver) { r.SetA("example.test", "192.0.2.1") },
+			whois:         available,
+			wantAvailable: true,
+		},
+		{
+			name:    "ignored A record with a registered WHOIS answer",
+			policy:  types.WildcardAIgnore,
+			program: func(r *testutil.Resolver) { r.SetA("example.test", "192.0.2.1") },
+			whois:   registeredWHOIS,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := testutil.NewResolver()
+			tt.program(resolver)
+			server := newWHOISServer(t, testutil.StaticWHOIS(tt.whois))
+			opts := CheckerOptions{DNSCheck: true, WHOISCheck: true}
+			if tt.policy != "" {
+				opts.WildcardDNS = map[string]string{".test": tt.policy}
+			}
+			c := newTestChecker(resolver, server, opts)
+
+			result, err := c.Check(context.Background(), "example.test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Available != tt.wantAvailable {
+				t.Errorf("Available = %v with signatures %v, want %v", result.Available, result.Signatures, tt.wantAvailable)
+			}
+		})
+	}
+}
+
+func TestRegistrationSignals(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		signatures     []string
+		wantRegistered bool
+		wantDNS        bool
+	}{
+		{name: "A record", signatures: []string{"DNS_A"}, wantRegistered: true, wantDNS: true},
+		{name: "HTTP answer", signatures: []string{"HTTP"}, wantRegistered: true},
+		{name: "ignored A record", policy: types.WildcardAIgnore, signatures: []string{"DNS_A"}},
+		{name: "ignored A record with HTTP", policy: types.WildcardAIgnore, signatures: []string{"DNS_A", "HTTP"}},
+		{name: "ignored A record with SSL", policy: types.WildcardAIgnore, signatures: []string{"DNS_A", "SSL"}, wantRegistered: true},
+		{name: "combined A record", policy: types.WildcardACombined, signatures: []string{"DNS_A"}},
+		{name: "combined HTTP answer", policy: types.WildcardACombined, signatures: []string{"HTTP"}},
+		// A weak signal backs up the A record
+		{name: "combined A record with HTTP", policy: types.WildcardACombined, signatures: []string{"DNS_A", "HTTP"},
+			wantRegistered: true, wantDNS: true},
+		{name: "combined A record with SSL", policy: types.WildcardACombined, signatures: []string{"DNS_A", "SSL"},
+			wantRegistered: true, wantDNS: true},
+		{name: "combined A record with MX", policy: types.WildcardACombined, signatures: []string{"DNS_A", "DNS_MX"},
+			wantRegistered: true, wantDNS: true},
+		{name: "combined A record with a reservation", policy: types.WildcardACombined, signatures: []string{"DNS_A", "RESERVED"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := CheckerOptions{DNSCheck: true}
+			if tt.policy != "" {
+				opts.WildcardDNS = map[string]string{".test": tt.policy}
+			}
+			c := NewChecker(opts)
+
+			registered, dns, _ := c.registrationSignals("example.test", tt.signatures)
+			if registered != tt.wantRegistered || dns != tt.wantDNS {
+				t.Errorf("registrationSignals(%v) = registered %v, DNS %v, want %v, %v",
+					tt.signatures, registered, dns, tt.wantRegistered, tt.wantDNS)
+			}
+		})
+	}
+}
+
+func TestCheckDNSRecordsSignatures(t *testing.T) {
+	tests := []struct {
+		name    string
+		program func(r *testutil.Resolver)
+		domain  string
+		want    []string
+	}{
+		{
+			name:    "NS only",
+			program: func(r *testutil.Resolver) { r.SetNS("example.test", "ns1.example.test") },
+			domain:  "example.test",
+			want:    []string{"DNS_NS"},
+		},
+		{
+			name:    "A only",
+			program: func(r *testutil.Resolver) { r.SetA("example.test", "192.0.2.1") },
+			domain:  "example.test",
+			want:    []string{"DNS_A"},
+		},
+		{
+			name:    "AAAA only",
+			program: func(r *testutil.Resolver) { r.SetA("example.test", "2001:db8::1") },
+			domain:  "example.test",
+			want:    []string{"DNS_A"},
+		},
+		{
+			name:    "wildcard A",
+			program: func(r *testutil.Resolver) { r.SetA("*.test", "192.0.2.1") },
+			domain:  "anything.test",
+			want:    []string{"DNS_A"},
+		},
+		{
+			name: "own records instead of the wildcard",
+			program: func(r *testutil.Resolver) {
+				r.SetA("*.test", "192.0.2.1")
+				r.SetMX("example.test", "mail.example.test")
+			},
+			domain: "example.test",
+			want:   []string{"DNS_MX"},
+		},
+		{
+			name: "full records",
+			program: func(r *testutil.Resolver) {
+				r.SetNS("example.test", "ns1.example.test", "ns2.example.test")
+				r.SetA("example.test", "192.0.2.1")
+				r.SetMX("example.test", "mail.example.test")
+				r.SetTXT("example.test", "v=spf1 -all")
+			},
+			domain: "example.test",
+			want:   []string{"DNS_NS", "DNS_A", "DNS_MX", "DNS_TXT"},
+		},
+		{
+			name:    "CNAME",
+			program: func(r *testutil.Resolver) { r.SetCNAME("www.example.test", "example.net") },
+			domain:  "www.example.test",
+			want:    []string{"DNS_CNAME"},
+		},
+		{
+			name: "partial failure",
+			program: func(r *testutil.Resolver) {
+				r.SetNS("example.test", "ns1.example.test")
+				r.SetA("example.test", "192.0.2.1")
+				r.Fail("example.test", testutil.RecordNS, testutil.ServFail("example.test"))
+			},
+			domain: "example.test",
+			want:   []string{"DNS_A"},
+		},
+		{
+			name: "all errors",
+			program: func(r *testutil.Resolver) {
+				r.SetNS("example.test", "ns1.example.test")
+				r.Fail("example.test", "", testutil.ServFail("example.test"))
+			},
+			domain: "example.test",
+		},
+		{
+			name:    "NXDOMAIN",
+			program: func(r *testutil.Resolver) {},
+			domain:  "example.test",
+		},
+	}
+
+	recordTypes := []string{testutil.RecordNS, testutil.RecordA, testutil.RecordMX, testutil.RecordTXT, testutil.RecordCNAME}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := testutil.NewResolver()
+			tt.program(resolver)
+			c := NewChecker(CheckerOptions{DNSCheck: true, Resolver: resolver})
+
+			got, err := c.checkDNSRecords(context.Background(), tt.domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkDNSRecords() = %v, want %v", got, tt.want)
+			}
+			for _, recordType := range recordTypes {
+				if n := resolver.Queries(tt.domain, recordType); n != 1 {
+					t.Errorf("%s queries = %d, want 1", recordType, n)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package domain
+
+import (
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// Status is the classification of a raw WHOIS response
+type Status int
+
+const (
+	// StatusUnknown means the response contained no recognizable indicator
+	StatusUnknown Status = iota
+	// StatusAvailable means the registry reported the domain as free
+	StatusAvailable
+	// StatusRegistered means the response contains registration data
+	StatusRegistered
+	// StatusReserved means the registry reserved or blocked the domain
+	StatusReserved
+	// StatusSpecial means the domain is in a transitional state such as redemption
+	StatusSpecial
+	// StatusRateLimited means the server refused the query instead of answering it
+	StatusRateLimited
+	// StatusConflict means the response contains both available and registered
+	// indicators and the conflict policy is "uncertain"
+	StatusConflict
+)
+
+// terseIndicator is reported for the short answers of terse TLDs, which carry no explicit indicator
+const terseIndicator = "terse response"
+
+// ConflictStatus marks domains whose WHOIS response was contradictory under the "uncertain" policy
+const ConflictStatus = "WHOIS_CONFLICT"
+
+var (
+	// WHOIS indicators for domain status detection
+	registeredIndicators = []string{
+		"registrar:",
+		"registrant:",
+		"creation date:",
+		"created:",
+		"updated date:",
+		"updated:",
+		"expiration date:",
+		"expires:",
+		"name server:",
+		"nserver:",
+		"nameserver:",
+		"status: active",
+		"status: client",
+		"status: ok",
+		"status: locked",
+		"status: connect", // Connect status indicates registered domain
+		"domain name:",
+		"domain:",
+		"nsentry:", // DENIC specific field
+		"changed:", // DENIC specific field
+	}
+
+	reservedIndicators = []string{
+		"status: reserved",
+		"status: restricted",
+		"status: blocked",
+		"status: prohibited",
+		"status: reserved for registry",
+		"status: reserved for registrar",
+		"status: reserved for registry operator",
+		"status: reserved for future use",
+		"status: not available for registration",
+		"status: not available for general registration",
+		"status: reserved for special purposes",
+		"status: reserved for government use",
+		"status: reserved for educational institutions",
+		"status: reserved for non-profit organizations",
+		"domain reserved",
+		"this domain is reserved",
+		"reserved domain",
+	}
+
+	// WHOIS indicators for domain availability detection
+	availableIndicators = []string{
+		"no match for", "not found", "no data found", "no entries found",
+		"domain not found", "no object found", "no matching record",
+		"status: free", "status: available", "available for registration",
+		"this domain is available", "domain is available", "domain available",
+	}
+
+	// WHOIS response fragments returned by servers that throttle or refuse queries
+	rateLimitIndicators = []string{
+		"connection refused",
+		"access control",
+		"limit exceeded",
+		"rate limit",
+		"too many requests",
+	}
+
+	// Registered indicators that echo the queried name and also appear in "not found"
+	// templates, so they do not contradict an available indicator
+	echoIndicators = map[string]bool{
+		"domain:":      true,
+		"domain name:": true,
+	}
+)
+
+// String returns the upper-case name of the status
+func (s Status) String() string {
+	switch s {
+	case StatusAvailable:
+		return "AVAILABLE"
+	case StatusRegistered:
+		return "REGISTERED"
+	case StatusReserved:
+		return "RESERVED"
+	case StatusSpecial:
+		return "SPECIAL"
+	case StatusRateLimited:
+		return "RATE_LIMITED"
+	case StatusConflict:
+		return "CONFLICT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ClassifyWHOIS classifies a raw WHOIS response for a domain under the given TLD
+// using the policies configured by SetConfig. It returns the status together with
+// the indicators that led to it and performs no network I/O.
+func ClassifyWHOIS(tld, raw string) (Status, []string) {
+	return defaultChecker().ClassifyWHOIS(tld, raw)
+}
+
+// ClassifyWHOIS is like the package-level ClassifyWHOIS but uses the checker's policies
+func (c *Checker) ClassifyWHOIS(tld, raw string) (Status, []string) {
+	result := strings.ToLower(raw)
+
+	// Throttled responses say nothing about the domain itself
+	if matched := containsIndicators(result, rateLimitIndicators); len(matched) > 0 {
+		return StatusRateLimited, matched
+	}
+
+	lines := parseWHOISLines(raw)
+
+	// Contradictory responses are decided by the configured conflict policy
+	if available, registered := conflictingIndicators(lines); len(registered) > 0 {
+		switch c.conflictPolicy() {
+		case types.ConflictRegisteredWins:
+			return StatusRegistered, registered
+		case types.ConflictUncertain:
+			return StatusConflict, append(available, registered...)
+		default:
+			return StatusAvailable, available
+		}
+	}
+
+	if matched := matchIndicators(lines, availableIndicators); len(matched) > 0 {
+		return StatusAvailable, matched
+	}
+
+	if matched := matchIndicators(lines, reservedIndicators); len(matched) > 0 {
+		return StatusReserved, matched
+	}
+
+	if matched := matchIndicators(lines, registeredIndicators); len(matched) > 0 {
+		return StatusRegistered, matched
+	}
+
+	if name := specialStatusOf(lines); name != "" {
+		return StatusSpecial, []string{specialStatusIndicator(name)}
+	}
+
+	// Terse registries answer unregistered names with an empty or minimal response
+	if c.isTerseTLD(tld) && len(strings.TrimSpace(result)) < c.terseThreshold() {
+		return StatusAvailable, []string{terseIndicator}
+	}
+
+	return StatusUnknown, nil
+}
+
+// WHOISConflict returns the available and registered indicators of a contradictory
+// WHOIS response; registered is empty when the response is not contradictory
+func WHOISConflict(raw string) (available, registered []string) {
+	return conflictingIndicators(parseWHOISLines(raw))
+}
+
+// conflictingIndicators finds registered indicators next to available ones in a parsed response
+func conflictingIndicators(lines []whoisLine) (available, registered []string) {
+	available = matchIndicators(lines, availableIndicators)
+	if len(available) == 0 {
+		return nil, nil
+	}
+	for _, indicator := range matchIndicators(lines, registeredIndicators) {
+		if !echoIndicators[indicator] {
+			registered = append(registered, indicator)
+		}
+	}
+	return available, registered
+}
+
+// conflictPolicy returns the checker's policy for contradictory WHOIS responses
+func (c *Checker) conflictPolicy() string {
+	if c.opts.WHOISConflict == "" {
+		return types.ConflictAvailableWins
+	}
+	return c.opts.WHOISConflict
+}
+
+// suffixOf returns everything after the first label of a domain name
+func suffixOf(domain string) string {
+	if idx := strings.Index(domain, "."); idx >= 0 {
+		return domain[idx+1:]
+	}
+	return domain
+}
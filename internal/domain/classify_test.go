@@ -0,0 +1,340 @@
+package domain
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"domain-scanner/internal/types"
+)
+
+func TestClassifyWHOISSamples(t *testing.T) {
+	tests := []struct {
+		name string
+		tld  string
+		raw  string
+		want Status
+	}{
+		{
+			name: "verisign registered",
+			tld:  "com",
+			raw: "   Domain Name: GOOGLE.COM\r\n" +
+				"   Registry Domain ID: 2138514_DOMAIN_COM-VRSN\r\n" +
+				"   Registrar WHOIS Server: whois.markmonitor.com\r\n" +
+				"   Registrar URL: http://www.markmonitor.com\r\n" +
+				"   Updated Date: 2019-09-09T15:39:04Z\r\n" +
+				"   Creation Date: 1997-09-15T04:00:00Z\r\n" +
+				"   Registry Expiry Date: 2028-09-14T04:00:00Z\r\n" +
+				"   Registrar: MarkMonitor Inc.\r\n" +
+				"   Domain Status: clientDeleteProhibited https://icann.org/epp#clientDeleteProhibited\r\n" +
+				"   Name Server: NS1.GOOGLE.COM\r\n" +
+				"   DNSSEC: unsigned\r\n" +
+				">>> Last update of whois database: 2024-05-01T10:00:00Z <<<\r\n",
+			want: StatusRegistered,
+		},
+		{
+			name: "verisign no match",
+			tld:  "com",
+			raw: "No match for \"QXZWVPLK.COM\".\r\n" +
+				">>> Last update of whois database: 2024-05-01T10:00:00Z <<<\r\n\r\n" +
+				"NOTICE: The expiration date displayed in this record is the date the\r\n" +
+				"registrar's sponsorship of the domain name registration in the registry is\r\n" +
+				"currently set to expire.\r\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "pir not found",
+			tld:  "org",
+			raw:  "Domain not found.\r\n>>> Last update of WHOIS database: 2024-05-01T10:00:00Z <<<\r\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "identity digital not found",
+			tld:  "io",
+			raw:  "NOT FOUND\r\n>>> Last update of WHOIS database: 2024-05-01T10:00:00Z <<<\r\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "neustar no data found",
+			tld:  "us",
+			raw:  "No Data Found\r\n>>> Last update of WHOIS database: 2024-05-01T10:00:00Z <<<\r\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "denic registered",
+			tld:  "de",
+			raw: "Domain: denic.de\n" +
+				"Nserver: ns1.denic.de\n" +
+				"Nserver: ns2.denic.de\n" +
+				"Dnskey: 257 3 8 AwEAAb/xrM2MD+xm84YNYby6TxkMaC6PtzF2bB9WBB7ux7iqzhViob4GKvQ6\n" +
+				"Status: connect\n" +
+				"Changed: 2022-03-02T13:45:13+01:00\n",
+			want: StatusRegistered,
+		},
+		{
+			name: "denic free",
+			tld:  "de",
+			raw:  "Domain: qxzwvplk.de\nStatus: free\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "denic rate limited",
+			tld:  "de",
+			raw:  "55000000002 Connection refused; access control limit reached. The number of requests per timeframe exceeded.\n",
+			want: StatusRateLimited,
+		},
+		{
+			name: "nominet no match",
+			tld:  "co.uk",
+			raw:  "\n    No match for \"qxzwvplk.co.uk\".\n\n    This domain name has not been registered.\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "afnic no entries",
+			tld:  "fr",
+			raw:  "%%\n%% This is the AFNIC Whois server.\n%%\n\n%% No entries found in the AFNIC Database.\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "eurid available",
+			tld:  "eu",
+			raw:  "% WHOIS qxzwvplk\nDomain: qxzwvplk.eu\nScript: LATIN\n\nStatus: AVAILABLE\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "dns belgium available with tabs",
+			tld:  "be",
+			raw:  "% .be Whois Server 6.1\n%\nDomain:\tqxzwvplk.be\nStatus:\tAVAILABLE\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "iis not found",
+			tld:  "se",
+			raw:  "# Copyright (c) 1997- The Swedish Internet Foundation.\n\ndomain \"qxzwvplk.se\" not found.\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "cnnic no matching record",
+			tld:  "cn",
+			raw:  "No matching record.\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "cira not found",
+			tld:  "ca",
+			raw:  "Not found: qxzwvplk.ca\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "tcinet no entries",
+			tld:  "ru",
+			raw:  "% TCI Whois Service. Terms of use:\n% https://tcinet.ru/documents/whois_ru_rf.pdf\n\nNo entries found for the selected source(s).\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "reserved status",
+			tld:  "xyz",
+			raw:  "Domain Name: NIC.XYZ\nDomain Status: reserved\n",
+			want: StatusReserved,
+		},
+		{
+			name: "redemption without registration data",
+			tld:  "com",
+			raw:  "Domain Status: redemptionPeriod https://icann.org/epp#redemptionPeriod\n",
+			want: StatusSpecial,
+		},
+		{
+			name: "too many requests",
+			tld:  "io",
+			raw:  "Too many requests, please try again later.\n",
+			want: StatusRateLimited,
+		},
+		{
+			name: "empty response",
+			tld:  "com",
+			raw:  "",
+			want: StatusUnknown,
+		},
+		{
+			name: "banner only",
+			tld:  "com",
+			raw:  "% This is a WHOIS server. Use of this data is subject to the terms of use.\n",
+			want: StatusUnknown,
+		},
+	}
+
+	c := NewChecker(CheckerOptions{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, indicators := c.ClassifyWHOIS(tt.tld, tt.raw)
+			if got != tt.want {
+				t.Errorf("ClassifyWHOIS() = %s %v, want %s", got, indicators, tt.want)
+			}
+			if got != StatusUnknown && len(indicators) == 0 {
+				t.Errorf("ClassifyWHOIS() = %s without indicators", got)
+			}
+		})
+	}
+}
+
+func TestClassifyWHOISTerseTLDs(t *testing.T) {
+	tests := []struct {
+		name string
+		tld  string
+		raw  string
+		want Status
+	}{
+		{name: "empty response of a terse TLD", tld: "example", raw: "", want: StatusAvailable},
+		{name: "short response of a terse TLD", tld: "sub.example", raw: "% no data\n", want: StatusAvailable},
+		{name: "long response of a terse TLD", tld: "example", raw: "% " + strings.Repeat("x", 80), want: StatusUnknown},
+		{name: "empty response of another TLD", tld: "com", raw: "", want: StatusUnknown},
+	}
+
+	c := NewChecker(CheckerOptions{TerseTLDs: []string{".example"}})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, indicators := c.ClassifyWHOIS(tt.tld, tt.raw); got != tt.want {
+				t.Errorf("ClassifyWHOIS() = %s %v, want %s", got, indicators, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyWHOISConflictPolicies(t *testing.T) {
+	policies := []string{"", types.ConflictAvailableWins, types.ConflictRegisteredWins, types.ConflictUncertain}
+	tests := []struct {
+		name string
+		raw  string
+		// want maps each policy to the expected status; the empty policy is available-wins
+		want     map[string]Status
+		conflict bool
+	}{
+		{
+			name: "not found with registrar data",
+			raw: "No match for \"EXAMPLE.COM\".\r\n" +
+				"Registrar: Example Registrar, Inc.\r\n" +
+				"Creation Date: 2001-04-12T00:00:00Z\r\n",
+			want: map[string]Status{
+				"":                           StatusAvailable,
+				types.ConflictAvailableWins:  StatusAvailable,
+				types.ConflictRegisteredWins: StatusRegistered,
+				types.ConflictUncertain:      StatusConflict,
+			},
+			conflict: true,
+		},
+		{
+			name: "free status with name servers",
+			raw:  "Domain: example.de\nStatus: free\nNserver: ns1.example.net\nChanged: 2020-01-01T00:00:00+01:00\n",
+			want: map[string]Status{
+				"":                           StatusAvailable,
+				types.ConflictAvailableWins:  StatusAvailable,
+				types.ConflictRegisteredWins: StatusRegistered,
+				types.ConflictUncertain:      StatusConflict,
+			},
+			conflict: true,
+		},
+		{
+			name: "available for registration with expiry",
+			raw:  "This domain is available for registration.\nExpiration Date: 2025-01-01\n",
+			want: map[string]Status{
+				"":                           StatusAvailable,
+				types.ConflictAvailableWins:  StatusAvailable,
+				types.ConflictRegisteredWins: StatusRegistered,
+				types.ConflictUncertain:      StatusConflict,
+			},
+			conflict: true,
+		},
+		{
+			// A "not found" template that echoes the queried name is not a contradiction
+			name: "not found echoing the name",
+			raw:  "Domain Name: EXAMPLE.ORG\r\nDomain not found.\r\n",
+			want: map[string]Status{
+				"":                           StatusAvailable,
+				types.ConflictAvailableWins:  StatusAvailable,
+				types.ConflictRegisteredWins: StatusAvailable,
+				types.ConflictUncertain:      StatusAvailable,
+			},
+		},
+		{
+			name: "registered only",
+			raw:  "Domain Name: EXAMPLE.NET\r\nRegistrar: Example Registrar, Inc.\r\nName Server: NS1.EXAMPLE.NET\r\n",
+			want: map[string]Status{
+				"":                           StatusRegistered,
+				types.ConflictAvailableWins:  StatusRegistered,
+				types.ConflictRegisteredWins: StatusRegistered,
+				types.ConflictUncertain:      StatusRegistered,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, policy := range policies {
+			name := policy
+			if name == "" {
+				name = "default"
+			}
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				var log bytes.Buffer
+				c := NewChecker(CheckerOptions{WHOISCheck: true, WHOISConflict: policy}).WithLog(&log)
+				status, indicators := c.classifyWHOISResponse("example.com", tt.raw)
+				if status != tt.want[policy] {
+					t.Errorf("status = %v %v, want %v", status, indicators, tt.want[policy])
+				}
+				if len(indicators) == 0 {
+					t.Errorf("no indicators reported for %v", status)
+				}
+				if logged := strings.Contains(log.String(), "WHOIS CONFLICT: example.com"); logged != tt.conflict {
+					t.Errorf("conflict logged = %v, want %v:\n%s", logged, tt.conflict, log.String())
+				}
+			})
+		}
+	}
+}
+
+// TestClassifyWHOISRegistryBodies classifies complete registry answers kept in
+// testdata/whois. Files are named <registry>-<tld>-<available|registered>.txt.
+func TestClassifyWHOISRegistryBodies(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "whois", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no WHOIS bodies in testdata/whois")
+	}
+
+	c := NewChecker(CheckerOptions{})
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".txt")
+		t.Run(name, func(t *testing.T) {
+			_, rest, _ := strings.Cut(name, "-")
+			i := strings.LastIndex(rest, "-")
+			if i < 0 {
+				t.Fatalf("file name %q does not end in -available or -registered", name)
+			}
+			tld, kind := rest[:i], rest[i+1:]
+
+			var want Status
+			switch kind {
+			case "available":
+				want = StatusAvailable
+			case "registered":
+				want = StatusRegistered
+			default:
+				t.Fatalf("file name %q does not end in -available or -registered", name)
+			}
+
+			raw, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, indicators := c.ClassifyWHOIS(tld, string(raw)); got != want {
+				t.Errorf("ClassifyWHOIS(%q) = %s %v, want %s", tld, got, indicators, want)
+			}
+			if available, registered := WHOISConflict(string(raw)); available != nil && registered != nil {
+				t.Errorf("WHOISConflict() = %v, %v, want no conflict", available, registered)
+			}
+		})
+	}
+}
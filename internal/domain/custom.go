@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Verdict is the answer of a custom check method about a domain
+type Verdict int
+
+const (
+	// VerdictUnknown means the method has no information; it does not affect the decision
+	VerdictUnknown Verdict = iota
+	// VerdictRegistered counts as a registration signal like DNS, WHOIS and SSL
+	VerdictRegistered
+	// VerdictAvailable means the method saw no registration; other signals still decide
+	VerdictAvailable
+	// VerdictReserved marks the domain as not available, like a reserved WHOIS status
+	VerdictReserved
+)
+
+// CustomSignaturePrefix starts the signature added for a custom method reporting registration
+const CustomSignaturePrefix = "CUSTOM_"
+
+// CustomCheckFunc is a custom check method. Errors are logged and treated as VerdictUnknown.
+type CustomCheckFunc func(ctx context.Context, domain string) (Verdict, error)
+
+// customCheckers holds the check methods registered through RegisterChecker
+var customCheckers struct {
+	sync.Mutex
+	funcs map[string]CustomCheckFunc
+}
+
+// RegisterChecker adds a custom check method that runs after the built-in methods for
+// every domain. Registering a name again replaces the method; a nil fn removes it.
+func RegisterChecker(name string, fn CustomCheckFunc) {
+	customCheckers.Lock()
+	defer customCheckers.Unlock()
+	if fn == nil {
+		delete(customCheckers.funcs, name)
+		return
+	}
+	if customCheckers.funcs == nil {
+		customCheckers.funcs = make(map[string]CustomCheckFunc)
+	}
+	customCheckers.funcs[name] = fn
+}
+
+// CustomSignature returns the signature recorded when the named method reports registration
+func CustomSignature(name string) string {
+	return CustomSignaturePrefix + strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(name))
+}
+
+// registeredCheckers returns the custom methods added through RegisterChecker
+func registeredCheckers() map[string]CustomCheckFunc {
+	customCheckers.Lock()
+	defer customCheckers.Unlock()
+	funcs := make(map[string]CustomCheckFunc, len(customCheckers.funcs))
+	for name, fn := range customCheckers.funcs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// customMethods returns the checker's custom methods
+func (c *Checker) customMethods() map[string]CustomCheckFunc {
+	methods := c.opts.Custom
+	if c.registry {
+		// The checker configured by SetConfig also sees methods registered later
+		methods = registeredCheckers()
+		for name, fn := range c.opts.Custom {
+			methods[name] = fn
+		}
+	}
+	return methods
+}
+
+// checkCustom runs the checker's custom methods in name order and returns the
+// signatures they contribute
+func (c *Checker) checkCustom(ctx context.Context, domain string) []string {
+	methods := c.customMethods()
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var signatures []string
+	for _, name := range names {
+		started := time.Now()
+		verdict, err := methods[name](ctx, domain)
+		c.observe("custom_"+name, started)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.logf("CUSTOM CHECK %s failed for %s: %v\n", name, domain, err)
+			}
+			continue
+		}
+		switch verdict {
+		case VerdictRegistered:
+			signatures = append(signatures, CustomSignature(name))
+		case VerdictReserved:
+			signatures = append(signatures, "RESERVED")
+		}
+	}
+	return signatures
+}
+
+// commandVerdicts maps the "status" field of a command's JSON output to a verdict
+var commandVerdicts = map[string]Verdict{
+	"registered": VerdictRegistered,
+	"available":  VerdictAvailable,
+	"reserved":   VerdictReserved,
+	"unknown":    VerdictUnknown,
+}
+
+// commandWaitDelay is how long a cancelled check command may keep its output open
+const commandWaitDelay = 100 * time.Millisecond
+
+// CommandChecker runs an external command for every domain. The command line is split
+// on whitespace and "{domain}" is replaced in every argument; no shell is involved.
+// A JSON object {"status": "registered|available|reserved|unknown"} on stdout decides
+// the verdict; without one the exit code does: 0 registered, 1 available, 2 reserved,
+// 3 unknown. Any other exit code or a timeout is an error.
+func CommandChecker(command string, timeout time.Duration) CustomCheckFunc {
+	return func(ctx context.Context, domain string) (Verdict, error) {
+		args := strings.Fields(command)
+		if len(args) == 0 {
+			return VerdictUnknown, errors.New("empty command")
+		}
+		for i := range args {
+			args[i] = strings.ReplaceAll(args[i], "{domain}", domain)
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		// Children of a killed command, e.g. of a shell script, may keep stdout open;
+		// stop waiting for them shortly after the timeout
+		cmd.WaitDelay = commandWaitDelay
+		output, err := cmd.Output()
+		if trimmed := strings.TrimSpace(string(output)); strings.HasPrefix(trimmed, "{") {
+			var result struct {
+				Status string `json:"status"`
+			}
+			if jsonErr := json.Unmarshal([]byte(trimmed), &result); jsonErr != nil {
+				return VerdictUnknown, fmt.Errorf("invalid JSON output: %w", jsonErr)
+			}
+			verdict, ok := commandVerdicts[strings.ToLower(result.Status)]
+			if !ok {
+				return VerdictUnknown, fmt.Errorf("invalid status %q in JSON output", result.Status)
+			}
+			return verdict, nil
+		}
+
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			return VerdictRegistered, nil
+		case ctx.Err() != nil:
+			return VerdictUnknown, ctx.Err()
+		case errors.As(err, &exitErr):
+			switch exitErr.ExitCode() {
+			case 1:
+				return VerdictAvailable, nil
+			case 2:
+				return VerdictReserved, nil
+			case 3:
+				return VerdictUnknown, nil
+			}
+			return VerdictUnknown, fmt.Errorf("exit code %d", exitErr.ExitCode())
+		default:
+			return VerdictUnknown, err
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubCommand is a check command answering by the name of the queried domain
+const stubCommand = `#!/bin/sh
+if [ "$2" != "--mode=check" ]; then
+	exit 9
+fi
+case "$1" in
+registered.test) exit 0 ;;
+available.test) exit 1 ;;
+reserved.test) exit 2 ;;
+unknown.test) exit 3 ;;
+crash.test) exit 7 ;;
+json-available.test) echo '{"status": "available"}' ;;
+json-reserved.test) echo '  {"status": "RESERVED", "detail": "premium"}'; exit 1 ;;
+json-invalid.test) echo '{"status": '; exit 0 ;;
+json-status.test) echo '{"status": "gone"}' ;;
+text.test) echo 'registered'; exit 1 ;;
+slow.test) sleep 5 ;;
+esac
+`
+
+// writeStubCommand writes the stub check command and returns its command line
+func writeStubCommand(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub command is a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "check.sh")
+	if err := os.WriteFile(path, []byte(stubCommand), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path + " {domain} --mode=check"
+}
+
+func TestCommandChecker(t *testing.T) {
+	command := writeStubCommand(t)
+
+	tests := []struct {
+		name    string
+		domain  string
+		command string
+		want    Verdict
+		wantErr string
+	}{
+		{domain: "registered.test", want: VerdictRegistered},
+		{domain: "available.test", want: VerdictAvailable},
+		{domain: "reserved.test", want: VerdictReserved},
+		{domain: "unknown.test", want: VerdictUnknown},
+		{domain: "crash.test", want: VerdictUnknown, wantErr: "exit code 7"},
+		{domain: "json-available.test", want: VerdictAvailable},
+		{domain: "json-reserved.test", want: VerdictReserved},
+		{domain: "json-invalid.test", want: VerdictUnknown, wantErr: "invalid JSON output"},
+		{domain: "json-status.test", want: VerdictUnknown, wantErr: `invalid status "gone"`},
+		{domain: "text.test", want: VerdictAvailable},
+		{domain: "slow.test", want: VerdictUnknown, wantErr: "deadline exceeded"},
+		{name: "missing argument", domain: "registered.test", command: strings.TrimSuffix(command, " --mode=check"), want: VerdictUnknown, wantErr: "exit code 9"},
+		{name: "empty command", domain: "registered.test", command: " ", want: VerdictUnknown, wantErr: "empty command"},
+		{name: "missing command", domain: "registered.test", command: filepath.Join(t.TempDir(), "missing") + " {domain}", want: VerdictUnknown, wantErr: "no such file"},
+	}
+
+	for _, tt := range tests {
+		name := tt.name
+		if name == "" {
+			name = tt.domain
+		}
+		t.Run(name, func(t *testing.T) {
+			line := command
+			if tt.command != "" {
+				line = tt.command
+			}
+			started := time.Now()
+			verdict, err := CommandChecker(line, 500*time.Millisecond)(context.Background(), tt.domain)
+			if verdict != tt.want {
+				t.Errorf("verdict = %v, want %v", verdict, tt.want)
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("error = %v, want none", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if elapsed := time.Since(started); elapsed > 3*time.Second {
+				t.Errorf("the check took %v despite the timeout", elapsed)
+			}
+		})
+	}
+}
+
+func TestCheckCustomWithCommand(t *testing.T) {
+	command := writeStubCommand(t)
+	var log bytes.Buffer
+	c := NewChecker(CheckerOptions{
+		WHOISCheck: true,
+		Custom: map[string]CustomCheckFunc{
+			"stub-registry": CommandChecker(command, time.Second),
+		},
+	}).WithLog(&log)
+
+	tests := []struct {
+		domain  string
+		want    []string
+		wantLog bool
+	}{
+		{domain: "registered.test", want: []string{"CUSTOM_STUB_REGISTRY"}},
+		{domain: "reserved.test", want: []string{"RESERVED"}},
+		{domain: "available.test"},
+		{domain: "unknown.test"},
+		{domain: "crash.test", wantLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			log.Reset()
+			if got := c.checkCustom(context.Background(), tt.domain); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkCustom() = %v, want %v", got, tt.want)
+			}
+			if logged := strings.Contains(log.String(), "CUSTOM CHECK stub-registry failed for "+tt.domain); logged != tt.wantLog {
+				t.Errorf("failure logged = %v, want %v:\n%s", logged, tt.wantLog, log.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxDoHResponse bounds the DNS-over-HTTPS answers read
+const maxDoHResponse = 1 << 20
+
+// DNS record types and response codes of the DNS-over-HTTPS JSON API
+const (
+	dohTypeA     = 1
+	dohTypeNS    = 2
+	dohTypeCNAME = 5
+	dohTypeMX    = 15
+	dohTypeTXT   = 16
+	dohTypeAAAA  = 28
+
+	dohNoError  = 0
+	dohNXDomain = 3
+)
+
+// DoHResolver answers the lookups of the DNS method through the JSON API of a
+// DNS-over-HTTPS server such as https://cloudflare-dns.com/dns-query or
+// https://dns.google/resolve, for networks where port 53 is blocked or intercepted.
+// Lookups the server cannot answer, e.g. on a network error, an HTTP error status or
+// SERVFAIL, go to the fallback resolver; a nonexistent name does not.
+type DoHResolver struct {
+	// URL is the endpoint of the JSON API, queried with name and type parameters
+	URL string
+	// Client sends the queries; nil uses the shared HTTPClient
+	Client *http.Client
+	// Fallback answers the lookups the server fails; nil uses net.DefaultResolver
+	Fallback Resolver
+}
+
+// dohResponse is the JSON answer of a DNS-over-HTTPS server
+type dohResponse struct {
+	Status int `json:"Status"`
+	Answer []struct {
+		Type int    `json:"type"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// errDoHFailed marks lookups the DNS-over-HTTPS server could not answer
+var errDoHFailed = errors.New("DNS-over-HTTPS lookup failed")
+
+// LookupNS returns the name servers of a name
+func (r *DoHResolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	data, err := r.query(ctx, name, dohTypeNS)
+	if r.failed(ctx, err) {
+		return r.fallback().LookupNS(ctx, name)
+	}
+	var records []*net.NS
+	for _, host := range data {
+		records = append(records, &net.NS{Host: host})
+	}
+	return records, err
+}
+
+// LookupIP returns the IPv4 and IPv6 addresses of a host; the network is ignored
+func (r *DoHResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, recordType := range []int{dohTypeA, dohTypeAAAA} {
+		data, err := r.query(ctx, host, recordType)
+		if r.failed(ctx, err) {
+			return r.fallback().LookupIP(ctx, network, host)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range data {
+			if ip := net.ParseIP(addr); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips, nil
+}
+
+// LookupMX returns the mail exchangers of a name
+func (r *DoHResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	data, err := r.query(ctx, name, dohTypeMX)
+	if r.failed(ctx, err) {
+		return r.fallback().LookupMX(ctx, name)
+	}
+	var records []*net.MX
+	for _, value := range data {
+		// MX data is "<preference> <host>"
+		pref, host, ok := strings.Cut(value, " ")
+		if n, err := strconv.ParseUint(pref, 10, 16); ok && err == nil {
+			records = append(records, &net.MX{Host: host, Pref: uint16(n)})
+		}
+	}
+	return records, err
+}
+
+// LookupTXT returns the TXT records of a name
+func (r *DoHResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	data, err := r.query(ctx, name, dohTypeTXT)
+	if r.failed(ctx, err) {
+		return r.fallback().LookupTXT(ctx, name)
+	}
+	var records []string
+	for _, value := range data {
+		// Long records are split into quoted strings, e.g. "v=spf1 " "-all"
+		records = append(records, strings.ReplaceAll(strings.Trim(value, `"`), `" "`, ""))
+	}
+	return records, err
+}
+
+// LookupCNAME returns the canonical name of a host, the host itself when it has no
+// CNAME record, like net.Resolver
+func (r *DoHResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	data, err := r.query(ctx, host, dohTypeCNAME)
+	if r.failed(ctx, err) {
+		return r.fallback().LookupCNAME(ctx, host)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(data) > 0 {
+		return data[0], nil
+	}
+	return strings.TrimSuffix(host, ".") + ".", nil
+}
+
+// query asks the server for the records of one type and returns their data. A
+// nonexistent name yields a not-found *net.DNSError, a failed query errDoHFailed.
+func (r *DoHResolver) query(ctx context.Context, name string, recordType int) ([]string, error) {
+	params := url.Values{"name": {name}, "type": {strconv.Itoa(recordType)}}
+	endpoint := r.URL + "?" + params.Encode()
+	if strings.Contains(r.URL, "?") {
+		endpoint = r.URL + "&" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDoHFailed, err)
+	}
+	req.Header.Set("Accept", "application/dns-json")
+
+	client := r.Client
+	if client == nil {
+		client = HTTPClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDoHFailed, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s answered %s", errDoHFailed, r.URL, resp.Status)
+	}
+
+	var answer dohResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDoHResponse)).Decode(&answer); err != nil {
+		return nil, fmt.Errorf("%w: %v", errDoHFailed, err)
+	}
+	switch answer.Status {
+	case dohNoError:
+	case dohNXDomain:
+		return nil, &net.DNSError{Err: "no such host", Name: name, Server: r.URL, IsNotFound: true}
+	default:
+		return nil, fmt.Errorf("%w: response code %d", errDoHFailed, answer.Status)
+	}
+
+	// The answer also holds the CNAME chain leading to the records asked for
+	var data []string
+	for _, record := range answer.Answer {
+		if record.Type == recordType {
+			data = append(data, record.Data)
+		}
+	}
+	return data, nil
+}
+
+// failed reports whether a lookup goes to the fallback resolver: the server failed
+// and the lookup was not cancelled
+func (r *DoHResolver) failed(ctx context.Context, err error) bool {
+	return errors.Is(err, errDoHFailed) && ctx.Err() == nil
+}
+
+// fallback returns the resolver of the lookups the server fails
+func (r *DoHResolver) fallback() Resolver {
+	if r.Fallback != nil {
+		return r.Fallback
+	}
+	return net.DefaultResolver
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"domain-scanner/internal/types"
+)
+
+// Defaults used when no config is loaded, matching the config file defaults
+const (
+	defaultDialTimeout           = 5 * time.Second
+	defaultTLSHandshakeTimeout   = 5 * time.Second
+	defaultResponseHeaderTimeout = 10 * time.Second
+	defaultRequestTimeout        = 15 * time.Second
+	defaultKeepAlive             = 30 * time.Second
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultMaxIdleConns          = 100
+	defaultMaxIdleConnsPerHost   = 10
+)
+
+// sharedHTTP holds the HTTP client shared by all HTTP-based checks of the process
+var sharedHTTP struct {
+	sync.Mutex
+	client *http.Client
+}
+
+// HTTPClient returns the shared HTTP client configured from [scanner.http].
+// Reusing one client keeps idle connections bounded and lets keep-alive work
+// across millions of requests; the timeouts stop slow hosts from stalling workers.
+func HTTPClient() *http.Client {
+	sharedHTTP.Lock()
+	defer sharedHTTP.Unlock()
+	if sharedHTTP.client == nil {
+		sharedHTTP.client = newHTTPClient(globalConfig)
+	}
+	return sharedHTTP.client
+}
+
+// httpClient returns the HTTP client of a checker, the shared one unless it has its own
+func (c *Checker) httpClient() *http.Client {
+	if c.http != nil {
+		return c.http
+	}
+	return HTTPClient()
+}
+
+// HTTPClient returns the HTTP client of the checker's RDAP and HTTP checks
+func (c *Checker) HTTPClient() *http.Client {
+	return c.httpClient()
+}
+
+// resetHTTPClient drops the shared client so the next call rebuilds it from the current config
+func resetHTTPClient() {
+	sharedHTTP.Lock()
+	defer sharedHTTP.Unlock()
+	if sharedHTTP.client != nil {
+		sharedHTTP.client.CloseIdleConnections()
+		sharedHTTP.client = nil
+	}
+}
+
+// newHTTPClient builds a client from the [scanner.http] section of a config, falling
+// back to the defaults for a nil config
+func newHTTPClient(config *types.Config) *http.Client {
+	dialTimeout := defaultDialTimeout
+	tlsTimeout := defaultTLSHandshakeTimeout
+	headerTimeout := defaultResponseHeaderTimeout
+	requestTimeout := defaultRequestTimeout
+	keepAlive := defaultKeepAlive
+	idleTimeout := defaultIdleConnTimeout
+	maxIdle := defaultMaxIdleConns
+	maxIdlePerHost := defaultMaxIdleConnsPerHost
+	disableKeepAlives := false
+
+	if config != nil {
+		cfg := config.Scanner.HTTP
+		dialTimeout = millisOr(cfg.DialTimeout, dialTimeout)
+		tlsTimeout = millisOr(cfg.TLSHandshakeTimeout, tlsTimeout)
+		headerTimeout = millisOr(cfg.ResponseHeaderTimeout, headerTimeout)
+		requestTimeout = millisOr(cfg.RequestTimeout, requestTimeout)
+		keepAlive = millisOr(cfg.KeepAlive, keepAlive)
+		idleTimeout = millisOr(cfg.IdleConnTimeout, idleTimeout)
+		if cfg.MaxIdleConns > 0 {
+			maxIdle = cfg.MaxIdleConns
+		}
+		if cfg.MaxIdleConnsPerHost > 0 {
+			maxIdlePerHost = cfg.MaxIdleConnsPerHost
+		}
+		disableKeepAlives = cfg.DisableKeepAlives
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: keepAlive,
+		}).DialContext,
+		TLSHandshakeTimeout:   tlsTimeout,
+		ResponseHeaderTimeout: headerTimeout,
+		IdleConnTimeout:       idleTimeout,
+		MaxIdleConns:          maxIdle,
+		MaxIdleConnsPerHost:   maxIdlePerHost,
+		DisableKeepAlives:     disableKeepAlives,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	return &http.Client{Transport: transport, Timeout: requestTimeout}
+}
+
+// millisOr converts a positive millisecond setting to a duration, or returns the fallback
+func millisOr(ms int, fallback time.Duration) time.Duration {
+	if ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return fallback
+}
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// UnicodeOf returns the Unicode form of an internationalized domain given in its ASCII
+// form, e.g. "bär.de" for "xn--br-via.de"; empty for domains without punycode labels
+// and for invalid ones
+func UnicodeOf(domain string) string {
+	if !strings.Contains(strings.ToLower(domain), "xn--") {
+		return ""
+	}
+	unicodeName, err := idna.Lookup.ToUnicode(domain)
+	if err != nil || unicodeName == domain {
+		return ""
+	}
+	return unicodeName
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// logOutput receives the checker's log lines such as special status and WHOIS conflicts
+var logOutput struct {
+	sync.Mutex
+	w io.Writer
+}
+
+// SetLogOutput redirects the checker's log lines; nil restores os.Stdout
+func SetLogOutput(w io.Writer) {
+	logOutput.Lock()
+	defer logOutput.Unlock()
+	logOutput.w = w
+}
+
+// logf writes a line to the checker log
+func logf(format string, args ...interface{}) {
+	logOutput.Lock()
+	defer logOutput.Unlock()
+	w := logOutput.w
+	if w == nil {
+		w = os.Stdout
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
+// debugf writes a trace line about a domain to the checker log when debugging is on
+func (c *Checker) debugf(domain, format string, args ...interface{}) {
+	if !c.opts.Debug {
+		return
+	}
+	c.logf("DEBUG "+domain+": "+format+"\n", args...)
+}
@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"strings"
+)
+
+// WHOIS indicators are matched line by line instead of anywhere in the response, so
+// that registrar remarks, referral text or name server names cannot flip a verdict:
+//
+//   - An indicator containing a colon, such as "registrar:" or "status: ok", matches a
+//     "key: value" line. The line's key must equal the indicator's key or end with it
+//     as a word ("domain status" for "status"), and the value must start with the
+//     indicator's value, or be non-empty when the indicator has none. Registries such
+//     as SWITCH (.ch, .li) put the value on the line after its key ("Registrar:" and
+//     then the registrar's name), so a key line without a value takes the next line
+//     as its value unless that line is a field or a comment. Comment lines starting
+//     with % or # never match.
+//   - Any other indicator is a phrase that must start a line, after comment markers
+//     such as "%%" or ">>>" are stripped. A line starting with such an availability
+//     phrase is a sentence even when it contains a colon, so that the "domain:" of
+//     "No matching record for domain: example.com" is no registered indicator.
+//   - The phrases of freeTextPhrases may also appear inside a free text line, one that
+//     is not a "key: value" line, and those of lineEndPhrases may end one. Registries
+//     phrase these answers as sentences, e.g. `domain "example.se" not found.`
+var (
+	freeTextPhrases = map[string]bool{
+		"available for registration": true,
+		"this domain is available":   true,
+		"domain is available":        true,
+		"domain reserved":            true,
+		"this domain is reserved":    true,
+		"reserved domain":            true,
+		"redemptionperiod":           true,
+		"redemption period":          true,
+	}
+	lineEndPhrases = map[string]bool{
+		"not found": true,
+	}
+)
+
+// maxFieldKey bounds the key of a "key: value" line; longer prefixes are sentences
+const maxFieldKey = 40
+
+// whoisLine is a lower-cased, whitespace-collapsed line of a WHOIS response
+type whoisLine struct {
+	text string
+	// key and value are set for "key: value" lines
+	key, value string
+	field      bool
+	comment    bool
+}
+
+// parseWHOISLines splits a WHOIS response into normalized lines
+func parseWHOISLines(raw string) []whoisLine {
+	var lines []whoisLine
+	for _, text := range strings.Split(strings.ToLower(raw), "\n") {
+		text = strings.Join(strings.Fields(text), " ")
+		if text == "" {
+			continue
+		}
+		line := whoisLine{text: text, comment: text[0] == '%' || text[0] == '#'}
+		if idx := strings.Index(text, ":"); idx > 0 && !line.comment && !startsWithPhrase(text, availableIndicators) {
+			key := strings.TrimRight(text[:idx], ". ")
+			if key != "" && len(key) <= maxFieldKey && !strings.HasPrefix(text[idx:], "://") {
+				line.key, line.value, line.field = key, strings.TrimSpace(text[idx+1:]), true
+			}
+		}
+		lines = append(lines, line)
+	}
+	// Blank lines were dropped, so the value of a key on a line of its own is the next line
+	for i := 0; i+1 < len(lines); i++ {
+		if next := lines[i+1]; lines[i].field && lines[i].value == "" && !next.field && !next.comment {
+			lines[i].value = next.text
+		}
+	}
+	return lines
+}
+
+// startsWithPhrase reports whether a line starts with one of the indicators that are
+// phrases rather than "key: value" fields
+func startsWithPhrase(text string, indicators []string) bool {
+	for _, indicator := range indicators {
+		if !strings.Contains(indicator, ":") && strings.HasPrefix(text, indicator) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchIndicators returns every indicator found in the parsed response
+func matchIndicators(lines []whoisLine, indicators []string) []string {
+	var matched []string
+	for _, indicator := range indicators {
+		for _, line := range lines {
+			if line.matches(indicator) {
+				matched = append(matched, indicator)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// matches reports whether the line matches an indicator by the rules above
+func (l whoisLine) matches(indicator string) bool {
+	if idx := strings.Index(indicator, ":"); idx >= 0 {
+		if !l.field {
+			return false
+		}
+		key, value := indicator[:idx], strings.TrimSpace(indicator[idx+1:])
+		if l.key != key && !strings.HasSuffix(l.key, " "+key) {
+			return false
+		}
+		if value == "" {
+			return l.value != ""
+		}
+		return strings.HasPrefix(l.value, value)
+	}
+
+	if strings.HasPrefix(strings.TrimLeft(l.text, "%#>*: "), indicator) {
+		return true
+	}
+	if l.field {
+		return false
+	}
+	if freeTextPhrases[indicator] && strings.Contains(l.text, indicator) {
+		return true
+	}
+	return lineEndPhrases[indicator] && strings.HasSuffix(strings.TrimRight(l.text, ".!"), indicator)
+}
+
+// containsIndicators returns every indicator contained anywhere in a lower-cased text,
+// for messages such as rate limit notices and network errors that have no line structure
+func containsIndicators(text string, indicators []string) []string {
+	var matched []string
+	for _, indicator := range indicators {
+		if strings.Contains(text, indicator) {
+			matched = append(matched, indicator)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyWHOISTrickyTranscripts(t *testing.T) {
+	tests := []struct {
+		name string
+		tld  string
+		raw  string
+		want Status
+	}{
+		{
+			name: "remark containing not found",
+			tld:  "com",
+			raw: "Domain Name: EXAMPLE.COM\n" +
+				"Registrar: Example Registrar, Inc.\n" +
+				"Remarks: abuse reports not found here are forwarded to the registrar\n",
+			want: StatusRegistered,
+		},
+		{
+			name: "name server containing available",
+			tld:  "net",
+			raw: "Domain Name: EXAMPLE.NET\n" +
+				"Name Server: available.example-dns.net\n" +
+				"Name Server: ns2.example-dns.net\n",
+			want: StatusRegistered,
+		},
+		{
+			name: "domain label inside a not found sentence",
+			tld:  "com",
+			raw:  "No match for domain: \"EXAMPLE.COM\".\n>>> Last update of whois database: 2024-01-01T00:00:00Z <<<\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "no matching record sentence",
+			tld:  "io",
+			raw:  "No matching record for domain: example.io\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "referral text mentioning domain",
+			tld:  "com",
+			raw: "% For more information on domain: lookups visit https://www.example.org\n" +
+				"No match for \"EXAMPLE.COM\".\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "not found ending a sentence",
+			tld:  "se",
+			raw:  "domain \"example.se\" not found.\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "url value is no field",
+			tld:  "org",
+			raw:  "https://registrar.example.org/terms\nThis domain is available for registration.\n",
+			want: StatusAvailable,
+		},
+		{
+			name: "empty registrar value",
+			tld:  "xyz",
+			raw:  "Registrar:\n",
+			want: StatusUnknown,
+		},
+		{
+			name: "SWITCH values on the next line",
+			tld:  "li",
+			raw: "Domain name:\nnic.li\n\n" +
+				"Holder of domain name:\nSWITCH\nWerdstrasse 2\nCH-8004 Zürich\nSwitzerland\n\n" +
+				"Registrar:\nSWITCH Domain Name Registry\n\n" +
+				"First registration date:\n1998-11-13\n\n" +
+				"DNSSEC:Y\n\n" +
+				"Name servers:\nns1.nic.ch\nns2.nic.ch\n",
+			want: StatusRegistered,
+		},
+		{
+			name: "key line followed by a field",
+			tld:  "li",
+			raw:  "Registrar:\nDNSSEC:N\n",
+			want: StatusUnknown,
+		},
+		{
+			name: "key line followed by a comment",
+			tld:  "li",
+			raw:  "Registrar:\n% no registrar assigned\n",
+			want: StatusUnknown,
+		},
+	}
+
+	c := NewChecker(CheckerOptions{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, indicators := c.ClassifyWHOIS(tt.tld, tt.raw)
+			if got != tt.want {
+				t.Errorf("ClassifyWHOIS() = %s %v, want %s", got, indicators, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWHOISLines(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []whoisLine
+	}{
+		{
+			name: "field",
+			raw:  "Registrar:   Example   Registrar\n",
+			want: []whoisLine{{text: "registrar: example registrar", key: "registrar", value: "example registrar", field: true}},
+		},
+		{
+			name: "comment",
+			raw:  "% Registrar: none\n",
+			want: []whoisLine{{text: "% registrar: none", comment: true}},
+		},
+		{
+			name: "value on the next non-blank line",
+			raw:  "Registrar:\n\n  SWITCH Domain Name Registry\n",
+			want: []whoisLine{
+				{text: "registrar:", key: "registrar", value: "switch domain name registry", field: true},
+				{text: "switch domain name registry"},
+			},
+		},
+		{
+			name: "availability sentence with a colon",
+			raw:  "No match for domain: example.com\n",
+			want: []whoisLine{{text: "no match for domain: example.com"}},
+		},
+		{
+			name: "url",
+			raw:  "https://example.com\n",
+			want: []whoisLine{{text: "https://example.com"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseWHOISLines(tt.raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseWHOISLines() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,451 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/likexian/whois"
+
+	"domain-scanner/internal/metrics"
+	"domain-scanner/internal/types"
+)
+
+// Timeouts used when the checker options leave them unset
+const (
+	defaultSSLTimeout     = 5 * time.Second
+	defaultHTTPTimeout    = 5 * time.Second
+	defaultTerseThreshold = 64
+)
+
+// CheckerOptions configure a Checker. Zero values select the defaults of the config file.
+type CheckerOptions struct {
+	// DNSCheck, RDAPCheck, WHOISCheck, SSLCheck and HTTPCheck enable the built-in
+	// methods; when all of them are false, DNS, WHOIS and SSL are enabled
+	DNSCheck   bool
+	RDAPCheck  bool
+	WHOISCheck bool
+	SSLCheck   bool
+	HTTPCheck  bool
+
+	// DNSTimeout limits the DNS lookups of a domain; zero relies on the context only
+	DNSTimeout time.Duration
+	// WHOISTimeout limits a single WHOIS query; zero uses the WHOIS client default
+	WHOISTimeout time.Duration
+	// SSLTimeout limits the TLS handshake; zero means 5 seconds
+	SSLTimeout time.Duration
+	// HTTPTimeout limits the HTTP check of a domain, redirects included; zero means 5 seconds
+	HTTPTimeout time.Duration
+
+	// WHOISServers maps a suffix such as "li", ".de" or "co.uk" to the WHOIS server
+	// queried for its domains; the longest matching suffix wins
+	WHOISServers map[string]string
+	// RDAPServers maps a suffix like WHOISServers to the RDAP base URL queried for its domains;
+	// other TLDs are looked up in the bootstrap registry at RDAPBootstrap (default
+	// DefaultRDAPBootstrap)
+	RDAPServers   map[string]string
+	RDAPBootstrap string
+	// WHOISInterval is the minimum time between two WHOIS queries of this checker to the same server
+	WHOISInterval time.Duration
+	// RateLimits maps a suffix like WHOISServers to the WHOIS queries per minute allowed
+	// for its domains, shared by all workers of the checker. Workers block on the token
+	// bucket of the suffix instead of waiting for the pacer of the scan.
+	RateLimits map[string]int
+
+	// TerseTLDs answer unregistered names with a response shorter than TerseThreshold
+	TerseTLDs      []string
+	TerseThreshold int
+	// WildcardDNS maps a TLD with wildcard DNS to its A-record policy (types.WildcardA*)
+	WildcardDNS map[string]string
+	// WHOISConflict decides contradictory WHOIS responses (types.Conflict*)
+	WHOISConflict string
+
+	// CheckOrder orders the enabled built-in methods (types.CheckDNS, CheckWHOIS and
+	// CheckSSL); methods left out run after the listed ones, in that default order.
+	// Unless Exhaustive is set, a check stops as soon as the methods run so far
+	// decide the domain and skips the rest, custom methods included.
+	CheckOrder []string
+	Exhaustive bool
+	// WHOISAlways queries WHOIS even for domains the methods before it decided, so that
+	// every registered domain comes with its WHOIS record; the other methods are still
+	// skipped
+	WHOISAlways bool
+
+	// Strict reports a domain available only when WHOIS answered with an explicit
+	// availability indicator such as "no match" or "status: free". Domains that would
+	// be available for lack of registration data, e.g. when WHOIS failed or a terse
+	// TLD answered briefly, get NoEvidenceStatus instead.
+	Strict bool
+
+	// Custom adds check methods by name, see RegisterChecker for their semantics
+	Custom map[string]CustomCheckFunc
+
+	// Resolver answers the lookups of the DNS method; nil uses net.DefaultResolver
+	Resolver Resolver
+
+	// Metrics receives the latency of every check method; nil disables it
+	Metrics metrics.Exporter
+
+	// Log receives special status, WHOIS conflict and custom method messages; nil discards them
+	Log io.Writer
+	// Debug adds a trace of how every domain was decided to Log
+	Debug bool
+}
+
+// Resolver looks up the records checked by the DNS method; *net.Resolver implements it
+type Resolver interface {
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+// Result is the outcome of checking a domain
+type Result struct {
+	Domain     string
+	Available  bool
+	Signatures []string
+	// SpecialStatus is set for domains that need manual review, e.g. REDEMPTIONPERIOD,
+	// WHOIS_RATE_LIMITED or WHOIS_CONFLICT; such domains are never reported available
+	SpecialStatus string
+	// WHOIS is the raw WHOIS response fetched during the check; empty when WHOIS was not queried
+	WHOIS string
+	// ExpiryDate is the expiration date of a registered domain given by its WHOIS
+	// response; zero when WHOIS was not queried or gave none
+	ExpiryDate time.Time
+	// Skipped is the number of methods left out because earlier ones decided the domain
+	Skipped int
+	// Elapsed is the time the check took and Phases the time spent in each method,
+	// keyed by types.CheckDNS, CheckWHOIS (retries and backoff included), CheckSSL
+	// and PhaseCustom
+	Elapsed time.Duration
+	Phases  map[string]time.Duration
+}
+
+// Checker checks domains with its own options, rate limiter and WHOIS client and
+// shares no state with other checkers
+type Checker struct {
+	opts    CheckerOptions
+	order   []string
+	limiter *rateLimiter
+	rates   *suffixLimiter
+	whois   *whois.Client
+	dns     Resolver
+	// http serves the RDAP and HTTP checks; nil uses the shared HTTPClient
+	http *http.Client
+	// registry makes the checker include the methods added through RegisterChecker
+	registry bool
+	metrics  metrics.Exporter
+	logf     func(format string, args ...interface{})
+}
+
+// NewChecker creates a checker from its options
+func NewChecker(opts CheckerOptions) *Checker {
+	if !opts.DNSCheck && !opts.RDAPCheck && !opts.WHOISCheck && !opts.SSLCheck && !opts.HTTPCheck {
+		opts.DNSCheck, opts.WHOISCheck, opts.SSLCheck = true, true, true
+	}
+	if opts.SSLTimeout <= 0 {
+		opts.SSLTimeout = defaultSSLTimeout
+	}
+	if opts.HTTPTimeout <= 0 {
+		opts.HTTPTimeout = defaultHTTPTimeout
+	}
+	if opts.TerseThreshold <= 0 {
+		opts.TerseThreshold = defaultTerseThreshold
+	}
+
+	opts.WHOISServers = normalizeServers(opts.WHOISServers)
+	opts.RDAPServers = normalizeServers(opts.RDAPServers)
+	opts.RateLimits = normalizeRateLimits(opts.RateLimits)
+
+	client := whois.NewClient()
+	if opts.WHOISTimeout > 0 {
+		client.SetTimeout(opts.WHOISTimeout)
+	}
+	c := &Checker{
+		opts:    opts,
+		order:   checkOrder(opts),
+		limiter: &rateLimiter{interval: opts.WHOISInterval},
+		rates:   &suffixLimiter{},
+		whois:   client,
+		dns:     opts.Resolver,
+		metrics: opts.Metrics,
+		logf:    func(string, ...interface{}) {},
+	}
+	if c.dns == nil {
+		c.dns = net.DefaultResolver
+	}
+	if c.metrics == nil {
+		c.metrics = metrics.Nop{}
+	}
+	if opts.Log != nil {
+		c.logf = func(format string, args ...interface{}) {
+			fmt.Fprintf(opts.Log, format, args...)
+		}
+	}
+	return c
+}
+
+// Check runs every enabled method once and decides whether the domain is available
+func (c *Checker) Check(ctx context.Context, domain string) (Result, error) {
+	started := time.Now()
+	pass, err := c.checkSignatures(ctx, domain)
+	result := Result{Domain: domain, Signatures: pass.signatures, Skipped: pass.skipped, Phases: pass.phases}
+	if err == nil {
+		result.Available, result.SpecialStatus, err = c.decideAvailability(ctx, domain, &pass)
+	}
+	result.WHOIS = pass.lookup.raw
+	if !result.Available {
+		result.ExpiryDate = expiryDate(domain, result.WHOIS)
+	}
+	result.Elapsed = time.Since(started)
+	if err != nil {
+		return result, err
+	}
+	if result.SpecialStatus != "" {
+		c.logf("SPECIAL STATUS: %s - %s\n", domain, result.SpecialStatus)
+	}
+	return result, err
+}
+
+// checkOrder returns the enabled built-in methods in the configured order
+func checkOrder(opts CheckerOptions) []string {
+	enabled := map[string]bool{
+		types.CheckDNS:   opts.DNSCheck,
+		types.CheckRDAP:  opts.RDAPCheck,
+		types.CheckWHOIS: opts.WHOISCheck,
+		types.CheckSSL:   opts.SSLCheck,
+		types.CheckHTTP:  opts.HTTPCheck,
+	}
+	var order []string
+	for _, method := range append(append([]string(nil), opts.CheckOrder...),
+		types.CheckDNS, types.CheckRDAP, types.CheckWHOIS, types.CheckSSL, types.CheckHTTP) {
+		method = strings.ToLower(method)
+		if enabled[method] {
+			order = append(order, method)
+			enabled[method] = false
+		}
+	}
+	return order
+}
+
+// observe records the latency of a check method started at the given time
+func (c *Checker) observe(method string, started time.Time) {
+	c.metrics.Observe(metrics.MethodLatency, time.Since(started), metrics.Labels{"method": method})
+}
+
+// whoisServer returns the configured WHOIS server for the domain's suffix, if any
+func (c *Checker) whoisServer(domain string) string {
+	return suffixServer(c.opts.WHOISServers, domain)
+}
+
+// normalizeServers copies a per-suffix server table with lower-case keys without
+// leading or trailing dots, so that ".DE" and "de" both configure .de
+func normalizeServers(servers map[string]string) map[string]string {
+	if len(servers) == 0 {
+		return nil
+	}
+	normalized := make(map[string]string, len(servers))
+	for suffix, server := range servers {
+		normalized[strings.Trim(strings.ToLower(strings.TrimSpace(suffix)), ".")] = strings.TrimSpace(server)
+	}
+	return normalized
+}
+
+// suffixServer returns the server of the longest suffix of a domain listed in a
+// normalized server table: "co.uk" is preferred over "uk" for example.co.uk
+func suffixServer(servers map[string]string, domain string) string {
+	if len(servers) == 0 {
+		return ""
+	}
+	name := strings.ToLower(strings.TrimSuffix(domain, "."))
+	for {
+		idx := strings.Index(name, ".")
+		if idx < 0 {
+			return ""
+		}
+		name = name[idx+1:]
+		if server := servers[name]; server != "" {
+			return server
+		}
+	}
+}
+
+// whoisKey names the WHOIS server the queries for a domain go to: the configured
+// server, or else the TLD, whose registry server the WHOIS client looks up
+func (c *Checker) whoisKey(domain string) string {
+	if server := c.whoisServer(domain); server != "" {
+		return server
+	}
+	return "." + tldOf(domain)
+}
+
+// normalizeRateLimits copies a per-suffix rate limit table with keys normalized like
+// normalizeServers, dropping limits that are not positive
+func normalizeRateLimits(limits map[string]int) map[string]int {
+	normalized := make(map[string]int, len(limits))
+	for suffix, perMinute := range limits {
+		if perMinute > 0 {
+			normalized[strings.Trim(strings.ToLower(strings.TrimSpace(suffix)), ".")] = perMinute
+		}
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
+
+// rateLimit returns the longest suffix of a domain with a configured rate limit and
+// its queries per minute; the suffix is empty when none applies
+func (c *Checker) rateLimit(domain string) (string, int) {
+	if len(c.opts.RateLimits) == 0 {
+		return "", 0
+	}
+	name := strings.ToLower(strings.TrimSuffix(domain, "."))
+	for {
+		idx := strings.Index(name, ".")
+		if idx < 0 {
+			return "", 0
+		}
+		name = name[idx+1:]
+		if perMinute := c.opts.RateLimits[name]; perMinute > 0 {
+			return name, perMinute
+		}
+	}
+}
+
+// tldOf returns the lower-case last label of a domain
+func tldOf(domain string) string {
+	tld := strings.TrimSuffix(domain, ".")
+	if idx := strings.LastIndex(tld, "."); idx >= 0 {
+		tld = tld[idx+1:]
+	}
+	return strings.ToLower(tld)
+}
+
+// CheckerOptionsFromConfig converts the [scanner] section of a config into checker
+// options; a nil config enables DNS, WHOIS and SSL with the default policies
+func CheckerOptionsFromConfig(cfg *types.Config) CheckerOptions {
+	if cfg == nil {
+		return CheckerOptions{DNSCheck: true, WHOISCheck: true, SSLCheck: true}
+	}
+	opts := CheckerOptions{
+		DNSCheck:       cfg.Scanner.Methods.DNSCheck,
+		WHOISCheck:     cfg.Scanner.Methods.WHOISCheck,
+		SSLCheck:       cfg.Scanner.Methods.SSLCheck,
+		HTTPCheck:      cfg.Scanner.Methods.HTTPCheck,
+		RDAPCheck:      cfg.Scanner.Methods.RDAPCheck,
+		RDAPServers:    cfg.Scanner.RDAPServers,
+		RDAPBootstrap:  cfg.Scanner.RDAPBootstrap,
+		WHOISTimeout:   time.Duration(cfg.Scanner.WHOISTimeout) * time.Millisecond,
+		WHOISServers:   cfg.Scanner.WHOISServers,
+		RateLimits:     cfg.Scanner.RateLimits,
+		TerseTLDs:      cfg.Scanner.TerseTLDs,
+		TerseThreshold: cfg.Scanner.TerseThreshold,
+		WildcardDNS:    cfg.Scanner.WildcardDNS,
+		WHOISConflict:  cfg.Scanner.WHOISConflict,
+		CheckOrder:     cfg.Scanner.CheckOrder,
+		Exhaustive:     cfg.Scanner.Exhaustive,
+		Strict:         cfg.Scanner.Strict,
+		Debug:          cfg.Scanner.Debug,
+		// The WHOIS records written to whois_json_file would otherwise miss every
+		// domain that DNS decided
+		WHOISAlways: cfg.Output.WHOISJSONFile != "",
+	}
+	if cfg.Scanner.DNS.DoHURL != "" {
+		opts.Resolver = &DoHResolver{URL: cfg.Scanner.DNS.DoHURL}
+	}
+	if custom := cfg.Scanner.Methods.Custom; custom.Command != "" {
+		opts.Custom = map[string]CustomCheckFunc{
+			custom.Name: CommandChecker(custom.Command, time.Duration(custom.Timeout)*time.Millisecond),
+		}
+	}
+	return opts
+}
+
+// NewConfigChecker creates a checker from the [scanner] section of a config, with the
+// methods added through RegisterChecker and an HTTP client of its own configured by
+// [scanner.http]. Unlike the checker configured by SetConfig it shares no state with
+// other checkers: its WHOIS limiter and per-suffix rate limits count only its own
+// queries, and its log lines are discarded until WithLog gives them a writer. A nil
+// exporter disables the metrics.
+func NewConfigChecker(cfg *types.Config, exporter metrics.Exporter) *Checker {
+	opts := CheckerOptionsFromConfig(cfg)
+	opts.Metrics = exporter
+	c := NewChecker(opts)
+	c.registry = true
+	c.http = newHTTPClient(cfg)
+	if doh, ok := c.dns.(*DoHResolver); ok {
+		doh.Client = c.http
+	}
+	return c
+}
+
+// WithLog returns a checker writing its log lines, such as special statuses and WHOIS
+// conflicts, to w; nil discards them. It shares the WHOIS limiter, rate limits, clients
+// and metrics of c, so that scans logging to different writers count toward the same
+// limits.
+func (c *Checker) WithLog(w io.Writer) *Checker {
+	logged := *c
+	logged.logf = func(string, ...interface{}) {}
+	if w != nil {
+		logged.logf = func(format string, args ...interface{}) {
+			fmt.Fprintf(w, format, args...)
+		}
+	}
+	return &logged
+}
+
+// newConfigChecker creates the checker of a config using the shared HTTP client, WHOIS
+// limiter, per-suffix rate limits and log
+func newConfigChecker(cfg *types.Config, exporter metrics.Exporter) *Checker {
+	opts := CheckerOptionsFromConfig(cfg)
+	opts.Metrics = exporter
+	c := NewChecker(opts)
+	c.limiter = whoisLimiter
+	c.rates = suffixLimits
+	c.registry = true
+	c.logf = logf
+	return c
+}
+
+// defaultCheck holds the checker behind the package-level functions, built from the
+// config set by SetConfig. It shares the process-wide WHOIS limiter, per-suffix rate
+// limits and log.
+var defaultCheck struct {
+	sync.Mutex
+	checker *Checker
+	metrics metrics.Exporter
+}
+
+// defaultChecker returns the checker configured by SetConfig
+func defaultChecker() *Checker {
+	defaultCheck.Lock()
+	defer defaultCheck.Unlock()
+	if defaultCheck.checker == nil {
+		defaultCheck.checker = newConfigChecker(globalConfig, defaultCheck.metrics)
+	}
+	return defaultCheck.checker
+}
+
+// SetMetrics sets the exporter receiving the method latencies of the checker
+// configured by SetConfig; nil disables them
+func SetMetrics(exporter metrics.Exporter) {
+	defaultCheck.Lock()
+	defer defaultCheck.Unlock()
+	defaultCheck.metrics = exporter
+	defaultCheck.checker = nil
+}
+
+// resetDefaultChecker drops the default checker so the next use rebuilds it from the current config
+func resetDefaultChecker() {
+	defaultCheck.Lock()
+	defer defaultCheck.Unlock()
+	defaultCheck.checker = nil
+}
@@ -0,0 +1,290 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"domain-scanner/internal/metrics"
+)
+
+// rateLimiter spaces queries issued by concurrent workers to the same server; queries
+// to different servers do not wait for each other
+type rateLimiter struct {
+	sync.Mutex
+	interval time.Duration
+	// next is the earliest time of the next query per server
+	next map[string]time.Time
+}
+
+// limiterNow and limiterSleep are the clock of the limiters; tests replace them with a fake one
+var (
+	limiterNow   = time.Now
+	limiterSleep = sleepContext
+)
+
+// whoisLimiter spaces WHOIS queries issued by all workers of the process that use
+// the checker configured by SetConfig
+var whoisLimiter = &rateLimiter{}
+
+// SetWHOISInterval sets the minimum interval between two WHOIS queries to the same
+// server across all workers and scans running in this process. Zero disables the limit.
+func SetWHOISInterval(interval time.Duration) {
+	whoisLimiter.setInterval(interval)
+}
+
+// setInterval changes the minimum interval between two queries; zero disables the limit
+func (l *rateLimiter) setInterval(interval time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	l.interval = interval
+}
+
+// wait blocks until the limiter allows the next query to the server
+func (l *rateLimiter) wait(ctx context.Context, server string) error {
+	l.Lock()
+	if l.interval <= 0 {
+		l.Unlock()
+		return ctx.Err()
+	}
+	if l.next == nil {
+		l.next = make(map[string]time.Time)
+	}
+	now := limiterNow()
+	slot := l.next[server]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[server] = slot.Add(l.interval)
+	l.Unlock()
+
+	return limiterSleep(ctx, slot.Sub(now))
+}
+
+// tokenBucket allows a steady number of queries per minute with bursts of up to a
+// second's worth of queries
+type tokenBucket struct {
+	perMinute int
+	tokens    float64
+	last      time.Time
+}
+
+// newTokenBucket creates a full bucket for the given number of queries per minute
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	b := &tokenBucket{perMinute: perMinute, last: now}
+	b.tokens = b.capacity()
+	return b
+}
+
+// capacity is the largest burst of the bucket, at least one query
+func (b *tokenBucket) capacity() float64 {
+	return math.Max(1, float64(b.perMinute)/60)
+}
+
+// reserve takes a token and returns how long the query has to wait for it. Tokens
+// may go negative, so that queries waiting together get consecutive slots.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	perSecond := float64(b.perMinute) / 60
+	b.tokens = math.Min(b.capacity(), b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / perSecond * float64(time.Second))
+}
+
+// suffixLimiter holds the token buckets of the suffixes with a configured rate limit,
+// shared by the workers checking their domains
+type suffixLimiter struct {
+	sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// suffixLimits holds the buckets of the checkers configured by SetConfig and
+// NewConfigChecker, so that every scan of the process counts toward the same limits
+var suffixLimits = &suffixLimiter{}
+
+// wait blocks until the bucket of the suffix has a token for the next query; a
+// cancelled wait gives its token back
+func (l *suffixLimiter) wait(ctx context.Context, suffix string, perMinute int) error {
+	l.Lock()
+	if l.buckets == nil {
+		l.buckets = make(map[string]*tokenBucket)
+	}
+	now := limiterNow()
+	bucket := l.buckets[suffix]
+	if bucket == nil || bucket.perMinute != perMinute {
+		bucket = newTokenBucket(perMinute, now)
+		l.buckets[suffix] = bucket
+	}
+	delay := bucket.reserve(now)
+	l.Unlock()
+
+	if err := limiterSleep(ctx, delay); err != nil {
+		l.Lock()
+		bucket.tokens++
+		l.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Pacer spaces the checks of domains whose WHOIS queries go to the same server. It
+// waits before a check rather than after it, so that workers starting together are
+// staggered, and domains of unrelated registries do not wait for each other. Domains
+// of suffixes with a configured rate limit are not paced; their WHOIS queries wait for
+// the token bucket of the suffix instead.
+type Pacer struct {
+	limiter *rateLimiter
+	// checker gives the WHOIS server and rate limit of a domain
+	checker *Checker
+}
+
+// NewPacer creates a pacer allowing one check per interval and WHOIS server of the
+// checker; a zero interval never waits. A nil checker uses the one configured by
+// SetConfig at the time of the call.
+func NewPacer(interval time.Duration, c *Checker) *Pacer {
+	if c == nil {
+		c = defaultChecker()
+	}
+	return &Pacer{limiter: &rateLimiter{interval: interval}, checker: c}
+}
+
+// Wait blocks until a check of the domain is due or ctx is cancelled; a nil pacer
+// never waits
+func (p *Pacer) Wait(ctx context.Context, domain string) error {
+	if p == nil {
+		return ctx.Err()
+	}
+	if suffix, _ := p.checker.rateLimit(domain); suffix != "" {
+		return ctx.Err()
+	}
+	return p.limiter.wait(ctx, p.checker.whoisKey(domain))
+}
+
+// queryWHOIS performs a WHOIS lookup once the checker's limiter and the rate limit of
+// the domain's suffix allow it, asking the configured server for the domain's TLD if there is one. The WHOIS client takes no
+// context, so cancelling ctx returns at once and leaves the query to its timeout.
+func (c *Checker) queryWHOIS(ctx context.Context, domain string) (string, error) {
+	if err := c.limiter.wait(ctx, c.whoisKey(domain)); err != nil {
+		return "", err
+	}
+	if suffix, perMinute := c.rateLimit(domain); suffix != "" {
+		if err := c.rates.wait(ctx, suffix, perMinute); err != nil {
+			return "", err
+		}
+	}
+	defer c.observe("whois", time.Now())
+	type answer struct {
+		raw string
+		err error
+	}
+	done := make(chan answer, 1)
+	go func() {
+		var a answer
+		if server := c.whoisServer(domain); server != "" {
+			a.raw, a.err = c.whois.Whois(domain, server)
+		} else {
+			a.raw, a.err = c.whois.Whois(domain)
+		}
+		done <- a
+	}()
+	select {
+	case a := <-done:
+		return a.raw, a.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// whoisAttempts bounds the WHOIS queries of one lookup
+const whoisAttempts = 5
+
+// WHOIS retry waits shared by every lookup of a check; tests shorten them
+var (
+	// whoisRateLimitDelay is the first wait after a throttled query; it doubles on every
+	// further throttled attempt (2s, 4s, 8s, 16s)
+	whoisRateLimitDelay = 2 * time.Second
+	// whoisErrorDelay is the wait after the first failed query, growing linearly
+	whoisErrorDelay = time.Second
+)
+
+// errWHOISRateLimited is returned by queryWhoisWithRetry when the last attempt was throttled
+var errWHOISRateLimited = errors.New("WHOIS rate limited")
+
+// whoisLookup is the outcome of queryWhoisWithRetry, kept so that a check queries WHOIS once
+type whoisLookup struct {
+	raw  string
+	err  error
+	done bool
+}
+
+// lookupWHOIS runs queryWhoisWithRetry and records its outcome
+func (c *Checker) lookupWHOIS(ctx context.Context, domain string) whoisLookup {
+	raw, err := c.queryWhoisWithRetry(ctx, domain)
+	return whoisLookup{raw: raw, err: err, done: true}
+}
+
+// queryWhoisWithRetry queries WHOIS until it gets an answer that is not a rate limit
+// notice, at most whoisAttempts times. Throttled attempts, refused or answered with a
+// notice, back off exponentially; other failures are retried after a shorter wait. It
+// returns errWHOISRateLimited when the last attempt was throttled and ctx.Err() as soon
+// as ctx is cancelled.
+func (c *Checker) queryWhoisWithRetry(ctx context.Context, domain string) (string, error) {
+	var lastErr error
+	throttled := 0
+	for i := 0; i < whoisAttempts; i++ {
+		if i > 0 {
+			wait := whoisErrorDelay * time.Duration(i)
+			if lastErr == errWHOISRateLimited {
+				wait = whoisRateLimitDelay << uint(throttled-1)
+			}
+			if err := sleepContext(ctx, wait); err != nil {
+				return "", err
+			}
+		}
+
+		raw, err := c.queryWHOIS(ctx, domain)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if err == nil && !isRateLimitText(raw) {
+			return raw, nil
+		}
+		c.debugf(domain, "WHOIS attempt %d/%d failed: %v", i+1, whoisAttempts, err)
+		if err == nil || isRateLimitText(err.Error()) {
+			throttled++
+			lastErr = errWHOISRateLimited
+			c.metrics.Count(metrics.WHOISRateLimited, 1, nil)
+		} else {
+			lastErr = err
+			c.metrics.Count(metrics.WHOISErrors, 1, nil)
+		}
+	}
+	return "", lastErr
+}
+
+// isRateLimitText reports whether a WHOIS response or error message says the query was throttled
+func isRateLimitText(s string) bool {
+	return len(containsIndicators(strings.ToLower(s), rateLimitIndicators)) > 0
+}
+
+// sleepContext waits for the given duration or until ctx is cancelled,
+// whichever comes first, and returns ctx.Err() on cancellation
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
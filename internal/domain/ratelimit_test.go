@@ -0,0 +1,432 @@
+package domain
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"domain-scanner/internal/metrics"
+	"domain-scanner/internal/testutil"
+)
+
+func TestPacerUsesItsChecker(t *testing.T) {
+	const interval = 200 * time.Millisecond
+	c := NewChecker(CheckerOptions{
+		WHOISServers: map[string]string{"a": "whois.shared.test", "b": "whois.shared.test"},
+		RateLimits:   map[string]int{"limited": 60},
+	})
+
+	tests := []struct {
+		name     string
+		domains  []string
+		wantWait bool
+	}{
+		{name: "same server", domains: []string{"x.a", "y.b"}, wantWait: true},
+		{name: "different servers", domains: []string{"x.a", "x.c"}},
+		{name: "rate limited suffix", domains: []string{"x.limited", "y.limited"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPacer(interval, c)
+			started := time.Now()
+			for _, name := range tt.domains {
+				if err := p.Wait(context.Background(), name); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if waited := time.Since(started) >= interval; waited != tt.wantWait {
+				t.Errorf("waited %v, want a wait: %v", time.Since(started), tt.wantWait)
+			}
+		})
+	}
+}
+
+// fakeClock stands in for the clock of the limiters: it records every wait and only
+// moves when the test advances it
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+// useFakeClock makes the limiters read and wait on a fake clock for the rest of the test
+func useFakeClock(t *testing.T) *fakeClock {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	now, sleep := limiterNow, limiterSleep
+	limiterNow, limiterSleep = clock.Now, clock.Sleep
+	t.Cleanup(func() {
+		limiterNow, limiterSleep = now, sleep
+	})
+	return clock
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = append(c.waits, d)
+	return ctx.Err()
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Waits returns the recorded waits and forgets them
+func (c *fakeClock) Waits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waits := c.waits
+	c.waits = nil
+	return waits
+}
+
+func TestPacerSpacesTheFirstChecks(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	c := NewChecker(CheckerOptions{WHOISServers: map[string]string{"li": "whois.nic.ch", "ch": "whois.nic.ch"}})
+
+	tests := []struct {
+		name    string
+		domains []string
+		want    []time.Duration
+	}{
+		{name: "one server", domains: []string{"a.test", "b.test", "c.test", "d.test"}, want: []time.Duration{0, interval, 2 * interval, 3 * interval}},
+		{name: "unrelated servers", domains: []string{"a.test", "a.example", "b.test", "b.example"}, want: []time.Duration{0, 0, interval, interval}},
+		{name: "server shared by two suffixes", domains: []string{"a.li", "a.ch", "b.li", "b.ch"}, want: []time.Duration{0, interval, 2 * interval, 3 * interval}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			p := NewPacer(interval, c)
+			// Workers starting together all ask before the clock moves
+			for _, name := range tt.domains {
+				if err := p.Wait(context.Background(), name); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := clock.Waits(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("waits = %v, want %v", got, tt.want)
+			}
+
+			// Once the scheduled checks are past, the next one is due at once
+			clock.Advance(time.Duration(len(tt.domains)) * interval)
+			if err := p.Wait(context.Background(), tt.domains[0]); err != nil {
+				t.Fatal(err)
+			}
+			if got := clock.Waits(); !reflect.DeepEqual(got, []time.Duration{0}) {
+				t.Errorf("wait after the schedule = %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestPacerWithoutInterval(t *testing.T) {
+	clock := useFakeClock(t)
+	var p *Pacer
+	for _, pacer := range []*Pacer{p, NewPacer(0, NewChecker(CheckerOptions{}))} {
+		for i := 0; i < 3; i++ {
+			if err := pacer.Wait(context.Background(), "a.test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got := clock.Waits(); len(got) != 0 {
+		t.Errorf("waits = %v, want none", got)
+	}
+}
+
+func TestSuffixLimiterSpacesBursts(t *testing.T) {
+	clock := useFakeClock(t)
+	l := &suffixLimiter{}
+	// 120 queries a minute allow bursts of two, then one query every 500ms
+	for i := 0; i < 4; i++ {
+		if err := l.wait(context.Background(), "test", 120); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []time.Duration{0, 0, 500 * time.Millisecond, time.Second}
+	if got := clock.Waits(); !reflect.DeepEqual(got, want) {
+		t.Errorf("waits = %v, want %v", got, want)
+	}
+}
+
+func TestSleepContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	started := time.Now()
+	if err := sleepContext(ctx, time.Minute); err != context.Canceled {
+		t.Errorf("sleepContext() = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("sleepContext() returned after %v, want promptly after the cancellation", elapsed)
+	}
+}
+
+func TestQueryWHOISCancelWhileLimited(t *testing.T) {
+	c := NewChecker(CheckerOptions{WHOISInterval: time.Minute})
+
+	// The first slot is free; take it so that the query below has to wait
+	if err := c.limiter.wait(context.Background(), c.whoisKey("example.test")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.queryWHOIS(ctx, "example.test")
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("queryWHOIS() = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("queryWHOIS() did not return promptly after the cancellation")
+	}
+}
+
+func TestQueryWhoisWithRetryCancel(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer func(string) string
+		opts   CheckerOptions
+	}{
+		{name: "rate limit backoff", answer: testutil.StaticWHOIS("Too many requests, please try again later.\n")},
+		{
+			name: "slow answer",
+			answer: func(string) string {
+				time.Sleep(3 * time.Second)
+				return registeredWHOIS
+			},
+		},
+		{
+			name:   "limiter wait",
+			answer: testutil.StaticWHOIS(registeredWHOIS),
+			opts:   CheckerOptions{WHOISInterval: time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queried := make(chan struct{}, 10)
+			server := newWHOISServer(t, func(domain string) string {
+				queried <- struct{}{}
+				return tt.answer(domain)
+			})
+			c := newTestChecker(testutil.NewResolver(), server, tt.opts)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// The limiter lets the first query through and makes the second wait
+			if tt.opts.WHOISInterval > 0 {
+				if _, err := c.queryWHOIS(ctx, "first.test"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			done := make(chan error, 1)
+			go func() {
+				_, err := c.queryWhoisWithRetry(ctx, "example.test")
+				done <- err
+			}()
+			if tt.opts.WHOISInterval > 0 {
+				time.Sleep(50 * time.Millisecond)
+			} else {
+				<-queried
+				// Let the answer arrive so the lookup is backing off
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			cancel()
+			select {
+			case err := <-done:
+				if err != context.Canceled {
+					t.Errorf("queryWhoisWithRetry() = %v, want %v", err, context.Canceled)
+				}
+			case <-time.After(500 * time.Millisecond):
+				t.Fatal("queryWhoisWithRetry() did not return promptly after the cancellation")
+			}
+		})
+	}
+}
+
+// shortenWHOISBackoff makes the waits between WHOIS attempts negligible for a test
+func shortenWHOISBackoff(t *testing.T) {
+	t.Helper()
+	rateLimitDelay, errorDelay := whoisRateLimitDelay, whoisErrorDelay
+	whoisRateLimitDelay, whoisErrorDelay = time.Millisecond, time.Millisecond
+	t.Cleanup(func() {
+		whoisRateLimitDelay, whoisErrorDelay = rateLimitDelay, errorDelay
+	})
+}
+
+func TestCheckAlwaysRateLimited(t *testing.T) {
+	shortenWHOISBackoff(t)
+
+	tests := []struct {
+		name        string
+		answer      string
+		withNS      bool
+		wantSpecial string
+	}{
+		{name: "too many requests", answer: "Too many requests, please try again later.\n", wantSpecial: RateLimitedStatus},
+		{name: "limit exceeded", answer: "%% Query limit exceeded for 127.0.0.1\n", wantSpecial: RateLimitedStatus},
+		{name: "access control", answer: "% Error: 55000000002 Connection refused; access control limit reached.\n", wantSpecial: RateLimitedStatus},
+		// NS records decide the domain registered when WHOIS never answers
+		{name: "with DNS signature", answer: "Rate limit reached.\n", withNS: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := testutil.NewResolver()
+			if tt.withNS {
+				resolver.SetNS("example.test", "ns1.example.test")
+			}
+			server := newWHOISServer(t, testutil.StaticWHOIS(tt.answer))
+			recorded := metrics.NewMemory()
+			c := newTestChecker(resolver, server, CheckerOptions{DNSCheck: true, WHOISCheck: true, WHOISAlways: true, Metrics: recorded})
+
+			result, err := c.Check(context.Background(), "example.test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Available {
+				t.Errorf("a rate-limited domain was reported available")
+			}
+			if result.SpecialStatus != tt.wantSpecial {
+				t.Errorf("SpecialStatus = %q, want %q", result.SpecialStatus, tt.wantSpecial)
+			}
+			if got := server.Queries("example.test"); got != whoisAttempts {
+				t.Errorf("WHOIS queries = %d, want %d", got, whoisAttempts)
+			}
+			if got := recorded.Counter(metrics.WHOISRateLimited, nil); got != whoisAttempts {
+				t.Errorf("%s = %d, want %d", metrics.WHOISRateLimited, got, whoisAttempts)
+			}
+			if got := recorded.Counter(metrics.WHOISErrors, nil); got != 0 {
+				t.Errorf("%s = %d, want 0", metrics.WHOISErrors, got)
+			}
+			if got := recorded.Observations(metrics.MethodLatency, metrics.Labels{"method": "whois"}); got != whoisAttempts {
+				t.Errorf("WHOIS latency observations = %d, want %d", got, whoisAttempts)
+			}
+		})
+	}
+}
+
+func TestQueryWhoisWithRetrySequences(t *testing.T) {
+	shortenWHOISBackoff(t)
+	const throttled = "Too many requests, please try again later.\n"
+
+	tests := []struct {
+		name        string
+		responses   []string
+		down        bool
+		want        string
+		wantErr     error
+		wantQueries int
+	}{
+		{name: "answered at once", responses: []string{registeredWHOIS}, want: registeredWHOIS, wantQueries: 1},
+		{name: "not found at once", responses: []string{"No match for \"EXAMPLE.TEST\".\n"}, want: "No match for \"EXAMPLE.TEST\".\n", wantQueries: 1},
+		{name: "throttled twice", responses: []string{throttled, "Rate limit exceeded\n", registeredWHOIS}, want: registeredWHOIS, wantQueries: 3},
+		{name: "throttled until the last attempt", responses: []string{throttled, throttled, throttled, throttled, registeredWHOIS}, want: registeredWHOIS, wantQueries: 5},
+		{name: "always throttled", responses: []string{throttled}, wantErr: errWHOISRateLimited, wantQueries: whoisAttempts},
+		{name: "access control notice", responses: []string{"%% Access control: limit exceeded\n"}, wantErr: errWHOISRateLimited, wantQueries: whoisAttempts},
+		// A refused connection counts as throttled as well
+		{name: "server down", down: true, wantErr: errWHOISRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer := testutil.StaticWHOIS("")
+			if len(tt.responses) > 0 {
+				answer = testutil.SequenceWHOIS(tt.responses...)
+			}
+			server := newWHOISServer(t, answer)
+			c := newTestChecker(testutil.NewResolver(), server, CheckerOptions{})
+			if tt.down {
+				server.Close()
+			}
+
+			// The client appends its own query footer to the answer
+			raw, err := c.queryWhoisWithRetry(context.Background(), "example.test")
+			if !strings.HasPrefix(raw, tt.want) || (tt.want == "") != (raw == "") || err != tt.wantErr {
+				t.Errorf("queryWhoisWithRetry() = %q, %v; want %q, %v", raw, err, tt.want, tt.wantErr)
+			}
+			if got := server.Queries("example.test"); got != tt.wantQueries {
+				t.Errorf("WHOIS queries = %d, want %d", got, tt.wantQueries)
+			}
+		})
+	}
+}
+
+func TestIsRateLimitText(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "Too Many Requests", want: true},
+		{text: "Your query was refused: RATE LIMIT reached", want: true},
+		{text: "dial tcp 192.0.2.1:43: connect: connection refused", want: true},
+		{text: "%% WHOIS LIMIT EXCEEDED - see http://www.example.net/whois", want: true},
+		{text: "% Error: 55000000002 Access Control: your address is blocked", want: true},
+		{text: registeredWHOIS},
+		{text: "No match for \"EXAMPLE.TEST\".\n"},
+		{text: "Registrant: Example Limited Liability Company\n"},
+		{text: "Status: free\n"},
+		{text: ""},
+	}
+
+	for _, tt := range tests {
+		if got := isRateLimitText(tt.text); got != tt.want {
+			t.Errorf("isRateLimitText(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCheckQueriesWHOISOnce(t *testing.T) {
+	tests := []struct {
+		name          string
+		answer        string
+		wantAvailable bool
+	}{
+		{name: "available", answer: "No match for \"EXAMPLE.TEST\".\n", wantAvailable: true},
+		{name: "registered", answer: registeredWHOIS},
+		{name: "special status", answer: "Status: redemptionPeriod\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newWHOISServer(t, testutil.StaticWHOIS(tt.answer))
+			c := newTestChecker(testutil.NewResolver(), server, CheckerOptions{DNSCheck: true, WHOISCheck: true})
+			result, err := c.Check(context.Background(), "example.test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Available != tt.wantAvailable {
+				t.Errorf("Available = %v, want %v", result.Available, tt.wantAvailable)
+			}
+			// The signature pass and the decision share one lookup
+			if got := server.Queries("example.test"); got != 1 {
+				t.Errorf("WHOIS queries = %d, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultRDAPBootstrap is the IANA registry of the RDAP servers of each TLD
+const DefaultRDAPBootstrap = "https://data.iana.org/rdap/dns.json"
+
+// maxRDAPResponse bounds the RDAP and bootstrap documents read
+const maxRDAPResponse = 4 << 20
+
+// rdapLookup is the outcome of the RDAP query of a domain
+type rdapLookup struct {
+	// status is StatusAvailable for a 404, StatusRegistered for a domain object,
+	// StatusSpecial when that object has a transitional status and StatusUnknown otherwise
+	status Status
+	// special is the label of the special status
+	special string
+	err     error
+}
+
+// rdapBootstraps caches the parsed bootstrap files by URL for the whole process
+var rdapBootstraps struct {
+	sync.Mutex
+	services map[string]map[string]string
+}
+
+// lookupRDAP queries the authoritative RDAP server of a domain. A 404 means the
+// domain is not registered; a domain object means it is. Rate limits, other answers
+// and failures leave the status unknown.
+func (c *Checker) lookupRDAP(ctx context.Context, domain string) rdapLookup {
+	base, err := c.rdapServer(ctx, domain)
+	if err != nil {
+		return rdapLookup{status: StatusUnknown, err: err}
+	}
+	if base == "" {
+		return rdapLookup{status: StatusUnknown, err: fmt.Errorf("no RDAP server for .%s", tldOf(domain))}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimSuffix(base, "/")+"/domain/"+strings.ToLower(domain), nil)
+	if err != nil {
+		return rdapLookup{status: StatusUnknown, err: err}
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return rdapLookup{status: StatusUnknown, err: err}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return rdapLookup{status: StatusAvailable}
+	case http.StatusOK:
+	default:
+		return rdapLookup{status: StatusUnknown, err: fmt.Errorf("RDAP server answered %s", resp.Status)}
+	}
+
+	var object struct {
+		ObjectClassName string   `json:"objectClassName"`
+		Status          []string `json:"status"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRDAPResponse)).Decode(&object); err != nil {
+		return rdapLookup{status: StatusUnknown, err: fmt.Errorf("invalid RDAP response: %w", err)}
+	}
+	if object.ObjectClassName != "domain" {
+		return rdapLookup{status: StatusUnknown, err: fmt.Errorf("RDAP response is a %q object", object.ObjectClassName)}
+	}
+	if special := rdapSpecialStatus(object.Status); special != "" {
+		return rdapLookup{status: StatusSpecial, special: special}
+	}
+	return rdapLookup{status: StatusRegistered}
+}
+
+// rdapSpecialStatus returns the label of the first transitional RDAP status such as
+// "redemption period" or "pending delete", using the table of the WHOIS statuses
+func rdapSpecialStatus(statuses []string) string {
+	for _, status := range specialStatuses {
+		for _, value := range statuses {
+			if strings.HasPrefix(squashStatus(strings.ToLower(value)), status.value) {
+				return status.name
+			}
+		}
+	}
+	return ""
+}
+
+// rdapServer returns the RDAP base URL for a domain: the configured server of its suffix,
+// or else the one the bootstrap registry lists; "" when there is none
+func (c *Checker) rdapServer(ctx context.Context, domain string) (string, error) {
+	if server := suffixServer(c.opts.RDAPServers, domain); server != "" {
+		return server, nil
+	}
+	url := c.opts.RDAPBootstrap
+	if url == "" {
+		url = DefaultRDAPBootstrap
+	}
+	services, err := rdapBootstrap(ctx, c.httpClient(), url)
+	if err != nil {
+		return "", err
+	}
+	return services[tldOf(domain)], nil
+}
+
+// rdapBootstrap returns the RDAP base URL of every TLD of a bootstrap file, fetching
+// it on first use. A failed fetch is retried by the next lookup.
+func rdapBootstrap(ctx context.Context, client *http.Client, url string) (map[string]string, error) {
+	rdapBootstraps.Lock()
+	defer rdapBootstraps.Unlock()
+	if services, ok := rdapBootstraps.services[url]; ok {
+		return services, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching RDAP bootstrap: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching RDAP bootstrap: %s", resp.Status)
+	}
+
+	// Each service pairs a list of TLDs with the base URLs serving them
+	var file struct {
+		Services [][][]string `json:"services"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRDAPResponse)).Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid RDAP bootstrap: %w", err)
+	}
+	services := make(map[string]string)
+	for _, service := range file.Services {
+		if len(service) < 2 || len(service[1]) == 0 {
+			continue
+		}
+		// Prefer an HTTPS server when several are listed
+		base := service[1][0]
+		for _, candidate := range service[1] {
+			if strings.HasPrefix(candidate, "https://") {
+				base = candidate
+				break
+			}
+		}
+		for _, tld := range service[0] {
+			services[strings.ToLower(tld)] = base
+		}
+	}
+
+	if rdapBootstraps.services == nil {
+		rdapBootstraps.services = make(map[string]map[string]string)
+	}
+	rdapBootstraps.services[url] = services
+	return services, nil
+}
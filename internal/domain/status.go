@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"strings"
+)
+
+// specialStatuses maps the WHOIS and EPP status values of transitional states to their
+// canonical label. Values are compared without case, spaces, hyphens or underscores, so
+// "redemptionPeriod", "Redemption Period" and "redemption-period" all read
+// REDEMPTIONPERIOD, and a value matches when the status starts with it. Where one value
+// is a prefix of another, the longer one comes first.
+var specialStatuses = []struct{ value, name string }{
+	{"redemptionperiod", "REDEMPTIONPERIOD"},
+	{"redemption", "REDEMPTIONPERIOD"},
+	{"pendingdelete", "PENDINGDELETE"},
+	{"hold", "HOLD"},
+	{"inactive", "INACTIVE"},
+	{"suspended", "SUSPENDED"},
+	{"quarantined", "QUARANTINED"},
+	{"pending", "PENDING"},
+	{"transfer", "TRANSFER"},
+	{"grace", "GRACE"},
+	{"autorenewperiod", "AUTORENEWPERIOD"},
+	{"expire", "EXPIRED"},
+	{"clienthold", "CLIENTHOLD"},
+	{"serverhold", "SERVERHOLD"},
+}
+
+// redemptionPhrases announce a redemption period in free text instead of a status line
+var redemptionPhrases = []string{"redemptionperiod", "redemption period"}
+
+// specialStatusOf returns the canonical label of the first special status found in a
+// parsed WHOIS response, or "" when there is none. Status lines are "status:" fields,
+// including prefixed keys such as "domain status:".
+func specialStatusOf(lines []whoisLine) string {
+	for _, status := range specialStatuses {
+		for _, line := range lines {
+			if !line.field || (line.key != "status" && !strings.HasSuffix(line.key, " status")) {
+				continue
+			}
+			if strings.HasPrefix(squashStatus(line.value), status.value) {
+				return status.name
+			}
+		}
+	}
+	if len(matchIndicators(lines, redemptionPhrases)) > 0 {
+		return "REDEMPTIONPERIOD"
+	}
+	return ""
+}
+
+// squashStatus removes the separators registries put between the words of a status
+func squashStatus(value string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(value)
+}
+
+// specialStatusIndicator is the indicator reported for a special status label
+func specialStatusIndicator(name string) string {
+	return "status: " + strings.ToLower(name)
+}
+
+// specialStatusName turns a special status indicator back into its label
+func specialStatusName(indicator string) string {
+	return strings.ToUpper(strings.TrimPrefix(indicator, "status: "))
+}
@@ -0,0 +1,89 @@
+package domain
+
+import "testing"
+
+func TestSpecialStatusSpellings(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "Status: redemptionPeriod\n", want: "REDEMPTIONPERIOD"},
+		{raw: "Status: Redemption Period\n", want: "REDEMPTIONPERIOD"},
+		{raw: "status: REDEMPTION-PERIOD\n", want: "REDEMPTIONPERIOD"},
+		{raw: "Status:\tredemption_period\n", want: "REDEMPTIONPERIOD"},
+		{raw: "Domain Status: redemptionPeriod https://icann.org/epp#redemptionPeriod\n", want: "REDEMPTIONPERIOD"},
+		{raw: "Status: REDEMPTION\n", want: "REDEMPTIONPERIOD"},
+		{raw: "Domain Status: pendingDelete https://icann.org/epp#pendingDelete\n", want: "PENDINGDELETE"},
+		{raw: "Status: Pending Delete\n", want: "PENDINGDELETE"},
+		{raw: "Status: PENDING-DELETE\n", want: "PENDINGDELETE"},
+		{raw: "Status: pending_delete\n", want: "PENDINGDELETE"},
+		{raw: "Status: pending transfer\n", want: "PENDING"},
+		{raw: "Status: pendingTransfer\n", want: "PENDING"},
+		{raw: "Domain Status: clientHold\n", want: "CLIENTHOLD"},
+		{raw: "Domain Status: Client Hold\n", want: "CLIENTHOLD"},
+		{raw: "Domain Status: serverHold\n", want: "SERVERHOLD"},
+		{raw: "Status: ON HOLD\n", want: ""},
+		{raw: "Status: Hold\n", want: "HOLD"},
+		{raw: "Status: Inactive\n", want: "INACTIVE"},
+		{raw: "Status: SUSPENDED\n", want: "SUSPENDED"},
+		{raw: "Status: quarantined\n", want: "QUARANTINED"},
+		{raw: "Status: Transfer Period\n", want: "TRANSFER"},
+		{raw: "Status: Grace Period\n", want: "GRACE"},
+		{raw: "Domain Status: autoRenewPeriod\n", want: "AUTORENEWPERIOD"},
+		{raw: "Domain Status: Auto-Renew Period\n", want: "AUTORENEWPERIOD"},
+		{raw: "Status: Expired\n", want: "EXPIRED"},
+		{raw: "EPP Status: pendingDelete\n", want: "PENDINGDELETE"},
+		{raw: "Registration Status\nREDEMPTION PERIOD\n", want: "REDEMPTIONPERIOD"},
+		// The first status of the table wins over the order of the lines
+		{raw: "Domain Status: clientHold\nDomain Status: redemptionPeriod\n", want: "REDEMPTIONPERIOD"},
+		// Free text announces a redemption period without a status line
+		{raw: "This domain is in its redemption period.\n", want: "REDEMPTIONPERIOD"},
+		{raw: "% Redemption Period ends on 2024-01-01\n", want: "REDEMPTIONPERIOD"},
+		{raw: "Domain Status: ok\n", want: ""},
+		{raw: "Domain Status: clientTransferProhibited\n", want: ""},
+		{raw: "Domain Status: serverTransferProhibited\n", want: ""},
+		{raw: "Domain Status: addPeriod\n", want: ""},
+		// Only status fields count
+		{raw: "Registrar: Pending Holdings Ltd\n", want: ""},
+		{raw: "% status: clientHold\n", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := specialStatusOf(parseWHOISLines(tt.raw)); got != tt.want {
+			t.Errorf("specialStatusOf(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestRDAPSpecialStatusSpellings(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     string
+	}{
+		{statuses: []string{"redemption period"}, want: "REDEMPTIONPERIOD"},
+		{statuses: []string{"active", "Redemption Period"}, want: "REDEMPTIONPERIOD"},
+		{statuses: []string{"pending delete"}, want: "PENDINGDELETE"},
+		{statuses: []string{"pending transfer"}, want: "PENDING"},
+		{statuses: []string{"client hold", "pending delete"}, want: "PENDINGDELETE"},
+		{statuses: []string{"client hold"}, want: "CLIENTHOLD"},
+		{statuses: []string{"server hold"}, want: "SERVERHOLD"},
+		{statuses: []string{"auto renew period"}, want: "AUTORENEWPERIOD"},
+		{statuses: []string{"inactive"}, want: "INACTIVE"},
+		{statuses: []string{"active", "client transfer prohibited"}, want: ""},
+		{statuses: nil, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := rdapSpecialStatus(tt.statuses); got != tt.want {
+			t.Errorf("rdapSpecialStatus(%q) = %q, want %q", tt.statuses, got, tt.want)
+		}
+	}
+}
+
+func TestSpecialStatusIndicatorRoundTrip(t *testing.T) {
+	for _, status := range specialStatuses {
+		if got := specialStatusName(specialStatusIndicator(status.name)); got != status.name {
+			t.Errorf("specialStatusName(specialStatusIndicator(%q)) = %q", status.name, got)
+		}
+	}
+}
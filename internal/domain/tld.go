@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+// CheckTLDs looks up the name servers of every suffix to find those that do not exist,
+// e.g. misspelled TLDs. Suffixes whose lookup failed otherwise, e.g. without network
+// access, are returned as unverified; both lists keep the input order.
+func CheckTLDs(ctx context.Context, suffixes []string) (unknown, unverified []string) {
+	return defaultChecker().CheckTLDs(ctx, suffixes)
+}
+
+// CheckTLDs is like the package-level CheckTLDs but uses the checker's resolver
+func (c *Checker) CheckTLDs(ctx context.Context, suffixes []string) (unknown, unverified []string) {
+	errs := make([]error, len(suffixes))
+	var wg sync.WaitGroup
+	for i, suffix := range suffixes {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			_, errs[i] = c.dns.LookupNS(ctx, name+".")
+		}(i, strings.Trim(suffix, "."))
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		var dnsErr *net.DNSError
+		switch {
+		case err == nil:
+		case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+			unknown = append(unknown, suffixes[i])
+		default:
+			unverified = append(unverified, suffixes[i])
+		}
+	}
+	return unknown, unverified
+}
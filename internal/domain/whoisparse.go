@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"sync"
+
+	"domain-scanner/internal/types"
+)
+
+// WHOISParser turns a raw WHOIS response into a structured record
+type WHOISParser interface {
+	Parse(domain, raw string) (*types.WHOISRecord, error)
+}
+
+// activeParser is the parser used by ParseWHOIS; builds with the "whoisparser" tag
+// replace the built-in parser with github.com/likexian/whois-parser
+var activeParser struct {
+	sync.Mutex
+	parser WHOISParser
+}
+
+// SetWHOISParser replaces the parser used by ParseWHOIS; nil restores the built-in parser
+func SetWHOISParser(parser WHOISParser) {
+	activeParser.Lock()
+	defer activeParser.Unlock()
+	activeParser.parser = parser
+}
+
+// ParseWHOIS parses a raw WHOIS response, falling back to a record holding only the
+// raw text when the response cannot be parsed
+func ParseWHOIS(domain, raw string) types.WHOISRecord {
+	activeParser.Lock()
+	parser := activeParser.parser
+	activeParser.Unlock()
+	if parser == nil {
+		parser = builtinParser{}
+	}
+
+	record, err := parser.Parse(domain, raw)
+	if err != nil || record == nil {
+		return types.WHOISRecord{Domain: domain, Raw: raw}
+	}
+	record.Domain = domain
+	return *record
+}
+
+// WHOIS field names of the common registry formats, lower-cased and without the colon
+var (
+	whoisRegistrarKeys = map[string]bool{
+		"registrar": true, "registrar name": true, "sponsoring registrar": true, "registrar organization": true,
+	}
+	whoisStatusKeys = map[string]bool{
+		"domain status": true, "status": true, "state": true,
+	}
+	whoisNameServerKeys = map[string]bool{
+		"name server": true, "name servers": true, "nameserver": true, "nameservers": true, "nserver": true,
+	}
+	whoisCreatedKeys = map[string]bool{
+		"creation date": true, "created": true, "created on": true, "registered on": true,
+		"registration time": true, "domain registration date": true, "first registration date": true,
+	}
+	whoisUpdatedKeys = map[string]bool{
+		"updated date": true, "updated": true, "last updated": true, "changed": true,
+		"last-update": true, "last modified": true, "modified": true,
+	}
+	whoisExpiresKeys = map[string]bool{
+		"registry expiry date": true, "registrar registration expiration date": true, "expiration date": true,
+		"expires": true, "expires on": true, "expiry date": true, "expire date": true, "paid-till": true,
+	}
+)
+
+// errUnparsedWHOIS is returned when a response contains none of the known fields
+var errUnparsedWHOIS = errors.New("no known WHOIS fields")
+
+// builtinParser understands "key: value" lines and the block format of registries such
+// as SWITCH and Nominet, where a "key:" line is followed by indented values
+type builtinParser struct{}
+
+// Parse extracts the registrar, statuses, name servers and dates of a WHOIS response
+func (builtinParser) Parse(domain, raw string) (*types.WHOISRecord, error) {
+	record := &types.WHOISRecord{Domain: domain}
+	found := false
+	set := func(key, value string) {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return
+		}
+		switch {
+		case whoisRegistrarKeys[key]:
+			if record.Registrar == "" {
+				record.Registrar = value
+				found = true
+			}
+		case whoisStatusKeys[key]:
+			// ICANN statuses carry an explanatory URL after the status code
+			record.Statuses = appendUnique(record.Statuses, strings.Fields(value)[0])
+			found = true
+		case whoisNameServerKeys[key]:
+			server := strings.TrimSuffix(strings.ToLower(strings.Fields(value)[0]), ".")
+			record.NameServers = appendUnique(record.NameServers, server)
+			found = true
+		case whoisCreatedKeys[key]:
+			if record.Created == "" {
+				record.Created = value
+				found = true
+			}
+		case whoisUpdatedKeys[key]:
+			if record.Updated == "" {
+				record.Updated = value
+				found = true
+			}
+		case whoisExpiresKeys[key]:
+			if record.Expires == "" {
+				record.Expires = value
+				found = true
+			}
+		}
+	}
+
+	// block is the key of a "key:" line without value whose values follow on the next lines
+	block := ""
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "%") || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ">>>") {
+			block = ""
+			continue
+		}
+
+		key, value, hasColon := strings.Cut(trimmed, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if block != "" && (!hasColon || !isWHOISKey(key)) {
+			set(block, trimmed)
+			continue
+		}
+		if !hasColon {
+			continue
+		}
+		if strings.TrimSpace(value) == "" {
+			block = key
+			continue
+		}
+		block = ""
+		set(key, value)
+	}
+
+	if !found {
+		return nil, errUnparsedWHOIS
+	}
+	return record, nil
+}
+
+// isWHOISKey reports whether a key is one of the fields the built-in parser extracts
+func isWHOISKey(key string) bool {
+	return whoisRegistrarKeys[key] || whoisStatusKeys[key] || whoisNameServerKeys[key] ||
+		whoisCreatedKeys[key] || whoisUpdatedKeys[key] || whoisExpiresKeys[key]
+}
+
+// appendUnique appends a value unless the slice already contains it
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,38 @@
+//go:build whoisparser
+
+package domain
+
+import (
+	whoisparser "github.com/likexian/whois-parser"
+
+	"domain-scanner/internal/types"
+)
+
+// Builds with the "whoisparser" tag parse WHOIS responses with likexian/whois-parser,
+// which knows many more registry formats than the built-in parser
+func init() {
+	SetWHOISParser(likexianParser{})
+}
+
+// likexianParser adapts github.com/likexian/whois-parser to WHOISParser
+type likexianParser struct{}
+
+// Parse converts the whois-parser result into a WHOIS record
+func (likexianParser) Parse(domain, raw string) (*types.WHOISRecord, error) {
+	info, err := whoisparser.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	record := &types.WHOISRecord{Domain: domain}
+	if info.Domain != nil {
+		record.Statuses = info.Domain.Status
+		record.NameServers = info.Domain.NameServers
+		record.Created = info.Domain.CreatedDate
+		record.Updated = info.Domain.UpdatedDate
+		record.Expires = info.Domain.ExpirationDate
+	}
+	if info.Registrar != nil {
+		record.Registrar = info.Registrar.Name
+	}
+	return record, nil
+}
@@ -0,0 +1,237 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"domain-scanner/internal/types"
+)
+
+func TestBuiltinParserRegistries(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want types.WHOISRecord
+	}{
+		{
+			name: "verisign",
+			raw: "   Domain Name: EXAMPLE.COM\r\n" +
+				"   Registry Domain ID: 2336799_DOMAIN_COM-VRSN\r\n" +
+				"   Registrar WHOIS Server: whois.example-registrar.com\r\n" +
+				"   Registrar URL: http://www.example-registrar.com\r\n" +
+				"   Updated Date: 2024-08-14T07:01:34Z\r\n" +
+				"   Creation Date: 1995-08-14T04:00:00Z\r\n" +
+				"   Registry Expiry Date: 2025-08-13T04:00:00Z\r\n" +
+				"   Registrar: Example Registrar, Inc.\r\n" +
+				"   Domain Status: clientDeleteProhibited https://icann.org/epp#clientDeleteProhibited\r\n" +
+				"   Domain Status: clientTransferProhibited https://icann.org/epp#clientTransferProhibited\r\n" +
+				"   Name Server: A.IANA-SERVERS.NET\r\n" +
+				"   Name Server: B.IANA-SERVERS.NET\r\n" +
+				"   DNSSEC: signedDelegation\r\n" +
+				">>> Last update of whois database: 2024-09-01T10:00:00Z <<<\r\n",
+			want: types.WHOISRecord{
+				Domain:      "example.com",
+				Registrar:   "Example Registrar, Inc.",
+				Statuses:    []string{"clientDeleteProhibited", "clientTransferProhibited"},
+				NameServers: []string{"a.iana-servers.net", "b.iana-servers.net"},
+				Created:     "1995-08-14T04:00:00Z",
+				Updated:     "2024-08-14T07:01:34Z",
+				Expires:     "2025-08-13T04:00:00Z",
+			},
+		},
+		{
+			name: "denic",
+			raw: "% Restricted rights.\n" +
+				"%\n" +
+				"% Terms and Conditions of Use\n\n" +
+				"Domain: example.de\n" +
+				"Nserver: ns1.example.net\n" +
+				"Nserver: ns2.example.net\n" +
+				"Status: connect\n" +
+				"Changed: 2018-03-12T21:44:25+01:00\n",
+			want: types.WHOISRecord{
+				Domain:      "example.de",
+				Statuses:    []string{"connect"},
+				NameServers: []string{"ns1.example.net", "ns2.example.net"},
+				Updated:     "2018-03-12T21:44:25+01:00",
+			},
+		},
+		{
+			name: "afnic",
+			raw: "%% This is the AFNIC Whois server.\n\n" +
+				"domain:                        example.fr\n" +
+				"status:                        ACTIVE\n" +
+				"hold:                          NO\n" +
+				"registrar:                     EXAMPLE SAS\n" +
+				"Expiry Date:                   2025-02-27T09:49:50Z\n" +
+				"created:                       2006-02-27T09:49:50Z\n" +
+				"last-update:                   2024-01-21T10:40:39Z\n" +
+				"nserver:                       ns1.example.fr\n" +
+				"nserver:                       ns2.example.fr\n",
+			want: types.WHOISRecord{
+				Domain:      "example.fr",
+				Registrar:   "EXAMPLE SAS",
+				Statuses:    []string{"ACTIVE"},
+				NameServers: []string{"ns1.example.fr", "ns2.example.fr"},
+				Created:     "2006-02-27T09:49:50Z",
+				Updated:     "2024-01-21T10:40:39Z",
+				Expires:     "2025-02-27T09:49:50Z",
+			},
+		},
+		{
+			name: "switch block format",
+			raw: "Domain name:\n" +
+				"example.ch\n\n" +
+				"Holder of domain name:\n" +
+				"Example AG\n" +
+				"Bahnhofstrasse 1\n" +
+				"8001 Zurich\n\n" +
+				"Registrar:\n" +
+				"Example Registrar AG\n\n" +
+				"DNSSEC:N\n\n" +
+				"Name servers:\n" +
+				"ns1.example.ch\t[192.0.2.1]\n" +
+				"ns2.example.ch.\n\n" +
+				"First registration date:\n" +
+				"2001-04-12\n",
+			want: types.WHOISRecord{
+				Domain:      "example.ch",
+				Registrar:   "Example Registrar AG",
+				NameServers: []string{"ns1.example.ch", "ns2.example.ch"},
+				Created:     "2001-04-12",
+			},
+		},
+		{
+			name: "nominet block format",
+			raw: "\n    Domain name:\n" +
+				"        example.co.uk\n\n" +
+				"    Registrar:\n" +
+				"        Example Ltd t/a Example [Tag = EXAMPLE]\n" +
+				"        URL: https://www.example.net\n\n" +
+				"    Relevant dates:\n" +
+				"        Registered on: 26-Aug-1996\n" +
+				"        Expiry date:  26-Aug-2026\n" +
+				"        Last updated:  25-Jul-2024\n\n" +
+				"    Registration status:\n" +
+				"        Registered until expiry date.\n\n" +
+				"    Name servers:\n" +
+				"        NS1.EXAMPLE.NET          192.0.2.1\n" +
+				"        ns2.example.net\n\n" +
+				"    WHOIS lookup made at 10:00:00 01-Sep-2024\n",
+			want: types.WHOISRecord{
+				Domain:      "example.co.uk",
+				Registrar:   "Example Ltd t/a Example [Tag = EXAMPLE]",
+				NameServers: []string{"ns1.example.net", "ns2.example.net"},
+				Created:     "26-Aug-1996",
+				Updated:     "25-Jul-2024",
+				Expires:     "26-Aug-2026",
+			},
+		},
+		{
+			name: "registro.br",
+			raw: "% Copyright (c) Nic.br\n\n" +
+				"domain:      example.com.br\n" +
+				"owner:       Example Ltda\n" +
+				"nserver:     a.dns.br\n" +
+				"nserver:     b.dns.br\n" +
+				"created:     19990101 #12345\n" +
+				"changed:     20240101\n" +
+				"expires:     20250101\n" +
+				"status:      published\n",
+			want: types.WHOISRecord{
+				Domain:      "example.com.br",
+				Statuses:    []string{"published"},
+				NameServers: []string{"a.dns.br", "b.dns.br"},
+				Created:     "19990101 #12345",
+				Updated:     "20240101",
+				Expires:     "20250101",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, err := builtinParser{}.Parse(tt.want.Domain, tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*record, tt.want) {
+				t.Errorf("Parse() = %+v\nwant %+v", *record, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinParserUnparsed(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"No match for \"EXAMPLE.COM\".\r\n>>> Last update of whois database: 2024-09-01T10:00:00Z <<<\r\n",
+		"% Error: 55000000002 Connection refused; access control limit reached.\n",
+	} {
+		if record, err := (builtinParser{}).Parse("example.com", raw); err != errUnparsedWHOIS {
+			t.Errorf("Parse(%q) = %+v, %v; want %v", raw, record, err, errUnparsedWHOIS)
+		}
+	}
+}
+
+// failingParser is a WHOIS parser that never understands a response
+type failingParser struct{}
+
+func (failingParser) Parse(string, string) (*types.WHOISRecord, error) {
+	return nil, errors.New("unsupported format")
+}
+
+func TestParseWHOISFallsBackToRaw(t *testing.T) {
+	previous := activeParser.parser
+	t.Cleanup(func() { SetWHOISParser(previous) })
+	SetWHOISParser(failingParser{})
+
+	raw := "Domain Name: EXAMPLE.COM\nRegistrar: Example Registrar, Inc.\n"
+	record := ParseWHOIS("example.com", raw)
+	if want := (types.WHOISRecord{Domain: "example.com", Raw: raw}); !reflect.DeepEqual(record, want) {
+		t.Errorf("ParseWHOIS() = %+v, want %+v", record, want)
+	}
+}
+
+func TestWHOISRecordJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		record types.WHOISRecord
+		want   string
+	}{
+		{
+			name: "parsed",
+			record: types.WHOISRecord{
+				Domain:      "example.com",
+				Registrar:   "Example Registrar, Inc.",
+				Statuses:    []string{"clientDeleteProhibited"},
+				NameServers: []string{"a.iana-servers.net"},
+				Created:     "1995-08-14T04:00:00Z",
+				Updated:     "2024-08-14T07:01:34Z",
+				Expires:     "2025-08-13T04:00:00Z",
+			},
+			want: `{"domain":"example.com","registrar":"Example Registrar, Inc.","statuses":["clientDeleteProhibited"],` +
+				`"name_servers":["a.iana-servers.net"],"created":"1995-08-14T04:00:00Z","updated":"2024-08-14T07:01:34Z",` +
+				`"expires":"2025-08-13T04:00:00Z"}`,
+		},
+		{
+			name:   "unparsed",
+			record: types.WHOISRecord{Domain: "example.ch", Raw: "Registrar:\n"},
+			want:   `{"domain":"example.ch","raw":"Registrar:\n"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("JSON = %s\nwant %s", data, tt.want)
+			}
+		})
+	}
+}
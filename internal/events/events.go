@@ -0,0 +1,266 @@
+// Package events writes a newline-delimited JSON event stream of a scan to a Unix
+// socket or an inherited file descriptor, for wrapper processes that supervise scans
+// without parsing the console output. The wrapper can send control commands back on
+// the same connection.
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/scanner"
+	"domain-scanner/internal/types"
+)
+
+// SchemaVersion is the version of the event format. It is included in every event and
+// changes whenever an event type or field is renamed, removed or changes meaning.
+const SchemaVersion = 1
+
+// Event types
+const (
+	TypeResult    = "result"
+	TypeProgress  = "progress"
+	TypeRateLimit = "rate_limit"
+	TypeState     = "state"
+	// TypeAck and TypeError answer control commands
+	TypeAck   = "ack"
+	TypeError = "error"
+)
+
+// Control commands accepted from the wrapper, one JSON object per line such as
+// {"command":"pause"}
+const (
+	CommandPause  = "pause"
+	CommandResume = "resume"
+	CommandStop   = "stop"
+)
+
+// bufferSize bounds the events waiting for a slow reader
+const bufferSize = 1000
+
+// drainTimeout bounds the wait for a slow reader when the stream is closed
+const drainTimeout = 5 * time.Second
+
+// Event is one line of the stream; only the fields of its type are set
+type Event struct {
+	SchemaVersion int                 `json:"schema_version"`
+	Type          string              `json:"type"`
+	Time          time.Time           `json:"time"`
+	Result        *types.DomainResult `json:"result,omitempty"`
+	Progress      *Progress           `json:"progress,omitempty"`
+	Domain        string              `json:"domain,omitempty"`
+	State         string              `json:"state,omitempty"`
+	Command       string              `json:"command,omitempty"`
+	Message       string              `json:"message,omitempty"`
+}
+
+// Progress is the JSON form of scanner.Progress
+type Progress struct {
+	Processed   int     `json:"processed"`
+	Total       int     `json:"total"`
+	Available   int     `json:"available"`
+	Registered  int     `json:"registered"`
+	Uncertain   int     `json:"uncertain"`
+	RateLimited int     `json:"rate_limited"`
+	Errors      int     `json:"errors"`
+	ElapsedMs   int64   `json:"elapsed_ms"`
+	Rate        float64 `json:"rate"`
+	// Dropped counts the events this stream discarded because the reader fell behind
+	Dropped int64 `json:"dropped_events"`
+}
+
+// Controller carries out the control commands of the wrapper
+type Controller interface {
+	Pause() bool
+	Resume() bool
+	Stop()
+}
+
+// Stream writes events to a connection without ever blocking the scan: events that
+// do not fit in the buffer are dropped and counted
+type Stream struct {
+	conn    io.WriteCloser
+	reader  io.Reader
+	queue   chan []byte
+	done    chan struct{}
+	dropped int64
+
+	// closed is set by Close; later events, e.g. acks of late commands, are discarded
+	mu     sync.Mutex
+	closed bool
+}
+
+// Open connects to the Unix socket at path, which the wrapper must be listening on, or
+// uses the inherited file descriptor fd when path is empty. A socket descriptor also
+// carries control commands; any other descriptor, e.g. a pipe, only receives events.
+func Open(path string, fd int) (*Stream, error) {
+	if path != "" {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to event socket: %w", err)
+		}
+		return New(conn, conn), nil
+	}
+	file := os.NewFile(uintptr(fd), fmt.Sprintf("event-fd-%d", fd))
+	if file == nil {
+		return nil, fmt.Errorf("invalid event file descriptor %d", fd)
+	}
+	if conn, err := net.FileConn(file); err == nil {
+		// FileConn duplicates the descriptor
+		file.Close()
+		return New(conn, conn), nil
+	}
+	if _, err := file.Stat(); err != nil {
+		return nil, fmt.Errorf("invalid event file descriptor %d: %w", fd, err)
+	}
+	return New(file, nil), nil
+}
+
+// New starts a stream writing to conn; control commands are read from r when it is not nil
+func New(conn io.WriteCloser, r io.Reader) *Stream {
+	s := &Stream{
+		conn:   conn,
+		reader: r,
+		queue:  make(chan []byte, bufferSize),
+		done:   make(chan struct{}),
+	}
+	go s.write()
+	return s
+}
+
+func (s *Stream) write() {
+	defer close(s.done)
+	w := bufio.NewWriter(s.conn)
+	for line := range s.queue {
+		if _, err := w.Write(line); err != nil {
+			// The reader is gone; keep draining so that senders never block
+			continue
+		}
+		if len(s.queue) == 0 {
+			w.Flush()
+		}
+	}
+	w.Flush()
+}
+
+// send queues an event, dropping it when the buffer is full
+func (s *Stream) send(e Event) {
+	e.SchemaVersion = SchemaVersion
+	e.Time = time.Now().UTC()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- append(line, '\n'):
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+// Dropped returns the number of events discarded so far
+func (s *Stream) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// Result sends a checked domain, followed by a rate_limit event when WHOIS throttled it
+func (s *Stream) Result(r types.DomainResult) {
+	s.send(Event{Type: TypeResult, Result: &r})
+	if r.SpecialStatus == domain.RateLimitedStatus {
+		s.send(Event{Type: TypeRateLimit, Domain: r.Domain})
+	}
+}
+
+// Progress sends a progress snapshot
+func (s *Stream) Progress(p scanner.Progress) {
+	s.send(Event{Type: TypeProgress, Progress: &Progress{
+		Processed:   p.Processed,
+		Total:       p.Total,
+		Available:   p.Available,
+		Registered:  p.Registered,
+		Uncertain:   p.Uncertain,
+		RateLimited: p.RateLimited,
+		Errors:      p.Errors,
+		ElapsedMs:   p.Elapsed.Milliseconds(),
+		Rate:        p.Rate(),
+		Dropped:     s.Dropped(),
+	}})
+}
+
+// State sends a state change of the scan
+func (s *Stream) State(state scanner.State) {
+	s.send(Event{Type: TypeState, State: string(state)})
+}
+
+// Control reads control commands until the connection is closed and carries them out,
+// answering each with an ack or error event. It returns at once for write-only streams.
+func (s *Stream) Control(c Controller) {
+	if s.reader == nil {
+		return
+	}
+	lines := bufio.NewScanner(s.reader)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" {
+			continue
+		}
+		var cmd struct {
+			Command string `json:"command"`
+		}
+		if err := json.Unmarshal([]byte(line), &cmd); err != nil {
+			s.send(Event{Type: TypeError, Message: fmt.Sprintf("invalid command: %v", err)})
+			continue
+		}
+		switch cmd.Command {
+		case CommandPause:
+			if !c.Pause() {
+				s.send(Event{Type: TypeError, Command: cmd.Command, Message: "scan is already paused"})
+				continue
+			}
+		case CommandResume:
+			if !c.Resume() {
+				s.send(Event{Type: TypeError, Command: cmd.Command, Message: "scan is not paused"})
+				continue
+			}
+		case CommandStop:
+			c.Stop()
+		default:
+			s.send(Event{Type: TypeError, Command: cmd.Command, Message: "unknown command"})
+			continue
+		}
+		s.send(Event{Type: TypeAck, Command: cmd.Command})
+	}
+}
+
+// Close sends the buffered events, waiting at most a few seconds for a slow reader, and
+// closes the connection
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	case <-time.After(drainTimeout):
+	}
+	return s.conn.Close()
+}
@@ -0,0 +1,252 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/scanner"
+	"domain-scanner/internal/types"
+)
+
+// fakeController records the control commands carried out by a stream
+type fakeController struct {
+	mu       sync.Mutex
+	paused   bool
+	stopped  bool
+	commands []string
+}
+
+func (c *fakeController) Pause() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commands = append(c.commands, CommandPause)
+	if c.paused {
+		return false
+	}
+	c.paused = true
+	return true
+}
+
+func (c *fakeController) Resume() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commands = append(c.commands, CommandResume)
+	if !c.paused {
+		return false
+	}
+	c.paused = false
+	return true
+}
+
+func (c *fakeController) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commands = append(c.commands, CommandStop)
+	c.stopped = true
+}
+
+// eventReader reads the events of a stream on the wrapper side
+type eventReader struct {
+	t     *testing.T
+	conn  net.Conn
+	lines *bufio.Scanner
+}
+
+func newEventReader(t *testing.T, conn net.Conn) *eventReader {
+	return &eventReader{t: t, conn: conn, lines: bufio.NewScanner(conn)}
+}
+
+// next returns the next event, failing the test when none arrives in time
+func (r *eventReader) next() Event {
+	r.t.Helper()
+	r.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if !r.lines.Scan() {
+		r.t.Fatalf("no event: %v", r.lines.Err())
+	}
+	var e Event
+	if err := json.Unmarshal(r.lines.Bytes(), &e); err != nil {
+		r.t.Fatalf("invalid event %q: %v", r.lines.Text(), err)
+	}
+	if e.SchemaVersion != SchemaVersion || e.Time.IsZero() {
+		r.t.Errorf("event %q lacks the schema version or time", r.lines.Text())
+	}
+	return e
+}
+
+// command sends a control command line
+func (r *eventReader) command(line string) {
+	r.t.Helper()
+	if _, err := io.WriteString(r.conn, line+"\n"); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// testConversation runs a scan's events and a wrapper's commands over a connected
+// stream and its wrapper end
+func testConversation(t *testing.T, stream *Stream, wrapper net.Conn) {
+	t.Helper()
+	controller := &fakeController{}
+	controlled := make(chan struct{})
+	go func() {
+		stream.Control(controller)
+		close(controlled)
+	}()
+	r := newEventReader(t, wrapper)
+
+	stream.State(scanner.StateStarted)
+	stream.Result(types.DomainResult{Domain: "free.test", Available: true, Elapsed: 20 * time.Millisecond})
+	stream.Result(types.DomainResult{Domain: "busy.test", SpecialStatus: domain.RateLimitedStatus})
+	stream.Progress(scanner.Progress{Processed: 2, Total: 10, Available: 1, RateLimited: 1, Elapsed: 2 * time.Second})
+
+	if e := r.next(); e.Type != TypeState || e.State != "started" {
+		t.Errorf("event 1 = %+v, want the started state", e)
+	}
+	if e := r.next(); e.Type != TypeResult || e.Result == nil || e.Result.Domain != "free.test" || !e.Result.Available {
+		t.Errorf("event 2 = %+v, want the available result", e)
+	}
+	if e := r.next(); e.Type != TypeResult || e.Result == nil || e.Result.SpecialStatus != domain.RateLimitedStatus {
+		t.Errorf("event 3 = %+v, want the rate-limited result", e)
+	}
+	if e := r.next(); e.Type != TypeRateLimit || e.Domain != "busy.test" {
+		t.Errorf("event 4 = %+v, want a rate_limit event", e)
+	}
+	e := r.next()
+	if e.Type != TypeProgress || e.Progress == nil {
+		t.Fatalf("event 5 = %+v, want progress", e)
+	}
+	if want := (Progress{Processed: 2, Total: 10, Available: 1, RateLimited: 1, ElapsedMs: 2000, Rate: 1}); *e.Progress != want {
+		t.Errorf("progress = %+v, want %+v", *e.Progress, want)
+	}
+
+	commands := []struct {
+		line        string
+		wantType    string
+		wantCommand string
+	}{
+		{line: `{"command":"pause"}`, wantType: TypeAck, wantCommand: CommandPause},
+		{line: `{"command":"pause"}`, wantType: TypeError, wantCommand: CommandPause},
+		{line: `  {"command": "resume"}  `, wantType: TypeAck, wantCommand: CommandResume},
+		{line: `{"command":"resume"}`, wantType: TypeError, wantCommand: CommandResume},
+		{line: `{"command":"restart"}`, wantType: TypeError, wantCommand: "restart"},
+		{line: `pause`, wantType: TypeError},
+		{line: `{"command":"stop"}`, wantType: TypeAck, wantCommand: CommandStop},
+	}
+	for _, cmd := range commands {
+		r.command(cmd.line)
+		if e := r.next(); e.Type != cmd.wantType || e.Command != cmd.wantCommand {
+			t.Errorf("answer to %s = %+v, want %s for %q", cmd.line, e, cmd.wantType, cmd.wantCommand)
+		}
+	}
+	// Empty lines are ignored
+	r.command("")
+
+	controller.mu.Lock()
+	if !controller.stopped || len(controller.commands) != 5 {
+		t.Errorf("controller got %v, stopped %v; want 5 commands ending with a stop", controller.commands, controller.stopped)
+	}
+	controller.mu.Unlock()
+
+	// Close waits for the wrapper to read the last event
+	stream.State(scanner.StateAborted)
+	closed := make(chan error, 1)
+	go func() { closed <- stream.Close() }()
+	if e := r.next(); e.Type != TypeState || e.State != "aborted" {
+		t.Errorf("last event = %+v, want the aborted state", e)
+	}
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	if r.lines.Scan() {
+		t.Errorf("event after Close: %s", r.lines.Text())
+	}
+	select {
+	case <-controlled:
+	case <-time.After(5 * time.Second):
+		t.Error("Control() did not return after Close")
+	}
+	// Events after Close are discarded
+	stream.State(scanner.StateFinished)
+}
+
+func TestStreamConversation(t *testing.T) {
+	local, wrapper := net.Pipe()
+	defer wrapper.Close()
+	testConversation(t, New(local, local), wrapper)
+}
+
+// stalledConn is a connection whose reader does not read until released
+type stalledConn struct {
+	release chan struct{}
+	once    sync.Once
+}
+
+func (c *stalledConn) Write(p []byte) (int, error) {
+	<-c.release
+	return len(p), nil
+}
+
+func (c *stalledConn) Close() error {
+	c.once.Do(func() { close(c.release) })
+	return nil
+}
+
+func TestStreamDropsForStalledReader(t *testing.T) {
+	conn := &stalledConn{release: make(chan struct{})}
+	stream := New(conn, nil)
+
+	// Sending never blocks: what does not fit in the buffer is dropped
+	const sent = 3 * bufferSize
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < sent; i++ {
+			stream.Result(types.DomainResult{Domain: "stalled.test"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sending events blocked on the stalled reader")
+	}
+	// The writer holds the events it has buffered and the queue bufferSize more
+	if dropped := stream.Dropped(); dropped < sent/2 || dropped > sent-bufferSize {
+		t.Errorf("Dropped() = %d, want %d to %d", dropped, sent/2, sent-bufferSize)
+	}
+
+	conn.Close()
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Control returns at once without a reader
+	stream.Control(&fakeController{})
+}
+
+// failingConn is a connection whose reader is gone
+type failingConn struct{}
+
+func (failingConn) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+func (failingConn) Close() error              { return nil }
+
+func TestStreamSurvivesGoneReader(t *testing.T) {
+	stream := New(failingConn{}, nil)
+	for i := 0; i < 2*bufferSize; i++ {
+		stream.Result(types.DomainResult{Domain: "gone.test"})
+	}
+	closed := make(chan error)
+	go func() { closed <- stream.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(drainTimeout / 2):
+		t.Fatal("Close() waited for a reader that is gone")
+	}
+}
@@ -0,0 +1,105 @@
+//go:build !windows
+
+package events
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// inherit duplicates the descriptor of f as a scanner process inherits it; the stream
+// opened on it owns the duplicate
+func inherit(t *testing.T, f *os.File) int {
+	t.Helper()
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return fd
+}
+
+func TestOpenSocketpair(t *testing.T) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapperEnd := os.NewFile(uintptr(fds[1]), "wrapper")
+	wrapper, err := net.FileConn(wrapperEnd)
+	wrapperEnd.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wrapper.Close()
+
+	// The scanner inherits its end as a descriptor, as with -event-fd
+	stream, err := Open("", inherit(t, os.NewFile(uintptr(fds[0]), "scanner")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testConversation(t, stream, wrapper)
+}
+
+func TestOpenSocketPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+		close(accepted)
+	}()
+
+	stream, err := Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapper := <-accepted
+	if wrapper == nil {
+		t.Fatal("the stream did not connect")
+	}
+	defer wrapper.Close()
+	testConversation(t, stream, wrapper)
+}
+
+func TestOpenPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// A pipe only receives events; Control returns at once
+	stream, err := Open("", inherit(t, w))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Control(&fakeController{})
+	stream.State("started")
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lines := bufio.NewScanner(r)
+	if !lines.Scan() {
+		t.Fatalf("no event in the pipe: %v", lines.Err())
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.sock"), 0); err == nil {
+		t.Error("Open() of a missing socket = nil error")
+	}
+	if _, err := Open("", 1000); err == nil {
+		t.Error("Open() of a closed descriptor = nil error")
+	}
+}
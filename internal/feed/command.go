@@ -0,0 +1,111 @@
+package feed
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"domain-scanner/internal/config"
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/scanner"
+	"domain-scanner/internal/types"
+)
+
+// RunCommand executes the "feed" subcommand and returns the process exit code
+func RunCommand(args []string) int {
+	fs := flag.NewFlagSet("feed", flag.ContinueOnError)
+	configPath := fs.String("config", "config/config.toml", "Path to config file")
+	watchlist := fs.String("watchlist", "", "File with one watched domain per line (default: [feed] watchlist)")
+	feedFile := fs.String("feed", "", "File the dropped domains are appended to (default: [feed] feed_file)")
+	interval := fs.Int("interval", 0, "Seconds between two passes over the watchlist (default: [feed] poll_interval)")
+	once := fs.Bool("once", false, "Run a single pass and exit")
+	fs.Usage = func() {
+		fmt.Println("Usage:")
+		fmt.Println("  domain-scanner feed -watchlist watchlist.txt [-feed dropped_domains.txt] [-interval SECONDS] [-once]")
+		fmt.Println("\nPolls the watched domains until stopped and appends every domain that becomes")
+		fmt.Println("available to the feed file. Domains already in the feed are not checked again.")
+		fmt.Println("\nOptions:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return scanner.FlagExitCode(err)
+	}
+
+	cfg := &types.Config{}
+	if _, err := os.Stat(*configPath); err == nil {
+		if cfg, err = config.LoadConfig(*configPath); err != nil {
+			fmt.Printf("Error loading config file: %v\n", err)
+			return scanner.ExitUsage
+		}
+	} else if err := config.ApplyDefaults(cfg); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return scanner.ExitUsage
+	}
+	domain.SetConfig(cfg)
+
+	if *watchlist == "" {
+		*watchlist = cfg.Feed.Watchlist
+	}
+	if *feedFile == "" {
+		*feedFile = cfg.Feed.FeedFile
+	}
+	if *interval == 0 {
+		*interval = cfg.Feed.PollInterval
+	}
+	if *watchlist == "" {
+		fmt.Println("Error: no watchlist given (use -watchlist or [feed] watchlist)")
+		return scanner.ExitUsage
+	}
+	if *interval < 1 {
+		fmt.Println("Error: -interval must be at least 1 second")
+		return scanner.ExitUsage
+	}
+
+	f, err := Open(*feedFile, time.Duration(cfg.Scanner.Delay)*time.Millisecond, os.Stdout)
+	if err != nil {
+		fmt.Printf("Error reading feed: %v\n", err)
+		return scanner.ExitUsage
+	}
+
+	// Ctrl-C ends the current pass; the feed file is complete after every append
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for pass := 1; ; pass++ {
+		// The watchlist is re-read every pass so it can be edited while the feed runs
+		domains, err := LoadWatchlist(*watchlist)
+		if err != nil {
+			fmt.Printf("Error reading watchlist: %v\n", err)
+			return scanner.ExitAborted
+		}
+
+		dropped, err := f.Poll(ctx, domains)
+		if err != nil {
+			fmt.Printf("Error writing feed: %v\n", err)
+			return scanner.ExitAborted
+		}
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted, stopping the feed")
+			return scanner.ExitOK
+		}
+
+		fmt.Printf("Pass %d finished: %d watched, %d dropped, %d in %s\n",
+			pass, len(domains), len(dropped), len(f.dropped), *feedFile)
+		if *once {
+			return scanner.ExitOK
+		}
+
+		next := time.Duration(*interval) * time.Second
+		fmt.Printf("Next pass at %s\n", time.Now().Add(next).Format("2006-01-02 15:04:05"))
+		select {
+		case <-ctx.Done():
+			fmt.Println("Interrupted, stopping the feed")
+			return scanner.ExitOK
+		case <-time.After(next):
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package feed
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"domain-scanner/internal/worker"
+)
+
+// Statuses recorded for watched domains besides the special status labels
+const (
+	StatusAvailable  = "AVAILABLE"
+	StatusRegistered = "REGISTERED"
+	// StatusUnknown is the status of a domain that has not been checked successfully yet
+	StatusUnknown = "UNKNOWN"
+)
+
+// Feed is an append-only file of domains that became available while being watched.
+// Each line holds the drop time, the domain and the last status seen before the drop.
+type Feed struct {
+	path string
+	// dropped holds the domains already in the feed file; they are no longer checked
+	dropped map[string]bool
+	// last is the most recent status of every watched domain
+	last map[string]string
+	// delay is the pause between two checks of a pass
+	delay time.Duration
+	out   io.Writer
+}
+
+// Open loads the domains already recorded in a feed file; a missing file is an empty feed
+func Open(path string, delay time.Duration, out io.Writer) (*Feed, error) {
+	f := &Feed{
+		path:    path,
+		dropped: make(map[string]bool),
+		last:    make(map[string]string),
+		delay:   delay,
+		out:     out,
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid feed line in %s: %q", path, line)
+		}
+		f.dropped[fields[1]] = true
+	}
+	return f, scanner.Err()
+}
+
+// LoadWatchlist reads one domain per line. Blank lines and lines starting with "#"
+// are skipped, domains are lower-cased and duplicates are dropped.
+func LoadWatchlist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var domains []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		name := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if name == "" || strings.HasPrefix(name, "#") || seen[name] {
+			continue
+		}
+		if !strings.Contains(name, ".") || strings.ContainsAny(name, " \t/") {
+			return nil, fmt.Errorf("%s:%d: invalid domain %q", path, line, name)
+		}
+		seen[name] = true
+		domains = append(domains, name)
+	}
+	return domains, scanner.Err()
+}
+
+// Poll checks every watched domain that is not in the feed yet and appends the ones
+// that became available. It returns the newly dropped domains; cancelling ctx ends
+// the pass early without recording the interrupted check.
+func (f *Feed) Poll(ctx context.Context, domains []string) ([]string, error) {
+	var dropped []string
+	first := true
+	for _, name := range domains {
+		if f.dropped[name] {
+			continue
+		}
+		if !first {
+			select {
+			case <-ctx.Done():
+				return dropped, nil
+			case <-time.After(f.delay):
+			}
+		}
+		first = false
+
+		result := worker.Check(ctx, name)
+		if ctx.Err() != nil {
+			return dropped, nil
+		}
+		if result.Error != nil {
+			fmt.Fprintf(f.out, "Error checking %s: %v (keeping status %s)\n", name, result.Error, f.status(name))
+			continue
+		}
+
+		status := StatusRegistered
+		switch {
+		case result.SpecialStatus != "":
+			status = result.SpecialStatus
+		case result.Available:
+			status = StatusAvailable
+		}
+
+		previous := f.status(name)
+		f.last[name] = status
+		if status != previous && previous != StatusUnknown {
+			fmt.Fprintf(f.out, "%s: %s -> %s\n", name, previous, status)
+		}
+		if status != StatusAvailable {
+			continue
+		}
+
+		if err := f.append(name, previous); err != nil {
+			return dropped, err
+		}
+		dropped = append(dropped, name)
+		fmt.Fprintf(f.out, "DROPPED: %s (last status: %s)\n", name, previous)
+	}
+	return dropped, nil
+}
+
+// status returns the last recorded status of a watched domain
+func (f *Feed) status(name string) string {
+	if status, ok := f.last[name]; ok {
+		return status
+	}
+	return StatusUnknown
+}
+
+// append records a dropped domain at the end of the feed file
+func (f *Feed) append(name, previous string) error {
+	if dir := filepath.Dir(f.path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(file, "%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), name, previous); err != nil {
+		file.Close()
+		return err
+	}
+	f.dropped[name] = true
+	return file.Close()
+}
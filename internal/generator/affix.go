@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Affixes are fixed strings around the generated characters of every name of a
+// keyspace: Prefix "go" with length 3 generates "goaaa" to "gozzz". Only the generated
+// characters count towards the length and the keyspace size.
+type Affixes struct {
+	Prefix string
+	Suffix string
+}
+
+// NormalizeAffix lowercases a name prefix or suffix and rejects characters that
+// cannot be part of a label
+func NormalizeAffix(affix string) (string, error) {
+	affix = strings.ToLower(strings.TrimSpace(affix))
+	for i := 0; i < len(affix); i++ {
+		c := affix[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return "", fmt.Errorf("invalid name affix %q: invalid character %q (use a-z, 0-9 and -)", affix, c)
+		}
+	}
+	return affix, nil
+}
+
+// IsZero reports whether no affix is set
+func (a Affixes) IsZero() bool {
+	return a.Prefix == "" && a.Suffix == ""
+}
+
+// Validate reports an error for affixes that cannot build a valid label with the
+// given lengths of generated characters
+func (a Affixes) Validate(lengths []int) error {
+	for _, affix := range []string{a.Prefix, a.Suffix} {
+		if normalized, err := NormalizeAffix(affix); err != nil {
+			return err
+		} else if normalized != affix {
+			return fmt.Errorf("invalid name affix %q: use lowercase characters", affix)
+		}
+	}
+	if strings.HasPrefix(a.Prefix, "-") {
+		return fmt.Errorf("invalid name prefix %q: a name cannot start with a hyphen", a.Prefix)
+	}
+	if strings.HasSuffix(a.Suffix, "-") {
+		return fmt.Errorf("invalid name suffix %q: a name cannot end with a hyphen", a.Suffix)
+	}
+	for _, length := range lengths {
+		if total := len(a.Prefix) + length + len(a.Suffix); total > maxLabelLength {
+			return fmt.Errorf("names of %d characters with their fixed prefix and suffix are longer than %d characters",
+				total, maxLabelLength)
+		}
+	}
+	return nil
+}
+
+// Label renders a generator pattern with the affixes for display and file names,
+// e.g. "go+D+hub"
+func (a Affixes) Label(pattern string) string {
+	label := pattern
+	if a.Prefix != "" {
+		label = a.Prefix + "+" + label
+	}
+	if a.Suffix != "" {
+		label += "+" + a.Suffix
+	}
+	return label
+}
+
+// Strip removes the affixes from the first label of a domain, keeping its suffix:
+// "goabc.com" becomes "abc.com" for Prefix "go". The bool is false when the domain
+// does not carry the affixes.
+func (a Affixes) Strip(domainName string) (string, bool) {
+	name, suffix := domainName, ""
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name, suffix = name[:idx], name[idx:]
+	}
+	if len(name) < len(a.Prefix)+len(a.Suffix) ||
+		!strings.HasPrefix(name, a.Prefix) || !strings.HasSuffix(name, a.Suffix) {
+		return "", false
+	}
+	return name[len(a.Prefix):len(name)-len(a.Suffix)] + suffix, true
+}
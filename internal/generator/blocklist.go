@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"domain-scanner/internal/types"
+)
+
+// Blocklist holds the strings and patterns, e.g. trademarks, that candidate names
+// must not contain. Matching is case-insensitive and applies to the label only.
+type Blocklist struct {
+	literals []string
+	patterns []*regexp.Regexp
+}
+
+// LoadBlocklist reads a blocklist file with one entry per line. Entries written as
+// /regex/ are regular expressions, all others literal substrings; empty lines and
+// lines starting with # are ignored.
+func LoadBlocklist(path string) (*Blocklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening blocklist: %w", err)
+	}
+	defer file.Close()
+
+	list := &Blocklist{}
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if len(entry) > 2 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+			// Go regexps run in linear time, so blocklist patterns need no ReDoS guard
+			pattern, err := regexp.Compile("(?i)" + entry[1:len(entry)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid blocklist pattern on line %d: %w", lineNo, err)
+			}
+			list.patterns = append(list.patterns, pattern)
+			continue
+		}
+		list.literals = append(list.literals, strings.ToLower(entry))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading blocklist: %w", err)
+	}
+	return list, nil
+}
+
+// BlocklistFromConfig loads the [domain] blocklist file, or returns nil when none is configured
+func BlocklistFromConfig(cfg *types.Config) (*Blocklist, error) {
+	if cfg == nil || cfg.Domain.Blocklist == "" {
+		return nil, nil
+	}
+	return LoadBlocklist(cfg.Domain.Blocklist)
+}
+
+// Len returns the number of entries in the blocklist
+func (b *Blocklist) Len() int {
+	return len(b.literals) + len(b.patterns)
+}
+
+// Match reports the first entry contained in the label of a domain
+func (b *Blocklist) Match(domainName string) (string, bool) {
+	label := strings.ToLower(domainName)
+	if idx := strings.Index(label, "."); idx >= 0 {
+		label = label[:idx]
+	}
+	for _, literal := range b.literals {
+		if strings.Contains(label, literal) {
+			return literal, true
+		}
+	}
+	for _, pattern := range b.patterns {
+		if pattern.MatchString(label) {
+			return "/" + strings.TrimPrefix(pattern.String(), "(?i)") + "/", true
+		}
+	}
+	return "", false
+}
+
+// Filter streams the domains that match no entry, counting the dropped ones in dropped;
+// cancelling ctx stops it
+func (b *Blocklist) Filter(ctx context.Context, domains <-chan string, dropped *int64) <-chan string {
+	filtered := make(chan string, 1000)
+	go func() {
+		defer close(filtered)
+		for name := range domains {
+			if _, ok := b.Match(name); ok {
+				atomic.AddInt64(dropped, 1)
+				continue
+			}
+			if !send(ctx, filtered, name) {
+				return
+			}
+		}
+	}()
+	return filtered
+}
@@ -0,0 +1,130 @@
+package generator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// maxLabelLength is the longest DNS label allowed by RFC 1035
+const maxLabelLength = 63
+
+// wordRegex matches the characters allowed in a word of a combinator list
+var wordRegex = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// LoadWordList reads one word per line from a file. Blank lines and lines starting
+// with "#" are skipped, words are lower-cased and duplicates are dropped.
+func LoadWordList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word == "" || strings.HasPrefix(word, "#") || seen[word] {
+			continue
+		}
+		if !wordRegex.MatchString(word) {
+			return nil, fmt.Errorf("%s:%d: invalid word %q (use a-z, 0-9 and -)", path, line, word)
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("word list %s is empty", path)
+	}
+	return words, nil
+}
+
+// LoadWordLists loads every word list of a combinator run in order
+func LoadWordLists(paths []string) ([][]string, error) {
+	lists := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		words, err := LoadWordList(path)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, words)
+	}
+	return lists, nil
+}
+
+// ValidateWordSeparator reports an error for a separator between the words of a
+// combination that cannot be part of a label; the empty separator joins them directly
+func ValidateWordSeparator(separator string) error {
+	if separator != "" && !wordRegex.MatchString(separator) {
+		return fmt.Errorf("invalid word separator %q (use a-z, 0-9 and -)", separator)
+	}
+	return nil
+}
+
+// CalculateCombinationsCount returns the number of labels built from the word lists,
+// which is the product of the list sizes
+func CalculateCombinationsCount(lists [][]string) int {
+	if len(lists) == 0 {
+		return 0
+	}
+	total := 1
+	for _, words := range lists {
+		total *= len(words)
+	}
+	return total
+}
+
+// GenerateCombinations streams every concatenation of one word from each list, in
+// list order and joined by separator, for the keyspace counter range
+// [offset, offset+limit). A limit of zero means "until the end of the keyspace".
+// Labels longer than 63 characters or with a leading or trailing hyphen are skipped.
+// Cancelling ctx stops the generation.
+func GenerateCombinations(ctx context.Context, lists [][]string, separator string, suffix string, regexFilter string, regexMode types.RegexMode, offset, limit int) (<-chan string, error) {
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	domainChan := make(chan string, 1000)
+
+	go func() {
+		defer close(domainChan)
+
+		total := CalculateCombinationsCount(lists)
+		end := total
+		if limit > 0 && offset+limit < total {
+			end = offset + limit
+		}
+		for counter := offset; counter < end; counter++ {
+			label := combinationAt(lists, separator, counter)
+			if invalidSuffixLabel(label) != "" || !matchesFilter(regex, regexMode, label, suffix) {
+				continue
+			}
+			if !send(ctx, domainChan, label+suffix) {
+				return
+			}
+		}
+	}()
+
+	return domainChan, nil
+}
+
+// combinationAt builds the label for a keyspace counter value; the last list varies fastest
+func combinationAt(lists [][]string, separator string, counter int) string {
+	parts := make([]string, len(lists))
+	for i := len(lists) - 1; i >= 0; i-- {
+		parts[i] = lists[i][counter%len(lists[i])]
+		counter /= len(lists[i])
+	}
+	return strings.Join(parts, separator)
+}
@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// ExcludeList holds the domains a scan skips, e.g. those whose status is already known.
+// Entries with a suffix such as example.com match that domain only, entries without one
+// such as example match the name under every suffix. Matching is case-insensitive.
+type ExcludeList struct {
+	domains map[string]bool
+	names   map[string]bool
+}
+
+// LoadExcludeList reads an exclusion list file with one domain or name per line. Only
+// the first field of a line counts, so result files can be used as they are; empty
+// lines and lines starting with # are ignored.
+func LoadExcludeList(path string) (*ExcludeList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening exclusion list: %w", err)
+	}
+	defer file.Close()
+
+	list := &ExcludeList{domains: make(map[string]bool), names: make(map[string]bool)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		entry := excludeKey(fields[0])
+		if strings.Contains(entry, ".") {
+			list.domains[entry] = true
+		} else if entry != "" {
+			list.names[entry] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading exclusion list: %w", err)
+	}
+	return list, nil
+}
+
+// excludeKey returns the lower-case ASCII form of a domain or name
+func excludeKey(domainName string) string {
+	return asciiDomain(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domainName)), "."))
+}
+
+// Len returns the number of entries in the exclusion list
+func (e *ExcludeList) Len() int {
+	return len(e.domains) + len(e.names)
+}
+
+// Contains reports whether a domain is excluded, by itself or by its name
+func (e *ExcludeList) Contains(domainName string) bool {
+	domainName = excludeKey(domainName)
+	if e.domains[domainName] {
+		return true
+	}
+	name := domainName
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name = name[:idx]
+	}
+	return e.names[name]
+}
+
+// Filter streams the domains that are not excluded, counting the skipped ones in skipped;
+// cancelling ctx stops it
+func (e *ExcludeList) Filter(ctx context.Context, domains <-chan string, skipped *int64) <-chan string {
+	filtered := make(chan string, 1000)
+	go func() {
+		defer close(filtered)
+		for name := range domains {
+			if e.Contains(name) {
+				atomic.AddInt64(skipped, 1)
+				continue
+			}
+			if !send(ctx, filtered, name) {
+				return
+			}
+		}
+	}()
+	return filtered
+}
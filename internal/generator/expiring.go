@@ -0,0 +1,190 @@
+package generator
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// ExpiringOptions describes a CSV list of expiring or pending-delete domains
+type ExpiringOptions struct {
+	// Column is the 1-based column holding the domain name
+	Column int
+	// DateColumn is the 1-based column holding the drop date; zero means none
+	DateColumn int
+	// Delimiter separates the fields; zero uses a comma
+	Delimiter rune
+	// SkipHeader ignores the first row
+	SkipHeader bool
+	// Suffixes keeps only domains ending in one of them, normalized like NormalizeSuffix;
+	// empty keeps every domain
+	Suffixes []string
+	// RegexFilter and RegexMode filter the domains like the keyspace generator does
+	RegexFilter string
+	RegexMode   types.RegexMode
+}
+
+// ExpiringList is the outcome of reading an expiring domain list
+type ExpiringList struct {
+	// Domains holds the ingested domains in file order, without duplicates
+	Domains []string
+	// DropDates maps a domain to the drop date of its row when a date column is set
+	DropDates map[string]string
+	// Rows is the number of data rows read; every row is either ingested or skipped
+	Rows     int
+	Ingested int
+	// Filtered counts rows skipped by the suffix or regex filter, Duplicates rows
+	// repeating an ingested domain and Invalid rows that could not be read
+	Filtered   int
+	Duplicates int
+	Invalid    int
+	// Problems describes the invalid rows with their row numbers
+	Problems []string
+}
+
+// Skipped returns the number of rows that were not ingested
+func (l *ExpiringList) Skipped() int {
+	return l.Filtered + l.Duplicates + l.Invalid
+}
+
+// LoadExpiringList reads the domains of an expiring domain list. Rows that cannot be
+// read are skipped and described in Problems with their 1-based row numbers; only an
+// unreadable file or invalid options are errors.
+func LoadExpiringList(path string, opts ExpiringOptions) (*ExpiringList, error) {
+	if opts.Column < 1 {
+		return nil, fmt.Errorf("invalid domain column %d (columns start at 1)", opts.Column)
+	}
+	if opts.DateColumn < 0 {
+		return nil, fmt.Errorf("invalid date column %d (columns start at 1)", opts.DateColumn)
+	}
+	regex, err := compileFilter(opts.RegexFilter)
+	if err != nil {
+		return nil, err
+	}
+	suffixes := make([]string, 0, len(opts.Suffixes))
+	for _, suffix := range opts.Suffixes {
+		if strings.TrimSpace(suffix) == "" {
+			continue
+		}
+		suffix, err := NormalizeSuffix(suffix)
+		if err != nil {
+			return nil, err
+		}
+		suffixes = append(suffixes, suffix)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening expiring list: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	list := &ExpiringList{DropDates: make(map[string]string)}
+	seen := make(map[string]bool)
+	invalid := func(row int, format string, args ...interface{}) {
+		list.Invalid++
+		list.Problems = append(list.Problems, fmt.Sprintf("row %d: %s", row, fmt.Sprintf(format, args...)))
+	}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if row == 1 && opts.SkipHeader {
+			if err != nil && !errors.As(err, new(*csv.ParseError)) {
+				return nil, fmt.Errorf("error reading expiring list: %w", err)
+			}
+			continue
+		}
+		list.Rows++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("error reading expiring list: %w", err)
+			}
+			invalid(row, "%v", parseErr.Err)
+			continue
+		}
+		if len(record) < opts.Column || len(record) < opts.DateColumn {
+			invalid(row, "has %d columns", len(record))
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(record[opts.Column-1]), "."))
+		if reason := invalidDomain(name); reason != "" {
+			invalid(row, "invalid domain %q: %s", name, reason)
+			continue
+		}
+		suffix, ok := matchSuffix(name, suffixes)
+		if !ok || !matchesFilter(regex, opts.RegexMode, strings.TrimSuffix(name, suffix), suffix) {
+			list.Filtered++
+			continue
+		}
+		if seen[name] {
+			list.Duplicates++
+			continue
+		}
+		seen[name] = true
+		list.Ingested++
+		list.Domains = append(list.Domains, name)
+		if opts.DateColumn > 0 {
+			list.DropDates[name] = strings.TrimSpace(record[opts.DateColumn-1])
+		}
+	}
+	return list, nil
+}
+
+// matchSuffix returns the suffix of a domain among the allowed ones. Without allowed
+// suffixes every domain passes with its last label as suffix.
+func matchSuffix(name string, suffixes []string) (string, bool) {
+	if len(suffixes) == 0 {
+		return name[strings.LastIndex(name, "."):], true
+	}
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+			return suffix, true
+		}
+	}
+	return "", false
+}
+
+// invalidDomain describes why a name is not a valid ASCII domain name, or returns ""
+func invalidDomain(name string) string {
+	if name == "" {
+		return "empty"
+	}
+	if !strings.Contains(name, ".") {
+		return "no suffix"
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return "empty label"
+		}
+		if len(label) > maxLabelLength {
+			return "label longer than 63 characters"
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return "label starts or ends with a hyphen"
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return "invalid characters (use the punycode form of IDNs)"
+			}
+		}
+	}
+	return ""
+}
@@ -1,8 +1,10 @@
 package generator
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"math"
+	"regexp"
 	"strings"
 	"time"
 
@@ -10,128 +12,559 @@ import (
 	"github.com/dlclark/regexp2"
 )
 
-// GenerateDomains returns a streaming domain channel instead of generating all domains at once
-func GenerateDomains(length int, suffix string, pattern string, regexFilter string, regexMode types.RegexMode) <-chan string {
+// GenerateDomains returns a streaming domain channel instead of generating all domains at once.
+// Cancelling ctx stops the generation and closes the channel.
+func GenerateDomains(ctx context.Context, length int, suffix string, pattern string, regexFilter string, regexMode types.RegexMode) (<-chan string, error) {
+	return GenerateDomainsRange(ctx, length, suffix, pattern, regexFilter, regexMode, 0, 0)
+}
+
+// GenerateDomainsRange streams the domains whose keyspace counter lies in
+// [offset, offset+limit). A limit of zero means "until the end of the keyspace". An
+// invalid pattern or regex filter is an error, and nothing is generated.
+func GenerateDomainsRange(ctx context.Context, length int, suffix string, pattern string, regexFilter string, regexMode types.RegexMode, offset, limit int) (<-chan string, error) {
+	charset, ok := charsetFor(pattern)
+	if !ok {
+		return nil, ValidatePattern(pattern)
+	}
+
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	domainChan := make(chan string, 1000) // Buffer pool for better performance
+
+	go func() {
+		defer close(domainChan)
+		generateCombinationsIterative(ctx, domainChan, charset, length, Affixes{}, suffix, regex, regexMode, offset, limit)
+	}()
+
+	return domainChan, nil
+}
+
+// ValidatePattern reports an error for an unknown domain pattern
+func ValidatePattern(pattern string) error {
+	if charset, ok := charsetFor(pattern); ok && pattern[0] == '[' {
+		if normalized, err := NormalizeCharset(charset); err != nil || normalized != charset {
+			return fmt.Errorf("invalid custom charset pattern %q", pattern)
+		}
+		return nil
+	}
+	if _, ok := charsetFor(pattern); !ok {
+		return fmt.Errorf("invalid pattern %q (use d for numbers, D for letters, a for alphanumeric, h for alphanumeric with hyphens)", pattern)
+	}
+	return nil
+}
+
+// ValidateFilter reports an error for a regex filter the generator would reject
+func ValidateFilter(regexFilter string) error {
+	_, err := compileFilter(regexFilter)
+	return err
+}
+
+// charsetFor returns the character set used by a domain pattern; a custom charset
+// pattern from CharsetPattern holds its characters in brackets
+func charsetFor(pattern string) (string, bool) {
 	letters := "abcdefghijklmnopqrstuvwxyz"
 	numbers := "0123456789"
 
-	var regex *regexp2.Regexp
-	var err error
-	if regexFilter != "" {
-		// Validate regex complexity
-		if err := validateRegexComplexity(regexFilter); err != nil {
-			fmt.Printf("Regex pattern rejected: %v\n", err)
-			os.Exit(1)
+	if len(pattern) > 2 && strings.HasPrefix(pattern, "[") && strings.HasSuffix(pattern, "]") {
+		return pattern[1 : len(pattern)-1], true
+	}
+	switch pattern {
+	case "d":
+		return numbers, true
+	case "D":
+		return letters, true
+	case "a":
+		return letters + numbers, true
+	case "h":
+		return letters + numbers + "-", true
+	default:
+		return "", false
+	}
+}
+
+// NormalizeCharset folds a custom charset to lower case and removes repeated
+// characters, keeping the order of first appearance, which is the generation order.
+// Only a-z, 0-9 and the hyphen are allowed.
+func NormalizeCharset(charset string) (string, error) {
+	seen := make(map[rune]bool)
+	var normalized strings.Builder
+	for _, c := range strings.ToLower(charset) {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return "", fmt.Errorf("invalid charset %q: %q is not allowed (use a-z, 0-9 and -)", charset, c)
+		}
+		if !seen[c] {
+			seen[c] = true
+			normalized.WriteRune(c)
 		}
+	}
+	if normalized.Len() == 0 {
+		return "", fmt.Errorf("invalid charset %q: no characters", charset)
+	}
+	return normalized.String(), nil
+}
 
-		regex, err = regexp2.Compile(regexFilter, regexp2.None)
-		if err != nil {
-			fmt.Printf("Invalid regex pattern: %v\n", err)
-			os.Exit(1)
+// CharsetPattern returns the pattern generating names from a normalized custom
+// charset; it is accepted wherever the built-in patterns d, D and a are
+func CharsetPattern(charset string) string {
+	return "[" + charset + "]"
+}
+
+// ExcludeChars returns the pattern generating names from the characters of a pattern
+// without those of exclude, e.g. the 24 letters other than q and v for pattern D and
+// exclude "qv"; the order of the remaining characters is kept. An empty exclude
+// returns the pattern unchanged, excluding every character is an error.
+func ExcludeChars(pattern, exclude string) (string, error) {
+	if exclude == "" {
+		return pattern, nil
+	}
+	excluded, err := NormalizeCharset(exclude)
+	if err != nil {
+		return "", fmt.Errorf("invalid excluded characters %q: use a-z, 0-9 and -", exclude)
+	}
+	charset, ok := charsetFor(pattern)
+	if !ok {
+		return "", fmt.Errorf("invalid pattern %q", pattern)
+	}
+	var kept strings.Builder
+	for i := 0; i < len(charset); i++ {
+		if !strings.ContainsRune(excluded, rune(charset[i])) {
+			kept.WriteByte(charset[i])
 		}
+	}
+	// A hyphen alone cannot form a name
+	if remaining := kept.String(); remaining != "" && remaining != "-" {
+		return CharsetPattern(remaining), nil
+	}
+	return "", fmt.Errorf("excluding %q leaves no characters to generate names from (charset %q)", excluded, charset)
+}
 
-		// Set timeout protection against ReDoS attacks
-		regex.MatchTimeout = 100 * time.Millisecond
+// compileFilter validates and compiles a regex filter; an empty filter yields nil
+func compileFilter(regexFilter string) (*regexp2.Regexp, error) {
+	if regexFilter == "" {
+		return nil, nil
 	}
 
-	domainChan := make(chan string, 1000) // Buffer pool for better performance
+	// Validate regex complexity
+	if err := validateRegexComplexity(regexFilter); err != nil {
+		return nil, fmt.Errorf("regex pattern rejected: %w", err)
+	}
 
-	go func() {
-		defer close(domainChan)
+	regex, err := regexp2.Compile(regexFilter, regexp2.None)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+	}
 
-		switch pattern {
-		case "d":
-			generateCombinationsIterative(domainChan, numbers, length, suffix, regex, regexMode)
-		case "D":
-			generateCombinationsIterative(domainChan, letters, length, suffix, regex, regexMode)
-		case "a":
-			generateCombinationsIterative(domainChan, letters+numbers, length, suffix, regex, regexMode)
-		default:
-			fmt.Println("Invalid pattern. Use -d for numbers, -D for letters, -a for alphanumeric")
-			os.Exit(1)
+	// Set timeout protection against ReDoS attacks
+	regex.MatchTimeout = 100 * time.Millisecond
+	return regex, nil
+}
+
+// nameAt builds the domain name (without suffix) for a keyspace counter value
+func nameAt(charset string, length, counter int) string {
+	charsetSize := len(charset)
+	name := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		name[i] = charset[counter%charsetSize]
+		counter /= charsetSize
+	}
+	return string(name)
+}
+
+// CounterOf returns the keyspace counter value that generates a domain for a pattern,
+// the inverse of the generator's counter-to-name mapping. The suffix is ignored; the
+// bool is false when the name contains characters outside the pattern's charset.
+func CounterOf(domainName string, pattern string) (int, bool) {
+	charset, ok := charsetFor(pattern)
+	if !ok {
+		return 0, false
+	}
+	name := domainName
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name = name[:idx]
+	}
+
+	counter := 0
+	for i := 0; i < len(name); i++ {
+		digit := strings.IndexByte(charset, name[i])
+		if digit < 0 || counter > (MaxCount-digit)/len(charset) {
+			return 0, false
 		}
-	}()
+		counter = counter*len(charset) + digit
+	}
+	return counter, true
+}
 
-	return domainChan
+// matchesFilter applies the regex filter to a name according to the regex mode
+func matchesFilter(regex *regexp2.Regexp, regexMode types.RegexMode, name, suffix string) bool {
+	if regex == nil {
+		return true
+	}
+	input := name + suffix
+	if regexMode == types.RegexModePrefix {
+		input = name
+	}
+	match, err := safeRegexMatch(regex, input)
+	if err != nil {
+		// Skip domain on regex matching error
+		return false
+	}
+	return match
 }
 
-// generateCombinationsIterative uses iterative method instead of recursive to prevent stack overflow
-func generateCombinationsIterative(domainChan chan<- string, charset string, length int, suffix string, regex *regexp2.Regexp, regexMode types.RegexMode) {
-	charsetSize := len(charset)
-	if charsetSize == 0 || length <= 0 {
+// generateCombinationsIterative uses iterative method instead of recursive to prevent stack overflow.
+// The affixes are added around every generated name before it is filtered. The names
+// are stepped through by an odometer, so keyspaces too large for an int counter are
+// generated to their end as well.
+func generateCombinationsIterative(ctx context.Context, domainChan chan<- string, charset string, length int, affixes Affixes, suffix string, regex *regexp2.Regexp, regexMode types.RegexMode, offset, limit int) {
+	if len(charset) == 0 || length <= 0 {
 		return
 	}
+	walkLengths(charset, []int{length}, offset, limit, func(name string) bool {
+		current := affixes.Prefix + name + affixes.Suffix
+		return !matchesKeyspace(regex, regexMode, current, suffix) || send(ctx, domainChan, current+suffix)
+	})
+}
 
-	// Use counter method to generate combinations
-	total := 1
-	for i := 0; i < length; i++ {
-		total *= charsetSize
+// odometer steps through the names of a charset and length in ascending counter order.
+// It keeps the digits of the current name instead of its counter, so that it runs
+// through keyspaces too large for an int to their end.
+type odometer struct {
+	charset string
+	digits  []int
+	name    []byte
+}
+
+// newOdometer starts an odometer at the name of a counter value
+func newOdometer(charset string, length, counter int) *odometer {
+	o := &odometer{charset: charset, digits: make([]int, length), name: make([]byte, length)}
+	for i := length - 1; i >= 0; i-- {
+		o.digits[i] = counter % len(charset)
+		o.name[i] = charset[o.digits[i]]
+		counter /= len(charset)
 	}
+	return o
+}
 
-	sentCount := 0
-	for counter := 0; counter < total; counter++ {
-		current := ""
-		temp := counter
-
-		// Generate domain string from counter
-		for i := 0; i < length; i++ {
-			current = string(charset[temp%charsetSize]) + current
-			temp /= charsetSize
-		}
-
-		domain := current + suffix
-		var match bool
-		switch regexMode {
-		case types.RegexModeFull:
-			if regex == nil {
-				match = true
-			} else {
-				var err error
-				match, err = safeRegexMatch(regex, domain)
-				if err != nil {
-					// Skip domain on regex matching error
-					match = false
-				}
+// next advances to the following name; it returns false after the last name
+func (o *odometer) next() bool {
+	for i := len(o.digits) - 1; i >= 0; i-- {
+		o.digits[i]++
+		if o.digits[i] < len(o.charset) {
+			o.name[i] = o.charset[o.digits[i]]
+			return true
+		}
+		o.digits[i] = 0
+		o.name[i] = o.charset[0]
+	}
+	return false
+}
+
+// walkLengths calls emit for the names of the concatenated keyspaces of several lengths
+// in ascending order, from the counter offset on, until emit returns false or limit
+// names were visited; a limit of zero visits every name to the end of the last length
+func walkLengths(charset string, lengths []int, offset, limit int, emit func(name string) bool) {
+	for _, length := range lengths {
+		if size, ok := keyspaceSize(len(charset), length); ok && offset >= size {
+			offset -= size
+			continue
+		}
+		o := newOdometer(charset, length, offset)
+		offset = 0
+		for {
+			if !emit(string(o.name)) {
+				return
+			}
+			if limit--; limit == 0 {
+				return
+			}
+			if !o.next() {
+				break
+			}
+		}
+	}
+}
+
+// GenerateList streams the domains of a list of names, in order, for the counter range
+// [offset, offset+limit) over the names; a limit of zero means "until the last name".
+// Cancelling ctx stops the generation.
+func GenerateList(ctx context.Context, names []string, suffix string, regexFilter string, regexMode types.RegexMode, offset, limit int) (<-chan string, error) {
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	domainChan := make(chan string, 1000)
+
+	go func() {
+		defer close(domainChan)
+		end := len(names)
+		if limit > 0 && offset+limit < end {
+			end = offset + limit
+		}
+		for counter := offset; counter < end; counter++ {
+			if matchesFilter(regex, regexMode, names[counter], suffix) && !send(ctx, domainChan, names[counter]+suffix) {
+				return
 			}
-		case types.RegexModePrefix:
-			if regex == nil {
-				match = true
-			} else {
-				var err error
-				match, err = safeRegexMatch(regex, current)
-				if err != nil {
-					// Skip domain on regex matching error
-					match = false
-				}
+		}
+	}()
+
+	return domainChan, nil
+}
+
+// send delivers a domain on a generator channel; it returns false without sending
+// once ctx is cancelled, when the generator should stop
+func send(ctx context.Context, domainChan chan<- string, domainName string) bool {
+	select {
+	case domainChan <- domainName:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Range is the keyspace counter interval [Offset, Offset+Limit) of a batch
+type Range struct {
+	Offset int
+	Limit  int
+	// Count is the number of domains in the range that pass the regex filter
+	Count int
+}
+
+// SplitByCount divides the keyspace into at most batches contiguous ranges that
+// hold approximately the same number of domains passing the regex filter.
+// The ranges cover the whole keyspace without overlapping.
+func SplitByCount(length int, suffix string, pattern string, regexFilter string, regexMode types.RegexMode, batches int) ([]Range, error) {
+	charset, ok := charsetFor(pattern)
+	if !ok {
+		return nil, fmt.Errorf("invalid pattern: %s", pattern)
+	}
+	if batches < 1 {
+		return nil, fmt.Errorf("batch count must be at least 1")
+	}
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	total := CalculateDomainsCount(length, pattern)
+	if total == 0 {
+		return nil, nil
+	}
+
+	// Without a filter every counter value is a candidate, so split arithmetically;
+	// hyphen charsets skip some counter values and are enumerated like a filter
+	if regex == nil && !strings.Contains(charset, "-") {
+		if batches > total {
+			batches = total
+		}
+		ranges := make([]Range, 0, batches)
+		offset := 0
+		for i := 0; i < batches; i++ {
+			limit := total / batches
+			if i < total%batches {
+				limit++
 			}
+			ranges = append(ranges, Range{Offset: offset, Limit: limit, Count: limit})
+			offset += limit
 		}
+		return ranges, nil
+	}
 
-		if match {
-			sentCount++
-			domainChan <- domain
+	// First pass: count the candidates passing the filter
+	matches := 0
+	for counter := 0; counter < total; counter++ {
+		if matchesKeyspace(regex, regexMode, nameAt(charset, length, counter), suffix) {
+			matches++
 		}
 	}
+	if matches == 0 {
+		return []Range{{Offset: 0, Limit: total}}, nil
+	}
+	if batches > matches {
+		batches = matches
+	}
+
+	// Second pass: close a range whenever it has reached its share of candidates
+	var ranges []Range
+	current := Range{}
+	for counter := 0; counter < total; counter++ {
+		if matchesKeyspace(regex, regexMode, nameAt(charset, length, counter), suffix) {
+			current.Count++
+		}
+		target := matches / batches
+		if len(ranges) < matches%batches {
+			target++
+		}
+		if current.Count == target && len(ranges) < batches-1 {
+			current.Limit = counter + 1 - current.Offset
+			ranges = append(ranges, current)
+			current = Range{Offset: counter + 1}
+		}
+	}
+	current.Limit = total - current.Offset
+	ranges = append(ranges, current)
+	return ranges, nil
 }
 
-// CalculateDomainsCount calculates the total number of domains for given pattern and length
+// simplePrefixRegex matches filters of the form "^abc" or "^abc.*" whose match count
+// can be derived without enumerating the keyspace
+var simplePrefixRegex = regexp.MustCompile(`^\^([a-z0-9]+)(\.\*)?$`)
+
+// ExpectedCount returns the number of domains generated for the keyspace counter range
+// [offset, offset+limit). Unfiltered and simple prefix filters are counted analytically,
+// other filters by a dry enumeration of at most maxEnumerate candidates. The returned
+// bool is false when the range is larger than maxEnumerate and the count is unknown.
+func ExpectedCount(length int, suffix string, pattern string, regexFilter string, regexMode types.RegexMode, offset, limit, maxEnumerate int) (int, bool, error) {
+	charset, ok := charsetFor(pattern)
+	if !ok {
+		return 0, false, fmt.Errorf("invalid pattern %q", pattern)
+	}
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return 0, false, err
+	}
+
+	total := CalculateDomainsCount(length, pattern)
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	if offset >= end {
+		return 0, true, nil
+	}
+	hyphens := strings.Contains(charset, "-")
+	if regex == nil && !hyphens {
+		return end - offset, true, nil
+	}
+	if regex == nil && offset == 0 && end == total {
+		return CalculateNamesCount(length, pattern), true, nil
+	}
+
+	// A literal prefix fixes the leading characters, the rest of the name is free
+	if m := simplePrefixRegex.FindStringSubmatch(regexFilter); m != nil && !hyphens && offset == 0 && end == total {
+		prefix := m[1]
+		if len(prefix) > length || strings.Trim(prefix, charset) != "" {
+			return 0, true, nil
+		}
+		return CalculateDomainsCount(length-len(prefix), pattern), true, nil
+	}
+
+	if end-offset > maxEnumerate {
+		return 0, false, nil
+	}
+	count := 0
+	for counter := offset; counter < end; counter++ {
+		if matchesKeyspace(regex, regexMode, nameAt(charset, length, counter), suffix) {
+			count++
+		}
+	}
+	return count, true, nil
+}
+
+// CalculateDomainsCount calculates the size of the keyspace of a pattern and length,
+// the counter range of offsets and limits. For charsets with a hyphen it is an upper
+// bound of the generated domains; CalculateNamesCount gives the exact number. For
+// PatternPronounceable the length is the number of syllables and the count that of
+// their combinations. Keyspaces that do not fit an int count as MaxCount.
 func CalculateDomainsCount(length int, pattern string) int {
-	var charsetSize int
-	switch pattern {
-	case "d": // Pure numbers
-		charsetSize = 10 // 0-9
-	case "D": // Pure letters
-		charsetSize = 26 // a-z
-	case "a": // Alphanumeric
-		charsetSize = 36 // a-z + 0-9
-	default:
+	if pattern == PatternPronounceable {
+		return pronounceableCount(length)
+	}
+	charset, ok := charsetFor(pattern)
+	if !ok {
 		return 0
 	}
+	total, ok := keyspaceSize(len(charset), length)
+	if !ok {
+		return MaxCount
+	}
+	return total
+}
+
+// MaxCount is the count of keyspaces whose size does not fit an int. They hold more
+// names than that and are generated all the same, but cannot be shuffled or sampled.
+const MaxCount = math.MaxInt
+
+// FormatCount renders a count for display, MaxCount as "more than" it
+func FormatCount(count int) string {
+	if count == MaxCount {
+		return fmt.Sprintf("more than %d", count)
+	}
+	return fmt.Sprint(count)
+}
+
+// AddCounts returns the sum of two counts, saturating at MaxCount
+func AddCounts(a, b int) int {
+	if a > MaxCount-b {
+		return MaxCount
+	}
+	return a + b
+}
+
+// MulCounts returns the product of two non-negative counts, saturating at MaxCount
+func MulCounts(a, b int) int {
+	if a > 0 && b > MaxCount/a {
+		return MaxCount
+	}
+	return a * b
+}
 
+// keyspaceSize returns size^length, or false when it does not fit an int
+func keyspaceSize(size, length int) (int, bool) {
 	total := 1
 	for i := 0; i < length; i++ {
-		total *= charsetSize
+		if size > 0 && total > math.MaxInt/size {
+			return 0, false
+		}
+		total *= size
 	}
-	return total
+	return total, true
+}
+
+// CalculateNamesCount returns the number of names of a pattern and length the generator
+// produces without a regex filter: the keyspace minus, for charsets with a hyphen, the
+// names with a leading, trailing or doubled hyphen
+func CalculateNamesCount(length int, pattern string) int {
+	if pattern == PatternPronounceable {
+		return CalculateDomainsCount(length, pattern)
+	}
+	charset, ok := charsetFor(pattern)
+	if !ok || length < 1 {
+		return 0
+	}
+	if !strings.Contains(charset, "-") {
+		return CalculateDomainsCount(length, pattern)
+	}
+
+	// Count by the last character: names ending in a non-hyphen may be followed by
+	// anything, names ending in a hyphen only by a non-hyphen
+	others := len(charset) - 1
+	endOther, endHyphen := others, 0
+	for i := 1; i < length; i++ {
+		endOther, endHyphen = MulCounts(others, AddCounts(endOther, endHyphen)), endOther
+	}
+	return endOther
+}
+
+// HyphenCharset reports whether the charset of a pattern has a hyphen, so that the
+// generator skips the names with a leading, trailing or doubled one
+func HyphenCharset(pattern string) bool {
+	charset, _ := charsetFor(pattern)
+	return strings.Contains(charset, "-")
+}
+
+// matchesKeyspace reports whether a name of the keyspace is generated: it must be a
+// valid label, without a leading, trailing or doubled hyphen (which also rules out the
+// "--" at positions 3-4 reserved for ACE labels), and pass the regex filter
+func matchesKeyspace(regex *regexp2.Regexp, regexMode types.RegexMode, name, suffix string) bool {
+	if strings.Contains(name, "-") &&
+		(name[0] == '-' || name[len(name)-1] == '-' || strings.Contains(name, "--")) {
+		return false
+	}
+	return matchesFilter(regex, regexMode, name, suffix)
 }
 
 // validateRegexComplexity checks regex complexity to prevent potential ReDoS attacks
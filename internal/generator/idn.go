@@ -0,0 +1,208 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+
+	"domain-scanner/internal/types"
+)
+
+// IsUnicodeCharset reports whether a charset holds characters other than ASCII, such
+// as "äöü" or "中国", which generate internationalized names
+func IsUnicodeCharset(charset string) bool {
+	for _, c := range charset {
+		if c > unicode.MaxASCII {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeUnicodeCharset folds a Unicode charset to lower case and removes repeated
+// characters and white space, keeping the order of first appearance, which is the
+// generation order. Besides a-z, 0-9 and the hyphen only letters, marks and digits
+// are allowed.
+func NormalizeUnicodeCharset(charset string) (string, error) {
+	seen := make(map[rune]bool)
+	var normalized strings.Builder
+	for _, c := range charset {
+		if unicode.IsSpace(c) {
+			continue
+		}
+		c = unicode.ToLower(c)
+		if c <= unicode.MaxASCII && !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') ||
+			c > unicode.MaxASCII && !unicode.IsLetter(c) && !unicode.IsMark(c) && !unicode.IsDigit(c) {
+			return "", fmt.Errorf("invalid charset %q: %q is not allowed (use letters, digits and -)", charset, c)
+		}
+		if !seen[c] {
+			seen[c] = true
+			normalized.WriteRune(c)
+		}
+	}
+	if normalized.Len() == 0 {
+		return "", fmt.Errorf("invalid charset %q: no characters", charset)
+	}
+	return normalized.String(), nil
+}
+
+// LoadCharsetFile reads a charset from a file; white space and line breaks between
+// the characters are ignored
+func LoadCharsetFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(string(data)), ""), nil
+}
+
+// CalculateIDNCount returns the size of the keyspace of a Unicode charset over several
+// lengths, counted in characters rather than bytes. Keyspaces that do not fit an int
+// are an error.
+func CalculateIDNCount(charset string, lengths []int) (int, error) {
+	size := len([]rune(charset))
+	total := 0
+	for _, length := range lengths {
+		count := 1
+		for i := 0; i < length; i++ {
+			if count > math.MaxInt/size {
+				return 0, fmt.Errorf("the keyspace of %d characters of length %d is too large", size, length)
+			}
+			count *= size
+		}
+		if total > math.MaxInt-count {
+			return 0, fmt.Errorf("the keyspace of %d characters of lengths %s is too large", size, FormatLengths(lengths))
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// ToASCII returns the ASCII (punycode) form of a Unicode label, e.g. "xn--br-via" for
+// "bär". The bool is false when the label is not a valid internationalized label or
+// its ASCII form exceeds the 63-byte limit of DNS labels.
+func ToASCII(label string) (string, bool) {
+	ace, err := idna.Lookup.ToASCII(label)
+	if err != nil || ace == "" || len(ace) > maxLabelLength {
+		return "", false
+	}
+	return ace, true
+}
+
+// asciiDomain converts a domain or name given in Unicode to its ASCII form, e.g.
+// "bär.de" to "xn--br-via.de". ASCII input and input that is no valid internationalized
+// name are returned unchanged, so that the caller's validation reports the latter.
+func asciiDomain(name string) string {
+	if !IsUnicodeCharset(name) {
+		return name
+	}
+	if ace, err := idna.Lookup.ToASCII(name); err == nil {
+		return ace
+	}
+	return name
+}
+
+// GenerateIDN streams the internationalized domains of a Unicode charset over several
+// lengths in their ASCII (punycode) form. Lengths count characters, the keyspaces of
+// the lengths are concatenated as by GenerateDomainsLengths and the counter range is
+// [offset, offset+limit); a limit of zero means "until the end of the last keyspace".
+// The counters are visited in the given order and the regex filter sees the Unicode
+// name. Names that are not valid labels, e.g. those starting with a combining mark,
+// and names whose ASCII form exceeds 63 bytes are skipped. Cancelling ctx stops the
+// generation.
+func GenerateIDN(ctx context.Context, charset string, lengths []int, suffix string, regexFilter string, regexMode types.RegexMode, offset, limit int, order Order) (<-chan string, error) {
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	domainChan := make(chan string, 1000)
+	runes := []rune(charset)
+
+	go func() {
+		defer close(domainChan)
+		sizes := make([]int, len(lengths))
+		end := 0
+		for i, length := range lengths {
+			size, err := CalculateIDNCount(charset, []int{length})
+			if err != nil {
+				return
+			}
+			sizes[i] = size
+			end += size
+		}
+		if limit > 0 && offset+limit < end {
+			end = offset + limit
+		}
+		order.walk(offset, end, func(counter int) bool {
+			i := 0
+			for counter >= sizes[i] {
+				counter -= sizes[i]
+				i++
+			}
+			name := idnNameAt(runes, lengths[i], counter)
+			if !matchesKeyspace(regex, regexMode, name, suffix) {
+				return true
+			}
+			ace, ok := ToASCII(name)
+			return !ok || send(ctx, domainChan, ace+suffix)
+		})
+	}()
+
+	return domainChan, nil
+}
+
+// idnNameAt builds the Unicode name of a length for a keyspace counter value; the last
+// character changes fastest
+func idnNameAt(runes []rune, length, counter int) string {
+	name := make([]rune, length)
+	for i := length - 1; i >= 0; i-- {
+		name[i] = runes[counter%len(runes)]
+		counter /= len(runes)
+	}
+	return string(name)
+}
+
+// IDNCounterOf returns the keyspace counter value that generates a domain from a
+// Unicode charset over several lengths, the inverse of GenerateIDN. The domain may be
+// given in its Unicode or ASCII form; the suffix is ignored. The bool is false when
+// the name is not generated from the charset.
+func IDNCounterOf(domainName, charset string, lengths []int) (int, bool) {
+	name := domainName
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name = name[:idx]
+	}
+	if unicodeName, err := idna.Lookup.ToUnicode(name); err == nil {
+		name = unicodeName
+	}
+	runes, chars := []rune(charset), []rune(name)
+	digits := make(map[rune]int, len(runes))
+	for i, c := range runes {
+		digits[c] = i
+	}
+	start := 0
+	for _, length := range lengths {
+		size, err := CalculateIDNCount(charset, []int{length})
+		if err != nil {
+			return 0, false
+		}
+		if length == len(chars) {
+			counter := 0
+			for _, c := range chars {
+				digit, ok := digits[c]
+				if !ok {
+					return 0, false
+				}
+				counter = counter*len(runes) + digit
+			}
+			return start + counter, true
+		}
+		start += size
+	}
+	return 0, false
+}
@@ -0,0 +1,162 @@
+package generator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// CountNames validates a name list file and returns the number of names it holds.
+// Every line is one name, with or without the suffix; blank lines and lines starting
+// with "#" are skipped. A name with another suffix or invalid characters is an error.
+func CountNames(path, suffix string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("error opening input file: %w", err)
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		_, ok, err := parseName(scanner.Text(), suffix)
+		if err != nil {
+			return 0, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		if ok {
+			count++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("error reading input file %s: %w", path, err)
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("input file %s is empty", path)
+	}
+	return count, nil
+}
+
+// GenerateFromFile streams the names of a list file with the suffix appended, in file
+// order, for the counter range [offset, offset+limit) over the names of the file. A
+// limit of zero means until the end of the file. Names rejected by the regex filter
+// are skipped; the file is expected to be validated by CountNames. Cancelling ctx stops
+// the generation.
+func GenerateFromFile(ctx context.Context, path, suffix, regexFilter string, regexMode types.RegexMode, offset, limit int) (<-chan string, error) {
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	domainChan := make(chan string, 1000)
+
+	go func() {
+		defer close(domainChan)
+
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Printf("Error opening input file: %v\n", err)
+			return
+		}
+		defer file.Close()
+
+		counter := 0
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			name, ok, err := parseName(scanner.Text(), suffix)
+			if err != nil || !ok {
+				continue
+			}
+			counter++
+			if counter <= offset {
+				continue
+			}
+			if limit > 0 && counter > offset+limit {
+				break
+			}
+			if matchesFilter(regex, regexMode, name, suffix) && !send(ctx, domainChan, name+suffix) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Printf("Error reading input file %s: %v\n", path, err)
+		}
+	}()
+
+	return domainChan, nil
+}
+
+// GenerateFromReader streams the domains of a line-oriented input such as standard
+// input as the lines arrive, so that checking starts before the input ends. Lines with
+// a suffix are used as they are, bare names get suffix appended; blank lines and lines
+// starting with "#" are skipped. Internationalized domains given in Unicode are
+// converted to their ASCII (punycode) form. Names rejected by the regex filter are skipped and
+// invalid lines are reported with their line number. The channel is closed at the end
+// of the input, or after the next line once ctx is cancelled.
+func GenerateFromReader(ctx context.Context, r io.Reader, suffix, regexFilter string, regexMode types.RegexMode) (<-chan string, error) {
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	domainChan := make(chan string)
+
+	go func() {
+		defer close(domainChan)
+
+		seen := make(map[string]bool)
+		scanner := bufio.NewScanner(r)
+		for line := 1; scanner.Scan(); line++ {
+			name := normalizeRetryDomain(scanner.Text())
+			if name == "" || strings.HasPrefix(name, "#") {
+				continue
+			}
+			if !strings.Contains(name, ".") {
+				name += suffix
+			}
+			name = asciiDomain(name)
+			if reason := invalidDomain(name); reason != "" {
+				fmt.Printf("Warning: skipping input line %d: invalid domain %q: %s\n", line, name, reason)
+				continue
+			}
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			label := name[:strings.Index(name, ".")]
+			if matchesFilter(regex, regexMode, label, name[len(label):]) && !send(ctx, domainChan, name) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Printf("Error reading input: %v\n", err)
+		}
+	}()
+
+	return domainChan, nil
+}
+
+// parseName returns the lower-cased name of a list line without the suffix, names
+// given in Unicode in their ASCII (punycode) form; ok is false for blank and comment
+// lines
+func parseName(line, suffix string) (name string, ok bool, err error) {
+	name = strings.ToLower(strings.TrimSpace(line))
+	if name == "" || strings.HasPrefix(name, "#") {
+		return "", false, nil
+	}
+	name = asciiDomain(strings.TrimSuffix(name, "."))
+	if trimmed := strings.TrimSuffix(name, suffix); trimmed != name && trimmed != "" {
+		name = trimmed
+	}
+	if strings.Contains(name, ".") {
+		return "", false, fmt.Errorf("name %q does not end in the suffix %s", name, suffix)
+	}
+	if len(name) > maxLabelLength || !wordRegex.MatchString(name) || name[0] == '-' || name[len(name)-1] == '-' {
+		return "", false, fmt.Errorf("invalid name %q (use a-z, 0-9 and inner -, at most 63 characters)", name)
+	}
+	return name, true, nil
+}
@@ -0,0 +1,71 @@
+package generator
+
+import "fmt"
+
+// Affix positions of keyword combinations
+const (
+	AffixPrefix = "prefix"
+	AffixSuffix = "suffix"
+	AffixBoth   = "both"
+)
+
+// MaxKeywordNames bounds the number of names of a keyword and affix combination, which
+// are held in memory to be deduplicated
+const MaxKeywordNames = 1 << 22
+
+// ValidateAffixPosition reports an error for an affix position other than prefix, suffix
+// and both
+func ValidateAffixPosition(position string) error {
+	switch position {
+	case AffixPrefix, AffixSuffix, AffixBoth:
+		return nil
+	default:
+		return fmt.Errorf("invalid affix position %q (use %s, %s or %s)", position, AffixPrefix, AffixSuffix, AffixBoth)
+	}
+}
+
+// KeywordNames combines every keyword with every affix, e.g. getshop for the prefix get
+// and shopify for the suffix ify: the affix goes after the keyword for the position
+// suffix, before it for prefix and both ways for both. With alone the keyword itself is
+// a name too. Names are returned keyword by keyword without duplicates; names longer
+// than 63 characters or with a leading or trailing hyphen are skipped.
+func KeywordNames(keywords, affixes []string, position string, alone bool) ([]string, error) {
+	if err := ValidateAffixPosition(position); err != nil {
+		return nil, err
+	}
+	perKeyword := len(affixes)
+	if position == AffixBoth {
+		perKeyword *= 2
+	}
+	if alone {
+		perKeyword++
+	}
+	if len(keywords) > MaxKeywordNames/max(perKeyword, 1) {
+		return nil, fmt.Errorf("%d keywords and %d affixes give more than %d names", len(keywords), len(affixes), MaxKeywordNames)
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if invalidSuffixLabel(name) == "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, keyword := range keywords {
+		if alone {
+			add(keyword)
+		}
+		if position != AffixPrefix {
+			for _, affix := range affixes {
+				add(keyword + affix)
+			}
+		}
+		if position != AffixSuffix {
+			for _, affix := range affixes {
+				add(affix + keyword)
+			}
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// MaxLeetVariants bounds the number of variants of a leetspeak seed word, which doubles
+// with every substitutable character
+const MaxLeetVariants = 1 << 20
+
+// DefaultLeetTable is the built-in leetspeak substitution table; every substitution
+// also applies the other way round, so "sh0p" varies to "shop" as well
+var DefaultLeetTable = map[string]string{"o": "0", "i": "1", "e": "3", "a": "4", "s": "5", "t": "7"}
+
+// LeetTable maps every character to the characters it may be replaced with
+type LeetTable map[byte]string
+
+// NewLeetTable builds the substitution table of character pairs such as {"o": "0"} or
+// {"i": "1l"}: every character of a value may replace the key and the key may replace
+// every character of its value. Only a-z and 0-9 are allowed.
+func NewLeetTable(pairs map[string]string) (LeetTable, error) {
+	table := make(LeetTable)
+	add := func(from, to byte) {
+		if from != to && strings.IndexByte(table[from], to) < 0 {
+			table[from] += string(to)
+		}
+	}
+	for key, value := range pairs {
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.ToLower(strings.TrimSpace(value))
+		if len(key) != 1 || !isLeetChar(key[0]) || value == "" {
+			return nil, fmt.Errorf("invalid leet substitution %q = %q: use a single character a-z or 0-9 and its replacements", key, value)
+		}
+		for i := 0; i < len(value); i++ {
+			if !isLeetChar(value[i]) {
+				return nil, fmt.Errorf("invalid leet substitution %q = %q: %q is not allowed (use a-z and 0-9)", key, value, value[i])
+			}
+			add(key[0], value[i])
+			add(value[i], key[0])
+		}
+	}
+	return table, nil
+}
+
+// isLeetChar reports whether a character may take part in a substitution
+func isLeetChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+// NormalizeLeetWord returns the lower-cased seed word of leetspeak variants, which must
+// be a valid name of a-z, 0-9 and inner hyphens
+func NormalizeLeetWord(word string) (string, error) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	if word == "" || len(word) > maxLabelLength || !wordRegex.MatchString(word) || word[0] == '-' || word[len(word)-1] == '-' {
+		return "", fmt.Errorf("invalid leet word %q (use a-z, 0-9 and inner -, at most 63 characters)", word)
+	}
+	return word, nil
+}
+
+// LeetVariants returns the seed word followed by every combination of substitutions
+// of its characters, without duplicates. Words with more than MaxLeetVariants variants
+// are an error.
+func LeetVariants(word string, table LeetTable) ([]string, error) {
+	options := make([]string, len(word))
+	total := 1
+	for i := 0; i < len(word); i++ {
+		options[i] = string(word[i]) + table[word[i]]
+		if total > MaxLeetVariants/len(options[i]) {
+			return nil, fmt.Errorf("the leet word %q has more than %d variants", word, MaxLeetVariants)
+		}
+		total *= len(options[i])
+	}
+
+	// The counter enumerates one option per character, the last character fastest;
+	// counter zero keeps every character and yields the seed word
+	seen := make(map[string]bool, total)
+	variants := make([]string, 0, total)
+	name := make([]byte, len(word))
+	for counter := 0; counter < total; counter++ {
+		rest := counter
+		for i := len(word) - 1; i >= 0; i-- {
+			name[i] = options[i][rest%len(options[i])]
+			rest /= len(options[i])
+		}
+		if variant := string(name); !seen[variant] {
+			seen[variant] = true
+			variants = append(variants, variant)
+		}
+	}
+	return variants, nil
+}
+
+// GenerateLeet streams the domains of leetspeak variants, in order, for the counter
+// range [offset, offset+limit) over the variants; a limit of zero means "until the last
+// variant". Cancelling ctx stops the generation.
+func GenerateLeet(ctx context.Context, variants []string, suffix string, regexFilter string, regexMode types.RegexMode, offset, limit int) (<-chan string, error) {
+	return GenerateList(ctx, variants, suffix, regexFilter, regexMode, offset, limit)
+}
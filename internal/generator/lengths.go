@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// ParseLengths parses a domain length specification: a single length ("3"), a range
+// ("2-4"), a comma-separated list ("2,4") or a mix of them ("2-3,5"). The lengths are
+// returned in ascending order without duplicates.
+func ParseLengths(spec string) ([]int, error) {
+	seen := make(map[int]bool)
+	var lengths []int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		low, err := parseLength(from, spec)
+		if err != nil {
+			return nil, err
+		}
+		high := low
+		if isRange {
+			if high, err = parseLength(to, spec); err != nil {
+				return nil, err
+			}
+			if high < low {
+				return nil, fmt.Errorf("invalid length range %q: %d is greater than %d", part, low, high)
+			}
+		}
+		for length := low; length <= high; length++ {
+			if !seen[length] {
+				seen[length] = true
+				lengths = append(lengths, length)
+			}
+		}
+	}
+	sort.Ints(lengths)
+	return lengths, nil
+}
+
+// parseLength parses one length of a specification
+func parseLength(value, spec string) (int, error) {
+	length, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || length < 1 || length > maxLabelLength {
+		return 0, fmt.Errorf("invalid length %q (use a length from 1 to %d, a range like 2-4 or a list like 2,4)",
+			spec, maxLabelLength)
+	}
+	return length, nil
+}
+
+// FormatLengths renders lengths for display and file names: runs of consecutive
+// lengths as "2-4", other lengths separated by underscores, e.g. "2-3_5"
+func FormatLengths(lengths []int) string {
+	var parts []string
+	for i := 0; i < len(lengths); {
+		j := i
+		for j+1 < len(lengths) && lengths[j+1] == lengths[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", lengths[i], lengths[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(lengths[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, "_")
+}
+
+// GenerateDomainsLengths streams the domains of several lengths in ascending order on
+// one channel. The keyspaces of the lengths are concatenated, so the counter range
+// [offset, offset+limit) counts the shorter lengths first; a limit of zero means "until
+// the end of the last keyspace". Cancelling ctx stops the generation.
+func GenerateDomainsLengths(ctx context.Context, lengths []int, suffix string, pattern string, regexFilter string, regexMode types.RegexMode, offset, limit int) (<-chan string, error) {
+	return GenerateAffixedLengths(ctx, lengths, Affixes{}, suffix, pattern, regexFilter, regexMode, offset, limit, Order{})
+}
+
+// GenerateAffixedLengths is like GenerateDomainsLengths with fixed affixes around the
+// generated characters of every name and the counters visited in the given order. The
+// keyspace, and so offset and limit, covers the generated characters only; the regex
+// filter sees the whole name. An invalid pattern or regex filter is an error.
+func GenerateAffixedLengths(ctx context.Context, lengths []int, affixes Affixes, suffix string, pattern string, regexFilter string, regexMode types.RegexMode, offset, limit int, order Order) (<-chan string, error) {
+	charset, ok := charsetFor(pattern)
+	if !ok {
+		return nil, ValidatePattern(pattern)
+	}
+
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	domainChan := make(chan string, 1000)
+
+	go func() {
+		defer close(domainChan)
+		// The ascending order needs no counters and runs past keyspaces too large for one
+		if !order.Shuffled() {
+			walkLengths(charset, lengths, offset, limit, func(name string) bool {
+				current := affixes.Prefix + name + affixes.Suffix
+				return !matchesKeyspace(regex, regexMode, current, suffix) || send(ctx, domainChan, current+suffix)
+			})
+			return
+		}
+		sizes := make([]int, len(lengths))
+		end := 0
+		for i, length := range lengths {
+			sizes[i] = CalculateDomainsCount(length, pattern)
+			end = AddCounts(end, sizes[i])
+		}
+		if limit > 0 && offset+limit < end {
+			end = offset + limit
+		}
+		order.walk(offset, end, func(counter int) bool {
+			// Find the length whose part of the keyspace holds the counter
+			i := 0
+			for counter >= sizes[i] {
+				counter -= sizes[i]
+				i++
+			}
+			current := affixes.Prefix + nameAt(charset, lengths[i], counter) + affixes.Suffix
+			return !matchesKeyspace(regex, regexMode, current, suffix) || send(ctx, domainChan, current+suffix)
+		})
+	}()
+
+	return domainChan, nil
+}
+
+// CalculateLengthsCount returns the size of the keyspaces of all lengths together,
+// MaxCount when it does not fit an int
+func CalculateLengthsCount(lengths []int, pattern string) int {
+	total := 0
+	for _, length := range lengths {
+		total = AddCounts(total, CalculateDomainsCount(length, pattern))
+	}
+	return total
+}
+
+// CalculateLengthsNamesCount is like CalculateNamesCount for all lengths together
+func CalculateLengthsNamesCount(lengths []int, pattern string) int {
+	total := 0
+	for _, length := range lengths {
+		total = AddCounts(total, CalculateNamesCount(length, pattern))
+	}
+	return total
+}
+
+// CounterOfLengths is like CounterOf for the concatenated keyspaces of
+// GenerateDomainsLengths
+func CounterOfLengths(domainName string, pattern string, lengths []int) (int, bool) {
+	counter, ok := CounterOf(domainName, pattern)
+	if !ok {
+		return 0, false
+	}
+	name := domainName
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name = name[:idx]
+	}
+	for _, length := range lengths {
+		if length == len(name) {
+			return counter, true
+		}
+		if counter = AddCounts(counter, CalculateDomainsCount(length, pattern)); counter == MaxCount {
+			return 0, false
+		}
+	}
+	return 0, false
+}
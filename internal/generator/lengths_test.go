@@ -0,0 +1,163 @@
+package generator
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"domain-scanner/internal/types"
+)
+
+// collect drains a generator channel; a generator that fails to start panics, which
+// fails the test
+func collect(domains <-chan string, err error) []string {
+	if err != nil {
+		panic(err)
+	}
+	var names []string
+	for name := range domains {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestWalkLengthsMatchesCounters(t *testing.T) {
+	tests := []struct {
+		name          string
+		charset       string
+		lengths       []int
+		offset, limit int
+	}{
+		{name: "whole keyspace", charset: "abc", lengths: []int{3}},
+		{name: "offset", charset: "abc", lengths: []int{3}, offset: 5},
+		{name: "range", charset: "abc", lengths: []int{3}, offset: 5, limit: 7},
+		{name: "lengths", charset: "ab", lengths: []int{1, 2, 3}},
+		{name: "range across lengths", charset: "ab", lengths: []int{1, 2, 3}, offset: 1, limit: 6},
+		{name: "offset into a later length", charset: "ab", lengths: []int{1, 2}, offset: 3},
+		{name: "limit past the end", charset: "ab", lengths: []int{2}, offset: 2, limit: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []string
+			counter := 0
+			for _, length := range tt.lengths {
+				size, _ := keyspaceSize(len(tt.charset), length)
+				for i := 0; i < size; i++ {
+					if counter >= tt.offset && (tt.limit == 0 || counter < tt.offset+tt.limit) {
+						want = append(want, nameAt(tt.charset, length, i))
+					}
+					counter++
+				}
+			}
+			var got []string
+			walkLengths(tt.charset, tt.lengths, tt.offset, tt.limit, func(name string) bool {
+				got = append(got, name)
+				return true
+			})
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("walkLengths() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestGenerateBeyondIntCounters(t *testing.T) {
+	// The names around the largest int counter of 13 alphanumeric characters
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	names := collect(GenerateAffixedLengths(context.Background(), []int{13}, Affixes{}, ".li", "a", "",
+		types.RegexModeFull, MaxCount-1, 3, Order{}))
+
+	var want []string
+	counter := new(big.Int).SetInt64(MaxCount - 1)
+	base := big.NewInt(int64(len(charset)))
+	for i := 0; i < 3; i++ {
+		name := make([]byte, 13)
+		rest := new(big.Int).Set(counter)
+		digit := new(big.Int)
+		for j := 12; j >= 0; j-- {
+			rest.DivMod(rest, base, digit)
+			name[j] = charset[digit.Int64()]
+		}
+		want = append(want, string(name)+".li")
+		counter.Add(counter, big.NewInt(1))
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("GenerateAffixedLengths() = %v, want %v", names, want)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		generate func() (<-chan string, error)
+	}{
+		{name: "invalid pattern", generate: func() (<-chan string, error) {
+			return GenerateDomainsLengths(ctx, []int{2}, ".li", "x", "", types.RegexModeFull, 0, 0)
+		}},
+		{name: "invalid regex", generate: func() (<-chan string, error) {
+			return GenerateDomainsLengths(ctx, []int{2}, ".li", "d", "(", types.RegexModeFull, 0, 0)
+		}},
+		{name: "invalid list regex", generate: func() (<-chan string, error) {
+			return GenerateList(ctx, []string{"ab"}, ".li", "(", types.RegexModeFull, 0, 0)
+		}},
+		{name: "invalid expansion regex", generate: func() (<-chan string, error) {
+			return ExpandSuffixes(ctx, make(chan string), ".com", []string{".com", ".net"}, "(", types.RegexModeFull)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domains, err := tt.generate()
+			if err == nil || domains != nil {
+				t.Errorf("generator = %v, %v, want an error", domains, err)
+			}
+		})
+	}
+}
+
+func TestGenerateToTheEndOfTheKeyspace(t *testing.T) {
+	names := collect(GenerateDomainsLengths(context.Background(), []int{1, 2}, ".li", "[ab]", "", types.RegexModeFull, 0, 0))
+	want := []string{"a.li", "b.li", "aa.li", "ab.li", "ba.li", "bb.li"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("GenerateDomainsLengths() = %v, want %v", names, want)
+	}
+}
+
+func TestCountsSaturate(t *testing.T) {
+	tests := []struct {
+		name string
+		got  int
+		want int
+	}{
+		{name: "fits", got: CalculateLengthsCount([]int{12}, "a"), want: 4738381338321616896},
+		{name: "length", got: CalculateLengthsCount([]int{13}, "a"), want: MaxCount},
+		{name: "lengths", got: CalculateLengthsCount([]int{12, 12}, "a"), want: MaxCount},
+		{name: "hyphen names", got: CalculateLengthsNamesCount([]int{13}, "h"), want: MaxCount},
+		{name: "sum", got: AddCounts(MaxCount-1, 2), want: MaxCount},
+		{name: "product", got: MulCounts(MaxCount/2+1, 2), want: MaxCount},
+		{name: "zero product", got: MulCounts(0, MaxCount), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %d, want %d", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCounterOfLengthsOverflow(t *testing.T) {
+	if counter, ok := CounterOfLengths("aaaaaaaaaaaab.li", "a", []int{13}); !ok || counter != 1 {
+		t.Errorf("CounterOfLengths() = %d, %v, want 1, true", counter, ok)
+	}
+	if _, ok := CounterOfLengths("9999999999999.li", "a", []int{13}); ok {
+		t.Errorf("CounterOfLengths() of a name beyond the int counters is ok")
+	}
+	if _, ok := CounterOfLengths("aa.li", "a", []int{13, 2}); ok {
+		t.Errorf("CounterOfLengths() of a length after a keyspace beyond the int counters is ok")
+	}
+}
@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// LetterPattern is a parsed repeated-letter pattern such as "AAB" or "ABAB": positions
+// holding the same letter take the same character and positions holding different
+// letters take different characters. Only the distinct letters are enumerated, so AAB
+// over 26 letters generates 26*25 names instead of 26^3.
+type LetterPattern struct {
+	pattern string
+	charset string
+	// vars is the variable of every position, numbered by first appearance
+	vars []int
+	// count is the number of distinct letters of the pattern
+	count int
+}
+
+// ParseLetterPattern parses a letter pattern such as "AAB" over the characters of a
+// generator pattern. The pattern is case-insensitive; the charset must not contain
+// hyphens, since names could otherwise start or end with one.
+func ParseLetterPattern(letterPattern, pattern string) (LetterPattern, error) {
+	charset, ok := charsetFor(pattern)
+	if !ok {
+		return LetterPattern{}, fmt.Errorf("invalid pattern %q for letter pattern %q", pattern, letterPattern)
+	}
+	if strings.Contains(charset, "-") {
+		return LetterPattern{}, fmt.Errorf("invalid pattern %q for letter pattern %q: letter patterns take characters without hyphens", pattern, letterPattern)
+	}
+	letterPattern = strings.ToUpper(strings.TrimSpace(letterPattern))
+	if letterPattern == "" || len(letterPattern) > maxLabelLength {
+		return LetterPattern{}, fmt.Errorf("invalid letter pattern %q: use 1 to %d letters", letterPattern, maxLabelLength)
+	}
+	p := LetterPattern{pattern: letterPattern, charset: charset}
+	vars := make(map[byte]int)
+	for i := 0; i < len(letterPattern); i++ {
+		c := letterPattern[i]
+		if c < 'A' || c > 'Z' {
+			return LetterPattern{}, fmt.Errorf("invalid letter pattern %q: %q is not allowed (use letters such as AAB or ABAB)", letterPattern, c)
+		}
+		if _, ok := vars[c]; !ok {
+			vars[c] = len(vars)
+		}
+		p.vars = append(p.vars, vars[c])
+	}
+	p.count = len(vars)
+	if p.count > len(charset) {
+		return LetterPattern{}, fmt.Errorf("invalid letter pattern %q: %d different letters need at least %d characters, the charset has %d",
+			letterPattern, p.count, p.count, len(charset))
+	}
+	size := 1
+	for i := 0; i < p.count; i++ {
+		if size > math.MaxInt/(len(charset)-i) {
+			return LetterPattern{}, fmt.Errorf("invalid letter pattern %q: too many different letters", letterPattern)
+		}
+		size *= len(charset) - i
+	}
+	return p, nil
+}
+
+// String returns the letter pattern in upper case
+func (p LetterPattern) String() string {
+	return p.pattern
+}
+
+// Len returns the length of the names of a letter pattern
+func (p LetterPattern) Len() int {
+	return len(p.vars)
+}
+
+// Count returns the number of names of a letter pattern, the number of ways to give
+// its distinct letters distinct characters; offsets and limits count in this keyspace
+func (p LetterPattern) Count() int {
+	total := 1
+	for i := 0; i < p.count; i++ {
+		total *= len(p.charset) - i
+	}
+	return total
+}
+
+// nameAt builds the name of a letter pattern for a keyspace counter value. The first
+// letter takes any character, every further one a character not taken yet; the last
+// letter changes fastest.
+func (p LetterPattern) nameAt(counter int) string {
+	digits := make([]int, p.count)
+	for i := p.count - 1; i >= 0; i-- {
+		digits[i] = counter % (len(p.charset) - i)
+		counter /= len(p.charset) - i
+	}
+	chars := make([]byte, p.count)
+	used := make([]bool, len(p.charset))
+	for i, digit := range digits {
+		for j := range p.charset {
+			if used[j] {
+				continue
+			}
+			if digit == 0 {
+				chars[i], used[j] = p.charset[j], true
+				break
+			}
+			digit--
+		}
+	}
+	name := make([]byte, len(p.vars))
+	for i, v := range p.vars {
+		name[i] = chars[v]
+	}
+	return string(name)
+}
+
+// CounterOf returns the keyspace counter value that generates a domain from a letter
+// pattern, the inverse of GenerateLetterPattern; the suffix is ignored. The bool is
+// false when the name does not fit the pattern.
+func (p LetterPattern) CounterOf(domainName string) (int, bool) {
+	name := domainName
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name = name[:idx]
+	}
+	if len(name) != len(p.vars) {
+		return 0, false
+	}
+	chars := make([]byte, p.count)
+	seen := make([]bool, p.count)
+	for i, v := range p.vars {
+		if seen[v] && chars[v] != name[i] {
+			return 0, false
+		}
+		chars[v], seen[v] = name[i], true
+	}
+	counter := 0
+	used := make([]bool, len(p.charset))
+	for i, c := range chars {
+		idx := strings.IndexByte(p.charset, c)
+		if idx < 0 || used[idx] {
+			return 0, false
+		}
+		// The digit of a letter counts the characters before it not taken yet
+		digit := 0
+		for j := 0; j < idx; j++ {
+			if !used[j] {
+				digit++
+			}
+		}
+		used[idx] = true
+		counter = counter*(len(p.charset)-i) + digit
+	}
+	return counter, true
+}
+
+// GenerateLetterPattern streams the domains of a letter pattern whose keyspace counter
+// lies in [offset, offset+limit) in the given order; a limit of zero means "until the
+// end of the keyspace". Cancelling ctx stops the generation.
+func GenerateLetterPattern(ctx context.Context, p LetterPattern, suffix string, regexFilter string, regexMode types.RegexMode, offset, limit int, order Order) (<-chan string, error) {
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	domainChan := make(chan string, 1000)
+
+	go func() {
+		defer close(domainChan)
+		end := p.Count()
+		if limit > 0 && offset+limit < end {
+			end = offset + limit
+		}
+		order.walk(offset, end, func(counter int) bool {
+			name := p.nameAt(counter)
+			return !matchesKeyspace(regex, regexMode, name, suffix) || send(ctx, domainChan, name+suffix)
+		})
+	}()
+
+	return domainChan, nil
+}
@@ -0,0 +1,193 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// Mask is a parsed name mask: every position holds the characters it may take, a
+// single one for the fixed positions. Only the wildcard positions are enumerated.
+type Mask struct {
+	mask string
+	sets []string
+}
+
+// ParseMask parses a name mask such as "a??9". Letters, digits and hyphens are fixed
+// characters; "?d" is any digit, "?l" any letter and any other "?" a character of the
+// pattern, e.g. the custom charset pattern of CharsetPattern.
+func ParseMask(mask, pattern string) (Mask, error) {
+	charset, ok := charsetFor(pattern)
+	if !ok {
+		return Mask{}, fmt.Errorf("invalid pattern %q for mask %q", pattern, mask)
+	}
+	mask = strings.ToLower(strings.TrimSpace(mask))
+	m := Mask{mask: mask}
+	for i := 0; i < len(mask); i++ {
+		c := mask[i]
+		switch {
+		case c == '?' && i+1 < len(mask) && mask[i+1] == 'd':
+			m.sets = append(m.sets, "0123456789")
+			i++
+		case c == '?' && i+1 < len(mask) && mask[i+1] == 'l':
+			m.sets = append(m.sets, "abcdefghijklmnopqrstuvwxyz")
+			i++
+		case c == '?':
+			m.sets = append(m.sets, charset)
+		case c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-':
+			m.sets = append(m.sets, string(c))
+		default:
+			return Mask{}, fmt.Errorf("invalid mask %q: %q is not allowed (use a-z, 0-9, -, ?, ?d and ?l)", mask, c)
+		}
+	}
+	if len(m.sets) == 0 || len(m.sets) > maxLabelLength {
+		return Mask{}, fmt.Errorf("invalid mask %q: use 1 to %d positions", mask, maxLabelLength)
+	}
+	size := 1
+	for _, set := range m.sets {
+		if size > math.MaxInt/len(set) {
+			return Mask{}, fmt.Errorf("invalid mask %q: too many wildcards", mask)
+		}
+		size *= len(set)
+	}
+	return m, nil
+}
+
+// maxTemplateLength bounds the length of templates, whose keyspace grows quickly
+const maxTemplateLength = 8
+
+// Character classes of templates
+const (
+	consonants = "bcdfghjklmnpqrstvwxyz"
+	vowels     = "aeiou"
+)
+
+// ParseTemplate parses a consonant/vowel template such as "CVCV" into the mask of its
+// names: C is a consonant, V a vowel, L any letter and N any digit, so CVCV generates
+// 21*5*21*5 names. Templates longer than 8 positions are rejected.
+func ParseTemplate(template string) (Mask, error) {
+	template = strings.ToUpper(strings.TrimSpace(template))
+	if template == "" || len(template) > maxTemplateLength {
+		return Mask{}, fmt.Errorf("invalid template %q: use 1 to %d positions", template, maxTemplateLength)
+	}
+	m := Mask{mask: template}
+	for i := 0; i < len(template); i++ {
+		switch template[i] {
+		case 'C':
+			m.sets = append(m.sets, consonants)
+		case 'V':
+			m.sets = append(m.sets, vowels)
+		case 'L':
+			m.sets = append(m.sets, "abcdefghijklmnopqrstuvwxyz")
+		case 'N':
+			m.sets = append(m.sets, "0123456789")
+		default:
+			return Mask{}, fmt.Errorf("invalid template %q: %q is not allowed (use C for consonants, V for vowels, L for letters and N for digits)",
+				template, template[i])
+		}
+	}
+	return m, nil
+}
+
+// String returns the mask as given, in lower case, or the template in upper case
+func (m Mask) String() string {
+	return m.mask
+}
+
+// Len returns the length of the names of a mask
+func (m Mask) Len() int {
+	return len(m.sets)
+}
+
+// Count returns the size of the keyspace of a mask, the product of the sizes of its
+// wildcard positions; offsets and limits count in this keyspace
+func (m Mask) Count() int {
+	total := 1
+	for _, set := range m.sets {
+		total *= len(set)
+	}
+	return total
+}
+
+// NamesCount returns the number of names a mask generates without a regex filter: its
+// keyspace without the names with a leading, trailing or doubled hyphen
+func (m Mask) NamesCount() int {
+	if len(m.sets) == 0 {
+		return 0
+	}
+	// Count by the last character as CalculateNamesCount does, position by position
+	endOther, endHyphen := 0, 0
+	for i, set := range m.sets {
+		hyphens := strings.Count(set, "-")
+		others := len(set) - hyphens
+		if i == 0 {
+			endOther = others
+			continue
+		}
+		endOther, endHyphen = others*(endOther+endHyphen), hyphens*endOther
+	}
+	return endOther
+}
+
+// nameAt builds the name of a mask for a keyspace counter value; the last wildcard
+// position changes fastest
+func (m Mask) nameAt(counter int) string {
+	name := make([]byte, len(m.sets))
+	for i := len(m.sets) - 1; i >= 0; i-- {
+		set := m.sets[i]
+		name[i] = set[counter%len(set)]
+		counter /= len(set)
+	}
+	return string(name)
+}
+
+// CounterOf returns the keyspace counter value that generates a domain from a mask,
+// the inverse of the mask generator; the suffix is ignored. The bool is false when
+// the name does not fit the mask.
+func (m Mask) CounterOf(domainName string) (int, bool) {
+	name := domainName
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name = name[:idx]
+	}
+	if len(name) != len(m.sets) {
+		return 0, false
+	}
+	counter := 0
+	for i, set := range m.sets {
+		digit := strings.IndexByte(set, name[i])
+		if digit < 0 {
+			return 0, false
+		}
+		counter = counter*len(set) + digit
+	}
+	return counter, true
+}
+
+// GenerateMask streams the domains of a mask whose keyspace counter lies in
+// [offset, offset+limit) in the given order; a limit of zero means "until the end of
+// the keyspace". Cancelling ctx stops the generation.
+func GenerateMask(ctx context.Context, m Mask, suffix string, regexFilter string, regexMode types.RegexMode, offset, limit int, order Order) (<-chan string, error) {
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	domainChan := make(chan string, 1000)
+
+	go func() {
+		defer close(domainChan)
+		end := m.Count()
+		if limit > 0 && offset+limit < end {
+			end = offset + limit
+		}
+		order.walk(offset, end, func(counter int) bool {
+			name := m.nameAt(counter)
+			return !matchesKeyspace(regex, regexMode, name, suffix) || send(ctx, domainChan, name+suffix)
+		})
+	}()
+
+	return domainChan, nil
+}
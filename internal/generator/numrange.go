@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// DefaultMaxNumRange is the largest number of integers a numeric range may hold when
+// the config sets no maximum
+const DefaultMaxNumRange = 10000000
+
+// NumRange is a parsed range of integers such as 8000-8999 whose numbers are the names,
+// optionally zero-padded to a width
+type NumRange struct {
+	from, to int
+	// pad is the minimum number of digits of a name; zero means no padding
+	pad int
+}
+
+// ParseNumRange parses a numeric range "from-to" such as "8000-8999", both ends
+// included. Names are zero-padded to pad digits, e.g. 0042 for pad 4. Ranges of more
+// than max numbers are an error; a max of zero means DefaultMaxNumRange.
+func ParseNumRange(spec string, pad, max int) (NumRange, error) {
+	if max <= 0 {
+		max = DefaultMaxNumRange
+	}
+	spec = strings.TrimSpace(spec)
+	fromText, toText, ok := strings.Cut(spec, "-")
+	from, fromErr := strconv.Atoi(strings.TrimSpace(fromText))
+	to, toErr := strconv.Atoi(strings.TrimSpace(toText))
+	if !ok || fromErr != nil || toErr != nil || from < 0 || to < 0 {
+		return NumRange{}, fmt.Errorf("invalid numeric range %q: use from-to with non-negative integers, e.g. 8000-8999", spec)
+	}
+	if from > to {
+		return NumRange{}, fmt.Errorf("invalid numeric range %q: %d is greater than %d", spec, from, to)
+	}
+	if pad < 0 || pad > maxLabelLength {
+		return NumRange{}, fmt.Errorf("invalid numeric padding %d: use 0 to %d digits", pad, maxLabelLength)
+	}
+	if to-from >= max {
+		return NumRange{}, fmt.Errorf("invalid numeric range %q: more than the maximum of %d numbers", spec, max)
+	}
+	return NumRange{from: from, to: to, pad: pad}, nil
+}
+
+// String returns the range with its padded ends, e.g. "0100-0999"
+func (r NumRange) String() string {
+	return r.name(r.from) + "-" + r.name(r.to)
+}
+
+// Count returns the number of names of a range; offsets and limits count in it
+func (r NumRange) Count() int {
+	return r.to - r.from + 1
+}
+
+// name returns the name of a number of the range
+func (r NumRange) name(n int) string {
+	return fmt.Sprintf("%0*d", r.pad, n)
+}
+
+// CounterOf returns the keyspace counter value that generates a domain from a numeric
+// range, the inverse of GenerateNumRange; the suffix is ignored. The bool is false when
+// the name is not a number of the range in its padded form.
+func (r NumRange) CounterOf(domainName string) (int, bool) {
+	name := domainName
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name = name[:idx]
+	}
+	if name == "" || strings.Trim(name, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(name)
+	if err != nil || n < r.from || n > r.to || r.name(n) != name {
+		return 0, false
+	}
+	return n - r.from, true
+}
+
+// GenerateNumRange streams the domains of a numeric range for the counter range
+// [offset, offset+limit) in the given order, ascending for the zero Order; a limit of
+// zero means "until the end of the range". Cancelling ctx stops the generation.
+func GenerateNumRange(ctx context.Context, r NumRange, suffix string, regexFilter string, regexMode types.RegexMode, offset, limit int, order Order) (<-chan string, error) {
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	domainChan := make(chan string, 1000)
+
+	go func() {
+		defer close(domainChan)
+		end := r.Count()
+		if limit > 0 && offset+limit < end {
+			end = offset + limit
+		}
+		order.walk(offset, end, func(counter int) bool {
+			name := r.name(r.from + counter)
+			return !matchesFilter(regex, regexMode, name, suffix) || send(ctx, domainChan, name+suffix)
+		})
+	}()
+
+	return domainChan, nil
+}
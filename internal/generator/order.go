@@ -0,0 +1,170 @@
+package generator
+
+import "math/bits"
+
+// feistelRounds is the number of rounds of the permutation of a shuffled order
+const feistelRounds = 4
+
+// Order is the order in which a generator enumerates the counter range of its
+// keyspace. The zero Order is ascending. A shuffled Order visits the same counters in
+// a pseudorandom permutation of the range that depends only on its seed and the range,
+// so that a run can be repeated and resumed; the permutation is computed per counter
+// and never held in memory.
+type Order struct {
+	shuffled bool
+	seed     uint64
+	// from is the counter a resumed shuffled order starts at, when resumed is set;
+	// the counters before it in the permutation are skipped
+	from    int
+	resumed bool
+	// visits caps the number of counters visited, filtered or not; zero means no cap
+	visits int
+}
+
+// ShuffledOrder returns the pseudorandom order of a seed
+func ShuffledOrder(seed int64) Order {
+	return Order{shuffled: true, seed: uint64(seed)}
+}
+
+// Shuffled reports whether an order is pseudorandom rather than ascending
+func (o Order) Shuffled() bool {
+	return o.shuffled
+}
+
+// StartingAt returns a shuffled order that resumes at a counter, skipping the counters
+// before it in the permutation. Ascending orders resume by moving their offset instead
+// and are returned unchanged.
+func (o Order) StartingAt(counter int) Order {
+	if o.shuffled {
+		o.from, o.resumed = counter, true
+	}
+	return o
+}
+
+// Capped returns an order that stops after visiting a number of counters, whether or
+// not their names pass the regex filter; it bounds the rejection sampling of a random
+// sample from a filtered keyspace
+func (o Order) Capped(visits int) Order {
+	o.visits = visits
+	return o
+}
+
+// Remaining returns the number of counters of [offset, end) an order visits
+func (o Order) Remaining(offset, end int) int {
+	if end <= offset {
+		return 0
+	}
+	remaining := end - offset - o.skipped(offset, end)
+	if o.visits > 0 && o.visits < remaining {
+		return o.visits
+	}
+	return remaining
+}
+
+// skipped returns the number of leading positions of the permutation of [offset, end)
+// a resumed order leaves out
+func (o Order) skipped(offset, end int) int {
+	if !o.resumed || o.from < offset || o.from >= end {
+		return 0
+	}
+	return newPermutation(end-offset, o.seed).indexOf(o.from - offset)
+}
+
+// walk calls emit for every counter of [offset, end) in the order until emit returns false
+func (o Order) walk(offset, end int, emit func(counter int) bool) {
+	if o.visits > 0 {
+		visits, next := o.visits, emit
+		emit = func(counter int) bool {
+			visits--
+			return next(counter) && visits > 0
+		}
+	}
+	if !o.shuffled {
+		for counter := offset; counter < end; counter++ {
+			if !emit(counter) {
+				return
+			}
+		}
+		return
+	}
+	if end <= offset {
+		return
+	}
+	p := newPermutation(end-offset, o.seed)
+	for i := o.skipped(offset, end); i < end-offset; i++ {
+		if !emit(offset + p.at(i)) {
+			return
+		}
+	}
+}
+
+// permutation is a bijection of [0, n): a balanced Feistel network over the smallest
+// even number of bits holding n-1, applied repeatedly until the value falls into the
+// range (cycle walking). The network's domain is less than four times n, so a value
+// takes a few rounds on average.
+type permutation struct {
+	n    int
+	half uint
+	mask uint64
+	keys [feistelRounds]uint64
+}
+
+func newPermutation(n int, seed uint64) permutation {
+	half := uint(bits.Len(uint(n-1))+1) / 2
+	if half == 0 {
+		half = 1
+	}
+	p := permutation{n: n, half: half, mask: 1<<half - 1}
+	for r := range p.keys {
+		p.keys[r] = mix64(seed + uint64(r+1)*0x9e3779b97f4a7c15)
+	}
+	return p
+}
+
+// at returns the counter offset at position i of the permutation
+func (p permutation) at(i int) int {
+	x := uint64(i)
+	for {
+		x = p.encrypt(x)
+		if x < uint64(p.n) {
+			return int(x)
+		}
+	}
+}
+
+// indexOf returns the position of a counter offset in the permutation, the inverse of at
+func (p permutation) indexOf(v int) int {
+	x := uint64(v)
+	for {
+		x = p.decrypt(x)
+		if x < uint64(p.n) {
+			return int(x)
+		}
+	}
+}
+
+func (p permutation) encrypt(x uint64) uint64 {
+	left, right := x>>p.half, x&p.mask
+	for _, key := range p.keys {
+		left, right = right, left^(mix64(right^key)&p.mask)
+	}
+	return left<<p.half | right
+}
+
+func (p permutation) decrypt(x uint64) uint64 {
+	left, right := x>>p.half, x&p.mask
+	for r := feistelRounds - 1; r >= 0; r-- {
+		left, right = right^(mix64(left^p.keys[r])&p.mask), left
+	}
+	return left<<p.half | right
+}
+
+// mix64 is the SplitMix64 finalizer, a fast bijective scrambling of 64 bits
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
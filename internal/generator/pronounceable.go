@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// PatternPronounceable is the pattern of names composed of syllables instead of
+// arbitrary characters, e.g. "tavo" or "mira"
+const PatternPronounceable = "pronounceable"
+
+// DefaultSyllables is the number of syllables of pronounceable names when none is set
+const DefaultSyllables = 2
+
+// MaxSyllables bounds the number of syllables, whose keyspace grows by a factor of
+// 450 with every syllable
+const MaxSyllables = 4
+
+// Syllable parts of pronounceable names: every syllable is an onset, a vowel and an
+// optional coda. Onsets are single consonants, so a name splits into its syllables in
+// exactly one way and no name is generated twice.
+const (
+	syllableOnsets = "bdfghklmnprstvz"
+	syllableVowels = "aeiou"
+	// syllableCodas are the consonants that may end a syllable; the first coda is none
+	syllableCodas = "lmnrs"
+)
+
+// syllableCount is the number of distinct syllables
+const syllableCount = len(syllableOnsets) * len(syllableVowels) * (len(syllableCodas) + 1)
+
+// ValidateSyllables reports an error for a syllable count the generator rejects
+func ValidateSyllables(syllables int) error {
+	if syllables < 1 || syllables > MaxSyllables {
+		return fmt.Errorf("invalid syllable count %d: use 1 to %d syllables", syllables, MaxSyllables)
+	}
+	return nil
+}
+
+// pronounceableCount returns the number of names of the given number of syllables
+func pronounceableCount(syllables int) int {
+	total := 1
+	for i := 0; i < syllables; i++ {
+		total *= syllableCount
+	}
+	return total
+}
+
+// syllableAt returns the syllable of a syllable index: onsets change slowest and
+// codas fastest, the syllable without coda first ("ba", "bal", "bam", ...)
+func syllableAt(index int) string {
+	codas := len(syllableCodas) + 1
+	coda := index % codas
+	index /= codas
+	syllable := []byte{syllableOnsets[index/len(syllableVowels)], syllableVowels[index%len(syllableVowels)]}
+	if coda > 0 {
+		syllable = append(syllable, syllableCodas[coda-1])
+	}
+	return string(syllable)
+}
+
+// pronounceableAt builds the name of a keyspace counter value; the last syllable
+// changes fastest, so the order is the same in every run
+func pronounceableAt(counter, syllables int) string {
+	parts := make([]string, syllables)
+	for i := syllables - 1; i >= 0; i-- {
+		parts[i] = syllableAt(counter % syllableCount)
+		counter /= syllableCount
+	}
+	return strings.Join(parts, "")
+}
+
+// PronounceableCounterOf returns the keyspace counter value that generates a domain
+// from pronounceable names of the given number of syllables, the inverse of
+// GeneratePronounceable; the suffix is ignored. The bool is false when the name is not
+// made of that many syllables.
+func PronounceableCounterOf(domainName string, syllables int) (int, bool) {
+	name := domainName
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name = name[:idx]
+	}
+	counter, pos := 0, 0
+	for i := 0; i < syllables; i++ {
+		if pos+2 > len(name) {
+			return 0, false
+		}
+		onset := strings.IndexByte(syllableOnsets, name[pos])
+		vowel := strings.IndexByte(syllableVowels, name[pos+1])
+		if onset < 0 || vowel < 0 {
+			return 0, false
+		}
+		pos += 2
+		// A coda is followed by the end of the name or the onset of the next syllable,
+		// never by a vowel
+		coda := 0
+		if pos < len(name) && (pos+1 == len(name) || strings.IndexByte(syllableVowels, name[pos+1]) < 0) {
+			if coda = strings.IndexByte(syllableCodas, name[pos]) + 1; coda > 0 {
+				pos++
+			}
+		}
+		counter = counter*syllableCount + (onset*len(syllableVowels)+vowel)*(len(syllableCodas)+1) + coda
+	}
+	if pos != len(name) {
+		return 0, false
+	}
+	return counter, true
+}
+
+// GeneratePronounceable streams the pronounceable domains of the given number of
+// syllables whose keyspace counter lies in [offset, offset+limit) in the given order;
+// a limit of zero means "until the end of the keyspace". Cancelling ctx stops the
+// generation.
+func GeneratePronounceable(ctx context.Context, syllables int, suffix string, regexFilter string, regexMode types.RegexMode, offset, limit int, order Order) (<-chan string, error) {
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	domainChan := make(chan string, 1000)
+
+	go func() {
+		defer close(domainChan)
+		end := pronounceableCount(syllables)
+		if limit > 0 && offset+limit < end {
+			end = offset + limit
+		}
+		order.walk(offset, end, func(counter int) bool {
+			name := pronounceableAt(counter, syllables)
+			return !matchesKeyspace(regex, regexMode, name, suffix) || send(ctx, domainChan, name+suffix)
+		})
+	}()
+
+	return domainChan, nil
+}
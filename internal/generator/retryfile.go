@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// RetryList is the outcome of reading the result files of earlier runs to recheck
+type RetryList struct {
+	// Domains holds the domains in file order, without duplicates
+	Domains []string
+	// Previous maps a domain to the status its line recorded; domains of bare lines
+	// have none
+	Previous map[string]string
+	// Lines is the number of non-comment lines read; every line is either ingested,
+	// a duplicate or invalid
+	Lines      int
+	Duplicates int
+	Invalid    int
+	// Problems describes the invalid lines with their file names and line numbers
+	Problems []string
+}
+
+// errorLinePrefix introduces the domain of a check error in the console output
+const errorLinePrefix = "Error checking domain "
+
+// errorStatus is the previous status of domains read from console error lines
+const errorStatus = "ERROR"
+
+// LoadRetryFiles reads the domains of earlier result files. Every line names a domain,
+// optionally followed by its status: bare domains, the special status and uncertain
+// files ("domain STATUS reason"), tab-separated lines such as the drop feed
+// ("time<TAB>domain<TAB>STATUS") and console error lines ("Error checking domain
+// DOMAIN: reason") are understood. Domains keep their own suffix. Lines starting with
+// # are comments; unreadable lines are skipped and described in Problems.
+func LoadRetryFiles(paths []string) (*RetryList, error) {
+	list := &RetryList{Previous: make(map[string]string)}
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if err := list.read(path, seen); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// read adds the domains of one file to the list
+func (l *RetryList) read(path string, seen map[string]bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening retry file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		l.Lines++
+		name, status, reason := parseRetryLine(line)
+		if reason != "" {
+			l.Invalid++
+			l.Problems = append(l.Problems, fmt.Sprintf("%s:%d: %s", path, n, reason))
+			continue
+		}
+		if seen[name] {
+			l.Duplicates++
+			// A later file, e.g. of a more recent run, knows the newer status
+			if status != "" {
+				l.Previous[name] = status
+			}
+			continue
+		}
+		seen[name] = true
+		l.Domains = append(l.Domains, name)
+		if status != "" {
+			l.Previous[name] = status
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading retry file %s: %w", path, err)
+	}
+	return nil
+}
+
+// parseRetryLine returns the domain and status of a line, or why it has no domain
+func parseRetryLine(line string) (name, status, reason string) {
+	if idx := strings.Index(line, errorLinePrefix); idx >= 0 {
+		rest := line[idx+len(errorLinePrefix):]
+		if end := strings.Index(rest, ":"); end >= 0 {
+			rest = rest[:end]
+		}
+		return parseRetryDomain(rest, errorStatus)
+	}
+
+	var fields []string
+	if strings.Contains(line, "\t") {
+		fields = strings.Split(line, "\t")
+	} else {
+		fields = strings.Fields(line)
+	}
+	// Tab-separated lines may lead with other columns such as a timestamp
+	for i, field := range fields {
+		if candidate := normalizeRetryDomain(field); invalidDomain(candidate) == "" {
+			if i+1 < len(fields) && isStatusLabel(strings.TrimSpace(fields[i+1])) {
+				status = strings.TrimSpace(fields[i+1])
+			}
+			return candidate, status, ""
+		}
+	}
+	return parseRetryDomain(fields[0], "")
+}
+
+// parseRetryDomain validates the domain of a line
+func parseRetryDomain(field, status string) (string, string, string) {
+	name := normalizeRetryDomain(field)
+	if reason := invalidDomain(name); reason != "" {
+		return "", "", fmt.Sprintf("invalid domain %q: %s", name, reason)
+	}
+	return name, status, ""
+}
+
+// normalizeRetryDomain lower-cases a domain field and removes its trailing dot
+func normalizeRetryDomain(field string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(field), "."))
+}
+
+// isStatusLabel reports whether a field is a status label such as WHOIS_RATE_LIMITED;
+// the trademark marker of the available domains file is none
+func isStatusLabel(field string) bool {
+	if field == "" || field == types.TrademarkRisk {
+		return false
+	}
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
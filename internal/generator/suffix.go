@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// NormalizeSuffix returns the canonical form of a domain suffix: lower case, without
+// surrounding whitespace or a trailing dot, and with a leading dot (" LI." becomes
+// ".li"). Multi-label suffixes such as ".co.uk" are kept. Suffixes with empty labels,
+// embedded whitespace or characters outside a-z, 0-9 and hyphen are rejected.
+func NormalizeSuffix(suffix string) (string, error) {
+	s := strings.ToLower(strings.TrimSpace(suffix))
+	s = strings.TrimSuffix(s, ".")
+	s = strings.TrimPrefix(s, ".")
+	if s == "" {
+		return "", fmt.Errorf("invalid suffix %q: empty", suffix)
+	}
+	for _, label := range strings.Split(s, ".") {
+		if reason := invalidSuffixLabel(label); reason != "" {
+			return "", fmt.Errorf("invalid suffix %q: %s", suffix, reason)
+		}
+	}
+	return "." + s, nil
+}
+
+// NormalizeSuffixes normalizes a comma-separated suffix list such as ".com, NET.",
+// dropping duplicates; an empty list is an error
+func NormalizeSuffixes(list string) ([]string, error) {
+	var suffixes []string
+	seen := make(map[string]bool)
+	for _, suffix := range strings.Split(list, ",") {
+		if strings.TrimSpace(suffix) == "" {
+			continue
+		}
+		normalized, err := NormalizeSuffix(suffix)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[normalized] {
+			seen[normalized] = true
+			suffixes = append(suffixes, normalized)
+		}
+	}
+	if len(suffixes) == 0 {
+		return nil, fmt.Errorf("invalid suffix list %q: empty", list)
+	}
+	return suffixes, nil
+}
+
+// ValidateSuffix reports an error for a suffix NormalizeSuffix would reject
+func ValidateSuffix(suffix string) error {
+	_, err := NormalizeSuffix(suffix)
+	return err
+}
+
+// invalidSuffixLabel describes why a label cannot be part of a suffix, or returns ""
+func invalidSuffixLabel(label string) string {
+	if label == "" {
+		return "empty label"
+	}
+	if len(label) > maxLabelLength {
+		return "label longer than 63 characters"
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return "label starts or ends with a hyphen"
+	}
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		switch {
+		case c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-':
+		case c == ' ' || c == '\t':
+			return "contains whitespace"
+		case c >= 0x80:
+			return "invalid characters (use the punycode form of IDN suffixes)"
+		default:
+			return fmt.Sprintf("invalid character %q", c)
+		}
+	}
+	return ""
+}
+
+// ExpandSuffixes checks every name of a channel of domains generated under suffix
+// under each of suffixes in turn: "abc.com" becomes "abc.com", "abc.net" and so on, so
+// that consecutive checks go to different registries. A regex filter is applied to
+// every expanded domain; generators feeding the channel should be given none in full
+// mode, whose matches depend on the suffix. Cancelling ctx stops the expansion.
+func ExpandSuffixes(ctx context.Context, domains <-chan string, suffix string, suffixes []string, regexFilter string, regexMode types.RegexMode) (<-chan string, error) {
+	regex, err := compileFilter(regexFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := make(chan string, 1000)
+
+	go func() {
+		defer close(expanded)
+		for domainName := range domains {
+			name := strings.TrimSuffix(domainName, suffix)
+			for _, s := range suffixes {
+				if matchesFilter(regex, regexMode, name, s) && !send(ctx, expanded, name+s) {
+					return
+				}
+			}
+		}
+	}()
+
+	return expanded, nil
+}
@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"domain-scanner/internal/types"
+)
+
+func TestNormalizeSuffix(t *testing.T) {
+	tests := []struct {
+		suffix  string
+		want    string
+		wantErr string
+	}{
+		{suffix: ".li", want: ".li"},
+		{suffix: "li", want: ".li"},
+		{suffix: "LI", want: ".li"},
+		{suffix: ".Li.", want: ".li"},
+		{suffix: " li. ", want: ".li"},
+		{suffix: "\t.COM\n", want: ".com"},
+		{suffix: ".co.uk", want: ".co.uk"},
+		{suffix: "CO.UK.", want: ".co.uk"},
+		{suffix: "xn--p1ai", want: ".xn--p1ai"},
+		{suffix: ".com.br", want: ".com.br"},
+		{suffix: "." + strings.Repeat("a", 63), want: "." + strings.Repeat("a", 63)},
+		{suffix: "", wantErr: "empty"},
+		{suffix: "   ", wantErr: "empty"},
+		{suffix: ".", wantErr: "empty"},
+		{suffix: "..", wantErr: "empty"},
+		{suffix: "..com", wantErr: "empty label"},
+		{suffix: ".co..uk", wantErr: "empty label"},
+		{suffix: ".com..", wantErr: "empty label"},
+		{suffix: ".c om", wantErr: "whitespace"},
+		{suffix: ".co.\tuk", wantErr: "whitespace"},
+		{suffix: ".-com", wantErr: "hyphen"},
+		{suffix: ".com-", wantErr: "hyphen"},
+		{suffix: ".co_uk", wantErr: `invalid character '_'`},
+		{suffix: ".com/", wantErr: `invalid character '/'`},
+		{suffix: ".рф", wantErr: "punycode"},
+		{suffix: ".bär", wantErr: "punycode"},
+		{suffix: "." + strings.Repeat("a", 64), wantErr: "longer than 63"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.suffix, func(t *testing.T) {
+			got, err := NormalizeSuffix(tt.suffix)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NormalizeSuffix(%q) = %q, %v; want an error containing %q", tt.suffix, got, err, tt.wantErr)
+				}
+				if ValidateSuffix(tt.suffix) == nil {
+					t.Errorf("ValidateSuffix(%q) = nil", tt.suffix)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizeSuffix(%q) = %q, %v; want %q", tt.suffix, got, err, tt.want)
+			}
+			// Normalized suffixes stay as they are
+			if again, err := NormalizeSuffix(got); err != nil || again != got {
+				t.Errorf("NormalizeSuffix(%q) = %q, %v; want it unchanged", got, again, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeSuffixes(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{list: ".com", want: []string{".com"}},
+		{list: ".com, NET., .org", want: []string{".com", ".net", ".org"}},
+		{list: "com,.COM, com.", want: []string{".com"}},
+		{list: ".de,,.ch,", want: []string{".de", ".ch"}},
+		{list: " .co.uk , .uk ", want: []string{".co.uk", ".uk"}},
+		{list: "", wantErr: true},
+		{list: " , ,", wantErr: true},
+		{list: ".com, .c_m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			got, err := NormalizeSuffixes(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeSuffixes(%q) error = %v, want error %v", tt.list, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeSuffixes(%q) = %q, want %q", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandSuffixes(t *testing.T) {
+	tests := []struct {
+		name        string
+		suffixes    []string
+		regexFilter string
+		regexMode   types.RegexMode
+		want        []string
+	}{
+		{
+			name:     "interleaved",
+			suffixes: []string{".com", ".net"},
+			want:     []string{"ab.com", "ab.net", "cd.com", "cd.net"},
+		},
+		{
+			name:     "multi-label",
+			suffixes: []string{".co.uk", ".de"},
+			want:     []string{"ab.co.uk", "ab.de", "cd.co.uk", "cd.de"},
+		},
+		{
+			name:        "full regex",
+			suffixes:    []string{".com", ".net"},
+			regexFilter: `\.net$`,
+			want:        []string{"ab.net", "cd.net"},
+		},
+		{
+			name:        "prefix regex",
+			suffixes:    []string{".com", ".net"},
+			regexFilter: "^c",
+			regexMode:   types.RegexModePrefix,
+			want:        []string{"cd.com", "cd.net"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domains := make(chan string, 2)
+			domains <- "ab.com"
+			domains <- "cd.com"
+			close(domains)
+			got := collect(ExpandSuffixes(context.Background(), domains, ".com", tt.suffixes, tt.regexFilter, tt.regexMode))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandSuffixes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLDList is the outcome of reading the suffixes of a reverse mode scan
+type TLDList struct {
+	// Suffixes holds the valid suffixes, normalized like NormalizeSuffix, in input order
+	// and without duplicates
+	Suffixes []string
+	// Problems describes the entries that were skipped as invalid
+	Problems []string
+}
+
+// ParseTLDs reads a comma-separated suffix list such as ".com,io, .DEV", skipping and
+// describing invalid entries instead of failing on them
+func ParseTLDs(list string) *TLDList {
+	tlds := &TLDList{}
+	tlds.add(strings.Split(list, ","), make(map[string]bool), func(i int) string { return fmt.Sprintf("entry %d", i+1) })
+	return tlds
+}
+
+// LoadTLDList reads a suffix list file with one or more comma- or space-separated
+// suffixes per line; lines starting with # are comments. Invalid entries are skipped
+// and described in Problems with their line numbers.
+func LoadTLDList(path string) (*TLDList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening TLD list: %w", err)
+	}
+	defer file.Close()
+
+	tlds := &TLDList{}
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		tlds.add(entries, seen, func(int) string { return fmt.Sprintf("%s:%d", path, n) })
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading TLD list %s: %w", path, err)
+	}
+	return tlds, nil
+}
+
+// add normalizes entries, skipping those already in seen; where locates entry i for
+// problem reports
+func (l *TLDList) add(entries []string, seen map[string]bool, where func(int) string) {
+	for i, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		suffix, err := NormalizeSuffix(entry)
+		if err != nil {
+			l.Problems = append(l.Problems, fmt.Sprintf("%s: %v", where(i), err))
+			continue
+		}
+		if !seen[suffix] {
+			seen[suffix] = true
+			l.Suffixes = append(l.Suffixes, suffix)
+		}
+	}
+}
+
+// ValidateName reports an error for a name that cannot be the first label of a domain
+func ValidateName(name string) error {
+	if strings.Contains(name, ".") {
+		return fmt.Errorf("invalid name %q: must be a single label without a suffix", name)
+	}
+	if reason := invalidSuffixLabel(name); reason != "" {
+		return fmt.Errorf("invalid name %q: %s", name, reason)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permutation types of typo variants, in generation order
+const (
+	TypoOmission      = "omission"
+	TypoRepetition    = "repetition"
+	TypoAdjacentKey   = "adjacent-key"
+	TypoTransposition = "transposition"
+	TypoWrongTLD      = "wrong-tld"
+)
+
+// TypoKinds lists the permutation types in generation order, the order in which
+// result files group them
+var TypoKinds = []string{TypoOmission, TypoRepetition, TypoAdjacentKey, TypoTransposition, TypoWrongTLD}
+
+// DefaultTypoTLDs are the suffixes of wrong-TLD variants: the common TLDs and the
+// usual slips of .com
+var DefaultTypoTLDs = []string{".com", ".net", ".org", ".co", ".io", ".info", ".biz", ".cm", ".om", ".cc"}
+
+// qwertyNeighbours maps every key of a QWERTY keyboard to the keys around it
+var qwertyNeighbours = map[byte]string{
+	'1': "2q", '2': "13qw", '3': "24we", '4': "35er", '5': "46rt",
+	'6': "57ty", '7': "68yu", '8': "79ui", '9': "80io", '0': "9op",
+	'q': "12wa", 'w': "23qeas", 'e': "34wrsd", 'r': "45etdf", 't': "56ryfg",
+	'y': "67tugh", 'u': "78yihj", 'i': "89uojk", 'o': "90ipkl", 'p': "0ol",
+	'a': "qwsz", 's': "weadzx", 'd': "erfsxc", 'f': "rtgdcv", 'g': "tyhfvb",
+	'h': "yujgbn", 'j': "uikhnm", 'k': "iolmj", 'l': "opk",
+	'z': "asx", 'x': "zsdc", 'c': "xdfv", 'v': "cfgb", 'b': "vghn", 'n': "bhjm", 'm': "njk",
+}
+
+// Typo is a typo variant of a domain and the permutation that produced it
+type Typo struct {
+	Domain string
+	Kind   string
+}
+
+// TypoVariants returns the typo variants of a domain such as "example.com": names with
+// one character left out, doubled, replaced by a neighbouring QWERTY key or swapped
+// with the next one, and the name under the other suffixes of tlds. Variants are
+// returned in the order of TypoKinds without duplicates and without the domain itself;
+// a variant produced by several permutations keeps the first. Variants that are no
+// valid names, e.g. those starting with a hyphen, are skipped.
+func TypoVariants(domainName string, tlds []string) ([]Typo, error) {
+	domainName = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(asciiDomain(domainName)), "."))
+	idx := strings.Index(domainName, ".")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid domain %q: use a name with its suffix, e.g. example.com", domainName)
+	}
+	name, suffix := domainName[:idx], domainName[idx:]
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	if _, err := NormalizeSuffix(suffix); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{domainName: true}
+	var typos []Typo
+	add := func(kind, name, suffix string) {
+		if invalidSuffixLabel(name) != "" || seen[name+suffix] {
+			return
+		}
+		seen[name+suffix] = true
+		typos = append(typos, Typo{Domain: name + suffix, Kind: kind})
+	}
+	for i := range name {
+		add(TypoOmission, name[:i]+name[i+1:], suffix)
+	}
+	for i := range name {
+		add(TypoRepetition, name[:i+1]+name[i:], suffix)
+	}
+	for i := range name {
+		for _, key := range []byte(qwertyNeighbours[name[i]]) {
+			add(TypoAdjacentKey, name[:i]+string(key)+name[i+1:], suffix)
+		}
+	}
+	for i := 0; i+1 < len(name); i++ {
+		add(TypoTransposition, name[:i]+string(name[i+1])+string(name[i])+name[i+2:], suffix)
+	}
+	for _, tld := range tlds {
+		add(TypoWrongTLD, name, tld)
+	}
+	return typos, nil
+}
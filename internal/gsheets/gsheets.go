@@ -0,0 +1,294 @@
+// Package gsheets appends scan results to a Google Sheets spreadsheet using a
+// service account. Sheet writes never fail a scan; errors are only reported.
+package gsheets
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/types"
+)
+
+const (
+	sheetsScope   = "https://www.googleapis.com/auth/spreadsheets"
+	sheetsBaseURL = "https://sheets.googleapis.com/v4/spreadsheets"
+	// defaultTokenURL is used when the service account file names no token_uri
+	defaultTokenURL = "https://oauth2.googleapis.com/token"
+	// credentialsEnv is the standard variable used when no credentials file is configured
+	credentialsEnv = "GOOGLE_APPLICATION_CREDENTIALS"
+	// maxBackoff caps the wait between two attempts of a throttled request
+	maxBackoff = time.Minute
+)
+
+// serviceAccount holds the fields of a service account key file the client needs
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// Client appends rows to one sheet of a spreadsheet
+type Client struct {
+	credentialsFile string
+	spreadsheetID   string
+	sheet           string
+	maxRetries      int
+	// BatchSize is the number of available domains appended at once during a run; zero
+	// appends them all at the end of the run
+	BatchSize int
+	baseURL   string
+	// client sends the requests; nil uses the shared domain.HTTPClient
+	client *http.Client
+
+	mu      sync.Mutex
+	account *serviceAccount
+	key     *rsa.PrivateKey
+	token   string
+	expiry  time.Time
+}
+
+// httpClient returns the client the requests go through
+func (c *Client) httpClient() *http.Client {
+	if c.client != nil {
+		return c.client
+	}
+	return domain.HTTPClient()
+}
+
+// FromConfig creates the client configured in [output.gsheets], or nil when no
+// spreadsheet is configured. The credentials are loaded on first use; the requests
+// go through client, or the shared domain.HTTPClient when it is nil.
+func FromConfig(cfg *types.Config, client *http.Client) *Client {
+	if cfg == nil || cfg.Output.GSheets.SpreadsheetID == "" {
+		return nil
+	}
+	g := cfg.Output.GSheets
+	return &Client{
+		credentialsFile: g.CredentialsFile,
+		spreadsheetID:   g.SpreadsheetID,
+		sheet:           g.Sheet,
+		maxRetries:      g.MaxRetries,
+		BatchSize:       g.BatchSize,
+		baseURL:         sheetsBaseURL,
+		client:          client,
+	}
+}
+
+// Append adds rows below the last row of the sheet, retrying throttled and failed
+// requests with exponential backoff
+func (c *Client) Append(ctx context.Context, rows [][]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(map[string]interface{}{"values": rows})
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/%s/values/%s:append?valueInputOption=RAW&insertDataOption=INSERT_ROWS",
+		c.baseURL, url.PathEscape(c.spreadsheetID), url.PathEscape(c.sheet))
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.post(ctx, endpoint, body)
+		if err == nil {
+			return nil
+		}
+		var apiErr *apiError
+		if !errors.As(err, &apiErr) || !apiErr.retryable() || attempt >= c.maxRetries {
+			return err
+		}
+		wait := backoff
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// post sends one append request and returns the server's Retry-After hint on failure
+func (c *Client) post(ctx context.Context, endpoint string, body []byte) (time.Duration, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return 0, &apiError{status: 0, message: err.Error()}
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 == 2 {
+		return 0, nil
+	}
+	retryAfter := time.Duration(0)
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	return retryAfter, &apiError{status: resp.StatusCode, message: strings.TrimSpace(string(data))}
+}
+
+// accessToken returns a cached OAuth token, requesting a new one shortly before it expires
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Until(c.expiry) > time.Minute {
+		return c.token, nil
+	}
+	if err := c.loadCredentials(); err != nil {
+		return "", err
+	}
+
+	assertion, err := c.signedJWT(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", &apiError{message: err.Error()}
+	}
+	defer resp.Body.Close()
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		message := token.ErrorDescription
+		if message == "" {
+			message = resp.Status
+		}
+		return "", &apiError{status: resp.StatusCode, message: "token request failed: " + message}
+	}
+	c.token = token.AccessToken
+	c.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+// loadCredentials reads the service account key file once. The path may reference
+// environment variables, e.g. "$HOME/sa.json"; empty uses GOOGLE_APPLICATION_CREDENTIALS.
+func (c *Client) loadCredentials() error {
+	if c.key != nil {
+		return nil
+	}
+	path := os.ExpandEnv(c.credentialsFile)
+	if path == "" {
+		path = os.Getenv(credentialsEnv)
+	}
+	if path == "" {
+		return fmt.Errorf("no service account file (set [output.gsheets] credentials_file or %s)", credentialsEnv)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading service account file: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return fmt.Errorf("error parsing service account file %s: %w", path, err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = defaultTokenURL
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil || account.ClientEmail == "" {
+		return fmt.Errorf("service account file %s has no client_email or private_key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("error parsing service account key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return errors.New("service account key is not an RSA key")
+	}
+	c.account, c.key = &account, key
+	return nil
+}
+
+// signedJWT builds the RS256-signed assertion exchanged for an access token
+func (c *Client) signedJWT(now time.Time) (string, error) {
+	encode := func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data), err
+	}
+	header, err := encode(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := encode(map[string]interface{}{
+		"iss":   c.account.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   c.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + claims
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// apiError is a failed request to Google; status is zero for network errors
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	if e.status == 0 {
+		return "google sheets: " + e.message
+	}
+	return fmt.Sprintf("google sheets: HTTP %d: %s", e.status, e.message)
+}
+
+// retryable reports whether the request may succeed when repeated: quota errors,
+// server errors and network errors
+func (e *apiError) retryable() bool {
+	return e.status == 0 || e.status == http.StatusTooManyRequests || e.status >= 500
+}
@@ -0,0 +1,142 @@
+package gsheets
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"domain-scanner/internal/pricing"
+	"domain-scanner/internal/types"
+)
+
+// Header is the column layout of the appended rows
+var Header = []string{"scanned_at", "domain", "signatures", "methods", "price"}
+
+// Publisher appends the available domains of one scan run. Rows are sent in batches
+// of the client's BatchSize in the background while the scan runs and the rest at
+// Finish; failures are reported through warn and never stop the scan.
+type Publisher struct {
+	client  *Client
+	methods string
+	stamp   string
+	warn    func(format string, args ...interface{})
+
+	mu        sync.Mutex
+	pending   []string
+	published map[string]bool
+	// signatures keeps the signatures of every available domain for its row
+	signatures map[string][]string
+
+	batches chan [][]string
+	done    chan struct{}
+}
+
+// NewPublisher starts publishing a run; methods names the check methods of the run
+// for the methods column, e.g. "dns+whois"
+func NewPublisher(client *Client, methods string, warn func(format string, args ...interface{})) *Publisher {
+	p := &Publisher{
+		client:     client,
+		methods:    methods,
+		stamp:      time.Now().UTC().Format(time.RFC3339),
+		warn:       warn,
+		published:  make(map[string]bool),
+		signatures: make(map[string][]string),
+		batches:    make(chan [][]string, 16),
+		done:       make(chan struct{}),
+	}
+	go p.send()
+	return p
+}
+
+// Add records a checked domain, queuing a batch once BatchSize available domains are
+// pending. Domains flagged by the blocklist are never published.
+func (p *Publisher) Add(result types.DomainResult) {
+	if p == nil || !result.Available || result.Error != nil {
+		return
+	}
+	for _, signature := range result.Signatures {
+		if signature == types.TrademarkRisk {
+			return
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.signatures[result.Domain] = result.Signatures
+	if p.client.BatchSize <= 0 {
+		return
+	}
+	p.pending = append(p.pending, result.Domain)
+	if len(p.pending) >= p.client.BatchSize {
+		p.batches <- p.rows(p.pending, nil)
+		p.pending = nil
+	}
+}
+
+// Finish appends the available domains not sent yet, with their prices when known,
+// and waits until every batch was sent or failed
+func (p *Publisher) Finish(available []string, prices map[string]pricing.Quote) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	var rest []string
+	for _, name := range available {
+		if !p.published[name] {
+			rest = append(rest, name)
+		}
+	}
+	if len(rest) > 0 {
+		p.batches <- p.rows(rest, prices)
+	}
+	p.mu.Unlock()
+	close(p.batches)
+	<-p.done
+}
+
+// rows renders the rows of the given domains and marks them published; p.mu is held
+func (p *Publisher) rows(names []string, prices map[string]pricing.Quote) [][]string {
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		price := ""
+		if quote, ok := prices[name]; ok && quote.Known() {
+			price = quote.String()
+		}
+		rows = append(rows, []string{p.stamp, name, strings.Join(p.signatures[name], ","), p.methods, price})
+		p.published[name] = true
+	}
+	return rows
+}
+
+// send appends the queued batches in order
+func (p *Publisher) send() {
+	defer close(p.done)
+	for rows := range p.batches {
+		if err := p.client.Append(context.Background(), rows); err != nil {
+			p.warn("Warning: could not append %d rows to Google Sheets: %v\n", len(rows), err)
+		}
+	}
+}
+
+// Methods describes the check methods enabled in a config, e.g. "dns+whois+ssl"
+func Methods(cfg *types.Config) string {
+	if cfg == nil {
+		return "dns+whois+ssl"
+	}
+	m := cfg.Scanner.Methods
+	var methods []string
+	for _, method := range []struct {
+		enabled bool
+		name    string
+	}{
+		{m.DNSCheck, "dns"},
+		{m.WHOISCheck, "whois"},
+		{m.SSLCheck, "ssl"},
+		{m.Custom.Command != "", m.Custom.Name},
+	} {
+		if method.enabled {
+			methods = append(methods, method.name)
+		}
+	}
+	return strings.Join(methods, "+")
+}
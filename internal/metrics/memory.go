@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyBounds are the histogram bucket upper bounds, in seconds
+var latencyBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// counterSeries is the cumulative value of a counter with one label set
+type counterSeries struct {
+	name   string
+	labels Labels
+	value  int64
+}
+
+// histogramSeries is the cumulative distribution of a histogram with one label set
+type histogramSeries struct {
+	name   string
+	labels Labels
+	count  uint64
+	sum    float64
+	// buckets has one count per bound plus the overflow bucket
+	buckets []uint64
+}
+
+// aggregate accumulates updates into cumulative series
+type aggregate struct {
+	mu         sync.Mutex
+	counters   map[string]*counterSeries
+	histograms map[string]*histogramSeries
+}
+
+func newAggregate() aggregate {
+	return aggregate{
+		counters:   make(map[string]*counterSeries),
+		histograms: make(map[string]*histogramSeries),
+	}
+}
+
+func (a *aggregate) count(name string, delta int64, labels Labels) {
+	key := seriesKey(name, labels)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	series, ok := a.counters[key]
+	if !ok {
+		series = &counterSeries{name: name, labels: copyLabels(labels)}
+		a.counters[key] = series
+	}
+	series.value += delta
+}
+
+func (a *aggregate) observe(name string, d time.Duration, labels Labels) {
+	key := seriesKey(name, labels)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	series, ok := a.histograms[key]
+	if !ok {
+		series = &histogramSeries{name: name, labels: copyLabels(labels), buckets: make([]uint64, len(latencyBounds)+1)}
+		a.histograms[key] = series
+	}
+	seconds := d.Seconds()
+	series.count++
+	series.sum += seconds
+	series.buckets[sort.SearchFloat64s(latencyBounds, seconds)]++
+}
+
+// snapshot copies the series so they can be encoded without holding the lock
+func (a *aggregate) snapshot() ([]counterSeries, []histogramSeries) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	counters := make([]counterSeries, 0, len(a.counters))
+	for _, series := range a.counters {
+		counters = append(counters, *series)
+	}
+	histograms := make([]histogramSeries, 0, len(a.histograms))
+	for _, series := range a.histograms {
+		h := *series
+		h.buckets = append([]uint64(nil), series.buckets...)
+		histograms = append(histograms, h)
+	}
+	sort.Slice(counters, func(i, j int) bool {
+		return seriesKey(counters[i].name, counters[i].labels) < seriesKey(counters[j].name, counters[j].labels)
+	})
+	sort.Slice(histograms, func(i, j int) bool {
+		return seriesKey(histograms[i].name, histograms[i].labels) < seriesKey(histograms[j].name, histograms[j].labels)
+	})
+	return counters, histograms
+}
+
+// copyLabels keeps the series independent of maps the caller may reuse
+func copyLabels(labels Labels) Labels {
+	if len(labels) == 0 {
+		return nil
+	}
+	copied := make(Labels, len(labels))
+	for key, value := range labels {
+		copied[key] = value
+	}
+	return copied
+}
+
+// Memory keeps the metrics in memory, e.g. to inspect them in tests
+type Memory struct {
+	agg aggregate
+}
+
+// NewMemory creates an empty in-memory exporter
+func NewMemory() *Memory {
+	return &Memory{agg: newAggregate()}
+}
+
+// Count adds delta to a counter
+func (m *Memory) Count(name string, delta int64, labels Labels) {
+	m.agg.count(name, delta, labels)
+}
+
+// Observe records a duration
+func (m *Memory) Observe(name string, d time.Duration, labels Labels) {
+	m.agg.observe(name, d, labels)
+}
+
+// Close does nothing; the recorded values stay readable
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Counter returns the value of a counter with exactly the given labels
+func (m *Memory) Counter(name string, labels Labels) int64 {
+	m.agg.mu.Lock()
+	defer m.agg.mu.Unlock()
+	if series, ok := m.agg.counters[seriesKey(name, labels)]; ok {
+		return series.value
+	}
+	return 0
+}
+
+// Observations returns the number of durations recorded for a histogram with exactly the given labels
+func (m *Memory) Observations(name string, labels Labels) uint64 {
+	m.agg.mu.Lock()
+	defer m.agg.mu.Unlock()
+	if series, ok := m.agg.histograms[seriesKey(name, labels)]; ok {
+		return series.count
+	}
+	return 0
+}
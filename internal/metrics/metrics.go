@@ -0,0 +1,139 @@
+// Package metrics exports scan counters and check latencies to push-based collectors
+// (StatsD or an OpenTelemetry OTLP/HTTP endpoint) or serves them for Prometheus to
+// scrape. A disabled exporter is a no-op, so callers record unconditionally.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"domain-scanner/internal/types"
+)
+
+// Metric names; exporters prepend the configured prefix
+const (
+	// Processed counts checked domains
+	Processed = "domains_processed"
+	// Available counts domains found available
+	Available = "domains_available"
+	// Registered counts domains found registered
+	Registered = "domains_registered"
+	// Special counts domains needing manual review, labelled by status
+	Special = "domains_special"
+	// Errors counts failed checks, labelled by error class
+	Errors = "check_errors"
+	// MethodLatency is the duration of a single check method, labelled by method
+	MethodLatency = "method_latency_seconds"
+	// CheckLatency is the duration of the whole check of a domain
+	CheckLatency = "check_latency_seconds"
+	// WHOISErrors counts WHOIS queries that failed, retried or not
+	WHOISErrors = "whois_errors"
+	// WHOISRateLimited counts WHOIS queries the server throttled or refused
+	WHOISRateLimited = "whois_rate_limited"
+)
+
+// Labels qualify a metric, e.g. {"method": "whois"}
+type Labels map[string]string
+
+// Exporter receives metric updates. Implementations are safe for concurrent use.
+type Exporter interface {
+	// Count adds delta to a counter
+	Count(name string, delta int64, labels Labels)
+	// Observe records a duration in a histogram
+	Observe(name string, d time.Duration, labels Labels)
+	// Close sends pending updates and releases the exporter
+	Close() error
+}
+
+// Nop is the exporter used when metrics are disabled
+type Nop struct{}
+
+func (Nop) Count(string, int64, Labels)           {}
+func (Nop) Observe(string, time.Duration, Labels) {}
+func (Nop) Close() error                          { return nil }
+
+// FromConfig creates the exporter configured in [metrics], or Nop when metrics are disabled
+func FromConfig(cfg *types.Config) (Exporter, error) {
+	if cfg == nil {
+		return Nop{}, nil
+	}
+	m := cfg.Metrics
+	switch m.Exporter {
+	case "":
+		return Nop{}, nil
+	case types.MetricsStatsD:
+		return NewStatsD(net.JoinHostPort(m.StatsD.Host, fmt.Sprint(m.StatsD.Port)), m.Prefix)
+	case types.MetricsOTLP:
+		return NewOTLP(m.OTLP.Endpoint, m.OTLP.Headers, m.Prefix, time.Duration(m.OTLP.Interval)*time.Millisecond), nil
+	case types.MetricsPrometheus:
+		return NewPrometheus(m.Prometheus.Addr, m.Prefix)
+	default:
+		return nil, fmt.Errorf("invalid metrics exporter %q (use %q, %q or %q)", m.Exporter,
+			types.MetricsStatsD, types.MetricsOTLP, types.MetricsPrometheus)
+	}
+}
+
+// RecordResult counts a checked domain by its outcome and records how long its check took
+func RecordResult(e Exporter, result types.DomainResult) {
+	e.Count(Processed, 1, nil)
+	if result.Elapsed > 0 {
+		e.Observe(CheckLatency, result.Elapsed, nil)
+	}
+	switch {
+	case result.Error != nil:
+		e.Count(Errors, 1, Labels{"class": ErrorClass(result.Error)})
+	case result.SpecialStatus != "":
+		e.Count(Special, 1, Labels{"status": result.SpecialStatus})
+	case result.Available:
+		e.Count(Available, 1, nil)
+	default:
+		e.Count(Registered, 1, nil)
+	}
+}
+
+// ErrorClass groups check errors into a few stable label values
+func ErrorClass(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case strings.Contains(strings.ToLower(err.Error()), "rate limit"):
+		return "rate_limited"
+	default:
+		return "other"
+	}
+}
+
+// seriesKey identifies a metric and label set, e.g. "check_errors{class=timeout}"
+func seriesKey(name string, labels Labels) string {
+	if len(labels) == 0 {
+		return name
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, key := range sortedKeys(labels) {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sortedKeys returns the label keys in a stable order
+func sortedKeys(labels Labels) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"domain-scanner/internal/types"
+)
+
+func TestRecordResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		result types.DomainResult
+		metric string
+		labels Labels
+	}{
+		{name: "available", result: types.DomainResult{Available: true, Elapsed: time.Second}, metric: Available},
+		{name: "registered", result: types.DomainResult{Elapsed: time.Second}, metric: Registered},
+		{name: "special", result: types.DomainResult{SpecialStatus: "REDEMPTIONPERIOD", Elapsed: time.Second},
+			metric: Special, labels: Labels{"status": "REDEMPTIONPERIOD"}},
+		{name: "timeout", result: types.DomainResult{Error: context.DeadlineExceeded, Elapsed: time.Second},
+			metric: Errors, labels: Labels{"class": "timeout"}},
+		{name: "rate limited", result: types.DomainResult{Error: errors.New("WHOIS rate limit reached"), Elapsed: time.Second},
+			metric: Errors, labels: Labels{"class": "rate_limited"}},
+		// Domains decided without a check record no latency
+		{name: "without elapsed time", result: types.DomainResult{Available: true}, metric: Available},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory()
+			RecordResult(m, tt.result)
+			RecordResult(m, tt.result)
+
+			if got := m.Counter(Processed, nil); got != 2 {
+				t.Errorf("%s = %d, want 2", Processed, got)
+			}
+			if got := m.Counter(tt.metric, tt.labels); got != 2 {
+				t.Errorf("%s%v = %d, want 2", tt.metric, tt.labels, got)
+			}
+			var want uint64
+			if tt.result.Elapsed > 0 {
+				want = 2
+			}
+			if got := m.Observations(CheckLatency, nil); got != want {
+				t.Errorf("%s observations = %d, want %d", CheckLatency, got, want)
+			}
+		})
+	}
+}
+
+func TestMemoryLabels(t *testing.T) {
+	m := NewMemory()
+	labels := Labels{"method": "whois"}
+	m.Count(WHOISRateLimited, 1, labels)
+	m.Observe(MethodLatency, time.Millisecond, labels)
+	// The exporter keeps its own copy of the labels
+	labels["method"] = "dns"
+	m.Count(WHOISRateLimited, 2, Labels{"method": "whois"})
+
+	if got := m.Counter(WHOISRateLimited, Labels{"method": "whois"}); got != 3 {
+		t.Errorf("Counter(method=whois) = %d, want 3", got)
+	}
+	if got := m.Counter(WHOISRateLimited, nil); got != 0 {
+		t.Errorf("Counter() without labels = %d, want 0", got)
+	}
+	if got := m.Observations(MethodLatency, Labels{"method": "whois"}); got != 1 {
+		t.Errorf("Observations(method=whois) = %d, want 1", got)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+}
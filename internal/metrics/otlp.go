@@ -0,0 +1,219 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// otlpAggregationCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE of the OTLP metrics protocol
+const otlpAggregationCumulative = 2
+
+// otlpServiceName is reported as the service.name resource attribute
+const otlpServiceName = "domain-scanner"
+
+// OTLP aggregates the updates and pushes cumulative sums and histograms to an
+// OTLP/HTTP endpoint (JSON encoding) every interval and on Close
+type OTLP struct {
+	endpoint string
+	headers  map[string]string
+	prefix   string
+	client   *http.Client
+	start    time.Time
+	agg      aggregate
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewOTLP creates an exporter pushing to endpoint, e.g. "http://localhost:4318/v1/metrics"
+func NewOTLP(endpoint string, headers map[string]string, prefix string, interval time.Duration) *OTLP {
+	o := &OTLP{
+		endpoint: endpoint,
+		headers:  headers,
+		prefix:   prefix,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		start:    time.Now(),
+		agg:      newAggregate(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go o.loop(interval)
+	return o
+}
+
+// Count adds delta to a counter
+func (o *OTLP) Count(name string, delta int64, labels Labels) {
+	o.agg.count(name, delta, labels)
+}
+
+// Observe records a duration
+func (o *OTLP) Observe(name string, d time.Duration, labels Labels) {
+	o.agg.observe(name, d, labels)
+}
+
+// Close stops the periodic pushes and sends the final values
+func (o *OTLP) Close() error {
+	o.once.Do(func() { close(o.stop) })
+	<-o.done
+	return o.push()
+}
+
+// loop pushes the metrics every interval until Close
+func (o *OTLP) loop(interval time.Duration) {
+	defer close(o.done)
+	if interval <= 0 {
+		<-o.stop
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-ticker.C:
+			// A failed push is retried with the cumulative values of the next one
+			_ = o.push()
+		}
+	}
+}
+
+// push sends the current cumulative values
+func (o *OTLP) push() error {
+	counters, histograms := o.agg.snapshot()
+	if len(counters) == 0 && len(histograms) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(o.encode(counters, histograms, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range o.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error pushing metrics to %s: %w", o.endpoint, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("error pushing metrics to %s: %s", o.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// encode builds an ExportMetricsServiceRequest in the OTLP JSON encoding, where
+// 64-bit integers are strings
+func (o *OTLP) encode(counters []counterSeries, histograms []histogramSeries, now time.Time) map[string]interface{} {
+	start := strconv.FormatInt(o.start.UnixNano(), 10)
+	timestamp := strconv.FormatInt(now.UnixNano(), 10)
+
+	// Series of the same metric share one metric entry
+	var metrics []map[string]interface{}
+	byName := make(map[string]map[string]interface{})
+	points := func(name string, build func() map[string]interface{}) map[string]interface{} {
+		metric, ok := byName[name]
+		if !ok {
+			metric = build()
+			byName[name] = metric
+			metrics = append(metrics, metric)
+		}
+		return metric
+	}
+
+	for _, series := range counters {
+		metric := points(series.name, func() map[string]interface{} {
+			return map[string]interface{}{
+				"name": o.metricName(series.name),
+				"sum": map[string]interface{}{
+					"aggregationTemporality": otlpAggregationCumulative,
+					"isMonotonic":            true,
+					"dataPoints":             []interface{}{},
+				},
+			}
+		})
+		sum := metric["sum"].(map[string]interface{})
+		sum["dataPoints"] = append(sum["dataPoints"].([]interface{}), map[string]interface{}{
+			"attributes":        otlpAttributes(series.labels),
+			"startTimeUnixNano": start,
+			"timeUnixNano":      timestamp,
+			"asInt":             strconv.FormatInt(series.value, 10),
+		})
+	}
+
+	for _, series := range histograms {
+		metric := points(series.name, func() map[string]interface{} {
+			return map[string]interface{}{
+				"name": o.metricName(series.name),
+				"unit": "s",
+				"histogram": map[string]interface{}{
+					"aggregationTemporality": otlpAggregationCumulative,
+					"dataPoints":             []interface{}{},
+				},
+			}
+		})
+		buckets := make([]string, len(series.buckets))
+		for i, count := range series.buckets {
+			buckets[i] = strconv.FormatUint(count, 10)
+		}
+		histogram := metric["histogram"].(map[string]interface{})
+		histogram["dataPoints"] = append(histogram["dataPoints"].([]interface{}), map[string]interface{}{
+			"attributes":        otlpAttributes(series.labels),
+			"startTimeUnixNano": start,
+			"timeUnixNano":      timestamp,
+			"count":             strconv.FormatUint(series.count, 10),
+			"sum":               series.sum,
+			"bucketCounts":      buckets,
+			"explicitBounds":    latencyBounds,
+		})
+	}
+
+	return map[string]interface{}{
+		"resourceMetrics": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": otlpAttributes(Labels{"service.name": otlpServiceName}),
+			},
+			"scopeMetrics": []interface{}{map[string]interface{}{
+				"scope":   map[string]interface{}{"name": otlpServiceName},
+				"metrics": metrics,
+			}},
+		}},
+	}
+}
+
+// metricName prepends the prefix, e.g. "domain_scanner.domains_processed"
+func (o *OTLP) metricName(name string) string {
+	if o.prefix == "" {
+		return name
+	}
+	return o.prefix + "." + name
+}
+
+// otlpAttributes converts labels to OTLP key-value attributes
+func otlpAttributes(labels Labels) []interface{} {
+	attributes := make([]interface{}, 0, len(labels))
+	for _, key := range sortedKeys(labels) {
+		attributes = append(attributes, map[string]interface{}{
+			"key":   key,
+			"value": map[string]interface{}{"stringValue": labels[key]},
+		})
+	}
+	return attributes
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// prometheusContentType is the content type of the Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Prometheus aggregates the updates and serves them for scraping on an embedded HTTP
+// server at /metrics, in the Prometheus text format. Counters get the usual _total
+// suffix, e.g. domain_scanner_domains_processed_total.
+type Prometheus struct {
+	prefix string
+	agg    aggregate
+	server *http.Server
+	addr   net.Addr
+}
+
+// NewPrometheus starts serving the metrics on addr, e.g. ":9090"
+func NewPrometheus(addr, prefix string) (*Prometheus, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error serving metrics on %s: %w", addr, err)
+	}
+	p := &Prometheus{prefix: prefix, agg: newAggregate(), addr: listener.Addr()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", p.serveMetrics)
+	p.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		// Serve returns http.ErrServerClosed once Close shuts the server down
+		_ = p.server.Serve(listener)
+	}()
+	return p, nil
+}
+
+// Addr returns the address the metrics are served on, with the port chosen for ":0"
+func (p *Prometheus) Addr() string {
+	return p.addr.String()
+}
+
+// Count adds delta to a counter
+func (p *Prometheus) Count(name string, delta int64, labels Labels) {
+	p.agg.count(name, delta, labels)
+}
+
+// Observe records a duration
+func (p *Prometheus) Observe(name string, d time.Duration, labels Labels) {
+	p.agg.observe(name, d, labels)
+}
+
+// Close stops serving the metrics, waiting briefly for running scrapes
+func (p *Prometheus) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return p.server.Shutdown(ctx)
+}
+
+func (p *Prometheus) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	_, _ = w.Write(p.encode())
+}
+
+// encode renders the current cumulative values in the Prometheus text format
+func (p *Prometheus) encode() []byte {
+	counters, histograms := p.agg.snapshot()
+	var buf bytes.Buffer
+
+	// Series of the same metric share one TYPE line; the snapshot sorts them by name
+	typed := make(map[string]bool)
+	declare := func(name, kind string) {
+		if !typed[name] {
+			typed[name] = true
+			fmt.Fprintf(&buf, "# TYPE %s %s\n", name, kind)
+		}
+	}
+
+	for _, series := range counters {
+		name := p.metricName(series.name) + "_total"
+		declare(name, "counter")
+		fmt.Fprintf(&buf, "%s%s %d\n", name, prometheusLabels(series.labels, ""), series.value)
+	}
+
+	for _, series := range histograms {
+		name := p.metricName(series.name)
+		declare(name, "histogram")
+		// Prometheus buckets are cumulative, the aggregate's are not
+		var cumulative uint64
+		for i, bound := range latencyBounds {
+			cumulative += series.buckets[i]
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, prometheusLabels(series.labels, le), cumulative)
+		}
+		fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, prometheusLabels(series.labels, "+Inf"), series.count)
+		fmt.Fprintf(&buf, "%s_sum%s %s\n", name, prometheusLabels(series.labels, ""), strconv.FormatFloat(series.sum, 'g', -1, 64))
+		fmt.Fprintf(&buf, "%s_count%s %d\n", name, prometheusLabels(series.labels, ""), series.count)
+	}
+	return buf.Bytes()
+}
+
+// metricName joins the prefix and a metric name with an underscore, the only
+// separator Prometheus names allow besides the colon
+func (p *Prometheus) metricName(name string) string {
+	if p.prefix == "" {
+		return name
+	}
+	return p.prefix + "_" + name
+}
+
+// prometheusLabels formats labels as {key="value",...}, with the le label of a
+// histogram bucket last when le is set
+func prometheusLabels(labels Labels, le string) string {
+	if len(labels) == 0 && le == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)+1)
+	for _, key := range sortedKeys(labels) {
+		pairs = append(pairs, prometheusLabelName(key)+"="+strconv.Quote(labels[key]))
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// prometheusLabelName replaces the characters Prometheus label names do not allow,
+// e.g. the dot of "service.name", with underscores
+func prometheusLabelName(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, key)
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// StatsD sends every update as a UDP packet. Label values become name segments,
+// e.g. "domain_scanner.check_errors.timeout:1|c", so plain StatsD servers accept them.
+type StatsD struct {
+	conn   net.Conn
+	prefix string
+}
+
+// NewStatsD creates an exporter sending to a StatsD server at host:port
+func NewStatsD(addr, prefix string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to StatsD at %s: %w", addr, err)
+	}
+	return &StatsD{conn: conn, prefix: prefix}, nil
+}
+
+// Count sends a counter update
+func (s *StatsD) Count(name string, delta int64, labels Labels) {
+	s.send(fmt.Sprintf("%s:%d|c", s.bucket(name, labels), delta))
+}
+
+// Observe sends a timing in milliseconds
+func (s *StatsD) Observe(name string, d time.Duration, labels Labels) {
+	name = strings.TrimSuffix(name, "_seconds")
+	s.send(fmt.Sprintf("%s:%.3f|ms", s.bucket(name, labels), float64(d)/float64(time.Millisecond)))
+}
+
+// Close closes the UDP socket
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+// bucket renders the StatsD bucket name of a metric
+func (s *StatsD) bucket(name string, labels Labels) string {
+	parts := []string{name}
+	if s.prefix != "" {
+		parts = []string{s.prefix, name}
+	}
+	for _, key := range sortedKeys(labels) {
+		parts = append(parts, statsdSanitizer.Replace(labels[key]))
+	}
+	return strings.Join(parts, ".")
+}
+
+// send writes a packet; StatsD is fire-and-forget, so errors are dropped
+func (s *StatsD) send(packet string) {
+	_, _ = s.conn.Write([]byte(packet))
+}
+
+// statsdSanitizer replaces the characters with a meaning in the StatsD line format
+var statsdSanitizer = strings.NewReplacer(".", "_", ":", "_", "|", "_", "@", "_", "#", "_", " ", "_")
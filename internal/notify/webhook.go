@@ -0,0 +1,273 @@
+// Package notify posts available domains to a webhook as soon as they are found.
+// Notifications never slow down or fail a scan; errors are only reported.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/pricing"
+	"domain-scanner/internal/types"
+)
+
+const (
+	// queueSize bounds the notifications waiting to be sent; further ones are dropped
+	// so that a slow webhook never blocks the scan
+	queueSize = 256
+	// maxBackoff caps the wait between two attempts of a notification
+	maxBackoff = 30 * time.Second
+)
+
+// Payload is the data of a body template. Without a template the body is the
+// canonical JSON form of the types.DomainResult instead.
+type Payload struct {
+	Domain     string   `json:"domain"`
+	Suffix     string   `json:"suffix"`
+	Signatures []string `json:"signatures"`
+	Timestamp  string   `json:"timestamp"`
+	// Price and Premium are the quoted registration price, empty without price lookups
+	Price   string `json:"price,omitempty"`
+	Premium bool   `json:"premium,omitempty"`
+}
+
+// NewPayload returns the template data of a result found at a time
+func NewPayload(result types.DomainResult, at time.Time) Payload {
+	suffix := ""
+	if idx := strings.Index(result.Domain, "."); idx >= 0 {
+		suffix = result.Domain[idx:]
+	}
+	payload := Payload{
+		Domain:     result.Domain,
+		Suffix:     suffix,
+		Signatures: result.Signatures,
+		Timestamp:  at.UTC().Format(time.RFC3339),
+		Price:      result.Price,
+		Premium:    result.Premium,
+	}
+	if payload.Signatures == nil {
+		payload.Signatures = []string{}
+	}
+	return payload
+}
+
+// notification is a queued notification of an available domain
+type notification struct {
+	result types.DomainResult
+	at     time.Time
+}
+
+// Webhook posts a notification for every available domain to a URL
+type Webhook struct {
+	url        string
+	body       *template.Template
+	filter     *regexp.Regexp
+	maxRetries int
+	timeout    time.Duration
+	// client sends the notifications; nil uses the shared domain.HTTPClient
+	client *http.Client
+}
+
+// FromConfig creates the webhook configured in [notify.webhook], or nil when no URL is
+// configured. It posts through client; nil uses the shared domain.HTTPClient.
+func FromConfig(cfg *types.Config, client *http.Client) (*Webhook, error) {
+	if cfg == nil || cfg.Notify.Webhook.URL == "" {
+		return nil, nil
+	}
+	w := cfg.Notify.Webhook
+	webhook := &Webhook{
+		url:        w.URL,
+		maxRetries: w.MaxRetries,
+		timeout:    time.Duration(w.Timeout) * time.Millisecond,
+		client:     client,
+	}
+	var err error
+	if webhook.body, err = ParseTemplate(w.Template); err != nil {
+		return nil, err
+	}
+	if w.Filter != "" {
+		if webhook.filter, err = regexp.Compile(w.Filter); err != nil {
+			return nil, fmt.Errorf("invalid webhook filter %q: %w", w.Filter, err)
+		}
+	}
+	return webhook, nil
+}
+
+// ParseTemplate parses a webhook body template, e.g. {"text": {{json .Domain}}} for a
+// chat webhook; the json function renders a value as JSON. Empty returns nil, which
+// posts the result in its canonical JSON form.
+func ParseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Notifier sends the notifications of one scan run in the background. Add never
+// blocks: when the queue is full the notification is dropped and counted.
+type Notifier struct {
+	webhook *Webhook
+	pricer  pricing.Provider
+	warn    func(format string, args ...interface{})
+	queue   chan notification
+	done    chan struct{}
+	dropped int64
+
+	mu   sync.Mutex
+	sent map[string]bool
+}
+
+// Start starts sending the notifications of a run; failures are reported through warn.
+// With a pricer every notification carries the price of its domain, quoted just before
+// it is posted.
+func (w *Webhook) Start(warn func(format string, args ...interface{}), pricer pricing.Provider) *Notifier {
+	n := &Notifier{
+		webhook: w,
+		pricer:  pricer,
+		warn:    warn,
+		queue:   make(chan notification, queueSize),
+		done:    make(chan struct{}),
+		sent:    make(map[string]bool),
+	}
+	go n.send()
+	return n
+}
+
+// Add queues a notification for an available domain that matches the filter. Domains
+// flagged by the blocklist are never notified, and every domain only once per run.
+func (n *Notifier) Add(result types.DomainResult) {
+	if n == nil || !result.Available || result.Error != nil {
+		return
+	}
+	for _, signature := range result.Signatures {
+		if signature == types.TrademarkRisk {
+			return
+		}
+	}
+	if n.webhook.filter != nil && !n.webhook.filter.MatchString(result.Domain) {
+		return
+	}
+	n.mu.Lock()
+	seen := n.sent[result.Domain]
+	n.sent[result.Domain] = true
+	n.mu.Unlock()
+	if seen {
+		return
+	}
+
+	select {
+	case n.queue <- notification{result: result, at: time.Now()}:
+	default:
+		atomic.AddInt64(&n.dropped, 1)
+	}
+}
+
+// Close waits until the queued notifications were sent or failed and reports the ones
+// dropped because the webhook could not keep up
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+	close(n.queue)
+	<-n.done
+	if dropped := atomic.LoadInt64(&n.dropped); dropped > 0 {
+		n.warn("Warning: dropped %d webhook notifications because the webhook was too slow\n", dropped)
+	}
+}
+
+// send posts the queued notifications in order
+func (n *Notifier) send() {
+	defer close(n.done)
+	for queued := range n.queue {
+		if n.pricer != nil {
+			quote := pricing.Lookup(context.Background(), n.pricer, queued.result.Domain)
+			queued.result.Price, queued.result.Premium = quote.String(), quote.Premium
+		}
+		if err := n.webhook.Post(context.Background(), queued.result, queued.at); err != nil {
+			n.warn("Warning: could not notify the webhook of %s: %v\n", queued.result.Domain, err)
+		}
+	}
+}
+
+// Post sends the notification of a result found at a time, retrying failed requests
+// and server errors with exponential backoff up to the configured number of retries
+func (w *Webhook) Post(ctx context.Context, result types.DomainResult, at time.Time) error {
+	body, err := w.render(result, at)
+	if err != nil {
+		return err
+	}
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil || !retry || attempt >= w.maxRetries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// render returns the body of a notification: the template output, or the result in
+// its canonical JSON form, versioned by types.SchemaVersion
+func (w *Webhook) render(result types.DomainResult, at time.Time) ([]byte, error) {
+	if w.body == nil {
+		return json.Marshal(result)
+	}
+	var buf bytes.Buffer
+	if err := w.body.Execute(&buf, NewPayload(result, at)); err != nil {
+		return nil, fmt.Errorf("error rendering webhook template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// post makes one attempt, reporting whether a failure is worth retrying
+func (w *Webhook) post(ctx context.Context, body []byte) (retry bool, err error) {
+	if w.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := w.client
+	if client == nil {
+		client = domain.HTTPClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+		fmt.Errorf("webhook answered %s", resp.Status)
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"domain-scanner/internal/pricing"
+	"domain-scanner/internal/types"
+)
+
+// receiveBody posts the result through a webhook with the template and returns the body
+// the server received
+func receiveBody(t *testing.T, template string, result types.DomainResult) []byte {
+	t.Helper()
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	cfg := &types.Config{}
+	cfg.Notify.Webhook.URL = server.URL
+	cfg.Notify.Webhook.Template = template
+	webhook, err := FromConfig(cfg, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := webhook.Post(context.Background(), result, at); err != nil {
+		t.Fatal(err)
+	}
+	return <-bodies
+}
+
+func TestWebhookBody(t *testing.T) {
+	result := types.DomainResult{Domain: "abc.com", Available: true, Signatures: []string{"SSL"}}
+
+	// The default body is the canonical result
+	body := receiveBody(t, "", result)
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("default body %s: %v", body, err)
+	}
+	if decoded["schema_version"] != float64(types.SchemaVersion) || decoded["domain"] != "abc.com" || decoded["available"] != true {
+		t.Errorf("default body = %s, want the canonical result", body)
+	}
+	var roundTrip types.DomainResult
+	if err := json.Unmarshal(body, &roundTrip); err != nil || roundTrip.Domain != result.Domain {
+		t.Errorf("default body %s does not decode as a DomainResult: %v", body, err)
+	}
+
+	// Templates render the payload
+	body = receiveBody(t, `{{.Domain}} {{.Suffix}} {{json .Signatures}} {{.Timestamp}}`, result)
+	if want := `abc.com .com ["SSL"] 2025-01-01T12:00:00Z`; string(body) != want {
+		t.Errorf("template body = %s, want %s", body, want)
+	}
+}
+
+// fakePricer quotes every domain at a fixed price, or fails for failing
+type fakePricer struct {
+	failing string
+}
+
+func (fakePricer) Name() string { return "fake" }
+
+func (p fakePricer) Quote(_ context.Context, domain string) (pricing.Quote, error) {
+	if domain == p.failing {
+		return pricing.Quote{Domain: domain}, errors.New("no price")
+	}
+	return pricing.Quote{Domain: domain, Price: "120.00", Currency: "USD", Premium: true}, nil
+}
+
+func TestNotifierQuotesPrices(t *testing.T) {
+	bodies := make(chan []byte, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer server.Close()
+	cfg := &types.Config{}
+	cfg.Notify.Webhook.URL = server.URL
+	webhook, err := FromConfig(cfg, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := webhook.Start(t.Logf, fakePricer{failing: "b.com"})
+	n.Add(types.DomainResult{Domain: "a.com", Available: true})
+	n.Add(types.DomainResult{Domain: "b.com", Available: true})
+	n.Close()
+
+	want := map[string]types.DomainResult{
+		"a.com": {Domain: "a.com", Available: true, Price: "premium 120.00 USD", Premium: true},
+		"b.com": {Domain: "b.com", Available: true, Price: "price unknown"},
+	}
+	for i := 0; i < len(want); i++ {
+		body := <-bodies
+		var got types.DomainResult
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+		if w := want[got.Domain]; got.Price != w.Price || got.Premium != w.Premium {
+			t.Errorf("notification of %s has price %q premium %v, want %q %v", got.Domain, got.Price, got.Premium, w.Price, w.Premium)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package pricing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"domain-scanner/internal/domain"
+)
+
+const (
+	// porkbunAPI is the base URL of the Porkbun JSON API
+	porkbunAPI = "https://api.porkbun.com/api/json/v3"
+	// baseRetry is the pause before a failed base price download is attempted again
+	baseRetry = time.Minute
+)
+
+// Porkbun quotes prices through the Porkbun API. Base prices of every TLD are fetched
+// once without authentication; per-domain quotes with the premium flag need API keys
+// and are spaced by the configured interval, since Porkbun limits checkDomain calls.
+type Porkbun struct {
+	baseURL      string
+	apiKey       string
+	secretAPIKey string
+	checkPremium bool
+	interval     time.Duration
+	// client sends the API requests; nil uses the shared domain.HTTPClient
+	client *http.Client
+
+	// base holds the TLD base prices once loaded; a failed load is retried after baseRetry
+	baseMu     sync.Mutex
+	base       map[string]porkbunPrice
+	baseErr    error
+	baseFailed time.Time
+
+	// next is the earliest time of the next per-domain query
+	mu   sync.Mutex
+	next time.Time
+}
+
+// porkbunPrice is an entry of the pricing/get response
+type porkbunPrice struct {
+	Registration string `json:"registration"`
+	Renewal      string `json:"renewal"`
+}
+
+// NewPorkbun creates a Porkbun provider. Per-domain quotes are used when checkPremium
+// is set and both keys are present; intervalMillis spaces them.
+func NewPorkbun(apiKey, secretAPIKey string, checkPremium bool, intervalMillis int) *Porkbun {
+	return &Porkbun{
+		baseURL:      porkbunAPI,
+		apiKey:       apiKey,
+		secretAPIKey: secretAPIKey,
+		checkPremium: checkPremium && apiKey != "" && secretAPIKey != "",
+		interval:     time.Duration(intervalMillis) * time.Millisecond,
+	}
+}
+
+// Name returns "porkbun"
+func (p *Porkbun) Name() string {
+	return "porkbun"
+}
+
+// Quote returns the per-domain price when premium checks are enabled, falling back to
+// the cached TLD base price when the per-domain query fails
+func (p *Porkbun) Quote(ctx context.Context, name string) (Quote, error) {
+	var domainErr error
+	if p.checkPremium {
+		quote, err := p.quoteDomain(ctx, name)
+		if err == nil {
+			return quote, nil
+		}
+		if ctx.Err() != nil {
+			return Quote{Domain: name}, err
+		}
+		domainErr = err
+	}
+
+	quote, err := p.quoteTLD(ctx, name)
+	if err != nil {
+		if domainErr != nil {
+			err = domainErr
+		}
+		return Quote{Domain: name}, err
+	}
+	if domainErr != nil {
+		quote.Error = domainErr.Error()
+	}
+	return quote, nil
+}
+
+// quoteTLD returns the base registration price of the domain's TLD
+func (p *Porkbun) quoteTLD(ctx context.Context, name string) (Quote, error) {
+	base, err := p.basePrices(ctx)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	price, ok := base[tldOf(name)]
+	if !ok || price.Registration == "" {
+		return Quote{}, &apiError{provider: p.Name(), message: fmt.Sprintf("no price for .%s", tldOf(name))}
+	}
+	return Quote{
+		Domain:   name,
+		Price:    price.Registration,
+		Renewal:  price.Renewal,
+		Currency: "USD",
+		Source:   "tld",
+	}, nil
+}
+
+// basePrices returns the cached TLD base prices, downloading them on first use
+func (p *Porkbun) basePrices(ctx context.Context) (map[string]porkbunPrice, error) {
+	p.baseMu.Lock()
+	defer p.baseMu.Unlock()
+	if p.base != nil {
+		return p.base, nil
+	}
+	if p.baseErr != nil && time.Since(p.baseFailed) < baseRetry {
+		return nil, p.baseErr
+	}
+
+	var resp struct {
+		Status  string                  `json:"status"`
+		Message string                  `json:"message"`
+		Pricing map[string]porkbunPrice `json:"pricing"`
+	}
+	err := p.post(ctx, "/pricing/get", struct{}{}, &resp)
+	if err == nil && resp.Status != "SUCCESS" {
+		err = &apiError{provider: p.Name(), message: resp.Message}
+	}
+	if err != nil {
+		p.baseErr, p.baseFailed = err, time.Now()
+		return nil, err
+	}
+	p.base = resp.Pricing
+	return p.base, nil
+}
+
+// quoteDomain asks Porkbun for the price and premium flag of a single domain
+func (p *Porkbun) quoteDomain(ctx context.Context, name string) (Quote, error) {
+	if err := p.wait(ctx); err != nil {
+		return Quote{}, err
+	}
+
+	var resp struct {
+		Status   string `json:"status"`
+		Message  string `json:"message"`
+		Response struct {
+			Avail        string `json:"avail"`
+			Price        string `json:"price"`
+			RegularPrice string `json:"regularPrice"`
+			Premium      string `json:"premium"`
+		} `json:"response"`
+	}
+	body := map[string]string{"apikey": p.apiKey, "secretapikey": p.secretAPIKey}
+	if err := p.post(ctx, "/domain/checkDomain/"+name, body, &resp); err != nil {
+		return Quote{}, err
+	}
+	if resp.Status != "SUCCESS" {
+		return Quote{}, &apiError{provider: p.Name(), message: resp.Message}
+	}
+	if resp.Response.Price == "" {
+		return Quote{}, &apiError{provider: p.Name(), message: fmt.Sprintf("no price for %s (avail: %s)", name, resp.Response.Avail)}
+	}
+	return Quote{
+		Domain:   name,
+		Price:    resp.Response.Price,
+		Renewal:  resp.Response.RegularPrice,
+		Currency: "USD",
+		Premium:  resp.Response.Premium == "yes",
+		Source:   "domain",
+	}, nil
+}
+
+// wait blocks until the next per-domain query is allowed
+func (p *Porkbun) wait(ctx context.Context) error {
+	p.mu.Lock()
+	now := time.Now()
+	slot := p.next
+	if slot.Before(now) {
+		slot = now
+	}
+	p.next = slot.Add(p.interval)
+	p.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// post sends a JSON request to the Porkbun API and decodes the JSON response
+func (p *Porkbun) post(ctx context.Context, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := p.client
+	if client == nil {
+		client = domain.HTTPClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s: invalid response (HTTP %d): %w", p.Name(), resp.StatusCode, err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// Package pricing looks up registration prices of available domains at a registrar.
+// Lookups never change the availability classification; a failed lookup only leaves
+// the price unknown.
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"domain-scanner/internal/types"
+)
+
+// Quote is the registration price of a domain
+type Quote struct {
+	Domain string `json:"domain"`
+	// Price is the first-year registration price as returned by the provider; empty when unknown
+	Price    string `json:"price,omitempty"`
+	Renewal  string `json:"renewal,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	Premium  bool   `json:"premium"`
+	// Source tells whether the price is the TLD base price ("tld") or was quoted for the domain ("domain")
+	Source string `json:"source,omitempty"`
+	// Error explains why the price is unknown
+	Error string `json:"error,omitempty"`
+}
+
+// Known reports whether the quote holds a price
+func (q Quote) Known() bool {
+	return q.Price != ""
+}
+
+// String renders the quote for result files and reports, e.g. "9.73 USD" or "premium 120.00 USD"
+func (q Quote) String() string {
+	if !q.Known() {
+		return "price unknown"
+	}
+	price := q.Price
+	if q.Currency != "" {
+		price += " " + q.Currency
+	}
+	if q.Premium {
+		price = "premium " + price
+	}
+	return price
+}
+
+// Provider quotes registration prices at a registrar
+type Provider interface {
+	// Name identifies the provider in progress messages
+	Name() string
+	// Quote returns the price of an available domain. It returns an error when the
+	// price is unknown; the quote then still names the domain.
+	Quote(ctx context.Context, domain string) (Quote, error)
+}
+
+// FromConfig creates the provider configured in [pricing], or nil when price lookups are
+// disabled. Its requests go through client; nil uses the shared domain.HTTPClient.
+func FromConfig(cfg *types.Config, client *http.Client) Provider {
+	if cfg == nil {
+		return nil
+	}
+	switch cfg.Pricing.Provider {
+	case types.PricingPorkbun:
+		porkbun := NewPorkbun(os.Getenv(cfg.Pricing.APIKeyEnv), os.Getenv(cfg.Pricing.SecretAPIKeyEnv),
+			cfg.Pricing.CheckPremium, cfg.Pricing.Interval)
+		porkbun.client = client
+		return porkbun
+	default:
+		return nil
+	}
+}
+
+// Lookup quotes a domain, recording an unknown price with its error
+func Lookup(ctx context.Context, provider Provider, domain string) Quote {
+	quote, err := provider.Quote(ctx, domain)
+	if err != nil {
+		quote = Quote{Domain: domain, Error: err.Error()}
+	}
+	return quote
+}
+
+// Annotate quotes every domain in order. Unknown prices are recorded with their error;
+// cancelling ctx stops the lookups and returns the quotes collected so far.
+func Annotate(ctx context.Context, provider Provider, domains []string) map[string]Quote {
+	quotes := make(map[string]Quote, len(domains))
+	for _, domain := range domains {
+		if ctx.Err() != nil {
+			break
+		}
+		quotes[domain] = Lookup(ctx, provider, domain)
+	}
+	return quotes
+}
+
+// cache is a Provider that quotes every domain once
+type cache struct {
+	Provider
+	mu     sync.Mutex
+	quotes map[string]Quote
+}
+
+// Cached returns a provider that remembers the known prices of a provider, so that a
+// domain quoted for its notification is not quoted again for the result files. Quotes
+// are looked up one at a time, which suits the rate limits of the providers.
+func Cached(provider Provider) Provider {
+	return &cache{Provider: provider, quotes: make(map[string]Quote)}
+}
+
+// Quote returns the remembered price of the domain or quotes it
+func (c *cache) Quote(ctx context.Context, domain string) (Quote, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if quote, ok := c.quotes[domain]; ok {
+		return quote, nil
+	}
+	quote, err := c.Provider.Quote(ctx, domain)
+	if err == nil {
+		c.quotes[domain] = quote
+	}
+	return quote, err
+}
+
+// tldOf returns the TLD of a domain without the leading dot
+func tldOf(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if idx := strings.LastIndex(domain, "."); idx >= 0 {
+		return domain[idx+1:]
+	}
+	return domain
+}
+
+// apiError is an error reported by a provider API
+type apiError struct {
+	provider string
+	message  string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.provider, e.message)
+}
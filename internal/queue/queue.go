@@ -0,0 +1,370 @@
+// Package queue distributes a scan over several machines through Redis streams.
+// A producer pushes the generated domains to a jobs stream, consumers check them
+// and push the results to a results stream, and a collector drains the results into
+// the usual output files. Consumers that crash leave their domains pending in the
+// consumer group, from where other consumers reclaim them.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/types"
+	"domain-scanner/internal/worker"
+)
+
+// Consumer group names
+const (
+	workerGroup    = "workers"
+	collectorGroup = "collectors"
+)
+
+// Tuning of the stream handling
+const (
+	// readBlock is how long a read waits for new entries before checking for the end of the scan
+	readBlock = 2 * time.Second
+	// pushBatch is the number of domains sent in one pipeline
+	pushBatch = 100
+	// maxBacklog pauses the producer while this many domains are waiting, so the
+	// keyspace is not buffered in Redis memory
+	maxBacklog = 10000
+	// reclaimEvery is how often a consumer looks for domains left by crashed consumers
+	reclaimEvery = time.Minute
+)
+
+// ReclaimIdle is how long a domain may stay unacknowledged before another consumer
+// takes it over; it must exceed the longest check including WHOIS retries
+var ReclaimIdle = 5 * time.Minute
+
+// Queue is a named scan in a Redis database
+type Queue struct {
+	url     string
+	jobs    string
+	results string
+	meta    string
+	log     io.Writer
+}
+
+// New describes the queue called name at the Redis URL; progress goes to log
+func New(url, name string, log io.Writer) *Queue {
+	prefix := "domain-scanner:" + name
+	return &Queue{
+		url:     url,
+		jobs:    prefix + ":jobs",
+		results: prefix + ":results",
+		meta:    prefix + ":meta",
+		log:     log,
+	}
+}
+
+// connect opens a connection and creates the consumer groups if needed
+func (q *Queue) connect(ctx context.Context) (*redisClient, error) {
+	c, err := dialRedis(ctx, q.url)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range [][2]string{{q.jobs, workerGroup}, {q.results, collectorGroup}} {
+		_, err := c.do(0, "XGROUP", "CREATE", group[0], group[1], "0", "MKSTREAM")
+		if err != nil && !strings.HasPrefix(err.Error(), "redis: BUSYGROUP") {
+			c.close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Produce pushes the domains to the jobs stream and records their number once the
+// channel is drained. Cancelling ctx stops producing; the scan then never completes.
+func (q *Queue) Produce(ctx context.Context, domains <-chan string) (int, error) {
+	c, err := q.connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.close()
+	if _, err := c.do(0, "HSET", q.meta, "done", "0", "produced", "0"); err != nil {
+		return 0, err
+	}
+
+	produced := 0
+	batch := make([][]string, 0, pushBatch)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		replies, err := c.pipeline(0, batch)
+		if err != nil {
+			return err
+		}
+		for _, reply := range replies {
+			if err, ok := reply.(redisError); ok {
+				return err
+			}
+		}
+		produced += len(batch)
+		batch = batch[:0]
+		return q.waitBacklog(ctx, c)
+	}
+
+	for name := range domains {
+		if ctx.Err() != nil {
+			return produced, ctx.Err()
+		}
+		batch = append(batch, []string{"XADD", q.jobs, "*", "domain", name})
+		if len(batch) == pushBatch {
+			if err := flush(); err != nil {
+				return produced, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return produced, err
+	}
+	_, err = c.do(0, "HSET", q.meta, "produced", strconv.Itoa(produced), "done", "1")
+	return produced, err
+}
+
+// waitBacklog pauses while too many domains are waiting to be checked
+func (q *Queue) waitBacklog(ctx context.Context, c *redisClient) error {
+	for {
+		reply, err := c.do(0, "XLEN", q.jobs)
+		if err != nil {
+			return err
+		}
+		if n, _ := reply.(int64); n < maxBacklog {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(readBlock):
+		}
+	}
+}
+
+// Consume checks domains from the jobs stream until the producer has finished and
+// no domain is pending, waiting for pacer before every check
+func (q *Queue) Consume(ctx context.Context, consumer string, check worker.CheckFunc, pacer *domain.Pacer) (int, error) {
+	c, err := q.connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.close()
+	if check == nil {
+		check = worker.Check
+	}
+
+	checked := 0
+	lastReclaim := time.Time{}
+	for ctx.Err() == nil {
+		var entries []streamEntry
+		if time.Since(lastReclaim) >= reclaimEvery {
+			lastReclaim = time.Now()
+			reply, err := c.do(0, "XAUTOCLAIM", q.jobs, workerGroup, consumer,
+				strconv.FormatInt(ReclaimIdle.Milliseconds(), 10), "0-0", "COUNT", "10")
+			if err != nil {
+				return checked, err
+			}
+			if parts, ok := reply.([]interface{}); ok && len(parts) >= 2 {
+				entries = parseEntries(parts[1])
+				if len(entries) > 0 {
+					fmt.Fprintf(q.log, "Reclaimed %d domains from stalled consumers\n", len(entries))
+				}
+			}
+		}
+		if len(entries) == 0 {
+			reply, err := c.do(readBlock, "XREADGROUP", "GROUP", workerGroup, consumer, "COUNT", "1",
+				"BLOCK", strconv.FormatInt(readBlock.Milliseconds(), 10), "STREAMS", q.jobs, ">")
+			if err != nil {
+				return checked, err
+			}
+			entries = streamEntries(reply)
+		}
+
+		if len(entries) == 0 {
+			finished, err := q.jobsFinished(c)
+			if err != nil || finished {
+				return checked, err
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			if err := pacer.Wait(ctx, entry.fields["domain"]); err != nil {
+				// Left pending so that another consumer picks the domain up
+				return checked, err
+			}
+			result := check(ctx, entry.fields["domain"])
+			if errors.Is(result.Error, context.Canceled) {
+				// Left pending so that another consumer picks the domain up
+				return checked, ctx.Err()
+			}
+			data, err := json.Marshal(result)
+			if err != nil {
+				return checked, err
+			}
+			replies, err := c.pipeline(0, [][]string{
+				{"XADD", q.results, "*", "result", string(data)},
+				{"XACK", q.jobs, workerGroup, entry.id},
+				{"XDEL", q.jobs, entry.id},
+			})
+			if err != nil {
+				return checked, err
+			}
+			for _, reply := range replies {
+				if err, ok := reply.(redisError); ok {
+					return checked, err
+				}
+			}
+			checked++
+			fmt.Fprintf(q.log, "[%d] %s %s\n", checked, result.Domain, resultLabel(result))
+		}
+	}
+	return checked, ctx.Err()
+}
+
+// jobsFinished reports whether the producer is done and every domain was checked
+func (q *Queue) jobsFinished(c *redisClient) (bool, error) {
+	replies, err := c.pipeline(0, [][]string{
+		{"HGET", q.meta, "done"},
+		{"XLEN", q.jobs},
+	})
+	if err != nil {
+		return false, err
+	}
+	done, _ := replies[0].(string)
+	waiting, _ := replies[1].(int64)
+	return done == "1" && waiting == 0, nil
+}
+
+// Collect streams the results from the results stream, dropping duplicates left by
+// reclaimed domains, until every produced domain has a result. Once the results are
+// saved, finish acknowledges and removes them and reports a read error that ended the
+// stream early; results that were not acknowledged are delivered again by the next Collect.
+func (q *Queue) Collect(ctx context.Context, collector string) (<-chan types.DomainResult, func() error, error) {
+	c, err := q.connect(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make(chan types.DomainResult)
+	var readErr error
+	var ids []string
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer close(results)
+		seen := make(map[string]bool)
+		// Pending results of an earlier collector run with the same name come first
+		start := "0"
+		for ctx.Err() == nil {
+			reply, err := c.do(readBlock, "XREADGROUP", "GROUP", collectorGroup, collector, "COUNT", "100",
+				"BLOCK", strconv.FormatInt(readBlock.Milliseconds(), 10), "STREAMS", q.results, start)
+			if err != nil {
+				readErr = err
+				return
+			}
+			entries := streamEntries(reply)
+			if start != ">" {
+				if len(entries) == 0 {
+					start = ">"
+					continue
+				}
+				start = entries[len(entries)-1].id
+			}
+			for _, entry := range entries {
+				var result types.DomainResult
+				if err := json.Unmarshal([]byte(entry.fields["result"]), &result); err != nil || seen[result.Domain] {
+					ids = append(ids, entry.id)
+					continue
+				}
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+				seen[result.Domain] = true
+				ids = append(ids, entry.id)
+			}
+
+			if len(entries) == 0 {
+				reply, err := c.do(0, "HMGET", q.meta, "done", "produced")
+				if err != nil {
+					readErr = err
+					return
+				}
+				values, _ := reply.([]interface{})
+				if len(values) == 2 {
+					done, _ := values[0].(string)
+					produced, _ := values[1].(string)
+					if n, _ := strconv.Atoi(produced); done == "1" && len(seen) >= n {
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	finish := func() error {
+		<-stopped
+		defer c.close()
+		if readErr != nil {
+			return readErr
+		}
+		for len(ids) > 0 {
+			n := len(ids)
+			if n > pushBatch {
+				n = pushBatch
+			}
+			chunk := ids[:n]
+			ids = ids[n:]
+			replies, err := c.pipeline(0, [][]string{
+				append([]string{"XACK", q.results, collectorGroup}, chunk...),
+				append([]string{"XDEL", q.results}, chunk...),
+			})
+			if err != nil {
+				return err
+			}
+			for _, reply := range replies {
+				if err, ok := reply.(redisError); ok {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return results, finish, nil
+}
+
+// streamEntries extracts the entries of the single stream in an XREADGROUP reply
+func streamEntries(reply interface{}) []streamEntry {
+	streams, _ := reply.([]interface{})
+	if len(streams) == 0 {
+		return nil
+	}
+	stream, _ := streams[0].([]interface{})
+	if len(stream) != 2 {
+		return nil
+	}
+	return parseEntries(stream[1])
+}
+
+// resultLabel renders a result for the consumer's progress output
+func resultLabel(result types.DomainResult) string {
+	switch {
+	case result.Error != nil:
+		return "ERROR: " + result.Error.Error()
+	case result.SpecialStatus != "":
+		return "SPECIAL: " + result.SpecialStatus
+	case result.Available:
+		return "AVAILABLE"
+	default:
+		return "REGISTERED"
+	}
+}
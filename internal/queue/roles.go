@@ -0,0 +1,146 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"domain-scanner/internal/types"
+	"domain-scanner/pkg/scanner"
+)
+
+// Roles of a process in a distributed scan
+const (
+	// RoleProducer generates the keyspace into the queue and collects the results
+	RoleProducer = "producer"
+	// RoleConsumer checks domains from the queue
+	RoleConsumer = "consumer"
+	// RoleCollector only collects the results into the output files
+	RoleCollector = "collector"
+)
+
+// collectorName is the consumer name of the collector in its group. A single
+// collector is expected; a restarted one takes over the unsaved results.
+const collectorName = "collector"
+
+// RunRole runs one role of a distributed scan with the scanner's options. Producers
+// and collectors return the summary of the collected results, consumers a nil summary.
+func RunRole(ctx context.Context, s *scanner.Scanner, opts scanner.ScanOptions, role string, q *Queue) (*scanner.Summary, error) {
+	switch role {
+	case RoleConsumer:
+		return nil, consume(ctx, s, opts, q)
+	case RoleProducer:
+		domains, err := s.GenerateContext(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		produceErr := make(chan error, 1)
+		go func() {
+			produced, err := q.Produce(ctx, domains)
+			if err == nil {
+				fmt.Fprintf(q.log, "Queued %d domains\n", produced)
+			}
+			produceErr <- err
+		}()
+		summary, err := collect(ctx, s, opts, q)
+		if err != nil {
+			return summary, err
+		}
+		return summary, <-produceErr
+	case RoleCollector:
+		return collect(ctx, s, opts, q)
+	default:
+		return nil, fmt.Errorf("invalid role %q (use %q, %q or %q)", role, RoleProducer, RoleConsumer, RoleCollector)
+	}
+}
+
+// consume checks domains from the queue with opts.Workers consumers, using the
+// scanner's checker unless the options replace it
+func consume(ctx context.Context, s *scanner.Scanner, opts scanner.ScanOptions, q *Queue) error {
+	check := opts.Checker
+	if check == nil {
+		check = s.CheckerWithLog(opts.Log)
+	}
+	host, _ := os.Hostname()
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	fmt.Fprintf(q.log, "Consuming domains using %d workers...\n", workers)
+
+	var wg sync.WaitGroup
+	pacer := s.NewPacer(opts.Delay, workers)
+	errs := make([]error, workers)
+	counts := make([]int, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("%s-%d-%d", host, os.Getpid(), i)
+			counts[i], errs[i] = q.Consume(ctx, name, check, pacer)
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	fmt.Fprintf(q.log, "Checked %d domains\n", total)
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collect feeds the results of the queue through a regular scan run, so the summary
+// and the output files are produced exactly as in a local scan
+func collect(ctx context.Context, s *scanner.Scanner, opts scanner.ScanOptions, q *Queue) (*scanner.Summary, error) {
+	results, finish, err := q.Collect(ctx, collectorName)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	collected := make(map[string]types.DomainResult)
+	domains := make(chan string)
+	go func() {
+		defer close(domains)
+		for result := range results {
+			mu.Lock()
+			collected[result.Domain] = result
+			mu.Unlock()
+			select {
+			case domains <- result.Domain:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	opts.Domains = domains
+	opts.Checker = func(_ context.Context, name string) types.DomainResult {
+		mu.Lock()
+		result := collected[name]
+		delete(collected, name)
+		mu.Unlock()
+		return result
+	}
+	// The results are already checked; the retry would only see the same results again
+	opts.Workers, opts.Delay = 1, 0
+	opts.RetryRateLimited = false
+	opts.ZoneFiles, opts.CTCheck, opts.Prefilter = nil, false, false
+	if opts.Log == nil {
+		opts.Log = io.Discard
+	}
+
+	summary, err := s.Run(ctx, opts)
+	if finishErr := finish(); finishErr != nil && err == nil {
+		err = finishErr
+	}
+	return summary, err
+}
@@ -0,0 +1,118 @@
+package rawdns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DNS constants used by the prefilter
+const (
+	typeNS  = 2
+	typeOPT = 41
+	classIN = 1
+
+	rcodeSuccess  = 0
+	rcodeNXDomain = 3
+
+	headerLen = 12
+	// udpSize is the EDNS0 payload size advertised, small enough to avoid fragmentation
+	udpSize = 1232
+)
+
+// response is the part of a DNS response the prefilter needs
+type response struct {
+	id        uint16
+	rcode     int
+	truncated bool
+	answers   int
+	name      string
+}
+
+// buildQuery encodes a recursive NS query for name with an EDNS0 OPT record
+func buildQuery(id uint16, name string) ([]byte, error) {
+	msg := make([]byte, headerLen, headerLen+len(name)+2+4+11)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], 0x0100) // RD
+	binary.BigEndian.PutUint16(msg[4:], 1)      // QDCOUNT
+	binary.BigEndian.PutUint16(msg[10:], 1)     // ARCOUNT: OPT
+
+	name = strings.TrimSuffix(name, ".")
+	if len(name) > 253 {
+		return nil, fmt.Errorf("domain name too long: %q", name)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("invalid domain name %q", name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0, 0, typeNS, 0, classIN)
+
+	// OPT pseudo-record: root name, type, payload size as class, zero TTL and rdata
+	msg = append(msg, 0, 0, typeOPT, byte(udpSize>>8), byte(udpSize&0xff), 0, 0, 0, 0, 0, 0)
+	return msg, nil
+}
+
+// parseResponse decodes the header and question of a DNS response
+func parseResponse(msg []byte) (response, error) {
+	if len(msg) < headerLen {
+		return response{}, errors.New("short DNS message")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&0x8000 == 0 {
+		return response{}, errors.New("DNS message is not a response")
+	}
+	if binary.BigEndian.Uint16(msg[4:]) != 1 {
+		return response{}, errors.New("DNS response without a single question")
+	}
+	r := response{
+		id:        binary.BigEndian.Uint16(msg[0:]),
+		rcode:     int(flags & 0x000f),
+		truncated: flags&0x0200 != 0,
+		answers:   int(binary.BigEndian.Uint16(msg[6:])),
+	}
+	name, _, err := readName(msg, headerLen)
+	if err != nil {
+		return response{}, err
+	}
+	r.name = name
+	return r, nil
+}
+
+// readName decodes a possibly compressed domain name at off and returns it in lower
+// case together with the offset following it
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errors.New("truncated DNS name")
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.ToLower(strings.Join(labels, ".")), end, nil
+		case length&0xc0 == 0xc0:
+			if off+1 >= len(msg) || jumps > 10 {
+				return "", 0, errors.New("invalid DNS name compression")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			jumps++
+		default:
+			if off+1+length > len(msg) {
+				return "", 0, errors.New("truncated DNS label")
+			}
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+}
@@ -0,0 +1,343 @@
+// Package rawdns is a high-throughput DNS prefilter. It sends raw NS queries over a
+// single UDP socket to a pool of recursive resolvers with thousands of queries in
+// flight, so that names with NS records are classified registered without the
+// per-domain lookups of the full checker.
+package rawdns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"domain-scanner/internal/types"
+)
+
+// Signature marks results classified registered by the prefilter
+const Signature = "RAWDNS_NS"
+
+// MaxConcurrency is the most queries in flight, bounded by the 16-bit query ID
+const MaxConcurrency = 50000
+
+// Verdict is the prefilter's answer for one name. Names that are not registered
+// include NXDOMAIN, empty answers, errors and unanswered queries; they need the full checks.
+type Verdict struct {
+	Name       string
+	Registered bool
+}
+
+// Prefilter classifies names with raw NS queries
+type Prefilter struct {
+	resolvers   []*net.UDPAddr
+	concurrency int
+	timeout     time.Duration
+	retries     int
+	tcpFallback bool
+}
+
+// query is a name waiting for its answer
+type query struct {
+	name    string
+	packet  []byte
+	attempt int
+	// resolver is the index of the resolver asked by the current attempt
+	resolver int
+	sent     time.Time
+}
+
+// New creates a prefilter for resolvers given as "host" or "host:port"
+func New(resolvers []string, concurrency, timeoutMillis, retries int, tcpFallback bool) (*Prefilter, error) {
+	if len(resolvers) == 0 {
+		return nil, fmt.Errorf("the DNS prefilter needs at least one resolver")
+	}
+	if concurrency < 1 || concurrency > MaxConcurrency {
+		return nil, fmt.Errorf("invalid DNS prefilter concurrency %d (use 1 to %d)", concurrency, MaxConcurrency)
+	}
+	p := &Prefilter{
+		concurrency: concurrency,
+		timeout:     time.Duration(timeoutMillis) * time.Millisecond,
+		retries:     retries,
+		tcpFallback: tcpFallback,
+	}
+	for _, resolver := range resolvers {
+		if _, _, err := net.SplitHostPort(resolver); err != nil {
+			resolver = net.JoinHostPort(resolver, "53")
+		}
+		addr, err := net.ResolveUDPAddr("udp", resolver)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DNS prefilter resolver %q: %w", resolver, err)
+		}
+		p.resolvers = append(p.resolvers, addr)
+	}
+	return p, nil
+}
+
+// FromConfig creates the prefilter selected by [scanner] prefilter, or nil when none is selected
+func FromConfig(cfg *types.Config) (*Prefilter, error) {
+	if cfg == nil || cfg.Scanner.Prefilter != types.PrefilterRawDNS {
+		return nil, nil
+	}
+	r := cfg.Scanner.RawDNS
+	return New(r.Resolvers, r.Concurrency, r.Timeout, r.Retries, r.TCPFallback)
+}
+
+// run is the state of one Run call
+type run struct {
+	*Prefilter
+	ctx  context.Context
+	conn *net.UDPConn
+	// slots bounds the names between being read from the input and being delivered
+	slots chan struct{}
+	// done carries finished verdicts to the forwarder; it holds one entry per slot
+	done chan Verdict
+
+	mu      sync.Mutex
+	pending map[uint16]*query
+	nextID  uint16
+	next    int
+	wg      sync.WaitGroup
+}
+
+// Run classifies the names of in and returns their verdicts in completion order. The
+// channel is closed once in is drained and every name is answered; cancelling ctx
+// stops reading in and drops the remaining verdicts.
+func (p *Prefilter) Run(ctx context.Context, in <-chan string) (<-chan Verdict, error) {
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening DNS prefilter socket: %w", err)
+	}
+	// Best effort: a small receive buffer only costs retries
+	conn.SetReadBuffer(4 << 20)
+	r := &run{
+		Prefilter: p,
+		ctx:       ctx,
+		conn:      conn,
+		slots:     make(chan struct{}, p.concurrency),
+		done:      make(chan Verdict, p.concurrency),
+		pending:   make(map[uint16]*query),
+	}
+	out := make(chan Verdict, 1000)
+
+	stopTimer := make(chan struct{})
+	go r.read()
+	go r.expire(stopTimer)
+	go func() {
+		defer close(out)
+		for v := range r.done {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+			}
+			<-r.slots
+		}
+	}()
+	go func() {
+		r.send(in)
+		r.wg.Wait()
+		close(stopTimer)
+		conn.Close()
+		close(r.done)
+	}()
+	return out, nil
+}
+
+// send queries every name of in, waiting for a free slot before each one
+func (r *run) send(in <-chan string) {
+	for {
+		var name string
+		select {
+		case next, ok := <-in:
+			if !ok {
+				return
+			}
+			name = next
+		case <-r.ctx.Done():
+			return
+		}
+		select {
+		case r.slots <- struct{}{}:
+		case <-r.ctx.Done():
+			return
+		}
+		r.wg.Add(1)
+		packet, err := buildQuery(0, name)
+		if err != nil {
+			r.finish(Verdict{Name: name})
+			continue
+		}
+		q := &query{name: strings.ToLower(strings.TrimSuffix(name, ".")), packet: packet}
+
+		r.mu.Lock()
+		// Slots bound the queries in flight far below the ID space, so a free ID is near
+		for {
+			r.nextID++
+			if _, used := r.pending[r.nextID]; !used {
+				break
+			}
+		}
+		binary.BigEndian.PutUint16(packet, r.nextID)
+		q.resolver = r.next % len(r.resolvers)
+		r.next++
+		r.pending[r.nextID] = q
+		r.mu.Unlock()
+		r.transmit(q)
+	}
+}
+
+// transmit sends the current attempt of a query; the caller no longer holds mu
+func (r *run) transmit(q *query) {
+	r.mu.Lock()
+	q.sent = time.Now()
+	addr := r.resolvers[q.resolver]
+	r.mu.Unlock()
+	// A failed write is handled like a lost packet by expire
+	r.conn.WriteToUDP(q.packet, addr)
+}
+
+// read matches responses to pending queries until the socket is closed
+func (r *run) read() {
+	buf := make([]byte, 65535)
+	for {
+		n, from, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		resp, err := parseResponse(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		r.mu.Lock()
+		q, ok := r.pending[resp.id]
+		// Spoofed or late answers for a reused ID do not match the resolver or the name
+		if !ok || q.name != resp.name || !sameAddr(from, r.resolvers[q.resolver]) {
+			r.mu.Unlock()
+			continue
+		}
+		switch {
+		case resp.truncated && r.tcpFallback:
+			delete(r.pending, resp.id)
+			r.mu.Unlock()
+			go r.finish(Verdict{Name: q.name, Registered: r.queryTCP(q)})
+		case resp.rcode == rcodeSuccess || resp.rcode == rcodeNXDomain || resp.truncated:
+			delete(r.pending, resp.id)
+			r.mu.Unlock()
+			r.finish(Verdict{Name: q.name, Registered: resp.rcode == rcodeSuccess && resp.answers > 0})
+		default:
+			// SERVFAIL, REFUSED and the like are retried at another resolver
+			r.mu.Unlock()
+			r.retry(resp.id, q)
+		}
+	}
+}
+
+// expire retries or gives up the queries that timed out, and gives up every query
+// once ctx is cancelled
+func (r *run) expire(stop <-chan struct{}) {
+	tick := r.timeout / 4
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			var expired []uint16
+			cancelled := r.ctx.Err() != nil
+			r.mu.Lock()
+			for id, q := range r.pending {
+				if cancelled || now.Sub(q.sent) >= r.timeout {
+					expired = append(expired, id)
+				}
+			}
+			queries := make([]*query, len(expired))
+			for i, id := range expired {
+				queries[i] = r.pending[id]
+			}
+			r.mu.Unlock()
+			for i, id := range expired {
+				r.retry(id, queries[i])
+			}
+		}
+	}
+}
+
+// retry sends a query again to the next resolver, or passes the name on once its
+// retries are used up or ctx is cancelled
+func (r *run) retry(id uint16, q *query) {
+	r.mu.Lock()
+	if r.pending[id] != q {
+		// Answered meanwhile
+		r.mu.Unlock()
+		return
+	}
+	q.attempt++
+	if q.attempt > r.retries || r.ctx.Err() != nil {
+		delete(r.pending, id)
+		r.mu.Unlock()
+		r.finish(Verdict{Name: q.name})
+		return
+	}
+	q.resolver = (q.resolver + 1) % len(r.resolvers)
+	q.sent = time.Now()
+	r.mu.Unlock()
+	r.transmit(q)
+}
+
+// finish hands a verdict to the forwarder; done has room for every slot
+func (r *run) finish(v Verdict) {
+	r.done <- v
+	r.wg.Done()
+}
+
+// queryTCP repeats a truncated query over TCP and reports whether the name has NS records
+func (r *run) queryTCP(q *query) bool {
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+	r.mu.Lock()
+	addr := r.resolvers[q.resolver]
+	r.mu.Unlock()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr.String())
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	frame := make([]byte, 2+len(q.packet))
+	binary.BigEndian.PutUint16(frame, uint16(len(q.packet)))
+	copy(frame[2:], q.packet)
+	if _, err := conn.Write(frame); err != nil {
+		return false
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return false
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return false
+	}
+	resp, err := parseResponse(msg)
+	if err != nil || resp.name != q.name {
+		return false
+	}
+	return resp.rcode == rcodeSuccess && resp.answers > 0
+}
+
+// sameAddr reports whether a response came from the queried resolver
+func sameAddr(a, b *net.UDPAddr) bool {
+	return a.Port == b.Port && a.IP.Equal(b.IP)
+}
@@ -0,0 +1,370 @@
+package rawdns
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dnsServer is a local DNS server answering NS queries over UDP and TCP by the first
+// label of the name:
+//
+//	reg*    NOERROR with an NS record
+//	free*   NXDOMAIN
+//	empty*  NOERROR without answers
+//	fail*   SERVFAIL
+//	flaky*  SERVFAIL for the first query, then like reg
+//	drop*   no answer
+//	spoof*  an answer for another name
+//	big*    truncated over UDP, an NS record over TCP
+type dnsServer struct {
+	udp *net.UDPConn
+	tcp net.Listener
+
+	mu      sync.Mutex
+	queries map[string]int
+}
+
+// newDNSServer starts a DNS server on a local port used for both UDP and TCP
+func newDNSServer(tb testing.TB) *dnsServer {
+	tb.Helper()
+	s := &dnsServer{queries: make(map[string]int)}
+	for attempt := 0; s.tcp == nil; attempt++ {
+		udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			tb.Fatal(err)
+		}
+		tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+		if err != nil {
+			udp.Close()
+			if attempt == 10 {
+				tb.Fatal(err)
+			}
+			continue
+		}
+		s.udp, s.tcp = udp, tcp
+	}
+	go s.serveUDP()
+	go s.serveTCP()
+	tb.Cleanup(func() {
+		s.udp.Close()
+		s.tcp.Close()
+	})
+	return s
+}
+
+// Addr returns the address of the server
+func (s *dnsServer) Addr() string {
+	return s.udp.LocalAddr().String()
+}
+
+// Queries returns how often name was asked over UDP
+func (s *dnsServer) Queries(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries[name]
+}
+
+func (s *dnsServer) serveUDP() {
+	buf := make([]byte, 512)
+	for {
+		n, from, err := s.udp.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if answer := s.answer(buf[:n], false); answer != nil {
+			s.udp.WriteToUDP(answer, from)
+		}
+	}
+}
+
+func (s *dnsServer) serveTCP() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			var size [2]byte
+			if _, err := io.ReadFull(conn, size[:]); err != nil {
+				return
+			}
+			msg := make([]byte, binary.BigEndian.Uint16(size[:]))
+			if _, err := io.ReadFull(conn, msg); err != nil {
+				return
+			}
+			answer := s.answer(msg, true)
+			frame := binary.BigEndian.AppendUint16(nil, uint16(len(answer)))
+			conn.Write(append(frame, answer...))
+		}()
+	}
+}
+
+// answer builds the response to a query, or nil to leave it unanswered
+func (s *dnsServer) answer(msg []byte, tcp bool) []byte {
+	name, end, err := readName(msg, headerLen)
+	if err != nil {
+		return nil
+	}
+	question := msg[headerLen : end+4]
+	label, _, _ := strings.Cut(name, ".")
+
+	s.mu.Lock()
+	if !tcp {
+		s.queries[name]++
+	}
+	count := s.queries[name]
+	s.mu.Unlock()
+
+	rcode, answers, truncated := rcodeSuccess, 0, false
+	switch {
+	case strings.HasPrefix(label, "reg"):
+		answers = 1
+	case strings.HasPrefix(label, "free"):
+		rcode = rcodeNXDomain
+	case strings.HasPrefix(label, "empty"):
+	case strings.HasPrefix(label, "fail"):
+		rcode = 2
+	case strings.HasPrefix(label, "flaky"):
+		if count == 1 {
+			rcode = 2
+		} else {
+			answers = 1
+		}
+	case strings.HasPrefix(label, "drop"):
+		return nil
+	case strings.HasPrefix(label, "spoof"):
+		question = append([]byte{5, 'o', 't', 'h', 'e', 'r'}, question...)
+		answers = 1
+	case strings.HasPrefix(label, "big"):
+		if tcp {
+			answers = 1
+		} else {
+			truncated = true
+		}
+	}
+
+	flags := uint16(0x8180) | uint16(rcode) // QR, RD, RA
+	if truncated {
+		flags |= 0x0200
+	}
+	resp := make([]byte, headerLen, 512)
+	copy(resp, msg[:2])
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	binary.BigEndian.PutUint16(resp[6:], uint16(answers))
+	resp = append(resp, question...)
+	if answers > 0 {
+		// NS record for the question name: ns1.example.net
+		resp = append(resp, 0xc0, headerLen, 0, typeNS, 0, classIN, 0, 0, 0x0e, 0x10)
+		rdata := []byte{3, 'n', 's', '1', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'n', 'e', 't', 0}
+		resp = binary.BigEndian.AppendUint16(resp, uint16(len(rdata)))
+		resp = append(resp, rdata...)
+	}
+	return resp
+}
+
+// runPrefilter classifies names and returns the registered ones, sorted, after
+// checking that every name got exactly one verdict
+func runPrefilter(t *testing.T, p *Prefilter, names []string) []string {
+	t.Helper()
+	in := make(chan string, len(names))
+	for _, name := range names {
+		in <- name
+	}
+	close(in)
+	out, err := p.Run(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verdicts := make(map[string]int)
+	var registered []string
+	for v := range out {
+		verdicts[v.Name]++
+		if v.Registered {
+			registered = append(registered, v.Name)
+		}
+	}
+	for _, name := range names {
+		if n := verdicts[strings.ToLower(strings.TrimSuffix(name, "."))]; n != 1 {
+			t.Errorf("%s got %d verdicts, want 1", name, n)
+		}
+	}
+	sort.Strings(registered)
+	return registered
+}
+
+func TestPrefilter(t *testing.T) {
+	server := newDNSServer(t)
+	names := []string{
+		"reg1.test", "REG2.test.", "free1.test", "empty1.test", "fail1.test",
+		"flaky1.test", "drop1.test", "spoof1.test", "big1.test", "in..valid.test",
+	}
+
+	tests := []struct {
+		name        string
+		retries     int
+		tcpFallback bool
+		want        []string
+	}{
+		{name: "retries and tcp fallback", retries: 2, tcpFallback: true, want: []string{"big1.test", "flaky1.test", "reg1.test", "reg2.test"}},
+		{name: "no tcp fallback", retries: 2, want: []string{"flaky1.test", "reg1.test", "reg2.test"}},
+		{name: "no retries", retries: 0, tcpFallback: true, want: []string{"big1.test", "reg1.test", "reg2.test"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.mu.Lock()
+			server.queries = make(map[string]int)
+			server.mu.Unlock()
+			p, err := New([]string{server.Addr()}, 4, 100, tt.retries, tt.tcpFallback)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := runPrefilter(t, p, names); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("registered = %v, want %v", got, tt.want)
+			}
+			// Failures and timeouts are asked once per attempt
+			for _, name := range []string{"fail1.test", "drop1.test"} {
+				if got := server.Queries(name); got != tt.retries+1 {
+					t.Errorf("%s asked %d times, want %d", name, got, tt.retries+1)
+				}
+			}
+			if got := server.Queries("reg1.test"); got != 1 {
+				t.Errorf("reg1.test asked %d times, want once", got)
+			}
+		})
+	}
+}
+
+func TestPrefilterRotatesResolvers(t *testing.T) {
+	dead, alive := newDNSServer(t), newDNSServer(t)
+	// The first resolver never answers, so every name needs the second one
+	dead.udp.Close()
+	p, err := New([]string{dead.Addr(), alive.Addr()}, 8, 50, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for i := 0; i < 20; i++ {
+		names = append(names, fmt.Sprintf("reg%d.test", i))
+	}
+	if got := runPrefilter(t, p, names); len(got) != len(names) {
+		t.Errorf("registered %d of %d names", len(got), len(names))
+	}
+}
+
+func TestPrefilterCancel(t *testing.T) {
+	server := newDNSServer(t)
+	p, err := New([]string{server.Addr()}, 2, 5000, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan string)
+	out, err := p.Run(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in <- "drop1.test"
+	in <- "drop2.test"
+	cancel()
+
+	// The unanswered queries give up at the next timer tick instead of their timeout,
+	// and Run stops reading in although it is never closed
+	done := make(chan struct{})
+	go func() {
+		for range out {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run() did not close its channel after the cancellation")
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	query, err := buildQuery(0x1234, "Example.COM.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := append([]byte(nil), query...)
+	binary.BigEndian.PutUint16(reply[2:], 0x8183) // QR, RD, RA, NXDOMAIN
+
+	tests := []struct {
+		name    string
+		msg     []byte
+		want    response
+		wantErr bool
+	}{
+		{name: "nxdomain", msg: reply, want: response{id: 0x1234, rcode: rcodeNXDomain, name: "example.com"}},
+		{name: "query", msg: query, wantErr: true},
+		{name: "short", msg: reply[:headerLen-1], wantErr: true},
+		{name: "truncated name", msg: reply[:headerLen+4], wantErr: true},
+		{name: "compression loop", msg: append(append([]byte(nil), reply[:headerLen]...), 0xc0, headerLen), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseResponse(tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseResponse() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseResponse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for _, name := range []string{"", "a..b", strings.Repeat("x", 64) + ".test", strings.Repeat("abcdefghi.", 26) + "test"} {
+		if _, err := buildQuery(1, name); err == nil {
+			t.Errorf("buildQuery(%q) = nil error", name)
+		}
+	}
+}
+
+func BenchmarkPrefilter(b *testing.B) {
+	server := newDNSServer(b)
+	p, err := New([]string{server.Addr()}, 1000, 1000, 2, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	in := make(chan string, 1000)
+	go func() {
+		for i := 0; i < b.N; i++ {
+			if i%2 == 0 {
+				in <- fmt.Sprintf("reg%d.test", i)
+			} else {
+				in <- fmt.Sprintf("free%d.test", i)
+			}
+		}
+		close(in)
+	}()
+
+	b.ResetTimer()
+	out, err := p.Run(context.Background(), in)
+	if err != nil {
+		b.Fatal(err)
+	}
+	verdicts := 0
+	for range out {
+		verdicts++
+	}
+	b.StopTimer()
+	if verdicts != b.N {
+		b.Fatalf("got %d verdicts for %d names", verdicts, b.N)
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "names/s")
+}
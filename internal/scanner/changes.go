@@ -0,0 +1,100 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"domain-scanner/internal/types"
+)
+
+// Statuses of rechecked domains besides the special status labels
+const (
+	StatusAvailable  = "AVAILABLE"
+	StatusRegistered = "REGISTERED"
+	StatusError      = "ERROR"
+)
+
+// StatusChange compares the status a domain had in an earlier run with its status now;
+// New is empty when the run was interrupted before the domain was checked
+type StatusChange struct {
+	Domain string
+	Old    string
+	New    string
+}
+
+// Changed reports whether the domain was checked and its status differs
+func (c StatusChange) Changed() bool {
+	return c.New != "" && c.New != c.Old
+}
+
+// resultStatus returns the status label of a checked domain
+func resultStatus(result types.DomainResult) string {
+	switch {
+	case result.Error != nil:
+		return StatusError
+	case result.Available:
+		return StatusAvailable
+	case result.SpecialStatus != "":
+		return result.SpecialStatus
+	default:
+		return StatusRegistered
+	}
+}
+
+// trackStatus records the status of a checked domain in Summary.Statuses
+func (s *Summary) trackStatus(opts Options, result types.DomainResult) {
+	if _, ok := opts.Previous[result.Domain]; !ok && !opts.TrackStatuses && !opts.TLDTable {
+		return
+	}
+	if s.Statuses == nil {
+		s.Statuses = make(map[string]string)
+	}
+	s.Statuses[result.Domain] = resultStatus(result)
+}
+
+// statusChanges lists the domains with an earlier status, changed ones first
+func statusChanges(opts Options, summary *Summary) []StatusChange {
+	changes := make([]StatusChange, 0, len(opts.Previous))
+	for name, old := range opts.Previous {
+		changes = append(changes, StatusChange{Domain: name, Old: old, New: summary.Statuses[name]})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Changed() != changes[j].Changed() {
+			return changes[i].Changed()
+		}
+		return changes[i].Domain < changes[j].Domain
+	})
+	return changes
+}
+
+// PrintStatusChanges writes the old and new status of every rechecked domain whose
+// status changed, followed by the number of unchanged and unchecked domains
+func PrintStatusChanges(out io.Writer, summary *Summary) {
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintf(out, "\nStatus changes:\n")
+	unchanged, unchecked := 0, 0
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, change := range summary.Changes {
+		switch {
+		case change.New == "":
+			unchecked++
+		case !change.Changed():
+			unchanged++
+		default:
+			fmt.Fprintf(tw, "- %s\t%s\t-> %s\n", change.Domain, change.Old, change.New)
+		}
+	}
+	_ = tw.Flush()
+	if changed := len(summary.Changes) - unchanged - unchecked; changed == 0 {
+		fmt.Fprintf(out, "- no status changed\n")
+	}
+	fmt.Fprintf(out, "- Unchanged: %d\n", unchanged)
+	if unchecked > 0 {
+		fmt.Fprintf(out, "- Not rechecked (interrupted): %d\n", unchecked)
+	}
+}
@@ -0,0 +1,96 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+
+	"domain-scanner/internal/types"
+	"domain-scanner/internal/worker"
+)
+
+// Gate pauses and resumes a running scan. While paused, workers finish their current
+// check and wait before the next one; cancelling the scan still stops them.
+type Gate struct {
+	mu sync.Mutex
+	// resume is closed on Resume; nil while running
+	resume chan struct{}
+	// watch is told about every change while a run uses the gate
+	watch func(paused bool)
+}
+
+// NewGate creates an open gate
+func NewGate() *Gate {
+	return &Gate{}
+}
+
+// Pause holds the workers before their next check; it reports whether the gate was open
+func (g *Gate) Pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resume != nil {
+		return false
+	}
+	g.resume = make(chan struct{})
+	if g.watch != nil {
+		g.watch(true)
+	}
+	return true
+}
+
+// Resume lets the workers continue; it reports whether the gate was paused
+func (g *Gate) Resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resume == nil {
+		return false
+	}
+	close(g.resume)
+	g.resume = nil
+	if g.watch != nil {
+		g.watch(false)
+	}
+	return true
+}
+
+// Paused reports whether the gate holds the workers
+func (g *Gate) Paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resume != nil
+}
+
+// wait blocks while the gate is paused or until ctx is cancelled
+func (g *Gate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	resume := g.resume
+	g.mu.Unlock()
+	if resume == nil {
+		return nil
+	}
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// setWatch installs the change callback of a run; nil removes it once no call is in flight
+func (g *Gate) setWatch(fn func(paused bool)) {
+	g.mu.Lock()
+	g.watch = fn
+	g.mu.Unlock()
+}
+
+// gateChecker wraps a checker so that every check waits for a paused gate first
+func gateChecker(gate *Gate, check worker.CheckFunc) worker.CheckFunc {
+	if check == nil {
+		check = worker.Check
+	}
+	return func(ctx context.Context, name string) types.DomainResult {
+		if err := gate.wait(ctx); err != nil {
+			return types.DomainResult{Domain: name, Error: err}
+		}
+		return check(ctx, name)
+	}
+}
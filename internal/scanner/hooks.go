@@ -0,0 +1,166 @@
+package scanner
+
+import (
+	"sync/atomic"
+	"time"
+
+	"domain-scanner/internal/types"
+)
+
+// State is a stage of a scan run reported to Options.OnStateChange
+type State string
+
+// Scan states. Every run goes from started to aborted or finished, with paused and
+// resumed in between when its Gate is used.
+const (
+	StateStarted  = State("started")
+	StatePaused   = State("paused")
+	StateResumed  = State("resumed")
+	StateAborted  = State("aborted")
+	StateFinished = State("finished")
+)
+
+// Progress is a snapshot of a running scan reported to Options.OnProgress
+type Progress struct {
+	// Processed counts the checked domains; Total is the number of domains to check,
+	// zero while generation is still running. The outcome counters add up to Processed
+	// like those of Summary.
+	Processed   int
+	Total       int
+	Available   int
+	Registered  int
+	Uncertain   int
+	RateLimited int
+	Errors      int
+	// Expected is the number of domains the generator will produce when known before
+	// generation ends, e.g. for keyspaces without a regex filter; zero otherwise
+	Expected int
+	Elapsed  time.Duration
+	// Dropped counts the hook events discarded so far because the callbacks fell behind
+	Dropped int64
+}
+
+// Rate returns the checked domains per second
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Processed) / p.Elapsed.Seconds()
+}
+
+// defaultHookQueue bounds the events waiting for slow callbacks
+const defaultHookQueue = 1000
+
+// defaultProgressInterval spaces the progress callbacks when no interval is set
+const defaultProgressInterval = time.Second
+
+// hookEvent is one pending callback; exactly one field is set
+type hookEvent struct {
+	result   *types.DomainResult
+	progress *Progress
+	state    State
+}
+
+// hooks runs the callbacks of a run on a single goroutine, so that slow or panicking
+// callbacks never stall the pipeline
+type hooks struct {
+	opts    Options
+	printf  func(string, ...interface{})
+	events  chan hookEvent
+	done    chan struct{}
+	dropped int64
+}
+
+// startHooks starts the callback goroutine, or returns nil when no callback is set
+func startHooks(opts Options, printf func(string, ...interface{})) *hooks {
+	if opts.OnResult == nil && opts.OnProgress == nil && opts.OnStateChange == nil {
+		return nil
+	}
+	size := opts.HookQueue
+	if size < 1 {
+		size = defaultHookQueue
+	}
+	h := &hooks{opts: opts, printf: printf, events: make(chan hookEvent, size), done: make(chan struct{})}
+	go h.run()
+	return h
+}
+
+func (h *hooks) run() {
+	defer close(h.done)
+	for e := range h.events {
+		switch {
+		case e.result != nil:
+			h.call("OnResult", func() { h.opts.OnResult(*e.result) })
+		case e.progress != nil:
+			h.call("OnProgress", func() { h.opts.OnProgress(*e.progress) })
+		default:
+			h.call("OnStateChange", func() { h.opts.OnStateChange(e.state) })
+		}
+	}
+}
+
+// call invokes a callback, reporting a panic instead of crashing the scan
+func (h *hooks) call(name string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.printf("Warning: %s callback panicked: %v\n", name, r)
+		}
+	}()
+	fn()
+}
+
+// offer queues an event unless the queue is full, in which case it is counted as dropped
+func (h *hooks) offer(e hookEvent) {
+	select {
+	case h.events <- e:
+	default:
+		atomic.AddInt64(&h.dropped, 1)
+	}
+}
+
+// result queues a checked domain for OnResult
+func (h *hooks) result(r types.DomainResult) {
+	if h == nil || h.opts.OnResult == nil {
+		return
+	}
+	h.offer(hookEvent{result: &r})
+}
+
+// progress queues a snapshot for OnProgress
+func (h *hooks) progress(p Progress) {
+	if h == nil || h.opts.OnProgress == nil {
+		return
+	}
+	p.Dropped = atomic.LoadInt64(&h.dropped)
+	h.offer(hookEvent{progress: &p})
+}
+
+// finish queues the final snapshot once every result is in; like a state change it
+// waits for room, since the pipeline is idle by then
+func (h *hooks) finish(p Progress) {
+	if h == nil || h.opts.OnProgress == nil {
+		return
+	}
+	p.Dropped = atomic.LoadInt64(&h.dropped)
+	h.events <- hookEvent{progress: &p}
+}
+
+// state queues a state change. State changes happen at the start and the end of a run,
+// when the pipeline is idle, or in the goroutine using the Gate, so they wait for room
+// instead of being dropped.
+func (h *hooks) state(s State) {
+	if h == nil || h.opts.OnStateChange == nil {
+		return
+	}
+	h.events <- hookEvent{state: s}
+}
+
+// close waits for the queued callbacks and returns the number of dropped events
+func (h *hooks) close() int {
+	if h == nil {
+		return 0
+	}
+	close(h.events)
+	<-h.done
+	return int(atomic.LoadInt64(&h.dropped))
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+
+package scanner
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// fileLock is an advisory flock held on a lock file; the kernel releases it when the
+// process exits, so a crashed run never leaves a stale lock behind
+type fileLock struct {
+	file *os.File
+}
+
+// lockFile takes an exclusive lock on path without waiting, returning errLocked when
+// another process holds it
+func lockFile(path string) (*fileLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errLocked
+		}
+		return nil, err
+	}
+	// A run that just finished may have removed the file between the open and the lock
+	locked, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if current, err := os.Stat(path); err != nil || !os.SameFile(locked, current) {
+		file.Close()
+		return nil, errLocked
+	}
+	return &fileLock{file: file}, nil
+}
+
+// unlock removes the lock file and releases the lock
+func (l *fileLock) unlock() {
+	// Removing before unlocking keeps a waiting run from locking a file that is gone
+	os.Remove(l.file.Name())
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package scanner
+
+import (
+	"errors"
+	"os"
+)
+
+// fileLock is a lock file created exclusively; a crashed run leaves it behind and it
+// has to be removed by hand
+type fileLock struct {
+	path string
+}
+
+// lockFile creates path exclusively, returning errLocked when it already exists
+func lockFile(path string) (*fileLock, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, errLocked
+		}
+		return nil, err
+	}
+	file.Close()
+	return &fileLock{path: path}, nil
+}
+
+// unlock removes the lock file
+func (l *fileLock) unlock() {
+	os.Remove(l.path)
+}
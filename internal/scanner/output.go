@@ -0,0 +1,229 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"domain-scanner/internal/types"
+)
+
+// maxRenames bounds the numeric suffixes tried by the rename policy
+const maxRenames = 1000
+
+// errLocked is returned by lockFile when another process holds the lock
+var errLocked = errors.New("locked by another process")
+
+// outputFiles are the result file names of a run, resolved before the scan starts so
+// that a conflict with existing files or a concurrent identical run is found at once
+type outputFiles struct {
+	available, registered, special, whois, prices, scores, tldTable string
+	// append adds the results to existing files instead of replacing them
+	append bool
+	// lock is held on the available file for the duration of the run
+	lock *fileLock
+}
+
+// planOutputs resolves the result files of a run by the [output] on_conflict policy
+// and locks them against concurrent runs writing the same files
+func planOutputs(opts Options) (*outputFiles, error) {
+	// Create output directory if specified in config
+	if opts.Config != nil && opts.Config.Output.OutputDir != "" {
+		// Always create directory if it doesn't exist, even if it's "."
+		if err := os.MkdirAll(opts.Config.Output.OutputDir, 0755); err != nil {
+			return nil, fmt.Errorf("error creating output directory: %w", err)
+		}
+	}
+
+	policy := types.OutputConflictOverwrite
+	var output types.Config
+	if opts.Config != nil {
+		output = *opts.Config
+		if output.Output.OnConflict != "" {
+			policy = output.Output.OnConflict
+		}
+	}
+	base := &outputFiles{
+		available:  outputFileName(opts, output.Output.AvailableFile, "available_domains"),
+		registered: outputFileName(opts, output.Output.RegisteredFile, "registered_domains"),
+		special:    outputFileName(opts, output.Output.SpecialStatusFile, "special_status_domains"),
+		whois:      outputFileName(opts, output.Output.WHOISJSONFile, "whois"),
+		prices:     outputFileName(opts, output.Output.PricesFile, "available_prices"),
+		scores:     outputFileName(opts, output.Output.ScoresFile, "available_scores"),
+		tldTable:   outputFileName(opts, output.Output.TLDTableFile, "tld_availability"),
+		append:     policy == types.OutputConflictAppend,
+	}
+
+	if policy != types.OutputConflictRename {
+		if policy == types.OutputConflictError {
+			if existing := base.existing(opts); existing != "" {
+				return nil, fmt.Errorf("output file %s already exists; remove it or set [output] on_conflict "+
+					"to %q, %q or %q", existing, types.OutputConflictRename, types.OutputConflictAppend,
+					types.OutputConflictOverwrite)
+			}
+		}
+		lock, err := lockFile(base.available + ".lock")
+		if err != nil {
+			return nil, lockError(base.available, err)
+		}
+		base.lock = lock
+		return base, nil
+	}
+
+	// Renamed runs take the first numeric suffix whose files neither exist nor are locked
+	for n := 1; n <= maxRenames; n++ {
+		files := base
+		if n > 1 {
+			files = base.renamed(n)
+		}
+		if files.existing(opts) != "" {
+			continue
+		}
+		lock, err := lockFile(files.available + ".lock")
+		if errors.Is(err, errLocked) {
+			continue
+		}
+		if err != nil {
+			return nil, lockError(files.available, err)
+		}
+		files.lock = lock
+		return files, nil
+	}
+	return nil, fmt.Errorf("no free output file name found for %s after %d attempts", base.available, maxRenames)
+}
+
+// existing returns the first result file of the run that already exists, or ""
+func (f *outputFiles) existing(opts Options) string {
+	paths := []string{f.available, f.special}
+	if opts.ShowRegistered {
+		paths = append(paths, f.registered)
+	}
+	if opts.Config != nil && opts.Config.Output.WHOISJSONFile != "" {
+		paths = append(paths, f.whois)
+	}
+	if opts.Pricer != nil {
+		paths = append(paths, f.prices)
+	}
+	if opts.Scorer != nil {
+		paths = append(paths, f.scores)
+	}
+	if opts.TLDTable {
+		paths = append(paths, f.tldTable)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// renamed returns the file names with the numeric suffix n before their extension
+func (f *outputFiles) renamed(n int) *outputFiles {
+	rename := func(path string) string {
+		ext := filepath.Ext(path)
+		return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(path, ext), n, ext)
+	}
+	return &outputFiles{
+		available:  rename(f.available),
+		registered: rename(f.registered),
+		special:    rename(f.special),
+		whois:      rename(f.whois),
+		prices:     rename(f.prices),
+		scores:     rename(f.scores),
+		tldTable:   rename(f.tldTable),
+		append:     f.append,
+	}
+}
+
+// release unlocks the files once the results are written
+func (f *outputFiles) release() {
+	if f != nil && f.lock != nil {
+		f.lock.unlock()
+	}
+}
+
+// lockError explains a failure to lock the result files
+func lockError(path string, err error) error {
+	if errors.Is(err, errLocked) {
+		return fmt.Errorf("another scan is writing %s; wait for it to finish or set [output] on_conflict = %q",
+			path, types.OutputConflictRename)
+	}
+	return fmt.Errorf("error locking output file %s: %w", path, err)
+}
+
+// SummaryFileName is the file in the output directory that records the outcome of
+// the last run for scripts
+const SummaryFileName = "summary.json"
+
+// summaryFile is the content of SummaryFileName
+type summaryFile struct {
+	Generated   int    `json:"generated"`
+	Processed   int    `json:"processed"`
+	Available   int    `json:"available"`
+	Registered  int    `json:"registered"`
+	Special     int    `json:"special"`
+	Uncertain   int    `json:"uncertain"`
+	RateLimited int    `json:"rate_limited"`
+	Errors      int    `json:"errors"`
+	Skipped     int    `json:"skipped"`
+	Interrupted bool   `json:"interrupted"`
+	ResumeFrom  string `json:"resume_from,omitempty"`
+	Seed        int64  `json:"seed,omitempty"`
+
+	// MediumConfidence is the part of Registered known only from CT logs
+	MediumConfidence int `json:"medium_confidence,omitempty"`
+
+	AvailableFile     string `json:"available_file,omitempty"`
+	RegisteredFile    string `json:"registered_file,omitempty"`
+	SpecialStatusFile string `json:"special_status_file,omitempty"`
+
+	ExitCode    int    `json:"exit_code"`
+	ExitMeaning string `json:"exit_meaning"`
+}
+
+// WriteSummaryFile writes the counts of a finished run with its exit code and the
+// meaning of the code to summary.json in dir, replacing the file of an earlier run,
+// and returns its path
+func WriteSummaryFile(dir string, summary *Summary, code int) (string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	data, err := json.MarshalIndent(summaryFile{
+		Generated:         summary.Generated,
+		Processed:         summary.Processed,
+		Available:         len(summary.Available),
+		Registered:        summary.RegisteredCount,
+		Special:           len(summary.Special),
+		Uncertain:         summary.Uncertain,
+		RateLimited:       summary.RateLimited,
+		Errors:            summary.Errors,
+		Skipped:           summary.Skipped,
+		Interrupted:       summary.Interrupted,
+		ResumeFrom:        summary.ResumeFrom,
+		Seed:              summary.Seed,
+		MediumConfidence:  summary.MediumConfidence,
+		AvailableFile:     summary.AvailableFile,
+		RegisteredFile:    summary.RegisteredFile,
+		SpecialStatusFile: summary.SpecialStatusFile,
+		ExitCode:          code,
+		ExitMeaning:       ExitMeaning(code),
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	// Write to a temp file first so scripts never read a partial summary
+	path := filepath.Join(dir, SummaryFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp, path)
+}
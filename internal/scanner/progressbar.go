@@ -0,0 +1,113 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressBarWidth is the number of cells of the bar
+const progressBarWidth = 30
+
+// spinnerFrames animate the progress line while the total is unknown
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// ProgressBar draws a single, redrawn progress line on a terminal: a bar with the
+// processed and total count, percentage, throughput and ETA when the total is known,
+// and a spinner with a running count otherwise. Messages written through Writer clear
+// the line first and redraw it afterwards, so that they never corrupt it.
+type ProgressBar struct {
+	mu  sync.Mutex
+	out io.Writer
+	// line is the last drawn progress line; empty before the first update
+	line  string
+	frame int
+	// last is the progress of the previous update and rate the smoothed throughput
+	last Progress
+	rate float64
+}
+
+// NewProgressBar returns a progress bar drawn on out, usually os.Stderr
+func NewProgressBar(out io.Writer) *ProgressBar {
+	return &ProgressBar{out: out}
+}
+
+// Update redraws the progress line for a snapshot; use it as Options.OnProgress
+func (b *ProgressBar) Update(p Progress) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// The throughput is that of the last updates rather than the average of the run,
+	// smoothed so that single slow checks do not make it jump
+	if elapsed := (p.Elapsed - b.last.Elapsed).Seconds(); elapsed > 0 {
+		current := float64(p.Processed-b.last.Processed) / elapsed
+		if b.last.Elapsed == 0 {
+			b.rate = current
+		} else {
+			b.rate = 0.3*current + 0.7*b.rate
+		}
+	}
+	b.last = p
+	b.line = b.render(p)
+	fmt.Fprint(b.out, "\r\033[K"+b.line)
+}
+
+// render builds the progress line of a snapshot
+func (b *ProgressBar) render(p Progress) string {
+	total := p.Total
+	if total == 0 {
+		total = p.Expected
+	}
+	if total <= 0 {
+		b.frame = (b.frame + 1) % len(spinnerFrames)
+		return fmt.Sprintf("%s %d checked, %.1f domains/s, elapsed %v",
+			spinnerFrames[b.frame], p.Processed, b.rate, p.Elapsed.Round(time.Second))
+	}
+	done := p.Processed
+	if done > total {
+		done = total
+	}
+	filled := done * progressBarWidth / total
+	eta := "?"
+	if b.rate > 0 {
+		eta = fmt.Sprint((time.Duration(float64(total-done)/b.rate) * time.Second).Round(time.Second))
+	}
+	return fmt.Sprintf("[%s%s] %d/%d %5.1f%% %.1f domains/s ETA %s",
+		strings.Repeat("#", filled), strings.Repeat(".", progressBarWidth-filled),
+		p.Processed, total, float64(done)*100/float64(total), b.rate, eta)
+}
+
+// Writer returns a writer for the messages of a scan, e.g. Options.Output, that
+// clears the progress line before every write and redraws it afterwards
+func (b *ProgressBar) Writer(w io.Writer) io.Writer {
+	return progressWriter{bar: b, w: w}
+}
+
+// Finish clears the progress line; messages written afterwards appear as usual
+func (b *ProgressBar) Finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.line != "" {
+		fmt.Fprint(b.out, "\r\033[K")
+		b.line = ""
+	}
+}
+
+// progressWriter writes messages around the progress line of a ProgressBar
+type progressWriter struct {
+	bar *ProgressBar
+	w   io.Writer
+}
+
+func (pw progressWriter) Write(data []byte) (int, error) {
+	pw.bar.mu.Lock()
+	defer pw.bar.mu.Unlock()
+	if pw.bar.line == "" {
+		return pw.w.Write(data)
+	}
+	fmt.Fprint(pw.bar.out, "\r\033[K")
+	n, err := pw.w.Write(data)
+	fmt.Fprint(pw.bar.out, pw.bar.line)
+	return n, err
+}
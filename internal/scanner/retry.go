@@ -0,0 +1,117 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/types"
+	"domain-scanner/internal/worker"
+)
+
+// retryRateLimited rechecks the domains this run left WHOIS_RATE_LIMITED, slowly and with
+// few workers. Domains with a confirmed result leave the special status list of the run,
+// or take their new special status; domains that are still throttled or fail again stay
+// there for manual review. Every recheck with a verdict is passed to record.
+func retryRateLimited(ctx context.Context, opts Options, summary *Summary, special *[]types.SpecialStatusDomain,
+	record func(types.DomainResult), printf func(string, ...interface{})) {
+	var pending []string
+	seen := make(map[string]bool)
+	for _, ssd := range *special {
+		if ssd.Status == domain.RateLimitedStatus && !seen[ssd.Domain] {
+			seen[ssd.Domain] = true
+			pending = append(pending, ssd.Domain)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	workers := opts.RetryWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	delay := opts.RetryDelay
+	if delay <= 0 {
+		delay = 10 * time.Second
+	}
+	printf("Retrying %d rate-limited domains with %d workers and %v delay...\n", len(pending), workers, delay)
+
+	jobs := make(chan string)
+	results := make(chan types.DomainResult, len(pending))
+	pacer := worker.NewPacer(delay, workers, opts.DomainChecker)
+	// Workers are joined before returning so that no late check marks a domain again
+	var wg sync.WaitGroup
+	for w := 1; w <= workers; w++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			worker.Worker(ctx, id, jobs, results, pacer, opts.Checker)
+		}(w)
+	}
+	defer wg.Wait()
+	go func() {
+		defer close(jobs)
+		for _, name := range pending {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- name:
+			}
+		}
+	}()
+
+	for i := range pending {
+		var result types.DomainResult
+		select {
+		case result = <-results:
+		case <-ctx.Done():
+			// Checks that never ran keep their previous verdict; in-flight checks end first
+			wg.Wait()
+			summary.RateLimitRetried = i
+			return
+		}
+
+		summary.RateLimitRetried++
+		progress := fmt.Sprintf("[retry %d/%d]", i+1, len(pending))
+		if result.Error != nil {
+			printf("%s Error rechecking domain %s: %v\n", progress, result.Domain, result.Error)
+			continue
+		}
+		record(result)
+		if result.SpecialStatus == domain.RateLimitedStatus {
+			printf("%s Domain %s is still rate limited\n", progress, displayName(result))
+			continue
+		}
+
+		summary.RateLimitResolved++
+		summary.RateLimited--
+		*special = replaceSpecialStatus(*special, result)
+		summary.trackStatus(opts, result)
+		if result.Available {
+			printf("%s Domain %s is AVAILABLE!\n", progress, displayName(result))
+			summary.Available = append(summary.Available, result.Domain)
+			tldStat(summary, result.Domain).Available++
+		} else if countUnavailable(summary, result) && opts.ShowRegistered && listRegistered(opts, summary, result) {
+			printf("%s\n", registeredMessage(progress, result))
+		}
+	}
+	printf("Rate-limit retry resolved %d of %d domains\n", summary.RateLimitResolved, len(pending))
+}
+
+// replaceSpecialStatus drops the entries of a rechecked domain from a special status
+// list and adds its new special status, if any
+func replaceSpecialStatus(special []types.SpecialStatusDomain, result types.DomainResult) []types.SpecialStatusDomain {
+	kept := special[:0]
+	for _, ssd := range special {
+		if ssd.Domain != result.Domain {
+			kept = append(kept, ssd)
+		}
+	}
+	if !result.Available && result.SpecialStatus != "" {
+		kept = append(kept, specialStatusOf(result))
+	}
+	return kept
+}
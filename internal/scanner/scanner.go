@@ -0,0 +1,2427 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"domain-scanner/internal/ctlog"
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/generator"
+	"domain-scanner/internal/gsheets"
+	"domain-scanner/internal/metrics"
+	"domain-scanner/internal/notify"
+	"domain-scanner/internal/pricing"
+	"domain-scanner/internal/rawdns"
+	"domain-scanner/internal/scoring"
+	"domain-scanner/internal/sqlite"
+	"domain-scanner/internal/types"
+	"domain-scanner/internal/worker"
+	"domain-scanner/internal/zonefile"
+)
+
+// Options describes a single scan run
+type Options struct {
+	Length int
+	// Lengths, when set, generates every listed length in ascending order instead of
+	// Length; the keyspaces are concatenated for Offset and Limit
+	Lengths []int
+	Suffix  string
+	// Suffixes, when it holds more than one suffix, checks every generated name under
+	// each of them in turn; Suffix is the first
+	Suffixes []string
+	Pattern  string
+	// Charset, when set, generates names from these characters instead of those of
+	// Pattern, in the given order. Charsets with Unicode characters such as "äöü"
+	// generate internationalized names, which are checked in their punycode form.
+	Charset string
+	// ExcludeChars removes these characters from the charset of Pattern or Charset
+	// before the enumeration, e.g. "qv0o" for easily confused characters
+	ExcludeChars string
+	// NamePrefix and NameSuffix are fixed around the generated characters of every
+	// name, e.g. NamePrefix "go" with Length 3 checks goaaa to gozzz. Length, Offset
+	// and Limit count the generated characters only.
+	NamePrefix string
+	NameSuffix string
+	// Mask, when set, generates the names of a mask such as "a??9" instead of Length:
+	// only its wildcards are enumerated, "?" from the characters of Pattern or Charset
+	Mask string
+	// LetterPattern, when set, generates the names of a repeated-letter pattern such
+	// as "AAB" or "ABAB" instead of Length: positions with equal letters take the same
+	// character of Pattern or Charset and different letters different characters
+	LetterPattern string
+	// Template, when set, generates the names of a consonant/vowel template such as
+	// "CVCV" instead of Length and Pattern: C is a consonant, V a vowel, L a letter
+	// and N a digit
+	Template string
+	// Syllables is the number of syllables of the names of Pattern
+	// generator.PatternPronounceable, which replaces Length; zero means
+	// generator.DefaultSyllables
+	Syllables   int
+	RegexFilter string
+	RegexMode   types.RegexMode
+	// WordLists switches to combinator mode: every concatenation of one word from each
+	// list file is checked and Length and Pattern are ignored
+	WordLists []string
+	// WordSeparator joins the words of a combination, e.g. "-" for blue-fox
+	WordSeparator string
+	// Leet, when set, checks the seed word and all its leetspeak variants instead of
+	// generating names, e.g. shop, sh0p, 5hop and 5h0p; Length and Pattern are ignored
+	Leet string
+	// LeetTable replaces generator.DefaultLeetTable; every substitution also applies
+	// the other way round
+	LeetTable map[string]string
+	// KeywordFile, when set, checks the words of this list file combined with those of
+	// AffixFile instead of generating names, e.g. getshop and shopify for the keyword
+	// shop: AffixPosition places the affixes before the keyword ("prefix"), after it
+	// ("suffix") or both ways ("both", the default), and KeywordAlone checks every
+	// keyword by itself too
+	KeywordFile   string
+	AffixFile     string
+	AffixPosition string
+	KeywordAlone  bool
+	// NumRange, when set, generates the integers of a range such as "8000-8999" as
+	// names instead of Length and Pattern, zero-padded to NumPad digits; ranges of more
+	// than MaxNumRange numbers (zero: generator.DefaultMaxNumRange) are refused
+	NumRange    string
+	NumPad      int
+	MaxNumRange int
+	// InputFile checks the names of a list file, one per line, under Suffix instead of
+	// generating them; Length, Pattern and WordLists are ignored
+	InputFile string
+	Offset    int
+	Limit     int
+	// StartFrom, when set, resumes a generated keyspace at this name, e.g. "abcx" or
+	// "go0042hub" with affixes: generation starts at its counter value and every
+	// earlier name is skipped. The name must be generated by the keyspace and lie in
+	// its Offset/Limit range.
+	StartFrom string
+	// Shuffle generates the keyspace range in a pseudorandom order instead of the
+	// ascending one, e.g. to sample a huge keyspace; the order depends only on Seed and
+	// the range, so a run with the same seed repeats it and StartFrom resumes it. A
+	// zero Seed is replaced by a random one, which the run prints.
+	Shuffle bool
+	Seed    int64
+	// Sample, when positive, checks only this many distinct names drawn uniformly from
+	// the keyspace range, the first ones of its shuffled order (Shuffle is implied).
+	// With a regex filter the names are drawn from the filtered keyspace; at most
+	// sampleAttempts candidates per name are tried before the sample ends short.
+	Sample int
+	// ExpectedCount is the number of domains the run should generate; nil when unknown.
+	// A mismatch is reported as a warning since it points at a stale split or a generator change.
+	ExpectedCount  *int
+	Delay          time.Duration
+	Workers        int
+	ShowRegistered bool
+	// ExpiringBefore, when set, limits the registered domains shown and saved under
+	// ShowRegistered to those whose WHOIS expiration date lies before it; the saved
+	// lines then carry the date, soonest first
+	ExpiringBefore time.Time
+
+	// RetryRateLimited rechecks domains left WHOIS_RATE_LIMITED at the end of the run
+	// with RetryDelay between queries and RetryWorkers concurrent workers
+	RetryRateLimited bool
+	RetryDelay       time.Duration
+	RetryWorkers     int
+
+	// Domains, when set, is checked instead of generating the keyspace
+	Domains <-chan string
+	// DropDates annotates the results of the listed domains with their drop date,
+	// e.g. from an expiring domain list
+	DropDates map[string]string
+	// Permutations annotates the results of typo variants with the permutation that
+	// produced them; result files group the domains by it
+	Permutations map[string]string
+	// Previous holds the earlier status of supplied domains, e.g. read from the result
+	// files of a run being rechecked; their new status is compared in Summary.Changes
+	Previous map[string]string
+	// Blocklist drops or flags candidates containing one of its entries, depending on
+	// BlocklistMode (types.BlocklistDrop or types.BlocklistFlag); nil checks every candidate
+	Blocklist     *generator.Blocklist
+	BlocklistMode string
+	// ExcludeFile lists domains, or names under every suffix, that are skipped without
+	// a check, e.g. those whose status is already known (see generator.LoadExcludeList)
+	ExcludeFile string
+	// ZoneFiles are TLD zone files, plain or gzip-compressed; candidates delegated in
+	// them are reported registered with the zonefile.Signature signature without any
+	// query. ZoneFalsePositiveRate > 0 loads them into a bloom filter instead of an
+	// exact set, confirming its hits with an NS lookup.
+	ZoneFiles             []string
+	ZoneFalsePositiveRate float64
+	// CT looks every candidate up in Certificate Transparency logs before checking it;
+	// hits are reported registered with the ctlog.Signature signature, or still checked
+	// when CTVerify is set. Failed lookups fall through to the checker. nil disables it.
+	CT       *ctlog.Client
+	CTVerify bool
+	// Prefilter classifies candidates in bulk with raw NS queries before the checks;
+	// names with NS records are reported registered with the rawdns.Signature signature
+	Prefilter *rawdns.Prefilter
+	// Checker replaces the built-in DNS/WHOIS/SSL checker when set
+	Checker worker.CheckFunc
+	// DomainChecker is the checker of the config: the checks are spaced by its WHOIS
+	// servers and rate limits, and its wildcard DNS overrides are reported. nil uses
+	// the checker configured by domain.SetConfig.
+	DomainChecker *domain.Checker
+	// Gate pauses and resumes the workers of the run; nil never pauses
+	Gate *Gate
+	// Pricer looks up the registration prices of the available domains; nil skips lookups
+	Pricer pricing.Provider
+	// Scorer rates the available domains by brandability; nil skips scoring
+	Scorer *scoring.Scorer
+	// Sheets receives the available domains as spreadsheet rows; nil skips publishing
+	Sheets *gsheets.Client
+	// Webhook is notified of the available domains as they are found; nil notifies nobody
+	Webhook *notify.Webhook
+	// OnResult, OnProgress and OnStateChange are called by Run from a single goroutine,
+	// never concurrently. OnResult receives every domain checked in the main pass,
+	// OnProgress a snapshot every ProgressInterval (default one second) and at the end,
+	// and OnStateChange the start, pauses and resumes of Gate and the end of the run. Slow callbacks do not stall the
+	// scan: up to HookQueue (default 1000) events wait for them and further results and
+	// snapshots are dropped and counted. A panicking callback is reported in the log.
+	OnResult         func(types.DomainResult)
+	OnProgress       func(Progress)
+	OnStateChange    func(State)
+	ProgressInterval time.Duration
+	HookQueue        int
+	// Metrics counts the results of the main pass by outcome; nil disables it
+	Metrics metrics.Exporter
+
+	// Config provides output file templates and the output directory; may be nil
+	Config *types.Config
+	// FileSuffix is inserted before the extension of every result file name, e.g. "_retry"
+	FileSuffix string
+	// TrackStatuses records the final status of every checked domain in Summary.Statuses
+	TrackStatuses bool
+	// TLDTable lists the checked domains by TLD in Summary.TLDTable and its own result
+	// file, for reverse mode scans of one name across many TLDs; it implies TrackStatuses
+	TLDTable bool
+	// SkipWrite disables writing the result files
+	SkipWrite bool
+
+	// Output receives progress messages; defaults to os.Stdout
+	Output io.Writer
+	// Prefix is prepended to every progress line, e.g. a batch name
+	Prefix string
+	// DebugIndex adds the generator counter value of each domain to the progress lines
+	DebugIndex bool
+	// SlowThreshold logs a warning with the phase breakdown for every domain whose
+	// check takes at least this long; zero disables the warnings
+	SlowThreshold time.Duration
+}
+
+// Summary holds the outcome of a scan run
+type Summary struct {
+	Processed       int
+	Generated       int
+	Available       []string
+	Registered      []string
+	RegisteredCount int
+	Special         []types.SpecialStatusDomain
+	// Errors, RegisteredCount, Uncertain and RateLimited count the processed domains by
+	// outcome together with Available; they add up to Processed. Uncertain domains have
+	// a special status other than WHOIS_RATE_LIMITED and need manual review.
+	Errors            int
+	Uncertain         int
+	RateLimited       int
+	AvailableFile     string
+	RegisteredFile    string
+	SpecialStatusFile string
+	Interrupted       bool
+	// ResumeFrom is the name an interrupted scan of a generated keyspace resumes at
+	// with Options.StartFrom: the first name in generation order whose check did not
+	// finish; empty for supplied domains
+	ResumeFrom string
+	// Seed is the seed of a shuffled scan, needed with ResumeFrom to resume it; zero
+	// for the ascending order
+	Seed int64
+	// Sample is the requested size of a sampled scan and SampleSpace the size of the
+	// keyspace range it was drawn from, zero when a regex filter makes it unknown
+	Sample      int
+	SampleSpace int
+	// Skipped counts the dispatched domains whose check was cut short by an interruption;
+	// they are not part of Processed
+	Skipped int
+	// RateLimitRetried and RateLimitResolved count the domains of the rate-limit retry phase
+	RateLimitRetried  int
+	RateLimitResolved int
+	// TLDStats aggregates the results per domain suffix
+	TLDStats map[string]*TLDStat
+	// Prices holds the registration price of every available domain when a Pricer is set
+	Prices     map[string]pricing.Quote
+	PricesFile string
+	// WHOISRecords holds the parsed WHOIS response of every registered domain when
+	// the config sets a WHOIS JSON file
+	WHOISRecords  []types.WHOISRecord
+	WHOISJSONFile string
+	// DatabaseFile is the SQLite database the checked domains were written to
+	DatabaseFile string
+	// Expiries holds the expiration date of the listed registered domains whose WHOIS
+	// response gave one
+	Expiries map[string]time.Time
+	// Unicode maps the checked internationalized domains, given in their ASCII form,
+	// to their Unicode form
+	Unicode map[string]string
+	// Permutations maps the checked typo variants to the permutation that produced them
+	Permutations map[string]string
+	// Scores holds the brandability score of every available domain, best first, when a Scorer is set
+	Scores     []scoring.Score
+	ScoresFile string
+	// Blocked is the number of candidates the blocklist dropped before checking
+	Blocked int
+	// Excluded is the number of candidates skipped because of Options.ExcludeFile
+	Excluded int
+	// Flagged lists the available domains that match the blocklist in flag mode
+	Flagged []string
+	// ZoneSkipped is the number of domains found in the zone files, which needed no check
+	ZoneSkipped int
+	// MediumConfidence counts the registered domains known only from Certificate
+	// Transparency logs, which no check confirmed
+	MediumConfidence int
+	// PrefilterSkipped is the number of domains the prefilter found registered
+	PrefilterSkipped int
+	// Resumed is the number of domains skipped because the SQLite database already had them
+	Resumed int
+	// HookEventsDropped counts the callback events dropped because the callbacks fell behind
+	HookEventsDropped int
+	// ChecksSkipped counts the check methods left out because earlier ones decided a domain
+	ChecksSkipped int
+	// Slowest holds the slowest checks of the main pass, slowest first
+	Slowest []SlowDomain
+	// Unconfirmed counts the uncertain domains that strict mode did not report available
+	// for lack of an explicit availability indicator; the lax default reports them available
+	Unconfirmed int
+	// Changes compares the earlier and current status of the domains in Options.Previous,
+	// changed ones first
+	Changes []StatusChange
+	// Statuses maps the checked domains to their final status (StatusAvailable,
+	// StatusRegistered, StatusError or a special status) when Options.TrackStatuses
+	// is set, and otherwise only the domains in Options.Previous
+	Statuses map[string]string
+	// TLDTable lists every checked domain by TLD, available first, when Options.TLDTable is set
+	TLDTable     []TLDRow
+	TLDTableFile string
+}
+
+// Counted returns the sum of the outcome counters, which equals Processed
+func (s *Summary) Counted() int {
+	return len(s.Available) + s.RegisteredCount + s.Uncertain + s.RateLimited + s.Errors
+}
+
+// countUnavailable counts a checked domain that is not available by its special
+// status and reports whether it is registered
+func countUnavailable(summary *Summary, result types.DomainResult) bool {
+	switch result.SpecialStatus {
+	case "":
+		summary.RegisteredCount++
+		if result.Confidence == types.ConfidenceMedium {
+			summary.MediumConfidence++
+		}
+		return true
+	case domain.RateLimitedStatus:
+		summary.RateLimited++
+	case domain.NoEvidenceStatus:
+		summary.Uncertain++
+		summary.Unconfirmed++
+	default:
+		summary.Uncertain++
+	}
+	return false
+}
+
+// expiryLayout is the form of the expiration dates in the output
+const expiryLayout = "2006-01-02"
+
+// listRegistered adds a registered domain to the registered list unless
+// opts.ExpiringBefore leaves it out, and reports whether it was added
+func listRegistered(opts Options, summary *Summary, result types.DomainResult) bool {
+	if !opts.ExpiringBefore.IsZero() &&
+		(result.ExpiryDate.IsZero() || !result.ExpiryDate.Before(opts.ExpiringBefore)) {
+		return false
+	}
+	summary.Registered = append(summary.Registered, result.Domain)
+	if !result.ExpiryDate.IsZero() {
+		if summary.Expiries == nil {
+			summary.Expiries = make(map[string]time.Time)
+		}
+		summary.Expiries[result.Domain] = result.ExpiryDate
+	}
+	return true
+}
+
+// registeredMessage returns the progress line of a registered domain
+func registeredMessage(progress string, result types.DomainResult) string {
+	msg := fmt.Sprintf("%s Domain %s is REGISTERED [%s]", progress, displayName(result), strings.Join(result.Signatures, ", "))
+	if !result.ExpiryDate.IsZero() {
+		msg += " (expires " + result.ExpiryDate.Format(expiryLayout) + ")"
+	}
+	if result.Confidence != "" {
+		msg += " (" + result.Confidence + " confidence)"
+	}
+	return msg
+}
+
+// displayName returns the domain of a result for the progress lines, followed by its
+// Unicode form for internationalized domains, e.g. "xn--br-via.de (bär.de)"
+func displayName(result types.DomainResult) string {
+	if result.Unicode != "" {
+		return result.Domain + " (" + result.Unicode + ")"
+	}
+	return result.Domain
+}
+
+// fileLine returns the line of a domain in the result files, followed by a tab and its
+// Unicode form for internationalized domains and by a tab and its permutation for typo
+// variants
+func fileLine(summary *Summary, name string) string {
+	line := name
+	if unicodeName := summary.Unicode[name]; unicodeName != "" {
+		line += "\t" + unicodeName
+	}
+	if permutation := summary.Permutations[name]; permutation != "" {
+		line += "\t" + permutation
+	}
+	return line
+}
+
+// fileLines returns the result file lines of domains, grouped by permutation for typo
+// variants
+func fileLines(summary *Summary, domains []string) []string {
+	if len(summary.Unicode) == 0 && len(summary.Permutations) == 0 {
+		return domains
+	}
+	domains = groupByPermutation(summary, domains)
+	lines := make([]string, len(domains))
+	for i, name := range domains {
+		lines[i] = fileLine(summary, name)
+	}
+	return lines
+}
+
+// groupByPermutation orders typo variants by their permutation in the order of
+// generator.TypoKinds, keeping the order within each permutation; other domains are
+// returned as they are
+func groupByPermutation(summary *Summary, domains []string) []string {
+	if len(summary.Permutations) == 0 {
+		return domains
+	}
+	rank := make(map[string]int, len(generator.TypoKinds))
+	for i, kind := range generator.TypoKinds {
+		rank[kind] = i
+	}
+	grouped := append([]string(nil), domains...)
+	sort.SliceStable(grouped, func(i, j int) bool {
+		return rank[summary.Permutations[grouped[i]]] < rank[summary.Permutations[grouped[j]]]
+	})
+	return grouped
+}
+
+// registeredLines returns the lines of the registered domains file: the domains, or
+// under opts.ExpiringBefore the domains with their expiration date, soonest first
+func registeredLines(opts Options, summary *Summary) []string {
+	if opts.ExpiringBefore.IsZero() {
+		return fileLines(summary, summary.Registered)
+	}
+	domains := append([]string(nil), summary.Registered...)
+	sort.SliceStable(domains, func(i, j int) bool {
+		return summary.Expiries[domains[i]].Before(summary.Expiries[domains[j]])
+	})
+	lines := make([]string, len(domains))
+	for i, name := range domains {
+		lines[i] = fileLine(summary, name) + " " + summary.Expiries[name].Format(expiryLayout)
+	}
+	return lines
+}
+
+// specialStatusOf returns the special status list entry of a result
+func specialStatusOf(result types.DomainResult) types.SpecialStatusDomain {
+	return types.SpecialStatusDomain{
+		Domain: result.Domain,
+		Status: result.SpecialStatus,
+		Reason: fmt.Sprintf("WHOIS status: %s", result.SpecialStatus),
+	}
+}
+
+// TLDStat holds the result counts of one domain suffix
+type TLDStat struct {
+	Checked    int `json:"checked"`
+	Available  int `json:"available"`
+	Registered int `json:"registered"`
+	Special    int `json:"special"`
+	Errors     int `json:"errors"`
+}
+
+// AvailabilityRate returns the share of checked domains that were available, in percent
+func (t *TLDStat) AvailabilityRate() float64 {
+	if t.Checked == 0 {
+		return 0
+	}
+	return float64(t.Available) * 100 / float64(t.Checked)
+}
+
+// Process exit codes of the scanner command
+const (
+	ExitOK = 0
+	// ExitUsage is returned for invalid flags or configuration, before scanning starts
+	ExitUsage = 1
+	// ExitAborted is returned when the scan stopped before checking every domain
+	ExitAborted = 2
+	// ExitErrors is returned when the scan completed but some checks failed
+	ExitErrors = 3
+)
+
+// ExitCode returns the process exit code for the outcome of a run
+func (s *Summary) ExitCode() int {
+	switch {
+	case s.Interrupted:
+		return ExitAborted
+	case s.Errors > 0:
+		return ExitErrors
+	default:
+		return ExitOK
+	}
+}
+
+// ExitMeaning describes a process exit code
+func ExitMeaning(code int) string {
+	switch code {
+	case ExitOK:
+		return "success"
+	case ExitUsage:
+		return "usage or config error"
+	case ExitAborted:
+		return "scan aborted"
+	case ExitErrors:
+		return "completed with errors"
+	default:
+		return fmt.Sprintf("exit code %d", code)
+	}
+}
+
+// FlagExitCode returns the exit code for an error of flag.FlagSet.Parse: asking for
+// help with -help succeeds, any other error is a usage error
+func FlagExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	return ExitUsage
+}
+
+// OptionsFromConfig builds scan options from a loaded configuration file
+func OptionsFromConfig(cfg *types.Config) Options {
+	var ct *ctlog.Client
+	if cfg.CT.Enabled {
+		ct = ctlog.FromConfig(cfg, nil)
+	}
+	// The config was validated when it was loaded, including the webhook
+	webhook, _ := notify.FromConfig(cfg, nil)
+	// The config lists several suffixes separated by commas
+	var suffixes []string
+	if strings.Contains(cfg.Domain.Suffix, ",") {
+		suffixes = strings.Split(cfg.Domain.Suffix, ",")
+	}
+	return Options{
+		Length:         cfg.Domain.Length,
+		Suffix:         strings.Split(cfg.Domain.Suffix, ",")[0],
+		Suffixes:       suffixes,
+		Pattern:        cfg.Domain.Pattern,
+		Charset:        cfg.Domain.Charset,
+		ExcludeChars:   cfg.Domain.ExcludeChars,
+		NamePrefix:     cfg.Domain.NamePrefix,
+		NameSuffix:     cfg.Domain.NameSuffix,
+		Mask:           cfg.Domain.Mask,
+		LetterPattern:  cfg.Domain.LetterPattern,
+		Template:       cfg.Domain.Template,
+		Syllables:      cfg.Domain.Syllables,
+		RegexFilter:    cfg.Domain.RegexFilter,
+		RegexMode:      types.RegexModeFull,
+		WordLists:      cfg.Domain.WordLists,
+		WordSeparator:  cfg.Domain.WordSeparator,
+		Leet:           cfg.Domain.Leet,
+		LeetTable:      cfg.Domain.LeetTable,
+		NumRange:       cfg.Domain.NumRange,
+		NumPad:         cfg.Domain.NumPad,
+		MaxNumRange:    cfg.Domain.MaxNumRange,
+		InputFile:      cfg.Domain.InputFile,
+		Offset:         cfg.Domain.Offset,
+		Limit:          cfg.Domain.Limit,
+		ExpectedCount:  cfg.Batch.ExpectedCount,
+		Delay:          time.Duration(cfg.Scanner.Delay) * time.Millisecond,
+		Workers:        cfg.Scanner.Workers,
+		ShowRegistered: cfg.Scanner.ShowRegistered,
+		Config:         cfg,
+		Pricer:         pricing.FromConfig(cfg, nil),
+		Sheets:         gsheets.FromConfig(cfg, nil),
+		Webhook:        webhook,
+		CT:             ct,
+		CTVerify:       cfg.CT.Verify,
+
+		RetryRateLimited: cfg.Scanner.RateLimitRetry,
+		RetryDelay:       time.Duration(cfg.Scanner.RateLimitRetryDelay) * time.Millisecond,
+		RetryWorkers:     cfg.Scanner.RateLimitRetryWorkers,
+	}
+}
+
+// pipeline is a running generate → check scan
+type pipeline struct {
+	// results is closed once every dispatched domain has been checked
+	results <-chan types.DomainResult
+	// generationDone is closed when no more domains will be dispatched
+	generationDone <-chan struct{}
+	// generated is the number of dispatched domains, final once generationDone is closed
+	generated *int64
+	// expected is the number of domains the generator will produce when known up front,
+	// i.e. for keyspaces without a regex filter or with ExpectedCount; zero otherwise
+	expected int
+	// blocked is the number of candidates dropped by the blocklist, final once generationDone is closed
+	blocked *int64
+	// excluded is the number of candidates on the exclusion list, final once generationDone is closed
+	excluded *int64
+	// zoned is the number of domains classified from the zone files, final once generationDone is closed
+	zoned *int64
+	// prefiltered is the number of domains classified by the prefilter, final once generationDone is closed
+	prefiltered *int64
+	// resumed is the number of domains already in the SQLite database, final once generationDone is closed
+	resumed *int64
+	// order tracks the unfinished domains of a generated keyspace; nil for supplied domains
+	order *dispatchOrder
+	// space is the size of the keyspace range without a regex filter; zero otherwise
+	space int
+}
+
+// dispatchOrder tracks the dispatched domains whose check has not finished, in
+// generation order, to find where an interrupted scan resumes. A nil dispatchOrder
+// tracks nothing.
+type dispatchOrder struct {
+	mu      sync.Mutex
+	next    int
+	pending map[string]int
+	// last is the most recently dispatched domain
+	last string
+}
+
+func newDispatchOrder() *dispatchOrder {
+	return &dispatchOrder{pending: make(map[string]int)}
+}
+
+// dispatch records a domain about to be handed to the workers
+func (d *dispatchOrder) dispatch(name string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending[name] = d.next
+	d.next++
+	d.last = name
+}
+
+// done records a domain whose check has finished
+func (d *dispatchOrder) done(name string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pending, name)
+}
+
+// resumeFrom returns the name of the earliest domain whose check has not finished, or
+// of the last dispatched domain when all of them have; empty when nothing was dispatched
+func (d *dispatchOrder) resumeFrom() string {
+	if d == nil {
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	resume, first := d.last, -1
+	for name, seq := range d.pending {
+		if first < 0 || seq < first {
+			resume, first = name, seq
+		}
+	}
+	if idx := strings.Index(resume, "."); idx >= 0 {
+		resume = resume[:idx]
+	}
+	return resume
+}
+
+// candidate is a domain to check, or one a pre-check already classified registered
+type candidate struct {
+	name string
+	// registeredBy is the signature of the pre-check that found the domain registered
+	registeredBy string
+}
+
+// precheck classifies the domains with the zone files and then the prefilter, either
+// of which may be nil. Cancelling ctx stops it.
+func precheck(ctx context.Context, domains <-chan string, zone *zonefile.Zone, prefilter *rawdns.Prefilter) (<-chan candidate, error) {
+	out := make(chan candidate, 1000)
+	emit := func(c candidate) bool {
+		select {
+		case out <- c:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var unknown chan string
+	var verdicts <-chan rawdns.Verdict
+	if prefilter != nil {
+		unknown = make(chan string, 1000)
+		var err error
+		if verdicts, err = prefilter.Run(ctx, unknown); err != nil {
+			return nil, err
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if unknown != nil {
+			defer close(unknown)
+		}
+		for name := range domains {
+			if zone != nil && zone.Contains(ctx, name) {
+				if !emit(candidate{name: name, registeredBy: zonefile.Signature}) {
+					return
+				}
+				continue
+			}
+			if unknown == nil {
+				if !emit(candidate{name: name}) {
+					return
+				}
+				continue
+			}
+			select {
+			case unknown <- name:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	if verdicts != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range verdicts {
+				c := candidate{name: v.Name}
+				if v.Registered {
+					c.registeredBy = rawdns.Signature
+				}
+				if !emit(c) {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out, nil
+}
+
+// Stream starts a scan and returns its results as they arrive. The channel is closed
+// when every dispatched domain has been checked and all workers have returned;
+// cancelling ctx stops dispatching. The caller must drain the channel until it is
+// closed, otherwise the workers block on their sends. No result files are written
+// and nothing is summarized.
+func Stream(ctx context.Context, opts Options) (<-chan types.DomainResult, error) {
+	opts = normalize(opts)
+	p, err := start(ctx, opts, nil, func(string, ...interface{}) {})
+	if err != nil {
+		return nil, err
+	}
+	return p.results, nil
+}
+
+// Generate returns the domains a scan with these options would check, without
+// checking them, together with the size of the keyspace they are drawn from.
+// Cancelling ctx stops the generation and closes the channel.
+func Generate(ctx context.Context, opts Options) (<-chan string, int, error) {
+	opts, err := startFrom(normalize(opts))
+	if err != nil {
+		return nil, 0, err
+	}
+	return source(ctx, opts, new(int64), new(int64), func(string, ...interface{}) {})
+}
+
+// Count returns the number of domains a scan with these options would check when it is
+// known without generating them: for a generated keyspace that no regex filter,
+// exclusion list or dropping blocklist thins out, and whose names skipped for their
+// hyphens are counted in closed form. ok is false otherwise, and the domains of Generate
+// have to be counted instead. A keyspace too large for an int counts as
+// generator.MaxCount, which means more domains than that.
+func Count(ctx context.Context, opts Options) (count int, ok bool, err error) {
+	opts, err = startFrom(normalize(opts))
+	if err != nil {
+		return 0, false, err
+	}
+	if !opts.generatedKeyspace() || opts.RegexFilter != "" || opts.ExcludeFile != "" ||
+		opts.Blocklist != nil && opts.BlocklistMode != types.BlocklistFlag {
+		return 0, false, nil
+	}
+	// Only the keyspace size of the names is needed; the generator of a cancelled
+	// context stops at once
+	single := opts
+	single.Suffixes = nil
+	genCtx, stop := context.WithCancel(ctx)
+	stop()
+	_, keyspace, err := candidates(genCtx, single, func(string, ...interface{}) {})
+	if err != nil {
+		return 0, false, err
+	}
+	if keyspace == generator.MaxCount && opts.Limit == 0 {
+		// Too many to count; the figure is capped
+		return generator.MaxCount, true, nil
+	}
+	names, ok := generatedNames(single)
+	if !ok {
+		return 0, false, nil
+	}
+	end := keyspace
+	if opts.Limit > 0 && opts.Offset+opts.Limit < end {
+		end = opts.Offset + opts.Limit
+	}
+	visited := opts.order().Remaining(opts.Offset, end)
+	count = visited
+	if names != keyspace {
+		// The skipped names are spread over the keyspace, so only the count of all of it
+		// is known; part of it has at least the names that are not skipped anywhere
+		count = names
+		if visited != keyspace {
+			count = visited - (keyspace - names)
+			if opts.Sample <= 0 || count*len(opts.suffixes()) < opts.Sample {
+				return 0, false, nil
+			}
+		}
+	}
+	count = generator.MulCounts(count, len(opts.suffixes()))
+	if opts.Sample > 0 && opts.Sample < count {
+		count = opts.Sample
+	}
+	return count, true, nil
+}
+
+// generatedNames returns the number of names the generator of a scan produces from its
+// whole keyspace without a regex filter, which is smaller than the keyspace where names
+// with a leading, trailing or doubled hyphen are skipped. The bool is false when only
+// generating the names counts them: for Unicode charsets, whose invalid labels are
+// found by converting them, and for hyphen charsets between fixed affixes.
+func generatedNames(opts Options) (int, bool) {
+	switch {
+	case opts.NumRange != "":
+		r, err := opts.numRange()
+		return r.Count(), err == nil
+	case opts.Template != "":
+		mask, err := opts.mask()
+		return mask.NamesCount(), err == nil
+	case opts.Pattern == generator.PatternPronounceable:
+		return generator.CalculateDomainsCount(opts.Syllables, generator.PatternPronounceable), true
+	case generator.IsUnicodeCharset(opts.Charset):
+		return 0, false
+	case opts.Mask != "":
+		mask, err := opts.mask()
+		return mask.NamesCount(), err == nil
+	case opts.LetterPattern != "":
+		// Letter patterns take no hyphens
+		p, err := generator.ParseLetterPattern(opts.LetterPattern, opts.pattern())
+		return p.Count(), err == nil
+	}
+	lengths, pattern := opts.lengths(), opts.pattern()
+	if !generator.HyphenCharset(pattern) {
+		return generator.CalculateLengthsCount(lengths, pattern), true
+	}
+	// A capped count tells nothing about the names skipped
+	names := generator.CalculateLengthsNamesCount(lengths, pattern)
+	if !opts.affixes().IsZero() || names == generator.MaxCount {
+		return 0, false
+	}
+	return names, true
+}
+
+// startFrom moves the keyspace range of a scan to begin at its StartFrom name,
+// shrinking the limit by the skipped names. A shuffled scan keeps its range, whose
+// permutation the resumed order skips into instead.
+func startFrom(opts Options) (Options, error) {
+	if opts.Sample > 0 && !opts.generatedKeyspace() {
+		return opts, fmt.Errorf("sampling requires a generated keyspace, not supplied domains, a name list, word lists, keyword combinations or leetspeak variants")
+	}
+	if opts.Shuffle && !opts.generatedKeyspace() {
+		return opts, fmt.Errorf("shuffling requires a generated keyspace, not supplied domains, a name list, word lists, keyword combinations or leetspeak variants")
+	}
+	if opts.StartFrom == "" {
+		return opts, nil
+	}
+	if opts.Sample > 0 {
+		return opts, fmt.Errorf("a start position cannot resume a sample; run the sample again with its seed")
+	}
+	if !opts.generatedKeyspace() {
+		return opts, fmt.Errorf("a start position requires a generated keyspace, not supplied domains, a name list, word lists, keyword combinations or leetspeak variants")
+	}
+	name := strings.ToLower(strings.TrimSpace(opts.StartFrom))
+	counter, ok := opts.counter()(name)
+	if !ok || strings.Contains(name, ".") {
+		return opts, fmt.Errorf("invalid start position %q: the name is not generated by this keyspace (check the pattern, charset and length)", opts.StartFrom)
+	}
+	if counter < opts.Offset || opts.Limit > 0 && counter >= opts.Offset+opts.Limit {
+		return opts, fmt.Errorf("invalid start position %q: keyspace position %d is outside the range [%d, %d)",
+			opts.StartFrom, counter, opts.Offset, opts.Offset+opts.Limit)
+	}
+	if opts.Shuffle {
+		opts.ExpectedCount = nil
+		return opts, nil
+	}
+	if opts.Limit > 0 {
+		opts.Limit -= counter - opts.Offset
+	}
+	// A batch expectation counts the whole range, not the part left
+	opts.Offset, opts.ExpectedCount = counter, nil
+	return opts, nil
+}
+
+// order returns the order in which a generated scan visits its keyspace range
+func (opts Options) order() generator.Order {
+	if !opts.Shuffle {
+		return generator.Order{}
+	}
+	order := generator.ShuffledOrder(opts.Seed)
+	if opts.Sample > 0 {
+		order = order.Capped(opts.Sample * sampleAttempts)
+	}
+	if opts.StartFrom != "" {
+		counter, _ := opts.counter()(strings.ToLower(strings.TrimSpace(opts.StartFrom)))
+		order = order.StartingAt(counter)
+	}
+	return order
+}
+
+// generatedKeyspace reports whether a scan generates a keyspace whose names have a
+// counter value, as opposed to supplied domains, name lists, word lists and leetspeak
+// variants
+func (opts Options) generatedKeyspace() bool {
+	return opts.Domains == nil && opts.InputFile == "" && len(opts.WordLists) == 0 && opts.KeywordFile == "" && opts.Leet == ""
+}
+
+// normalize fills the defaults every scan relies on
+func normalize(opts Options) Options {
+	// Invalid suffixes are kept as they are and rejected by candidates
+	if opts.Suffix == "" && len(opts.Suffixes) > 0 {
+		opts.Suffix = opts.Suffixes[0]
+	}
+	if suffix, err := generator.NormalizeSuffix(opts.Suffix); err == nil {
+		opts.Suffix = suffix
+	}
+	// Invalid charsets are likewise rejected by candidates
+	if charset, err := generator.NormalizeCharset(opts.Charset); opts.Charset != "" && err == nil {
+		opts.Charset = charset
+	} else if charset, err := generator.NormalizeUnicodeCharset(opts.Charset); generator.IsUnicodeCharset(opts.Charset) && err == nil {
+		opts.Charset = charset
+	}
+	if excluded, err := generator.NormalizeCharset(opts.ExcludeChars); opts.ExcludeChars != "" && err == nil {
+		opts.ExcludeChars = excluded
+	}
+	if prefix, err := generator.NormalizeAffix(opts.NamePrefix); err == nil {
+		opts.NamePrefix = prefix
+	}
+	if suffix, err := generator.NormalizeAffix(opts.NameSuffix); err == nil {
+		opts.NameSuffix = suffix
+	}
+	if opts.Pattern == generator.PatternPronounceable && opts.Syllables == 0 {
+		opts.Syllables = generator.DefaultSyllables
+	}
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.Sample > 0 {
+		opts.Shuffle = true
+	}
+	for opts.Shuffle && opts.Seed == 0 {
+		opts.Seed = rand.Int63()
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.Nop{}
+	}
+	if opts.CT != nil {
+		opts.Checker = ctChecker(opts.CT, opts.CTVerify, opts.Checker)
+	}
+	if len(opts.DropDates) > 0 {
+		opts.Checker = dropDateChecker(opts.DropDates, opts.Checker)
+	}
+	if len(opts.Permutations) > 0 {
+		opts.Checker = permutationChecker(opts.Permutations, opts.Checker)
+	}
+	if opts.Blocklist != nil && opts.BlocklistMode == types.BlocklistFlag {
+		opts.Checker = flagChecker(opts.Blocklist, opts.Checker)
+	}
+	if opts.Gate != nil {
+		opts.Checker = gateChecker(opts.Gate, opts.Checker)
+	}
+	return opts
+}
+
+// dropDateChecker wraps a checker so that results carry the drop date of their domain
+func dropDateChecker(dates map[string]string, check worker.CheckFunc) worker.CheckFunc {
+	if check == nil {
+		check = worker.Check
+	}
+	return func(ctx context.Context, name string) types.DomainResult {
+		result := check(ctx, name)
+		result.DropDate = dates[name]
+		return result
+	}
+}
+
+// permutationChecker wraps a checker so that results carry the typo permutation of their domain
+func permutationChecker(permutations map[string]string, check worker.CheckFunc) worker.CheckFunc {
+	if check == nil {
+		check = worker.Check
+	}
+	return func(ctx context.Context, name string) types.DomainResult {
+		result := check(ctx, name)
+		result.Permutation = permutations[name]
+		return result
+	}
+}
+
+// ctChecker wraps a checker with the Certificate Transparency pre-check. CT only shows
+// that a name was registered at some point, so verify still runs the checker for hits.
+func ctChecker(client *ctlog.Client, verify bool, check worker.CheckFunc) worker.CheckFunc {
+	if check == nil {
+		check = worker.Check
+	}
+	return func(ctx context.Context, name string) types.DomainResult {
+		issued, err := client.Issued(ctx, name)
+		if err != nil || !issued {
+			return check(ctx, name)
+		}
+		if !verify {
+			return types.DomainResult{Domain: name, Signatures: []string{ctlog.Signature}, Confidence: types.ConfidenceMedium}
+		}
+		result := check(ctx, name)
+		result.Signatures = append(result.Signatures, ctlog.Signature)
+		return result
+	}
+}
+
+// flagChecker wraps a checker so that results of domains matching the blocklist carry
+// the TrademarkRisk signature
+func flagChecker(list *generator.Blocklist, check worker.CheckFunc) worker.CheckFunc {
+	if check == nil {
+		check = worker.Check
+	}
+	return func(ctx context.Context, name string) types.DomainResult {
+		result := check(ctx, name)
+		if _, ok := list.Match(name); ok && !trademarkRisk(result) {
+			result.Signatures = append(result.Signatures, types.TrademarkRisk)
+		}
+		return result
+	}
+}
+
+// trademarkRisk reports whether a result was flagged by the blocklist
+func trademarkRisk(result types.DomainResult) bool {
+	for _, signature := range result.Signatures {
+		if signature == types.TrademarkRisk {
+			return true
+		}
+	}
+	return false
+}
+
+// source returns the domains to check: the explicit Domains channel, word list
+// combinations or the length/pattern keyspace, together with the keyspace size.
+// Candidates on the exclusion list are removed and counted in excluded, in drop mode
+// those matching the blocklist likewise in blocked.
+func source(ctx context.Context, opts Options, blocked, excluded *int64, printf func(string, ...interface{})) (<-chan string, int, error) {
+	var exclude *generator.ExcludeList
+	if opts.ExcludeFile != "" {
+		var err error
+		if exclude, err = generator.LoadExcludeList(opts.ExcludeFile); err != nil {
+			return nil, 0, err
+		}
+	}
+	domains, total, err := sampled(ctx, opts, printf)
+	if err != nil {
+		return nil, 0, err
+	}
+	if exclude != nil {
+		printf("Skipping candidates that match %d entries of %s\n", exclude.Len(), opts.ExcludeFile)
+		domains = exclude.Filter(ctx, domains, excluded)
+	}
+	if opts.Blocklist == nil {
+		return domains, total, nil
+	}
+	if opts.BlocklistMode == types.BlocklistFlag {
+		printf("Flagging candidates that match %d blocklist entries as %s\n", opts.Blocklist.Len(), types.TrademarkRisk)
+		return domains, total, nil
+	}
+	printf("Dropping candidates that match %d blocklist entries\n", opts.Blocklist.Len())
+	return opts.Blocklist.Filter(ctx, domains, blocked), total, nil
+}
+
+// sampleAttempts is the number of candidates a sampled scan tries per name of the
+// sample before it ends short, which bounds the rejection sampling of a regex filter
+const sampleAttempts = 1000
+
+// sampled returns the candidates of a scan, only the first Sample ones of a sampled
+// scan; the generator is stopped once the sample is complete
+func sampled(ctx context.Context, opts Options, printf func(string, ...interface{})) (<-chan string, int, error) {
+	if opts.Sample <= 0 {
+		return candidates(ctx, opts, printf)
+	}
+	genCtx, stop := context.WithCancel(ctx)
+	domains, total, err := candidates(genCtx, opts, printf)
+	if err != nil {
+		stop()
+		return nil, 0, err
+	}
+	sample := make(chan string, 1000)
+	go func() {
+		defer close(sample)
+		defer stop()
+		taken := 0
+		for domainName := range domains {
+			select {
+			case sample <- domainName:
+			case <-ctx.Done():
+				return
+			}
+			if taken++; taken == opts.Sample {
+				return
+			}
+		}
+		if opts.RegexFilter != "" && ctx.Err() == nil {
+			printf("Warning: sampled only %d of %d domains; the regex filter rejected the other candidates tried (at most %d)\n",
+				taken, opts.Sample, opts.Sample*sampleAttempts)
+		}
+	}()
+	return sample, total, nil
+}
+
+// candidates returns the unfiltered domains to check and the keyspace size
+func candidates(ctx context.Context, opts Options, printf func(string, ...interface{})) (<-chan string, int, error) {
+	if opts.Domains != nil {
+		printf("Checking supplied domains using %d workers...\n", opts.Workers)
+		return opts.Domains, 0, nil
+	}
+	if len(opts.Suffixes) > 1 {
+		return suffixCandidates(ctx, opts, printf)
+	}
+	if err := generator.ValidateSuffix(opts.Suffix); err != nil {
+		return nil, 0, err
+	}
+	if err := generator.ValidateFilter(opts.RegexFilter); err != nil {
+		return nil, 0, err
+	}
+	if opts.InputFile != "" {
+		count, err := generator.CountNames(opts.InputFile, opts.Suffix)
+		if err != nil {
+			return nil, 0, err
+		}
+		printf("Checking %d names from %s using %d workers...\n", count, opts.InputFile, opts.Workers)
+		domains, err := generator.GenerateFromFile(ctx, opts.InputFile, opts.Suffix, opts.RegexFilter, opts.RegexMode, opts.Offset, opts.Limit)
+		return domains, count, err
+	}
+	if len(opts.WordLists) > 0 {
+		lists, err := generator.LoadWordLists(opts.WordLists)
+		if err != nil {
+			return nil, 0, fmt.Errorf("loading word lists: %w", err)
+		}
+		if err := generator.ValidateWordSeparator(opts.WordSeparator); err != nil {
+			return nil, 0, err
+		}
+		total := generator.CalculateCombinationsCount(lists)
+		printf("Checking combinations of %d word lists using %d workers...\n", len(lists), opts.Workers)
+		printf("Word lists of %s words give %d combinations\n", formatListSizes(lists), total)
+		domains, err := generator.GenerateCombinations(ctx, lists, opts.WordSeparator, opts.Suffix, opts.RegexFilter, opts.RegexMode, opts.Offset, opts.Limit)
+		return domains, total, err
+	}
+	if opts.KeywordFile != "" {
+		return keywordCandidates(ctx, opts, printf)
+	}
+	if opts.Leet != "" {
+		return leetCandidates(ctx, opts, printf)
+	}
+	if opts.NumRange != "" {
+		return numRangeCandidates(ctx, opts, printf)
+	}
+	if opts.Template != "" {
+		return maskCandidates(ctx, opts, printf)
+	}
+	if opts.Pattern == generator.PatternPronounceable {
+		return pronounceableCandidates(ctx, opts, printf)
+	}
+	if generator.IsUnicodeCharset(opts.Charset) {
+		return idnCandidates(ctx, opts, printf)
+	}
+	if opts.Charset != "" {
+		if _, err := generator.NormalizeCharset(opts.Charset); err != nil {
+			return nil, 0, err
+		}
+	} else if err := generator.ValidatePattern(opts.Pattern); err != nil {
+		return nil, 0, err
+	}
+	if opts.ExcludeChars != "" {
+		pattern, err := generator.ExcludeChars(opts.basePattern(), opts.ExcludeChars)
+		if err != nil {
+			return nil, 0, err
+		}
+		printf("Excluding characters %q: generating from %s\n", opts.ExcludeChars, strings.Trim(pattern, "[]"))
+	}
+	if opts.Mask != "" {
+		return maskCandidates(ctx, opts, printf)
+	}
+	if opts.LetterPattern != "" {
+		return letterPatternCandidates(ctx, opts, printf)
+	}
+	lengths, pattern, affixes := opts.lengths(), opts.pattern(), opts.affixes()
+	if err := affixes.Validate(lengths); err != nil {
+		return nil, 0, err
+	}
+	keyspace := generator.CalculateLengthsCount(lengths, pattern)
+	if keyspace == generator.MaxCount && opts.Shuffle && opts.Limit == 0 {
+		return nil, 0, fmt.Errorf("shuffling and sampling need a keyspace of at most %d names, and pattern %s of length %s has more",
+			generator.MaxCount, opts.basePattern(), generator.FormatLengths(lengths))
+	}
+	printf("Checking domains with pattern %s and length %s using %d workers...\n",
+		affixes.Label(opts.basePattern()), generator.FormatLengths(lengths), opts.Workers)
+	if keyspace == generator.MaxCount {
+		printf("The keyspace holds more than %d names; they are generated in order without a total\n", generator.MaxCount)
+	}
+	if !affixes.IsZero() {
+		printf("Generating %s characters between the fixed name prefix %q and suffix %q\n",
+			generator.FormatLengths(lengths), affixes.Prefix, affixes.Suffix)
+	}
+	// Hyphen charsets skip part of their keyspace; the exact count ignores the affixes
+	names := generator.CalculateLengthsNamesCount(lengths, pattern)
+	if names < keyspace && affixes.IsZero() {
+		printf("Skipping names with a leading, trailing or doubled hyphen: %d of %d keyspace names are generated\n",
+			names, keyspace)
+	}
+	domains, err := generator.GenerateAffixedLengths(ctx, lengths, affixes, opts.Suffix, pattern, opts.RegexFilter, opts.RegexMode, opts.Offset, opts.Limit, opts.order())
+	return domains, keyspace, err
+}
+
+// maskCandidates generates the names of the mask or template of a scan, enumerating
+// only its wildcard positions
+func maskCandidates(ctx context.Context, opts Options, printf func(string, ...interface{})) (<-chan string, int, error) {
+	if !opts.affixes().IsZero() {
+		return nil, 0, fmt.Errorf("a mask or template cannot be combined with a name prefix or suffix")
+	}
+	if opts.Mask != "" && opts.Template != "" {
+		return nil, 0, fmt.Errorf("a mask cannot be combined with a template")
+	}
+	if opts.LetterPattern != "" {
+		return nil, 0, fmt.Errorf("a letter pattern cannot be combined with a mask or template")
+	}
+	if opts.Template != "" && opts.ExcludeChars != "" {
+		return nil, 0, fmt.Errorf("a template cannot be combined with excluded characters")
+	}
+	mask, err := opts.mask()
+	if err != nil {
+		return nil, 0, err
+	}
+	kind := "mask"
+	if opts.Template != "" {
+		kind = "template"
+	}
+	printf("Checking domains with %s %s using %d workers...\n", kind, mask, opts.Workers)
+	printf("The %s %s generates %d names of %d characters (keyspace %d)\n", kind, mask, mask.NamesCount(), mask.Len(), mask.Count())
+	domains, err := generator.GenerateMask(ctx, mask, opts.Suffix, opts.RegexFilter, opts.RegexMode, opts.Offset, opts.Limit, opts.order())
+	return domains, mask.Count(), err
+}
+
+// letterPatternCandidates generates the names of the letter pattern of a scan,
+// enumerating only its distinct letters
+func letterPatternCandidates(ctx context.Context, opts Options, printf func(string, ...interface{})) (<-chan string, int, error) {
+	if !opts.affixes().IsZero() {
+		return nil, 0, fmt.Errorf("a letter pattern cannot be combined with a name prefix or suffix")
+	}
+	p, err := generator.ParseLetterPattern(opts.LetterPattern, opts.pattern())
+	if err != nil {
+		return nil, 0, err
+	}
+	printf("Checking domains with letter pattern %s using %d workers...\n", p, opts.Workers)
+	printf("The letter pattern %s generates %d names of %d characters\n", p, p.Count(), p.Len())
+	domains, err := generator.GenerateLetterPattern(ctx, p, opts.Suffix, opts.RegexFilter, opts.RegexMode, opts.Offset, opts.Limit, opts.order())
+	return domains, p.Count(), err
+}
+
+// numRangeCandidates generates the integers of the numeric range of a scan as names
+func numRangeCandidates(ctx context.Context, opts Options, printf func(string, ...interface{})) (<-chan string, int, error) {
+	if opts.Mask != "" || opts.LetterPattern != "" || opts.Template != "" || opts.Charset != "" || opts.ExcludeChars != "" || !opts.affixes().IsZero() {
+		return nil, 0, fmt.Errorf("a numeric range cannot be combined with a mask, letter pattern, template, charset, excluded characters or name prefix or suffix")
+	}
+	r, err := opts.numRange()
+	if err != nil {
+		return nil, 0, err
+	}
+	printf("Checking domains with numbers %s using %d workers...\n", r, opts.Workers)
+	domains, err := generator.GenerateNumRange(ctx, r, opts.Suffix, opts.RegexFilter, opts.RegexMode, opts.Offset, opts.Limit, opts.order())
+	return domains, r.Count(), err
+}
+
+// idnCandidates generates the internationalized names of a Unicode charset in their
+// ASCII form
+func idnCandidates(ctx context.Context, opts Options, printf func(string, ...interface{})) (<-chan string, int, error) {
+	if opts.Mask != "" || opts.LetterPattern != "" || opts.ExcludeChars != "" || !opts.affixes().IsZero() {
+		return nil, 0, fmt.Errorf("a Unicode charset cannot be combined with a mask, letter pattern, excluded characters or name prefix or suffix")
+	}
+	charset, err := generator.NormalizeUnicodeCharset(opts.Charset)
+	if err != nil {
+		return nil, 0, err
+	}
+	lengths := opts.lengths()
+	total, err := generator.CalculateIDNCount(charset, lengths)
+	if err != nil {
+		return nil, 0, err
+	}
+	printf("Checking internationalized domains of %d characters %q and length %s using %d workers...\n",
+		len([]rune(charset)), charset, generator.FormatLengths(lengths), opts.Workers)
+	printf("Names are checked in their punycode form; invalid labels and labels over 63 bytes in that form are skipped\n")
+	domains, err := generator.GenerateIDN(ctx, charset, lengths, opts.Suffix, opts.RegexFilter, opts.RegexMode, opts.Offset, opts.Limit, opts.order())
+	return domains, total, err
+}
+
+// keywordCandidates generates the combinations of the keywords and affixes of a scan
+func keywordCandidates(ctx context.Context, opts Options, printf func(string, ...interface{})) (<-chan string, int, error) {
+	if opts.AffixFile == "" {
+		return nil, 0, fmt.Errorf("keyword combinations require an affix list")
+	}
+	keywords, err := generator.LoadWordList(opts.KeywordFile)
+	if err != nil {
+		return nil, 0, fmt.Errorf("loading keywords: %w", err)
+	}
+	affixes, err := generator.LoadWordList(opts.AffixFile)
+	if err != nil {
+		return nil, 0, fmt.Errorf("loading affixes: %w", err)
+	}
+	names, err := generator.KeywordNames(keywords, affixes, opts.affixPosition(), opts.KeywordAlone)
+	if err != nil {
+		return nil, 0, err
+	}
+	printf("Checking %d keywords with %d affixes (%s) using %d workers...\n", len(keywords), len(affixes), opts.affixPosition(), opts.Workers)
+	printf("The keywords and affixes give %d distinct names\n", len(names))
+	domains, err := generator.GenerateList(ctx, names, opts.Suffix, opts.RegexFilter, opts.RegexMode, opts.Offset, opts.Limit)
+	return domains, len(names), err
+}
+
+// affixPosition returns the affix position of keyword combinations, both by default
+func (opts Options) affixPosition() string {
+	if opts.AffixPosition == "" {
+		return generator.AffixBoth
+	}
+	return opts.AffixPosition
+}
+
+// leetCandidates generates the leetspeak variants of the seed word of a scan
+func leetCandidates(ctx context.Context, opts Options, printf func(string, ...interface{})) (<-chan string, int, error) {
+	word, err := generator.NormalizeLeetWord(opts.Leet)
+	if err != nil {
+		return nil, 0, err
+	}
+	table, err := generator.NewLeetTable(opts.leetTable())
+	if err != nil {
+		return nil, 0, err
+	}
+	variants, err := generator.LeetVariants(word, table)
+	if err != nil {
+		return nil, 0, err
+	}
+	printf("Checking leetspeak variants of %q using %d workers...\n", word, opts.Workers)
+	printf("The word %s has %d variants including itself\n", word, len(variants))
+	domains, err := generator.GenerateLeet(ctx, variants, opts.Suffix, opts.RegexFilter, opts.RegexMode, opts.Offset, opts.Limit)
+	return domains, len(variants), err
+}
+
+// leetTable returns the leetspeak substitutions of a scan
+func (opts Options) leetTable() map[string]string {
+	if len(opts.LeetTable) > 0 {
+		return opts.LeetTable
+	}
+	return generator.DefaultLeetTable
+}
+
+// formatListSizes renders the sizes of word lists as a product, e.g. "12 x 30"
+func formatListSizes(lists [][]string) string {
+	sizes := make([]string, len(lists))
+	for i, words := range lists {
+		sizes[i] = fmt.Sprint(len(words))
+	}
+	return strings.Join(sizes, " x ")
+}
+
+// pronounceableCandidates generates the names of a scan from syllables instead of
+// arbitrary characters
+func pronounceableCandidates(ctx context.Context, opts Options, printf func(string, ...interface{})) (<-chan string, int, error) {
+	if opts.Mask != "" || opts.LetterPattern != "" || opts.Charset != "" || opts.ExcludeChars != "" || !opts.affixes().IsZero() {
+		return nil, 0, fmt.Errorf("pronounceable names cannot be combined with a mask, letter pattern, charset, excluded characters or name prefix or suffix")
+	}
+	if err := generator.ValidateSyllables(opts.Syllables); err != nil {
+		return nil, 0, err
+	}
+	total := generator.CalculateDomainsCount(opts.Syllables, generator.PatternPronounceable)
+	printf("Checking pronounceable domains of %d syllable(s) using %d workers...\n", opts.Syllables, opts.Workers)
+	printf("Pronounceable names of %d syllable(s): %d\n", opts.Syllables, total)
+	domains, err := generator.GeneratePronounceable(ctx, opts.Syllables, opts.Suffix, opts.RegexFilter, opts.RegexMode, opts.Offset, opts.Limit, opts.order())
+	return domains, total, err
+}
+
+// suffixCandidates generates the names of a scan once and checks each under all of its
+// suffixes. A full-mode regex filter depends on the suffix and is applied to every
+// expanded domain instead of the generated names.
+func suffixCandidates(ctx context.Context, opts Options, printf func(string, ...interface{})) (<-chan string, int, error) {
+	for _, suffix := range opts.Suffixes {
+		if err := generator.ValidateSuffix(suffix); err != nil {
+			return nil, 0, err
+		}
+	}
+	single := opts
+	single.Suffixes = nil
+	filter := ""
+	if opts.RegexMode == types.RegexModeFull {
+		single.RegexFilter, filter = "", opts.RegexFilter
+	}
+	if err := generator.ValidateFilter(filter); err != nil {
+		return nil, 0, err
+	}
+	names, total, err := candidates(ctx, single, printf)
+	if err != nil {
+		return nil, 0, err
+	}
+	printf("Checking every name under %d suffixes: %s\n", len(opts.Suffixes), strings.Join(opts.Suffixes, ", "))
+	domains, err := generator.ExpandSuffixes(ctx, names, opts.Suffix, opts.Suffixes, filter, opts.RegexMode)
+	return domains, generator.MulCounts(total, len(opts.Suffixes)), err
+}
+
+// lengths returns the domain lengths a generated scan covers
+func (opts Options) lengths() []int {
+	if len(opts.Lengths) > 0 {
+		return opts.Lengths
+	}
+	return []int{opts.Length}
+}
+
+// suffixes returns the suffixes a scan checks its names under
+func (opts Options) suffixes() []string {
+	if len(opts.Suffixes) > 1 {
+		return opts.Suffixes
+	}
+	return []string{opts.Suffix}
+}
+
+// wildcardAPolicy returns the wildcard A-record policy of a suffix of the scan
+func (opts Options) wildcardAPolicy(suffix string) string {
+	if opts.DomainChecker != nil {
+		return opts.DomainChecker.WildcardAPolicy(suffix)
+	}
+	return domain.WildcardAPolicy(suffix)
+}
+
+// pattern returns the generator pattern of a scan: the custom charset if one is set,
+// without the excluded characters
+func (opts Options) pattern() string {
+	pattern := opts.basePattern()
+	if reduced, err := generator.ExcludeChars(pattern, opts.ExcludeChars); err == nil {
+		return reduced
+	}
+	return pattern
+}
+
+// basePattern returns the generator pattern of a scan before excluding characters
+func (opts Options) basePattern() string {
+	if opts.Charset != "" {
+		return generator.CharsetPattern(opts.Charset)
+	}
+	return opts.Pattern
+}
+
+// affixes returns the fixed name prefix and suffix of a generated scan
+func (opts Options) affixes() generator.Affixes {
+	return generator.Affixes{Prefix: opts.NamePrefix, Suffix: opts.NameSuffix}
+}
+
+// mask returns the parsed template or mask of a scan
+func (opts Options) mask() (generator.Mask, error) {
+	if opts.Template != "" {
+		return generator.ParseTemplate(opts.Template)
+	}
+	return generator.ParseMask(opts.Mask, opts.pattern())
+}
+
+// numRange returns the parsed numeric range of a scan
+func (opts Options) numRange() (generator.NumRange, error) {
+	return generator.ParseNumRange(opts.NumRange, opts.NumPad, opts.MaxNumRange)
+}
+
+// counter returns the inverse of the generator of a scan: the keyspace counter of a
+// domain, from its mask or else without the fixed affixes of its name
+func (opts Options) counter() func(string) (int, bool) {
+	if opts.Mask != "" || opts.Template != "" {
+		mask, err := opts.mask()
+		if err != nil {
+			return func(string) (int, bool) { return 0, false }
+		}
+		return mask.CounterOf
+	}
+	if opts.LetterPattern != "" {
+		p, err := generator.ParseLetterPattern(opts.LetterPattern, opts.pattern())
+		if err != nil {
+			return func(string) (int, bool) { return 0, false }
+		}
+		return p.CounterOf
+	}
+	if opts.NumRange != "" {
+		r, err := opts.numRange()
+		if err != nil {
+			return func(string) (int, bool) { return 0, false }
+		}
+		return r.CounterOf
+	}
+	if opts.Pattern == generator.PatternPronounceable {
+		syllables := opts.Syllables
+		return func(domainName string) (int, bool) {
+			return generator.PronounceableCounterOf(domainName, syllables)
+		}
+	}
+	if generator.IsUnicodeCharset(opts.Charset) {
+		charset, lengths := opts.Charset, opts.lengths()
+		return func(domainName string) (int, bool) {
+			return generator.IDNCounterOf(domainName, charset, lengths)
+		}
+	}
+	affixes, pattern, lengths := opts.affixes(), opts.pattern(), opts.lengths()
+	return func(domainName string) (int, bool) {
+		domainName, ok := affixes.Strip(domainName)
+		if !ok {
+			return 0, false
+		}
+		return generator.CounterOfLengths(domainName, pattern, lengths)
+	}
+}
+
+// loadZone loads the zone files of a scan, keeping only the labels the scan can
+// generate when the keyspace is known; nil without zone files
+func loadZone(opts Options, printf func(string, ...interface{})) (*zonefile.Zone, error) {
+	if len(opts.ZoneFiles) == 0 {
+		return nil, nil
+	}
+	var keep func(string) bool
+	if opts.Domains == nil && opts.InputFile == "" && len(opts.WordLists) == 0 && opts.KeywordFile == "" && opts.Leet == "" {
+		counter := opts.counter()
+		keep = func(label string) bool {
+			_, ok := counter(label)
+			return ok
+		}
+	}
+	started := time.Now()
+	zone, err := zonefile.Load(opts.ZoneFiles, opts.Suffix, keep, opts.ZoneFalsePositiveRate)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ZoneFalsePositiveRate > 0 {
+		printf("Loaded %d zone files into a bloom filter (false-positive rate %g) in %s\n",
+			len(opts.ZoneFiles), opts.ZoneFalsePositiveRate, time.Since(started).Round(time.Millisecond))
+	} else {
+		printf("Loaded %d delegated %s names from %d zone files in %s\n",
+			zone.Len(), opts.Suffix, len(opts.ZoneFiles), time.Since(started).Round(time.Millisecond))
+	}
+	return zone, nil
+}
+
+// start launches the generator, the feeder and the worker pool of a scan; with a
+// database, the domains it already has are skipped when [output] sqlite_resume is set
+func start(ctx context.Context, opts Options, db *sqlite.Writer, printf func(string, ...interface{})) (*pipeline, error) {
+	opts, err := startFrom(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Sample > 0 {
+		printf("Sampling %d random domains with seed %d\n", opts.Sample, opts.Seed)
+	} else if opts.Shuffle {
+		printf("Shuffling the generation order with seed %d\n", opts.Seed)
+	}
+	if opts.StartFrom != "" && opts.Shuffle {
+		printf("Starting from %s in the shuffled order\n", strings.ToLower(strings.TrimSpace(opts.StartFrom)))
+	} else if opts.StartFrom != "" {
+		printf("Starting from %s at keyspace position %d\n", strings.ToLower(strings.TrimSpace(opts.StartFrom)), opts.Offset)
+	}
+	blocked, excluded := new(int64), new(int64)
+	domainChan, baseDomainCount, err := source(ctx, opts, blocked, excluded, printf)
+	if err != nil {
+		return nil, err
+	}
+	resumed := new(int64)
+	if db != nil && opts.Config.Output.SQLiteResume {
+		printf("Skipping domains already checked in %s\n", db.Path())
+		domainChan = db.Filter(ctx, domainChan, resumed)
+	}
+
+	// Calculate total domains count (base count, may be reduced by regex filter)
+	expected, space := 0, 0
+	if opts.Domains == nil {
+		end := baseDomainCount
+		if opts.Limit > 0 && opts.Offset+opts.Limit < end {
+			end = opts.Offset + opts.Limit
+		}
+		if opts.Offset > 0 || opts.Limit > 0 {
+			printf("Using keyspace range [%d, %d)\n", opts.Offset, end)
+		}
+		if opts.RegexFilter != "" {
+			printf("Using regex filter: %s (domain space: %s)\n", opts.RegexFilter, generator.FormatCount(baseDomainCount))
+		} else if baseDomainCount == generator.MaxCount && opts.Limit == 0 {
+			// Progress goes without a total
+			printf("Total domains to check: %s\n", generator.FormatCount(baseDomainCount))
+		} else {
+			expected = opts.order().Remaining(opts.Offset, end)
+			if space = end - opts.Offset; opts.Sample > 0 && opts.Sample < expected {
+				expected = opts.Sample
+			}
+			printf("Total domains to check: %d\n", expected)
+		}
+	}
+	if opts.ExpectedCount != nil {
+		printf("Expected domains to generate: %d\n", *opts.ExpectedCount)
+		expected = *opts.ExpectedCount
+	}
+
+	zone, err := loadZone(opts, printf)
+	if err != nil {
+		return nil, err
+	}
+	prechecked, err := precheck(ctx, domainChan, zone, opts.Prefilter)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Prefilter != nil {
+		printf("Prefiltering candidates with raw DNS NS queries\n")
+	}
+
+	// Make DNS overrides visible since they change how registration is decided
+	for _, suffix := range opts.suffixes() {
+		if policy := opts.wildcardAPolicy(suffix); policy != "" {
+			printf("Warning: wildcard DNS override active for %s: A records %s\n", suffix, describeWildcardPolicy(policy))
+		}
+	}
+
+	// Create channels for jobs and results; jobs is unbuffered so that a cancelled
+	// scan only finishes the checks workers have already picked up
+	jobs := make(chan string)
+	results := make(chan types.DomainResult, 1000)
+
+	// Start workers; results is closed once all of them have drained the jobs
+	var workers sync.WaitGroup
+	pacer := worker.NewPacer(opts.Delay, opts.Workers, opts.DomainChecker)
+	for w := 1; w <= opts.Workers; w++ {
+		workers.Add(1)
+		go func(id int) {
+			defer workers.Done()
+			worker.Worker(ctx, id, jobs, results, pacer, opts.Checker)
+		}(w)
+	}
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	// Send jobs from domain generator
+	p := &pipeline{results: results, generated: new(int64), expected: expected, space: space, blocked: blocked, excluded: excluded, zoned: new(int64), prefiltered: new(int64), resumed: resumed}
+	if opts.generatedKeyspace() {
+		p.order = newDispatchOrder()
+	}
+	generationDone := make(chan struct{})
+	p.generationDone = generationDone
+	go func() {
+		defer close(generationDone)
+		defer close(jobs)
+		domainCount := 0
+	feed:
+		for c := range prechecked {
+			domainName := c.name
+			// Names classified by a pre-check are registered; their result skips the
+			// workers. The results channel stays open until jobs is closed below.
+			if c.registeredBy != "" {
+				domainCount++
+				atomic.StoreInt64(p.generated, int64(domainCount))
+				if c.registeredBy == zonefile.Signature {
+					atomic.AddInt64(p.zoned, 1)
+				} else {
+					atomic.AddInt64(p.prefiltered, 1)
+				}
+				results <- types.DomainResult{Domain: domainName, Signatures: []string{c.registeredBy},
+					DropDate: opts.DropDates[domainName], Permutation: opts.Permutations[domainName]}
+				continue
+			}
+			// A domain cancelled before a worker took it stays pending: it was not checked
+			p.order.dispatch(domainName)
+			select {
+			case <-ctx.Done():
+				break feed
+			case jobs <- domainName:
+				domainCount++
+				atomic.StoreInt64(p.generated, int64(domainCount))
+			}
+		}
+		if ctx.Err() != nil {
+			printf("Scan interrupted, finishing %d dispatched domains\n", domainCount)
+		} else {
+			printf("Total domains to process: %d\n", domainCount)
+			if n := atomic.LoadInt64(excluded); n > 0 {
+				printf("Skipped %d candidates on the exclusion list\n", n)
+			}
+			if n := atomic.LoadInt64(resumed); n > 0 {
+				printf("Skipped %d domains already in the database\n", n)
+			}
+			if n := atomic.LoadInt64(blocked); n > 0 {
+				printf("Dropped %d candidates matching the blocklist\n", n)
+			}
+			if opts.ExpectedCount != nil && domainCount != *opts.ExpectedCount {
+				printf("Warning: generated %d domains but %d were expected; the batch split may be stale or the generator changed\n",
+					domainCount, *opts.ExpectedCount)
+			}
+		}
+	}()
+	return p, nil
+}
+
+// Run generates domains, checks them with a pool of workers and writes the result files.
+// When ctx is cancelled no new domains are dispatched; in-flight checks are finished and
+// the partial results are written with Summary.Interrupted set.
+func Run(ctx context.Context, opts Options) (*Summary, error) {
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	printf := func(format string, args ...interface{}) {
+		fmt.Fprintf(out, opts.Prefix+format, args...)
+	}
+
+	opts = normalize(opts)
+	// Resolve and lock the result files first so that a conflict fails before any check
+	var files *outputFiles
+	if !opts.SkipWrite {
+		var err error
+		if files, err = planOutputs(opts); err != nil {
+			return nil, err
+		}
+		defer files.release()
+	}
+	var db *sqlite.Writer
+	if files != nil && opts.Config != nil && opts.Config.Output.SQLite != "" {
+		var err error
+		// Failed batches are reported as they happen; the scan goes on and the result
+		// files are still written
+		report := func(err error) { printf("Warning: %v\n", err) }
+		if db, err = sqlite.Open(databaseFile(opts), report); err != nil {
+			return nil, err
+		}
+	}
+	p, err := start(ctx, opts, db, printf)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	summary := &Summary{TLDStats: make(map[string]*TLDStat)}
+	started := time.Now()
+	h := startHooks(opts, printf)
+	h.state(StateStarted)
+	if opts.Gate != nil {
+		opts.Gate.setWatch(func(paused bool) {
+			if paused {
+				h.state(StatePaused)
+			} else {
+				h.state(StateResumed)
+			}
+		})
+	}
+	defer func() {
+		if opts.Gate != nil {
+			opts.Gate.setWatch(nil)
+		}
+		if summary.Interrupted {
+			h.state(StateAborted)
+		} else {
+			h.state(StateFinished)
+		}
+		summary.HookEventsDropped = h.close()
+	}()
+	var publisher *gsheets.Publisher
+	if opts.Sheets != nil {
+		publisher = gsheets.NewPublisher(opts.Sheets, gsheets.Methods(opts.Config), printf)
+	}
+	// Notified domains are quoted once, for their notification and the result files
+	pricer := opts.Pricer
+	if pricer != nil {
+		pricer = pricing.Cached(pricer)
+	}
+	var notifier *notify.Notifier
+	if opts.Webhook != nil {
+		notifier = opts.Webhook.Start(printf, pricer)
+	}
+
+	// Create a channel for domain status messages
+	statusChan := make(chan string, 1000)
+	printerDone := make(chan struct{})
+	go func() {
+		defer close(printerDone)
+		for msg := range statusChan {
+			printf("%s\n", msg)
+		}
+	}()
+
+	// Collect results until every dispatched domain has been checked
+	processedCount := 0
+	cancelled := 0
+	var reported []types.SpecialStatusDomain
+	keepWHOIS := opts.Config != nil && opts.Config.Output.WHOISJSONFile != "" && !opts.SkipWrite
+	var tick <-chan time.Time
+	if opts.OnProgress != nil {
+		interval := opts.ProgressInterval
+		if interval <= 0 {
+			interval = defaultProgressInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	snapshot := func() Progress {
+		progress := Progress{
+			Processed:   processedCount - cancelled,
+			Available:   len(summary.Available),
+			Registered:  summary.RegisteredCount,
+			Uncertain:   summary.Uncertain,
+			RateLimited: summary.RateLimited,
+			Errors:      summary.Errors,
+			Expected:    p.expected,
+			Elapsed:     time.Since(started),
+		}
+		select {
+		case <-p.generationDone:
+			progress.Total = int(atomic.LoadInt64(p.generated))
+		default:
+		}
+		return progress
+	}
+	for {
+		var result types.DomainResult
+		var ok bool
+		select {
+		case result, ok = <-p.results:
+		case <-tick:
+			h.progress(snapshot())
+			continue
+		}
+		if !ok {
+			break
+		}
+		processedCount++
+
+		// The total is only known once generation has finished
+		var progress string
+		select {
+		case <-p.generationDone:
+			progress = fmt.Sprintf("[%d/%d]", processedCount, atomic.LoadInt64(p.generated))
+		default:
+			progress = fmt.Sprintf("[%d]", processedCount)
+		}
+
+		if opts.DebugIndex {
+			progress += " " + counterLabel(opts, result.Domain)
+		}
+
+		// Checks cut short by cancellation have no verdict and are not reported
+		if errors.Is(result.Error, context.Canceled) {
+			cancelled++
+			continue
+		}
+		p.order.done(result.Domain)
+
+		metrics.RecordResult(opts.Metrics, result)
+		publisher.Add(result)
+		notifier.Add(result)
+		db.Add(result)
+		h.result(result)
+		summary.trackStatus(opts, result)
+		if result.Unicode != "" {
+			if summary.Unicode == nil {
+				summary.Unicode = make(map[string]string)
+			}
+			summary.Unicode[result.Domain] = result.Unicode
+		}
+		if result.Permutation != "" {
+			if summary.Permutations == nil {
+				summary.Permutations = make(map[string]string)
+			}
+			summary.Permutations[result.Domain] = result.Permutation
+		}
+
+		stat := tldStat(summary, result.Domain)
+		stat.Checked++
+		summary.ChecksSkipped += result.SkippedChecks
+		recordTiming(summary, result)
+		if opts.SlowThreshold > 0 && result.Elapsed >= opts.SlowThreshold {
+			statusChan <- fmt.Sprintf("%s WARN: slow domain %s took %v (%s)", progress, result.Domain,
+				roundDuration(result.Elapsed), FormatPhases(result.Phases))
+		}
+
+		if result.Error != nil {
+			stat.Errors++
+			summary.Errors++
+			statusChan <- fmt.Sprintf("%s Error checking domain %s: %v", progress, result.Domain, result.Error)
+			continue
+		}
+
+		if result.Available {
+			msg := fmt.Sprintf("%s Domain %s is AVAILABLE!", progress, displayName(result))
+			if trademarkRisk(result) {
+				msg += " [" + types.TrademarkRisk + "]"
+			}
+			if result.DropDate != "" {
+				msg += " (drop date " + result.DropDate + ")"
+			}
+			if result.Permutation != "" {
+				msg += " (" + result.Permutation + ")"
+			}
+			statusChan <- msg
+			summary.Available = append(summary.Available, result.Domain)
+			stat.Available++
+		} else {
+			if keepWHOIS && result.WHOIS != "" {
+				summary.WHOISRecords = append(summary.WHOISRecords, domain.ParseWHOIS(result.Domain, result.WHOIS))
+			}
+			registered := countUnavailable(summary, result)
+			if !registered {
+				reported = append(reported, specialStatusOf(result))
+			}
+			// Always count registered domains, but only show if requested
+			if opts.ShowRegistered {
+				sigStr := strings.Join(result.Signatures, ", ")
+				if registered {
+					if listRegistered(opts, summary, result) {
+						statusChan <- registeredMessage(progress, result)
+					}
+				} else {
+					statusChan <- fmt.Sprintf("%s Domain %s has special status %s [%s]", progress, displayName(result), result.SpecialStatus, sigStr)
+				}
+			}
+		}
+	}
+	close(statusChan)
+	<-printerDone
+	<-p.generationDone
+	h.finish(snapshot())
+
+	summary.Processed = processedCount - cancelled
+	summary.Skipped = cancelled
+	summary.Generated = int(atomic.LoadInt64(p.generated))
+	summary.Blocked = int(atomic.LoadInt64(p.blocked))
+	summary.Excluded = int(atomic.LoadInt64(p.excluded))
+	summary.ZoneSkipped = int(atomic.LoadInt64(p.zoned))
+	summary.PrefilterSkipped = int(atomic.LoadInt64(p.prefiltered))
+	summary.Resumed = int(atomic.LoadInt64(p.resumed))
+	summary.Interrupted = ctx.Err() != nil
+	if summary.Interrupted && opts.Sample == 0 {
+		summary.ResumeFrom = p.order.resumeFrom()
+	}
+	if opts.Shuffle {
+		summary.Seed = opts.Seed
+	}
+	if opts.Sample > 0 {
+		summary.Sample, summary.SampleSpace = opts.Sample, p.space
+	}
+
+	if opts.RetryRateLimited && !summary.Interrupted {
+		retryRateLimited(ctx, opts, summary, &reported, db.Add, printf)
+		summary.Interrupted = ctx.Err() != nil
+	}
+
+	// The special statuses come from the results of this run only
+	for _, ssd := range reported {
+		summary.Special = append(summary.Special, ssd)
+		tldStat(summary, ssd.Domain).Special++
+	}
+	for _, stat := range summary.TLDStats {
+		stat.Registered = stat.Checked - stat.Available - stat.Special - stat.Errors
+	}
+	if opts.Previous != nil {
+		summary.Changes = statusChanges(opts, summary)
+	}
+	if opts.TLDTable {
+		summary.TLDTable = tldTable(summary)
+	}
+
+	// Prices are looked up last so that domains resolved by the retry are included
+	if opts.Pricer != nil && len(summary.Available) > 0 && !summary.Interrupted {
+		printf("Looking up prices of %d available domains via %s...\n", len(summary.Available), opts.Pricer.Name())
+		summary.Prices = pricing.Annotate(ctx, pricer, summary.Available)
+		summary.Interrupted = ctx.Err() != nil
+	}
+	// Flagged domains are kept in the result files but never published
+	publishable := summary.Available
+	if opts.Blocklist != nil && opts.BlocklistMode == types.BlocklistFlag {
+		publishable = nil
+		for _, name := range summary.Available {
+			if _, ok := opts.Blocklist.Match(name); ok {
+				summary.Flagged = append(summary.Flagged, name)
+			} else {
+				publishable = append(publishable, name)
+			}
+		}
+	}
+	publisher.Finish(publishable, summary.Prices)
+	notifier.Close()
+	// A failed database is reported once the result files are written
+	var dbErr error
+	if db != nil {
+		if dbErr = db.Close(); dbErr == nil {
+			summary.DatabaseFile = db.Path()
+		}
+	}
+	if opts.Scorer != nil && len(summary.Available) > 0 {
+		summary.Scores = opts.Scorer.Rank(summary.Available)
+	}
+
+	// The outcome counters are kept independently; a gap points at a pipeline bug
+	if counted := summary.Counted(); counted != summary.Processed {
+		printf("WARNING: result counters add up to %d but %d domains were processed "+
+			"(available %d, registered %d, uncertain %d, rate limited %d, errors %d); please report this bug\n",
+			counted, summary.Processed, len(summary.Available), summary.RegisteredCount, summary.Uncertain,
+			summary.RateLimited, summary.Errors)
+	}
+
+	if opts.SkipWrite {
+		return summary, nil
+	}
+	if err := writeResults(opts, files, summary); err != nil {
+		return summary, err
+	}
+	return summary, dbErr
+}
+
+// counterLabel renders the generator counter of a domain for debug output
+func counterLabel(opts Options, domainName string) string {
+	if opts.InputFile != "" || len(opts.WordLists) > 0 || opts.KeywordFile != "" || opts.Leet != "" {
+		return "#?"
+	}
+	counter, ok := opts.counter()(domainName)
+	if !ok {
+		return "#?"
+	}
+	return fmt.Sprintf("#%d", counter)
+}
+
+// tldStat returns the stats entry for the suffix of a domain, creating it if needed
+func tldStat(summary *Summary, domainName string) *TLDStat {
+	suffix := domainName
+	if idx := strings.Index(domainName, "."); idx >= 0 {
+		suffix = domainName[idx:]
+	}
+	stat, ok := summary.TLDStats[suffix]
+	if !ok {
+		stat = &TLDStat{}
+		summary.TLDStats[suffix] = stat
+	}
+	return stat
+}
+
+// describeWildcardPolicy explains a wildcard A-record policy for the startup warning
+func describeWildcardPolicy(policy string) string {
+	if policy == types.WildcardACombined {
+		return "only count together with another registration signal"
+	}
+	return "are ignored for registration"
+}
+
+// outputFileName expands a file name template from the config or falls back to the default name
+func outputFileName(opts Options, template, defaultPrefix string) string {
+	suffix := strings.TrimPrefix(opts.Suffix, ".")
+	if len(opts.Suffixes) > 1 {
+		// Runs over several suffixes are named after all of them, e.g. com.net.io
+		suffix = strings.TrimPrefix(strings.Join(opts.Suffixes, ""), ".")
+	}
+	pattern, length := opts.Pattern, fmt.Sprint(opts.Length)
+	if opts.Charset != "" {
+		// Custom charset runs are named after their characters
+		pattern = opts.Charset
+	}
+	if opts.ExcludeChars != "" {
+		// Excluded characters follow a minus sign, e.g. D-qv
+		pattern += "-" + opts.ExcludeChars
+	}
+	if opts.InputFile != "" {
+		// Name list runs are named after the input instead of a keyspace
+		pattern, length = "input", "0"
+	} else if len(opts.WordLists) > 0 {
+		// Combinator runs are named after the number of word lists
+		pattern, length = "combo", fmt.Sprint(len(opts.WordLists))
+	} else if opts.KeywordFile != "" {
+		// Keyword runs are named after the affix position, e.g. keywords_both
+		pattern, length = "keywords", opts.affixPosition()
+	} else if opts.Leet != "" {
+		// Leetspeak runs are named after the seed word, e.g. leet_shop
+		pattern, length = "leet", strings.ToLower(opts.Leet)
+	} else if r, err := opts.numRange(); opts.NumRange != "" && err == nil {
+		// Numeric range runs are named after the range, e.g. num_8000-8999
+		pattern, length = "num", r.String()
+	} else if len(opts.Lengths) > 0 {
+		// Runs over several lengths are named after all of them, e.g. 2-4
+		length = generator.FormatLengths(opts.Lengths)
+	}
+	if opts.InputFile == "" && len(opts.WordLists) == 0 && opts.KeywordFile == "" && opts.Leet == "" {
+		// Fixed affixes are part of the pattern label, e.g. go+D+hub
+		pattern = opts.affixes().Label(pattern)
+		if opts.Mask != "" {
+			// Mask runs are named after the mask with _ for ?, e.g. mask_a__9
+			pattern, length = "mask", strings.ReplaceAll(strings.ToLower(opts.Mask), "?", "_")
+		} else if opts.LetterPattern != "" {
+			// Letter pattern runs are named after the pattern, e.g. letters_AAB
+			pattern, length = "letters", strings.ToUpper(opts.LetterPattern)
+		} else if opts.Template != "" {
+			// Template runs are named after the template, e.g. template_CVCV
+			pattern, length = "template", strings.ToUpper(opts.Template)
+		} else if opts.Pattern == generator.PatternPronounceable {
+			// Pronounceable runs are named after their syllable count, e.g. pronounceable_2s
+			length = fmt.Sprintf("%ds", opts.Syllables)
+		}
+	}
+	name := fmt.Sprintf("%s_%s_%s_%s.txt", defaultPrefix, pattern, length, suffix)
+	if opts.Config != nil && template != "" {
+		name = strings.Replace(template, "{pattern}", pattern, -1)
+		name = strings.Replace(name, "{length}", length, -1)
+		name = strings.Replace(name, "{suffix}", suffix, -1)
+	}
+	if opts.FileSuffix != "" {
+		ext := filepath.Ext(name)
+		name = strings.TrimSuffix(name, ext) + opts.FileSuffix + ext
+	}
+
+	// Use output directory if specified in config
+	if opts.Config != nil && opts.Config.Output.OutputDir != "" {
+		name = opts.Config.Output.OutputDir + "/" + name
+	}
+	return name
+}
+
+// databaseFile returns the path of the [output] sqlite database; relative paths are in
+// the output directory like the result files, but unlike them the name is not
+// templated, so that every run adds to the same database
+func databaseFile(opts Options) string {
+	path := opts.Config.Output.SQLite
+	if opts.Config.Output.OutputDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(opts.Config.Output.OutputDir, path)
+	}
+	return path
+}
+
+// writeResults saves the available, registered and special status domains to their files
+func writeResults(opts Options, files *outputFiles, summary *Summary) error {
+	// Save available domains to file; flagged domains are followed by the TrademarkRisk marker
+	summary.AvailableFile = files.available
+	available := fileLines(summary, summary.Available)
+	if len(summary.Flagged) > 0 {
+		flagged := make(map[string]bool, len(summary.Flagged))
+		for _, name := range summary.Flagged {
+			flagged[name] = true
+		}
+		available = make([]string, len(summary.Available))
+		for i, name := range groupByPermutation(summary, summary.Available) {
+			available[i] = fileLine(summary, name)
+			if flagged[name] {
+				available[i] += " " + types.TrademarkRisk
+			}
+		}
+	}
+	if err := writeLines(summary.AvailableFile, files.append, nil, available); err != nil {
+		return fmt.Errorf("error writing available domains file: %w", err)
+	}
+
+	// Save registered domains to file only if show-registered is true
+	if opts.ShowRegistered {
+		summary.RegisteredFile = files.registered
+		if err := writeLines(summary.RegisteredFile, files.append, nil, registeredLines(opts, summary)); err != nil {
+			return fmt.Errorf("error writing registered domains file: %w", err)
+		}
+	}
+
+	// Save special status domains to file if any exist
+	if len(summary.Special) > 0 {
+		summary.SpecialStatusFile = files.special
+		header := []string{
+			"# Special Status Domains",
+			"# Format: domain status reason",
+			"#",
+		}
+		var lines []string
+		for _, ssd := range summary.Special {
+			lines = append(lines, fmt.Sprintf("%s %s %s", ssd.Domain, ssd.Status, ssd.Reason))
+		}
+		if err := writeLines(summary.SpecialStatusFile, files.append, header, lines); err != nil {
+			return fmt.Errorf("error writing special status file: %w", err)
+		}
+	}
+
+	// Save the parsed WHOIS responses of the registered domains as JSON lines
+	if len(summary.WHOISRecords) > 0 {
+		summary.WHOISJSONFile = files.whois
+		var lines []string
+		for _, record := range summary.WHOISRecords {
+			data, err := json.Marshal(record)
+			if err != nil {
+				return fmt.Errorf("error encoding WHOIS record of %s: %w", record.Domain, err)
+			}
+			lines = append(lines, string(data))
+		}
+		if err := writeLines(summary.WHOISJSONFile, files.append, nil, lines); err != nil {
+			return fmt.Errorf("error writing WHOIS JSON file: %w", err)
+		}
+	}
+
+	// Save the prices next to the available domains, which stay one domain per line
+	if len(summary.Prices) > 0 {
+		summary.PricesFile = files.prices
+		header := []string{
+			fmt.Sprintf("# Available Domain Prices (%s)", opts.Pricer.Name()),
+			"# Format: domain price",
+			"#",
+		}
+		var lines []string
+		for _, name := range summary.Available {
+			if quote, ok := summary.Prices[name]; ok {
+				lines = append(lines, fmt.Sprintf("%s %s", name, quote))
+			}
+		}
+		if err := writeLines(summary.PricesFile, files.append, header, lines); err != nil {
+			return fmt.Errorf("error writing prices file: %w", err)
+		}
+	}
+
+	// Save the scores best first with their breakdown
+	if len(summary.Scores) > 0 {
+		summary.ScoresFile = files.scores
+		header := []string{
+			"# Available Domain Scores (0-100, best first)",
+			"# Format: domain score breakdown",
+			"#",
+		}
+		lines := make([]string, 0, len(summary.Scores))
+		for _, score := range summary.Scores {
+			lines = append(lines, fmt.Sprintf("%s %s", score.Domain, score))
+		}
+		if err := writeLines(summary.ScoresFile, files.append, header, lines); err != nil {
+			return fmt.Errorf("error writing scores file: %w", err)
+		}
+	}
+
+	// Save the availability of a reverse mode scan by TLD, available first
+	if len(summary.TLDTable) > 0 {
+		summary.TLDTableFile = files.tldTable
+		header := []string{
+			"# Availability by TLD (available first)",
+			"# Format: tld<TAB>domain<TAB>status",
+			"#",
+		}
+		if err := writeLines(summary.TLDTableFile, files.append, header, tldTableLines(summary.TLDTable)); err != nil {
+			return fmt.Errorf("error writing TLD table file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeLines creates a file containing the header lines followed by the given lines.
+// In append mode the lines are added to an existing file, whose header is kept.
+func writeLines(path string, appendLines bool, header, lines []string) (err error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendLines {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if appendLines {
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		if info.Size() > 0 {
+			header = nil
+		}
+	}
+	for _, line := range header {
+		if _, err := file.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	for _, line := range lines {
+		if _, err := file.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// topScores is the number of best scored domains listed by PrintSummary
+const topScores = 10
+
+// PrintSummary prints the result file locations and counts of a finished run
+func PrintSummary(out io.Writer, summary *Summary, showRegistered bool) {
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintf(out, "\n\nResults saved to:\n")
+	fmt.Fprintf(out, "- Available domains: %s\n", summary.AvailableFile)
+	if showRegistered {
+		fmt.Fprintf(out, "- Registered domains: %s\n", summary.RegisteredFile)
+	}
+	if len(summary.Special) > 0 {
+		fmt.Fprintf(out, "- Special status domains: %s\n", summary.SpecialStatusFile)
+	}
+	if summary.PricesFile != "" {
+		fmt.Fprintf(out, "- Prices: %s\n", summary.PricesFile)
+	}
+	if summary.ScoresFile != "" {
+		fmt.Fprintf(out, "- Scores: %s\n", summary.ScoresFile)
+	}
+	if summary.WHOISJSONFile != "" {
+		fmt.Fprintf(out, "- WHOIS records: %s\n", summary.WHOISJSONFile)
+	}
+	if summary.TLDTableFile != "" {
+		fmt.Fprintf(out, "- Availability by TLD: %s\n", summary.TLDTableFile)
+	}
+	if summary.DatabaseFile != "" {
+		fmt.Fprintf(out, "- Results database: %s\n", summary.DatabaseFile)
+	}
+	fmt.Fprintf(out, "\nSummary:\n")
+	fmt.Fprintf(out, "- Total domains processed: %d\n", summary.Processed)
+	fmt.Fprintf(out, "- Available domains: %d\n", len(summary.Available))
+	if showRegistered {
+		fmt.Fprintf(out, "- Registered domains: %d\n", summary.RegisteredCount)
+	} else {
+		fmt.Fprintf(out, "- Registered domains: %d (not saved to file)\n", summary.RegisteredCount)
+	}
+	if summary.MediumConfidence > 0 {
+		fmt.Fprintf(out, "- Registered with medium confidence: %d of the registered domains are known only from CT logs\n",
+			summary.MediumConfidence)
+	}
+	fmt.Fprintf(out, "- Uncertain domains: %d (require manual review)\n", summary.Uncertain)
+	if summary.Unconfirmed > 0 {
+		fmt.Fprintf(out, "- Unconfirmed available (strict mode): %d of the uncertain domains would be reported available without -strict\n",
+			summary.Unconfirmed)
+	}
+	fmt.Fprintf(out, "- Rate-limited domains: %d\n", summary.RateLimited)
+	fmt.Fprintf(out, "- Errors: %d\n", summary.Errors)
+	fmt.Fprintf(out, "- Skipped by interruption: %d\n", summary.Skipped)
+	if summary.ZoneSkipped > 0 {
+		fmt.Fprintf(out, "- Checks avoided by zone files: %d\n", summary.ZoneSkipped)
+	}
+	if summary.PrefilterSkipped > 0 {
+		fmt.Fprintf(out, "- Checks avoided by the DNS prefilter: %d\n", summary.PrefilterSkipped)
+	}
+	if summary.ChecksSkipped > 0 {
+		fmt.Fprintf(out, "- Check methods skipped after a definitive verdict: %d\n", summary.ChecksSkipped)
+	}
+	if summary.Excluded > 0 {
+		fmt.Fprintf(out, "- Candidates skipped by the exclusion list: %d\n", summary.Excluded)
+	}
+	if summary.Resumed > 0 {
+		fmt.Fprintf(out, "- Domains skipped as already in the database: %d\n", summary.Resumed)
+	}
+	if summary.Blocked > 0 {
+		fmt.Fprintf(out, "- Candidates dropped by the blocklist: %d\n", summary.Blocked)
+	}
+	if len(summary.Flagged) > 0 {
+		fmt.Fprintf(out, "- Available domains flagged %s: %d\n", types.TrademarkRisk, len(summary.Flagged))
+	}
+	if len(summary.Prices) > 0 {
+		known, premium := 0, 0
+		for _, quote := range summary.Prices {
+			if quote.Known() {
+				known++
+			}
+			if quote.Premium {
+				premium++
+			}
+		}
+		fmt.Fprintf(out, "- Priced available domains: %d/%d (%d premium)\n", known, len(summary.Available), premium)
+	}
+	if len(summary.Scores) > 0 {
+		fmt.Fprintf(out, "- Top scored available domains:\n")
+		for i, score := range summary.Scores {
+			if i == topScores {
+				break
+			}
+			fmt.Fprintf(out, "    %3d  %s\n", score.Total, score.Domain)
+		}
+	}
+	if summary.RateLimitRetried > 0 {
+		fmt.Fprintf(out, "- Rate-limited domains resolved on retry: %d/%d\n", summary.RateLimitResolved, summary.RateLimitRetried)
+	}
+	if summary.Sample > 0 && summary.Processed > 0 {
+		// The availability of the sample estimates that of the keyspace, with the
+		// 95% margin of error of a proportion
+		rate := float64(len(summary.Available)) / float64(summary.Processed)
+		margin := 1.96 * math.Sqrt(rate*(1-rate)/float64(summary.Processed))
+		if summary.SampleSpace > 0 {
+			fmt.Fprintf(out, "- Sample: %d random domains of %d (seed %d)\n", summary.Processed, summary.SampleSpace, summary.Seed)
+			fmt.Fprintf(out, "- Estimated availability: %.1f%% ± %.1f%%, about %d available domains\n",
+				rate*100, margin*100, int(math.Round(rate*float64(summary.SampleSpace))))
+		} else {
+			fmt.Fprintf(out, "- Sample: %d random domains of the filtered keyspace (seed %d)\n", summary.Processed, summary.Seed)
+			fmt.Fprintf(out, "- Estimated availability: %.1f%% ± %.1f%%\n", rate*100, margin*100)
+		}
+	}
+	if summary.Interrupted {
+		fmt.Fprintf(out, "- Scan was interrupted after dispatching %d domains\n", summary.Generated)
+		if summary.ResumeFrom != "" && summary.Seed != 0 {
+			fmt.Fprintf(out, "- Resume with -start-from %s -shuffle -seed %d\n", summary.ResumeFrom, summary.Seed)
+		} else if summary.ResumeFrom != "" {
+			fmt.Fprintf(out, "- Resume with -start-from %s\n", summary.ResumeFrom)
+		}
+	}
+}
+
+// PrintTLDStats prints the per-suffix availability table of a finished run
+func PrintTLDStats(out io.Writer, summary *Summary) {
+	if out == nil {
+		out = os.Stdout
+	}
+	suffixes := make([]string, 0, len(summary.TLDStats))
+	for suffix := range summary.TLDStats {
+		suffixes = append(suffixes, suffix)
+	}
+	sort.Strings(suffixes)
+
+	fmt.Fprintf(out, "\nPer-TLD statistics:\n")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TLD\tCHECKED\tAVAILABLE\tREGISTERED\tSPECIAL\tERRORS\tAVAILABLE %")
+	for _, suffix := range suffixes {
+		stat := summary.TLDStats[suffix]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%.1f%%\n", suffix, stat.Checked, stat.Available,
+			stat.Registered, stat.Special, stat.Errors, stat.AvailabilityRate())
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,499 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"domain-scanner/internal/ctlog"
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/generator"
+	"domain-scanner/internal/types"
+	"domain-scanner/internal/worker"
+)
+
+func TestCountMatchesGenerate(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		wantOK bool
+	}{
+		{name: "letters", opts: Options{Length: 3, Pattern: "D", Suffix: ".li"}, wantOK: true},
+		{name: "hyphens", opts: Options{Length: 3, Pattern: "h", Suffix: ".li"}, wantOK: true},
+		{name: "hyphen lengths", opts: Options{Lengths: []int{1, 2, 3}, Pattern: "h", Suffix: ".li"}, wantOK: true},
+		{name: "hyphens shuffled", opts: Options{Length: 2, Pattern: "h", Suffix: ".li", Shuffle: true, Seed: 7}, wantOK: true},
+		{name: "hyphens limited", opts: Options{Length: 3, Pattern: "h", Suffix: ".li", Offset: 100, Limit: 500}},
+		{name: "letters limited", opts: Options{Length: 3, Pattern: "D", Suffix: ".li", Offset: 100, Limit: 500}, wantOK: true},
+		{name: "suffixes", opts: Options{Length: 2, Pattern: "h", Suffixes: []string{".li", ".ch"}}, wantOK: true},
+		{name: "suffixes limited", opts: Options{Length: 2, Pattern: "D", Suffixes: []string{".li", ".ch"}, Limit: 10}, wantOK: true},
+		{name: "hyphens between affixes", opts: Options{Length: 2, Pattern: "h", Suffix: ".li", NamePrefix: "x"}},
+		{name: "letters between affixes", opts: Options{Length: 2, Pattern: "D", Suffix: ".li", NamePrefix: "x"}, wantOK: true},
+		{name: "mask", opts: Options{Mask: "a?-?", Pattern: "h", Suffix: ".li"}, wantOK: true},
+		{name: "charset", opts: Options{Length: 3, Charset: "ab-", Suffix: ".li"}, wantOK: true},
+		{name: "sample", opts: Options{Length: 3, Pattern: "h", Suffix: ".li", Sample: 20, Seed: 7}, wantOK: true},
+		{name: "start from", opts: Options{Length: 2, Pattern: "D", Suffix: ".li", StartFrom: "ma"}, wantOK: true},
+		{name: "regex", opts: Options{Length: 2, Pattern: "D", Suffix: ".li", RegexFilter: "^a"}},
+		{name: "unicode", opts: Options{Length: 2, Charset: "aä", Suffix: ".de"}},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, ok, err := Count(ctx, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("Count() ok = %v, want %v", ok, tt.wantOK)
+			}
+			domains, _, err := Generate(ctx, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			generated := 0
+			for range domains {
+				generated++
+			}
+			if ok && count != generated {
+				t.Errorf("Count() = %d, Generate() gives %d domains", count, generated)
+			}
+		})
+	}
+}
+
+func TestCountCapped(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		wantCount int
+		wantOK    bool
+	}{
+		{name: "alphanumeric", opts: Options{Length: 13, Pattern: "a", Suffix: ".li"}, wantCount: generator.MaxCount, wantOK: true},
+		{name: "hyphens", opts: Options{Length: 13, Pattern: "h", Suffix: ".li"}, wantCount: generator.MaxCount, wantOK: true},
+		{name: "suffixes", opts: Options{Length: 13, Pattern: "a", Suffixes: []string{".li", ".ch"}}, wantCount: generator.MaxCount, wantOK: true},
+		{name: "limited", opts: Options{Length: 13, Pattern: "a", Suffix: ".li", Offset: 10, Limit: 1000}, wantCount: 1000, wantOK: true},
+		{name: "limited hyphens", opts: Options{Length: 13, Pattern: "h", Suffix: ".li", Limit: 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, ok, err := Count(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.wantCount || ok != tt.wantOK {
+				t.Errorf("Count() = %d, %v, want %d, %v", count, ok, tt.wantCount, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestShuffleCappedKeyspace(t *testing.T) {
+	if _, _, err := Count(context.Background(), Options{Length: 13, Pattern: "a", Suffix: ".li", Sample: 10}); err == nil {
+		t.Errorf("Count() of a sample of a keyspace beyond the int counters succeeded")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary Summary
+		want    int
+	}{
+		{name: "success", summary: Summary{Processed: 10}, want: ExitOK},
+		{name: "errors", summary: Summary{Processed: 10, Errors: 1}, want: ExitErrors},
+		{name: "interrupted", summary: Summary{Processed: 5, Interrupted: true}, want: ExitAborted},
+		{name: "interrupted with errors", summary: Summary{Processed: 5, Errors: 1, Interrupted: true}, want: ExitAborted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.summary.ExitCode(); got != tt.want {
+				t.Errorf("ExitCode() = %d (%s), want %d (%s)", got, ExitMeaning(got), tt.want, ExitMeaning(tt.want))
+			}
+		})
+	}
+}
+
+func TestFlagExitCode(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if got := FlagExitCode(fs.Parse([]string{"-help"})); got != ExitOK {
+		t.Errorf("FlagExitCode(-help) = %d, want %d", got, ExitOK)
+	}
+	if got := FlagExitCode(fs.Parse([]string{"-bogus"})); got != ExitUsage {
+		t.Errorf("FlagExitCode(-bogus) = %d, want %d", got, ExitUsage)
+	}
+}
+
+func TestWriteSummaryFile(t *testing.T) {
+	dir := t.TempDir()
+	summary := &Summary{Processed: 3, Available: []string{"a.li"}, RegisteredCount: 1, Errors: 1}
+	path, err := WriteSummaryFile(dir, summary, summary.ExitCode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got summaryFile
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := summaryFile{Processed: 3, Available: 1, Registered: 1, Errors: 1, ExitCode: ExitErrors, ExitMeaning: "completed with errors"}
+	if got != want {
+		t.Errorf("summary.json = %+v, want %+v", got, want)
+	}
+}
+
+func TestCTCheckerConfidence(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") == "seen.test" {
+			w.Write([]byte(`[{"common_name": "seen.test"}]`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	check := func(ctx context.Context, name string) types.DomainResult {
+		return types.DomainResult{Domain: name, Signatures: []string{"DNS_NS"}}
+	}
+
+	tests := []struct {
+		name           string
+		domain         string
+		verify         bool
+		wantConfidence string
+		wantSignatures []string
+	}{
+		{"CT hit", "seen.test", false, types.ConfidenceMedium, []string{ctlog.Signature}},
+		{"verified CT hit", "seen.test", true, "", []string{"DNS_NS", ctlog.Signature}},
+		{"CT miss", "new.test", false, "", []string{"DNS_NS"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := ctlog.New(server.URL+"/?q={domain}", 0, 0)
+			result := ctChecker(client, tt.verify, check)(context.Background(), tt.domain)
+			if result.Confidence != tt.wantConfidence || strings.Join(result.Signatures, ",") != strings.Join(tt.wantSignatures, ",") {
+				t.Errorf("result = confidence %q signatures %v, want %q %v",
+					result.Confidence, result.Signatures, tt.wantConfidence, tt.wantSignatures)
+			}
+
+			summary := &Summary{}
+			countUnavailable(summary, result)
+			if want := tt.wantConfidence != ""; (summary.MediumConfidence == 1) != want || summary.RegisteredCount != 1 {
+				t.Errorf("summary counts %d registered, %d medium confidence", summary.RegisteredCount, summary.MediumConfidence)
+			}
+		})
+	}
+}
+
+// domainList supplies names as Options.Domains
+func domainList(names ...string) <-chan string {
+	domains := make(chan string, len(names))
+	for _, name := range names {
+		domains <- name
+	}
+	close(domains)
+	return domains
+}
+
+func TestRunAlwaysRateLimited(t *testing.T) {
+	names := []string{"one.test", "two.test", "three.test"}
+
+	tests := []struct {
+		name string
+		// retry rechecks the rate-limited domains at the end of the run
+		retry bool
+		// resolves makes the recheck of every domain find it available
+		resolves     bool
+		wantSpecial  int
+		wantResolved int
+	}{
+		{name: "no retry", wantSpecial: 3},
+		{name: "retry still limited", retry: true, wantSpecial: 3},
+		{name: "retry resolves", retry: true, resolves: true, wantResolved: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			checks := make(map[string]int)
+			// The checker reports what a checker gets from a WHOIS server that always
+			// rate-limits: no verdict, only the special status
+			checker := func(ctx context.Context, name string) types.DomainResult {
+				mu.Lock()
+				checks[name]++
+				recheck := checks[name] > 1
+				mu.Unlock()
+				if recheck && tt.resolves {
+					return types.DomainResult{Domain: name, Available: true}
+				}
+				return types.DomainResult{Domain: name, SpecialStatus: domain.RateLimitedStatus}
+			}
+			cfg := &types.Config{}
+			cfg.Output.OutputDir = t.TempDir()
+
+			summary, err := Run(context.Background(), Options{
+				Domains:          domainList(names...),
+				Workers:          2,
+				Checker:          checker,
+				Config:           cfg,
+				Pattern:          "list",
+				Suffix:           ".test",
+				RetryRateLimited: tt.retry,
+				RetryDelay:       time.Millisecond,
+				RetryWorkers:     2,
+				Output:           io.Discard,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantRetried := 0
+			if tt.retry {
+				wantRetried = len(names)
+			}
+			if summary.RateLimited != tt.wantSpecial || len(summary.Special) != tt.wantSpecial ||
+				summary.RateLimitRetried != wantRetried || summary.RateLimitResolved != tt.wantResolved {
+				t.Errorf("rate limited %d, special %d, retried %d, resolved %d; want %d, %d, %d, %d",
+					summary.RateLimited, len(summary.Special), summary.RateLimitRetried, summary.RateLimitResolved,
+					tt.wantSpecial, tt.wantSpecial, wantRetried, tt.wantResolved)
+			}
+			if len(summary.Available) != tt.wantResolved {
+				t.Errorf("available = %v, want %d domains", summary.Available, tt.wantResolved)
+			}
+			if code := summary.ExitCode(); code != ExitOK {
+				t.Errorf("ExitCode() = %d, want %d", code, ExitOK)
+			}
+
+			available, err := os.ReadFile(summary.AvailableFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lines := strings.Count(string(available), "\n"); lines != tt.wantResolved {
+				t.Errorf("available file lists %d domains, want %d:\n%s", lines, tt.wantResolved, available)
+			}
+			if tt.wantSpecial == 0 {
+				if summary.SpecialStatusFile != "" {
+					t.Errorf("special status file %s written without special statuses", summary.SpecialStatusFile)
+				}
+				return
+			}
+			special, err := os.ReadFile(summary.SpecialStatusFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range names {
+				if !strings.Contains(string(special), name+" "+domain.RateLimitedStatus) {
+					t.Errorf("special status file lacks %s:\n%s", name, special)
+				}
+			}
+		})
+	}
+}
+
+func TestRunResumesFromDatabase(t *testing.T) {
+	var mu sync.Mutex
+	var checked []string
+	checker := func(ctx context.Context, name string) types.DomainResult {
+		mu.Lock()
+		checked = append(checked, name)
+		mu.Unlock()
+		if name == "failed.test" {
+			return types.DomainResult{Domain: name, Error: errors.New("timeout")}
+		}
+		return types.DomainResult{Domain: name, Available: true}
+	}
+	cfg := &types.Config{}
+	cfg.Output.OutputDir = t.TempDir()
+	cfg.Output.SQLite = "results.db"
+	cfg.Output.SQLiteResume = true
+	run := func(names ...string) *Summary {
+		t.Helper()
+		mu.Lock()
+		checked = nil
+		mu.Unlock()
+		summary, err := Run(context.Background(), Options{
+			Domains: domainList(names...), Workers: 2, Checker: checker, Config: cfg,
+			Pattern: "list", Suffix: ".test", Output: io.Discard,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return summary
+	}
+
+	run("one.test", "failed.test")
+	summary := run("one.test", "failed.test", "two.test")
+	sort.Strings(checked)
+	// The failed check left no row, so it is checked again
+	if got := strings.Join(checked, " "); got != "failed.test two.test" {
+		t.Errorf("second run checked %q, want %q", got, "failed.test two.test")
+	}
+	if summary.Resumed != 1 {
+		t.Errorf("Resumed = %d, want 1", summary.Resumed)
+	}
+	if summary.DatabaseFile == "" {
+		t.Errorf("DatabaseFile is empty after a successful run")
+	}
+
+	cfg.Output.SQLiteResume = false
+	if summary := run("one.test", "two.test"); len(checked) != 2 || summary.Resumed != 0 {
+		t.Errorf("without sqlite_resume checked %v and resumed %d, want both domains checked", checked, summary.Resumed)
+	}
+}
+
+// checkNoGoroutineLeak fails the test if goroutines started during it are still running
+// shortly after it ends
+func checkNoGoroutineLeak(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(2 * time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				buf := make([]byte, 1<<20)
+				t.Errorf("%d goroutines running, %d before the test:\n%s",
+					runtime.NumGoroutine(), before, buf[:runtime.Stack(buf, true)])
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+func TestPipelineLeavesNoGoroutines(t *testing.T) {
+	// The fake checker finds even numbers available and leaves odd ones rate limited
+	checker := func(ctx context.Context, name string) types.DomainResult {
+		if n := strings.TrimSuffix(name, ".test"); (n[len(n)-1]-'0')%2 == 0 {
+			return types.DomainResult{Domain: name, Available: true}
+		}
+		return types.DomainResult{Domain: name, SpecialStatus: domain.RateLimitedStatus}
+	}
+	// cancelAfter wraps the fake checker so that its n-th check cancels the scan
+	cancelAfter := func(n int32, cancel context.CancelFunc) worker.CheckFunc {
+		var checks int32
+		return func(ctx context.Context, name string) types.DomainResult {
+			if atomic.AddInt32(&checks, 1) == n {
+				cancel()
+			}
+			return checker(ctx, name)
+		}
+	}
+	options := func(t *testing.T) Options {
+		cfg := &types.Config{}
+		cfg.Output.OutputDir = t.TempDir()
+		return Options{
+			Length: 2, Pattern: "d", Suffix: ".test",
+			Workers: 4, Checker: checker, Config: cfg, Output: io.Discard,
+			RetryDelay: time.Millisecond, RetryWorkers: 2,
+		}
+	}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, cancel context.CancelFunc)
+	}{
+		{
+			name: "run",
+			run: func(t *testing.T, ctx context.Context, cancel context.CancelFunc) {
+				if _, err := Run(ctx, options(t)); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "run with retry",
+			run: func(t *testing.T, ctx context.Context, cancel context.CancelFunc) {
+				opts := options(t)
+				opts.RetryRateLimited = true
+				if _, err := Run(ctx, opts); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "cancelled run",
+			run: func(t *testing.T, ctx context.Context, cancel context.CancelFunc) {
+				opts := options(t)
+				opts.Checker = cancelAfter(10, cancel)
+				summary, err := Run(ctx, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !summary.Interrupted {
+					t.Error("Run() did not report the interruption")
+				}
+			},
+		},
+		{
+			name: "cancelled retry",
+			run: func(t *testing.T, ctx context.Context, cancel context.CancelFunc) {
+				opts := options(t)
+				opts.RetryRateLimited = true
+				// The main pass checks 100 domains, the retry pass is cancelled on its tenth check
+				opts.Checker = cancelAfter(110, cancel)
+				if _, err := Run(ctx, opts); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "drained stream",
+			run: func(t *testing.T, ctx context.Context, cancel context.CancelFunc) {
+				// More results than the channel buffers
+				opts := options(t)
+				opts.Length = 4
+				results, err := Stream(ctx, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				n := 0
+				for range results {
+					n++
+				}
+				if n != 10000 {
+					t.Errorf("Stream() gave %d results, want 10000", n)
+				}
+			},
+		},
+		{
+			name: "cancelled stream",
+			run: func(t *testing.T, ctx context.Context, cancel context.CancelFunc) {
+				opts := options(t)
+				opts.Length = 4
+				results, err := Stream(ctx, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				<-results
+				cancel()
+				for range results {
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkNoGoroutineLeak(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			tt.run(t, ctx, cancel)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"domain-scanner/internal/types"
+)
+
+// slowestCount is the number of domains kept in Summary.Slowest
+const slowestCount = 10
+
+// phaseOrder lists the check phases in the order they are printed
+var phaseOrder = []string{types.CheckDNS, types.CheckWHOIS, types.CheckSSL, types.PhaseCustom}
+
+// SlowDomain is the check time of a domain split by phase
+type SlowDomain struct {
+	Domain  string
+	Elapsed time.Duration
+	Phases  map[string]time.Duration
+}
+
+// recordTiming keeps the result in Summary.Slowest when it is among the slowest checks
+func recordTiming(summary *Summary, result types.DomainResult) {
+	if result.Elapsed <= 0 {
+		return
+	}
+	n := len(summary.Slowest)
+	if n == slowestCount && result.Elapsed <= summary.Slowest[n-1].Elapsed {
+		return
+	}
+	i := sort.Search(n, func(i int) bool { return summary.Slowest[i].Elapsed < result.Elapsed })
+	summary.Slowest = append(summary.Slowest, SlowDomain{})
+	copy(summary.Slowest[i+1:], summary.Slowest[i:])
+	summary.Slowest[i] = SlowDomain{Domain: result.Domain, Elapsed: result.Elapsed, Phases: result.Phases}
+	if len(summary.Slowest) > slowestCount {
+		summary.Slowest = summary.Slowest[:slowestCount]
+	}
+}
+
+// FormatPhases describes the time spent per phase, e.g. "dns 120ms, whois 31.2s"
+func FormatPhases(phases map[string]time.Duration) string {
+	var parts []string
+	for _, phase := range phaseOrder {
+		if d, ok := phases[phase]; ok {
+			parts = append(parts, fmt.Sprintf("%s %v", phase, roundDuration(d)))
+		}
+	}
+	if len(parts) == 0 {
+		return "no phases recorded"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// roundDuration rounds a duration for display, keeping milliseconds below a second
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(100 * time.Millisecond)
+}
+
+// PrintSlowest writes the slowest checks of a finished run with their phase breakdown
+func PrintSlowest(out io.Writer, summary *Summary) {
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintf(out, "\nSlowest domains:\n")
+	if len(summary.Slowest) == 0 {
+		fmt.Fprintf(out, "- no timed checks\n")
+		return
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOMAIN\tTOTAL\tDNS\tWHOIS\tSSL\tCUSTOM")
+	for _, slow := range summary.Slowest {
+		fmt.Fprintf(tw, "%s\t%v", slow.Domain, roundDuration(slow.Elapsed))
+		for _, phase := range phaseOrder {
+			if d, ok := slow.Phases[phase]; ok {
+				fmt.Fprintf(tw, "\t%v", roundDuration(d))
+			} else {
+				fmt.Fprint(tw, "\t-")
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// TLDRow is the status of a domain in the TLD table of a reverse mode scan
+type TLDRow struct {
+	TLD    string
+	Domain string
+	Status string
+}
+
+// Free, taken and uncertain rank the rows of a TLD table; uncertain covers special
+// statuses and errors
+const (
+	tldFree = iota
+	tldUncertain
+	tldTaken
+)
+
+// rank returns the availability rank of the row
+func (r TLDRow) rank() int {
+	switch r.Status {
+	case StatusAvailable:
+		return tldFree
+	case StatusRegistered:
+		return tldTaken
+	default:
+		return tldUncertain
+	}
+}
+
+// tldTable lists the checked domains sorted by availability, then TLD
+func tldTable(summary *Summary) []TLDRow {
+	rows := make([]TLDRow, 0, len(summary.Statuses))
+	for name, status := range summary.Statuses {
+		suffix := name
+		if idx := strings.Index(name, "."); idx >= 0 {
+			suffix = name[idx:]
+		}
+		rows = append(rows, TLDRow{TLD: suffix, Domain: name, Status: status})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].rank() != rows[j].rank() {
+			return rows[i].rank() < rows[j].rank()
+		}
+		return rows[i].TLD < rows[j].TLD
+	})
+	return rows
+}
+
+// tldCounts returns the number of free, taken and uncertain rows
+func tldCounts(rows []TLDRow) (free, taken, uncertain int) {
+	for _, row := range rows {
+		switch row.rank() {
+		case tldFree:
+			free++
+		case tldTaken:
+			taken++
+		default:
+			uncertain++
+		}
+	}
+	return free, taken, uncertain
+}
+
+// tldTableLines formats the rows for the TLD table file
+func tldTableLines(rows []TLDRow) []string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%s", row.TLD, row.Domain, row.Status))
+	}
+	return lines
+}
+
+// PrintTLDTable writes the per-TLD availability of a reverse mode scan, available
+// first, followed by the free, taken and uncertain counts across the TLDs
+func PrintTLDTable(out io.Writer, summary *Summary) {
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintf(out, "\nAvailability by TLD:\n")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TLD\tDOMAIN\tSTATUS")
+	for _, row := range summary.TLDTable {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", row.TLD, row.Domain, row.Status)
+	}
+	_ = tw.Flush()
+	free, taken, uncertain := tldCounts(summary.TLDTable)
+	fmt.Fprintf(out, "- Free: %d, taken: %d, uncertain: %d (of %d TLDs)\n", free, taken, uncertain, len(summary.TLDTable))
+}
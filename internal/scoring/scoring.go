@@ -0,0 +1,265 @@
+// Package scoring rates available domains by offline brandability heuristics so that
+// large result lists can be reviewed best first. Scoring needs no network access.
+package scoring
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"domain-scanner/internal/generator"
+	"domain-scanner/internal/types"
+)
+
+// Weights sets how many points each component contributes to the total score.
+// The total is normalized to 0–100, so only the ratios between the weights matter.
+type Weights struct {
+	Length           float64 `toml:"length"`
+	Pronounceability float64 `toml:"pronounceability"`
+	Dictionary       float64 `toml:"dictionary"`
+	Clean            float64 `toml:"clean"`
+	Repetition       float64 `toml:"repetition"`
+	TLD              float64 `toml:"tld"`
+}
+
+// DefaultWeights are used when the config sets no weight
+var DefaultWeights = Weights{
+	Length:           30,
+	Pronounceability: 25,
+	Dictionary:       15,
+	Clean:            10,
+	Repetition:       10,
+	TLD:              10,
+}
+
+// DefaultTLDWeights rate common suffixes when the config lists none; other suffixes get defaultTLDWeight
+var DefaultTLDWeights = map[string]float64{
+	".com": 1.0,
+	".io":  0.8,
+	".ai":  0.8,
+	".net": 0.7,
+	".org": 0.7,
+	".co":  0.7,
+}
+
+// defaultTLDWeight rates suffixes missing from the TLD weights
+const defaultTLDWeight = 0.5
+
+// minPrefixWord is the shortest dictionary word counted as a prefix match
+const minPrefixWord = 3
+
+// Breakdown holds the points each component contributed to a score
+type Breakdown struct {
+	Length           float64 `json:"length"`
+	Pronounceability float64 `json:"pronounceability"`
+	Dictionary       float64 `json:"dictionary"`
+	Clean            float64 `json:"clean"`
+	Repetition       float64 `json:"repetition"`
+	TLD              float64 `json:"tld"`
+}
+
+// Score is the brandability score of a domain
+type Score struct {
+	Domain string `json:"domain"`
+	// Total is the score from 0 to 100, the rounded sum of the breakdown
+	Total     int       `json:"total"`
+	Breakdown Breakdown `json:"breakdown"`
+}
+
+// String renders the score for result files, e.g. "87 length=30.0 pronounceability=25.0 ..."
+func (s Score) String() string {
+	b := s.Breakdown
+	return fmt.Sprintf("%d length=%.1f pronounceability=%.1f dictionary=%.1f clean=%.1f repetition=%.1f tld=%.1f",
+		s.Total, b.Length, b.Pronounceability, b.Dictionary, b.Clean, b.Repetition, b.TLD)
+}
+
+// Scorer computes brandability scores
+type Scorer struct {
+	weights    Weights
+	tldWeights map[string]float64
+	words      map[string]bool
+}
+
+// New creates a scorer. Zero weights select DefaultWeights and empty TLD weights
+// DefaultTLDWeights. Without words the dictionary component is left out of the total.
+func New(weights Weights, tldWeights map[string]float64, words []string) *Scorer {
+	if weights == (Weights{}) {
+		weights = DefaultWeights
+	}
+	if len(tldWeights) == 0 {
+		tldWeights = DefaultTLDWeights
+	}
+	s := &Scorer{
+		weights:    weights,
+		tldWeights: make(map[string]float64, len(tldWeights)),
+		words:      make(map[string]bool, len(words)),
+	}
+	for suffix, weight := range tldWeights {
+		s.tldWeights["."+strings.TrimPrefix(strings.ToLower(suffix), ".")] = weight
+	}
+	for _, word := range words {
+		s.words[strings.ToLower(word)] = true
+	}
+	if len(s.words) == 0 {
+		s.weights.Dictionary = 0
+	}
+	return s
+}
+
+// FromConfig creates the scorer configured in [scoring], loading its dictionary file
+func FromConfig(cfg *types.Config) (*Scorer, error) {
+	if cfg == nil {
+		return New(Weights{}, nil, nil), nil
+	}
+	var words []string
+	if cfg.Scoring.Dictionary != "" {
+		var err error
+		words, err = generator.LoadWordList(cfg.Scoring.Dictionary)
+		if err != nil {
+			return nil, fmt.Errorf("error loading scoring dictionary: %w", err)
+		}
+	}
+	w := cfg.Scoring.Weights
+	weights := Weights{
+		Length:           w.Length,
+		Pronounceability: w.Pronounceability,
+		Dictionary:       w.Dictionary,
+		Clean:            w.Clean,
+		Repetition:       w.Repetition,
+		TLD:              w.TLD,
+	}
+	return New(weights, cfg.Scoring.TLDWeights, words), nil
+}
+
+// Score rates a single domain
+func (s *Scorer) Score(domain string) Score {
+	domain = strings.ToLower(domain)
+	label, suffix := domain, ""
+	if idx := strings.Index(domain, "."); idx >= 0 {
+		label, suffix = domain[:idx], domain[idx:]
+	}
+
+	w := s.weights
+	sum := w.Length + w.Pronounceability + w.Dictionary + w.Clean + w.Repetition + w.TLD
+	if sum <= 0 {
+		return Score{Domain: domain}
+	}
+	points := func(weight, value float64) float64 {
+		return 100 * weight * value / sum
+	}
+
+	tldWeight, ok := s.tldWeights[suffix]
+	if !ok {
+		tldWeight = defaultTLDWeight
+	}
+	b := Breakdown{
+		Length:           points(w.Length, lengthValue(label)),
+		Pronounceability: points(w.Pronounceability, pronounceability(label)),
+		Dictionary:       points(w.Dictionary, s.dictionaryValue(label)),
+		Clean:            points(w.Clean, cleanValue(label)),
+		Repetition:       points(w.Repetition, repetitionValue(label)),
+		TLD:              points(w.TLD, clamp(tldWeight)),
+	}
+	total := b.Length + b.Pronounceability + b.Dictionary + b.Clean + b.Repetition + b.TLD
+	return Score{Domain: domain, Total: int(total + 0.5), Breakdown: b}
+}
+
+// Rank scores the domains and sorts them by descending score, ties alphabetically
+func (s *Scorer) Rank(domains []string) []Score {
+	scores := make([]Score, 0, len(domains))
+	for _, domain := range domains {
+		scores = append(scores, s.Score(domain))
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Total != scores[j].Total {
+			return scores[i].Total > scores[j].Total
+		}
+		return scores[i].Domain < scores[j].Domain
+	})
+	return scores
+}
+
+// lengthValue prefers short names: up to 4 characters score fully, 15 and more score nothing
+func lengthValue(label string) float64 {
+	return clamp(1 - float64(len(label)-4)/11)
+}
+
+// pronounceability is the share of adjacent letter pairs alternating between consonant and vowel
+func pronounceability(label string) float64 {
+	var letters []byte
+	for i := 0; i < len(label); i++ {
+		if label[i] >= 'a' && label[i] <= 'z' {
+			letters = append(letters, label[i])
+		}
+	}
+	if len(letters) < 2 {
+		return 1
+	}
+	alternating := 0
+	for i := 1; i < len(letters); i++ {
+		if isVowel(letters[i]) != isVowel(letters[i-1]) {
+			alternating++
+		}
+	}
+	return float64(alternating) / float64(len(letters)-1)
+}
+
+// dictionaryValue rewards names that are a word (1) or start with one (0.6)
+func (s *Scorer) dictionaryValue(label string) float64 {
+	if s.words[label] {
+		return 1
+	}
+	for end := len(label) - 1; end >= minPrefixWord; end-- {
+		if s.words[label[:end]] {
+			return 0.6
+		}
+	}
+	return 0
+}
+
+// cleanValue penalizes digits and hyphens by half each
+func cleanValue(label string) float64 {
+	value := 1.0
+	if strings.ContainsAny(label, "0123456789") {
+		value -= 0.5
+	}
+	if strings.Contains(label, "-") {
+		value -= 0.5
+	}
+	return value
+}
+
+// repetitionValue penalizes repeated letters; a run of three or more scores nothing
+func repetitionValue(label string) float64 {
+	if len(label) < 2 {
+		return 1
+	}
+	repeats, run := 0, 1
+	for i := 1; i < len(label); i++ {
+		if label[i] != label[i-1] {
+			run = 1
+			continue
+		}
+		repeats++
+		if run++; run >= 3 {
+			return 0
+		}
+	}
+	return 1 - float64(repeats)/float64(len(label)-1)
+}
+
+// isVowel reports whether a lowercase letter is a vowel; y counts as one
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiouy", c) >= 0
+}
+
+// clamp limits a component value to the range 0 to 1
+func clamp(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,153 @@
+package scoring
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"domain-scanner/internal/types"
+)
+
+// near reports whether two component values agree to rounding errors
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestComponents(t *testing.T) {
+	tests := []struct {
+		label            string
+		length           float64
+		pronounceability float64
+		clean            float64
+		repetition       float64
+	}{
+		{label: "tavo", length: 1, pronounceability: 1, clean: 1, repetition: 1},
+		{label: "a", length: 1, pronounceability: 1, clean: 1, repetition: 1},
+		{label: "xkcd", length: 1, pronounceability: 0, clean: 1, repetition: 1},
+		{label: "bookit", length: 1 - 2.0/11, pronounceability: 0.8, clean: 1, repetition: 0.8},
+		{label: "zzz", length: 1, pronounceability: 0, clean: 1, repetition: 0},
+		{label: "go-4u", length: 1 - 1.0/11, pronounceability: 0.5, clean: 0, repetition: 1},
+		{label: "123", length: 1, pronounceability: 1, clean: 0.5, repetition: 1},
+		{label: "supercalifragilistic", length: 0, pronounceability: 16.0 / 19, clean: 1, repetition: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if got := lengthValue(tt.label); !near(got, tt.length) {
+				t.Errorf("lengthValue() = %v, want %v", got, tt.length)
+			}
+			if got := pronounceability(tt.label); !near(got, tt.pronounceability) {
+				t.Errorf("pronounceability() = %v, want %v", got, tt.pronounceability)
+			}
+			if got := cleanValue(tt.label); !near(got, tt.clean) {
+				t.Errorf("cleanValue() = %v, want %v", got, tt.clean)
+			}
+			if got := repetitionValue(tt.label); !near(got, tt.repetition) {
+				t.Errorf("repetitionValue() = %v, want %v", got, tt.repetition)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	plain := New(Weights{}, nil, nil)
+	withWords := New(Weights{}, nil, []string{"Shop", "tavo"})
+	custom := New(Weights{Length: 1, TLD: 1}, map[string]float64{"DE": 1, ".li": 0.2}, nil)
+
+	tests := []struct {
+		name   string
+		scorer *Scorer
+		domain string
+		want   int
+	}{
+		// Without a dictionary its weight is left out: every other component is at
+		// its best, so the name scores 100
+		{name: "perfect name", scorer: plain, domain: "Tavo.com", want: 100},
+		// (30 + 10 + 10 + 10*0.7) / 85
+		{name: "unpronounceable", scorer: plain, domain: "xkcd.net", want: 67},
+		// (30*(1-3/11) + 25 + 10 + 10*0.5) / 85; .de has the default weight
+		{name: "digits and hyphen", scorer: plain, domain: "bo0k-it.de", want: 73},
+		// (30 + 25*2/3 + 15 + 10 + 10 + 10) / 100 with the dictionary counted
+		{name: "dictionary word", scorer: withWords, domain: "shop.com", want: 92},
+		// (30*(1-3/11) + 25*5/6 + 15*0.6 + 10 + 10 + 10) / 100; y counts as a vowel
+		{name: "dictionary prefix", scorer: withWords, domain: "shopify.com", want: 82},
+		// (30*(1-1/11) + 25*0.5 + 10 + 10 + 10) / 100
+		{name: "no dictionary match", scorer: withWords, domain: "xshop.com", want: 70},
+		{name: "custom tld weight", scorer: custom, domain: "abcdefghijklmnop.de", want: 50},
+		{name: "custom low tld weight", scorer: custom, domain: "ab.li", want: 60},
+		{name: "unlisted tld", scorer: custom, domain: "ab.com", want: 75},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := tt.scorer.Score(tt.domain)
+			if score.Total != tt.want {
+				t.Errorf("Score(%q) = %s, want total %d", tt.domain, score, tt.want)
+			}
+			b := score.Breakdown
+			sum := b.Length + b.Pronounceability + b.Dictionary + b.Clean + b.Repetition + b.TLD
+			if int(sum+0.5) != score.Total {
+				t.Errorf("breakdown adds up to %v, total is %d", sum, score.Total)
+			}
+		})
+	}
+}
+
+func TestScoreOnlyDependsOnWeightRatios(t *testing.T) {
+	double := DefaultWeights
+	double.Length *= 2
+	double.Pronounceability *= 2
+	double.Dictionary *= 2
+	double.Clean *= 2
+	double.Repetition *= 2
+	double.TLD *= 2
+	words := []string{"shop"}
+
+	for _, domain := range []string{"tavo.com", "xkcd.net", "shopify.io", "zzz-9.li"} {
+		if a, b := New(DefaultWeights, nil, words).Score(domain), New(double, nil, words).Score(domain); a.Total != b.Total {
+			t.Errorf("%s scores %d with the default weights and %d with doubled ones", domain, a.Total, b.Total)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	scores := New(Weights{}, nil, nil).Rank([]string{"xkcd.net", "tavo.com", "bo0k-it.de", "kavo.com", "zzzzzzzzzzzzzzz.li"})
+	var got []string
+	for _, score := range scores {
+		got = append(got, score.Domain)
+	}
+	// kavo and tavo tie at 100 and are ordered alphabetically
+	want := []string{"kavo.com", "tavo.com", "bo0k-it.de", "xkcd.net", "zzzzzzzzzzzzzzz.li"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Rank() = %v, want %v", got, want)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	dictionary := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(dictionary, []byte("# brand words\nshop\nTavo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg types.Config
+	cfg.Scoring.Dictionary = dictionary
+	cfg.Scoring.Weights.Length = 1
+	cfg.Scoring.Weights.Dictionary = 1
+	scorer, err := FromConfig(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score := scorer.Score("shop.com"); score.Total != 100 {
+		t.Errorf("Score(shop.com) = %s, want 100", score)
+	}
+	if score := scorer.Score("shopify.com"); score.Total != 66 {
+		t.Errorf("Score(shopify.com) = %s, want 66", score)
+	}
+
+	cfg.Scoring.Dictionary = filepath.Join(t.TempDir(), "missing.txt")
+	if _, err := FromConfig(&cfg); err == nil {
+		t.Error("FromConfig() with a missing dictionary = nil error")
+	}
+}
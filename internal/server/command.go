@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"domain-scanner/internal/config"
+	"domain-scanner/internal/types"
+	"domain-scanner/pkg/scanner"
+)
+
+// TokenEnv overrides the [server] auth_token of the config file
+const TokenEnv = "DOMAIN_SCANNER_API_TOKEN"
+
+// RunCommand executes the "serve" subcommand and returns the process exit code
+func RunCommand(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	configPath := fs.String("config", "config/config.toml", "Path to config file")
+	listen := fs.String("listen", "", "Address to listen on (default: [server] listen or :8080)")
+	maxScans := fs.Int("max-scans", 0, "Maximum number of simultaneous scans (default: [server] max_scans or 2)")
+	fs.Usage = func() {
+		fmt.Println("Usage:")
+		fmt.Println("  domain-scanner serve [-listen :8080] [-max-scans N] [-config config.toml]")
+		fmt.Println("\nEndpoints:")
+		fmt.Println("  POST   /scans               Start a scan (JSON body with scan parameters), returns its ID")
+		fmt.Println("  GET    /scans               List scans")
+		fmt.Println("  GET    /scans/{id}          State, progress and summary of a scan")
+		fmt.Println("  GET    /scans/{id}/results  Stream the results as JSON lines")
+		fmt.Println("  DELETE /scans/{id}          Cancel a scan")
+		fmt.Printf("\nSet %s or [server] auth_token to require a bearer token.\n", TokenEnv)
+		fmt.Println("\nOptions:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return scanner.FlagExitCode(err)
+	}
+
+	cfg := types.Config{}
+	if _, err := os.Stat(*configPath); err == nil {
+		loaded, err := config.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Printf("Error loading config file: %v\n", err)
+			return scanner.ExitUsage
+		}
+		cfg = *loaded
+	}
+	s, err := scanner.New(cfg)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return scanner.ExitUsage
+	}
+	cfg = s.Config()
+
+	if *listen == "" {
+		*listen = cfg.Server.Listen
+	}
+	if *maxScans == 0 {
+		*maxScans = cfg.Server.MaxScans
+	}
+	if *maxScans < 1 {
+		fmt.Println("Error: -max-scans must be at least 1")
+		return scanner.ExitUsage
+	}
+	token := cfg.Server.AuthToken
+	if env := os.Getenv(TokenEnv); env != "" {
+		token = env
+	}
+
+	// Ctrl-C cancels the running scans and shuts the server down once they are saved
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	api := New(ctx, s, Options{
+		MaxScans:    *maxScans,
+		MaxWorkers:  cfg.Server.MaxWorkers,
+		Retention:   time.Duration(cfg.Server.ScanRetention) * time.Second,
+		MaxFinished: cfg.Server.MaxFinishedScans,
+		Token:       token,
+		Log:         os.Stdout,
+	})
+	httpServer := &http.Server{
+		Addr:              *listen,
+		Handler:           api.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	auth := "disabled"
+	if token != "" {
+		auth = "bearer token"
+	}
+	fmt.Printf("Serving the scan API on %s (max %d scans, authentication %s)\n", *listen, *maxScans, auth)
+
+	select {
+	case err := <-serveErr:
+		fmt.Printf("Error: %v\n", err)
+		return scanner.ExitUsage
+	case <-ctx.Done():
+	}
+
+	fmt.Println("Shutting down, waiting for running scans to save their results")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("Error: %v\n", err)
+		return scanner.ExitAborted
+	}
+	api.Wait()
+	if err := s.Close(); err != nil {
+		fmt.Printf("Warning: could not send metrics: %v\n", err)
+	}
+	return scanner.ExitOK
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"domain-scanner/pkg/scanner"
+)
+
+// Scan states
+const (
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateCancelled = "cancelled"
+	StateFailed    = "failed"
+)
+
+// ScanRequest is the body of POST /scans; omitted fields keep the server's config values
+type ScanRequest struct {
+	Length      int    `json:"length"`
+	Suffix      string `json:"suffix"`
+	Pattern     string `json:"pattern"`
+	RegexFilter string `json:"regex_filter,omitempty"`
+	RegexMode   string `json:"regex_mode"`
+	Offset      int    `json:"offset,omitempty"`
+	Limit       int    `json:"limit,omitempty"`
+	// Domains checks the given names instead of generating the keyspace
+	Domains []string `json:"domains,omitempty"`
+	// Delay is the pause between two queries of a worker, in milliseconds
+	Delay            int  `json:"delay"`
+	Workers          int  `json:"workers"`
+	ShowRegistered   bool `json:"show_registered"`
+	RetryRateLimited bool `json:"retry_rate_limited"`
+	// Score rates the available domains by brandability in the summary
+	Score bool `json:"score"`
+}
+
+// Progress counts the results received so far
+type Progress struct {
+	Processed int `json:"processed"`
+	Available int `json:"available"`
+	Errors    int `json:"errors"`
+}
+
+// SpecialStatus is a domain that needs manual review
+type SpecialStatus struct {
+	Domain string `json:"domain"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// Summary is the outcome of a finished scan
+type Summary struct {
+	Processed         int                           `json:"processed"`
+	Generated         int                           `json:"generated"`
+	Available         []string                      `json:"available"`
+	RegisteredCount   int                           `json:"registered_count"`
+	Special           []SpecialStatus               `json:"special,omitempty"`
+	Uncertain         int                           `json:"uncertain"`
+	RateLimited       int                           `json:"rate_limited"`
+	Errors            int                           `json:"errors"`
+	Skipped           int                           `json:"skipped"`
+	Interrupted       bool                          `json:"interrupted"`
+	AvailableFile     string                        `json:"available_file,omitempty"`
+	RegisteredFile    string                        `json:"registered_file,omitempty"`
+	SpecialStatusFile string                        `json:"special_status_file,omitempty"`
+	RateLimitRetried  int                           `json:"rate_limit_retried,omitempty"`
+	RateLimitResolved int                           `json:"rate_limit_resolved,omitempty"`
+	TLDStats          map[string]*scanner.TLDStat   `json:"tld_stats,omitempty"`
+	Prices            map[string]scanner.PriceQuote `json:"prices,omitempty"`
+	PricesFile        string                        `json:"prices_file,omitempty"`
+	Scores            []scanner.Score               `json:"scores,omitempty"`
+	ScoresFile        string                        `json:"scores_file,omitempty"`
+}
+
+// ScanStatus is the body of GET /scans/{id}
+type ScanStatus struct {
+	ID         string      `json:"id"`
+	State      string      `json:"state"`
+	CreatedAt  time.Time   `json:"created_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Request    ScanRequest `json:"request"`
+	Progress   Progress    `json:"progress"`
+	Summary    *Summary    `json:"summary,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// scan is a scan started through the API; results are kept in memory
+type scan struct {
+	mu      sync.Mutex
+	status  ScanStatus
+	results []scanner.DomainResult
+	cancel  context.CancelFunc
+	// changed is closed and replaced whenever a result arrives or the state changes
+	changed chan struct{}
+}
+
+// newScan creates a running scan with a random ID
+func newScan(req ScanRequest, cancel context.CancelFunc) *scan {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &scan{
+		status: ScanStatus{
+			ID:        hex.EncodeToString(id),
+			State:     StateRunning,
+			CreatedAt: time.Now().UTC(),
+			Request:   req,
+		},
+		cancel:  cancel,
+		changed: make(chan struct{}),
+	}
+}
+
+// add records a checked domain
+func (s *scan) add(result scanner.DomainResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The raw WHOIS responses would make the results kept in memory grow quickly
+	result.WHOIS = ""
+	s.status.Progress.Processed++
+	switch {
+	case result.Error != nil:
+		s.status.Progress.Errors++
+	case result.Available:
+		s.status.Progress.Available++
+	}
+	s.results = append(s.results, result)
+	s.notify()
+}
+
+// finish records the outcome of the scan
+func (s *scan) finish(summary *scanner.Summary, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	s.status.FinishedAt = &now
+	switch {
+	case err != nil:
+		s.status.State = StateFailed
+		s.status.Error = err.Error()
+	case summary != nil && summary.Interrupted:
+		s.status.State = StateCancelled
+	default:
+		s.status.State = StateCompleted
+	}
+	if summary != nil {
+		s.status.Summary = convertSummary(summary)
+	}
+	s.notify()
+}
+
+// notify wakes up everyone waiting for a change; the caller holds mu
+func (s *scan) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// snapshot returns a copy of the scan status
+func (s *scan) snapshot() ScanStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// finishedAt returns when the scan finished; zero while it runs
+func (s *scan) finishedAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status.FinishedAt == nil {
+		return time.Time{}
+	}
+	return *s.status.FinishedAt
+}
+
+// next returns the results from index from on, whether the scan is finished and a
+// channel that is closed on the next change
+func (s *scan) next(from int) ([]scanner.DomainResult, bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := append([]scanner.DomainResult(nil), s.results[from:]...)
+	return results, s.status.State != StateRunning, s.changed
+}
+
+// convertSummary turns a scanner summary into its API representation
+func convertSummary(summary *scanner.Summary) *Summary {
+	out := &Summary{
+		Processed:         summary.Processed,
+		Generated:         summary.Generated,
+		Available:         summary.Available,
+		RegisteredCount:   summary.RegisteredCount,
+		Uncertain:         summary.Uncertain,
+		RateLimited:       summary.RateLimited,
+		Errors:            summary.Errors,
+		Skipped:           summary.Skipped,
+		Interrupted:       summary.Interrupted,
+		AvailableFile:     summary.AvailableFile,
+		RegisteredFile:    summary.RegisteredFile,
+		SpecialStatusFile: summary.SpecialStatusFile,
+		RateLimitRetried:  summary.RateLimitRetried,
+		RateLimitResolved: summary.RateLimitResolved,
+		TLDStats:          summary.TLDStats,
+		Prices:            summary.Prices,
+		PricesFile:        summary.PricesFile,
+		Scores:            summary.Scores,
+		ScoresFile:        summary.ScoresFile,
+	}
+	if out.Available == nil {
+		out.Available = []string{}
+	}
+	for _, ssd := range summary.Special {
+		out.Special = append(out.Special, SpecialStatus{Domain: ssd.Domain, Status: ssd.Status, Reason: ssd.Reason})
+	}
+	return out
+}
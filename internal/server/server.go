@@ -0,0 +1,370 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"domain-scanner/internal/generator"
+	"domain-scanner/pkg/scanner"
+)
+
+// maxRequestBody limits the size of a POST /scans body
+const maxRequestBody = 1 << 20
+
+// Options configure a Server
+type Options struct {
+	// MaxScans limits the number of scans running at the same time
+	MaxScans int
+	// MaxWorkers is the largest number of workers a scan may use; zero means no limit
+	MaxWorkers int
+	// Retention is how long a finished scan is kept; zero keeps it until MaxFinished drops it
+	Retention time.Duration
+	// MaxFinished is the number of finished scans kept; zero means no limit
+	MaxFinished int
+	// Token, when not empty, requires bearer authentication
+	Token string
+	Log   io.Writer
+}
+
+// Server serves the scan API
+type Server struct {
+	scanner *scanner.Scanner
+	opts    Options
+	// ctx is the parent of every scan; cancelling it stops all scans
+	ctx context.Context
+	// slots holds one entry per running scan
+	slots chan struct{}
+	// running tracks the scans whose goroutine has not returned yet
+	running sync.WaitGroup
+	log     io.Writer
+
+	mu    sync.Mutex
+	scans map[string]*scan
+}
+
+// New creates an API server for a scanner
+func New(ctx context.Context, s *scanner.Scanner, opts Options) *Server {
+	if opts.MaxScans < 1 {
+		opts.MaxScans = 1
+	}
+	if opts.Log == nil {
+		opts.Log = io.Discard
+	}
+	return &Server{
+		scanner: s,
+		opts:    opts,
+		ctx:     ctx,
+		slots:   make(chan struct{}, opts.MaxScans),
+		log:     opts.Log,
+		scans:   make(map[string]*scan),
+	}
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /scans", s.handleCreate)
+	mux.HandleFunc("GET /scans", s.handleList)
+	mux.HandleFunc("GET /scans/{id}", s.handleStatus)
+	mux.HandleFunc("GET /scans/{id}/results", s.handleResults)
+	mux.HandleFunc("DELETE /scans/{id}", s.handleCancel)
+	return s.authenticate(mux)
+}
+
+// Wait blocks until every started scan has finished and saved its results
+func (s *Server) Wait() {
+	s.running.Wait()
+}
+
+// authenticate rejects requests without the configured bearer token
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.opts.Token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="domain-scanner"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleCreate validates the scan parameters and starts the scan in the background
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	req := s.defaultRequest()
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid scan request: %v", err))
+		return
+	}
+	opts, err := s.scanOptions(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("%d scans are already running", cap(s.slots)))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	sc := newScan(req, cancel)
+	s.mu.Lock()
+	s.prune(time.Now())
+	s.scans[sc.status.ID] = sc
+	s.mu.Unlock()
+
+	opts.OnResult = sc.add
+	opts.Prefix = fmt.Sprintf("[scan %s] ", sc.status.ID)
+	if len(req.Domains) > 0 {
+		domains := make(chan string)
+		opts.Domains = domains
+		go func() {
+			defer close(domains)
+			for _, name := range req.Domains {
+				select {
+				case <-ctx.Done():
+					return
+				case domains <- name:
+				}
+			}
+		}()
+	}
+
+	fmt.Fprintf(s.log, "Starting scan %s\n", sc.status.ID)
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer func() { <-s.slots }()
+		defer cancel()
+		summary, err := s.scanner.Run(ctx, opts)
+		sc.finish(summary, err)
+		fmt.Fprintf(s.log, "Scan %s %s\n", sc.status.ID, sc.snapshot().State)
+	}()
+
+	w.Header().Set("Location", "/scans/"+sc.status.ID)
+	writeJSON(w, http.StatusAccepted, sc.snapshot())
+}
+
+// handleList returns the status of every scan, newest first
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.prune(time.Now())
+	statuses := make([]ScanStatus, 0, len(s.scans))
+	for _, sc := range s.scans {
+		statuses = append(statuses, sc.snapshot())
+	}
+	s.mu.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].CreatedAt.After(statuses[j].CreatedAt)
+	})
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// handleStatus returns the state, progress and (once finished) summary of a scan
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	sc := s.lookup(w, r)
+	if sc == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, sc.snapshot())
+}
+
+// handleResults streams the results of a scan as JSON lines, following the scan
+// until it is finished or the client disconnects
+func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
+	sc := s.lookup(w, r)
+	if sc == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	sent := 0
+	for {
+		results, done, changed := sc.next(sent)
+		for _, result := range results {
+			if err := encoder.Encode(result); err != nil {
+				return
+			}
+		}
+		sent += len(results)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		}
+	}
+}
+
+// handleCancel cancels a running scan; checks in flight are finished and the
+// partial results are kept
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	sc := s.lookup(w, r)
+	if sc == nil {
+		return
+	}
+	sc.cancel()
+	writeJSON(w, http.StatusAccepted, sc.snapshot())
+}
+
+// lookup finds the scan named in the request path or writes a 404
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) *scan {
+	s.mu.Lock()
+	s.prune(time.Now())
+	sc := s.scans[r.PathValue("id")]
+	s.mu.Unlock()
+	if sc == nil {
+		writeError(w, http.StatusNotFound, "scan not found")
+	}
+	return sc
+}
+
+// prune drops the finished scans older than the retention period and then the oldest
+// finished scans beyond MaxFinished; running scans are always kept. s.mu must be held.
+func (s *Server) prune(now time.Time) {
+	type finishedScan struct {
+		id string
+		at time.Time
+	}
+	var finished []finishedScan
+	for id, sc := range s.scans {
+		at := sc.finishedAt()
+		if at.IsZero() {
+			continue
+		}
+		if s.opts.Retention > 0 && now.Sub(at) > s.opts.Retention {
+			delete(s.scans, id)
+			continue
+		}
+		finished = append(finished, finishedScan{id: id, at: at})
+	}
+	if s.opts.MaxFinished <= 0 || len(finished) <= s.opts.MaxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].at.After(finished[j].at)
+	})
+	for _, f := range finished[s.opts.MaxFinished:] {
+		delete(s.scans, f.id)
+	}
+}
+
+// defaultRequest returns the scan parameters described by the server's config
+func (s *Server) defaultRequest() ScanRequest {
+	opts := s.scanner.DefaultOptions()
+	// The configured workers are a default that must fit the limit of a request
+	if s.opts.MaxWorkers > 0 && opts.Workers > s.opts.MaxWorkers {
+		opts.Workers = s.opts.MaxWorkers
+	}
+	return ScanRequest{
+		Length:           opts.Length,
+		Suffix:           opts.Suffix,
+		Pattern:          opts.Pattern,
+		RegexFilter:      opts.RegexFilter,
+		RegexMode:        "full",
+		Delay:            int(opts.Delay / time.Millisecond),
+		Workers:          opts.Workers,
+		ShowRegistered:   opts.ShowRegistered,
+		RetryRateLimited: opts.RetryRateLimited,
+		Score:            opts.Score,
+	}
+}
+
+// scanOptions validates a scan request and converts it to scan options
+func (s *Server) scanOptions(req ScanRequest) (scanner.ScanOptions, error) {
+	opts := s.scanner.DefaultOptions()
+	// Keyspace ranges, charsets, excluded characters, name affixes, masks, letter patterns, templates, leet words, numeric ranges, word lists and name lists of the config only apply to the CLI
+	opts.WordLists, opts.InputFile, opts.Charset, opts.ExcludeChars, opts.ExpectedCount = nil, "", "", "", nil
+	opts.NamePrefix, opts.NameSuffix, opts.Mask, opts.Template, opts.WordSeparator = "", "", "", "", ""
+	opts.LetterPattern, opts.Leet, opts.NumRange = "", "", ""
+
+	switch req.RegexMode {
+	case "full":
+		opts.RegexMode = scanner.RegexModeFull
+	case "prefix":
+		opts.RegexMode = scanner.RegexModePrefix
+	default:
+		return opts, fmt.Errorf("invalid regex_mode %q (use \"full\" or \"prefix\")", req.RegexMode)
+	}
+	if len(req.Domains) == 0 {
+		if req.Length < 1 {
+			return opts, fmt.Errorf("length must be at least 1")
+		}
+		if err := generator.ValidatePattern(req.Pattern); err != nil {
+			return opts, err
+		}
+	}
+	if err := generator.ValidateFilter(req.RegexFilter); err != nil {
+		return opts, err
+	}
+	suffix, err := generator.NormalizeSuffix(req.Suffix)
+	if err != nil {
+		return opts, err
+	}
+	if req.Workers < 1 {
+		return opts, fmt.Errorf("workers must be at least 1")
+	}
+	if s.opts.MaxWorkers > 0 && req.Workers > s.opts.MaxWorkers {
+		return opts, fmt.Errorf("workers must be at most %d", s.opts.MaxWorkers)
+	}
+	if req.Delay < 0 || req.Offset < 0 || req.Limit < 0 {
+		return opts, fmt.Errorf("delay, offset and limit must not be negative")
+	}
+
+	opts.Length = req.Length
+	opts.Suffix, opts.Suffixes = suffix, nil
+	opts.Pattern = req.Pattern
+	opts.RegexFilter = req.RegexFilter
+	opts.Offset = req.Offset
+	opts.Limit = req.Limit
+	opts.Delay = time.Duration(req.Delay) * time.Millisecond
+	opts.Workers = req.Workers
+	opts.ShowRegistered = req.ShowRegistered
+	opts.RetryRateLimited = req.RetryRateLimited
+	opts.Score = req.Score
+	opts.WriteFiles = true
+	opts.Log = s.log
+	return opts, nil
+}
+
+// writeJSON writes a JSON response body
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
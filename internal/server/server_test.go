@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"domain-scanner/internal/types"
+	"domain-scanner/pkg/scanner"
+)
+
+// newTestServer creates a server for a scanner with the default config
+func newTestServer(t *testing.T, opts Options) *Server {
+	t.Helper()
+	s, err := scanner.New(types.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return New(context.Background(), s, opts)
+}
+
+func TestScanOptionsMaxWorkers(t *testing.T) {
+	srv := newTestServer(t, Options{MaxWorkers: 4})
+
+	// The configured default of 10 workers is lowered to the limit
+	req := srv.defaultRequest()
+	if req.Workers != 4 {
+		t.Errorf("default workers = %d, want 4", req.Workers)
+	}
+	if _, err := srv.scanOptions(req); err != nil {
+		t.Errorf("scanOptions() with the default request: %v", err)
+	}
+
+	req.Workers = 5
+	if _, err := srv.scanOptions(req); err == nil || !strings.Contains(err.Error(), "at most 4") {
+		t.Errorf("scanOptions() with 5 workers = %v, want the limit error", err)
+	}
+
+	unlimited := newTestServer(t, Options{})
+	req = unlimited.defaultRequest()
+	req.Workers = 1000
+	if _, err := unlimited.scanOptions(req); err != nil {
+		t.Errorf("scanOptions() without a limit: %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	// finished gives the age of each scan's end; negative ages are running scans
+	finished := map[string]time.Duration{
+		"running": -1,
+		"fresh":   time.Minute,
+		"recent":  10 * time.Minute,
+		"older":   30 * time.Minute,
+		"expired": 2 * time.Hour,
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{name: "no limits", opts: Options{}, want: []string{"expired", "fresh", "older", "recent", "running"}},
+		{name: "retention", opts: Options{Retention: time.Hour}, want: []string{"fresh", "older", "recent", "running"}},
+		{name: "max finished", opts: Options{MaxFinished: 2}, want: []string{"fresh", "recent", "running"}},
+		{name: "both", opts: Options{Retention: 20 * time.Minute, MaxFinished: 3}, want: []string{"fresh", "recent", "running"}},
+		{name: "none finished kept", opts: Options{MaxFinished: -1}, want: []string{"expired", "fresh", "older", "recent", "running"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.opts)
+			for id, age := range finished {
+				sc := newScan(ScanRequest{}, func() {})
+				if age >= 0 {
+					at := now.Add(-age)
+					sc.status.FinishedAt = &at
+					sc.status.State = StateCompleted
+				}
+				srv.scans[id] = sc
+			}
+
+			srv.mu.Lock()
+			srv.prune(now)
+			srv.mu.Unlock()
+
+			var kept []string
+			for id := range srv.scans {
+				kept = append(kept, id)
+			}
+			sort.Strings(kept)
+			if got, want := strings.Join(kept, " "), strings.Join(tt.want, " "); got != want {
+				t.Errorf("kept %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Package sqlite writes scan results into a SQLite database, so that large scans can be
+// queried and resumed. It uses a pure Go driver, so the build needs no C toolchain and
+// the scan no sqlite3 command.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"domain-scanner/internal/types"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// BatchSize is the number of results written in one transaction
+	BatchSize = 1000
+	// flushInterval is the longest time a result waits for its transaction, so that an
+	// interrupted scan loses little
+	flushInterval = time.Second
+	// pragmas are set on every connection: WAL lets the resume lookups read while a
+	// batch is written, and the busy timeout waits out other runs sharing the file
+	pragmas = "?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)"
+)
+
+// schema creates the results table; domain is unique, so a domain checked again
+// replaces its earlier row
+const schema = `CREATE TABLE IF NOT EXISTS results (
+  domain TEXT NOT NULL,
+  available INTEGER NOT NULL,
+  signatures TEXT NOT NULL,
+  special_status TEXT NOT NULL,
+  checked_at TEXT NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS results_domain ON results (domain);
+`
+
+// row is a result as it is stored
+type row struct {
+	domain, signatures, specialStatus, checkedAt string
+	available                                    bool
+}
+
+// Writer writes the results of one scan run in batched transactions from a background
+// goroutine; Add only blocks when the database falls a whole queue behind
+type Writer struct {
+	path    string
+	db      *sql.DB
+	insert  *sql.Stmt
+	checked *sql.Stmt
+	report  func(error)
+
+	rows chan row
+	done chan struct{}
+	// mu guards err, the first error, which Close returns
+	mu  sync.Mutex
+	err error
+}
+
+// Open creates the database and its results table if needed and starts the writer.
+// report, when not nil, is called from the writer for each batch that could not be
+// written, so that a failing database shows during the scan rather than at its end.
+func Open(path string, report func(error)) (*Writer, error) {
+	db, err := sql.Open("sqlite", path+pragmas)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", path, err)
+	}
+	// The schema is created here, so that a database that cannot be opened fails before
+	// the scan starts rather than at its end
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating %s: %w", path, err)
+	}
+	insert, err := db.Prepare(`INSERT OR REPLACE INTO results (domain, available, signatures, special_status, checked_at)
+VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error preparing %s: %w", path, err)
+	}
+	checked, err := db.Prepare(`SELECT 1 FROM results WHERE domain = ?`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error preparing %s: %w", path, err)
+	}
+	if report == nil {
+		report = func(error) {}
+	}
+
+	w := &Writer{path: path, db: db, insert: insert, checked: checked, report: report,
+		rows: make(chan row, 4*BatchSize), done: make(chan struct{})}
+	go w.write()
+	return w, nil
+}
+
+// Path returns the database file
+func (w *Writer) Path() string {
+	return w.path
+}
+
+// Add queues a checked domain. Checks without a verdict are left out, so that the
+// table only lists domains whose status is known.
+func (w *Writer) Add(result types.DomainResult) {
+	if w == nil || result.Error != nil {
+		return
+	}
+	signatures := result.Signatures
+	if signatures == nil {
+		signatures = []string{}
+	}
+	encoded, _ := json.Marshal(signatures)
+	w.rows <- row{
+		domain:        result.Domain,
+		available:     result.Available,
+		signatures:    string(encoded),
+		specialStatus: result.SpecialStatus,
+		checkedAt:     time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// Checked reports whether the results table already has a row for the domain
+func (w *Writer) Checked(ctx context.Context, domain string) (bool, error) {
+	var one int
+	switch err := w.checked.QueryRowContext(ctx, domain).Scan(&one); err {
+	case nil:
+		return true, nil
+	case sql.ErrNoRows:
+		return false, nil
+	default:
+		return false, fmt.Errorf("error reading %s: %w", w.path, err)
+	}
+}
+
+// Filter streams the domains that have no row yet, counting the others in skipped, so
+// that a scan run again resumes where the last one stopped; cancelling ctx stops it. A
+// domain whose lookup fails is checked again, and the error is reported like a failed batch.
+func (w *Writer) Filter(ctx context.Context, domains <-chan string, skipped *int64) <-chan string {
+	filtered := make(chan string, 1000)
+	go func() {
+		defer close(filtered)
+		for name := range domains {
+			done, err := w.Checked(ctx, name)
+			if err != nil && ctx.Err() == nil {
+				w.fail(err)
+			}
+			if done {
+				atomic.AddInt64(skipped, 1)
+				continue
+			}
+			select {
+			case filtered <- name:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return filtered
+}
+
+// Close commits the queued results, waits for the database to be written and returns
+// the first error of the run
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	close(w.rows)
+	<-w.done
+	if err := w.db.Close(); err != nil {
+		w.fail(fmt.Errorf("error closing %s: %w", w.path, err))
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// fail reports an error and keeps the first one for Close
+func (w *Writer) fail(err error) {
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.mu.Unlock()
+	w.report(err)
+}
+
+// write commits the queued rows in transactions of up to BatchSize rows, at least
+// every flushInterval. A failed batch is reported and the next one tried anyway, so
+// that a passing problem such as a locked file loses only its own rows.
+func (w *Writer) write() {
+	defer close(w.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []row
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.writeBatch(batch); err != nil {
+			w.fail(fmt.Errorf("error writing %d results to %s: %w", len(batch), w.path, err))
+		}
+		batch = batch[:0]
+	}
+	for open := true; open; {
+		select {
+		case r, ok := <-w.rows:
+			if !ok {
+				open = false
+				break
+			}
+			if batch = append(batch, r); len(batch) >= BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+	flush()
+}
+
+// writeBatch writes one transaction replacing the rows of its domains
+func (w *Writer) writeBatch(batch []row) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+	insert := tx.Stmt(w.insert)
+	for _, r := range batch {
+		if _, err := insert.Exec(r.domain, r.available, r.signatures, r.specialStatus, r.checkedAt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
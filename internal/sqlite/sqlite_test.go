@@ -0,0 +1,177 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"domain-scanner/internal/types"
+)
+
+// readRows returns the stored rows of the database by domain
+func readRows(t *testing.T, path string) map[string]row {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT domain, available, signatures, special_status, checked_at FROM results`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	stored := make(map[string]row)
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.domain, &r.available, &r.signatures, &r.specialStatus, &r.checkedAt); err != nil {
+			t.Fatal(err)
+		}
+		stored[r.domain] = r
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+func TestWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	w, err := Open(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Add(types.DomainResult{Domain: "free.com", Available: true})
+	w.Add(types.DomainResult{Domain: "taken.com", Signatures: []string{"DNS_NS", "WHOIS"}})
+	w.Add(types.DomainResult{Domain: "held.com", SpecialStatus: "REDEMPTIONPERIOD"})
+	w.Add(types.DomainResult{Domain: "failed.com", Error: errors.New("timeout")})
+	w.Add(types.DomainResult{Domain: "o'quote.com", Available: true})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := readRows(t, path)
+	want := map[string]row{
+		"free.com":    {domain: "free.com", available: true, signatures: "[]"},
+		"taken.com":   {domain: "taken.com", signatures: `["DNS_NS","WHOIS"]`},
+		"held.com":    {domain: "held.com", signatures: "[]", specialStatus: "REDEMPTIONPERIOD"},
+		"o'quote.com": {domain: "o'quote.com", available: true, signatures: "[]"},
+	}
+	if len(stored) != len(want) {
+		t.Errorf("stored %d rows, want %d: %v", len(stored), len(want), stored)
+	}
+	for domain, r := range want {
+		got, ok := stored[domain]
+		if !ok {
+			t.Errorf("no row for %s", domain)
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, got.checkedAt); err != nil {
+			t.Errorf("checked_at of %s = %q: %v", domain, got.checkedAt, err)
+		}
+		got.checkedAt = ""
+		if got != r {
+			t.Errorf("row of %s = %+v, want %+v", domain, got, r)
+		}
+	}
+
+	// A second run shares the file and replaces the rows of the domains it checks again
+	w, err = Open(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Add(types.DomainResult{Domain: "free.com", Signatures: []string{"DNS_NS"}})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readRows(t, path)["free.com"]; got.available || got.signatures != `["DNS_NS"]` {
+		t.Errorf("row of free.com after a second run = %+v, want it registered", got)
+	}
+}
+
+func TestWriterFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	w, err := Open(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Add(types.DomainResult{Domain: "a.com", Available: true})
+	w.Add(types.DomainResult{Domain: "c.com"})
+	w.Add(types.DomainResult{Domain: "d.com", Error: errors.New("timeout")})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = Open(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	domains := make(chan string, 4)
+	for _, domain := range []string{"a.com", "b.com", "c.com", "d.com"} {
+		domains <- domain
+	}
+	close(domains)
+
+	var skipped int64
+	var passed []string
+	for domain := range w.Filter(context.Background(), domains, &skipped) {
+		passed = append(passed, domain)
+	}
+	// Domains without a verdict have no row and are checked again
+	if got := strings.Join(passed, " "); got != "b.com d.com" {
+		t.Errorf("Filter() passed %q, want %q", got, "b.com d.com")
+	}
+	if skipped != 2 {
+		t.Errorf("skipped = %d, want 2", skipped)
+	}
+}
+
+func TestWriterReportsFailedBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	reported := make(chan error, 10)
+	w, err := Open(path, func(err error) { reported <- err })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A trigger makes the batch holding rejected.com fail
+	if _, err := w.db.Exec(`CREATE TRIGGER reject BEFORE INSERT ON results WHEN NEW.domain = 'rejected.com'
+BEGIN SELECT RAISE(ABORT, 'rejected'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	w.Add(types.DomainResult{Domain: "rejected.com"})
+	// The failure is reported while the writer runs, not only by Close
+	select {
+	case err := <-reported:
+		if !strings.Contains(err.Error(), "rejected") {
+			t.Errorf("reported error = %v, want the trigger's message", err)
+		}
+	case <-time.After(5 * flushInterval):
+		t.Fatal("the failed batch was not reported")
+	}
+
+	// Later batches are still written
+	w.Add(types.DomainResult{Domain: "kept.com"})
+	if err := w.Close(); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("Close() = %v, want the first batch error", err)
+	}
+	stored := readRows(t, path)
+	if _, ok := stored["kept.com"]; !ok {
+		t.Errorf("kept.com was not written after a failed batch")
+	}
+	if _, ok := stored["rejected.com"]; ok {
+		t.Errorf("rejected.com was written despite the failed batch")
+	}
+}
+
+func TestOpenFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "results.db")
+	if _, err := Open(path, nil); err == nil {
+		t.Errorf("Open(%s) succeeded in a missing directory", path)
+	}
+}
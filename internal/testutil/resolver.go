@@ -0,0 +1,229 @@
+// Package testutil provides fakes of the network services the checks depend on, so
+// that checks can run without network access and with the same answers everywhere.
+package testutil
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Record types answered by Resolver
+const (
+	RecordNS    = "NS"
+	RecordA     = "A"
+	RecordMX    = "MX"
+	RecordTXT   = "TXT"
+	RecordCNAME = "CNAME"
+)
+
+// Resolver is a domain.Resolver answering from programmed records. Names without any
+// record are NXDOMAIN; a wildcard name such as "*.example" answers for every name
+// directly below it that has no records of its own. Names are compared without case
+// and trailing dot.
+type Resolver struct {
+	mu      sync.Mutex
+	ns      map[string][]*net.NS
+	ips     map[string][]net.IP
+	mx      map[string][]*net.MX
+	txt     map[string][]string
+	cname   map[string]string
+	failing map[string]map[string]error
+	queries map[string]int
+}
+
+// NewResolver returns a resolver without records
+func NewResolver() *Resolver {
+	return &Resolver{
+		ns:      make(map[string][]*net.NS),
+		ips:     make(map[string][]net.IP),
+		mx:      make(map[string][]*net.MX),
+		txt:     make(map[string][]string),
+		cname:   make(map[string]string),
+		failing: make(map[string]map[string]error),
+		queries: make(map[string]int),
+	}
+}
+
+// NXDomain returns the error of a lookup for a name that does not exist
+func NXDomain(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+// ServFail returns the error of a lookup the name server failed to answer
+func ServFail(name string) error {
+	return &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+}
+
+// SetNS adds name server records to a name
+func (r *Resolver) SetNS(name string, hosts ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name = canonical(name)
+	for _, host := range hosts {
+		r.ns[name] = append(r.ns[name], &net.NS{Host: canonical(host) + "."})
+	}
+}
+
+// SetA adds address records to a name; IPv6 addresses are accepted as well
+func (r *Resolver) SetA(name string, addrs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name = canonical(name)
+	for _, addr := range addrs {
+		r.ips[name] = append(r.ips[name], net.ParseIP(addr))
+	}
+}
+
+// SetMX adds mail exchanger records to a name
+func (r *Resolver) SetMX(name string, hosts ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name = canonical(name)
+	for i, host := range hosts {
+		r.mx[name] = append(r.mx[name], &net.MX{Host: canonical(host) + ".", Pref: uint16(10 * (i + 1))})
+	}
+}
+
+// SetTXT adds text records to a name
+func (r *Resolver) SetTXT(name string, texts ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name = canonical(name)
+	r.txt[name] = append(r.txt[name], texts...)
+}
+
+// SetCNAME makes a name an alias of target
+func (r *Resolver) SetCNAME(name, target string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cname[canonical(name)] = canonical(target) + "."
+}
+
+// Fail makes lookups of a record type for a name return err, e.g. ServFail(name);
+// an empty record type fails every lookup of the name
+func (r *Resolver) Fail(name, recordType string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name = canonical(name)
+	if r.failing[name] == nil {
+		r.failing[name] = make(map[string]error)
+	}
+	r.failing[name][recordType] = err
+}
+
+// Queries returns the number of lookups of a record type made for a name
+func (r *Resolver) Queries(name, recordType string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.queries[canonical(name)+" "+recordType]
+}
+
+// LookupNS implements domain.Resolver
+func (r *Resolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	var records []*net.NS
+	err := r.lookup(ctx, name, RecordNS, func(name string) bool {
+		records = r.ns[name]
+		return len(records) > 0
+	})
+	return records, err
+}
+
+// LookupIP implements domain.Resolver; network "ip4" and "ip6" select the address family
+func (r *Resolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	var records []net.IP
+	err := r.lookup(ctx, host, RecordA, func(name string) bool {
+		records = nil
+		for _, ip := range r.ips[name] {
+			v4 := ip.To4() != nil
+			if network == "ip" || (network == "ip4" && v4) || (network == "ip6" && !v4) {
+				records = append(records, ip)
+			}
+		}
+		return len(records) > 0
+	})
+	return records, err
+}
+
+// LookupMX implements domain.Resolver
+func (r *Resolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	var records []*net.MX
+	err := r.lookup(ctx, name, RecordMX, func(name string) bool {
+		records = r.mx[name]
+		return len(records) > 0
+	})
+	return records, err
+}
+
+// LookupTXT implements domain.Resolver
+func (r *Resolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	var records []string
+	err := r.lookup(ctx, name, RecordTXT, func(name string) bool {
+		records = r.txt[name]
+		return len(records) > 0
+	})
+	return records, err
+}
+
+// LookupCNAME implements domain.Resolver. Like net.Resolver it returns the name itself
+// when the name exists without an alias.
+func (r *Resolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	var target string
+	err := r.lookup(ctx, host, RecordCNAME, func(name string) bool {
+		if alias, ok := r.cname[name]; ok {
+			target = alias
+			return true
+		}
+		if r.exists(name) {
+			target = canonical(host) + "."
+			return true
+		}
+		return false
+	})
+	return target, err
+}
+
+// lookup counts a query and answers it from the name or its wildcard; found reports
+// whether a name has records of the queried type and stores them
+func (r *Resolver) lookup(ctx context.Context, host, recordType string, found func(name string) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := canonical(host)
+	r.queries[name+" "+recordType]++
+
+	if failing, ok := r.failing[name]; ok {
+		if err, ok := failing[recordType]; ok {
+			return err
+		}
+		if err, ok := failing[""]; ok {
+			return err
+		}
+	}
+	if !r.exists(name) {
+		if idx := strings.Index(name, "."); idx >= 0 && r.exists("*"+name[idx:]) {
+			name = "*" + name[idx:]
+		} else {
+			return NXDomain(host)
+		}
+	}
+	if !found(name) {
+		// The name exists, but has no records of this type
+		return &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return nil
+}
+
+// exists reports whether a name has records of any type; callers hold r.mu
+func (r *Resolver) exists(name string) bool {
+	_, alias := r.cname[name]
+	return len(r.ns[name]) > 0 || len(r.ips[name]) > 0 || len(r.mx[name]) > 0 || len(r.txt[name]) > 0 || alias
+}
+
+// canonical lower-cases a name and removes its trailing dot
+func canonical(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,102 @@
+package testutil
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+)
+
+// WHOISServer is a WHOIS server on a local port that answers every query with the
+// response of a function. Configure Addr as the WHOIS server of a suffix, e.g.
+// WHOISServers{"test": s.Addr()}, to check domains against it.
+type WHOISServer struct {
+	listener net.Listener
+	answer   func(domain string) string
+	wg       sync.WaitGroup
+
+	mu      sync.Mutex
+	queries map[string]int
+}
+
+// NewWHOISServer starts a server answering the query for a domain with answer(domain);
+// the domain is lower-cased
+func NewWHOISServer(answer func(domain string) string) (*WHOISServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &WHOISServer{listener: listener, answer: answer, queries: make(map[string]int)}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// StaticWHOIS answers every query with the same response
+func StaticWHOIS(response string) func(string) string {
+	return func(string) string { return response }
+}
+
+// SequenceWHOIS answers the n-th query for a domain with the n-th response, and every
+// later query with the last one
+func SequenceWHOIS(responses ...string) func(string) string {
+	var mu sync.Mutex
+	queries := make(map[string]int)
+	return func(domain string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		n := queries[domain]
+		queries[domain]++
+		if n >= len(responses) {
+			n = len(responses) - 1
+		}
+		return responses[n]
+	}
+}
+
+// Addr returns the host:port of the server
+func (s *WHOISServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Queries returns the number of queries made for a domain
+func (s *WHOISServer) Queries(domain string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries[strings.ToLower(domain)]
+}
+
+// Close stops the server and waits for the queries being answered
+func (s *WHOISServer) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// serve accepts connections until the listener is closed
+func (s *WHOISServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+// handle answers the single query of a connection and closes it, as WHOIS servers do
+func (s *WHOISServer) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	domain := strings.ToLower(strings.TrimSpace(line))
+	s.mu.Lock()
+	s.queries[domain]++
+	s.mu.Unlock()
+	_, _ = conn.Write([]byte(s.answer(domain)))
+}
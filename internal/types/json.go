@@ -0,0 +1,108 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// SchemaVersion is the version of the JSON form of DomainResult. It is included in
+// every encoded result and changes whenever a field is renamed, removed or changes meaning.
+const SchemaVersion = 1
+
+// domainResultJSON is the canonical JSON form of DomainResult; its keys are those of
+// the DomainResult json tags, which TestDomainResultTags keeps in step
+type domainResultJSON struct {
+	SchemaVersion int      `json:"schema_version"`
+	Domain        string   `json:"domain"`
+	Unicode       string   `json:"unicode,omitempty"`
+	Available     bool     `json:"available"`
+	Error         string   `json:"error,omitempty"`
+	Signatures    []string `json:"signatures,omitempty"`
+	SpecialStatus string   `json:"special_status,omitempty"`
+	WHOIS         string   `json:"whois,omitempty"`
+	DropDate      string   `json:"drop_date,omitempty"`
+	Permutation   string   `json:"permutation,omitempty"`
+	Price         string   `json:"price,omitempty"`
+	Premium       bool     `json:"premium,omitempty"`
+	Confidence    string   `json:"confidence,omitempty"`
+	// ExpiryDate is ExpiryDate in RFC 3339 form
+	ExpiryDate    string `json:"expiry_date,omitempty"`
+	SkippedChecks int    `json:"skipped_checks,omitempty"`
+	// ElapsedMs and PhasesMs are Elapsed and Phases in milliseconds
+	ElapsedMs int64            `json:"elapsed_ms,omitempty"`
+	PhasesMs  map[string]int64 `json:"phases_ms,omitempty"`
+}
+
+// MarshalJSON encodes the result in its canonical form, with the error as its message
+func (r DomainResult) MarshalJSON() ([]byte, error) {
+	doc := domainResultJSON{
+		SchemaVersion: SchemaVersion,
+		Domain:        r.Domain,
+		Unicode:       r.Unicode,
+		Available:     r.Available,
+		Signatures:    r.Signatures,
+		SpecialStatus: r.SpecialStatus,
+		WHOIS:         r.WHOIS,
+		DropDate:      r.DropDate,
+		Permutation:   r.Permutation,
+		Price:         r.Price,
+		Premium:       r.Premium,
+		Confidence:    r.Confidence,
+		SkippedChecks: r.SkippedChecks,
+		ElapsedMs:     r.Elapsed.Milliseconds(),
+	}
+	if !r.ExpiryDate.IsZero() {
+		doc.ExpiryDate = r.ExpiryDate.Format(time.RFC3339)
+	}
+	for phase, d := range r.Phases {
+		if doc.PhasesMs == nil {
+			doc.PhasesMs = make(map[string]int64, len(r.Phases))
+		}
+		doc.PhasesMs[phase] = d.Milliseconds()
+	}
+	if r.Error != nil {
+		doc.Error = r.Error.Error()
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON decodes a result encoded by MarshalJSON; the error only keeps its message
+func (r *DomainResult) UnmarshalJSON(data []byte) error {
+	var doc domainResultJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*r = DomainResult{
+		Domain:        doc.Domain,
+		Unicode:       doc.Unicode,
+		Available:     doc.Available,
+		Signatures:    doc.Signatures,
+		SpecialStatus: doc.SpecialStatus,
+		WHOIS:         doc.WHOIS,
+		DropDate:      doc.DropDate,
+		Permutation:   doc.Permutation,
+		Price:         doc.Price,
+		Premium:       doc.Premium,
+		Confidence:    doc.Confidence,
+		SkippedChecks: doc.SkippedChecks,
+		Elapsed:       time.Duration(doc.ElapsedMs) * time.Millisecond,
+	}
+	if doc.ExpiryDate != "" {
+		expiry, err := time.Parse(time.RFC3339, doc.ExpiryDate)
+		if err != nil {
+			return err
+		}
+		r.ExpiryDate = expiry
+	}
+	for phase, ms := range doc.PhasesMs {
+		if r.Phases == nil {
+			r.Phases = make(map[string]time.Duration, len(doc.PhasesMs))
+		}
+		r.Phases[phase] = time.Duration(ms) * time.Millisecond
+	}
+	if doc.Error != "" {
+		r.Error = errors.New(doc.Error)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// update rewrites the snapshots in testdata/result instead of comparing with them
+var update = flag.Bool("update", false, "rewrite the JSON snapshots")
+
+func TestDomainResultJSONSnapshots(t *testing.T) {
+	tests := []struct {
+		name   string
+		result DomainResult
+	}{
+		{
+			name:   "available",
+			result: DomainResult{Domain: "qxzv.de", Available: true, SkippedChecks: 2, Elapsed: 1534 * time.Millisecond},
+		},
+		{
+			name: "registered",
+			result: DomainResult{
+				Domain:     "example.com",
+				Signatures: []string{"DNS_NS", "WHOIS", "SSL"},
+				WHOIS:      "Domain Name: EXAMPLE.COM\r\nRegistrar: Example Registrar, Inc.\r\n",
+				ExpiryDate: time.Date(2025, 8, 13, 4, 0, 0, 0, time.UTC),
+				Elapsed:    2*time.Second + 250*time.Millisecond,
+				Phases: map[string]time.Duration{
+					"dns":   120 * time.Millisecond,
+					"whois": 2 * time.Second,
+					"ssl":   130 * time.Millisecond,
+				},
+			},
+		},
+		{
+			name: "special-status",
+			result: DomainResult{
+				Domain:        "xn--br-via.de",
+				Unicode:       "bär.de",
+				SpecialStatus: "REDEMPTIONPERIOD",
+				DropDate:      "2024-09-30",
+				Permutation:   "omission",
+			},
+		},
+		{
+			name:   "error",
+			result: DomainResult{Domain: "timeout.li", Error: errors.New("WHOIS query failed: i/o timeout")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.MarshalIndent(tt.result, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = append(data, '\n')
+			path := filepath.Join("testdata", "result", tt.name+".json")
+			if *update {
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, want) {
+				t.Errorf("JSON of %s changed; bump SchemaVersion when it has to, then run go test -update\ngot\n%s\nwant\n%s",
+					tt.name, data, want)
+			}
+
+			// The snapshot decodes to the result, with the error reduced to its message
+			var decoded DomainResult
+			if err := json.Unmarshal(want, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(withoutError(decoded), withoutError(tt.result)) || errorText(decoded.Error) != errorText(tt.result.Error) {
+				t.Errorf("decoded %+v\nwant %+v", decoded, tt.result)
+			}
+		})
+	}
+}
+
+// withoutError returns r without its error, which does not survive the round trip as is
+func withoutError(r DomainResult) DomainResult {
+	r.Error = nil
+	return r
+}
+
+// errorText returns the message of err, or empty without an error
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestDomainResultTags(t *testing.T) {
+	canonical := make(map[string]string)
+	doc := reflect.TypeOf(domainResultJSON{})
+	for i := 0; i < doc.NumField(); i++ {
+		canonical[doc.Field(i).Name] = doc.Field(i).Tag.Get("json")
+	}
+	// Fields encoded in another form are stored under a different name
+	encodedAs := map[string]string{"Elapsed": "ElapsedMs", "Phases": "PhasesMs"}
+
+	result := reflect.TypeOf(DomainResult{})
+	for i := 0; i < result.NumField(); i++ {
+		field := result.Field(i)
+		name := field.Name
+		if encoded, ok := encodedAs[name]; ok {
+			name = encoded
+		}
+		want, ok := canonical[name]
+		if !ok {
+			t.Errorf("DomainResult.%s is missing from the canonical JSON form", field.Name)
+			continue
+		}
+		if got := field.Tag.Get("json"); got != want {
+			t.Errorf("DomainResult.%s has json tag %q, want %q", field.Name, got, want)
+		}
+		delete(canonical, name)
+	}
+	delete(canonical, "SchemaVersion")
+	for name := range canonical {
+		t.Errorf("canonical JSON field %s has no DomainResult field", name)
+	}
+}
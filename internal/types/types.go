@@ -1,12 +1,60 @@
 package types
 
-// DomainResult represents the result of a domain availability check
+import "time"
+
+// DomainResult represents the result of a domain availability check. The json tags
+// name the fields of its canonical JSON form, which MarshalJSON writes with a
+// schema_version versioned by SchemaVersion and empty fields omitted. Error is
+// encoded as its message, ExpiryDate in RFC 3339 form and Elapsed and Phases in
+// milliseconds, hence their different keys.
 type DomainResult struct {
-	Domain       string
-	Available    bool
-	Error        error
-	Signatures   []string
-	SpecialStatus string
+	Domain string `json:"domain"`
+	// Unicode is the Unicode form of an internationalized Domain, which holds its ASCII
+	// (punycode) form, e.g. "bär.de" for "xn--br-via.de"; empty for other domains
+	Unicode       string   `json:"unicode,omitempty"`
+	Available     bool     `json:"available"`
+	Error         error    `json:"error,omitempty"`
+	Signatures    []string `json:"signatures,omitempty"`
+	SpecialStatus string   `json:"special_status,omitempty"`
+	// WHOIS is the raw WHOIS response fetched during the check, if any
+	WHOIS string `json:"whois,omitempty"`
+	// DropDate is the drop date given for the domain by an expiring domain list, if any
+	DropDate string `json:"drop_date,omitempty"`
+	// Permutation is the typo permutation that produced the domain, e.g. "omission" or
+	// "wrong-tld", when checking the typo variants of a domain
+	Permutation string `json:"permutation,omitempty"`
+	// Price is the registration price quoted for an available domain as in the result
+	// files, e.g. "9.73 USD", "premium 120.00 USD" or "price unknown"; empty when not quoted
+	Price string `json:"price,omitempty"`
+	// Premium marks a premium Price
+	Premium bool `json:"premium,omitempty"`
+	// Confidence is ConfidenceMedium for a registered domain known only from indirect
+	// evidence, e.g. Certificate Transparency logs without a verifying check; empty when
+	// the checks decided the domain
+	Confidence string `json:"confidence,omitempty"`
+	// ExpiryDate is the expiration date of a registered domain from its WHOIS response;
+	// zero when unknown
+	ExpiryDate time.Time `json:"expiry_date,omitempty"`
+	// SkippedChecks is the number of check methods left out because earlier ones decided the domain
+	SkippedChecks int `json:"skipped_checks,omitempty"`
+	// Elapsed is the time the check took; Phases splits it by method (CheckDNS, CheckWHOIS
+	// with its retries and backoff, CheckRDAP, CheckSSL, CheckHTTP and PhaseCustom). Both are empty for domains
+	// decided without a check, e.g. by a zone file.
+	Elapsed time.Duration            `json:"elapsed_ms,omitempty"`
+	Phases  map[string]time.Duration `json:"phases_ms,omitempty"`
+}
+
+// WHOISRecord is the structured form of a WHOIS response. Raw is set instead of the
+// parsed fields when the response could not be parsed.
+type WHOISRecord struct {
+	Domain      string   `json:"domain"`
+	Registrar   string   `json:"registrar,omitempty"`
+	Statuses    []string `json:"statuses,omitempty"`
+	NameServers []string `json:"name_servers,omitempty"`
+	Created     string   `json:"created,omitempty"`
+	Updated     string   `json:"updated,omitempty"`
+	Expires     string   `json:"expires,omitempty"`
+	Raw         string   `json:"raw,omitempty"`
 }
 
 // SpecialStatusDomain represents a domain with special status
@@ -24,6 +72,79 @@ const (
 	RegexModePrefix
 )
 
+// DNS A-record policies for TLDs with wildcard DNS
+const (
+	// WildcardAIgnore never counts an A record toward registration
+	WildcardAIgnore = "ignore"
+	// WildcardACombined counts an A record only together with another registration
+	// signal, such as an HTTP answer or an SSL certificate
+	WildcardACombined = "combined"
+)
+
+// Blocklist modes for candidates that match a [domain] blocklist entry
+const (
+	// BlocklistDrop removes matching candidates before they are checked
+	BlocklistDrop = "drop"
+	// BlocklistFlag checks matching candidates but marks them with the TrademarkRisk signature
+	BlocklistFlag = "flag"
+)
+
+// TrademarkRisk is the signature of checked domains that match the blocklist
+const TrademarkRisk = "TRADEMARK_RISK"
+
+// ConfidenceMedium is the DomainResult.Confidence of registrations that no check confirmed
+const ConfidenceMedium = "medium"
+
+// Policies for WHOIS responses that contain both available and registered indicators
+const (
+	// ConflictAvailableWins classifies contradictory responses as available
+	ConflictAvailableWins = "available-wins"
+	// ConflictRegisteredWins classifies contradictory responses as registered
+	ConflictRegisteredWins = "registered-wins"
+	// ConflictUncertain reports contradictory responses as special status for manual review
+	ConflictUncertain = "uncertain"
+)
+
+// Registrar APIs supported for price lookups
+const (
+	PricingPorkbun = "porkbun"
+)
+
+// Policies for result files that already exist when a scan starts
+const (
+	// OutputConflictError refuses to start the scan
+	OutputConflictError = "error"
+	// OutputConflictRename writes to new names with a numeric suffix, e.g. "_2"
+	OutputConflictRename = "rename"
+	// OutputConflictAppend appends the results to the existing files
+	OutputConflictAppend = "append"
+	// OutputConflictOverwrite replaces the existing files
+	OutputConflictOverwrite = "overwrite"
+)
+
+// Built-in check methods, in their default order
+const (
+	CheckDNS   = "dns"
+	CheckRDAP  = "rdap"
+	CheckWHOIS = "whois"
+	CheckSSL   = "ssl"
+	CheckHTTP  = "http"
+)
+
+// PhaseCustom names the time spent in custom check methods in DomainResult.Phases; the
+// phases of the built-in methods are named after them
+const PhaseCustom = "custom"
+
+// PrefilterRawDNS selects the bulk raw NS query prefilter
+const PrefilterRawDNS = "rawdns"
+
+// Metrics exporters
+const (
+	MetricsStatsD     = "statsd"
+	MetricsOTLP       = "otlp"
+	MetricsPrometheus = "prometheus"
+)
+
 // Config represents the application configuration
 type Config struct {
 	Domain struct {
@@ -31,25 +152,301 @@ type Config struct {
 		Suffix      string `toml:"suffix"`
 		Pattern     string `toml:"pattern"`
 		RegexFilter string `toml:"regex_filter"`
+		// Charset generates names from these characters instead of those of the pattern;
+		// Unicode characters generate internationalized names
+		Charset string `toml:"charset"`
+		// ExcludeChars removes these characters from the charset before generating names
+		ExcludeChars string `toml:"exclude_chars"`
+		// NamePrefix and NameSuffix are fixed around the generated characters of every name
+		NamePrefix string `toml:"name_prefix"`
+		NameSuffix string `toml:"name_suffix"`
+		// Mask generates the names of a mask such as "a??9" instead of the length
+		Mask string `toml:"mask"`
+		// LetterPattern generates the names of a repeated-letter pattern such as "AAB"
+		// instead of the length: equal letters take equal characters of the pattern
+		LetterPattern string `toml:"letter_pattern"`
+		// Template generates the names of a consonant/vowel template such as "CVCV"
+		// when the pattern is "template"
+		Template string `toml:"template"`
+		// Syllables is the number of syllables of the names of pattern "pronounceable"
+		Syllables int `toml:"syllables"`
+		// WordLists enables combinator mode: every concatenation of one word
+		// from each list file is checked instead of the length/pattern keyspace
+		WordLists []string `toml:"word_lists"`
+		// WordSeparator joins the words of a combination, e.g. "-"
+		WordSeparator string `toml:"word_separator"`
+		// Leet checks the leetspeak variants of a seed word, e.g. sh0p and 5hop for shop,
+		// instead of the length/pattern keyspace
+		Leet string `toml:"leet"`
+		// LeetTable replaces the built-in substitutions, e.g. {o = "0", a = "4"}; every
+		// substitution also applies the other way round
+		LeetTable map[string]string `toml:"leet_table"`
+		// NumRange checks the integers of a range such as "8000-8999" as names instead
+		// of the length/pattern keyspace; NumPad zero-pads them to a number of digits
+		NumRange string `toml:"num_range"`
+		NumPad   int    `toml:"num_pad"`
+		// MaxNumRange bounds the numbers of NumRange; zero means 10000000
+		MaxNumRange int `toml:"max_num_range"`
+		// InputFile checks the names of a file, one per line, under the suffix
+		// instead of generating them
+		InputFile string `toml:"input_file"`
+		// Offset and Limit restrict generation to the keyspace counter range
+		// [offset, offset+limit); a zero limit means until the end of the keyspace
+		Offset int `toml:"offset"`
+		Limit  int `toml:"limit"`
+		// Blocklist is a file of strings and /regexes/, e.g. trademarks, that candidate
+		// names must not contain; BlocklistMode decides what happens to matches
+		Blocklist     string `toml:"blocklist"`
+		BlocklistMode string `toml:"blocklist_mode"`
 	} `toml:"domain"`
 
 	Scanner struct {
 		Delay         int  `toml:"delay"`
 		Workers       int  `toml:"workers"`
 		ShowRegistered bool `toml:"show_registered"`
+		// TerseTLDs lists suffixes whose registries answer unregistered names
+		// with an empty or minimal WHOIS response instead of a clear indicator
+		TerseTLDs      []string `toml:"terse_tlds"`
+		TerseThreshold int      `toml:"terse_threshold"`
+		// WildcardDNS maps a TLD with wildcard DNS to its A-record policy
+		WildcardDNS map[string]string `toml:"wildcard_dns"`
+		// WHOISServers maps a suffix such as "li", ".de" or "co.uk" to the WHOIS server to
+		// query for it; the longest matching suffix wins
+		WHOISServers map[string]string `toml:"whois_servers"`
+		// RateLimits maps a suffix like WHOISServers to the WHOIS queries per minute
+		// allowed for it across all workers
+		RateLimits map[string]int `toml:"rate_limits"`
+		// RDAPServers maps a suffix like WHOISServers to the RDAP base URL to query for it,
+		// overriding the bootstrap registry
+		RDAPServers map[string]string `toml:"rdap_servers"`
+		// RDAPBootstrap is the URL of the RDAP bootstrap registry; empty uses IANA's
+		RDAPBootstrap string `toml:"rdap_bootstrap"`
+		// WHOISTimeout limits a single WHOIS query, in milliseconds; zero uses the client default
+		WHOISTimeout int `toml:"whois_timeout"`
+		// WHOISConflict decides contradictory WHOIS responses (see the Conflict* policies)
+		WHOISConflict string `toml:"whois_conflict"`
+		// RateLimitRetry rechecks WHOIS_RATE_LIMITED domains at the end of a run,
+		// using a long delay (milliseconds) and few workers
+		RateLimitRetry        bool `toml:"rate_limit_retry"`
+		RateLimitRetryDelay   int  `toml:"rate_limit_retry_delay"`
+		RateLimitRetryWorkers int  `toml:"rate_limit_retry_workers"`
+		// Prefilter classifies candidates in bulk before the full checks; empty disables
+		// it, PrefilterRawDNS sends raw NS queries configured in RawDNS
+		Prefilter string `toml:"prefilter"`
+		RawDNS    struct {
+			Resolvers []string `toml:"resolvers"`
+			// Concurrency is the number of queries in flight
+			Concurrency int `toml:"concurrency"`
+			// Timeout is the wait for an answer in milliseconds before a query is retried
+			// at the next resolver, at most Retries times
+			Timeout     int  `toml:"timeout"`
+			Retries     int  `toml:"retries"`
+			TCPFallback bool `toml:"tcp_fallback"`
+		} `toml:"rawdns"`
+		// CheckOrder is the order of the enabled built-in methods (CheckDNS, CheckRDAP, CheckWHOIS, CheckSSL, CheckHTTP);
+		// methods left out run after the listed ones. Unless Exhaustive is set, the
+		// remaining methods are skipped once the results so far decide the domain.
+		CheckOrder []string `toml:"check_order"`
+		Exhaustive bool     `toml:"exhaustive"`
+		// Strict reports a domain available only on an explicit availability indicator;
+		// domains without one are reported uncertain instead
+		Strict bool `toml:"strict"`
+		// Debug logs how every domain was decided: signatures, WHOIS attempts and responses
+		Debug bool `toml:"debug"`
 		Methods       struct {
 			DNSCheck  bool `toml:"dns_check"`
 			WHOISCheck bool `toml:"whois_check"`
 			SSLCheck  bool `toml:"ssl_check"`
 			HTTPCheck bool `toml:"http_check"`
+			// RDAPCheck queries the TLD's RDAP server; a 404 means available
+			RDAPCheck bool `toml:"rdap_check"`
+			// Custom runs an external command for every domain; see domain.RegisterChecker
+			// for custom methods registered in code
+			Custom struct {
+				Name    string `toml:"name"`
+				Command string `toml:"command"`
+				// Timeout limits a single run of the command, in milliseconds
+				Timeout int `toml:"timeout"`
+			} `toml:"custom"`
 		} `toml:"methods"`
+		// HTTP tunes the shared client used by HTTP-based checks; durations are in milliseconds
+		HTTP struct {
+			DialTimeout           int  `toml:"dial_timeout"`
+			TLSHandshakeTimeout   int  `toml:"tls_handshake_timeout"`
+			ResponseHeaderTimeout int  `toml:"response_header_timeout"`
+			RequestTimeout        int  `toml:"request_timeout"`
+			KeepAlive             int  `toml:"keep_alive"`
+			IdleConnTimeout       int  `toml:"idle_conn_timeout"`
+			MaxIdleConns          int  `toml:"max_idle_conns"`
+			MaxIdleConnsPerHost   int  `toml:"max_idle_conns_per_host"`
+			DisableKeepAlives     bool `toml:"disable_keep_alives"`
+		} `toml:"http"`
+		// DNS configures the resolver of the DNS method
+		DNS struct {
+			// DoHURL sends the lookups to the JSON API of a DNS-over-HTTPS server, e.g.
+			// https://cloudflare-dns.com/dns-query, instead of the system resolver
+			DoHURL string `toml:"doh_url"`
+		} `toml:"dns"`
 	} `toml:"scanner"`
 
 	Output struct {
 		AvailableFile    string `toml:"available_file"`
 		RegisteredFile   string `toml:"registered_file"`
 		SpecialStatusFile string `toml:"special_status_file"`
+		// WHOISJSONFile receives one parsed WHOIS JSON object per registered domain; empty disables it
+		WHOISJSONFile string `toml:"whois_json_file"`
+		// SQLite is a database file that receives every checked domain as a row of its
+		// results table; empty disables it
+		SQLite string `toml:"sqlite"`
+		// SQLiteResume skips the domains that already have a row in the SQLite database,
+		// so that a scan run again resumes where the last one stopped
+		SQLiteResume bool `toml:"sqlite_resume"`
+		// PricesFile receives the registration prices of available domains when [pricing] is enabled
+		PricesFile string `toml:"prices_file"`
+		// ScoresFile receives the brandability scores of available domains, best first
+		ScoresFile string `toml:"scores_file"`
+		// TLDTableFile receives the per-TLD availability of a reverse mode scan
+		TLDTableFile string `toml:"tld_table_file"`
 		OutputDir        string `toml:"output_dir"`
 		Verbose          bool   `toml:"verbose"`
+		// OnConflict decides what a run does when its result files already exist (see
+		// the OutputConflict* policies); empty overwrites them
+		OnConflict string `toml:"on_conflict"`
+
+		// GSheets appends the available domains to a Google Sheets spreadsheet
+		GSheets struct {
+			// CredentialsFile is the service account key file; environment variables are
+			// expanded and empty uses GOOGLE_APPLICATION_CREDENTIALS
+			CredentialsFile string `toml:"credentials_file"`
+			// SpreadsheetID enables publishing when set
+			SpreadsheetID string `toml:"spreadsheet_id"`
+			// Sheet is the name of the sheet the rows are appended to
+			Sheet string `toml:"sheet"`
+			// BatchSize appends rows during the run in batches of this size; zero appends at the end
+			BatchSize int `toml:"batch_size"`
+			// MaxRetries is the number of retries of a throttled or failed append
+			MaxRetries int `toml:"max_retries"`
+		} `toml:"gsheets"`
 	} `toml:"output"`
+
+	// Pricing annotates available domains with registration prices from a registrar API
+	Pricing struct {
+		// Provider selects the registrar API; empty disables price lookups
+		Provider string `toml:"provider"`
+		// APIKeyEnv and SecretAPIKeyEnv name the environment variables holding the API keys
+		APIKeyEnv       string `toml:"api_key_env"`
+		SecretAPIKeyEnv string `toml:"secret_api_key_env"`
+		// CheckPremium queries every domain for its own price and premium flag instead of
+		// using the cached base price of its TLD; requires the API keys
+		CheckPremium bool `toml:"check_premium"`
+		// Interval is the minimum time between two per-domain queries, in milliseconds
+		Interval int `toml:"interval"`
+	} `toml:"pricing"`
+
+	// Scoring rates available domains by offline brandability heuristics
+	Scoring struct {
+		// Enabled scores every run as if -score was given
+		Enabled bool `toml:"enabled"`
+		// Dictionary is a word list file for the dictionary-word component; unset leaves it out
+		Dictionary string `toml:"dictionary"`
+		// Weights set the share of each component in the 0–100 score; all zero uses the defaults
+		Weights struct {
+			Length           float64 `toml:"length"`
+			Pronounceability float64 `toml:"pronounceability"`
+			Dictionary       float64 `toml:"dictionary"`
+			Clean            float64 `toml:"clean"`
+			Repetition       float64 `toml:"repetition"`
+			TLD              float64 `toml:"tld"`
+		} `toml:"weights"`
+		// TLDWeights rate suffixes from 0 to 1, e.g. {".com" = 1.0}; unlisted suffixes get 0.5
+		TLDWeights map[string]float64 `toml:"tld_weights"`
+	} `toml:"scoring"`
+
+	// CT looks domains up in Certificate Transparency logs before the other checks
+	CT struct {
+		// Enabled runs the pre-check in every scan as if -ct was given
+		Enabled bool `toml:"enabled"`
+		// Endpoint is the search URL with a {domain} placeholder; it must answer with
+		// crt.sh-style JSON. Empty uses crt.sh.
+		Endpoint string `toml:"endpoint"`
+		// Interval is the minimum time between two queries and Timeout the limit of one
+		// query, in milliseconds
+		Interval int `toml:"interval"`
+		Timeout  int `toml:"timeout"`
+		// Verify still checks CT hits with DNS, WHOIS and SSL instead of reporting them registered
+		Verify bool `toml:"verify"`
+	} `toml:"ct"`
+
+	// Metrics pushes scan counters and check latencies to a StatsD or OTLP collector,
+	// or serves them for Prometheus
+	Metrics struct {
+		// Exporter selects the collector protocol; empty disables metrics
+		Exporter string `toml:"exporter"`
+		// Prefix is prepended to every metric name
+		Prefix string `toml:"prefix"`
+		StatsD struct {
+			Host string `toml:"host"`
+			Port int    `toml:"port"`
+		} `toml:"statsd"`
+		OTLP struct {
+			// Endpoint is the OTLP/HTTP metrics URL
+			Endpoint string            `toml:"endpoint"`
+			Headers  map[string]string `toml:"headers"`
+			// Interval is the time between two pushes, in milliseconds
+			Interval int `toml:"interval"`
+		} `toml:"otlp"`
+		Prometheus struct {
+			// Addr is the listen address of the /metrics endpoint, e.g. ":9090"
+			Addr string `toml:"addr"`
+		} `toml:"prometheus"`
+	} `toml:"metrics"`
+
+	// Notify alerts about available domains as soon as they are found
+	Notify struct {
+		// Webhook receives a POST request for every available domain
+		Webhook struct {
+			// URL enables the notifications when set
+			URL string `toml:"url"`
+			// Template renders the request body with the fields of notify.Payload, e.g.
+			// {"text": {{json .Domain}}}; empty posts the DomainResult as JSON
+			Template string `toml:"template"`
+			// Filter is a regex the available domain must match to be notified; empty notifies all
+			Filter string `toml:"filter"`
+			// MaxRetries is the number of retries of a failed request
+			MaxRetries int `toml:"max_retries"`
+			// Timeout limits one request, in milliseconds
+			Timeout int `toml:"timeout"`
+		} `toml:"webhook"`
+	} `toml:"notify"`
+
+	// Feed configures the drop feed: watched domains are polled and appended to the
+	// feed file once they become available
+	Feed struct {
+		Watchlist string `toml:"watchlist"`
+		FeedFile  string `toml:"feed_file"`
+		// PollInterval is the pause between two passes over the watchlist, in seconds
+		PollInterval int `toml:"poll_interval"`
+	} `toml:"feed"`
+
+	// Server configures the HTTP API of "domain-scanner serve"
+	Server struct {
+		Listen string `toml:"listen"`
+		// MaxScans is the number of scans that may run at the same time
+		MaxScans int `toml:"max_scans"`
+		// MaxWorkers is the largest number of workers a scan request may ask for
+		MaxWorkers int `toml:"max_workers"`
+		// ScanRetention is how long a finished scan stays listed with its results, in seconds
+		ScanRetention int `toml:"scan_retention"`
+		// MaxFinishedScans is the number of finished scans kept; older ones are dropped first
+		MaxFinishedScans int `toml:"max_finished_scans"`
+		// AuthToken enables bearer token authentication when set
+		AuthToken string `toml:"auth_token"`
+	} `toml:"server"`
+
+	// Batch is set in generated batch configs and enables batch status tracking
+	Batch struct {
+		Name string `toml:"name"`
+		// ExpectedCount is the number of domains the batch should generate; nil when unknown
+		ExpectedCount *int `toml:"expected_count"`
+	} `toml:"batch"`
 }
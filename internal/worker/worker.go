@@ -1,28 +1,74 @@
 package worker
 
 import (
+	"context"
 	"time"
 
 	"domain-scanner/internal/domain"
 	"domain-scanner/internal/types"
 )
 
-// Worker processes domain availability checks
-func Worker(id int, jobs <-chan string, results chan<- types.DomainResult, delay time.Duration) {
+// CheckFunc checks a single domain and reports the result
+type CheckFunc func(ctx context.Context, domainName string) types.DomainResult
+
+// Check is the built-in checker using the configured DNS, WHOIS and SSL methods
+func Check(ctx context.Context, domainName string) types.DomainResult {
+	// One pass over the check methods yields the verdict, the signatures and the WHOIS response
+	check, err := domain.CheckDomainContext(ctx, domainName)
+	return resultOf(domainName, check, err)
+}
+
+// CheckWith returns a check function using a checker of its own instead of the one
+// configured by domain.SetConfig
+func CheckWith(c *domain.Checker) CheckFunc {
+	return func(ctx context.Context, domainName string) types.DomainResult {
+		check, err := c.Check(ctx, domainName)
+		return resultOf(domainName, check, err)
+	}
+}
+
+// resultOf converts the outcome of a checker pass into a domain result
+func resultOf(domainName string, check domain.Result, err error) types.DomainResult {
+	return types.DomainResult{
+		Domain:        domainName,
+		Unicode:       domain.UnicodeOf(domainName),
+		Available:     check.Available,
+		Error:         err,
+		Signatures:    check.Signatures,
+		SpecialStatus: check.SpecialStatus,
+		WHOIS:         check.WHOIS,
+		ExpiryDate:    check.ExpiryDate,
+		SkippedChecks: check.Skipped,
+		Elapsed:       check.Elapsed,
+		Phases:        check.Phases,
+	}
+}
+
+// Worker processes domain availability checks until jobs is closed, waiting for
+// pacer before every check; a nil pacer never waits. Cancelling ctx interrupts the
+// wait and WHOIS retries. A nil check uses the built-in Check.
+func Worker(ctx context.Context, id int, jobs <-chan string, results chan<- types.DomainResult, pacer *domain.Pacer, check CheckFunc) {
+	if check == nil {
+		check = Check
+	}
 	for domainName := range jobs {
-		available, err := domain.CheckDomainAvailability(domainName)
-		signatures, _ := domain.CheckDomainSignatures(domainName)
-		
-		// Check for special status (placeholder for future implementation)
-		specialStatus := ""
-		
-		results <- types.DomainResult{
-			Domain:        domainName,
-			Available:     available,
-			Error:         err,
-			Signatures:    signatures,
-			SpecialStatus: specialStatus,
+		if err := pacer.Wait(ctx, domainName); err != nil {
+			results <- types.DomainResult{Domain: domainName, Error: err}
+			continue
 		}
-		time.Sleep(delay)
+		results <- check(ctx, domainName)
+	}
+}
+
+// NewPacer returns the pacer giving workers sharing it the average rate of workers
+// that each wait delay between two checks, spacing the checks by the WHOIS servers of
+// c (nil: the checker configured by domain.SetConfig); nil when delay is zero
+func NewPacer(delay time.Duration, workers int, c *domain.Checker) *domain.Pacer {
+	if delay <= 0 {
+		return nil
+	}
+	if workers < 1 {
+		workers = 1
 	}
+	return domain.NewPacer(delay/time.Duration(workers), c)
 }
@@ -0,0 +1,59 @@
+package zonefile
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// bloomFilter is a fixed-size bloom filter over strings using double hashing
+type bloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes int
+}
+
+// newBloomFilter sizes a filter for n entries at the false-positive rate p
+func newBloomFilter(n int, p float64) *bloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	size := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if size < 64 {
+		size = 64
+	}
+	hashes := int(math.Round(float64(size) / float64(n) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &bloomFilter{bits: make([]uint64, (size+63)/64), size: size, hashes: hashes}
+}
+
+// positions derives the two base hashes of a key
+func positions(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	// The upper half, made odd, serves as the step so that every probe differs
+	return sum, (sum>>32 | 1)
+}
+
+// add inserts a key
+func (b *bloomFilter) add(key string) {
+	h1, h2 := positions(key)
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.size
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// contains reports whether a key may have been added
+func (b *bloomFilter) contains(key string) bool {
+	h1, h2 := positions(key)
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.size
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
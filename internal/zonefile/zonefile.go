@@ -0,0 +1,168 @@
+// Package zonefile loads the delegated names of TLD zone files (e.g. from ICANN CZDS)
+// so that names present in a zone are classified as registered without any query.
+package zonefile
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Signature marks results classified from a zone file
+const Signature = "ZONEFILE"
+
+// verifyTimeout bounds the NS lookup that confirms a bloom filter hit
+const verifyTimeout = 5 * time.Second
+
+// Zone is the set of labels delegated directly under one suffix. It is either an
+// exact sorted list or, when a false-positive rate is given, a bloom filter whose hits
+// are confirmed with an NS lookup.
+type Zone struct {
+	suffix string
+	labels []string
+	bloom  *bloomFilter
+}
+
+// Load reads the zone files, plain or gzip-compressed, and keeps the labels delegated
+// directly under suffix (".com" keeps "example" of "example.com." but not
+// "ns1.example.com."). keep restricts the labels to the possible candidates of a scan;
+// nil keeps all of them. A positive fpRate stores the labels in a bloom filter with
+// that false-positive rate instead of an exact list, which reads the files twice.
+func Load(paths []string, suffix string, keep func(label string) bool, fpRate float64) (*Zone, error) {
+	suffix = strings.ToLower(suffix)
+	if !strings.HasPrefix(suffix, ".") {
+		suffix = "." + suffix
+	}
+	if fpRate < 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("invalid zone file false-positive rate %v (use 0 for an exact set or a value below 1)", fpRate)
+	}
+	z := &Zone{suffix: suffix}
+
+	if fpRate == 0 {
+		// Zone files are sorted, so repeated lines of a name are skipped as they are read
+		err := z.read(paths, keep, func(label string) {
+			if n := len(z.labels); n == 0 || z.labels[n-1] != label {
+				z.labels = append(z.labels, label)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(z.labels)
+		z.labels = dedupe(z.labels)
+		return z, nil
+	}
+
+	// The filter is sized by a first pass; zone files list most names several times
+	// (one line per NS record), which only makes the filter a little larger
+	count := 0
+	if err := z.read(paths, keep, func(string) { count++ }); err != nil {
+		return nil, err
+	}
+	z.bloom = newBloomFilter(count, fpRate)
+	if err := z.read(paths, keep, z.bloom.add); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// read passes every kept label of the zone files to add
+func (z *Zone) read(paths []string, keep func(string) bool, add func(string)) error {
+	for _, path := range paths {
+		if err := z.readFile(path, keep, add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFile passes the kept labels of one zone file to add
+func (z *Zone) readFile(path string, keep func(string) bool, add func(string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening zone file: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = bufio.NewReaderSize(file, 1<<20)
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("error reading zone file %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		label, ok := z.label(scanner.Text())
+		if ok && (keep == nil || keep(label)) {
+			add(label)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading zone file %s: %w", path, err)
+	}
+	return nil
+}
+
+// label extracts the label of a zone file line owned by a name directly under the suffix
+func (z *Zone) label(line string) (string, bool) {
+	end := strings.IndexAny(line, " \t")
+	if end <= 0 || line[0] == ';' || line[0] == '$' {
+		return "", false
+	}
+	name := strings.ToLower(strings.TrimSuffix(line[:end], "."))
+	label := strings.TrimSuffix(name, z.suffix)
+	if label == name || label == "" || strings.Contains(label, ".") {
+		return "", false
+	}
+	return label, true
+}
+
+// Len returns the number of labels of the exact set, or zero for a bloom filter
+func (z *Zone) Len() int {
+	return len(z.labels)
+}
+
+// Contains reports whether a domain is delegated in the zone. Bloom filter hits are
+// confirmed with an NS lookup; a failed lookup reports false so that the domain is
+// checked normally.
+func (z *Zone) Contains(ctx context.Context, domainName string) bool {
+	name := strings.ToLower(domainName)
+	label := strings.TrimSuffix(name, z.suffix)
+	if label == name || strings.Contains(label, ".") {
+		return false
+	}
+	if z.bloom == nil {
+		i := sort.SearchStrings(z.labels, label)
+		return i < len(z.labels) && z.labels[i] == label
+	}
+	if !z.bloom.contains(label) {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+	records, err := net.DefaultResolver.LookupNS(ctx, name+".")
+	return err == nil && len(records) > 0
+}
+
+// dedupe removes adjacent duplicates from a sorted list
+func dedupe(sorted []string) []string {
+	out := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
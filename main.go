@@ -1,18 +1,28 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
+	"math/rand"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
+	"domain-scanner/internal/batch"
 	"domain-scanner/internal/config"
 	"domain-scanner/internal/domain"
+	"domain-scanner/internal/events"
+	"domain-scanner/internal/feed"
 	"domain-scanner/internal/generator"
+	"domain-scanner/internal/queue"
+	"domain-scanner/internal/server"
 	"domain-scanner/internal/types"
-	"domain-scanner/internal/worker"
+	"domain-scanner/pkg/scanner"
 )
 
 // Create a global variable to hold the config
@@ -26,17 +36,54 @@ var appConfig *types.Config
 
 
 
+// maxReportedProblems limits the invalid rows of an expiring list printed individually
+const maxReportedProblems = 20
+
+// tldCheckTimeout bounds the lookups that find unknown TLDs in reverse mode
+const tldCheckTimeout = 10 * time.Second
+
+// progressBarInterval spaces the redraws of the -progress bar
+const progressBarInterval = 500 * time.Millisecond
+
+// largeScan is the number of domains above which a scan asks for confirmation
+const largeScan = 1_000_000_000
+
+// stringList collects the values of a repeatable flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func printHelp() {
 	fmt.Println("Domain Scanner - A tool to check domain availability")
 	fmt.Println("\nUsage:")
 	fmt.Println("  go run main.go [options]")
 	fmt.Println("\nOptions:")
-	fmt.Println("  -l int      Domain length (default: 3)")
+	fmt.Println("  -l string   Domain length, a range like 2-4 or a list like 2,4 (default: 3)")
 	fmt.Println("  -s string   Domain suffix (default: .li)")
 	fmt.Println("  -p string   Domain pattern:")
 	fmt.Println("              d: Pure numbers (e.g., 123.li)")
 	fmt.Println("              D: Pure letters (e.g., abc.li)")
 	fmt.Println("              a: Alphanumeric (e.g., a1b.li)")
+	fmt.Println("              h: Alphanumeric with hyphens (e.g., a-1.li)")
+	fmt.Println("              template: Names of the -template template")
+	fmt.Println("              pronounceable: Names of -syllables syllables (e.g., tavo.li)")
+	fmt.Println("  -template string  Template for -p template: C consonant, V vowel, L letter, N digit, e.g. CVCV (at most 8)")
+	fmt.Println("  -syllables int  Number of syllables of -p pronounceable names, 1 to 4 (default: 2)")
+	fmt.Println("  -charset string  Characters to generate names from instead of those of -p, e.g. aeiou168;")
+	fmt.Println("                   Unicode characters such as äöü generate internationalized names, checked in punycode")
+	fmt.Println("  -charset-file string  File holding the -charset characters, e.g. a list of CJK characters")
+	fmt.Println("  -exclude-chars string  Characters removed from the charset of -p or -charset, e.g. qv0o")
+	fmt.Println("  -name-prefix string  Fixed start of every generated name, e.g. go checks go + -l characters")
+	fmt.Println("  -name-suffix string  Fixed end of every generated name, e.g. hub checks -l characters + hub")
+	fmt.Println("  -mask string  Name mask instead of -l, e.g. a??9: ? is a character of -p, ?d a digit, ?l a letter")
+	fmt.Println("  -letter-pattern string  Repeated-letter pattern such as AAB, ABB, ABAB or AABB: equal letters are the same character of -p, different letters differ")
 	fmt.Println("  -r string   Regex filter for domain names")
 	fmt.Println("  -regex-mode string Regex matching mode (default: full)")
 	fmt.Println("    full: Match entire domain name")
@@ -44,8 +91,54 @@ func printHelp() {
 	fmt.Println("  -delay int  Delay between queries in milliseconds (default: 1000)")
 	fmt.Println("  -workers int Number of concurrent workers (default: 10)")
 	fmt.Println("  -show-registered Show registered domains in output (default: false)")
+	fmt.Println("  -expiring-before string  Only list registered domains whose WHOIS expiration date is before this date (2025-12-31) or within this window (30d, 72h)")
 	fmt.Println("  -config string  Path to config file (default: config.toml)")
+	fmt.Println("  -words string  Comma-separated word list files; checks every concatenation of one word per list (a single file: word+word from it)")
+	fmt.Println("  -words1 string -words2 string  Word list files of a two-word combination, e.g. colors and animals")
+	fmt.Println("  -keywords string -affixes string  Check every keyword combined with every affix, e.g. getshop and shopify for shop with get and ify")
+	fmt.Println("  -affix-position string  Where the affixes go: prefix, suffix or both (default: both)")
+	fmt.Println("  -keyword-alone  Also check every keyword of -keywords by itself")
+	fmt.Println("  -word-sep string  Separator between the words of a combination, e.g. - for blue-fox")
+	fmt.Println("  -leet string  Check a word and all its leetspeak variants, e.g. shop, sh0p, 5hop, 5h0p (table: [domain] leet_table)")
+	fmt.Println("  -num-range string  Check the integers of a range as names instead of -l and -p, e.g. 8000-8999 (limit: [domain] max_num_range)")
+	fmt.Println("  -num-pad int  Zero-pad the numbers of -num-range to this many digits, e.g. 4 for 0100")
+	fmt.Println("  -i string  File of names to check under -s, one per line, instead of generating them")
+	fmt.Println("  -stdin  Check the names or domains read from standard input as they arrive; bare names get -s appended")
+	fmt.Println("  -input string  File of domains to check, one per line, instead of generating them; - reads standard input")
+	fmt.Println("  -metrics-addr string  Serve Prometheus metrics at /metrics on this address while scanning, e.g. :9090 (overrides [metrics] exporter)")
+	fmt.Println("  -exclude-file string  File of domains to skip without checking, one per line; entries without a suffix such as example skip the name under every suffix")
+	fmt.Println("  -debug  Log how every domain is decided: signatures, WHOIS attempts and responses")
+	fmt.Println("  -dry-run  Only list the domains the scan would check, without any DNS, WHOIS or SSL check, and count them")
+	fmt.Println("  -dry-run-file string  Write the domains of -dry-run to this file instead of printing them")
+	fmt.Println("  -count-only  Only print the exact number of domains the scan would check, after the regex filter, and the first 20 of them")
+	fmt.Println("  -start-from string  Resume the keyspace at this name, e.g. abcx, skipping every earlier one (printed when a scan is interrupted)")
+	fmt.Println("  -shuffle  Generate the keyspace in a pseudorandom order instead of the ascending one, e.g. to sample a huge keyspace")
+	fmt.Println("  -seed int  Seed of -shuffle or -sample; the same seed repeats the order and resumes it with -start-from (default: random, printed)")
+	fmt.Println("  -sample int  Check only this many distinct random domains of the keyspace (of the -r filtered one with -r) and estimate the availability rate")
+	fmt.Println("  -debug-index  Show the generator counter value of each domain (to verify offset/limit ranges)")
+	fmt.Println("  -tld-stats  Show availability statistics per domain suffix")
+	fmt.Println("  -retry-rate-limited  Recheck WHOIS rate-limited domains slowly at the end of the run")
+	fmt.Println("  -retry-delay int  Delay between queries in milliseconds for the rate-limited retry (default: 10000)")
+	fmt.Println("  -retry-workers int  Number of concurrent workers for the rate-limited retry (default: 1)")
+	fmt.Println("  -event-socket string  Stream NDJSON events to a Unix socket the wrapper listens on")
+	fmt.Println("  -event-fd int  Stream NDJSON events to an inherited file descriptor (3 or higher)")
+	fmt.Println("  -progress-interval int  Seconds between progress lines with counts and rate; 0 disables them (default: 30)")
+	fmt.Println("  -progress  Show a progress bar with percentage, throughput and ETA on stderr instead of progress lines")
+	fmt.Println("  -slow-threshold int  Warn about domains whose check takes at least this many seconds, with the time per phase; 0 disables (default: 30)")
+	fmt.Println("  -strict     Only report domains available when WHOIS explicitly says so (\"no match\", \"status: free\"); others become uncertain")
+	fmt.Println("  -verbose    Show the 10 slowest domains with their time per check phase in the summary")
+	fmt.Println("  -retry-file string  Recheck the domains of an earlier special status, uncertain or error output file; repeatable")
+	fmt.Println("  -name string  Reverse mode: check this name under every TLD of -tlds or -tld-list")
+	fmt.Println("  -tlds string  Comma-separated TLDs for -name, e.g. .com,.io,.dev")
+	fmt.Println("  -tld-list string  File with the TLDs for -name, one or more per line")
+	fmt.Println("  -typos string  Check the typo variants of a domain, e.g. exmple.com, examlpe.com, example.net")
 	fmt.Println("  -h          Show help information")
+	fmt.Println("\nExit codes:")
+	fmt.Println("  0  Success")
+	fmt.Println("  1  Usage or config error")
+	fmt.Println("  2  Scan aborted (interrupted, or failed after it started)")
+	fmt.Println("  3  Scan completed, but some domains could not be checked")
+	fmt.Println("  The code and its meaning are also written to summary.json in the output directory.")
 	fmt.Println("\nExamples:")
 	fmt.Println("  1. Check 3-letter .li domains with 20 workers:")
 	fmt.Println("     go run main.go -l 3 -s .li -p D -workers 20")
@@ -61,6 +154,26 @@ func printHelp() {
 	fmt.Println("     go run main.go -l 3 -s .li -p D -r \"^[a-z]{2}\" -regex-mode prefix")
 }
 
+// scanControl carries out the control commands of the event stream
+type scanControl struct {
+	*scanner.Gate
+	cancel context.CancelFunc
+}
+
+func (c scanControl) Stop() {
+	c.cancel()
+}
+
+// printProgress prints a progress line of a running scan
+func printProgress(p scanner.Progress) {
+	total := "?"
+	if p.Total > 0 {
+		total = fmt.Sprint(p.Total)
+	}
+	fmt.Printf("Progress: %d/%s checked, %d available, %d errors, %.1f domains/s, elapsed %v\n",
+		p.Processed, total, p.Available, p.Errors, p.Rate(), p.Elapsed.Round(time.Second))
+}
+
 func showMOTD() {
 	fmt.Println("\033[1;36m") // Cyan color
 	fmt.Println("╔════════════════════════════════════════════════════════════╗")
@@ -79,359 +192,1317 @@ func showMOTD() {
 }
 
 func main() {
+	// Dispatch subcommands before regular flag parsing
+	if len(os.Args) > 1 && os.Args[1] == "batch" {
+		exit(batch.RunCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "feed" {
+		exit(feed.RunCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		exit(server.RunCommand(os.Args[2:]))
+	}
+	exit(run())
+}
+
+// exit ends the process with an exit code. It is the only call of os.Exit: the
+// commands return their code once their files are closed, and exit flushes standard
+// output, which may be a file the output is redirected to.
+func exit(code int) {
+	_ = os.Stdout.Sync()
+	_ = os.Stderr.Sync()
+	os.Exit(code)
+}
+
+// cliFlags holds the command line flags of a scan
+type cliFlags struct {
+	lengthSpec       string
+	suffix           string
+	pattern          string
+	regexFilter      string
+	charset          string
+	charsetFile      string
+	excludeChars     string
+	namePrefix       string
+	nameSuffix       string
+	template         string
+	syllables        int
+	maskFlag         string
+	letterPattern    string
+	delay            int
+	workers          int
+	showRegistered   bool
+	expiringBefore   string
+	configPath       string
+	help             bool
+	regexMode        string
+	words            string
+	words1           string
+	words2           string
+	wordSep          string
+	keywordFile      string
+	affixFile        string
+	affixPosition    string
+	keywordAlone     bool
+	leetWord         string
+	numRange         string
+	numPad           int
+	inputFile        string
+	inputList        string
+	fromStdin        bool
+	dryRun           bool
+	dryRunFile       string
+	countOnly        bool
+	startFromName    string
+	shuffle          bool
+	seed             int64
+	sample           int
+	debugIndex       bool
+	tldStats         bool
+	retryRateLimited bool
+	retryDelay       int
+	retryWorkers     int
+	queueURL         string
+	role             string
+	queueName        string
+	gsheetsTest      bool
+	blocklistMode    string
+	metricsAddr      string
+	excludeFile      string
+	zoneFiles        stringList
+	zoneFP           float64
+	ctCheck          bool
+	ctVerify         bool
+	expiringList     string
+	column           int
+	dateColumn       int
+	delimiter        string
+	skipHeader       bool
+	score            bool
+	eventSocket      string
+	eventFD          int
+	progressInterval int
+	showProgressBar  bool
+	slowThreshold    int
+	debug            bool
+	strict           bool
+	verbose          bool
+	retryFiles       stringList
+	reverseName      string
+	tldsFlag         string
+	tldListPath      string
+	typosOf          string
+}
+
+// defineFlags registers the command line flags of a scan
+func defineFlags() *cliFlags {
+	f := &cliFlags{}
+	flag.StringVar(&f.lengthSpec, "l", "3", "Domain length, a range like 2-4 or a list like 2,4")
+	flag.StringVar(&f.suffix, "s", ".li", "Domain suffix")
+	flag.StringVar(&f.pattern, "p", "D", "Domain pattern (d: numbers, D: letters, a: alphanumeric, h: alphanumeric with hyphens)")
+	flag.StringVar(&f.regexFilter, "r", "", "Regex filter for domain names")
+	flag.StringVar(&f.charset, "charset", "", "Characters to generate names from instead of those of -p, e.g. aeiou168; Unicode characters such as äöü generate internationalized names")
+	flag.StringVar(&f.charsetFile, "charset-file", "", "File holding the -charset characters; white space is ignored")
+	flag.StringVar(&f.excludeChars, "exclude-chars", "", "Characters removed from the charset of -p or -charset before generating names, e.g. qv0o")
+	flag.StringVar(&f.namePrefix, "name-prefix", "", "Fixed start of every generated name; -l counts the generated characters only")
+	flag.StringVar(&f.nameSuffix, "name-suffix", "", "Fixed end of every generated name; -l counts the generated characters only")
+	flag.StringVar(&f.template, "template", "", "Template for -p template: C consonant, V vowel, L letter, N digit, e.g. CVCV")
+	flag.IntVar(&f.syllables, "syllables", generator.DefaultSyllables, "Number of syllables of -p pronounceable names (1 to 4)")
+	flag.StringVar(&f.maskFlag, "mask", "", "Name mask instead of -l, e.g. a??9: ? is a character of -p, ?d a digit, ?l a letter")
+	flag.StringVar(&f.letterPattern, "letter-pattern", "", "Repeated-letter pattern such as AAB or ABAB: equal letters are the same character of -p, different letters differ")
+	flag.IntVar(&f.delay, "delay", 1000, "Delay between queries in milliseconds")
+	flag.IntVar(&f.workers, "workers", 10, "Number of concurrent workers")
+	flag.BoolVar(&f.showRegistered, "show-registered", false, "Show registered domains in output")
+	flag.StringVar(&f.expiringBefore, "expiring-before", "", "Only list registered domains whose WHOIS expiration date is before this date (2025-12-31) or within this window (30d, 72h); implies -show-registered")
+	flag.StringVar(&f.configPath, "config", "config/config.toml", "Path to config file")
+	flag.BoolVar(&f.help, "h", false, "Show help information")
+	flag.StringVar(&f.regexMode, "regex-mode", "full", "Regex match mode: 'full' or 'prefix'")
+	flag.StringVar(&f.words, "words", "", "Comma-separated word list files; checks every concatenation of one word per list (a single file: word+word from it)")
+	flag.StringVar(&f.words1, "words1", "", "First word list file of a two-word combination; use with -words2")
+	flag.StringVar(&f.words2, "words2", "", "Second word list file of a two-word combination; use with -words1")
+	flag.StringVar(&f.wordSep, "word-sep", "", "Separator between the words of a combination, e.g. - for blue-fox")
+	flag.StringVar(&f.keywordFile, "keywords", "", "File of keywords to combine with the affixes of -affixes, one per line, e.g. shop")
+	flag.StringVar(&f.affixFile, "affixes", "", "File of popular prefixes and suffixes combined with every keyword of -keywords, e.g. get and ify")
+	flag.StringVar(&f.affixPosition, "affix-position", generator.AffixBoth, "Where the affixes of -affixes go: prefix (getshop), suffix (shopify) or both")
+	flag.BoolVar(&f.keywordAlone, "keyword-alone", false, "Also check every keyword of -keywords by itself")
+	flag.StringVar(&f.leetWord, "leet", "", "Check a word and all its leetspeak variants, e.g. shop, sh0p, 5hop and 5h0p")
+	flag.StringVar(&f.numRange, "num-range", "", "Check the integers of a range such as 8000-8999 as names instead of -l and -p")
+	flag.IntVar(&f.numPad, "num-pad", 0, "Zero-pad the numbers of -num-range to this many digits, e.g. 4 for 0100")
+	flag.StringVar(&f.inputFile, "i", "", "File of names to check under -s, one per line, instead of generating them")
+	flag.StringVar(&f.inputList, "input", "", "File of domains to check, one per line, instead of generating them (- for standard input); -l and -p are ignored")
+	flag.BoolVar(&f.fromStdin, "stdin", false, "Check the names or domains read from standard input as they arrive; bare names get -s appended")
+	flag.BoolVar(&f.dryRun, "dry-run", false, "Only list the domains the scan would check, without checking them, and count them")
+	flag.StringVar(&f.dryRunFile, "dry-run-file", "", "Write the domains of -dry-run to this file instead of printing them")
+	flag.BoolVar(&f.countOnly, "count-only", false, "Only print the exact number of domains the scan would check, after the regex filter, and the first 20 of them")
+	flag.StringVar(&f.startFromName, "start-from", "", "Resume the keyspace at this name, e.g. abcx, skipping every earlier one")
+	flag.BoolVar(&f.shuffle, "shuffle", false, "Generate the keyspace in a pseudorandom order instead of the ascending one")
+	flag.Int64Var(&f.seed, "seed", 0, "Seed of -shuffle or -sample to repeat or resume a shuffled scan (default: random, printed)")
+	flag.IntVar(&f.sample, "sample", 0, "Check only this many random domains of the keyspace and estimate its availability rate")
+	flag.BoolVar(&f.debugIndex, "debug-index", false, "Show the generator counter value of each domain in the progress output")
+	flag.BoolVar(&f.tldStats, "tld-stats", false, "Show availability statistics per domain suffix")
+	flag.BoolVar(&f.retryRateLimited, "retry-rate-limited", false, "Recheck WHOIS rate-limited domains slowly at the end of the run")
+	flag.IntVar(&f.retryDelay, "retry-delay", 10000, "Delay between queries in milliseconds for the rate-limited retry")
+	flag.IntVar(&f.retryWorkers, "retry-workers", 1, "Number of concurrent workers for the rate-limited retry")
+	flag.StringVar(&f.queueURL, "queue", "", "Redis URL of a distributed scan, e.g. redis://host:6379/0")
+	flag.StringVar(&f.role, "role", "", "Role in a distributed scan: producer, consumer or collector")
+	flag.StringVar(&f.queueName, "queue-name", "default", "Name of the distributed scan, to run several on one Redis")
+	flag.BoolVar(&f.gsheetsTest, "gsheets-test", false, "Append a test row to the [output.gsheets] spreadsheet and exit")
+	flag.StringVar(&f.blocklistMode, "blocklist-mode", "", "What to do with candidates matching the [domain] blocklist: 'drop' or 'flag' (default from config)")
+	flag.StringVar(&f.metricsAddr, "metrics-addr", "", "Serve Prometheus metrics at /metrics on this address while scanning, e.g. :9090")
+	flag.StringVar(&f.excludeFile, "exclude-file", "", "File of domains (or names, under every suffix) to skip without checking, one per line")
+	flag.Var(&f.zoneFiles, "zonefile", "Zone file (plain or .gz) whose names are registered without checking; repeatable")
+	flag.Float64Var(&f.zoneFP, "zonefile-fp", 0, "Load zone files into a bloom filter with this false-positive rate instead of an exact set")
+	flag.BoolVar(&f.ctCheck, "ct", false, "Look domains up in Certificate Transparency logs before checking them")
+	flag.BoolVar(&f.ctVerify, "ct-verify", false, "Still check domains found in Certificate Transparency logs with DNS/WHOIS/SSL")
+	flag.StringVar(&f.expiringList, "expiring-list", "", "CSV file of expiring domains to check instead of generating names; filtered by -s (comma-separated) and -r")
+	flag.IntVar(&f.column, "column", 1, "Column of the domain name in the -expiring-list file (starting at 1)")
+	flag.IntVar(&f.dateColumn, "date-column", 0, "Column of the drop date in the -expiring-list file; 0 for none")
+	flag.StringVar(&f.delimiter, "delimiter", ",", "Field delimiter of the -expiring-list file (use \\t for tabs)")
+	flag.BoolVar(&f.skipHeader, "skip-header", false, "Skip the first row of the -expiring-list file")
+	flag.BoolVar(&f.score, "score", false, "Rate available domains by brandability (0-100) and list them best first")
+	flag.StringVar(&f.eventSocket, "event-socket", "", "Unix socket, listened on by a wrapper, to stream NDJSON events to and read control commands from")
+	flag.IntVar(&f.eventFD, "event-fd", 0, "Inherited file descriptor (3 or higher) to stream NDJSON events to; a socket also accepts control commands")
+	flag.IntVar(&f.progressInterval, "progress-interval", 30, "Seconds between progress lines with counts and rate; 0 disables them")
+	flag.BoolVar(&f.showProgressBar, "progress", false, "Show a progress bar with percentage, throughput and ETA on stderr instead of progress lines")
+	flag.IntVar(&f.slowThreshold, "slow-threshold", 30, "Warn about domains whose check takes at least this many seconds; 0 disables the warnings")
+	flag.BoolVar(&f.debug, "debug", false, "Log how every domain is decided (signatures, WHOIS attempts and responses)")
+	flag.BoolVar(&f.strict, "strict", false, "Only report domains available on an explicit availability indicator; others become uncertain")
+	flag.BoolVar(&f.verbose, "verbose", false, "Add the slowest domains with their time per check phase to the summary")
+	flag.Var(&f.retryFiles, "retry-file", "Special status, uncertain or error output file whose domains are rechecked instead of generating names; repeatable")
+	flag.StringVar(&f.reverseName, "name", "", "Check this name under every TLD of -tlds or -tld-list instead of generating names")
+	flag.StringVar(&f.tldsFlag, "tlds", "", "Comma-separated TLDs checked with -name")
+	flag.StringVar(&f.tldListPath, "tld-list", "", "File of TLDs checked with -name, one or more per line")
+	flag.StringVar(&f.typosOf, "typos", "", "Check the omission, repetition, adjacent-key, transposition and wrong-TLD typo variants of this domain")
+	return f
+}
+
+// run scans with the command line flags and returns the process exit code. Every exit
+// path returns here instead of calling os.Exit, so deferred cleanup such as releasing
+// the batch lock always runs.
+func run() int {
 	// Show MOTD
 	showMOTD()
 
-	// Define command line flags
-	length := flag.Int("l", 3, "Domain length")
-	suffix := flag.String("s", ".li", "Domain suffix")
-	pattern := flag.String("p", "D", "Domain pattern (d: numbers, D: letters, a: alphanumeric)")
-	regexFilter := flag.String("r", "", "Regex filter for domain names")
-	delay := flag.Int("delay", 1000, "Delay between queries in milliseconds")
-	workers := flag.Int("workers", 10, "Number of concurrent workers")
-	showRegistered := flag.Bool("show-registered", false, "Show registered domains in output")
-	configPath := flag.String("config", "config/config.toml", "Path to config file")
-	help := flag.Bool("h", false, "Show help information")
-	regexMode := flag.String("regex-mode", "full", "Regex match mode: 'full' or 'prefix'")
-	flag.Parse()
-
-	if *help {
+	f := defineFlags()
+	// Invalid flags are usage errors instead of the exit code 2 of flag.ExitOnError
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		return scanner.FlagExitCode(err)
+	}
+
+	if f.help {
 		printHelp()
-		os.Exit(0)
+		return scanner.ExitOK
 	}
 
 	// Load config file if specified and exists
-	if *configPath != "" {
-		if _, err := os.Stat(*configPath); err == nil {
+	if f.configPath != "" {
+		if _, err := os.Stat(f.configPath); err == nil {
 			var err error
-			appConfig, err = config.LoadConfig(*configPath)
+			appConfig, err = config.LoadConfig(f.configPath)
 			if err != nil {
 				fmt.Printf("Error loading config file: %v\n", err)
-				os.Exit(1)
-			}
-
-			// Set global config for domain checker
-			domain.SetConfig(appConfig)
-
-			// Override command line flags with config values only if they weren't explicitly set
-			if flag.Lookup("l").Value.String() == "3" { // Default value
-				*length = appConfig.Domain.Length
-			}
-			if flag.Lookup("s").Value.String() == ".li" { // Default value
-				*suffix = appConfig.Domain.Suffix
-			}
-			if flag.Lookup("p").Value.String() == "D" { // Default value
-				*pattern = appConfig.Domain.Pattern
-			}
-			if *regexFilter == "" && appConfig.Domain.RegexFilter != "" {
-				*regexFilter = appConfig.Domain.RegexFilter
-			}
-			if flag.Lookup("delay").Value.String() == "1000" { // Default value
-				*delay = appConfig.Scanner.Delay
-			}
-			if flag.Lookup("workers").Value.String() == "10" { // Default value
-				*workers = appConfig.Scanner.Workers
-			}
-			if flag.Lookup("show-registered").Value.String() == "false" { // Default value
-				*showRegistered = appConfig.Scanner.ShowRegistered
+				return scanner.ExitUsage
 			}
+			f.applyConfig(appConfig)
 		} else {
-			fmt.Printf("Config file %s not found, using command line parameters\n", *configPath)
+			fmt.Printf("Config file %s not found, using command line parameters\n", f.configPath)
 		}
 	}
 
-	// Ensure suffix starts with a dot
-	if !strings.HasPrefix(*suffix, ".") {
-		*suffix = "." + *suffix
+	ks, err := validateKeyspace(f)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return scanner.ExitUsage
+	}
+	in, err := loadInputs(f, ks)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return scanner.ExitUsage
 	}
 
-	// Determine regex mode
-	var regexModeEnum types.RegexMode
-	if *regexMode == "full" {
-		regexModeEnum = types.RegexModeFull
-	} else if *regexMode == "prefix" {
-		regexModeEnum = types.RegexModePrefix
-	} else {
-		fmt.Println("Invalid regex-mode. Use 'full' or 'prefix'")
-		os.Exit(1)
+	// Configs without a file still get the defaults a loaded config would have
+	scanConfig := types.Config{}
+	if appConfig != nil {
+		scanConfig = *appConfig
+	}
+	scanConfig.Scanner.Strict = f.strict
+	scanConfig.Scanner.Debug = f.debug
+	if !ks.expiryCutoff.IsZero() {
+		// WHOIS runs first so that DNS records do not decide registered domains without it
+		scanConfig.Scanner.CheckOrder = append([]string{types.CheckWHOIS}, scanConfig.Scanner.CheckOrder...)
+	}
+	if f.metricsAddr != "" {
+		scanConfig.Metrics.Exporter = types.MetricsPrometheus
+		scanConfig.Metrics.Prometheus.Addr = f.metricsAddr
+	}
+	domainScanner, err := scanner.New(scanConfig)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return scanner.ExitUsage
+	}
+	if f.metricsAddr != "" {
+		fmt.Printf("Serving Prometheus metrics at http://%s/metrics\n", f.metricsAddr)
 	}
 
-	domainChan := generator.GenerateDomains(*length, *suffix, *pattern, *regexFilter, regexModeEnum)
-	availableDomains := []string{}
-	registeredDomains := []string{}
-	specialStatusDomains := []string{}
+	// Ctrl-C stops dispatching new domains and saves the partial results
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Calculate total domains count (base count, may be reduced by regex filter)
-	baseDomainCount := generator.CalculateDomainsCount(*length, *pattern)
-	fmt.Printf("Checking domains with pattern %s and length %d using %d workers...\n",
-		*pattern, *length, *workers)
-	if *regexFilter != "" {
-		fmt.Printf("Using regex filter: %s (domain space: %d)\n", *regexFilter, baseDomainCount)
-	} else {
-		fmt.Printf("Total domains to check: %d\n", baseDomainCount)
-	}
-
-	// Create channels for jobs and results
-	jobs := make(chan string, 1000)
-	results := make(chan types.DomainResult, 1000)
-
-	// Start workers
-	for w := 1; w <= *workers; w++ {
-		go worker.Worker(w, jobs, results, time.Duration(*delay)*time.Millisecond)
-	}
-
-	// Send jobs from domain generator
-	var totalGenerated int
-	go func() {
-		defer close(jobs)
-		domainCount := 0
-		for domain := range domainChan {
-			domainCount++
-			jobs <- domain
-		}
-		totalGenerated = domainCount
-		fmt.Printf("Total domains to process: %d\n", domainCount)
-	}()
-
-	// Create a channel for domain status messages
-	statusChan := make(chan string, 1000)
-
-	// Start a goroutine to print status messages and capture special status
-	go func() {
-		for msg := range statusChan {
-			// Check for special status messages
-			if strings.HasPrefix(msg, "SPECIAL STATUS:") {
-				// Extract domain from special status message
-				parts := strings.Split(msg, " ")
-				if len(parts) >= 3 {
-					domain := parts[2]
-					specialStatusDomains = append(specialStatusDomains, domain)
-				}
-			}
-			fmt.Println(msg)
-		}
-	}()
-
-	// Collect results
-	var wg sync.WaitGroup
-	var totalProcessed int
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		processedCount := 0
-		for result := range results {
-			processedCount++
-			totalProcessed = processedCount // Update global counter
-
-			// Show progress - wait a bit for totalGenerated to be set
-			var progress string
-			if totalGenerated > 0 {
-				progress = fmt.Sprintf("[%d/%d]", processedCount, totalGenerated)
-			} else {
-				progress = fmt.Sprintf("[%d]", processedCount)
-			}
+	if f.gsheetsTest {
+		if err := domainScanner.TestSheets(ctx); err != nil {
+			fmt.Printf("Google Sheets test failed: %v\n", err)
+			return scanner.ExitUsage
+		}
+		fmt.Println("Google Sheets test row appended")
+		return scanner.ExitOK
+	}
 
-			if result.Error != nil {
-				statusChan <- fmt.Sprintf("%s Error checking domain %s: %v", progress, result.Domain, result.Error)
-				continue
-			}
+	scanOptions := buildScanOptions(f, ks, in, scanConfig)
+	// The progress bar replaces the progress lines; messages clear and redraw it
+	var progressBar *scanner.ProgressBar
+	if f.showProgressBar {
+		progressBar = scanner.NewProgressBar(os.Stderr)
+		scanOptions.Log = progressBar.Writer(os.Stdout)
+		scanOptions.ProgressInterval = progressBarInterval
+		scanOptions.OnProgress = progressBar.Update
+	}
 
-			if result.Available {
-				statusChan <- fmt.Sprintf("%s Domain %s is AVAILABLE!", progress, result.Domain)
-				availableDomains = append(availableDomains, result.Domain)
-			} else {
-				// Always count registered domains, but only show if requested
-				if *showRegistered {
-					sigStr := strings.Join(result.Signatures, ", ")
-					statusChan <- fmt.Sprintf("%s Domain %s is REGISTERED [%s]", progress, result.Domain, sigStr)
-					registeredDomains = append(registeredDomains, result.Domain)
-				}
-			}
+	// A wrapper process supervises the scan through the event stream
+	if f.eventSocket != "" || f.eventFD != 0 {
+		var closeStream func()
+		if ctx, closeStream, err = attachEvents(ctx, f, &scanOptions); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return scanner.ExitUsage
 		}
-		close(statusChan)
-	}()
+		defer closeStream()
+	}
 
-	// Monitor task completion
-	go func() {
-		// Wait for all jobs to be sent
-		for totalGenerated == 0 {
-			time.Sleep(100 * time.Millisecond)
-		}
+	closeInput, err := attachDomains(ctx, f, ks, in, &scanOptions)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return scanner.ExitUsage
+	}
+	defer closeInput()
 
-		// Wait for all results to be processed
-		for totalProcessed < totalGenerated {
-			time.Sleep(100 * time.Millisecond)
-		}
+	// A dry run lists the domains without checking them; nothing touches the network
+	if f.dryRun && f.countOnly {
+		fmt.Println("Error: -count-only cannot be combined with -dry-run")
+		return scanner.ExitUsage
+	}
+	if f.dryRun || f.countOnly {
+		return preview(ctx, f, domainScanner, scanOptions)
+	}
+	if f.dryRunFile != "" {
+		fmt.Println("Error: -dry-run-file requires -dry-run")
+		return scanner.ExitUsage
+	}
 
-		// Give a bit more time for final processing
-		time.Sleep(1 * time.Second)
-		close(results)
-	}()
+	summary, code := scan(ctx, f, domainScanner, scanOptions, progressBar)
+	if summary == nil {
+		return code
+	}
+	printResults(f, in, scanOptions, summary)
+	return writeSummary(domainScanner, summary, summary.ExitCode())
+}
 
-	wg.Wait()
+// applyConfig overrides the flags left at their defaults with the values of a config
+func (f *cliFlags) applyConfig(cfg *types.Config) {
+	if flag.Lookup("l").Value.String() == "3" { // Default value
+		f.lengthSpec = fmt.Sprint(cfg.Domain.Length)
+	}
+	if flag.Lookup("s").Value.String() == ".li" { // Default value
+		f.suffix = cfg.Domain.Suffix
+	}
+	if flag.Lookup("p").Value.String() == "D" { // Default value
+		f.pattern = cfg.Domain.Pattern
+	}
+	if f.regexFilter == "" && cfg.Domain.RegexFilter != "" {
+		f.regexFilter = cfg.Domain.RegexFilter
+	}
+	if f.charset == "" && cfg.Domain.Charset != "" {
+		f.charset = cfg.Domain.Charset
+	}
+	if f.excludeChars == "" && cfg.Domain.ExcludeChars != "" {
+		f.excludeChars = cfg.Domain.ExcludeChars
+	}
+	if f.namePrefix == "" && cfg.Domain.NamePrefix != "" {
+		f.namePrefix = cfg.Domain.NamePrefix
+	}
+	if f.nameSuffix == "" && cfg.Domain.NameSuffix != "" {
+		f.nameSuffix = cfg.Domain.NameSuffix
+	}
+	if f.maskFlag == "" && cfg.Domain.Mask != "" {
+		f.maskFlag = cfg.Domain.Mask
+	}
+	if f.letterPattern == "" && cfg.Domain.LetterPattern != "" {
+		f.letterPattern = cfg.Domain.LetterPattern
+	}
+	if f.template == "" && cfg.Domain.Template != "" {
+		f.template = cfg.Domain.Template
+	}
+	if flag.Lookup("syllables").Value.String() == fmt.Sprint(generator.DefaultSyllables) && cfg.Domain.Syllables != 0 { // Default value
+		f.syllables = cfg.Domain.Syllables
+	}
+	if f.words == "" && f.words1 == "" && f.words2 == "" && len(cfg.Domain.WordLists) > 0 {
+		f.words = strings.Join(cfg.Domain.WordLists, ",")
+	}
+	if f.leetWord == "" && cfg.Domain.Leet != "" {
+		f.leetWord = cfg.Domain.Leet
+	}
+	if f.numRange == "" && cfg.Domain.NumRange != "" {
+		f.numRange = cfg.Domain.NumRange
+	}
+	if f.numPad == 0 && cfg.Domain.NumPad != 0 {
+		f.numPad = cfg.Domain.NumPad
+	}
+	if f.wordSep == "" && cfg.Domain.WordSeparator != "" {
+		f.wordSep = cfg.Domain.WordSeparator
+	}
+	if f.inputFile == "" && cfg.Domain.InputFile != "" {
+		f.inputFile = cfg.Domain.InputFile
+	}
+	if flag.Lookup("delay").Value.String() == "1000" { // Default value
+		f.delay = cfg.Scanner.Delay
+	}
+	if flag.Lookup("workers").Value.String() == "10" { // Default value
+		f.workers = cfg.Scanner.Workers
+	}
+	if flag.Lookup("show-registered").Value.String() == "false" { // Default value
+		f.showRegistered = cfg.Scanner.ShowRegistered
+	}
+	if flag.Lookup("retry-rate-limited").Value.String() == "false" { // Default value
+		f.retryRateLimited = cfg.Scanner.RateLimitRetry
+	}
+	if flag.Lookup("retry-delay").Value.String() == "10000" { // Default value
+		f.retryDelay = cfg.Scanner.RateLimitRetryDelay
+	}
+	if flag.Lookup("retry-workers").Value.String() == "1" { // Default value
+		f.retryWorkers = cfg.Scanner.RateLimitRetryWorkers
+	}
+	if flag.Lookup("score").Value.String() == "false" { // Default value
+		f.score = cfg.Scoring.Enabled
+	}
+	if flag.Lookup("ct").Value.String() == "false" { // Default value
+		f.ctCheck = cfg.CT.Enabled
+	}
+	if flag.Lookup("ct-verify").Value.String() == "false" { // Default value
+		f.ctVerify = cfg.CT.Verify
+	}
+	if flag.Lookup("strict").Value.String() == "false" { // Default value
+		f.strict = cfg.Scanner.Strict
+	}
+	if flag.Lookup("debug").Value.String() == "false" { // Default value
+		f.debug = cfg.Scanner.Debug
+	}
+}
 
-	// Get special status domains from the domain checker
-	specialStatusDomainsFromChecker := domain.GetSpecialStatusDomains()
+// keyspace is the generated keyspace described by the validated flags
+type keyspace struct {
+	regexMode     types.RegexMode
+	lengths       []int
+	affixes       generator.Affixes
+	suffixes      []string
+	expiryCutoff  time.Time
+	blocklistMode string
+}
 
-	// Convert to simple string slice for compatibility with existing code
-	for _, ssd := range specialStatusDomainsFromChecker {
-		specialStatusDomains = append(specialStatusDomains, ssd.Domain)
+// validateKeyspace checks the flags describing the generated names and normalizes them
+// in f
+func validateKeyspace(f *cliFlags) (*keyspace, error) {
+	ks := &keyspace{}
+	switch f.regexMode {
+	case "full":
+		ks.regexMode = types.RegexModeFull
+	case "prefix":
+		ks.regexMode = types.RegexModePrefix
+	default:
+		return nil, fmt.Errorf("invalid regex-mode %q: use 'full' or 'prefix'", f.regexMode)
 	}
 
-	// Save available domains to file
-	availableFile := fmt.Sprintf("available_domains_%s_%d_%s.txt", *pattern, *length, strings.TrimPrefix(*suffix, "."))
-	if appConfig != nil && appConfig.Output.AvailableFile != "" {
-		availableFile = strings.Replace(appConfig.Output.AvailableFile, "{pattern}", *pattern, -1)
-		availableFile = strings.Replace(availableFile, "{length}", fmt.Sprintf("%d", *length), -1)
-		availableFile = strings.Replace(availableFile, "{suffix}", strings.TrimPrefix(*suffix, "."), -1)
+	if err := f.loadCharset(); err != nil {
+		return nil, err
 	}
 
-	// Create output directory if specified in config
-	outputDir := "."
-	if appConfig != nil && appConfig.Output.OutputDir != "" {
-		outputDir = appConfig.Output.OutputDir
-		// Always create directory if it doesn't exist, even if it's "."
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			fmt.Printf("Error creating output directory: %v\n", err)
-			os.Exit(1)
-		}
-		availableFile = outputDir + "/" + availableFile
+	// Unicode charsets generate internationalized names, which are checked in punycode
+	if generator.IsUnicodeCharset(f.charset) && (f.maskFlag != "" || f.letterPattern != "" || f.excludeChars != "" ||
+		f.namePrefix != "" || f.nameSuffix != "" || f.pattern == "template" || f.pattern == generator.PatternPronounceable) {
+		return nil, fmt.Errorf("a Unicode -charset cannot be combined with -mask, -letter-pattern, -exclude-chars, -name-prefix, -name-suffix, -p template or -p pronounceable")
 	}
 
-	file, err := os.Create(availableFile)
+	// Several lengths are generated one after the other in a single run
+	var err error
+	if ks.lengths, err = generator.ParseLengths(f.lengthSpec); err != nil {
+		return nil, err
+	}
+
+	// Fixed name affixes surround the generated characters of the keyspace
+	if ks.affixes.Prefix, err = generator.NormalizeAffix(f.namePrefix); err == nil {
+		ks.affixes.Suffix, err = generator.NormalizeAffix(f.nameSuffix)
+	}
+	if err == nil {
+		err = ks.affixes.Validate(ks.lengths)
+	}
 	if err != nil {
-		fmt.Printf("Error creating output file: %v\n", err)
-		os.Exit(1)
+		return nil, err
+	}
+	f.namePrefix, f.nameSuffix = ks.affixes.Prefix, ks.affixes.Suffix
+
+	if err := ks.validateShape(f); err != nil {
+		return nil, err
 	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Printf("Error closing file: %v\n", closeErr)
+
+	// Several comma-separated suffixes check every name under each of them
+	if ks.suffixes, err = generator.NormalizeSuffixes(f.suffix); err != nil {
+		return nil, err
+	}
+	if (f.fromStdin || f.inputList != "") && len(ks.suffixes) > 1 {
+		return nil, fmt.Errorf("invalid suffix %q: -stdin and -input take a single suffix", f.suffix)
+	}
+	f.suffix = strings.Join(ks.suffixes, ",")
+
+	// An expiry filter lists registered domains, so it needs their WHOIS records
+	if f.expiringBefore != "" {
+		if ks.expiryCutoff, err = parseExpiringBefore(f.expiringBefore, time.Now()); err != nil {
+			return nil, err
 		}
-	}()
+		f.showRegistered = true
+	}
 
-	for _, domain := range availableDomains {
-		_, err := file.WriteString(domain + "\n")
+	// The blocklist only comes from the config; the flag selects its mode
+	if appConfig != nil && appConfig.Domain.Blocklist != "" {
+		ks.blocklistMode = appConfig.Domain.BlocklistMode
+		if f.blocklistMode != "" {
+			ks.blocklistMode = f.blocklistMode
+		}
+	} else if f.blocklistMode != "" {
+		fmt.Println("Warning: -blocklist-mode has no effect without [domain] blocklist in the config")
+	}
+	if ks.blocklistMode != "" && ks.blocklistMode != types.BlocklistDrop && ks.blocklistMode != types.BlocklistFlag {
+		return nil, fmt.Errorf("invalid blocklist-mode %q: use 'drop' or 'flag'", ks.blocklistMode)
+	}
+	return ks, nil
+}
+
+// loadCharset reads and normalizes a custom charset, which replaces the characters of
+// the pattern
+func (f *cliFlags) loadCharset() error {
+	if f.charsetFile != "" {
+		if flag.Lookup("charset").Value.String() != "" {
+			return fmt.Errorf("-charset-file cannot be combined with -charset")
+		}
+		loaded, err := generator.LoadCharsetFile(f.charsetFile)
 		if err != nil {
-			fmt.Printf("Error writing to file: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("invalid charset file: %w", err)
 		}
+		f.charset = loaded
 	}
+	if f.charset != "" {
+		normalize := generator.NormalizeCharset
+		if generator.IsUnicodeCharset(f.charset) {
+			normalize = generator.NormalizeUnicodeCharset
+		}
+		normalized, err := normalize(f.charset)
+		if err != nil {
+			return err
+		}
+		f.charset = normalized
+	}
+	return nil
+}
 
-	// Save registered domains to file only if show-registered is true
-	registeredFile := fmt.Sprintf("registered_domains_%s_%d_%s.txt", *pattern, *length, strings.TrimPrefix(*suffix, "."))
-	if *showRegistered {
-		if appConfig != nil && appConfig.Output.RegisteredFile != "" {
-			registeredFile = strings.Replace(appConfig.Output.RegisteredFile, "{pattern}", *pattern, -1)
-			registeredFile = strings.Replace(registeredFile, "{length}", fmt.Sprintf("%d", *length), -1)
-			registeredFile = strings.Replace(registeredFile, "{suffix}", strings.TrimPrefix(*suffix, "."), -1)
+// validateShape checks the flags shaping the generated names, templates, syllables,
+// excluded characters, masks and letter patterns, and normalizes them in f
+func (ks *keyspace) validateShape(f *cliFlags) error {
+	var err error
+	// A template replaces the length and the pattern's characters
+	if f.pattern == "template" || f.template != "" {
+		switch {
+		case f.pattern != "template":
+			return fmt.Errorf("-template requires -p template")
+		case f.template == "":
+			return fmt.Errorf("-p template requires -template, e.g. -template CVCV")
+		case f.maskFlag != "" || f.charset != "" || f.excludeChars != "" || !ks.affixes.IsZero():
+			return fmt.Errorf("-template cannot be combined with -mask, -charset, -exclude-chars, -name-prefix or -name-suffix")
 		}
+		parsed, err := generator.ParseTemplate(f.template)
+		if err != nil {
+			return err
+		}
+		f.template = parsed.String()
+	}
 
-		// Use output directory if specified in config
-		if appConfig != nil && appConfig.Output.OutputDir != "" {
-			registeredFile = outputDir + "/" + registeredFile
+	// Pronounceable names are composed of syllables instead of -l characters
+	if f.pattern == generator.PatternPronounceable || f.syllables != generator.DefaultSyllables {
+		switch {
+		case f.pattern != generator.PatternPronounceable:
+			return fmt.Errorf("-syllables requires -p pronounceable")
+		case f.maskFlag != "" || f.charset != "" || f.excludeChars != "" || f.template != "" || !ks.affixes.IsZero():
+			return fmt.Errorf("-p pronounceable cannot be combined with -mask, -charset, -exclude-chars, -template, -name-prefix or -name-suffix")
 		}
+		if err := generator.ValidateSyllables(f.syllables); err != nil {
+			return err
+		}
+	}
 
-		regFile, err := os.Create(registeredFile)
+	// Excluded characters are removed from the charset of the pattern
+	generatedPattern := f.pattern
+	if f.charset != "" {
+		generatedPattern = generator.CharsetPattern(f.charset)
+	}
+	if f.excludeChars != "" && f.template == "" && f.pattern != generator.PatternPronounceable {
+		if generatedPattern, err = generator.ExcludeChars(generatedPattern, f.excludeChars); err != nil {
+			return err
+		}
+		f.excludeChars, _ = generator.NormalizeCharset(f.excludeChars)
+	}
+
+	// A mask replaces the length and fixes characters at given positions
+	if f.maskFlag != "" {
+		mask, err := generator.ParseMask(f.maskFlag, generatedPattern)
 		if err != nil {
-			fmt.Printf("Error creating registered domains file: %v\n", err)
-			os.Exit(1)
+			return err
 		}
-		defer func() {
-			if closeErr := regFile.Close(); closeErr != nil {
-				fmt.Printf("Error closing registered domains file: %v\n", closeErr)
-			}
-		}()
+		if !ks.affixes.IsZero() {
+			return fmt.Errorf("-mask cannot be combined with -name-prefix or -name-suffix; write them into the mask")
+		}
+		f.maskFlag = mask.String()
+	}
 
-		for _, domain := range registeredDomains {
-			_, err := regFile.WriteString(domain + "\n")
-			if err != nil {
-				fmt.Printf("Error writing to registered domains file: %v\n", err)
-				os.Exit(1)
-			}
+	// A letter pattern sets the length and enumerates only its distinct letters
+	if f.letterPattern != "" {
+		p, err := generator.ParseLetterPattern(f.letterPattern, generatedPattern)
+		switch {
+		case err != nil:
+			return err
+		case f.maskFlag != "" || f.template != "" || f.pattern == generator.PatternPronounceable || !ks.affixes.IsZero():
+			return fmt.Errorf("-letter-pattern cannot be combined with -mask, -template, -p pronounceable, -name-prefix or -name-suffix")
+		case flag.Lookup("l").Value.String() != "3" && (len(ks.lengths) != 1 || ks.lengths[0] != p.Len()): // Set on the command line
+			return fmt.Errorf("-letter-pattern %s has %d letters but -l is %s; leave out -l or match it", p, p.Len(), f.lengthSpec)
+		}
+		f.letterPattern = p.String()
+		ks.lengths = []int{p.Len()}
+	}
+	return nil
+}
+
+// inputs are the candidates replacing the generated keyspace, loaded from the flags
+type inputs struct {
+	wordLists      []string
+	typos          []generator.Typo
+	expiring       *generator.ExpiringList
+	retry          *generator.RetryList
+	reverseDomains []string
+}
+
+// listInput reports whether a flag replaces the candidates with a list of names or
+// domains
+func (f *cliFlags) listInput() bool {
+	return f.fromStdin || f.inputList != "" || f.inputFile != "" || f.expiringList != "" || len(f.retryFiles) > 0 ||
+		f.reverseName != "" || f.tldsFlag != "" || f.tldListPath != ""
+}
+
+// shapesKeyspace reports whether a flag shapes the names of the generated keyspace
+func (f *cliFlags) shapesKeyspace(ks *keyspace) bool {
+	return !ks.affixes.IsZero() || f.maskFlag != "" || f.letterPattern != "" || f.template != "" || f.excludeChars != ""
+}
+
+// loadInputs checks the flags replacing the generated candidates, which exclude each
+// other, and loads their lists
+func loadInputs(f *cliFlags, ks *keyspace) (*inputs, error) {
+	in := &inputs{}
+	// Combinator mode replaces the length/pattern keyspace with word list concatenations
+	for _, path := range strings.Split(f.words, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			in.wordLists = append(in.wordLists, path)
 		}
 	}
+	if f.words1 != "" || f.words2 != "" {
+		if f.words1 == "" || f.words2 == "" || len(in.wordLists) > 0 {
+			return nil, fmt.Errorf("-words1 and -words2 are used together and not with -words")
+		}
+		in.wordLists = []string{f.words1, f.words2}
+	}
+	// A single list combines its words with each other
+	if len(in.wordLists) == 1 {
+		in.wordLists = append(in.wordLists, in.wordLists[0])
+	}
+	if err := generator.ValidateWordSeparator(f.wordSep); err != nil {
+		return nil, err
+	}
+	if f.wordSep != "" && len(in.wordLists) == 0 {
+		return nil, fmt.Errorf("-word-sep requires -words or -words1 and -words2")
+	}
+	listInput := f.listInput() || len(in.wordLists) > 0
 
-	// Save special status domains to file if any exist
-	var specialStatusFile string
-	if len(specialStatusDomains) > 0 {
-		specialStatusFile = fmt.Sprintf("special_status_domains_%s_%d_%s.txt", *pattern, *length, strings.TrimPrefix(*suffix, "."))
-		if appConfig != nil && appConfig.Output.SpecialStatusFile != "" {
-			specialStatusFile = strings.Replace(appConfig.Output.SpecialStatusFile, "{pattern}", *pattern, -1)
-			specialStatusFile = strings.Replace(specialStatusFile, "{length}", fmt.Sprintf("%d", *length), -1)
-			specialStatusFile = strings.Replace(specialStatusFile, "{suffix}", strings.TrimPrefix(*suffix, "."), -1)
+	// Leetspeak variants of a word replace the generated candidates
+	if f.leetWord != "" {
+		var err error
+		if f.leetWord, err = generator.NormalizeLeetWord(f.leetWord); err != nil {
+			return nil, err
+		}
+		if listInput {
+			return nil, fmt.Errorf("-leet cannot be combined with -stdin, -input, -i, -words, -expiring-list, -retry-file or -name")
 		}
+		if f.shapesKeyspace(ks) || f.charset != "" {
+			return nil, fmt.Errorf("-leet cannot be combined with -name-prefix, -name-suffix, -mask, -letter-pattern, -template, -exclude-chars or -charset")
+		}
+	}
 
-		// Use output directory if specified in config
-		if appConfig != nil && appConfig.Output.OutputDir != "" {
-			specialStatusFile = outputDir + "/" + specialStatusFile
+	// A numeric range replaces the generated candidates; its size bounds the scan
+	if f.numRange != "" {
+		maxNumRange := 0
+		if appConfig != nil {
+			maxNumRange = appConfig.Domain.MaxNumRange
 		}
+		if _, err := generator.ParseNumRange(f.numRange, f.numPad, maxNumRange); err != nil {
+			return nil, err
+		}
+		if listInput || f.leetWord != "" {
+			return nil, fmt.Errorf("-num-range cannot be combined with -stdin, -input, -i, -words, -expiring-list, -retry-file, -name or -leet")
+		}
+		if f.shapesKeyspace(ks) || f.charset != "" || f.pattern == generator.PatternPronounceable {
+			return nil, fmt.Errorf("-num-range cannot be combined with -name-prefix, -name-suffix, -mask, -letter-pattern, -template, -exclude-chars, -charset or -p pronounceable")
+		}
+	} else if f.numPad != 0 {
+		return nil, fmt.Errorf("-num-pad requires -num-range")
+	}
 
-		specialFile, err := os.Create(specialStatusFile)
+	// Keyword and affix combinations replace the generated candidates
+	if f.keywordFile != "" {
+		if err := generator.ValidateAffixPosition(f.affixPosition); err != nil {
+			return nil, err
+		}
+		switch {
+		case f.affixFile == "":
+			return nil, fmt.Errorf("-keywords requires -affixes")
+		case listInput || f.leetWord != "" || f.numRange != "":
+			return nil, fmt.Errorf("-keywords cannot be combined with -stdin, -input, -i, -words, -expiring-list, -retry-file, -name, -leet or -num-range")
+		case f.shapesKeyspace(ks) || f.charset != "":
+			return nil, fmt.Errorf("-keywords cannot be combined with -name-prefix, -name-suffix, -mask, -letter-pattern, -template, -exclude-chars or -charset")
+		}
+	} else if f.affixFile != "" || f.keywordAlone || f.affixPosition != generator.AffixBoth {
+		return nil, fmt.Errorf("-affixes, -affix-position and -keyword-alone require -keywords")
+	}
+	if f.sample < 0 {
+		return nil, fmt.Errorf("invalid sample size %d: use a positive number of domains", f.sample)
+	}
+	if f.seed != 0 && !f.shuffle && f.sample == 0 {
+		return nil, fmt.Errorf("-seed requires -shuffle or -sample")
+	}
+	// The seed is chosen here so that a dry run can print it like a scan
+	for (f.shuffle || f.sample > 0) && f.seed == 0 {
+		f.seed = rand.Int63()
+	}
+
+	// Typo variants of a domain replace the generated candidates
+	if f.typosOf != "" {
+		var err error
+		if in.typos, err = generator.TypoVariants(f.typosOf, generator.DefaultTypoTLDs); err != nil {
+			return nil, err
+		}
+		if listInput || f.leetWord != "" || f.numRange != "" || f.keywordFile != "" {
+			return nil, fmt.Errorf("-typos cannot be combined with -stdin, -input, -i, -words, -expiring-list, -retry-file, -name, -leet, -num-range or -keywords")
+		}
+		if f.shapesKeyspace(ks) || f.charset != "" {
+			return nil, fmt.Errorf("-typos cannot be combined with -name-prefix, -name-suffix, -mask, -letter-pattern, -template, -exclude-chars or -charset")
+		}
+		fmt.Printf("Generated %d typo variants of %s\n", len(in.typos), strings.ToLower(strings.TrimSpace(f.typosOf)))
+	}
+
+	// A name list replaces the generated candidates like the other inputs, never alongside them
+	reverse := f.reverseName != "" || f.tldsFlag != "" || f.tldListPath != ""
+	if f.inputFile != "" && (len(in.wordLists) > 0 || f.expiringList != "" || len(f.retryFiles) > 0) {
+		return nil, fmt.Errorf("-i cannot be combined with -words, -expiring-list or -retry-file")
+	}
+	if f.fromStdin && (f.inputFile != "" || len(in.wordLists) > 0 || f.expiringList != "" || len(f.retryFiles) > 0 || reverse) {
+		return nil, fmt.Errorf("-stdin cannot be combined with -i, -words, -expiring-list, -retry-file or -name")
+	}
+	if f.inputList != "" && (f.fromStdin || f.inputFile != "" || len(in.wordLists) > 0 || f.expiringList != "" || len(f.retryFiles) > 0 || reverse) {
+		return nil, fmt.Errorf("-input cannot be combined with -stdin, -i, -words, -expiring-list, -retry-file or -name")
+	}
+	// Name affixes, masks, letter patterns, templates and excluded characters only apply to the generated keyspace
+	if f.shapesKeyspace(ks) && listInput {
+		return nil, fmt.Errorf("-name-prefix, -name-suffix, -mask, -letter-pattern, -template and -exclude-chars cannot be combined with -stdin, -input, -i, -words, -expiring-list, -retry-file or -name")
+	}
+
+	if err := in.loadLists(f, ks, reverse); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// loadLists loads the expiring list, retry files or reverse TLDs replacing the
+// generated candidates
+func (in *inputs) loadLists(f *cliFlags, ks *keyspace, reverse bool) error {
+	// An expiring domain list replaces the generated candidates
+	if f.expiringList != "" {
+		sep := []rune(strings.ReplaceAll(f.delimiter, `\t`, "\t"))
+		if len(sep) != 1 {
+			return fmt.Errorf("-delimiter must be a single character")
+		}
+		var err error
+		in.expiring, err = generator.LoadExpiringList(f.expiringList, generator.ExpiringOptions{
+			Column:      f.column,
+			DateColumn:  f.dateColumn,
+			Delimiter:   sep[0],
+			SkipHeader:  f.skipHeader,
+			Suffixes:    ks.suffixes,
+			RegexFilter: f.regexFilter,
+			RegexMode:   ks.regexMode,
+		})
 		if err != nil {
-			fmt.Printf("Error creating special status file: %v\n", err)
-		} else {
-			defer func() {
-				if closeErr := specialFile.Close(); closeErr != nil {
-					fmt.Printf("Error closing special status file: %v\n", closeErr)
-				}
-			}()
-
-			// Write header
-			_, err := specialFile.WriteString("# Special Status Domains\n")
-			if err == nil {
-				_, err = specialFile.WriteString("# Format: domain status reason\n")
-			}
-			if err == nil {
-				_, err = specialFile.WriteString("#\n")
+			return err
+		}
+		for i, problem := range in.expiring.Problems {
+			if i == maxReportedProblems {
+				fmt.Printf("Warning: %d more invalid rows\n", len(in.expiring.Problems)-i)
+				break
 			}
+			fmt.Printf("Warning: %s: %s\n", f.expiringList, problem)
+		}
+		fmt.Printf("Ingested %d of %d rows from %s\n", in.expiring.Ingested, in.expiring.Rows, f.expiringList)
+	}
 
-			if err == nil {
-				// Write detailed special status information
-				for _, ssd := range specialStatusDomainsFromChecker {
-					line := fmt.Sprintf("%s %s %s\n", ssd.Domain, ssd.Status, ssd.Reason)
-					_, err = specialFile.WriteString(line)
-					if err != nil {
-						fmt.Printf("Error writing to special status file: %v\n", err)
-						break
-					}
-				}
-
-				// Also write simple domain list for backward compatibility
-				if len(specialStatusDomainsFromChecker) == 0 {
-					for _, domain := range specialStatusDomains {
-						_, err := specialFile.WriteString(domain + " UNKNOWN Unknown_status\n")
-						if err != nil {
-							fmt.Printf("Error writing to special status file: %v\n", err)
-							break
-						}
-					}
-				}
+	// Retry files replace the generated candidates with the domains of earlier runs
+	if len(f.retryFiles) > 0 {
+		if in.expiring != nil {
+			return fmt.Errorf("-retry-file cannot be combined with -expiring-list")
+		}
+		var err error
+		if in.retry, err = generator.LoadRetryFiles(f.retryFiles); err != nil {
+			return err
+		}
+		for i, problem := range in.retry.Problems {
+			if i == maxReportedProblems {
+				fmt.Printf("Warning: %d more invalid lines\n", len(in.retry.Problems)-i)
+				break
 			}
+			fmt.Printf("Warning: %s\n", problem)
+		}
+		fmt.Printf("Rechecking %d domains from %d lines (%d duplicate, %d invalid)\n",
+			len(in.retry.Domains), in.retry.Lines, in.retry.Duplicates, in.retry.Invalid)
+	}
 
+	// Reverse mode checks one name under a list of TLDs
+	if reverse {
+		var err error
+		if in.reverseDomains, err = reverseCandidates(f.reverseName, f.tldsFlag, f.tldListPath, !f.dryRun); err != nil {
+			return err
+		}
+		if in.expiring != nil || in.retry != nil || len(in.wordLists) > 0 || f.inputFile != "" {
+			return fmt.Errorf("-name cannot be combined with -expiring-list, -retry-file, -words or -i")
+		}
+	}
+	return nil
+}
+
+// buildScanOptions returns the options of a scan of the keyspace; the inputs replacing
+// it are attached by attachDomains
+func buildScanOptions(f *cliFlags, ks *keyspace, in *inputs, scanConfig types.Config) scanner.ScanOptions {
+	opts := scanner.ScanOptions{
+		Length:         ks.lengths[0],
+		Suffix:         ks.suffixes[0],
+		Pattern:        f.pattern,
+		Charset:        f.charset,
+		ExcludeChars:   f.excludeChars,
+		NamePrefix:     f.namePrefix,
+		NameSuffix:     f.nameSuffix,
+		Mask:           f.maskFlag,
+		LetterPattern:  f.letterPattern,
+		Template:       f.template,
+		Syllables:      f.syllables,
+		RegexFilter:    f.regexFilter,
+		RegexMode:      ks.regexMode,
+		WordLists:      in.wordLists,
+		WordSeparator:  f.wordSep,
+		Leet:           f.leetWord,
+		LeetTable:      scanConfig.Domain.LeetTable,
+		KeywordFile:    f.keywordFile,
+		AffixFile:      f.affixFile,
+		AffixPosition:  f.affixPosition,
+		KeywordAlone:   f.keywordAlone,
+		NumRange:       f.numRange,
+		NumPad:         f.numPad,
+		MaxNumRange:    scanConfig.Domain.MaxNumRange,
+		InputFile:      f.inputFile,
+		StartFrom:      f.startFromName,
+		Shuffle:        f.shuffle,
+		Seed:           f.seed,
+		Sample:         f.sample,
+		Delay:          time.Duration(f.delay) * time.Millisecond,
+		Workers:        f.workers,
+		ShowRegistered: f.showRegistered,
+		ExpiringBefore: ks.expiryCutoff,
+		WriteFiles:     true,
+		LookupPrices:   appConfig != nil && appConfig.Pricing.Provider != "",
+		Score:          f.score,
+		PublishSheets:  appConfig != nil && appConfig.Output.GSheets.SpreadsheetID != "",
+		Notify:         appConfig != nil && appConfig.Notify.Webhook.URL != "",
+		BlocklistMode:  ks.blocklistMode,
+		ExcludeFile:    f.excludeFile,
+		ZoneFiles:      f.zoneFiles,
+		CTCheck:        f.ctCheck,
+		CTVerify:       f.ctVerify,
+		Prefilter:      appConfig != nil && appConfig.Scanner.Prefilter != "",
+		Log:            os.Stdout,
+
+		DebugIndex:       f.debugIndex,
+		SlowThreshold:    time.Duration(f.slowThreshold) * time.Second,
+		RetryRateLimited: f.retryRateLimited,
+		RetryDelay:       time.Duration(f.retryDelay) * time.Millisecond,
+		RetryWorkers:     f.retryWorkers,
+
+		ZoneFalsePositiveRate: f.zoneFP,
+	}
+	if len(ks.lengths) > 1 {
+		opts.Lengths = ks.lengths
+	}
+	if len(ks.suffixes) > 1 {
+		opts.Suffixes = ks.suffixes
+	}
+	if f.progressInterval > 0 {
+		opts.ProgressInterval = time.Duration(f.progressInterval) * time.Second
+		opts.OnProgress = printProgress
+	}
+
+	// Batch configs split by count restrict generation to a keyspace range
+	if appConfig != nil {
+		opts.Offset = appConfig.Domain.Offset
+		opts.Limit = appConfig.Domain.Limit
+
+		// The recorded expectation only holds if no flag changed the generated keyspace
+		if len(ks.lengths) == 1 && ks.lengths[0] == appConfig.Domain.Length && f.suffix == appConfig.Domain.Suffix &&
+			f.pattern == appConfig.Domain.Pattern && f.charset == appConfig.Domain.Charset &&
+			f.excludeChars == appConfig.Domain.ExcludeChars &&
+			f.namePrefix == appConfig.Domain.NamePrefix && f.nameSuffix == appConfig.Domain.NameSuffix &&
+			f.maskFlag == appConfig.Domain.Mask && f.template == appConfig.Domain.Template &&
+			f.letterPattern == appConfig.Domain.LetterPattern && strings.EqualFold(f.leetWord, strings.TrimSpace(appConfig.Domain.Leet)) &&
+			f.numRange == appConfig.Domain.NumRange && f.numPad == appConfig.Domain.NumPad &&
+			(f.syllables == appConfig.Domain.Syllables || appConfig.Domain.Syllables == 0 && f.syllables == generator.DefaultSyllables) &&
+			f.regexFilter == appConfig.Domain.RegexFilter && f.keywordFile == "" &&
+			ks.regexMode == types.RegexModeFull {
+			opts.ExpectedCount = appConfig.Batch.ExpectedCount
+		}
+	}
+	return opts
+}
+
+// attachEvents streams the events of the scan to a wrapper process and lets it control
+// the scan; the returned context is cancelled by the wrapper's stop command
+func attachEvents(ctx context.Context, f *cliFlags, opts *scanner.ScanOptions) (context.Context, func(), error) {
+	if f.eventSocket == "" && f.eventFD < 3 {
+		return nil, nil, fmt.Errorf("-event-fd must be 3 or higher")
+	}
+	stream, err := events.Open(f.eventSocket, f.eventFD)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	gate := scanner.NewGate()
+	go stream.Control(scanControl{Gate: gate, cancel: cancel})
+
+	opts.Gate = gate
+	opts.OnResult = stream.Result
+	opts.OnStateChange = stream.State
+	printLine := opts.OnProgress
+	opts.OnProgress = func(p scanner.Progress) {
+		stream.Progress(p)
+		if printLine != nil {
+			printLine(p)
+		}
+	}
+	if opts.ProgressInterval == 0 {
+		opts.ProgressInterval = time.Second
+	}
+	return ctx, func() {
+		cancel()
+		stream.Close()
+	}, nil
+}
+
+// sendDomains returns a closed channel holding the domains
+func sendDomains(names []string) <-chan string {
+	domains := make(chan string, len(names))
+	for _, name := range names {
+		domains <- name
+	}
+	close(domains)
+	return domains
+}
+
+// attachDomains makes the scan check the loaded inputs instead of the generated
+// keyspace. The returned function closes the input file of -input; an input that cannot
+// be opened or filtered is an error.
+func attachDomains(ctx context.Context, f *cliFlags, ks *keyspace, in *inputs, opts *scanner.ScanOptions) (func(), error) {
+	closeInput := func() {}
+	if in.expiring != nil {
+		opts.Domains = sendDomains(in.expiring.Domains)
+		opts.DropDates = in.expiring.DropDates
+		// Result files are named after the list instead of a keyspace
+		opts.Pattern, opts.Length, opts.Lengths = "expiring", 0, nil
+		opts.Suffix = strings.Join(ks.suffixes, "")
+		opts.ExpectedCount = nil
+	}
+	if in.retry != nil {
+		opts.Domains = sendDomains(in.retry.Domains)
+		opts.Previous = in.retry.Previous
+		// Fresh result files next to those of the run being rechecked
+		opts.FileSuffix = "_retry"
+		opts.ExpectedCount = nil
+	}
+	if f.fromStdin {
+		// Standard input is checked as it arrives; the total is known once it ends
+		domains, err := generator.GenerateFromReader(ctx, os.Stdin, f.suffix, f.regexFilter, ks.regexMode)
+		if err != nil {
+			return nil, err
+		}
+		opts.Domains = domains
+		opts.Pattern, opts.Length, opts.Lengths = "stdin", 0, nil
+		opts.ExpectedCount = nil
+	}
+	if f.inputList != "" {
+		// A domain list is checked as it is read, like standard input; "-" is standard input
+		input := os.Stdin
+		if f.inputList != "-" {
+			file, err := os.Open(f.inputList)
 			if err != nil {
-				fmt.Printf("Error writing special status file header: %v\n", err)
+				return nil, fmt.Errorf("error opening input file: %w", err)
 			}
+			closeInput = func() { file.Close() }
+			input = file
+		}
+		domains, err := generator.GenerateFromReader(ctx, input, f.suffix, f.regexFilter, ks.regexMode)
+		if err != nil {
+			closeInput()
+			return nil, err
+		}
+		opts.Domains = domains
+		opts.Pattern, opts.Length, opts.Lengths = "list", 0, nil
+		opts.ExpectedCount = nil
+	}
+	if in.reverseDomains != nil {
+		opts.Domains = sendDomains(in.reverseDomains)
+		opts.TLDTable = true
+		// Result files are named after the name instead of a keyspace
+		opts.Pattern, opts.Length, opts.Lengths = "reverse", 0, nil
+		opts.Suffix, opts.Suffixes = strings.ToLower(f.reverseName), nil
+		opts.ExpectedCount = nil
+	}
+	if in.typos != nil {
+		names := make([]string, len(in.typos))
+		opts.Permutations = make(map[string]string, len(in.typos))
+		for i, typo := range in.typos {
+			names[i] = typo.Domain
+			opts.Permutations[typo.Domain] = typo.Kind
+		}
+		opts.Domains = sendDomains(names)
+		// Result files are named after the domain instead of a keyspace, e.g. typos_0_example.com
+		opts.Pattern, opts.Length, opts.Lengths = "typos", 0, nil
+		opts.Suffix, opts.Suffixes = strings.ToLower(strings.TrimSpace(f.typosOf)), nil
+		opts.ExpectedCount = nil
+	}
+	return closeInput, nil
+}
+
+// preview lists or counts the domains of -dry-run and -count-only without checking
+// them; it neither tracks the batch nor touches the network
+func preview(ctx context.Context, f *cliFlags, s *scanner.Scanner, opts scanner.ScanOptions) int {
+	if f.sample > 0 {
+		fmt.Printf("Sampling %d random domains with seed %d\n", f.sample, f.seed)
+	} else if f.shuffle {
+		fmt.Printf("Shuffling the generation order with seed %d\n", f.seed)
+	}
+	if f.countOnly {
+		return countDomains(ctx, s, opts)
+	}
+	return listDomains(ctx, s, opts, f.dryRunFile)
+}
+
+// scan runs the scan, alone or in its role of a distributed scan, and tracks it in the
+// batch status of a batch config. It returns the summary to print, or nil and the exit
+// code when there is none.
+func scan(ctx context.Context, f *cliFlags, s *scanner.Scanner, opts scanner.ScanOptions, progressBar *scanner.ProgressBar) (*scanner.Summary, int) {
+	if f.queueURL == "" && f.role != "" {
+		fmt.Println("Error: -role requires -queue")
+		return nil, scanner.ExitUsage
+	}
+	if f.queueURL == "" && !confirmLargeScan(ctx, s, opts) {
+		return nil, scanner.ExitAborted
+	}
+
+	// Track batch progress when running from a generated batch config; the batch is
+	// only marked running once nothing but the scan itself can fail
+	var batchStatus *batch.Status
+	if appConfig != nil && appConfig.Batch.Name != "" {
+		var releaseBatchLock func()
+		var err error
+		batchStatus, releaseBatchLock, err = batch.Begin(f.configPath, appConfig)
+		if err != nil {
+			fmt.Printf("Error starting batch %s: %v\n", appConfig.Batch.Name, err)
+			return nil, scanner.ExitUsage
+		}
+		defer releaseBatchLock()
+	}
+
+	var summary *scanner.Summary
+	var err error
+	if f.queueURL != "" {
+		// Distributed mode: producers and collectors summarize the results of all consumers
+		summary, err = queue.RunRole(ctx, s, opts, f.role, queue.New(f.queueURL, f.queueName, os.Stdout))
+	} else {
+		summary, err = s.Run(ctx, opts)
+	}
+	if progressBar != nil {
+		progressBar.Finish()
+	}
+	if closeErr := s.Close(); closeErr != nil {
+		fmt.Printf("Warning: could not send metrics: %v\n", closeErr)
+	}
+	if batchStatus != nil {
+		// A run that fails without a summary never scanned; consumers leave the
+		// summary to the collector but have finished their part
+		var statusErr error
+		switch {
+		case summary != nil:
+			statusErr = batchStatus.Finish(summary)
+		case err != nil:
+			statusErr = batchStatus.Abort()
+		default:
+			statusErr = batchStatus.MarkFinished(batch.StateCompleted, batch.Counts{})
+		}
+		if statusErr != nil {
+			fmt.Printf("Warning: could not write batch status: %v\n", statusErr)
+		}
+	}
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		// Without a summary the scan never started: its options or inputs were invalid
+		if summary == nil {
+			return nil, scanner.ExitUsage
 		}
+		return nil, writeSummary(s, summary, scanner.ExitAborted)
 	}
+	// Consumers leave the results to the collector
+	return summary, scanner.ExitOK
+}
 
-	fmt.Printf("\n\nResults saved to:\n")
-	fmt.Printf("- Available domains: %s\n", availableFile)
-	if *showRegistered {
-		fmt.Printf("- Registered domains: %s\n", registeredFile)
+// printResults prints the summary of a scan and the tables of its mode
+func printResults(f *cliFlags, in *inputs, opts scanner.ScanOptions, summary *scanner.Summary) {
+	scanner.PrintSummary(os.Stdout, summary, f.showRegistered)
+	if in.expiring != nil {
+		fmt.Printf("- Expiring list rows: %d ingested, %d skipped (%d filtered, %d duplicate, %d invalid)\n",
+			in.expiring.Ingested, in.expiring.Skipped(), in.expiring.Filtered, in.expiring.Duplicates, in.expiring.Invalid)
+	}
+	// Runs over several suffixes always break the results down per suffix
+	if f.tldStats || len(opts.Suffixes) > 1 {
+		scanner.PrintTLDStats(os.Stdout, summary)
+	}
+	if f.verbose {
+		scanner.PrintSlowest(os.Stdout, summary)
+	}
+	if in.retry != nil {
+		scanner.PrintStatusChanges(os.Stdout, summary)
+	}
+	if in.reverseDomains != nil {
+		scanner.PrintTLDTable(os.Stdout, summary)
+	}
+}
+
+// writeSummary records the outcome of a run in summary.json in the output directory
+// and returns its exit code
+func writeSummary(s *scanner.Scanner, summary *scanner.Summary, code int) int {
+	if _, err := scanner.WriteSummaryFile(s.Config().Output.OutputDir, summary, code); err != nil {
+		fmt.Printf("Warning: could not write summary: %v\n", err)
+	}
+	return code
+}
+
+// parseExpiringBefore parses the -expiring-before cutoff: an expiration date in one of
+// the WHOIS date formats or a window from now in days ("30d") or as a duration ("72h")
+func parseExpiringBefore(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, n), nil
+		}
+	}
+	if window, err := time.ParseDuration(value); err == nil && window > 0 {
+		return now.Add(window), nil
+	}
+	if cutoff, ok := domain.ParseExpiryDate(value); ok {
+		return cutoff, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid -expiring-before %q: use a date such as 2025-12-31 or a window such as 30d or 72h", value)
+}
+
+// listDomains prints the domains a scan would check, or writes them to a file, and
+// counts them; the domains are generated exactly as for the scan but not checked
+func listDomains(ctx context.Context, s *scanner.Scanner, opts scanner.ScanOptions, path string) int {
+	domains, err := s.GenerateContext(ctx, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return scanner.ExitUsage
+	}
+	out := bufio.NewWriter(os.Stdout)
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			fmt.Printf("Error creating dry run file: %v\n", err)
+			return scanner.ExitUsage
+		}
+		defer file.Close()
+		out = bufio.NewWriter(file)
+	}
+	count := 0
+	for name := range domains {
+		fmt.Fprintln(out, name)
+		count++
+	}
+	if err := out.Flush(); err != nil {
+		fmt.Printf("Error writing dry run domains: %v\n", err)
+		return scanner.ExitErrors
 	}
-	if len(specialStatusDomains) > 0 {
-		fmt.Printf("- Special status domains: %s\n", specialStatusFile)
+	if ctx.Err() != nil {
+		fmt.Printf("Dry run interrupted after %d domains\n", count)
+		return scanner.ExitAborted
 	}
-	fmt.Printf("\nSummary:\n")
-	fmt.Printf("- Total domains processed: %d\n", totalProcessed)
-	fmt.Printf("- Available domains: %d\n", len(availableDomains))
-	if *showRegistered {
-		fmt.Printf("- Registered domains: %d\n", len(registeredDomains))
+	if path != "" {
+		fmt.Printf("Dry run: %d domains would be checked, written to %s\n", count, path)
 	} else {
-		registeredCount := totalProcessed - len(availableDomains) - len(specialStatusDomains)
-		fmt.Printf("- Registered domains: %d (not saved to file)\n", registeredCount)
+		fmt.Printf("Dry run: %d domains would be checked\n", count)
+	}
+	return scanner.ExitOK
+}
+
+// confirmLargeScan warns about a scan of more than largeScan domains and, when standard
+// input is a terminal, asks whether to go on. Errors of the options are left to the scan.
+func confirmLargeScan(ctx context.Context, s *scanner.Scanner, opts scanner.ScanOptions) bool {
+	count, known, err := s.Count(ctx, opts)
+	if err != nil || !known || count <= largeScan {
+		return true
+	}
+	if count == generator.MaxCount {
+		fmt.Printf("Warning: the scan covers more than %d domains, too many to count; narrow it with -l, -p or -r\n", count)
+	} else {
+		fmt.Printf("Warning: the scan covers %d domains, more than %d; narrow it with -r or -sample, or check the count with -count-only\n",
+			count, largeScan)
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return true
+	}
+	fmt.Print("Continue? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	fmt.Println("Scan cancelled")
+	return false
+}
+
+// countSample is the number of domains -count-only prints as a sample
+const countSample = 20
+
+// countDomains prints the number of domains a scan would check and the first of them.
+// The number of a keyspace that nothing filters is known at once; otherwise every
+// domain is generated and filtered exactly as for the scan, but not checked.
+func countDomains(ctx context.Context, s *scanner.Scanner, opts scanner.ScanOptions) int {
+	total, known, err := s.Count(ctx, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return scanner.ExitUsage
+	}
+	genCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	domains, err := s.GenerateContext(genCtx, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return scanner.ExitUsage
+	}
+	var first []string
+	count := 0
+	for name := range domains {
+		if len(first) < countSample {
+			first = append(first, name)
+		}
+		count++
+		if known && len(first) == countSample {
+			stop()
+		}
+	}
+	if ctx.Err() != nil {
+		fmt.Printf("Count interrupted after %d domains\n", count)
+		return scanner.ExitAborted
+	}
+	if known {
+		count = total
+	}
+	fmt.Printf("Count: %s domains would be checked\n", generator.FormatCount(count))
+	if len(first) > 0 {
+		fmt.Printf("First %d:\n", len(first))
+		for _, name := range first {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	return scanner.ExitOK
+}
+
+// reverseCandidates returns the domains of a reverse mode scan: name under every TLD of
+// the comma-separated list and the list file. Invalid TLDs, and with verify the TLDs
+// unknown to the DNS root, are reported and skipped; a scan without any valid TLD is an
+// error.
+func reverseCandidates(name, list, path string, verify bool) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil, fmt.Errorf("-tlds and -tld-list require -name")
+	}
+	if err := generator.ValidateName(name); err != nil {
+		return nil, err
+	}
+	if list == "" && path == "" {
+		return nil, fmt.Errorf("-name requires -tlds or -tld-list")
+	}
+
+	tlds := generator.ParseTLDs(list)
+	if path != "" {
+		fromFile, err := generator.LoadTLDList(path)
+		if err != nil {
+			return nil, err
+		}
+		tlds.Problems = append(tlds.Problems, fromFile.Problems...)
+		tlds.Suffixes = append(tlds.Suffixes, fromFile.Suffixes...)
+	}
+	for _, problem := range tlds.Problems {
+		fmt.Printf("Warning: skipping TLD %s\n", problem)
+	}
+
+	skip := make(map[string]bool)
+	if verify {
+		ctx, cancel := context.WithTimeout(context.Background(), tldCheckTimeout)
+		defer cancel()
+		unknown, unverified := domain.CheckTLDs(ctx, tlds.Suffixes)
+		for _, suffix := range unknown {
+			fmt.Printf("Warning: skipping unknown TLD %s (not delegated in the DNS root)\n", suffix)
+			skip[suffix] = true
+		}
+		if len(unverified) > 0 {
+			fmt.Printf("Warning: could not verify %d TLDs, checking them anyway\n", len(unverified))
+		}
+	}
+
+	var domains []string
+	seen := make(map[string]bool)
+	for _, suffix := range tlds.Suffixes {
+		if !skip[suffix] && !seen[suffix] {
+			seen[suffix] = true
+			domains = append(domains, name+suffix)
+		}
 	}
-	if len(specialStatusDomains) > 0 {
-		fmt.Printf("- Special status domains: %d (require manual review)\n", len(specialStatusDomains))
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no valid TLD to check %q under", name)
 	}
+	fmt.Printf("Checking %s under %d TLDs\n", name, len(domains))
+	return domains, nil
 }
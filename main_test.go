@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"domain-scanner/internal/batch"
+	"domain-scanner/internal/testutil"
+)
+
+// buildBinary compiles the scanner command into a temporary directory
+func buildBinary(t *testing.T) string {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "domain-scanner")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	if out, err := exec.Command("go", "build", "-o", binary, ".").CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+	return binary
+}
+
+// writeConfig writes a config scanning the ten one-digit .test domains with WHOIS
+// only, asking the WHOIS server at addr, and writing the results into dir
+func writeConfig(t *testing.T, dir, addr string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.toml")
+	content := fmt.Sprintf(`[domain]
+length = 1
+suffix = ".test"
+pattern = "d"
+
+[scanner]
+delay = 1
+workers = 2
+whois_servers = { test = %q }
+
+[scanner.methods]
+dns_check = false
+whois_check = true
+
+[output]
+output_dir = %q
+`, addr, dir)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// exitCode returns the exit code of a finished command
+func exitCode(t *testing.T, err error) int {
+	t.Helper()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return 0
+}
+
+// readSummary reads the summary.json of a run
+func readSummary(t *testing.T, dir string) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := make(map[string]interface{})
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	return summary
+}
+
+func TestExitCodes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the binary")
+	}
+	binary := buildBinary(t)
+	dir := t.TempDir()
+	invalidConfig := filepath.Join(dir, "invalid.toml")
+	if err := os.WriteFile(invalidConfig, []byte("[scanner\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missingConfig := filepath.Join(dir, "missing.toml")
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "help", args: []string{"-h"}, want: 0},
+		{name: "flag help", args: []string{"-help", "-config", missingConfig}, want: 0},
+		{name: "unknown flag", args: []string{"-bogus"}, want: 1},
+		{name: "invalid flag value", args: []string{"-workers", "many"}, want: 1},
+		{name: "invalid config", args: []string{"-config", invalidConfig}, want: 1},
+		{name: "invalid option", args: []string{"-config", missingConfig, "-dry-run", "-count-only"}, want: 1},
+		{name: "count only", args: []string{"-config", missingConfig, "-count-only", "-l", "1", "-p", "d"}, want: 0},
+		{name: "batch without command", args: []string{"batch"}, want: 1},
+		{name: "unknown batch command", args: []string{"batch", "bogus"}, want: 1},
+		{name: "batch unknown flag", args: []string{"batch", "status", "-bogus"}, want: 1},
+		{name: "batch help", args: []string{"batch", "status", "-h"}, want: 0},
+		{name: "batch invalid parallelism", args: []string{"batch", "run", "-parallel", "0"}, want: 1},
+		{name: "feed without watchlist", args: []string{"feed", "-config", missingConfig}, want: 1},
+		{name: "serve invalid max scans", args: []string{"serve", "-config", missingConfig, "-max-scans", "-1"}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(binary, tt.args...)
+			cmd.Dir = dir
+			out, err := cmd.CombinedOutput()
+			if got := exitCode(t, err); got != tt.want {
+				t.Errorf("exit code = %d, want %d\n%s", got, tt.want, out)
+			}
+		})
+	}
+}
+
+func TestCountOnlyLeavesBatchStatus(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the binary")
+	}
+	binary := buildBinary(t)
+	dir := t.TempDir()
+	configPath := writeConfig(t, dir, "127.0.0.1:1")
+	config, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.WriteString("\n[batch]\nname = \"b1\"\n"); err != nil {
+		t.Fatal(err)
+	}
+	config.Close()
+
+	completed := batch.NewPendingStatus("b1", configPath, dir)
+	if err := completed.MarkFinished(batch.StateCompleted, batch.Counts{Processed: 10, Available: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []string{"-count-only", "-dry-run"} {
+		cmd := exec.Command(binary, "-config", configPath, mode)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if got := exitCode(t, err); got != 0 {
+			t.Fatalf("%s exit code = %d, want 0\n%s", mode, got, out)
+		}
+		status, err := batch.ReadStatus(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.State != batch.StateCompleted || status.Counts.Processed != 10 || status.PID != 0 {
+			t.Errorf("after %s the batch is %s with %d processed and PID %d, want it completed as before",
+				mode, status.State, status.Counts.Processed, status.PID)
+		}
+		if _, err := os.Stat(filepath.Join(dir, batch.LockFileName)); !os.IsNotExist(err) {
+			t.Errorf("after %s the batch lock exists: %v", mode, err)
+		}
+	}
+}
+
+func TestExitCodeOfScan(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the binary")
+	}
+	binary := buildBinary(t)
+	server, err := testutil.NewWHOISServer(testutil.StaticWHOIS("No match for domain.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	dir := t.TempDir()
+	cmd := exec.Command(binary, "-config", writeConfig(t, dir, server.Addr()))
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if got := exitCode(t, err); got != 0 {
+		t.Fatalf("exit code = %d, want 0\n%s", got, out)
+	}
+
+	summary := readSummary(t, dir)
+	if summary["exit_code"] != 0.0 || summary["exit_meaning"] != "success" {
+		t.Errorf("summary.json exit = %v %v, want 0 success", summary["exit_code"], summary["exit_meaning"])
+	}
+	if summary["available"] != 10.0 {
+		t.Errorf("summary.json available = %v, want 10", summary["available"])
+	}
+	// The result files are complete when the process has exited
+	data, err := os.ReadFile(filepath.Join(dir, "available_domains_d_1_test.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 10 {
+		t.Errorf("available file has %d lines, want 10", lines)
+	}
+}
+
+func TestExitCodeOfInterruptedScan(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the binary")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGINT on Windows")
+	}
+	binary := buildBinary(t)
+	// The WHOIS server answers slowly enough for the scan to be interrupted
+	started := make(chan struct{}, 10)
+	server, err := testutil.NewWHOISServer(func(string) string {
+		started <- struct{}{}
+		time.Sleep(2 * time.Second)
+		return "No match for domain.\n"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	dir := t.TempDir()
+	cmd := exec.Command(binary, "-config", writeConfig(t, dir, server.Addr()))
+	cmd.Dir = dir
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(30 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("the scan did not query WHOIS")
+	}
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	if got := exitCode(t, cmd.Wait()); got != 2 {
+		t.Fatalf("exit code = %d, want 2", got)
+	}
+
+	summary := readSummary(t, dir)
+	if summary["exit_code"] != 2.0 || summary["exit_meaning"] != "scan aborted" || summary["interrupted"] != true {
+		t.Errorf("summary.json = %v, want an interrupted scan with exit code 2", summary)
+	}
+}
@@ -0,0 +1,63 @@
+// Package domain is the public single-domain API of the domain scanner. A Checker
+// checks one domain at a time with DNS, WHOIS, SSL and custom methods; checkers
+// share no state, so a program may use several with different options concurrently.
+package domain
+
+import (
+	"time"
+
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/metrics"
+	"domain-scanner/internal/types"
+)
+
+type (
+	// Checker checks domains with its own options, WHOIS rate limiter and client
+	Checker = domain.Checker
+	// CheckerOptions configure a Checker
+	CheckerOptions = domain.CheckerOptions
+	// Result is the outcome of checking a domain
+	Result = domain.Result
+	// CheckFunc is a custom check method, see CheckerOptions.Custom
+	CheckFunc = domain.CustomCheckFunc
+	// Verdict is the answer of a custom check method
+	Verdict = domain.Verdict
+	// Resolver answers the DNS lookups of a Checker, see CheckerOptions.Resolver
+	Resolver = domain.Resolver
+	// MetricsExporter receives the check method latencies, see CheckerOptions.Metrics
+	MetricsExporter = metrics.Exporter
+	// MetricLabels qualify a metric, e.g. {"method": "whois"}
+	MetricLabels = metrics.Labels
+)
+
+// Verdicts of custom check methods
+const (
+	VerdictUnknown    = domain.VerdictUnknown
+	VerdictRegistered = domain.VerdictRegistered
+	VerdictAvailable  = domain.VerdictAvailable
+	VerdictReserved   = domain.VerdictReserved
+)
+
+// Policies for TLDs with wildcard DNS, see CheckerOptions.WildcardDNS
+const (
+	WildcardAIgnore   = types.WildcardAIgnore
+	WildcardACombined = types.WildcardACombined
+)
+
+// Policies for contradictory WHOIS responses, see CheckerOptions.WHOISConflict
+const (
+	ConflictAvailableWins  = types.ConflictAvailableWins
+	ConflictRegisteredWins = types.ConflictRegisteredWins
+	ConflictUncertain      = types.ConflictUncertain
+)
+
+// NewChecker creates a checker; when no method is enabled, DNS, WHOIS and SSL are used
+func NewChecker(opts CheckerOptions) *Checker {
+	return domain.NewChecker(opts)
+}
+
+// CommandChecker returns a check method running an external command with the domain
+// as its only argument, as configured by [scanner.methods.custom] in config.toml
+func CommandChecker(command string, timeout time.Duration) CheckFunc {
+	return domain.CommandChecker(command, timeout)
+}
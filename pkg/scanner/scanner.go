@@ -0,0 +1,706 @@
+// Package scanner is the public API of the domain scanner. It generates candidate
+// domains, checks them with the configured DNS, WHOIS and SSL methods and reports
+// the results, without printing or exiting; all user interaction is left to the caller.
+//
+// Every Scanner checks domains with a checker of its own built from its config, so
+// scanners of different configs can run side by side. They share the process-wide
+// WHOIS rate limiter, which keeps their queries to the same server apart.
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"domain-scanner/internal/config"
+	"domain-scanner/internal/ctlog"
+	"domain-scanner/internal/domain"
+	"domain-scanner/internal/generator"
+	"domain-scanner/internal/gsheets"
+	"domain-scanner/internal/metrics"
+	"domain-scanner/internal/notify"
+	"domain-scanner/internal/pricing"
+	"domain-scanner/internal/rawdns"
+	core "domain-scanner/internal/scanner"
+	"domain-scanner/internal/scoring"
+	"domain-scanner/internal/types"
+	"domain-scanner/internal/worker"
+)
+
+type (
+	// Config is the scanner configuration, as loaded from a config.toml file
+	Config = types.Config
+	// DomainResult is the outcome of checking a single domain
+	DomainResult = types.DomainResult
+	// SpecialStatusDomain is a domain that needs manual review
+	SpecialStatusDomain = types.SpecialStatusDomain
+	// RegexMode selects whether the regex filter matches the full domain or the name only
+	RegexMode = types.RegexMode
+	// Summary is the outcome of a blocking scan run
+	Summary = core.Summary
+	// TLDStat holds the result counts of one domain suffix
+	TLDStat = core.TLDStat
+	// SlowDomain is the check time of one of the slowest domains of a run, by phase
+	SlowDomain = core.SlowDomain
+	// StatusChange compares the earlier and current status of a rechecked domain
+	StatusChange = core.StatusChange
+	// TLDRow is the status of a domain in the availability table of a reverse mode scan
+	TLDRow = core.TLDRow
+	// PriceQuote is the registration price of an available domain
+	PriceQuote = pricing.Quote
+	// Score is the brandability score of an available domain
+	Score = scoring.Score
+	// Progress is a snapshot of a running scan passed to ScanOptions.OnProgress
+	Progress = core.Progress
+	// ScanState is a stage of a scan passed to ScanOptions.OnStateChange
+	ScanState = core.State
+	// Gate pauses and resumes a running scan
+	Gate = core.Gate
+	// ProgressBar draws the progress of a scan as a single redrawn terminal line
+	ProgressBar = core.ProgressBar
+	// Checker checks a single domain; it replaces the built-in checker, e.g. in tests
+	Checker = worker.CheckFunc
+	// CheckerFunc is a custom check method consulted next to DNS, WHOIS and SSL
+	CheckerFunc = domain.CustomCheckFunc
+	// Verdict is the answer of a custom check method
+	Verdict = domain.Verdict
+	// Pacer spaces the checks of the workers sharing it by WHOIS server
+	Pacer = domain.Pacer
+)
+
+// Regex match modes
+const (
+	RegexModeFull   = types.RegexModeFull
+	RegexModePrefix = types.RegexModePrefix
+)
+
+// Scan states; a run goes from started to aborted or finished, with paused and resumed
+// in between when ScanOptions.Gate is used
+const (
+	StateStarted  = core.StateStarted
+	StatePaused   = core.StatePaused
+	StateResumed  = core.StateResumed
+	StateAborted  = core.StateAborted
+	StateFinished = core.StateFinished
+)
+
+// Custom check method verdicts
+const (
+	VerdictUnknown    = domain.VerdictUnknown
+	VerdictRegistered = domain.VerdictRegistered
+	VerdictAvailable  = domain.VerdictAvailable
+	VerdictReserved   = domain.VerdictReserved
+)
+
+// ConfidenceMedium is the DomainResult.Confidence of CT hits that no check confirmed
+const ConfidenceMedium = types.ConfidenceMedium
+
+// Process exit codes of the scanner command; Summary.ExitCode maps a run to one of them
+const (
+	ExitOK      = core.ExitOK
+	ExitUsage   = core.ExitUsage
+	ExitAborted = core.ExitAborted
+	ExitErrors  = core.ExitErrors
+)
+
+// NewGate creates a gate for ScanOptions.Gate; scans start running
+func NewGate() *Gate {
+	return core.NewGate()
+}
+
+// NewProgressBar returns a progress bar drawn on out, usually os.Stderr. Pass Update
+// as ScanOptions.OnProgress and Writer(os.Stdout) as ScanOptions.Log, and call Finish
+// when the scan returns.
+func NewProgressBar(out io.Writer) *ProgressBar {
+	return core.NewProgressBar(out)
+}
+
+// ExitMeaning describes a process exit code
+func ExitMeaning(code int) string {
+	return core.ExitMeaning(code)
+}
+
+// SummaryFileName is the file in the output directory WriteSummaryFile writes
+const SummaryFileName = core.SummaryFileName
+
+// WriteSummaryFile writes the counts of a finished run with its exit code and the
+// meaning of the code to summary.json in dir and returns its path
+func WriteSummaryFile(dir string, summary *Summary, code int) (string, error) {
+	return core.WriteSummaryFile(dir, summary, code)
+}
+
+// FlagExitCode returns the exit code for an error of flag.FlagSet.Parse: ExitOK for
+// -help and ExitUsage for anything else
+func FlagExitCode(err error) int {
+	return core.FlagExitCode(err)
+}
+
+// RegisterChecker adds a custom check method to the built-in checker of every scan.
+// A registered verdict adds the signature CUSTOM_<NAME> and counts as a registration
+// signal, a reserved verdict marks the domain as not available, and available or
+// unknown verdicts and errors leave the decision to the other methods. Registering
+// a name again replaces the method; a nil fn removes it.
+func RegisterChecker(name string, fn CheckerFunc) {
+	domain.RegisterChecker(name, fn)
+}
+
+// ScanOptions describes a single scan
+type ScanOptions struct {
+	Length int
+	// Lengths generates every listed length in ascending order instead of Length
+	Lengths []int
+	Suffix  string
+	// Suffixes checks every generated name under each of them; Suffix is the first
+	Suffixes []string
+	Pattern  string
+	// Charset generates names from these characters instead of those of Pattern;
+	// Unicode characters such as "äöü" generate internationalized names, checked in
+	// their punycode form
+	Charset string
+	// ExcludeChars removes these characters from the charset of Pattern or Charset,
+	// e.g. "qv0o"; excluding every character is an error
+	ExcludeChars string
+	// NamePrefix and NameSuffix are fixed around the generated characters of every
+	// name; Length, Offset and Limit count the generated characters only
+	NamePrefix string
+	NameSuffix string
+	// Mask generates the names of a mask such as "a??9" instead of Length: letters,
+	// digits and hyphens are fixed, "?d" is a digit, "?l" a letter and "?" a character
+	// of Pattern or Charset; only the wildcards are enumerated
+	Mask string
+	// LetterPattern generates the names of a repeated-letter pattern such as "AAB" or
+	// "ABAB" instead of Length: positions with equal letters take the same character of
+	// Pattern or Charset and different letters different ones
+	LetterPattern string
+	// Template generates the names of a consonant/vowel template such as "CVCV"
+	// instead of Length and Pattern: C is a consonant, V a vowel, L a letter and N a digit
+	Template string
+	// Syllables is the number of syllables of the names of Pattern "pronounceable",
+	// which composes names of syllables such as "tavo" instead of Length characters
+	Syllables   int
+	RegexFilter string
+	RegexMode   RegexMode
+	// WordLists checks every concatenation of one word from each list file instead of the keyspace
+	WordLists []string
+	// WordSeparator joins the words of a combination, e.g. "-" for blue-fox
+	WordSeparator string
+	// Leet checks a seed word and all its leetspeak variants instead of the keyspace,
+	// e.g. shop, sh0p, 5hop and 5h0p
+	Leet string
+	// LeetTable replaces the built-in substitutions (o-0, i-1, e-3, a-4, s-5, t-7);
+	// every substitution also applies the other way round
+	LeetTable map[string]string
+	// KeywordFile checks the keywords of this list file combined with the affixes of
+	// AffixFile instead of the keyspace; AffixPosition is "prefix", "suffix" or "both"
+	// (the default) and KeywordAlone also checks every keyword by itself
+	KeywordFile   string
+	AffixFile     string
+	AffixPosition string
+	KeywordAlone  bool
+	// NumRange checks the integers of a range such as "8000-8999" as names instead of
+	// the keyspace, zero-padded to NumPad digits; ranges of more than MaxNumRange
+	// numbers (default 10000000) are refused
+	NumRange    string
+	NumPad      int
+	MaxNumRange int
+	// InputFile checks the names of a list file, one per line, under Suffix instead of the keyspace
+	InputFile string
+	// Offset and Limit restrict generation to the keyspace counter range [offset, offset+limit)
+	Offset int
+	Limit  int
+	// StartFrom resumes the keyspace at this name, skipping every earlier one; an
+	// interrupted run reports where to resume in Summary.ResumeFrom
+	StartFrom string
+	// Shuffle generates the keyspace in a pseudorandom order that depends only on Seed;
+	// a zero Seed is replaced by a random one, reported in Summary.Seed
+	Shuffle bool
+	Seed    int64
+	// Sample, when positive, checks only this many distinct random names of the keyspace
+	// (of the regex-filtered one with RegexFilter); Summary.Sample and SampleSpace
+	// extrapolate the availability
+	Sample int
+	// Domains, when set, is checked instead of generating candidates
+	Domains <-chan string
+	// DropDates annotates the results of the listed domains with their drop date
+	DropDates map[string]string
+	// Permutations annotates the results of typo variants with the permutation that
+	// produced them, e.g. "omission"; result files group the domains by it
+	Permutations map[string]string
+	// Previous holds the earlier status of supplied domains; Run compares it with
+	// their new status in Summary.Changes
+	Previous map[string]string
+	// BlocklistMode applies the [domain] blocklist: "drop" removes matching candidates,
+	// "flag" checks them but marks them TRADEMARK_RISK; empty ignores the blocklist
+	BlocklistMode string
+	// ExcludeFile lists domains, or names under every suffix, that are skipped without
+	// any query; the skipped candidates are counted in Summary.Excluded
+	ExcludeFile string
+	// ZoneFiles are TLD zone files, plain or gzip-compressed; domains delegated in them
+	// are reported registered with the ZONEFILE signature without any query
+	ZoneFiles []string
+	// ZoneFalsePositiveRate > 0 keeps the zone names in a bloom filter with this
+	// false-positive rate instead of an exact set; hits are confirmed with an NS lookup
+	ZoneFalsePositiveRate float64
+	// CTCheck looks every candidate up in Certificate Transparency logs ([ct] section)
+	// first; hits are reported registered with the CT_LOG signature and ConfidenceMedium,
+	// or still checked when CTVerify is set. Lookup failures fall through to the normal checks.
+	CTCheck  bool
+	CTVerify bool
+	// Prefilter classifies candidates in bulk with raw NS queries ([scanner] prefilter =
+	// "rawdns") before the full checks; names with NS records are reported registered
+	// with the RAWDNS_NS signature
+	Prefilter bool
+
+	Delay          time.Duration
+	Workers        int
+	ShowRegistered bool
+	// ExpiringBefore, when set, limits the registered domains listed under
+	// ShowRegistered to those whose WHOIS expiration date lies before it
+	ExpiringBefore time.Time
+
+	// RetryRateLimited rechecks WHOIS rate-limited domains at the end of Run
+	RetryRateLimited bool
+	RetryDelay       time.Duration
+	RetryWorkers     int
+
+	// LookupPrices makes Run quote the available domains at the [pricing] provider
+	LookupPrices bool
+	// PublishSheets makes Run append the available domains to the [output.gsheets] spreadsheet
+	PublishSheets bool
+	// Notify makes Run post the available domains to the [notify.webhook] URL as they are found
+	Notify bool
+	// Score makes Run rate the available domains with the [scoring] weights
+	Score bool
+
+	// ExpectedCount makes Run warn when a different number of domains is generated
+	ExpectedCount *int
+	// DebugIndex adds the generator counter of each domain to the progress log
+	DebugIndex bool
+	// SlowThreshold logs a warning with the time per phase for every domain whose check
+	// takes at least this long; zero disables the warnings
+	SlowThreshold time.Duration
+
+	// WriteFiles makes Run write the result files named by the config's [output] section
+	WriteFiles bool
+	// FileSuffix is inserted before the extension of every result file name, e.g. "_retry"
+	FileSuffix string
+	// TrackStatuses records the final status of every checked domain in Summary.Statuses
+	TrackStatuses bool
+	// TLDTable lists the checked domains by TLD, available first, in Summary.TLDTable and
+	// the [output] tld_table_file, for checking one name across many TLDs
+	TLDTable bool
+	// Log receives progress and checker log lines; nil discards them
+	Log io.Writer
+	// Prefix is prepended to every progress line
+	Prefix string
+	// Checker replaces the built-in checker when set
+	Checker Checker
+	// Gate pauses and resumes the scan: while paused, workers finish their current
+	// check and wait before the next one. nil never pauses.
+	Gate *Gate
+	// OnResult, OnProgress and OnStateChange are called by Run from a single goroutine,
+	// never concurrently, so they need no locking among themselves. OnResult receives
+	// every domain checked before the rate-limit retry, OnProgress a snapshot every
+	// ProgressInterval (default one second) and once at the end, and OnStateChange the
+	// start, the pauses and resumes of Gate and the end of the run. Callbacks never stall the scan: up to HookQueue
+	// (default 1000) events wait for slow callbacks, further results and snapshots are
+	// dropped and counted in Progress.Dropped and Summary.HookEventsDropped. A panic in
+	// a callback is recovered and reported in the log.
+	OnResult         func(DomainResult)
+	OnProgress       func(Progress)
+	OnStateChange    func(ScanState)
+	ProgressInterval time.Duration
+	HookQueue        int
+}
+
+// Scanner checks domains with a fixed configuration
+type Scanner struct {
+	cfg *Config
+	// pricer is shared by all runs so that cached prices and rate limits carry over
+	pricer  pricing.Provider
+	scorer  *scoring.Scorer
+	metrics metrics.Exporter
+	sheets  *gsheets.Client
+	webhook *notify.Webhook
+	// blocklist holds the [domain] blocklist entries; nil when none is configured
+	blocklist *generator.Blocklist
+	// ct is shared by all runs so that cached answers and the query spacing carry over
+	ct *ctlog.Client
+	// prefilter is the configured bulk DNS prefilter; nil when none is configured
+	prefilter *rawdns.Prefilter
+	// checker is the checker of the config, used unless ScanOptions.Checker replaces
+	// it; every scan of the scanner shares its WHOIS limiter and rate limits
+	checker *domain.Checker
+}
+
+// New validates the configuration, fills in its defaults and creates the checker of
+// the scanner. The checker, price, CT, Sheets and webhook lookups of the scanner share
+// an HTTP client configured by [scanner.http]; scanners of different configs leave
+// each other and the rest of the process alone.
+func New(cfg Config) (*Scanner, error) {
+	if err := config.ApplyDefaults(&cfg); err != nil {
+		return nil, err
+	}
+	scorer, err := scoring.FromConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := metrics.FromConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	blocklist, err := generator.BlocklistFromConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	prefilter, err := rawdns.FromConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	checker := domain.NewConfigChecker(&cfg, exporter)
+	client := checker.HTTPClient()
+	webhook, err := notify.FromConfig(&cfg, client)
+	if err != nil {
+		return nil, err
+	}
+	return &Scanner{cfg: &cfg, pricer: pricing.FromConfig(&cfg, client), scorer: scorer, metrics: exporter,
+		sheets: gsheets.FromConfig(&cfg, client), webhook: webhook, blocklist: blocklist,
+		ct: ctlog.FromConfig(&cfg, client), prefilter: prefilter, checker: checker}, nil
+}
+
+// Checker returns the check function of the scanner's config, as used by scans whose
+// options set no Checker. Its log lines are discarded.
+func (s *Scanner) Checker() Checker {
+	return s.CheckerWithLog(nil)
+}
+
+// CheckerWithLog is like Checker but writes the log lines of the checks, such as
+// special statuses and WHOIS conflicts, to w
+func (s *Scanner) CheckerWithLog(w io.Writer) Checker {
+	return worker.CheckWith(s.checker.WithLog(w))
+}
+
+// NewPacer returns the pacer giving workers sharing it the average rate of workers
+// that each wait delay between two checks, spacing the checks by the WHOIS servers of
+// the scanner's config; nil when delay is zero
+func (s *Scanner) NewPacer(delay time.Duration, workers int) *Pacer {
+	return worker.NewPacer(delay, workers, s.checker)
+}
+
+// Check checks a single domain with the configured methods. The error is that of the
+// result; special statuses needing manual review are reported in the result.
+func (s *Scanner) Check(name string) (DomainResult, error) {
+	return s.CheckContext(context.Background(), name)
+}
+
+// CheckContext is like Check but aborts WHOIS retries and backoff waits as soon as
+// ctx is cancelled
+func (s *Scanner) CheckContext(ctx context.Context, name string) (DomainResult, error) {
+	result := s.Checker()(ctx, name)
+	return result, result.Error
+}
+
+// CheckBatch checks a list of domains with the configured workers and delay and
+// streams the results in the order they complete. The channel is closed once every
+// domain has been checked; cancelling ctx stops dispatching the remaining domains.
+// Read the channel until it is closed.
+func (s *Scanner) CheckBatch(ctx context.Context, domains []string) <-chan DomainResult {
+	names := make(chan string)
+	go func() {
+		defer close(names)
+		for _, name := range domains {
+			select {
+			case names <- name:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	results, err := s.Scan(ctx, ScanOptions{
+		Domains: names,
+		Workers: s.cfg.Scanner.Workers,
+		Delay:   time.Duration(s.cfg.Scanner.Delay) * time.Millisecond,
+	})
+	if err == nil {
+		return results
+	}
+	// Scans of supplied domains only fail to start on a broken setup; every domain
+	// reports the failure
+	failed := make(chan DomainResult)
+	go func() {
+		defer close(failed)
+		for name := range names {
+			failed <- DomainResult{Domain: name, Error: err}
+		}
+	}()
+	return failed
+}
+
+// TestSheets appends a single test row to the [output.gsheets] spreadsheet to validate
+// the credentials and the spreadsheet access
+func (s *Scanner) TestSheets(ctx context.Context) error {
+	if s.sheets == nil {
+		return fmt.Errorf("no spreadsheet configured in [output.gsheets]")
+	}
+	row := make([]string, len(gsheets.Header))
+	row[0], row[1] = time.Now().UTC().Format(time.RFC3339), "domain-scanner test row"
+	return s.sheets.Append(ctx, [][]string{row})
+}
+
+// Close flushes the metrics configured in [metrics]; the scanner must not be used afterwards
+func (s *Scanner) Close() error {
+	return s.metrics.Close()
+}
+
+// Config returns the effective configuration including defaults
+func (s *Scanner) Config() Config {
+	return *s.cfg
+}
+
+// DefaultOptions returns the scan options described by the configuration
+func (s *Scanner) DefaultOptions() ScanOptions {
+	opts := core.OptionsFromConfig(s.cfg)
+	return ScanOptions{
+		Length:           opts.Length,
+		Suffix:           opts.Suffix,
+		Suffixes:         opts.Suffixes,
+		Pattern:          opts.Pattern,
+		Charset:          opts.Charset,
+		ExcludeChars:     opts.ExcludeChars,
+		NamePrefix:       opts.NamePrefix,
+		NameSuffix:       opts.NameSuffix,
+		Mask:             opts.Mask,
+		LetterPattern:    opts.LetterPattern,
+		Template:         opts.Template,
+		Syllables:        opts.Syllables,
+		RegexFilter:      opts.RegexFilter,
+		RegexMode:        opts.RegexMode,
+		WordLists:        opts.WordLists,
+		WordSeparator:    opts.WordSeparator,
+		Leet:             opts.Leet,
+		LeetTable:        opts.LeetTable,
+		KeywordFile:      opts.KeywordFile,
+		AffixFile:        opts.AffixFile,
+		AffixPosition:    opts.AffixPosition,
+		KeywordAlone:     opts.KeywordAlone,
+		NumRange:         opts.NumRange,
+		NumPad:           opts.NumPad,
+		MaxNumRange:      opts.MaxNumRange,
+		InputFile:        opts.InputFile,
+		Offset:           opts.Offset,
+		StartFrom:        opts.StartFrom,
+		Shuffle:          opts.Shuffle,
+		Seed:             opts.Seed,
+		Sample:           opts.Sample,
+		Limit:            opts.Limit,
+		Delay:            opts.Delay,
+		Workers:          opts.Workers,
+		ShowRegistered:   opts.ShowRegistered,
+		ExpiringBefore:   opts.ExpiringBefore,
+		RetryRateLimited: opts.RetryRateLimited,
+		RetryDelay:       opts.RetryDelay,
+		RetryWorkers:     opts.RetryWorkers,
+		ExpectedCount:    opts.ExpectedCount,
+		LookupPrices:     s.pricer != nil,
+		Score:            s.cfg.Scoring.Enabled,
+		PublishSheets:    s.sheets != nil,
+		Notify:           s.webhook != nil,
+		BlocklistMode:    s.blocklistMode(),
+		CTCheck:          s.cfg.CT.Enabled,
+		CTVerify:         s.cfg.CT.Verify,
+		Prefilter:        s.prefilter != nil,
+	}
+}
+
+// blocklistMode returns the configured blocklist mode, or empty without a blocklist
+func (s *Scanner) blocklistMode() string {
+	if s.blocklist == nil {
+		return ""
+	}
+	return s.cfg.Domain.BlocklistMode
+}
+
+// Scan starts a scan and streams the results. The channel is closed once every
+// dispatched domain has been checked; cancelling ctx stops dispatching new domains
+// and interrupts in-flight checks. Results cut short by cancellation carry ctx.Err().
+// Read the channel until it is closed; the workers block until their results are taken.
+func (s *Scanner) Scan(ctx context.Context, opts ScanOptions) (<-chan DomainResult, error) {
+	return core.Stream(ctx, s.coreOptions(opts))
+}
+
+// Generate returns the domains a scan with these options would check, without checking
+// them. The channel is closed once the keyspace is exhausted and must be drained.
+func (s *Scanner) Generate(opts ScanOptions) (<-chan string, error) {
+	return s.GenerateContext(context.Background(), opts)
+}
+
+// GenerateContext is like Generate but stops generating and closes the channel once
+// ctx is cancelled, so the channel need not be drained after a cancellation
+func (s *Scanner) GenerateContext(ctx context.Context, opts ScanOptions) (<-chan string, error) {
+	domains, _, err := core.Generate(ctx, s.coreOptions(opts))
+	return domains, err
+}
+
+// Count returns the number of domains a scan with these options would check, without
+// generating them. It is only known up front for a generated keyspace without a regex
+// filter, exclusion list or dropping blocklist, and not for every hyphen or Unicode
+// charset; otherwise ok is false and the domains of GenerateContext have to be counted.
+func (s *Scanner) Count(ctx context.Context, opts ScanOptions) (count int, ok bool, err error) {
+	return core.Count(ctx, s.coreOptions(opts))
+}
+
+// Run scans and blocks until the scan is finished or ctx is cancelled, in which case
+// the partial summary is returned with Interrupted set
+func (s *Scanner) Run(ctx context.Context, opts ScanOptions) (*Summary, error) {
+	return core.Run(ctx, s.coreOptions(opts))
+}
+
+// coreOptions converts public scan options to the internal scan options
+func (s *Scanner) coreOptions(opts ScanOptions) core.Options {
+	var pricer pricing.Provider
+	if opts.LookupPrices {
+		pricer = s.pricer
+	}
+	var sheets *gsheets.Client
+	if opts.PublishSheets {
+		sheets = s.sheets
+	}
+	var webhook *notify.Webhook
+	if opts.Notify {
+		webhook = s.webhook
+	}
+	var blocklist *generator.Blocklist
+	if opts.BlocklistMode != "" {
+		blocklist = s.blocklist
+	}
+	var ct *ctlog.Client
+	if opts.CTCheck {
+		ct = s.ct
+	}
+	var prefilter *rawdns.Prefilter
+	if opts.Prefilter {
+		prefilter = s.prefilter
+	}
+	var scorer *scoring.Scorer
+	if opts.Score {
+		scorer = s.scorer
+	}
+	// The checker of the config writes its log lines to the log of the scan
+	checker := opts.Checker
+	if checker == nil {
+		checker = s.CheckerWithLog(opts.Log)
+	}
+	return core.Options{
+		Length:           opts.Length,
+		Lengths:          opts.Lengths,
+		Suffix:           opts.Suffix,
+		Suffixes:         opts.Suffixes,
+		Pattern:          opts.Pattern,
+		Charset:          opts.Charset,
+		ExcludeChars:     opts.ExcludeChars,
+		NamePrefix:       opts.NamePrefix,
+		NameSuffix:       opts.NameSuffix,
+		Mask:             opts.Mask,
+		LetterPattern:    opts.LetterPattern,
+		Template:         opts.Template,
+		Syllables:        opts.Syllables,
+		RegexFilter:      opts.RegexFilter,
+		RegexMode:        opts.RegexMode,
+		WordLists:        opts.WordLists,
+		WordSeparator:    opts.WordSeparator,
+		Leet:             opts.Leet,
+		LeetTable:        opts.LeetTable,
+		KeywordFile:      opts.KeywordFile,
+		AffixFile:        opts.AffixFile,
+		AffixPosition:    opts.AffixPosition,
+		KeywordAlone:     opts.KeywordAlone,
+		NumRange:         opts.NumRange,
+		NumPad:           opts.NumPad,
+		MaxNumRange:      opts.MaxNumRange,
+		InputFile:        opts.InputFile,
+		Offset:           opts.Offset,
+		StartFrom:        opts.StartFrom,
+		Shuffle:          opts.Shuffle,
+		Seed:             opts.Seed,
+		Sample:           opts.Sample,
+		Limit:            opts.Limit,
+		ExpectedCount:    opts.ExpectedCount,
+		Delay:            opts.Delay,
+		Workers:          opts.Workers,
+		ShowRegistered:   opts.ShowRegistered,
+		ExpiringBefore:   opts.ExpiringBefore,
+		RetryRateLimited: opts.RetryRateLimited,
+		RetryDelay:       opts.RetryDelay,
+		RetryWorkers:     opts.RetryWorkers,
+		Domains:          opts.Domains,
+		DropDates:        opts.DropDates,
+		Permutations:     opts.Permutations,
+		Previous:         opts.Previous,
+		Blocklist:        blocklist,
+		BlocklistMode:    opts.BlocklistMode,
+		ExcludeFile:      opts.ExcludeFile,
+		ZoneFiles:        opts.ZoneFiles,
+		CT:               ct,
+		CTVerify:         opts.CTVerify,
+		Prefilter:        prefilter,
+		Checker:          checker,
+		DomainChecker:    s.checker,
+		Gate:             opts.Gate,
+		OnResult:         opts.OnResult,
+		OnProgress:       opts.OnProgress,
+		OnStateChange:    opts.OnStateChange,
+		ProgressInterval: opts.ProgressInterval,
+		HookQueue:        opts.HookQueue,
+		Pricer:           pricer,
+		Scorer:           scorer,
+		Sheets:           sheets,
+		Webhook:          webhook,
+		Metrics:          s.metrics,
+		Config:           s.cfg,
+		SkipWrite:        !opts.WriteFiles,
+		FileSuffix:       opts.FileSuffix,
+		TrackStatuses:    opts.TrackStatuses,
+		TLDTable:         opts.TLDTable,
+		Output:           logWriter(opts.Log),
+		Prefix:           opts.Prefix,
+		DebugIndex:       opts.DebugIndex,
+		SlowThreshold:    opts.SlowThreshold,
+
+		ZoneFalsePositiveRate: opts.ZoneFalsePositiveRate,
+	}
+}
+
+// logWriter returns the log destination, discarding output when none is set
+func logWriter(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+	return w
+}
+
+// PrintSummary writes the result file locations and counts of a finished run
+func PrintSummary(w io.Writer, summary *Summary, showRegistered bool) {
+	core.PrintSummary(w, summary, showRegistered)
+}
+
+// PrintTLDStats writes the per-suffix availability table of a finished run
+func PrintTLDStats(w io.Writer, summary *Summary) {
+	core.PrintTLDStats(w, summary)
+}
+
+// PrintSlowest writes the slowest checks of a finished run with their time per phase
+func PrintSlowest(w io.Writer, summary *Summary) {
+	core.PrintSlowest(w, summary)
+}
+
+// PrintTLDTable writes the availability by TLD of a reverse mode scan with its free,
+// taken and uncertain counts
+func PrintTLDTable(w io.Writer, summary *Summary) {
+	core.PrintTLDTable(w, summary)
+}
+
+// PrintStatusChanges writes the rechecked domains whose status changed since the earlier run
+func PrintStatusChanges(w io.Writer, summary *Summary) {
+	core.PrintStatusChanges(w, summary)
+}
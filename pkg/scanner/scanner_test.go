@@ -0,0 +1,305 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"domain-scanner/internal/testutil"
+)
+
+// syncBuffer is a log shared by the workers of a scan
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// newWHOISServer starts a fake WHOIS server answering every query with response
+func newWHOISServer(t *testing.T, response string) *testutil.WHOISServer {
+	t.Helper()
+	server, err := testutil.NewWHOISServer(testutil.StaticWHOIS(response))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+	return server
+}
+
+// newWHOISScanner creates a scanner checking .test domains with WHOIS only, asking
+// the server at addr
+func newWHOISScanner(t *testing.T, addr string) *Scanner {
+	t.Helper()
+	cfg := Config{}
+	cfg.Scanner.Methods.WHOISCheck = true
+	cfg.Scanner.WHOISServers = map[string]string{"test": addr}
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestScannersKeepTheirOwnConfig(t *testing.T) {
+	special := newWHOISServer(t, "Status: redemptionPeriod\n")
+	free := newWHOISServer(t, "No match for domain.\n")
+	// Creating the second scanner must not change the WHOIS server of the first
+	first := newWHOISScanner(t, special.Addr())
+	second := newWHOISScanner(t, free.Addr())
+
+	result, err := first.Check("one.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Available || result.SpecialStatus != "REDEMPTIONPERIOD" {
+		t.Errorf("first Check() = available %v, special %q, want REDEMPTIONPERIOD", result.Available, result.SpecialStatus)
+	}
+	result, err = second.Check("two.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Available {
+		t.Errorf("second Check() = %+v, want available", result)
+	}
+	if special.Queries("two.test") != 0 || free.Queries("one.test") != 0 {
+		t.Errorf("a scanner asked the WHOIS server of the other")
+	}
+}
+
+func TestScansLogToTheirOwnWriter(t *testing.T) {
+	special := newWHOISServer(t, "Status: redemptionPeriod\n")
+	free := newWHOISServer(t, "No match for domain.\n")
+	scans := []struct {
+		scanner *Scanner
+		domain  string
+		log     *syncBuffer
+	}{
+		{scanner: newWHOISScanner(t, special.Addr()), domain: "one.test", log: &syncBuffer{}},
+		{scanner: newWHOISScanner(t, free.Addr()), domain: "two.test", log: &syncBuffer{}},
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(scans))
+	for i, scan := range scans {
+		domains := make(chan string, 1)
+		domains <- scan.domain
+		close(domains)
+		wg.Add(1)
+		go func(i int, s *Scanner, log *syncBuffer) {
+			defer wg.Done()
+			_, errs[i] = s.Run(context.Background(), ScanOptions{Domains: domains, Workers: 1, Log: log})
+		}(i, scan.scanner, scan.log)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if log := scans[0].log.String(); !strings.Contains(log, "SPECIAL STATUS: one.test") {
+		t.Errorf("log of the first scan lacks its special status:\n%s", log)
+	}
+	if log := scans[1].log.String(); strings.Contains(log, "one.test") {
+		t.Errorf("log of the second scan has a line of the first:\n%s", log)
+	}
+}
+
+// fakeChecker answers without any query: names listed in special get that special
+// status, names in failing an error, and the rest are available when available says so
+func fakeChecker(available func(name string) bool, special map[string]string, failing map[string]bool) Checker {
+	return func(ctx context.Context, name string) DomainResult {
+		result := DomainResult{Domain: name}
+		switch {
+		case failing[name]:
+			result.Error = errors.New("lookup failed")
+		case special[name] != "":
+			result.SpecialStatus = special[name]
+		default:
+			result.Available = available(name)
+		}
+		return result
+	}
+}
+
+// evenNumber reports whether the name of a domain like "42.test" is an even number
+func evenNumber(name string) bool {
+	n, err := strconv.Atoi(strings.TrimSuffix(name, ".test"))
+	return err == nil && n%2 == 0
+}
+
+// domainSource supplies names as ScanOptions.Domains
+func domainSource(names ...string) <-chan string {
+	domains := make(chan string, len(names))
+	for _, name := range names {
+		domains <- name
+	}
+	close(domains)
+	return domains
+}
+
+func TestRunWithFakeChecker(t *testing.T) {
+	s, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		opts           ScanOptions
+		wantProcessed  int
+		wantAvailable  int
+		wantRegistered int
+		wantErrors     int
+		wantUncertain  int
+	}{
+		{
+			name:          "generated keyspace",
+			opts:          ScanOptions{Length: 2, Suffix: ".test", Pattern: "d"},
+			wantProcessed: 100, wantAvailable: 50, wantRegistered: 50,
+		},
+		{
+			name:          "keyspace range",
+			opts:          ScanOptions{Length: 2, Suffix: ".test", Pattern: "d", Offset: 10, Limit: 21},
+			wantProcessed: 21, wantAvailable: 11, wantRegistered: 10,
+		},
+		{
+			name:          "regex filter",
+			opts:          ScanOptions{Length: 2, Suffix: ".test", Pattern: "d", RegexFilter: "^1"},
+			wantProcessed: 10, wantAvailable: 5, wantRegistered: 5,
+		},
+		{
+			name:          "supplied domains",
+			opts:          ScanOptions{Domains: domainSource("2.test", "3.test", "4.test", "5.test", "6.test")},
+			wantProcessed: 5, wantAvailable: 2, wantRegistered: 1, wantErrors: 1, wantUncertain: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Workers = 4
+			opts.Checker = fakeChecker(evenNumber, map[string]string{"5.test": "REDEMPTIONPERIOD"}, map[string]bool{"6.test": true})
+			summary, err := s.Run(context.Background(), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.Processed != tt.wantProcessed || len(summary.Available) != tt.wantAvailable ||
+				summary.RegisteredCount != tt.wantRegistered || summary.Errors != tt.wantErrors || summary.Uncertain != tt.wantUncertain {
+				t.Errorf("Run() processed %d, available %d, registered %d, errors %d, uncertain %d; want %d, %d, %d, %d, %d",
+					summary.Processed, len(summary.Available), summary.RegisteredCount, summary.Errors, summary.Uncertain,
+					tt.wantProcessed, tt.wantAvailable, tt.wantRegistered, tt.wantErrors, tt.wantUncertain)
+			}
+			for _, name := range summary.Available {
+				if !evenNumber(name) {
+					t.Errorf("Run() reported %s available", name)
+				}
+			}
+			if summary.Interrupted {
+				t.Errorf("Run() reported an interrupted scan")
+			}
+		})
+	}
+}
+
+func TestScanChecksEveryGeneratedDomainOnce(t *testing.T) {
+	s, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts ScanOptions
+	}{
+		{name: "digits", opts: ScanOptions{Length: 2, Suffix: ".test", Pattern: "d"}},
+		{name: "letters", opts: ScanOptions{Length: 2, Suffix: ".test", Pattern: "D"}},
+		{name: "shuffled", opts: ScanOptions{Length: 2, Suffix: ".test", Pattern: "d", Shuffle: true, Seed: 7}},
+		{name: "mask", opts: ScanOptions{Suffix: ".test", Pattern: "D", Mask: "x?d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var generated []string
+			names, err := s.Generate(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name := range names {
+				generated = append(generated, name)
+			}
+			if count, ok, err := s.Count(context.Background(), tt.opts); err != nil || (ok && count != len(generated)) {
+				t.Errorf("Count() = %d %v %v, want %d", count, ok, err, len(generated))
+			}
+
+			opts := tt.opts
+			opts.Workers = 3
+			opts.Checker = fakeChecker(func(string) bool { return true }, nil, nil)
+			results, err := s.Scan(context.Background(), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checked := make(map[string]int)
+			for result := range results {
+				checked[result.Domain]++
+			}
+			if len(checked) != len(generated) {
+				t.Errorf("Scan() checked %d domains, Generate() returned %d", len(checked), len(generated))
+			}
+			for _, name := range generated {
+				if checked[name] != 1 {
+					t.Errorf("%s checked %d times, want once", name, checked[name])
+				}
+			}
+		})
+	}
+}
+
+func TestRunInterruptedWithFakeChecker(t *testing.T) {
+	s, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The fifth check cancels the scan and every check from then on waits for it
+	var checks int32
+	checker := func(ctx context.Context, name string) DomainResult {
+		if atomic.AddInt32(&checks, 1) >= 5 {
+			cancel()
+			<-ctx.Done()
+			return DomainResult{Domain: name, Error: ctx.Err()}
+		}
+		return DomainResult{Domain: name, Available: true}
+	}
+	summary, err := s.Run(ctx, ScanOptions{Length: 2, Suffix: ".test", Pattern: "d", Workers: 1, Checker: checker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Interrupted {
+		t.Fatal("Run() did not report the interruption")
+	}
+	if summary.Processed != 4 || summary.ResumeFrom != "04" {
+		t.Errorf("Run() processed %d, resumes from %q; want 4 and 04", summary.Processed, summary.ResumeFrom)
+	}
+	if code := summary.ExitCode(); code != ExitAborted {
+		t.Errorf("ExitCode() = %d, want %d", code, ExitAborted)
+	}
+}
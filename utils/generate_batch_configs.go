@@ -1,61 +1,383 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"domain-scanner/internal/batch"
+	"domain-scanner/internal/generator"
+	"domain-scanner/internal/types"
 )
 
+// batchSpec describes a single generated batch
+type batchSpec struct {
+	name        string
+	description string
+	charType    string
+	regex       string
+	offset      int
+	limit       int
+	count       int
+	counted     bool
+	spanStart   string
+	spanEnd     string
+	expected    *int
+	configPath  string
+	outputDir   string
+}
+
+// scannerSettings holds the scanner tuning written into every generated config
+type scannerSettings struct {
+	workers        int
+	delay          int
+	showRegistered bool
+	dnsCheck       bool
+	whoisCheck     bool
+	sslCheck       bool
+	httpCheck      bool
+	rdapCheck      bool
+}
+
+// newScannerSettings validates the scanner tuning arguments
+func newScannerSettings(workers, delay int, methods string, showRegistered bool) (scannerSettings, error) {
+	settings := scannerSettings{workers: workers, delay: delay, showRegistered: showRegistered}
+	if workers < 1 {
+		return settings, fmt.Errorf("-workers must be at least 1")
+	}
+	if delay < 0 {
+		return settings, fmt.Errorf("-delay must not be negative")
+	}
+	for _, method := range strings.Split(methods, ",") {
+		switch strings.ToLower(strings.TrimSpace(method)) {
+		case "dns":
+			settings.dnsCheck = true
+		case "whois":
+			settings.whoisCheck = true
+		case "ssl":
+			settings.sslCheck = true
+		case "http":
+			settings.httpCheck = true
+		case "rdap":
+			settings.rdapCheck = true
+		case "":
+		default:
+			return settings, fmt.Errorf("unknown detection method %q (use dns, rdap, whois, ssl, http)", method)
+		}
+	}
+	if len(settings.methodNames()) == 0 {
+		return settings, fmt.Errorf("-methods must enable at least one detection method")
+	}
+	return settings, nil
+}
+
+// methodNames lists the enabled detection methods
+func (s scannerSettings) methodNames() []string {
+	var names []string
+	if s.dnsCheck {
+		names = append(names, "dns")
+	}
+	if s.rdapCheck {
+		names = append(names, "rdap")
+	}
+	if s.whoisCheck {
+		names = append(names, "whois")
+	}
+	if s.sslCheck {
+		names = append(names, "ssl")
+	}
+	if s.httpCheck {
+		names = append(names, "http")
+	}
+	return names
+}
+
 func main() {
 	generateBatchConfigs()
 }
 
 func generateBatchConfigs() {
 	// Parse command line arguments
-	args := os.Args[1:]
-	batchStart := 0
-	batchSize := 26
-	baseDomain := ".de"
-	domainLength := 4
-	pattern := "D"
-	outputDir := "./results"
-	configDir := "./config"
-	
-	for i := 0; i < len(args); i += 2 {
-		if i+1 >= len(args) {
-			break
-		}
-		switch args[i] {
-		case "-batch-start":
-			if val, err := strconv.Atoi(args[i+1]); err == nil {
-				batchStart = val
-			}
-		case "-batch-size":
-			if val, err := strconv.Atoi(args[i+1]); err == nil {
-				batchSize = val
-			}
-		case "-base-domain":
-			baseDomain = args[i+1]
-		case "-domain-length":
-			if val, err := strconv.Atoi(args[i+1]); err == nil {
-				domainLength = val
-			}
-		case "-pattern":
-			pattern = args[i+1]
-		case "-output-dir":
-			outputDir = args[i+1]
-		case "-config-dir":
-			configDir = args[i+1]
-		}
+	var (
+		batchStart, batchSize, domainLength, batchCount, expectCap int
+		baseDomain, pattern, outputDir, configDir, splitBy         string
+		regexFilter                                                string
+	)
+	flag.IntVar(&batchStart, "batch-start", 0, "Index of the first prefix batch")
+	flag.IntVar(&batchSize, "batch-size", 26, "Number of prefix batches to generate")
+	flag.StringVar(&baseDomain, "base-domain", ".de", "Domain suffix")
+	flag.IntVar(&domainLength, "domain-length", 4, "Domain length")
+	flag.StringVar(&pattern, "pattern", "D", "Domain pattern (d: numbers, D: letters, a: alphanumeric, h: alphanumeric with hyphens)")
+	flag.StringVar(&outputDir, "output-dir", "./results", "Base directory for batch results")
+	flag.StringVar(&configDir, "config-dir", "./config", "Directory for the generated configs")
+	flag.StringVar(&splitBy, "split-by", "prefix", "Split mode: prefix, count or range")
+	flag.IntVar(&batchCount, "batches", 26, "Number of batches with -split-by count")
+	chunk := flag.Int("chunk", 1000, "Numbers per batch with -split-by range")
+	flag.StringVar(&regexFilter, "regex", "", "Regex filter applied to the keyspace with -split-by count or range")
+	flag.IntVar(&expectCap, "expect-cap", 1000000, "Maximum candidates enumerated per batch to compute its expected count")
+	workers := flag.Int("workers", 8, "Number of concurrent workers in every batch")
+	delay := flag.Int("delay", 1000, "Delay between queries in milliseconds in every batch")
+	methods := flag.String("methods", "dns,whois", "Comma-separated detection methods: dns, rdap, whois, ssl, http")
+	showRegistered := flag.Bool("show-registered", true, "Show registered domains in batch output")
+	force := flag.Bool("force", false, "Reuse existing non-empty batch output directories")
+	clean := flag.Bool("clean", false, "Delete the contents of existing batch output directories first")
+	yes := flag.Bool("yes", false, "Do not ask for confirmation before -clean deletes files")
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		fmt.Printf("Unexpected arguments: %s\n", strings.Join(flag.Args(), " "))
+		os.Exit(2)
+	}
+
+	settings, err := newScannerSettings(*workers, *delay, *methods, *showRegistered)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+	if *force && *clean {
+		fmt.Println("Error: -force and -clean cannot be combined")
+		os.Exit(2)
 	}
-	
+
 	// Create config directory if it doesn't exist
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		fmt.Printf("Error creating config directory: %v\n", err)
 		os.Exit(1)
 	}
-	
-	// Generate configurations
+
+	var specs []batchSpec
+	switch splitBy {
+	case "prefix":
+		specs = prefixBatches(pattern, batchStart, batchSize, regexFilter, configDir, outputDir)
+	case "count":
+		specs = countBatches(pattern, domainLength, baseDomain, regexFilter, batchCount, configDir, outputDir)
+	case "range":
+		specs = rangeBatches(pattern, domainLength, regexFilter, *chunk, configDir, outputDir)
+	default:
+		fmt.Printf("Invalid split mode: %s. Use prefix, count or range\n", splitBy)
+		os.Exit(1)
+	}
+	setExpectedCounts(specs, domainLength, baseDomain, pattern, expectCap)
+
+	paramsOf := func(spec batchSpec) batch.Params {
+		return batch.Params{
+			BaseDomain: baseDomain,
+			Length:     domainLength,
+			Pattern:    pattern,
+			SplitBy:    splitBy,
+			Regex:      spec.regex,
+			Offset:     spec.offset,
+			Limit:      spec.limit,
+		}
+	}
+	if !resolveCollisions(specs, paramsOf, *force, *clean, *yes) {
+		os.Exit(1)
+	}
+
+	fmt.Printf("Generating batch configurations...\n")
+	fmt.Printf("Split by: %s\n", splitBy)
+	if splitBy == "prefix" {
+		fmt.Printf("Batch start: %d\n", batchStart)
+		fmt.Printf("Batch size: %d\n", batchSize)
+	} else {
+		fmt.Printf("Batches: %d\n", len(specs))
+	}
+	fmt.Printf("Base domain: %s\n", baseDomain)
+	fmt.Printf("Domain length: %d\n", domainLength)
+	fmt.Printf("Pattern: %s\n", pattern)
+	fmt.Printf("Config directory: %s\n", configDir)
+	fmt.Printf("Output directory: %s\n", outputDir)
+	fmt.Printf("Workers: %d, delay: %dms, methods: %s\n", settings.workers, settings.delay, strings.Join(settings.methodNames(), ","))
+
+	for _, spec := range specs {
+		content := renderBatchConfig(spec, domainLength, baseDomain, pattern, settings)
+
+		// Write config file
+		err := os.WriteFile(spec.configPath, []byte(content), 0644)
+		if err != nil {
+			fmt.Printf("Error writing config file %s: %v\n", spec.configPath, err)
+			continue
+		}
+
+		// Create output directory
+		if err := os.MkdirAll(spec.outputDir, 0755); err != nil {
+			fmt.Printf("Error creating output directory %s: %v\n", spec.outputDir, err)
+			continue
+		}
+
+		if err := batch.WriteParams(spec.outputDir, paramsOf(spec)); err != nil {
+			fmt.Printf("Warning: could not write batch marker for %s: %v\n", spec.name, err)
+		}
+
+		// Record the batch as pending unless a previous run already tracks it
+		if _, err := batch.ReadStatus(spec.outputDir); err != nil {
+			if err := batch.WriteStatus(batch.NewPendingStatus(spec.name, spec.configPath, spec.outputDir)); err != nil {
+				fmt.Printf("Warning: could not write batch status for %s: %v\n", spec.name, err)
+			}
+		}
+
+		fmt.Printf("Generated: %s -> %s\n", spec.configPath, spec.outputDir)
+	}
+
+	fmt.Printf("\nBatch configuration generation completed!\n")
+	if splitBy == "prefix" {
+		fmt.Printf("Generated %d configurations for batches %d to %d\n", len(specs), batchStart, batchStart+len(specs)-1)
+	} else if splitBy == "range" {
+		fmt.Printf("Generated %d numeric range configurations\n", len(specs))
+	} else {
+		fmt.Printf("Generated %d count-balanced configurations\n", len(specs))
+	}
+	fmt.Printf("Config directory: %s\n", configDir)
+	fmt.Printf("Output base directory: %s\n", outputDir)
+
+	// Create a batch index file
+	indexFile := fmt.Sprintf("%s/batch_index.txt", configDir)
+	indexContent := fmt.Sprintf(`# Batch Configuration Index
+# Auto-generated batch configuration summary
+# Generated at: $(date)
+
+# Batch Configuration Summary
+===================================
+Split By: %s
+Batch Start: %d
+Batch End: %d
+Total Batches: %d
+Base Domain: %s
+Domain Length: %d
+Pattern: %s
+Config Directory: %s
+Output Directory: %s
+Workers: %d
+Delay: %dms
+Methods: %s
+Show Registered: %v
+
+# Generated Configuration Files
+===================================`, splitBy, batchStart, batchStart+len(specs)-1, len(specs), baseDomain, domainLength, pattern, configDir, outputDir,
+		settings.workers, settings.delay, strings.Join(settings.methodNames(), ","), settings.showRegistered)
+
+	for i, spec := range specs {
+		indexContent += fmt.Sprintf("\nBatch %2d: %s -> %s\n  Config: %s\n  Output: %s\n",
+			i+1, spec.description, spec.name, spec.configPath, spec.outputDir)
+		if splitBy == "count" {
+			indexContent += fmt.Sprintf("  Range: [%d, %d)\n", spec.offset, spec.offset+spec.limit)
+		}
+		if spec.spanStart != "" {
+			indexContent += fmt.Sprintf("  Span: %s-%s (%d numbers)\n", spec.spanStart, spec.spanEnd, spec.limit)
+		}
+		if spec.expected != nil {
+			indexContent += fmt.Sprintf("  Expected: %d domains\n", *spec.expected)
+		} else {
+			indexContent += "  Expected: unknown\n"
+		}
+	}
+
+	if err := os.WriteFile(indexFile, []byte(indexContent), 0644); err != nil {
+		fmt.Printf("Warning: Could not write index file: %v\n", err)
+	} else {
+		fmt.Printf("Index file created: %s\n", indexFile)
+	}
+
+	// Write the machine-readable index used by "domain-scanner batch matrix"
+	index := &batch.Index{
+		GeneratedAt: time.Now().UTC(),
+		BaseDomain:  baseDomain,
+		Length:      domainLength,
+		Pattern:     pattern,
+		SplitBy:     splitBy,
+		Keyspace:    generator.CalculateDomainsCount(domainLength, pattern),
+		ConfigDir:   configDir,
+		OutputDir:   outputDir,
+		Scanner: batch.IndexScanner{
+			Workers:        settings.workers,
+			Delay:          settings.delay,
+			Methods:        settings.methodNames(),
+			ShowRegistered: settings.showRegistered,
+		},
+	}
+	for _, spec := range specs {
+		index.Batches = append(index.Batches, batch.IndexEntry{
+			Name:      spec.name,
+			Config:    spec.configPath,
+			OutputDir: spec.outputDir,
+			Regex:     spec.regex,
+			Offset:    spec.offset,
+			Limit:     spec.limit,
+			SpanStart: spec.spanStart,
+			SpanEnd:   spec.spanEnd,
+			Expected:  spec.expected,
+		})
+	}
+	if err := batch.WriteIndex(configDir, index); err != nil {
+		fmt.Printf("Warning: Could not write %s: %v\n", batch.IndexFileName, err)
+	} else {
+		fmt.Printf("Index file created: %s/%s\n", configDir, batch.IndexFileName)
+	}
+}
+
+// resolveCollisions finds batch output directories that already hold data from a previous
+// campaign and refuses to reuse them unless -force or -clean was given
+func resolveCollisions(specs []batchSpec, paramsOf func(batchSpec) batch.Params, force, clean, yes bool) bool {
+	var collisions []*batch.Collision
+	for _, spec := range specs {
+		collision, err := batch.CheckOutputDir(spec.outputDir, paramsOf(spec))
+		if err != nil {
+			fmt.Printf("Error inspecting output directory %s: %v\n", spec.outputDir, err)
+			return false
+		}
+		if collision != nil {
+			collisions = append(collisions, collision)
+		}
+	}
+	if len(collisions) == 0 {
+		return true
+	}
+
+	fmt.Printf("Found %d existing batch output directories:\n", len(collisions))
+	for _, collision := range collisions {
+		fmt.Printf("  %s: %d existing files\n", collision.OutputDir, collision.Files)
+		if len(collision.Mismatch) > 0 {
+			fmt.Printf("    generated with different parameters (%s)\n", strings.Join(collision.Mismatch, ", "))
+		}
+	}
+
+	switch {
+	case force:
+		fmt.Println("Reusing existing output directories (-force)")
+		return true
+	case clean:
+		if !yes && !confirm(fmt.Sprintf("Delete the contents of %d directories?", len(collisions))) {
+			fmt.Println("Aborted, nothing was deleted")
+			return false
+		}
+		for _, collision := range collisions {
+			if err := batch.CleanOutputDir(collision.OutputDir); err != nil {
+				fmt.Printf("Error cleaning %s: %v\n", collision.OutputDir, err)
+				return false
+			}
+			fmt.Printf("Cleaned: %s\n", collision.OutputDir)
+		}
+		return true
+	default:
+		fmt.Println("Refusing to reuse them; pass -force to reuse or -clean to delete their contents first")
+		return false
+	}
+}
+
+// confirm asks a yes/no question on stdin and defaults to no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// prefixBatches creates one batch per leading character of the pattern's charset
+func prefixBatches(pattern string, batchStart, batchSize int, regexFilter, configDir, outputDir string) []batchSpec {
 	var charset string
 	var maxBatches int
 
@@ -73,6 +395,9 @@ func generateBatchConfigs() {
 		fmt.Printf("Invalid pattern: %s. Use D for letters, d for digits, a for alphanumeric\n", pattern)
 		os.Exit(1)
 	}
+	if regexFilter != "" {
+		fmt.Println("Warning: -regex is only applied with -split-by count; prefix batches use their own regex")
+	}
 
 	startIdx := batchStart
 	endIdx := batchStart + batchSize
@@ -80,45 +405,159 @@ func generateBatchConfigs() {
 	if endIdx > maxBatches {
 		endIdx = maxBatches
 	}
-	
-	fmt.Printf("Generating batch configurations...\n")
-	fmt.Printf("Batch start: %d\n", startIdx)
-	fmt.Printf("Batch size: %d\n", batchSize)
-	fmt.Printf("Base domain: %s\n", baseDomain)
-	fmt.Printf("Domain length: %d\n", domainLength)
-	fmt.Printf("Pattern: %s\n", pattern)
-	fmt.Printf("Config directory: %s\n", configDir)
-	fmt.Printf("Output directory: %s\n", outputDir)
-	
+
+	var charType string
+	switch pattern {
+	case "D":
+		charType = "letter"
+	case "d":
+		charType = "digit"
+	case "a":
+		charType = "character"
+	}
+
+	var specs []batchSpec
 	for i := startIdx; i < endIdx; i++ {
 		char := string(charset[i])
-		configPath := fmt.Sprintf("%s/config_batch_%s.toml", configDir, char)
-		batchOutputDir := fmt.Sprintf("%s/batch_%s", outputDir, char)
-
-		// Create regex based on pattern
-		regex := ""
-		switch pattern {
-		case "D": // Letters only
-			regex = fmt.Sprintf("^%s.*", char)
-		case "d": // Digits only
-			// For digits, create regex that matches domains starting with this digit
-			regex = fmt.Sprintf("^%s.*", char)
-		case "a": // Alphanumeric
-			// For alphanumeric, match domains starting with this character (letter or digit)
-			regex = fmt.Sprintf("^%s.*", char)
-		}
-		
-		var charType string
-		switch pattern {
-		case "D":
-			charType = "letter"
-		case "d":
-			charType = "digit"
-		case "a":
-			charType = "character"
-		}
-
-		content := fmt.Sprintf(`# Batch domain scanner configuration for %s "%s"
+		specs = append(specs, batchSpec{
+			name:        char,
+			description: fmt.Sprintf("%s \"%s\"", charType, char),
+			charType:    charType,
+			regex:       fmt.Sprintf("^%s.*", char),
+			configPath:  fmt.Sprintf("%s/config_batch_%s.toml", configDir, char),
+			outputDir:   fmt.Sprintf("%s/batch_%s", outputDir, char),
+		})
+	}
+	return specs
+}
+
+// countBatches splits the keyspace into ranges with approximately equal candidate counts
+func countBatches(pattern string, domainLength int, baseDomain, regexFilter string, batchCount int, configDir, outputDir string) []batchSpec {
+	ranges, err := generator.SplitByCount(domainLength, baseDomain, pattern, regexFilter, types.RegexModeFull, batchCount)
+	if err != nil {
+		fmt.Printf("Error splitting keyspace: %v\n", err)
+		os.Exit(1)
+	}
+
+	width := len(strconv.Itoa(len(ranges) - 1))
+	var specs []batchSpec
+	for i, r := range ranges {
+		name := fmt.Sprintf("%0*d", width, i)
+		specs = append(specs, batchSpec{
+			name:        name,
+			description: fmt.Sprintf("range [%d, %d)", r.Offset, r.Offset+r.Limit),
+			regex:       regexFilter,
+			offset:      r.Offset,
+			limit:       r.Limit,
+			count:       r.Count,
+			counted:     true,
+			configPath:  fmt.Sprintf("%s/config_batch_%s.toml", configDir, name),
+			outputDir:   fmt.Sprintf("%s/batch_%s", outputDir, name),
+		})
+	}
+	return specs
+}
+
+// rangeBatches splits a digit keyspace into consecutive numeric spans of chunk numbers.
+// For pattern "d" the keyspace counter equals the numeric value of the name, so each
+// span maps directly onto an offset/limit range; spans are built back to back and
+// the last one is cut at the end of the keyspace, so they can neither overlap nor leave gaps.
+func rangeBatches(pattern string, domainLength int, regexFilter string, chunk int, configDir, outputDir string) []batchSpec {
+	if pattern != "d" {
+		fmt.Println("Error: -split-by range requires -pattern d")
+		os.Exit(1)
+	}
+	if chunk < 1 {
+		fmt.Println("Error: -chunk must be at least 1")
+		os.Exit(1)
+	}
+
+	total := generator.CalculateDomainsCount(domainLength, pattern)
+	var specs []batchSpec
+	for start := 0; start < total; start += chunk {
+		limit := chunk
+		if start+limit > total {
+			limit = total - start
+		}
+		spanStart := fmt.Sprintf("%0*d", domainLength, start)
+		spanEnd := fmt.Sprintf("%0*d", domainLength, start+limit-1)
+		name := spanStart + "-" + spanEnd
+		specs = append(specs, batchSpec{
+			name:        name,
+			description: fmt.Sprintf("numbers %s to %s", spanStart, spanEnd),
+			regex:       regexFilter,
+			offset:      start,
+			limit:       limit,
+			spanStart:   spanStart,
+			spanEnd:     spanEnd,
+			configPath:  fmt.Sprintf("%s/config_batch_%s.toml", configDir, name),
+			outputDir:   fmt.Sprintf("%s/batch_%s", outputDir, name),
+		})
+	}
+	return specs
+}
+
+// setExpectedCounts records how many domains each batch will generate. Count-split
+// batches already know their candidate count; other batches are counted by the generator,
+// enumerating at most expectCap candidates per batch.
+func setExpectedCounts(specs []batchSpec, domainLength int, baseDomain, pattern string, expectCap int) {
+	for i := range specs {
+		spec := &specs[i]
+		if spec.counted {
+			count := spec.count
+			spec.expected = &count
+			continue
+		}
+		count, known, err := generator.ExpectedCount(domainLength, baseDomain, pattern, spec.regex, types.RegexModeFull, spec.offset, spec.limit, expectCap)
+		if err != nil {
+			fmt.Printf("Warning: could not compute expected count for batch %s: %v\n", spec.name, err)
+			continue
+		}
+		if known {
+			spec.expected = &count
+		}
+	}
+}
+
+// renderBatchConfig renders the TOML config of a batch
+func renderBatchConfig(spec batchSpec, domainLength int, baseDomain, pattern string, settings scannerSettings) string {
+	regexComment := fmt.Sprintf(`# Regular expression filter for domains starting with "%s"
+# This ensures only domains starting with this %s are scanned`, spec.name, spec.charType)
+	rangeSection := ""
+	explanation := fmt.Sprintf(`# Regex filter explanation:
+# %s - Matches domains starting with %s
+# This reduces the domain space significantly for faster scanning
+# Example for %s 'a': "a.*" matches "ab.de", "abc.de", etc.
+`, spec.regex, spec.description, spec.charType)
+	if spec.limit > 0 {
+		regexComment = "# Regular expression filter applied to the whole keyspace (empty: none)"
+		rangeSection = fmt.Sprintf(`
+# Keyspace counter range [offset, offset+limit) of this batch
+offset = %d
+limit = %d
+`, spec.offset, spec.limit)
+		explanation = `# Count-balanced batch:
+# Batches were split so each range holds about the same number of candidates.
+# Together the ranges cover the keyspace exactly once (see batch_index.json).
+`
+		if spec.spanStart != "" {
+			explanation = fmt.Sprintf(`# Numeric range batch:
+# Generates the numbers %s to %s; consecutive batches are adjacent,
+# so together they cover the keyspace exactly once (see batch_index.json).
+`, spec.spanStart, spec.spanEnd)
+		}
+	}
+
+	expectedSection := `# Expected domains: unknown (keyspace larger than -expect-cap)
+`
+	if spec.expected != nil {
+		expectedSection = fmt.Sprintf(`# Expected domains: %d
+# The scanner warns when the number of generated domains differs
+expected_count = %d
+`, *spec.expected, *spec.expected)
+	}
+
+	return fmt.Sprintf(`# Batch domain scanner configuration for %s
 # Auto-generated for batch processing
 # Generated at: $(date)
 
@@ -128,7 +567,7 @@ func generateBatchConfigs() {
 length = %d
 
 # Domain suffix (e.g., .de, .com)
-suffix = "%s"
+suffix = %s
 
 # Domain pattern:
 # D: Pure letters (e.g., abc.de)
@@ -136,34 +575,36 @@ suffix = "%s"
 # a: Alphanumeric (e.g., a1b.de)
 pattern = "%s"
 
-# Regular expression filter for domains starting with "%s"
-# This ensures only domains starting with this %s are scanned
-regex_filter = "%s"
-
+%s
+regex_filter = %s
+%s
 # Scanner behavior configuration
 [scanner]
 # Delay between queries in milliseconds (increased to prevent rate limiting)
-delay = 1000
+delay = %d
 
 # Number of concurrent workers (reduced to prevent rate limiting)
-workers = 8
+workers = %d
 
 # Show registered domains in output
-show_registered = true
+show_registered = %v
 
 # Enabled detection methods (optimized for speed)
 [scanner.methods]
 # Check DNS records (NS, A, MX, TXT, CNAME) - fast
-dns_check = true
+dns_check = %v
 
 # Check WHOIS information - primary method
-whois_check = true
+whois_check = %v
 
-# Check SSL certificates - disabled for speed
-ssl_check = false
+# Check SSL certificates
+ssl_check = %v
 
-# Check HTTP responses - disabled
-http_check = false
+# Check HTTP responses
+http_check = %v
+
+# Check RDAP records
+rdap_check = %v
 
 # Output configuration
 [output]
@@ -177,78 +618,25 @@ registered_file = "registered_domains_batch_%s_{pattern}_{length}_{suffix}.txt"
 special_status_file = "special_status_domains_batch_%s_{pattern}_{length}_{suffix}.txt"
 
 # Output directory for this batch
-output_dir = "%s"
+output_dir = %s
 
 # Show detailed results in console (enabled for debugging)
 verbose = true
 
-# Regex filter explanation:
-# ^%s.* - Matches domains starting with %s "%s"
-# This reduces the domain space significantly for faster scanning
-# Example for %s 'a': "a.*" matches "ab.de", "abc.de", etc.
-`, charType, char, domainLength, baseDomain, pattern, char, charType, regex, char, char, char, batchOutputDir, char, charType, char, charType)
-		
-		// Write config file
-		err := os.WriteFile(configPath, []byte(content), 0644)
-		if err != nil {
-			fmt.Printf("Error writing config file %s: %v\n", configPath, err)
-			continue
-		}
-		
-		// Create output directory
-		if err := os.MkdirAll(batchOutputDir, 0755); err != nil {
-			fmt.Printf("Error creating output directory %s: %v\n", batchOutputDir, err)
-			continue
-		}
-		
-		fmt.Printf("Generated: %s -> %s\n", configPath, batchOutputDir)
-	}
-	
-	fmt.Printf("\nBatch configuration generation completed!\n")
-	fmt.Printf("Generated %d configurations for batches %d to %d\n", endIdx-startIdx, startIdx, endIdx-1)
-	fmt.Printf("Config directory: %s\n", configDir)
-	fmt.Printf("Output base directory: %s\n", outputDir)
-	
-	// Create a batch index file
-	indexFile := fmt.Sprintf("%s/batch_index.txt", configDir)
-	indexContent := fmt.Sprintf(`# Batch Configuration Index
-# Auto-generated batch configuration summary
-# Generated at: $(date)
+# Batch tracking configuration
+[batch]
+# Batch name recorded in the batch status file of the output directory
+name = "%s"
 
-# Batch Configuration Summary
-===================================
-Batch Start: %d
-Batch End: %d
-Total Batches: %d
-Base Domain: %s
-Domain Length: %d
-Pattern: %s
-Config Directory: %s
-Output Directory: %s
+%s
+%s`, spec.description, domainLength, tomlString(baseDomain), pattern, regexComment, tomlString(spec.regex), rangeSection,
+		settings.delay, settings.workers, settings.showRegistered,
+		settings.dnsCheck, settings.whoisCheck, settings.sslCheck, settings.httpCheck, settings.rdapCheck,
+		spec.name, spec.name, spec.name, tomlString(spec.outputDir), spec.name, expectedSection, explanation)
+}
 
-# Generated Configuration Files
-===================================`, startIdx, endIdx-1, endIdx-startIdx, baseDomain, domainLength, pattern, configDir, outputDir)
-	
-	for i := startIdx; i < endIdx; i++ {
-		char := string(charset[i])
-		configPath := fmt.Sprintf("config_batch_%s.toml", char)
-		outputPath := fmt.Sprintf("%s/batch_%s", outputDir, char)
-		var charType string
-		switch pattern {
-		case "D":
-			charType = "Letter"
-		case "d":
-			charType = "Digit"
-		case "a":
-			charType = "Character"
-		}
-		indexContent += fmt.Sprintf("\nBatch %2d: %s '%s' -> %s\n  Config: %s\n  Output: %s\n",
-			i-startIdx+1, charType, char, char, configPath, outputPath)
-	}
-	
-	if err := os.WriteFile(indexFile, []byte(indexContent), 0644); err != nil {
-		fmt.Printf("Warning: Could not write index file: %v\n", err)
-	} else {
-		fmt.Printf("Index file created: %s\n", indexFile)
-	}
-}
\ No newline at end of file
+// tomlString quotes s as a TOML basic string, so regex backslashes and quotes
+// survive the round trip
+func tomlString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"domain-scanner/internal/generator"
+	"domain-scanner/internal/types"
+	"github.com/BurntSushi/toml"
+)
+
+func TestRenderBatchConfig(t *testing.T) {
+	expected := 100
+	tests := []struct {
+		name     string
+		spec     batchSpec
+		workers  int
+		delay    int
+		methods  string
+		showReg  bool
+		suffix   string
+		pattern  string
+		wantMeth [5]bool // dns, whois, ssl, http, rdap
+	}{
+		{
+			name:     "prefix batch",
+			spec:     batchSpec{name: "a", description: "letter a", charType: "letter", regex: "^a", outputDir: "results/batch_a"},
+			workers:  7,
+			delay:    1500,
+			methods:  "whois",
+			suffix:   ".de",
+			pattern:  "D",
+			wantMeth: [5]bool{false, true, false, false, false},
+		},
+		{
+			name: "range batch",
+			spec: batchSpec{
+				name: "000-099", description: "numbers 000 to 099", offset: 0, limit: 100,
+				spanStart: "000", spanEnd: "099", expected: &expected, outputDir: "results/batch_000-099",
+			},
+			workers:  1,
+			delay:    0,
+			methods:  "dns, rdap,http",
+			showReg:  true,
+			suffix:   ".li",
+			pattern:  "d",
+			wantMeth: [5]bool{true, false, false, true, true},
+		},
+		{
+			name:     "count batch",
+			spec:     batchSpec{name: "02", description: "count batch 2", regex: `^\d{2}[a-z]"?$`, offset: 1234, limit: 4321, outputDir: "out/batch_02"},
+			workers:  32,
+			delay:    250,
+			methods:  "ssl,whois,dns",
+			suffix:   ".com",
+			pattern:  "a",
+			wantMeth: [5]bool{true, true, true, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := newScannerSettings(tt.workers, tt.delay, tt.methods, tt.showReg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rendered := renderBatchConfig(tt.spec, 3, tt.suffix, tt.pattern, settings)
+			var cfg types.Config
+			if _, err := toml.Decode(rendered, &cfg); err != nil {
+				t.Fatalf("generated config is not valid TOML: %v\n%s", err, rendered)
+			}
+
+			if cfg.Domain.Length != 3 || cfg.Domain.Suffix != tt.suffix || cfg.Domain.Pattern != tt.pattern {
+				t.Errorf("domain = %d %q %q, want 3 %q %q", cfg.Domain.Length, cfg.Domain.Suffix, cfg.Domain.Pattern, tt.suffix, tt.pattern)
+			}
+			if cfg.Domain.RegexFilter != tt.spec.regex {
+				t.Errorf("regex_filter = %q, want %q", cfg.Domain.RegexFilter, tt.spec.regex)
+			}
+			if cfg.Domain.Offset != tt.spec.offset || cfg.Domain.Limit != tt.spec.limit {
+				t.Errorf("offset/limit = %d/%d, want %d/%d", cfg.Domain.Offset, cfg.Domain.Limit, tt.spec.offset, tt.spec.limit)
+			}
+			if cfg.Scanner.Workers != tt.workers || cfg.Scanner.Delay != tt.delay || cfg.Scanner.ShowRegistered != tt.showReg {
+				t.Errorf("scanner = workers %d delay %d show_registered %v, want %d %d %v",
+					cfg.Scanner.Workers, cfg.Scanner.Delay, cfg.Scanner.ShowRegistered, tt.workers, tt.delay, tt.showReg)
+			}
+			m := cfg.Scanner.Methods
+			if got := [5]bool{m.DNSCheck, m.WHOISCheck, m.SSLCheck, m.HTTPCheck, m.RDAPCheck}; got != tt.wantMeth {
+				t.Errorf("methods (dns, whois, ssl, http, rdap) = %v, want %v", got, tt.wantMeth)
+			}
+			if cfg.Output.OutputDir != tt.spec.outputDir || cfg.Batch.Name != tt.spec.name {
+				t.Errorf("output_dir %q, batch name %q, want %q %q", cfg.Output.OutputDir, cfg.Batch.Name, tt.spec.outputDir, tt.spec.name)
+			}
+			if !reflect.DeepEqual(cfg.Batch.ExpectedCount, tt.spec.expected) {
+				t.Errorf("expected_count = %v, want %v", cfg.Batch.ExpectedCount, tt.spec.expected)
+			}
+		})
+	}
+}
+
+func TestNewScannerSettingsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		delay   int
+		methods string
+	}{
+		{name: "no workers", workers: 0, delay: 100, methods: "whois"},
+		{name: "negative delay", workers: 1, delay: -1, methods: "whois"},
+		{name: "unknown method", workers: 1, delay: 100, methods: "whois,ftp"},
+		{name: "no methods", workers: 1, delay: 100, methods: " , "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newScannerSettings(tt.workers, tt.delay, tt.methods, false); err == nil {
+				t.Errorf("newScannerSettings(%d, %d, %q) = nil error, want an error", tt.workers, tt.delay, tt.methods)
+			}
+		})
+	}
+}
+
+func TestRangeBatchesCoverTheKeyspace(t *testing.T) {
+	tests := []struct {
+		length    int
+		chunk     int
+		wantSpecs int
+	}{
+		{length: 1, chunk: 3, wantSpecs: 4},
+		{length: 2, chunk: 10, wantSpecs: 10},
+		{length: 2, chunk: 7, wantSpecs: 15},
+		{length: 3, chunk: 1000, wantSpecs: 1},
+		{length: 3, chunk: 2000, wantSpecs: 1},
+		{length: 3, chunk: 333, wantSpecs: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("length %d chunk %d", tt.length, tt.chunk), func(t *testing.T) {
+			specs := rangeBatches("d", tt.length, "", tt.chunk, "configs", "results")
+			if len(specs) != tt.wantSpecs {
+				t.Fatalf("got %d batches, want %d", len(specs), tt.wantSpecs)
+			}
+
+			// Every number must be generated by exactly one batch, in order
+			next := 0
+			for _, spec := range specs {
+				if spec.offset != next {
+					t.Errorf("batch %s starts at %d, want %d (overlap or gap)", spec.name, spec.offset, next)
+				}
+				if spec.limit < 1 || spec.limit > tt.chunk {
+					t.Errorf("batch %s has limit %d, want 1 to %d", spec.name, spec.limit, tt.chunk)
+				}
+				domains, err := generator.GenerateDomainsRange(context.Background(), tt.length, ".li", "d", "", types.RegexModeFull, spec.offset, spec.limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var names []string
+				for domain := range domains {
+					names = append(names, strings.TrimSuffix(domain, ".li"))
+				}
+				if len(names) != spec.limit {
+					t.Fatalf("batch %s generated %d domains, want %d", spec.name, len(names), spec.limit)
+				}
+				for i, name := range names {
+					if want := fmt.Sprintf("%0*d", tt.length, next+i); name != want {
+						t.Fatalf("batch %s generated %s at position %d, want %s", spec.name, name, i, want)
+					}
+				}
+				if names[0] != spec.spanStart || names[len(names)-1] != spec.spanEnd {
+					t.Errorf("batch %s generated %s to %s, want its span %s to %s",
+						spec.name, names[0], names[len(names)-1], spec.spanStart, spec.spanEnd)
+				}
+				if spec.name != spec.spanStart+"-"+spec.spanEnd {
+					t.Errorf("batch name = %q, want %q", spec.name, spec.spanStart+"-"+spec.spanEnd)
+				}
+				next += spec.limit
+			}
+			if total := generator.CalculateDomainsCount(tt.length, "d"); next != total {
+				t.Errorf("batches cover %d numbers, want %d", next, total)
+			}
+		})
+	}
+}